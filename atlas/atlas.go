@@ -14,6 +14,7 @@ import (
 	"github.com/go-spatial/tegola/internal/log"
 	"github.com/go-spatial/tegola/internal/observer"
 	"github.com/go-spatial/tegola/observability"
+	"github.com/go-spatial/tegola/provider"
 )
 
 var (
@@ -112,17 +113,14 @@ func (a *Atlas) AllMaps() []Map {
 }
 
 // SeedMapTile will generate a tile and persist it to the
-// configured cache backend
-func (a *Atlas) SeedMapTile(ctx context.Context, m Map, z, x, y uint) error {
+// configured cache backend. For maps with query parameters, the tile is
+// generated and keyed with params, nil params are the defaults of the map.
+func (a *Atlas) SeedMapTile(ctx context.Context, m Map, z, x, y uint, params provider.Params) error {
 
 	if a == nil {
 		// Use the default Atlas if a, is nil. This way the empty value is
 		// still useful.
-		return defaultAtlas.SeedMapTile(ctx, m, z, x, y)
-	}
-
-	if len(m.Params) > 0 {
-		return nil
+		return defaultAtlas.SeedMapTile(ctx, m, z, x, y, params)
 	}
 
 	ctx = context.WithValue(ctx, observability.ObserveVarMapName, m.Name)
@@ -131,52 +129,135 @@ func (a *Atlas) SeedMapTile(ctx context.Context, m Map, z, x, y uint) error {
 		return ErrMissingCache
 	}
 
+	params, err := m.defaultParams(params)
+	if err != nil {
+		return err
+	}
+
 	tile := slippy.Tile{Z: slippy.Zoom(z), X: x, Y: y}
 
 	// encode the tile
-	b, err := m.Encode(ctx, tile, nil)
+	b, err := m.Encode(ctx, tile, params)
 	if err != nil {
 		return err
 	}
 
 	// cache key
 	key := cache.Key{
-		MapName: m.Name,
-		Z:       z,
-		X:       x,
-		Y:       y,
+		MapName:    m.Name,
+		Version:    m.CacheVersion,
+		ParamsHash: m.ParamsHash(params),
+		Z:          z,
+		X:          x,
+		Y:          y,
 	}
 
 	return a.cacher.Set(ctx, &key, b)
 }
 
-// PurgeMapTile will purge a map tile from the configured cache backend
-func (a *Atlas) PurgeMapTile(ctx context.Context, m Map, tile *tegola.Tile) error {
+// PurgeMapTile will purge a map tile from the configured cache backend.
+// For maps with query parameters, only the tile generated with params is
+// purged, nil params are the defaults of the map. Use PurgeMapTiles to purge
+// the tiles of all the params of a map.
+func (a *Atlas) PurgeMapTile(ctx context.Context, m Map, tile *tegola.Tile, params provider.Params) error {
 	if a == nil {
 		// Use the default Atlas if a, is nil. This way the empty value is
 		// still useful.
-		return defaultAtlas.PurgeMapTile(ctx, m, tile)
+		return defaultAtlas.PurgeMapTile(ctx, m, tile, params)
 	}
 
-	if len(m.Params) > 0 {
-		return nil
+	if a.cacher == nil {
+		return ErrMissingCache
+	}
+
+	params, err := m.defaultParams(params)
+	if err != nil {
+		return err
+	}
+
+	// cache key
+	key := cache.Key{
+		MapName:    m.Name,
+		Version:    m.CacheVersion,
+		ParamsHash: m.ParamsHash(params),
+		Z:          tile.Z,
+		X:          tile.X,
+		Y:          tile.Y,
+	}
+
+	return a.cacher.Purge(ctx, &key)
+}
+
+// PurgeMapLayerTile will purge the tile of a single layer of a map, as served
+// by the /maps/:map_name/:layer_name/:z/:x/:y endpoint, from the configured
+// cache backend. params are handled as by PurgeMapTile.
+func (a *Atlas) PurgeMapLayerTile(ctx context.Context, m Map, layerName string, tile *tegola.Tile, params provider.Params) error {
+	if a == nil {
+		// Use the default Atlas if a, is nil. This way the empty value is
+		// still useful.
+		return defaultAtlas.PurgeMapLayerTile(ctx, m, layerName, tile, params)
 	}
 
 	if a.cacher == nil {
 		return ErrMissingCache
 	}
 
+	params, err := m.defaultParams(params)
+	if err != nil {
+		return err
+	}
+
 	// cache key
 	key := cache.Key{
-		MapName: m.Name,
-		Z:       tile.Z,
-		X:       tile.X,
-		Y:       tile.Y,
+		MapName:    m.Name,
+		LayerName:  layerName,
+		Version:    m.CacheVersion,
+		ParamsHash: m.ParamsHash(params),
+		Z:          tile.Z,
+		X:          tile.X,
+		Y:          tile.Y,
 	}
 
 	return a.cacher.Purge(ctx, &key)
 }
 
+// PurgeMapTiles will purge all the tiles of a map between minZoom and maxZoom
+// from the configured cache backend, for all the query parameters of the map.
+// When layerName is set the tiles of the layer, as served by the
+// /maps/:map_name/:layer_name/:z/:x/:y endpoint, are purged along with the
+// tiles of the map, which include the layer. The cache backend needs to
+// implement cache.PrefixPurger.
+func (a *Atlas) PurgeMapTiles(ctx context.Context, m Map, layerName string, minZoom, maxZoom uint) error {
+	if a == nil {
+		// Use the default Atlas if a, is nil. This way the empty value is
+		// still useful.
+		return defaultAtlas.PurgeMapTiles(ctx, m, layerName, minZoom, maxZoom)
+	}
+
+	if a.cacher == nil {
+		return ErrMissingCache
+	}
+
+	return cache.PurgePrefix(ctx, a.cacher, m.Name, layerName, minZoom, maxZoom)
+}
+
+// PurgeMapVersions will purge the tiles of the cache versions of a map other
+// than the CacheVersion of the map from the configured cache backend. The
+// cache backend needs to implement cache.VersionPurger.
+func (a *Atlas) PurgeMapVersions(ctx context.Context, m Map) error {
+	if a == nil {
+		// Use the default Atlas if a, is nil. This way the empty value is
+		// still useful.
+		return defaultAtlas.PurgeMapVersions(ctx, m)
+	}
+
+	if a.cacher == nil {
+		return ErrMissingCache
+	}
+
+	return cache.PurgeVersions(ctx, a.cacher, m.Name, m.CacheVersion)
+}
+
 // Map looks up a Map by name and returns a copy of the Map
 func (a *Atlas) Map(mapName string) (Map, error) {
 	if a == nil {
@@ -331,14 +412,24 @@ func SetCache(c cache.Interface) {
 
 // SeedMapTile will generate a tile and persist it to the
 // configured cache backend for the defaultAtlas
-func SeedMapTile(ctx context.Context, m Map, z, x, y uint) error {
-	return defaultAtlas.SeedMapTile(ctx, m, z, x, y)
+func SeedMapTile(ctx context.Context, m Map, z, x, y uint, params provider.Params) error {
+	return defaultAtlas.SeedMapTile(ctx, m, z, x, y, params)
 }
 
 // PurgeMapTile will purge a map tile from the configured cache backend
 // for the defaultAtlas
-func PurgeMapTile(ctx context.Context, m Map, tile *tegola.Tile) error {
-	return defaultAtlas.PurgeMapTile(ctx, m, tile)
+func PurgeMapTile(ctx context.Context, m Map, tile *tegola.Tile, params provider.Params) error {
+	return defaultAtlas.PurgeMapTile(ctx, m, tile, params)
+}
+
+// PurgeMapTiles will purge the tiles of a map using the default Atlas.
+func PurgeMapTiles(ctx context.Context, m Map, layerName string, minZoom, maxZoom uint) error {
+	return defaultAtlas.PurgeMapTiles(ctx, m, layerName, minZoom, maxZoom)
+}
+
+// PurgeMapVersions will purge the previous cache versions of a map using the default Atlas.
+func PurgeMapVersions(ctx context.Context, m Map) error {
+	return defaultAtlas.PurgeMapVersions(ctx, m)
 }
 
 // SetObservability sets the observability backend for the defaultAtlas
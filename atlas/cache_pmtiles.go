@@ -0,0 +1,12 @@
+// +build !noPMTilesCache
+
+package atlas
+
+// The point of this file is to load and register the PMTiles cache backend.
+// the PMTiles cache can be excluded during the build with the `noPMTilesCache` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noPMTilesCache'
+import (
+	_ "github.com/go-spatial/tegola/cache/pmtiles"
+)
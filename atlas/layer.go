@@ -17,7 +17,10 @@ type Layer struct {
 	Provider provider.Tiler
 	// default tags to include when encoding the layer. provider tags take precedence
 	DefaultTags env.Dict
-	GeomType    geom.Geometry
+	// Fields limits the tags encoded for the features of the layer to the
+	// listed ones. nil (default) encodes all the tags
+	Fields   []string
+	GeomType geom.Geometry
 	// DontSimplify indicates whether feature simplification should be applied.
 	// We use a negative in the name so the default is to simplify
 	DontSimplify bool
@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 
@@ -17,8 +18,10 @@ import (
 	"github.com/go-spatial/geom/encoding/mvt"
 	"github.com/go-spatial/geom/slippy"
 	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/auth"
 	"github.com/go-spatial/tegola/basic"
 	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/grid"
 	"github.com/go-spatial/tegola/internal/convert"
 	"github.com/go-spatial/tegola/internal/log"
 	"github.com/go-spatial/tegola/maths/simplify"
@@ -56,6 +59,14 @@ type Map struct {
 	Layers []Layer
 	// Params holds configured query parameters
 	Params []provider.QueryParameter
+	// CacheVersion is the version of the cached tiles of the map, empty
+	// for maps without a cache version
+	CacheVersion string
+	// Principals and Scopes restrict the access to the map to the callers
+	// named in Principals or granted one of the Scopes. The map is public
+	// when both are empty.
+	Principals []string
+	Scopes     []string
 
 	SRID uint64
 	// MVT output values
@@ -65,9 +76,37 @@ type Map struct {
 	mvtProviderName string
 	mvtProvider     provider.MVTTiler
 
+	// grid the tiles are laid out in, nil is WebMercatorQuad
+	grid *grid.Grid
+
 	observer observability.Interface
 }
 
+// IsPublic reports if the access to the map is not restricted
+func (m Map) IsPublic() bool { return len(m.Principals) == 0 && len(m.Scopes) == 0 }
+
+// Allows reports if the principal may access the map. p is nil for anonymous
+// callers, which can only access public maps.
+func (m Map) Allows(p *auth.Principal) bool {
+	if m.IsPublic() {
+		return true
+	}
+	if p == nil {
+		return false
+	}
+	for _, name := range m.Principals {
+		if name == p.Name {
+			return true
+		}
+	}
+	for _, scope := range m.Scopes {
+		if p.HasScope(scope) {
+			return true
+		}
+	}
+	return false
+}
+
 // HasMVTProvider indicates if map is a mvt provider based map
 func (m Map) HasMVTProvider() bool { return m.mvtProvider != nil }
 
@@ -84,6 +123,29 @@ func (m *Map) SetMVTProvider(name string, p provider.MVTTiler) provider.MVTTiler
 	return p
 }
 
+// Grid returns the tile grid of the map
+func (m Map) Grid() *grid.Grid {
+	if m.grid == nil {
+		return grid.WebMercatorQuad
+	}
+	return m.grid
+}
+
+// SetGrid sets the tile grid of the map. The SRID of the map is set to the SRID of the grid,
+// features are reprojected to it.
+func (m *Map) SetGrid(g *grid.Grid) {
+	m.grid = g
+	m.SRID = uint64(g.SRID())
+}
+
+// providerTile returns the tile handed to the providers, with the extent of the tile in the grid of the map
+func (m Map) providerTile(tile slippy.Tile) provider.Tile {
+	if m.grid == nil {
+		return provider.NewTile(tile.Z, tile.X, tile.Y, uint(m.TileBuffer), uint(m.SRID))
+	}
+	return provider.NewTileInGrid(m.grid, tile.Z, tile.X, tile.Y, uint(m.TileBuffer))
+}
+
 func (m Map) Collectors(prefix string, config func(configKey string) map[string]interface{}) ([]observability.Collector, error) {
 	if m.mvtProviderName != "" {
 		collect, ok := m.mvtProvider.(observability.Observer)
@@ -160,19 +222,16 @@ func (m Map) FilterLayersByZoom(zoom slippy.Zoom) Map {
 	return m
 }
 
-// FilterLayersByName returns a copy of a Map with a subset of layers that match the supplied list of layer names
+// FilterLayersByName returns a copy of a Map with a subset of layers that match the supplied list of layer names.
+// The names are matched exactly against the MVT name of the layers.
 func (m Map) FilterLayersByName(names ...string) Map {
 	var layers []Layer
 
-	nameStr := strings.Join(names, ",")
 	for i := range m.Layers {
-		// if we have a name set, use it for the lookup
-		if m.Layers[i].Name != "" && nameStr == m.Layers[i].Name {
-			layers = append(layers, m.Layers[i])
-			continue
-		} else if m.Layers[i].ProviderLayerName != "" && strings.Contains(nameStr, m.Layers[i].ProviderLayerName) { // default to using the ProviderLayerName for the lookup
+		// layers are looked up by the name they are encoded with, the same
+		// name FilterLayerFields is keyed by
+		if slices.Contains(names, m.Layers[i].MVTName()) {
 			layers = append(layers, m.Layers[i])
-			continue
 		}
 	}
 
@@ -184,17 +243,29 @@ func (m Map) FilterLayersByName(names ...string) Map {
 
 func (m Map) encodeMVTProviderTile(ctx context.Context, tile slippy.Tile, params provider.Params) ([]byte, error) {
 	// get the list of our layers
-	ptile := provider.NewTile(tile.Z, tile.X, tile.Y, uint(m.TileBuffer), uint(m.SRID))
+	ptile := m.providerTile(tile)
 
 	layers := make([]provider.Layer, len(m.Layers))
+	layerNames := make([]string, len(m.Layers))
 	for i := range m.Layers {
 		layers[i] = provider.Layer{
 			Name:    m.Layers[i].ProviderLayerName,
 			MVTName: m.Layers[i].MVTName(),
 		}
+		layerNames[i] = m.Layers[i].MVTName()
+	}
+
+	ctx, span := observability.StartSpan(ctx, "tegola.mvt_provider.fetch",
+		append(m.tileAttributes(tile), observability.Attr(observability.AttrLayerName, strings.Join(layerNames, ",")))...,
+	)
+	b, err := m.mvtProvider.MVTForLayers(ctx, ptile, params, layers)
+	span.RecordError(err)
+	span.End()
+	if err != nil || !m.hasFieldFilters() {
+		return b, err
 	}
-	return m.mvtProvider.MVTForLayers(ctx, ptile, params, layers)
 
+	return m.filterMVTFields(b)
 }
 
 // encodeMVTTile will encode the given tile into mvt format
@@ -224,8 +295,13 @@ func (m Map) encodeMVTTile(ctx context.Context, tile slippy.Tile, params provide
 			// on completion let the wait group know
 			defer wg.Done()
 
-			ptile := provider.NewTile(tile.Z, tile.X, tile.Y,
-				uint(m.TileBuffer), uint(m.SRID))
+			ptile := m.providerTile(tile)
+
+			// trace the fetching of the layer, the spans of the provider are its children
+			ctx, span := observability.StartSpan(ctx, "tegola.layer.fetch",
+				append(m.tileAttributes(tile), observability.Attr(observability.AttrLayerName, l.MVTName()))...,
+			)
+			defer span.End()
 
 			// fetch layer from data provider
 			err := l.Provider.TileFeatures(ctx, l.ProviderLayerName, ptile, params, func(f *provider.Feature) error {
@@ -237,12 +313,11 @@ func (m Map) encodeMVTTile(ctx context.Context, tile slippy.Tile, params provide
 
 				geo := f.Geometry
 
-				// check if the feature SRID and map SRID are different. If they are then reprojected
-				if f.SRID != m.SRID {
-					// TODO(arolek): support for additional projections
-					g, err := basic.ToWebMercator(f.SRID, geo)
+				// check if the feature SRID and the SRID of the tile grid are different. If they are then reprojected
+				if gridSRID := uint64(m.Grid().Srid); f.SRID != gridSRID {
+					g, err := basic.Reproject(f.SRID, gridSRID, geo)
 					if err != nil {
-						return fmt.Errorf("unable to transform geometry to webmercator from SRID (%v) for feature %v due to error: %w", f.SRID, f.ID, err)
+						return fmt.Errorf("unable to transform geometry to SRID (%v) from SRID (%v) for feature %v due to error: %w", gridSRID, f.SRID, f.ID, err)
 					}
 					geo = g
 				}
@@ -320,7 +395,7 @@ func (m Map) encodeMVTTile(ctx context.Context, tile slippy.Tile, params provide
 
 				mvtLayer.AddFeatures(mvt.Feature{
 					ID:       &f.ID,
-					Tags:     f.Tags,
+					Tags:     l.filterTags(f.Tags),
 					Geometry: geo,
 				})
 
@@ -344,6 +419,7 @@ func (m Map) encodeMVTTile(ctx context.Context, tile slippy.Tile, params provide
 					// we can't just write to the response as the WaitGroup is going to write to the response as well
 					log.Errorf("err fetching tile (%v) features: %v", tile, err)
 				}
+				span.RecordError(err)
 				return
 			}
 
@@ -378,6 +454,16 @@ func (m Map) encodeMVTTile(ctx context.Context, tile slippy.Tile, params provide
 	return proto.Marshal(vtile)
 }
 
+// tileAttributes returns the attributes of the spans working on the tile of the map
+func (m Map) tileAttributes(tile slippy.Tile) []observability.Attribute {
+	return []observability.Attribute{
+		observability.Attr(observability.AttrMapName, m.Name),
+		observability.Attr(observability.AttrTileZ, uint(tile.Z)),
+		observability.Attr(observability.AttrTileX, tile.X),
+		observability.Attr(observability.AttrTileY, tile.Y),
+	}
+}
+
 // Encode will encode the given tile into mvt format
 func (m Map) Encode(ctx context.Context, tile slippy.Tile, params provider.Params) ([]byte, error) {
 	var (
@@ -393,12 +479,18 @@ func (m Map) Encode(ctx context.Context, tile slippy.Tile, params provider.Param
 		return nil, err
 	}
 
+	// return encoded, gzipped tile
+	return gzipBytes(tileBytes)
+}
+
+// gzipBytes compresses b with gzip
+func gzipBytes(b []byte) ([]byte, error) {
 	// buffer to store our compressed bytes
 	var gzipBuf bytes.Buffer
 
 	// compress the encoded bytes
 	w := gzip.NewWriter(&gzipBuf)
-	_, err = w.Write(tileBytes)
+	_, err := w.Write(b)
 	if err != nil {
 		return nil, err
 	}
@@ -408,6 +500,5 @@ func (m Map) Encode(ctx context.Context, tile slippy.Tile, params provider.Param
 		return nil, err
 	}
 
-	// return encoded, gzipped tile
 	return gzipBuf.Bytes(), nil
 }
@@ -0,0 +1,167 @@
+package atlas
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/golang/protobuf/proto"
+)
+
+// FilterLayerFields returns a copy of a Map which only encodes the listed
+// fields (tags) of its layers. fields is keyed by the MVT name of the layers,
+// the layers which are not listed keep all their fields.
+func (m Map) FilterLayerFields(fields map[string][]string) Map {
+	layers := make([]Layer, len(m.Layers))
+	for i, l := range m.Layers {
+		if f, ok := fields[l.MVTName()]; ok {
+			l.Fields = f
+		}
+		layers[i] = l
+	}
+
+	// overwrite the Map's layers with our copy
+	m.Layers = layers
+
+	return m
+}
+
+// hasFieldFilters reports if any layer of the map only encodes some of its fields
+func (m Map) hasFieldFilters() bool {
+	for i := range m.Layers {
+		if m.Layers[i].Fields != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// filterTags returns the tags of the Fields of the layer, or all of them when
+// the layer has no Fields
+func (l *Layer) filterTags(tags map[string]interface{}) map[string]interface{} {
+	if l.Fields == nil {
+		return tags
+	}
+
+	filtered := make(map[string]interface{}, len(l.Fields))
+	for _, f := range l.Fields {
+		if v, ok := tags[f]; ok {
+			filtered[f] = v
+		}
+	}
+	return filtered
+}
+
+// filterMVTFields drops the fields the layers of the map don't encode from
+// the tile of a mvt provider. The keys and values of the layers are
+// rebuilt, so the dropped fields don't take space in the tile.
+func (m Map) filterMVTFields(b []byte) ([]byte, error) {
+	var vtile vectorTile.Tile
+	if err := proto.Unmarshal(b, &vtile); err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string][]string, len(m.Layers))
+	for i := range m.Layers {
+		if m.Layers[i].Fields != nil {
+			fields[m.Layers[i].MVTName()] = m.Layers[i].Fields
+		}
+	}
+
+	for _, l := range vtile.Layers {
+		f, ok := fields[l.GetName()]
+		if !ok {
+			continue
+		}
+		filterVTileLayerFields(l, f)
+	}
+
+	return proto.Marshal(&vtile)
+}
+
+// SelectMVT reduces a tile encoded by Encode, like the cached tile of the
+// whole map, to the layers of the map and to their Fields, so the layer and
+// field selections of a map can be served from the tile of the whole map.
+func (m Map) SelectMVT(b []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	if b, err = io.ReadAll(r); err != nil {
+		return nil, err
+	}
+
+	var vtile vectorTile.Tile
+	if err := proto.Unmarshal(b, &vtile); err != nil {
+		return nil, err
+	}
+
+	layers := make(map[string]*Layer, len(m.Layers))
+	for i := range m.Layers {
+		layers[m.Layers[i].MVTName()] = &m.Layers[i]
+	}
+
+	selected := vtile.Layers[:0]
+	for _, l := range vtile.Layers {
+		ml, ok := layers[l.GetName()]
+		if !ok {
+			continue
+		}
+		if ml.Fields != nil {
+			filterVTileLayerFields(l, ml.Fields)
+		}
+		selected = append(selected, l)
+	}
+	vtile.Layers = selected
+
+	if b, err = proto.Marshal(&vtile); err != nil {
+		return nil, err
+	}
+	return gzipBytes(b)
+}
+
+// filterVTileLayerFields keeps the fields of the features of l which are listed
+func filterVTileLayerFields(l *vectorTile.Tile_Layer, fields []string) {
+	keep := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		keep[f] = true
+	}
+
+	var (
+		keys    []string
+		values  []*vectorTile.Tile_Value
+		keyIdx  = map[uint32]uint32{}
+		valIdx  = map[uint32]uint32{}
+		numKeys = uint32(len(l.Keys))
+		numVals = uint32(len(l.Values))
+	)
+	for _, f := range l.Features {
+		tags := make([]uint32, 0, len(f.Tags))
+		for i := 0; i+1 < len(f.Tags); i += 2 {
+			k, v := f.Tags[i], f.Tags[i+1]
+			if k >= numKeys || v >= numVals || !keep[l.Keys[k]] {
+				continue
+			}
+
+			nk, ok := keyIdx[k]
+			if !ok {
+				nk = uint32(len(keys))
+				keys = append(keys, l.Keys[k])
+				keyIdx[k] = nk
+			}
+			nv, ok := valIdx[v]
+			if !ok {
+				nv = uint32(len(values))
+				values = append(values, l.Values[v])
+				valIdx[v] = nv
+			}
+			tags = append(tags, nk, nv)
+		}
+		f.Tags = tags
+	}
+
+	l.Keys = keys
+	l.Values = values
+}
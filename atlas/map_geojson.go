@@ -0,0 +1,228 @@
+package atlas
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/geojson"
+	"github.com/go-spatial/geom/encoding/mvt"
+	"github.com/go-spatial/geom/slippy"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/basic"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/golang/protobuf/proto"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+)
+
+// EncodeGeoJSON will encode the given tile into a JSON object holding a
+// GeoJSON FeatureCollection for each layer, keyed by the layer's MVT name.
+// Geometries are transformed to WGS84 (EPSG:4326) and the feature tags are
+// used as the properties. The geometries are returned as fetched from the
+// provider, they are not simplified or clipped to the tile.
+// The returned bytes are gzipped, the same as Encode.
+func (m Map) EncodeGeoJSON(ctx context.Context, tile slippy.Tile, params provider.Params) ([]byte, error) {
+	var (
+		collections map[string]*geojson.FeatureCollection
+		err         error
+	)
+	if m.HasMVTProvider() {
+		collections, err = m.geoJSONMVTProviderTile(ctx, tile, params)
+	} else {
+		collections, err = m.geoJSONTile(ctx, tile, params)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(collections)
+	if err != nil {
+		return nil, err
+	}
+
+	return gzipBytes(b)
+}
+
+// geoJSONTile fetches the features of the layers from their providers
+func (m Map) geoJSONTile(ctx context.Context, tile slippy.Tile, params provider.Params) (map[string]*geojson.FeatureCollection, error) {
+	var wg sync.WaitGroup
+
+	features := make([][]geojson.Feature, len(m.Layers))
+	errs := make([]error, len(m.Layers))
+
+	wg.Add(len(m.Layers))
+	for i, layer := range m.Layers {
+		go func(i int, l Layer) {
+			defer wg.Done()
+
+			ptile := m.providerTile(tile)
+
+			errs[i] = l.Provider.TileFeatures(ctx, l.ProviderLayerName, ptile, params, func(f *provider.Feature) error {
+				// skip row if geometry collection empty.
+				if g, ok := f.Geometry.(geom.Collection); ok && len(g.Geometries()) == 0 {
+					return nil
+				}
+
+				geo, err := toWGS84(f.SRID, f.Geometry)
+				if err != nil {
+					return fmt.Errorf("unable to transform geometry to WGS84 from SRID (%v) for feature %v due to error: %w", f.SRID, f.ID, err)
+				}
+
+				props := make(map[string]interface{}, len(f.Tags)+len(l.DefaultTags))
+				for k, v := range l.DefaultTags {
+					props[k] = v
+				}
+				// provider tags take precedence over the default tags
+				for k, v := range f.Tags {
+					props[k] = v
+				}
+
+				id := f.ID
+				features[i] = append(features[i], geojson.Feature{
+					ID:         &id,
+					Geometry:   geojson.Geometry{Geometry: geo},
+					Properties: l.filterTags(props),
+				})
+				return nil
+			})
+		}(i, layer)
+	}
+
+	wg.Wait()
+
+	// stop processing if the context has an error
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	collections := make(map[string]*geojson.FeatureCollection, len(m.Layers))
+	for i := range m.Layers {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("err fetching tile (%v) features for layer (%v): %w", tile, m.Layers[i].MVTName(), errs[i])
+		}
+		addFeatures(collections, m.Layers[i].MVTName(), features[i])
+	}
+
+	return collections, nil
+}
+
+// geoJSONMVTProviderTile decodes the tile returned by the mvt provider. The
+// geometries of the tile are in tile coordinates, they are scaled back to the
+// extent of the tile before being transformed to WGS84.
+func (m Map) geoJSONMVTProviderTile(ctx context.Context, tile slippy.Tile, params provider.Params) (map[string]*geojson.FeatureCollection, error) {
+	b, err := m.encodeMVTProviderTile(ctx, tile, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var vtile vectorTile.Tile
+	if err = proto.Unmarshal(b, &vtile); err != nil {
+		return nil, fmt.Errorf("err decoding tile (%v) from mvt provider: %w", tile, err)
+	}
+
+	ext, srid := m.providerTile(tile).Extent()
+
+	collections := make(map[string]*geojson.FeatureCollection, len(vtile.Layers))
+	for _, l := range vtile.Layers {
+		extent := float64(l.GetExtent())
+		if extent == 0 {
+			extent = float64(mvt.DefaultExtent)
+		}
+
+		// tile coordinates have their origin in the top left corner
+		toExtent := func(coords ...float64) ([]float64, error) {
+			return []float64{
+				ext.MinX() + coords[0]/extent*ext.XSpan(),
+				ext.MaxY() - coords[1]/extent*ext.YSpan(),
+			}, nil
+		}
+
+		features := make([]geojson.Feature, 0, len(l.Features))
+		for _, f := range l.Features {
+			switch f.GetType() {
+			case vectorTile.Tile_POINT, vectorTile.Tile_LINESTRING, vectorTile.Tile_POLYGON:
+			default:
+				// unknown geometries can't be decoded
+				continue
+			}
+
+			geo, err := mvt.DecodeGeometry(f.GetType(), f.Geometry)
+			if err != nil {
+				return nil, fmt.Errorf("err decoding feature %v of layer (%v): %w", f.GetId(), l.GetName(), err)
+			}
+			if geo, err = basic.ApplyToPoints(geo, toExtent); err != nil {
+				return nil, err
+			}
+			if geo, err = toWGS84(srid, geo); err != nil {
+				return nil, err
+			}
+
+			feature := geojson.Feature{
+				Geometry:   geojson.Geometry{Geometry: geo},
+				Properties: vtileTags(l, f.Tags),
+			}
+			if f.Id != nil {
+				id := f.GetId()
+				feature.ID = &id
+			}
+			features = append(features, feature)
+		}
+
+		addFeatures(collections, l.GetName(), features)
+	}
+
+	return collections, nil
+}
+
+// addFeatures appends the features to the collection of the layer, creating
+// the collection if needed. Layers without features still get an empty collection.
+func addFeatures(collections map[string]*geojson.FeatureCollection, name string, features []geojson.Feature) {
+	fc, ok := collections[name]
+	if !ok {
+		fc = &geojson.FeatureCollection{Features: []geojson.Feature{}}
+		collections[name] = fc
+	}
+	fc.Features = append(fc.Features, features...)
+}
+
+// vtileTags decodes the key / value index pairs of a feature using the
+// dictionaries of the layer
+func vtileTags(l *vectorTile.Tile_Layer, tags []uint32) map[string]interface{} {
+	props := make(map[string]interface{}, len(tags)/2)
+	for i := 0; i+1 < len(tags); i += 2 {
+		k, v := int(tags[i]), int(tags[i+1])
+		if k >= len(l.Keys) || v >= len(l.Values) {
+			continue
+		}
+
+		val := l.Values[v]
+		switch {
+		case val.StringValue != nil:
+			props[l.Keys[k]] = val.GetStringValue()
+		case val.FloatValue != nil:
+			props[l.Keys[k]] = val.GetFloatValue()
+		case val.DoubleValue != nil:
+			props[l.Keys[k]] = val.GetDoubleValue()
+		case val.IntValue != nil:
+			props[l.Keys[k]] = val.GetIntValue()
+		case val.UintValue != nil:
+			props[l.Keys[k]] = val.GetUintValue()
+		case val.SintValue != nil:
+			props[l.Keys[k]] = val.GetSintValue()
+		case val.BoolValue != nil:
+			props[l.Keys[k]] = val.GetBoolValue()
+		}
+	}
+	return props
+}
+
+// toWGS84 transforms the geometry from the given SRID to WGS84
+func toWGS84(srid uint64, geo geom.Geometry) (geom.Geometry, error) {
+	if srid == tegola.WGS84 {
+		return geo, nil
+	}
+	return basic.Reproject(srid, tegola.WGS84, geo)
+}
@@ -0,0 +1,100 @@
+package atlas
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/go-spatial/tegola/provider"
+)
+
+// ResolveParams returns the values of the configured query parameters of the
+// map, using the defaults of the params missing from values. nil is returned
+// for maps without parameters.
+func (m Map) ResolveParams(values url.Values) (provider.Params, error) {
+	if len(m.Params) == 0 {
+		return nil, nil
+	}
+
+	params := make(provider.Params, len(m.Params))
+	for _, param := range m.Params {
+		var (
+			val provider.QueryParameterValue
+			err error
+		)
+		if values.Has(param.Name) {
+			val, err = param.ToValue(values.Get(param.Name))
+		} else {
+			val, err = param.ToDefaultValue()
+		}
+		if err != nil {
+			return nil, err
+		}
+		params[param.Token] = val
+	}
+
+	return params, nil
+}
+
+// defaultParams returns params, or the default values of the query parameters
+// of the map when params is nil
+func (m Map) defaultParams(params provider.Params) (provider.Params, error) {
+	if params != nil || len(m.Params) == 0 {
+		return params, nil
+	}
+	return m.ResolveParams(nil)
+}
+
+// HasParam reports if the map declares a query parameter named name
+func (m Map) HasParam(name string) bool {
+	for _, param := range m.Params {
+		if param.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasCacheKeyParams reports if the map declares query parameters which are
+// part of the cache key, in which case the tiles of the map are cached once per
+// combination of their values
+func (m Map) HasCacheKeyParams() bool {
+	for _, param := range m.Params {
+		if param.InCacheKey() {
+			return true
+		}
+	}
+	return false
+}
+
+// ParamsHash returns the canonical hash of the resolved params used to key the
+// cached tiles of the map. The params which are not part of the cache key are
+// ignored. Equal values hash the same regardless of how they were written in
+// the request (i.e. "01" and "1" for an int). An empty string is returned when
+// no param is part of the cache key.
+func (m Map) ParamsHash(params provider.Params) string {
+	names := make([]string, 0, len(m.Params))
+	tokens := make(map[string]string, len(m.Params))
+	for _, param := range m.Params {
+		if !param.InCacheKey() {
+			continue
+		}
+		names = append(names, param.Name)
+		tokens[param.Name] = param.Token
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		val := params[tokens[name]]
+		// the SQL differs when the default_sql of a param is used
+		fmt.Fprintf(h, "%s\x00%s\x00%T\x00%v\x00", name, val.SQL, val.Value, val.Value)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
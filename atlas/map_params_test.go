@@ -0,0 +1,157 @@
+package atlas_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/provider"
+)
+
+func newTestParamsMap() atlas.Map {
+	noCacheKey := false
+
+	m := atlas.NewWebMercatorMap("test-map")
+	m.Layers = append(m.Layers, testLayer1)
+	m.Params = []provider.QueryParameter{
+		{Name: "year", Token: "!YEAR!", Type: "int", SQL: "AND year = ?", DefaultValue: "2020"},
+		{Name: "kind", Token: "!KIND!", Type: "string", SQL: "AND kind = ?", DefaultSQL: " "},
+		{Name: "trace", Token: "!TRACE!", Type: "string", DefaultSQL: " ", CacheKey: &noCacheKey},
+	}
+	return m
+}
+
+func TestMapParamsHash(t *testing.T) {
+	type tcase struct {
+		a, b  url.Values
+		equal bool
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			m := newTestParamsMap()
+
+			pa, err := m.ResolveParams(tc.a)
+			if err != nil {
+				t.Fatalf("resolve a, expected nil got %v", err)
+			}
+			pb, err := m.ResolveParams(tc.b)
+			if err != nil {
+				t.Fatalf("resolve b, expected nil got %v", err)
+			}
+
+			ha, hb := m.ParamsHash(pa), m.ParamsHash(pb)
+			if ha == "" || hb == "" {
+				t.Fatalf("hash, expected non empty hashes got %q and %q", ha, hb)
+			}
+			if (ha == hb) != tc.equal {
+				t.Errorf("hash equality, expected %v got %v (%v, %v)", tc.equal, ha == hb, ha, hb)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"same values": {
+			a:     url.Values{"year": {"2021"}},
+			b:     url.Values{"year": {"2021"}},
+			equal: true,
+		},
+		"different values": {
+			a: url.Values{"year": {"2021"}},
+			b: url.Values{"year": {"2022"}},
+		},
+		"normalized value": {
+			a:     url.Values{"year": {"2021"}},
+			b:     url.Values{"year": {"+2021"}},
+			equal: true,
+		},
+		"default value": {
+			a:     url.Values{},
+			b:     url.Values{"year": {"2020"}},
+			equal: true,
+		},
+		"default sql": {
+			a: url.Values{},
+			b: url.Values{"kind": {" "}},
+		},
+		"param not in cache key": {
+			a:     url.Values{"trace": {"a"}},
+			b:     url.Values{"trace": {"b"}},
+			equal: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestMapParamsHashNoParams(t *testing.T) {
+	m := atlas.NewWebMercatorMap("test-map")
+	params, err := m.ResolveParams(url.Values{"year": {"2021"}})
+	if err != nil {
+		t.Fatalf("resolve, expected nil got %v", err)
+	}
+	if params != nil {
+		t.Errorf("params, expected nil got %v", params)
+	}
+	if h := m.ParamsHash(params); h != "" {
+		t.Errorf("hash, expected empty got %v", h)
+	}
+}
+
+func TestSeedPurgeMapTileParams(t *testing.T) {
+	ctx := context.Background()
+	m := newTestParamsMap()
+
+	a := &atlas.Atlas{}
+	a.AddMap(m)
+	c, err := memory.New(nil)
+	if err != nil {
+		t.Fatalf("cache, expected nil got %v", err)
+	}
+	a.SetCache(c)
+
+	params, err := m.ResolveParams(url.Values{"year": {"2021"}})
+	if err != nil {
+		t.Fatalf("resolve, expected nil got %v", err)
+	}
+	defaults, err := m.ResolveParams(nil)
+	if err != nil {
+		t.Fatalf("resolve defaults, expected nil got %v", err)
+	}
+	key := cache.Key{MapName: m.Name, ParamsHash: m.ParamsHash(params), Z: 4, X: 1, Y: 2}
+	defaultKey := cache.Key{MapName: m.Name, ParamsHash: m.ParamsHash(defaults), Z: 4, X: 1, Y: 2}
+
+	if err := a.SeedMapTile(ctx, m, 4, 1, 2, params); err != nil {
+		t.Fatalf("seed, expected nil got %v", err)
+	}
+	if _, hit, _ := c.Get(ctx, &key); !hit {
+		t.Errorf("seeded tile, expected hit got miss")
+	}
+	if _, hit, _ := c.Get(ctx, &defaultKey); hit {
+		t.Errorf("default params tile, expected miss got hit")
+	}
+
+	// nil params are the defaults
+	if err := a.SeedMapTile(ctx, m, 4, 1, 2, nil); err != nil {
+		t.Fatalf("seed defaults, expected nil got %v", err)
+	}
+	if _, hit, _ := c.Get(ctx, &defaultKey); !hit {
+		t.Errorf("default params tile, expected hit got miss")
+	}
+
+	if err := a.PurgeMapTile(ctx, m, tegola.NewTile(4, 1, 2), params); err != nil {
+		t.Fatalf("purge, expected nil got %v", err)
+	}
+	if _, hit, _ := c.Get(ctx, &key); hit {
+		t.Errorf("purged tile, expected miss got hit")
+	}
+	if _, hit, _ := c.Get(ctx, &defaultKey); !hit {
+		t.Errorf("default params tile, expected hit got miss")
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/json"
 	"io"
+	"math"
 	"reflect"
 	"testing"
 
@@ -13,6 +15,7 @@ import (
 	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
 	"github.com/go-spatial/geom/slippy"
 	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/auth"
 	"github.com/go-spatial/tegola/internal/p"
 	"github.com/go-spatial/tegola/provider/test"
 	"github.com/go-spatial/tegola/provider/test/emptycollection"
@@ -147,7 +150,7 @@ func TestMapFilterLayersByZoom(t *testing.T) {
 func TestMapFilterLayersByName(t *testing.T) {
 	testcases := []struct {
 		grid     atlas.Map
-		name     string
+		names    []string
 		expected atlas.Map
 	}{
 		{
@@ -165,7 +168,7 @@ func TestMapFilterLayersByName(t *testing.T) {
 					},
 				},
 			},
-			name: "layer1",
+			names: []string{"layer1"},
 			expected: atlas.Map{
 				Layers: []atlas.Layer{
 					{
@@ -187,7 +190,7 @@ func TestMapFilterLayersByName(t *testing.T) {
 					},
 				},
 			},
-			name: "layer1roads",
+			names: []string{"layer1roads"},
 			expected: atlas.Map{
 				Layers: []atlas.Layer{
 					{
@@ -196,10 +199,82 @@ func TestMapFilterLayersByName(t *testing.T) {
 				},
 			},
 		},
+		{
+			grid: atlas.Map{
+				Layers: []atlas.Layer{
+					{
+						Name: "roads",
+					},
+					{
+						Name: "water",
+					},
+					{
+						Name: "buildings",
+					},
+				},
+			},
+			names: []string{"water", "roads"},
+			expected: atlas.Map{
+				Layers: []atlas.Layer{
+					{
+						Name: "roads",
+					},
+					{
+						Name: "water",
+					},
+				},
+			},
+		},
+		{
+			// layers without a name are matched on their provider layer name
+			grid: atlas.Map{
+				Layers: []atlas.Layer{
+					{
+						ProviderLayerName: "roads",
+					},
+					{
+						ProviderLayerName: "water",
+					},
+				},
+			},
+			names: []string{"roads"},
+			expected: atlas.Map{
+				Layers: []atlas.Layer{
+					{
+						ProviderLayerName: "roads",
+					},
+				},
+			},
+		},
+		{
+			// names are not matched on a part of the provider layer name
+			grid: atlas.Map{
+				Layers: []atlas.Layer{
+					{
+						ProviderLayerName: "roads",
+					},
+				},
+			},
+			names:    []string{"roads_labels"},
+			expected: atlas.Map{},
+		},
+		{
+			// named layers are not matched on their provider layer name
+			grid: atlas.Map{
+				Layers: []atlas.Layer{
+					{
+						Name:              "streets",
+						ProviderLayerName: "roads",
+					},
+				},
+			},
+			names:    []string{"roads"},
+			expected: atlas.Map{},
+		},
 	}
 
 	for i, tc := range testcases {
-		output := tc.grid.FilterLayersByName(tc.name)
+		output := tc.grid.FilterLayersByName(tc.names...)
 
 		if !reflect.DeepEqual(output, tc.expected) {
 			t.Errorf("testcase (%v) failed. output \n\n%+v\n\n does not match expected \n\n%+v", i, output, tc.expected)
@@ -207,6 +282,66 @@ func TestMapFilterLayersByName(t *testing.T) {
 	}
 }
 
+func TestMapAllows(t *testing.T) {
+	type tcase struct {
+		principals []string
+		scopes     []string
+		principal  *auth.Principal
+		expected   bool
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			m := atlas.NewWebMercatorMap("osm")
+			m.Principals = tc.principals
+			m.Scopes = tc.scopes
+
+			if got := m.Allows(tc.principal); got != tc.expected {
+				t.Errorf("allows, expected %v got %v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"public anonymous": {
+			expected: true,
+		},
+		"public authenticated": {
+			principal: &auth.Principal{Name: "acme"},
+			expected:  true,
+		},
+		"restricted anonymous": {
+			principals: []string{"acme"},
+			expected:   false,
+		},
+		"listed principal": {
+			principals: []string{"acme"},
+			principal:  &auth.Principal{Name: "acme"},
+			expected:   true,
+		},
+		"other principal": {
+			principals: []string{"acme"},
+			principal:  &auth.Principal{Name: "globex"},
+			expected:   false,
+		},
+		"listed scope": {
+			scopes:    []string{"osm:read"},
+			principal: &auth.Principal{Name: "globex", Scopes: []string{"other", "osm:read"}},
+			expected:  true,
+		},
+		"other scope": {
+			principals: []string{"acme"},
+			scopes:     []string{"osm:read"},
+			principal:  &auth.Principal{Name: "globex", Scopes: []string{"other"}},
+			expected:   false,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
 func TestEncode(t *testing.T) {
 	// create vars for the vector tile types so we can take their addresses
 	// unknown := vectorTile.Tile_UNKNOWN
@@ -443,3 +578,177 @@ func TestEncode(t *testing.T) {
 		t.Run(name, fn(tc))
 	}
 }
+
+func TestEncodeGeoJSON(t *testing.T) {
+	type collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry struct {
+				Type        string         `json:"type"`
+				Coordinates [][][2]float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}
+
+	type tcase struct {
+		grid atlas.Map
+		tile slippy.Tile
+		// expectedProperties of the single feature of each layer
+		expectedProperties map[string]map[string]interface{}
+		// expectedBounds of the single feature of each layer, in WGS84
+		expectedBounds [4]float64
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			out, err := tc.grid.EncodeGeoJSON(context.Background(), tc.tile, nil)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+
+			r, err := gzip.NewReader(bytes.NewReader(out))
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+
+			var layers map[string]collection
+			if err = json.NewDecoder(r).Decode(&layers); err != nil {
+				t.Fatalf("error decoding output: %v", err)
+			}
+
+			if len(layers) != len(tc.expectedProperties) {
+				t.Fatalf("expected (%d) layers, got (%d)", len(tc.expectedProperties), len(layers))
+			}
+
+			for name, props := range tc.expectedProperties {
+				fc, ok := layers[name]
+				if !ok {
+					t.Errorf("expected layer %v, got none", name)
+					continue
+				}
+				if fc.Type != "FeatureCollection" {
+					t.Errorf("layer %v type, expected FeatureCollection got %v", name, fc.Type)
+				}
+				if len(fc.Features) != 1 {
+					t.Errorf("layer %v, expected 1 feature got %v", name, len(fc.Features))
+					continue
+				}
+
+				f := fc.Features[0]
+				if !reflect.DeepEqual(f.Properties, props) {
+					t.Errorf("layer %v properties, expected %v got %v", name, props, f.Properties)
+				}
+
+				bounds := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+				for _, ring := range f.Geometry.Coordinates {
+					for _, pt := range ring {
+						bounds[0], bounds[1] = math.Min(bounds[0], pt[0]), math.Min(bounds[1], pt[1])
+						bounds[2], bounds[3] = math.Max(bounds[2], pt[0]), math.Max(bounds[3], pt[1])
+					}
+				}
+				for i := range bounds {
+					if math.Abs(bounds[i]-tc.expectedBounds[i]) > 1e-6 {
+						t.Errorf("layer %v bounds, expected %v got %v", name, tc.expectedBounds, bounds)
+						break
+					}
+				}
+			}
+		}
+	}
+
+	polygon := vectorTile.Tile_POLYGON
+	mvtTile, err := proto.Marshal(&vectorTile.Tile{
+		Layers: []*vectorTile.Tile_Layer{
+			{
+				Version: p.Uint32(2),
+				Name:    p.String("mvt_layer"),
+				Features: []*vectorTile.Tile_Feature{
+					{
+						Id:       p.Uint64(1),
+						Tags:     []uint32{0, 0, 1, 1},
+						Type:     &polygon,
+						Geometry: []uint32{9, 0, 0, 26, 8192, 0, 0, 8192, 8191, 0, 15},
+					},
+				},
+				Keys:   []string{"type", "name"},
+				Values: []*vectorTile.Tile_Value{{StringValue: p.String("outline")}, {StringValue: p.String("tile")}},
+				Extent: p.Uint32(vectorTile.Default_Tile_Layer_Extent),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	mvtMap := atlas.NewWebMercatorMap("mvt")
+	mvtMap.Layers = []atlas.Layer{{ProviderLayerName: "mvt_layer"}}
+	mvtMap.SetMVTProvider("test", &test.TileProvider{MVTTile: mvtTile})
+
+	stdMap := atlas.NewWebMercatorMap("std")
+	stdMap.Layers = []atlas.Layer{
+		{
+			Name:     "layer1",
+			Provider: &test.TileProvider{},
+			DefaultTags: map[string]interface{}{
+				"foo": "bar",
+			},
+		},
+		{
+			Name:     "layer2",
+			Provider: &test.TileProvider{},
+		},
+	}
+
+	// tile 2/3/3 is the bottom right tile of zoom 2
+	bounds := [4]float64{90, -85.0511287798066, 180, -66.51326044311186}
+
+	tests := map[string]tcase{
+		"test_provider": {
+			grid: stdMap,
+			tile: slippy.Tile{Z: 2, X: 3, Y: 3},
+			expectedProperties: map[string]map[string]interface{}{
+				"layer1": {"type": "debug_buffer_outline", "foo": "bar"},
+				"layer2": {"type": "debug_buffer_outline"},
+			},
+			expectedBounds: bounds,
+		},
+		"mvt_provider": {
+			grid: mvtMap,
+			tile: slippy.Tile{Z: 2, X: 3, Y: 3},
+			expectedProperties: map[string]map[string]interface{}{
+				"mvt_layer": {"type": "outline", "name": "tile"},
+			},
+			expectedBounds: bounds,
+		},
+		"test_provider fields": {
+			grid: stdMap.FilterLayerFields(map[string][]string{"layer1": {"foo", "missing"}}),
+			tile: slippy.Tile{Z: 2, X: 3, Y: 3},
+			expectedProperties: map[string]map[string]interface{}{
+				"layer1": {"foo": "bar"},
+				"layer2": {"type": "debug_buffer_outline"},
+			},
+			expectedBounds: bounds,
+		},
+		"mvt_provider fields": {
+			grid: mvtMap.FilterLayerFields(map[string][]string{"mvt_layer": {"name"}}),
+			tile: slippy.Tile{Z: 2, X: 3, Y: 3},
+			expectedProperties: map[string]map[string]interface{}{
+				"mvt_layer": {"name": "tile"},
+			},
+			expectedBounds: bounds,
+		},
+		"mvt_provider no fields": {
+			grid: mvtMap.FilterLayerFields(map[string][]string{"mvt_layer": {}}),
+			tile: slippy.Tile{Z: 2, X: 3, Y: 3},
+			expectedProperties: map[string]map[string]interface{}{
+				"mvt_layer": {},
+			},
+			expectedBounds: bounds,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
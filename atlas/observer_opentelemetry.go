@@ -0,0 +1,9 @@
+// +build !noOpenTelemetryObserver
+
+package atlas
+
+// The point of this file is to load and register the opentelemetry observer backend.
+// The opentelemetry observer can be excluded during the build with the `noOpenTelemetryObserver` build flag
+import (
+	_ "github.com/go-spatial/tegola/observability/opentelemetry"
+)
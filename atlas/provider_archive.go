@@ -0,0 +1,12 @@
+// +build !noArchiveProvider
+
+package atlas
+
+// The point of this file is to load and register the MBTiles and PMTiles archive providers.
+// the archive providers can be excluded during the build with the `noArchiveProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noArchiveProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/archive"
+)
@@ -0,0 +1,12 @@
+// +build !noGeojsonProvider
+
+package atlas
+
+// The point of this file is to load and register the GeoJSON provider.
+// the GeoJSON provider can be excluded during the build with the `noGeojsonProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noGeojsonProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/geojson"
+)
@@ -0,0 +1,12 @@
+// +build !noGeoparquetProvider
+
+package atlas
+
+// The point of this file is to load and register the GeoParquet provider.
+// the GeoParquet provider can be excluded during the build with the `noGeoparquetProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noGeoparquetProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/geoparquet"
+)
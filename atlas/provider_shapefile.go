@@ -0,0 +1,12 @@
+// +build !noShapefileProvider
+
+package atlas
+
+// The point of this file is to load and register the Shapefile provider.
+// the Shapefile provider can be excluded during the build with the `noShapefileProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noShapefileProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/shapefile"
+)
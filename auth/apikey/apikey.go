@@ -0,0 +1,119 @@
+// Package apikey authenticates requests by static API keys
+package apikey
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-spatial/tegola/auth"
+	"github.com/go-spatial/tegola/dict"
+)
+
+const AuthType = "api_key"
+
+const (
+	ConfigKeyKeys      = "keys"
+	ConfigKeyKey       = "key"
+	ConfigKeyPrincipal = "principal"
+	ConfigKeyScopes    = "scopes"
+)
+
+const (
+	// HeaderAPIKey is the request header carrying the API key
+	HeaderAPIKey = "X-Api-Key"
+	// QueryParamAPIKey is the query param carrying the API key, for clients
+	// which can't set headers
+	QueryParamAPIKey = "api_key"
+)
+
+var ErrNoKeys = errors.New("api_key auth: at least one key is required")
+
+// ErrInvalidKey is returned when a key of the config is invalid
+type ErrInvalidKey struct {
+	// Index of the key in the config
+	Index int
+	Err   error
+}
+
+func (e ErrInvalidKey) Error() string {
+	return fmt.Sprintf("api_key auth: key (%v): %v", e.Index, e.Err)
+}
+
+func (e ErrInvalidKey) Unwrap() error { return e.Err }
+
+func init() {
+	auth.Register(AuthType, New) //nolint:errcheck
+}
+
+// New instantiates an API key authenticator. The config expects the following params:
+//
+//	keys (array of tables): the API keys, each with:
+//		key (string): the API key
+//		principal (string): the name of the owner of the key
+//		scopes ([]string): the scopes granted to the key
+func New(config dict.Dicter) (auth.Authenticator, error) {
+	keyConfigs, err := config.MapSlice(ConfigKeyKeys)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyConfigs) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	a := Authenticator{
+		principals: make(map[[sha256.Size]byte]*auth.Principal, len(keyConfigs)),
+	}
+	for i, kc := range keyConfigs {
+		key, err := kc.String(ConfigKeyKey, nil)
+		if err != nil {
+			return nil, ErrInvalidKey{Index: i, Err: err}
+		}
+		if key == "" {
+			return nil, ErrInvalidKey{Index: i, Err: errors.New("empty key")}
+		}
+
+		p := auth.Principal{}
+		if p.Name, err = kc.String(ConfigKeyPrincipal, nil); err != nil {
+			return nil, ErrInvalidKey{Index: i, Err: err}
+		}
+		if p.Scopes, err = kc.StringSlice(ConfigKeyScopes); err != nil {
+			return nil, ErrInvalidKey{Index: i, Err: err}
+		}
+
+		hash := sha256.Sum256([]byte(key))
+		if _, ok := a.principals[hash]; ok {
+			return nil, ErrInvalidKey{Index: i, Err: errors.New("duplicated key")}
+		}
+		a.principals[hash] = &p
+	}
+
+	return &a, nil
+}
+
+// Authenticator authenticates requests by the API key of the X-Api-Key
+// header, or of the api_key query param.
+type Authenticator struct {
+	// principals by the hash of their key. looking up hashes does not leak
+	// the keys through timing
+	principals map[[sha256.Size]byte]*auth.Principal
+}
+
+func (a *Authenticator) Authenticate(r *http.Request) (*auth.Principal, error) {
+	key := r.Header.Get(HeaderAPIKey)
+	if key == "" {
+		key = r.URL.Query().Get(QueryParamAPIKey)
+	}
+	if key == "" {
+		return nil, auth.ErrNoCredentials
+	}
+
+	p, ok := a.principals[sha256.Sum256([]byte(key))]
+	if !ok {
+		return nil, auth.ErrInvalidCredentials
+	}
+	return p, nil
+}
+
+func (a *Authenticator) QueryParams() []string { return []string{QueryParamAPIKey} }
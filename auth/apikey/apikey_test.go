@@ -0,0 +1,131 @@
+package apikey_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/tegola/auth"
+	"github.com/go-spatial/tegola/auth/apikey"
+	"github.com/go-spatial/tegola/dict"
+)
+
+func TestNew(t *testing.T) {
+	type tcase struct {
+		config      dict.Dict
+		expectedErr error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			_, err := apikey.New(tc.config)
+			if tc.expectedErr != nil {
+				if err == nil || err.Error() != tc.expectedErr.Error() {
+					t.Errorf("invalid error. expected: %v, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected err: %v", err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"valid": {
+			config: dict.Dict{
+				"keys": []map[string]interface{}{
+					{"key": "k1", "principal": "acme", "scopes": []string{"read"}},
+					{"key": "k2", "principal": "globex"},
+				},
+			},
+		},
+		"no keys": {
+			config:      dict.Dict{"keys": []map[string]interface{}{}},
+			expectedErr: apikey.ErrNoKeys,
+		},
+		"empty key": {
+			config: dict.Dict{
+				"keys": []map[string]interface{}{
+					{"key": "", "principal": "acme"},
+				},
+			},
+			expectedErr: apikey.ErrInvalidKey{Index: 0, Err: errors.New("empty key")},
+		},
+		"duplicated key": {
+			config: dict.Dict{
+				"keys": []map[string]interface{}{
+					{"key": "k1", "principal": "acme"},
+					{"key": "k1", "principal": "globex"},
+				},
+			},
+			expectedErr: apikey.ErrInvalidKey{Index: 1, Err: errors.New("duplicated key")},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	a, err := apikey.New(dict.Dict{
+		"keys": []map[string]interface{}{
+			{"key": "k1", "principal": "acme", "scopes": []string{"read"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	type tcase struct {
+		uri         string
+		header      string
+		expected    *auth.Principal
+		expectedErr error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			r := httptest.NewRequest("GET", tc.uri, nil)
+			if tc.header != "" {
+				r.Header.Set(apikey.HeaderAPIKey, tc.header)
+			}
+
+			p, err := a.Authenticate(r)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("invalid error. expected: %v, got %v", tc.expectedErr, err)
+				return
+			}
+			if !reflect.DeepEqual(p, tc.expected) {
+				t.Errorf("principal, expected %+v got %+v", tc.expected, p)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"header": {
+			uri:      "/capabilities",
+			header:   "k1",
+			expected: &auth.Principal{Name: "acme", Scopes: []string{"read"}},
+		},
+		"query param": {
+			uri:      "/capabilities?api_key=k1",
+			expected: &auth.Principal{Name: "acme", Scopes: []string{"read"}},
+		},
+		"no key": {
+			uri:         "/capabilities",
+			expectedErr: auth.ErrNoCredentials,
+		},
+		"unknown key": {
+			uri:         "/capabilities",
+			header:      "k2",
+			expectedErr: auth.ErrInvalidCredentials,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
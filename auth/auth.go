@@ -0,0 +1,123 @@
+// Package auth authenticates the requests of the server. Authenticators are
+// registered by type, like cache backends, and are configured in the
+// [[webserver.auth]] section of the config.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/go-spatial/tegola/dict"
+)
+
+var (
+	// ErrNoCredentials is returned by authenticators when the request does
+	// not carry credentials for them
+	ErrNoCredentials = errors.New("auth: no credentials")
+	// ErrInvalidCredentials is returned by authenticators when the
+	// credentials of the request are invalid
+	ErrInvalidCredentials = errors.New("auth: invalid credentials")
+	// ErrExpiredCredentials is returned by authenticators when the
+	// credentials of the request have expired
+	ErrExpiredCredentials = errors.New("auth: expired credentials")
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	// Name identifies the caller, i.e. the owner of an API key or the
+	// subject of a token
+	Name string
+	// Scopes granted to the caller
+	Scopes []string
+}
+
+// HasScope reports if the principal was granted the scope
+func (p *Principal) HasScope(scope string) bool {
+	return p != nil && slices.Contains(p.Scopes, scope)
+}
+
+// Authenticator authenticates requests
+type Authenticator interface {
+	// Authenticate returns the principal of the request. ErrNoCredentials is
+	// returned when the request has no credentials for the authenticator,
+	// so the next one can be tried.
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// QueryParamer is implemented by the authenticators reading credentials from
+// the query string. The params are removed from the authenticated requests,
+// so they are not mistaken for map params.
+type QueryParamer interface {
+	QueryParams() []string
+}
+
+// Authenticate tries the authenticators in order and returns the principal of
+// the first one the request has credentials for. The principal is nil when
+// the request has no credentials, which is an anonymous request.
+func Authenticate(r *http.Request, authenticators []Authenticator) (*Principal, error) {
+	for _, a := range authenticators {
+		p, err := a.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return p, err
+	}
+	return nil, nil
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx holding the principal
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal held by ctx, nil for anonymous requests
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// InitFunc initializes an authenticator given a config map.
+// The InitFunc should validate the config map, and report any errors.
+// This is called by the For function.
+type InitFunc func(dict.Dicter) (Authenticator, error)
+
+var authenticators map[string]InitFunc
+
+// Register is called by the init functions of the authenticators.
+func Register(authType string, init InitFunc) error {
+	if authenticators == nil {
+		authenticators = make(map[string]InitFunc)
+	}
+
+	if _, ok := authenticators[authType]; ok {
+		return fmt.Errorf("auth: authenticator (%v) already exists", authType)
+	}
+	authenticators[authType] = init
+
+	return nil
+}
+
+// Registered returns the types of the authenticators that have been registered.
+func Registered() (a []string) {
+	for k := range authenticators {
+		a = append(a, k)
+	}
+	sort.Strings(a)
+	return a
+}
+
+// For returns a configured authenticator of the given type, provided the correct config map.
+func For(authType string, config dict.Dicter) (Authenticator, error) {
+	init, ok := authenticators[authType]
+	if !ok {
+		return nil, fmt.Errorf("auth: no authenticator registered by the type: (%v)", authType)
+	}
+
+	return init(config)
+}
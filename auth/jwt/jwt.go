@@ -0,0 +1,267 @@
+// Package jwt authenticates requests by JSON Web Tokens verified with the
+// public keys of a local JWKS file
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-spatial/tegola/auth"
+	"github.com/go-spatial/tegola/dict"
+)
+
+const AuthType = "jwt"
+
+const (
+	ConfigKeyJWKSFile       = "jwks_file"
+	ConfigKeyIssuer         = "issuer"
+	ConfigKeyAudience       = "audience"
+	ConfigKeyPrincipalClaim = "principal_claim"
+	ConfigKeyScopesClaim    = "scopes_claim"
+	ConfigKeyLeeway         = "leeway"
+)
+
+var (
+	defaultIssuer         = ""
+	defaultAudience       = ""
+	defaultPrincipalClaim = "sub"
+	defaultScopesClaim    = "scope"
+	defaultLeeway         = 60
+)
+
+var (
+	ErrMissingJWKSFile = errors.New("jwt auth: missing required param 'jwks_file'")
+	ErrNoKeys          = errors.New("jwt auth: the JWKS has no signing keys")
+)
+
+func init() {
+	auth.Register(AuthType, New) //nolint:errcheck
+}
+
+// New instantiates a JWT authenticator. The config expects the following params:
+//
+//	jwks_file (string): the path of the JWKS file with the public keys the tokens are signed with
+//	issuer (string): the required iss claim, if not set the issuer is not checked
+//	audience (string): the required aud claim, if not set the audience is not checked
+//	principal_claim (string): the claim naming the principal, defaults to sub
+//	scopes_claim (string): the claim with the scopes, a space separated string or an array, defaults to scope
+//	leeway (int): seconds of clock skew tolerated checking exp and nbf, defaults to 60
+func New(config dict.Dicter) (auth.Authenticator, error) {
+	path, err := config.String(ConfigKeyJWKSFile, nil)
+	if err != nil || path == "" {
+		return nil, ErrMissingJWKSFile
+	}
+
+	keys, err := readJWKS(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwt auth: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	a := Authenticator{keys: keys}
+	if a.Issuer, err = config.String(ConfigKeyIssuer, &defaultIssuer); err != nil {
+		return nil, err
+	}
+	if a.Audience, err = config.String(ConfigKeyAudience, &defaultAudience); err != nil {
+		return nil, err
+	}
+	if a.PrincipalClaim, err = config.String(ConfigKeyPrincipalClaim, &defaultPrincipalClaim); err != nil {
+		return nil, err
+	}
+	if a.ScopesClaim, err = config.String(ConfigKeyScopesClaim, &defaultScopesClaim); err != nil {
+		return nil, err
+	}
+	leeway, err := config.Int(ConfigKeyLeeway, &defaultLeeway)
+	if err != nil {
+		return nil, err
+	}
+	a.Leeway = time.Duration(leeway) * time.Second
+
+	return &a, nil
+}
+
+// Authenticator authenticates requests by the JWT of the Authorization header
+// (Bearer scheme). Tokens signed with RS256, RS384, RS512, PS256, PS384,
+// PS512, ES256, ES384, ES512 or EdDSA are supported.
+type Authenticator struct {
+	// Issuer is the required iss claim, empty to not check it
+	Issuer string
+	// Audience is the required aud claim, empty to not check it
+	Audience string
+	// PrincipalClaim names the principal
+	PrincipalClaim string
+	// ScopesClaim holds the scopes
+	ScopesClaim string
+	// Leeway is the clock skew tolerated checking exp and nbf
+	Leeway time.Duration
+
+	keys []key
+}
+
+func (a *Authenticator) Authenticate(r *http.Request) (*auth.Principal, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil, auth.ErrNoCredentials
+	}
+
+	claims, err := a.verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", auth.ErrInvalidCredentials, err)
+	}
+
+	now := time.Now()
+	if exp, ok := claims["exp"].(float64); ok && now.After(unixTime(exp).Add(a.Leeway)) {
+		return nil, auth.ErrExpiredCredentials
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(a.Leeway).Before(unixTime(nbf)) {
+		return nil, fmt.Errorf("%w: token not valid yet", auth.ErrInvalidCredentials)
+	}
+	if a.Issuer != "" && claims["iss"] != a.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer", auth.ErrInvalidCredentials)
+	}
+	if a.Audience != "" && !slices.Contains(stringsClaim(claims["aud"], false), a.Audience) {
+		return nil, fmt.Errorf("%w: unexpected audience", auth.ErrInvalidCredentials)
+	}
+
+	p := auth.Principal{
+		Scopes: stringsClaim(claims[a.ScopesClaim], true),
+	}
+	p.Name, _ = claims[a.PrincipalClaim].(string)
+	return &p, nil
+}
+
+// verify checks the signature of the token and returns its claims
+func (a *Authenticator) verify(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature: %w", err)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, k := range a.keys {
+		if (header.Kid != "" && k.id != header.Kid) || (k.alg != "" && k.alg != header.Alg) {
+			continue
+		}
+		if verifySignature(header.Alg, k.pub, signed, sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("invalid signature (alg %v)", header.Alg)
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed claims: %w", err)
+	}
+	return claims, nil
+}
+
+// verifySignature reports if sig is the signature of signed with the
+// algorithm alg by the key pub. The algorithm needs to match the type of key.
+func verifySignature(alg string, pub crypto.PublicKey, signed, sig []byte) bool {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "PS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "PS512", "ES512":
+		hash = crypto.SHA512
+	case "EdDSA":
+		pub, ok := pub.(ed25519.PublicKey)
+		return ok && ed25519.Verify(pub, signed, sig)
+	default:
+		return false
+	}
+
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "RS":
+		pub, ok := pub.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(pub, hash, digest, sig) == nil
+	case "PS":
+		pub, ok := pub.(*rsa.PublicKey)
+		return ok && rsa.VerifyPSS(pub, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+	case "ES":
+		pub, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return false
+		}
+		// the curve of the key needs to match the algorithm
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if (alg == "ES256") != (size == 32) || (alg == "ES384") != (size == 48) || (alg == "ES512") != (size == 66) {
+			return false
+		}
+		if len(sig) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		return ecdsa.Verify(pub, digest, r, s)
+	}
+	return false
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func unixTime(secs float64) time.Time {
+	return time.Unix(int64(secs), 0)
+}
+
+// stringsClaim returns the strings of a claim which is a string or an array
+// of strings. Strings are split on spaces when split is set.
+func stringsClaim(claim any, split bool) []string {
+	switch v := claim.(type) {
+	case string:
+		if split {
+			return strings.Fields(v)
+		}
+		return []string{v}
+	case []any:
+		s := make([]string, 0, len(v))
+		for _, e := range v {
+			if str, ok := e.(string); ok {
+				s = append(s, str)
+			}
+		}
+		return s
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package jwt_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/auth"
+	"github.com/go-spatial/tegola/auth/jwt"
+	"github.com/go-spatial/tegola/dict"
+)
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+// token returns a JWT with the claims signed by sign
+func token(t *testing.T, alg, kid string, claims map[string]any, sign func(signed []byte) []byte) string {
+	t.Helper()
+
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := b64(header) + "." + b64(payload)
+	return signed + "." + b64(sign([]byte(signed)))
+}
+
+func TestAuthenticate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks, err := json.Marshal(map[string]any{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": "rsa",
+				"use": "sig",
+				"n":   b64(rsaKey.N.Bytes()),
+				"e":   b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "EC",
+				"kid": "ec",
+				"crv": "P-256",
+				"x":   b64(ecKey.X.FillBytes(make([]byte, 32))),
+				"y":   b64(ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err = os.WriteFile(jwksFile, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := jwt.New(dict.Dict{
+		"jwks_file": jwksFile,
+		"issuer":    "https://auth.example.com",
+		"audience":  "tegola",
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	signRS256 := func(key *rsa.PrivateKey) func([]byte) []byte {
+		return func(signed []byte) []byte {
+			digest := sha256.Sum256(signed)
+			sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			return sig
+		}
+	}
+	signES256 := func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+
+	now := time.Now().Unix()
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"sub":   "acme",
+			"iss":   "https://auth.example.com",
+			"aud":   []string{"tegola", "other"},
+			"exp":   now + 60,
+			"scope": "read write",
+		}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+
+	type tcase struct {
+		authorization string
+		expected      *auth.Principal
+		expectedErr   error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/capabilities", nil)
+			if tc.authorization != "" {
+				r.Header.Set("Authorization", tc.authorization)
+			}
+
+			p, err := a.Authenticate(r)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("invalid error. expected: %v, got %v", tc.expectedErr, err)
+				return
+			}
+			if !reflect.DeepEqual(p, tc.expected) {
+				t.Errorf("principal, expected %+v got %+v", tc.expected, p)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"RS256": {
+			authorization: "Bearer " + token(t, "RS256", "rsa", claims(nil), signRS256(rsaKey)),
+			expected:      &auth.Principal{Name: "acme", Scopes: []string{"read", "write"}},
+		},
+		"ES256 scopes array": {
+			authorization: "Bearer " + token(t, "ES256", "ec", claims(map[string]any{"scope": []string{"read"}}), signES256),
+			expected:      &auth.Principal{Name: "acme", Scopes: []string{"read"}},
+		},
+		"no token": {
+			expectedErr: auth.ErrNoCredentials,
+		},
+		"basic auth": {
+			authorization: "Basic YWNtZTpzZWNyZXQ=",
+			expectedErr:   auth.ErrNoCredentials,
+		},
+		"unknown key": {
+			authorization: "Bearer " + token(t, "RS256", "rsa", claims(nil), signRS256(otherKey)),
+			expectedErr:   auth.ErrInvalidCredentials,
+		},
+		"alg of other key type": {
+			authorization: "Bearer " + token(t, "ES256", "rsa", claims(nil), signRS256(rsaKey)),
+			expectedErr:   auth.ErrInvalidCredentials,
+		},
+		"alg none": {
+			authorization: "Bearer " + token(t, "none", "", claims(nil), func([]byte) []byte { return nil }),
+			expectedErr:   auth.ErrInvalidCredentials,
+		},
+		"expired": {
+			authorization: "Bearer " + token(t, "RS256", "rsa", claims(map[string]any{"exp": now - 120}), signRS256(rsaKey)),
+			expectedErr:   auth.ErrExpiredCredentials,
+		},
+		"expired within leeway": {
+			authorization: "Bearer " + token(t, "RS256", "rsa", claims(map[string]any{"exp": now - 30}), signRS256(rsaKey)),
+			expected:      &auth.Principal{Name: "acme", Scopes: []string{"read", "write"}},
+		},
+		"not valid yet": {
+			authorization: "Bearer " + token(t, "RS256", "rsa", claims(map[string]any{"nbf": now + 120}), signRS256(rsaKey)),
+			expectedErr:   auth.ErrInvalidCredentials,
+		},
+		"other issuer": {
+			authorization: "Bearer " + token(t, "RS256", "rsa", claims(map[string]any{"iss": "https://evil.example.com"}), signRS256(rsaKey)),
+			expectedErr:   auth.ErrInvalidCredentials,
+		},
+		"other audience": {
+			authorization: "Bearer " + token(t, "RS256", "rsa", claims(map[string]any{"aud": "other"}), signRS256(rsaKey)),
+			expectedErr:   auth.ErrInvalidCredentials,
+		},
+		"malformed": {
+			authorization: "Bearer not-a-token",
+			expectedErr:   auth.ErrInvalidCredentials,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := jwt.New(dict.Dict{}); err != jwt.ErrMissingJWKSFile {
+		t.Errorf("invalid error. expected: %v, got %v", jwt.ErrMissingJWKSFile, err)
+	}
+
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksFile, []byte(`{"keys":[{"kty":"RSA","use":"enc","n":"AQAB","e":"AQAB"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.New(dict.Dict{"jwks_file": jwksFile}); err != jwt.ErrNoKeys {
+		t.Errorf("invalid error. expected: %v, got %v", jwt.ErrNoKeys, err)
+	}
+}
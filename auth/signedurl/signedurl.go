@@ -0,0 +1,142 @@
+// Package signedurl authenticates requests by URLs signed with a shared
+// secret (HMAC-SHA256) which expire
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-spatial/tegola/auth"
+	"github.com/go-spatial/tegola/dict"
+)
+
+const AuthType = "signed_url"
+
+const (
+	ConfigKeySecret = "secret"
+	ConfigKeyMaxTTL = "max_ttl"
+)
+
+// the query params of signed URLs
+const (
+	QueryParamPrincipal = "principal"
+	QueryParamScopes    = "scopes"
+	QueryParamExpires   = "expires"
+	QueryParamSignature = "signature"
+)
+
+var ErrMissingSecret = errors.New("signed_url auth: missing required param 'secret'")
+
+var defaultMaxTTL = 0
+
+func init() {
+	auth.Register(AuthType, New) //nolint:errcheck
+}
+
+// New instantiates a signed URL authenticator. The config expects the following params:
+//
+//	secret (string): the secret the URLs are signed with
+//	max_ttl (int): the max seconds a URL can be signed for, if not set defaults to 0 = no limit
+func New(config dict.Dicter) (auth.Authenticator, error) {
+	secret, err := config.String(ConfigKeySecret, nil)
+	if err != nil || secret == "" {
+		return nil, ErrMissingSecret
+	}
+
+	maxTTL, err := config.Int(ConfigKeyMaxTTL, &defaultMaxTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Authenticator{
+		Secret: []byte(secret),
+		MaxTTL: time.Duration(maxTTL) * time.Second,
+	}, nil
+}
+
+// Authenticator authenticates requests by the principal, scopes and expiry
+// of their query params. The signature query param signs the path and the
+// query of the URL, so the URL can't be changed to request other tiles.
+type Authenticator struct {
+	// Secret the URLs are signed with
+	Secret []byte
+	// MaxTTL rejects URLs expiring further in the future, 0 is no limit
+	MaxTTL time.Duration
+}
+
+func (a *Authenticator) Authenticate(r *http.Request) (*auth.Principal, error) {
+	query := r.URL.Query()
+	signature := query.Get(QueryParamSignature)
+	if signature == "" {
+		return nil, auth.ErrNoCredentials
+	}
+
+	principal, scopes, expires := query.Get(QueryParamPrincipal), query.Get(QueryParamScopes), query.Get(QueryParamExpires)
+
+	sig, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, sign(a.Secret, r.URL.EscapedPath(), query)) {
+		return nil, auth.ErrInvalidCredentials
+	}
+
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return nil, auth.ErrInvalidCredentials
+	}
+	expiresAt := time.Unix(exp, 0)
+	if time.Now().After(expiresAt) {
+		return nil, auth.ErrExpiredCredentials
+	}
+	if a.MaxTTL > 0 && time.Until(expiresAt) > a.MaxTTL {
+		return nil, auth.ErrInvalidCredentials
+	}
+
+	p := auth.Principal{Name: principal}
+	if scopes != "" {
+		p.Scopes = strings.Split(scopes, ",")
+	}
+	return &p, nil
+}
+
+func (a *Authenticator) QueryParams() []string {
+	return []string{QueryParamPrincipal, QueryParamScopes, QueryParamExpires, QueryParamSignature}
+}
+
+// Sign returns a copy of the URL u signed for the principal with the scopes
+// until expires. The signature covers the path and the query of u, which
+// can't be changed once signed.
+func Sign(secret []byte, u *url.URL, principal string, scopes []string, expires time.Time) *url.URL {
+	query := u.Query()
+	query.Set(QueryParamPrincipal, principal)
+	query.Del(QueryParamScopes)
+	if len(scopes) > 0 {
+		query.Set(QueryParamScopes, strings.Join(scopes, ","))
+	}
+	query.Set(QueryParamExpires, strconv.FormatInt(expires.Unix(), 10))
+	query.Set(QueryParamSignature, hex.EncodeToString(sign(secret, u.EscapedPath(), query)))
+
+	signed := *u
+	signed.RawQuery = query.Encode()
+	return &signed
+}
+
+// sign returns the HMAC-SHA256 of the path and of the canonical query, its
+// params sorted by name without the signature, each on a line of their own
+func sign(secret []byte, path string, query url.Values) []byte {
+	q := make(url.Values, len(query))
+	for name, values := range query {
+		if name != QueryParamSignature {
+			q[name] = values
+		}
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(path + "\n" + q.Encode()))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,132 @@
+package signedurl_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/auth"
+	"github.com/go-spatial/tegola/auth/signedurl"
+	"github.com/go-spatial/tegola/dict"
+)
+
+func TestNew(t *testing.T) {
+	if _, err := signedurl.New(dict.Dict{}); err != signedurl.ErrMissingSecret {
+		t.Errorf("invalid error. expected: %v, got %v", signedurl.ErrMissingSecret, err)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	secret := []byte("secret")
+	a, err := signedurl.New(dict.Dict{
+		"secret":  string(secret),
+		"max_ttl": 3600,
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	now := time.Now()
+
+	type tcase struct {
+		url         *url.URL
+		expected    *auth.Principal
+		expectedErr error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			r := httptest.NewRequest("GET", tc.url.String(), nil)
+
+			p, err := a.Authenticate(r)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("invalid error. expected: %v, got %v", tc.expectedErr, err)
+				return
+			}
+			if !reflect.DeepEqual(p, tc.expected) {
+				t.Errorf("principal, expected %+v got %+v", tc.expected, p)
+			}
+		}
+	}
+
+	tile, _ := url.Parse("/maps/osm/1/2/3.pbf?year=2021")
+	sign := func(secret []byte, scopes []string, expires time.Time) *url.URL {
+		return signedurl.Sign(secret, tile, "acme", scopes, expires)
+	}
+	// tamper returns the signed URL changed by fn
+	tamper := func(fn func(u *url.URL, query url.Values)) *url.URL {
+		u := sign(secret, []string{"read"}, now.Add(time.Minute))
+		query := u.Query()
+		fn(u, query)
+		u.RawQuery = query.Encode()
+		return u
+	}
+
+	// the params of the signed URL in another order, year first
+	reordered := sign(secret, []string{"read"}, now.Add(time.Minute))
+	query := reordered.Query()
+	query.Del("year")
+	reordered.RawQuery = "year=2021&" + query.Encode()
+
+	tests := map[string]tcase{
+		"valid": {
+			url:      sign(secret, []string{"read", "write"}, now.Add(time.Minute)),
+			expected: &auth.Principal{Name: "acme", Scopes: []string{"read", "write"}},
+		},
+		"no scopes": {
+			url:      sign(secret, nil, now.Add(time.Minute)),
+			expected: &auth.Principal{Name: "acme"},
+		},
+		"unsigned": {
+			url:         &url.URL{Path: "/maps/osm/1/2/3.pbf", RawQuery: "principal=acme"},
+			expectedErr: auth.ErrNoCredentials,
+		},
+		"expired": {
+			url:         sign(secret, nil, now.Add(-time.Minute)),
+			expectedErr: auth.ErrExpiredCredentials,
+		},
+		"beyond max ttl": {
+			url:         sign(secret, nil, now.Add(2*time.Hour)),
+			expectedErr: auth.ErrInvalidCredentials,
+		},
+		"other secret": {
+			url:         sign([]byte("other"), nil, now.Add(time.Minute)),
+			expectedErr: auth.ErrInvalidCredentials,
+		},
+		"tampered scopes": {
+			url: tamper(func(_ *url.URL, query url.Values) {
+				query.Set(signedurl.QueryParamScopes, "read,admin")
+			}),
+			expectedErr: auth.ErrInvalidCredentials,
+		},
+		"tampered path": {
+			url: tamper(func(u *url.URL, _ url.Values) {
+				u.Path = "/maps/osm/1/2/4.pbf"
+			}),
+			expectedErr: auth.ErrInvalidCredentials,
+		},
+		"tampered param": {
+			url: tamper(func(_ *url.URL, query url.Values) {
+				query.Set("year", "2022")
+			}),
+			expectedErr: auth.ErrInvalidCredentials,
+		},
+		"added param": {
+			url: tamper(func(_ *url.URL, query url.Values) {
+				query.Set("debug", "true")
+			}),
+			expectedErr: auth.ErrInvalidCredentials,
+		},
+		"params reordered": {
+			url:      reordered,
+			expected: &auth.Principal{Name: "acme", Scopes: []string{"read"}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
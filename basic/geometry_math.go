@@ -7,6 +7,7 @@ import (
 	"errors"
 
 	"github.com/go-spatial/geom"
+	"github.com/go-spatial/proj"
 	"github.com/go-spatial/tegola"
 	"github.com/go-spatial/tegola/maths/webmercator"
 )
@@ -232,6 +233,40 @@ func FromWebMercator(SRID uint64, geometry geom.Geometry) (geom.Geometry, error)
 	}
 }
 
+// Reproject takes a geometry encoded using the src SRID and returns the geometry encoded using the dst SRID.
+// Conversions between WGS84 and WebMercator use ToWebMercator and FromWebMercator, other SRIDs
+// need to be known to the proj package.
+func Reproject(src, dst uint64, geometry geom.Geometry) (geom.Geometry, error) {
+	switch {
+	case src == dst:
+		return CloneGeometry(geometry)
+	case dst == tegola.WebMercator && src == tegola.WGS84:
+		return ToWebMercator(src, geometry)
+	case src == tegola.WebMercator && dst == tegola.WGS84:
+		return FromWebMercator(dst, geometry)
+	}
+
+	for _, srid := range []uint64{src, dst} {
+		if srid != tegola.WGS84 && !proj.IsKnownConversionSRID(proj.EPSGCode(srid)) {
+			return nil, fmt.Errorf("don't know how to convert from %v to %v.", src, dst)
+		}
+	}
+
+	return ApplyToPoints(geometry, func(coords ...float64) ([]float64, error) {
+		var err error
+		lonlat := coords[:2]
+		if src != tegola.WGS84 {
+			if lonlat, err = proj.Inverse(proj.EPSGCode(src), lonlat); err != nil {
+				return nil, err
+			}
+		}
+		if dst == tegola.WGS84 {
+			return lonlat, nil
+		}
+		return proj.Convert(proj.EPSGCode(dst), lonlat)
+	})
+}
+
 func interfaceAsFloatslice(v interface{}) (vals []float64, err error) {
 	vs, ok := v.([]interface{})
 	if !ok {
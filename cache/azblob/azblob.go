@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"strings"
 
 	"github.com/Azure/azure-storage-blob-go/2017-07-29/azblob"
 
@@ -263,3 +264,53 @@ func (azb *Cache) makeBlob(key *cache.Key) azblob.BlobURL {
 
 	return azb.Container.NewBlobURL(k)
 }
+
+// PurgePrefix deletes the tiles of the map, or of the layer of the map,
+// between minZoom and maxZoom.
+func (azb *Cache) PurgePrefix(ctx context.Context, mapName, layerName string, minZoom, maxZoom uint) error {
+	return azb.purgeMatching(ctx, mapName, func(key *cache.Key) bool {
+		return key.MatchesPrefix(mapName, layerName, minZoom, maxZoom)
+	})
+}
+
+// PurgeVersions deletes the tiles of the map which are not of keepVersion.
+func (azb *Cache) PurgeVersions(ctx context.Context, mapName, keepVersion string) error {
+	return azb.purgeMatching(ctx, mapName, func(key *cache.Key) bool {
+		return key.Version != keepVersion
+	})
+}
+
+// purgeMatching lists the blobs under the prefix of the map and deletes the
+// tiles matching match.
+func (azb *Cache) purgeMatching(ctx context.Context, mapName string, match func(key *cache.Key) bool) error {
+	if azb.ReadOnly {
+		return nil
+	}
+
+	opts := azblob.ListBlobsSegmentOptions{
+		Prefix: filepath.Join(azb.Basepath, mapName) + "/",
+	}
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		res, err := azb.Container.ListBlobsFlatSegment(ctx, marker, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, blob := range res.Blobs.Blob {
+			key, err := cache.ParseKey(strings.TrimPrefix(blob.Name, azb.Basepath))
+			if err != nil || !match(key) {
+				continue
+			}
+
+			_, err = azb.Container.NewBlobURL(blob.Name).
+				Delete(ctx, azblob.DeleteSnapshotsOptionNone, azblob.BlobAccessConditions{})
+			if err != nil {
+				return err
+			}
+		}
+
+		marker = res.NextMarker
+	}
+
+	return nil
+}
@@ -2,16 +2,22 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-spatial/tegola"
 	"github.com/go-spatial/tegola/dict"
-	"github.com/go-spatial/tegola/maths"
+	"github.com/go-spatial/tegola/grid"
 )
 
 // Interface defines a cache back end
@@ -27,7 +33,114 @@ type Wrapped interface {
 	Original() Interface
 }
 
+// TileInfo describes a cached tile
+type TileInfo struct {
+	// Modified is when the tile was cached, zero if unknown
+	Modified time.Time
+	// Expires is when the tile expires, zero if it does not expire
+	Expires time.Time
+	// Stale is set for tiles which expired but are still served while
+	// they are regenerated
+	Stale bool
+}
+
+// InfoGetter is implemented by cache backends which know when their tiles
+// were cached and when they expire.
+type InfoGetter interface {
+	// GetInfo reads the tile of the key along with its info. Backends
+	// configured to serve stale tiles return the tiles which expired within
+	// their stale while revalidate period as hits marked Stale, where Get
+	// reports them as misses.
+	GetInfo(ctx context.Context, key *Key) (val []byte, info TileInfo, hit bool, err error)
+}
+
+// GetInfo reads the tile of the key from the cache c along with its info. For
+// caches which do not implement InfoGetter the info is empty.
+func GetInfo(ctx context.Context, c Interface, key *Key) ([]byte, TileInfo, bool, error) {
+	if ig, ok := c.(InfoGetter); ok {
+		return ig.GetInfo(ctx, key)
+	}
+
+	val, hit, err := c.Get(ctx, key)
+	return val, TileInfo{}, hit, err
+}
+
+// EvictionNotifier is implemented by cache backends which evict tiles on their
+// own (i.e. to stay within a size limit). fn is called with the key of each
+// evicted tile and must not block.
+type EvictionNotifier interface {
+	NotifyEviction(fn func(key *Key))
+}
+
+// PrefixPurger is implemented by cache backends which can remove the tiles of
+// a map, or of a layer of a map, at once instead of tile by tile.
+type PrefixPurger interface {
+	// PurgePrefix removes the tiles of the map with zooms between minZoom and
+	// maxZoom inclusive, for all the versions and query parameters of the map. An empty
+	// layerName removes the tiles of the map and of all its layers. When
+	// layerName is set, the tiles of the other layers are kept but the tiles
+	// of the map are removed too, as they include the layer.
+	PurgePrefix(ctx context.Context, mapName, layerName string, minZoom, maxZoom uint) error
+}
+
+// ErrPurgePrefixNotSupported is returned when purging by prefix a cache
+// backend which does not implement PrefixPurger
+var ErrPurgePrefixNotSupported = errors.New("cache: the cache backend does not support purging by prefix")
+
+// PurgePrefix removes the tiles of the map, or of the layer of the map, with
+// zooms between minZoom and maxZoom from the cache c. ErrPurgePrefixNotSupported
+// is returned if c does not implement PrefixPurger.
+func PurgePrefix(ctx context.Context, c Interface, mapName, layerName string, minZoom, maxZoom uint) error {
+	p, ok := c.(PrefixPurger)
+	if !ok {
+		return ErrPurgePrefixNotSupported
+	}
+	return p.PurgePrefix(ctx, mapName, layerName, minZoom, maxZoom)
+}
+
+// VersionPurger is implemented by cache backends which can remove the tiles of
+// the versions of a map other than the version in use.
+type VersionPurger interface {
+	// PurgeVersions removes the tiles of the map which are not of the version
+	// keepVersion. An empty keepVersion keeps the tiles without a version.
+	PurgeVersions(ctx context.Context, mapName, keepVersion string) error
+}
+
+// ErrPurgeVersionsNotSupported is returned when purging the versions of a
+// map from a cache backend which does not implement VersionPurger
+var ErrPurgeVersionsNotSupported = errors.New("cache: the cache backend does not support purging versions")
+
+// PurgeVersions removes the tiles of the map which are not of the version
+// keepVersion from the cache c. ErrPurgeVersionsNotSupported is returned if c
+// does not implement VersionPurger.
+func PurgeVersions(ctx context.Context, c Interface, mapName, keepVersion string) error {
+	p, ok := c.(VersionPurger)
+	if !ok {
+		return ErrPurgeVersionsNotSupported
+	}
+	return p.PurgeVersions(ctx, mapName, keepVersion)
+}
+
+// validVersion matches the versions which can be used as a path element
+var validVersion = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]*$`)
+
+// IsValidVersion reports if version can be used as the version of the tiles
+// of a map. Versions are made of letters, digits, '.', '_' and '-' and don't
+// start with a '.' or a '-'.
+func IsValidVersion(version string) bool {
+	return validVersion.MatchString(version)
+}
+
+// IsValidLayerName reports if name can be used as the name of a layer in the
+// keys of the tiles. Names starting with '~' or '@' would be read back as the
+// version or the query parameters element of the key.
+func IsValidLayerName(name string) bool {
+	return !strings.HasPrefix(name, "~") && !strings.HasPrefix(name, "@")
+}
+
 // ParseKey will parse a string in the format /:map/:layer/:z/:x/:y into a Key struct. The :layer value is optional
+// The :map value can be followed by the ~:version element of the tiles and the @:params_hash element of
+// the query parameters of the map
 // ParseKey also supports other OS delimiters (i.e. Windows - "\")
 func ParseKey(str string) (*Key, error) {
 	var err error
@@ -40,6 +153,16 @@ func ParseKey(str string) (*Key, error) {
 	// remove the base-path and the first slash, then split the parts
 	keyParts := strings.Split(strings.TrimLeft(str, "/"), "/")
 
+	// the version and the query parameters of the map follow the map name
+	if len(keyParts) > 2 && strings.HasPrefix(keyParts[1], "~") {
+		key.Version = strings.TrimPrefix(keyParts[1], "~")
+		keyParts = append(keyParts[:1], keyParts[2:]...)
+	}
+	if len(keyParts) > 2 && strings.HasPrefix(keyParts[1], "@") {
+		key.ParamsHash = strings.TrimPrefix(keyParts[1], "@")
+		keyParts = append(keyParts[:1], keyParts[2:]...)
+	}
+
 	// we're expecting a z/x/y scheme
 	if len(keyParts) < 3 || len(keyParts) > 5 {
 		err = ErrInvalidFileKeyParts{
@@ -80,10 +203,13 @@ func ParseKey(str string) (*Key, error) {
 	}
 
 	key.Z = uint(placeholder)
-	maxXYatZ := maths.Exp2(placeholder) - 1
+	// the number of columns and rows depends on the tile grid of the map,
+	// which the key doesn't know about. x and y are checked against the
+	// largest grid.
+	maxX, maxY := grid.MaxMatrixSize(key.Z)
 
 	placeholder, err = strconv.ParseUint(zxy[1], 10, 32)
-	if err != nil || placeholder > maxXYatZ {
+	if err != nil || placeholder >= uint64(maxX) {
 		err = ErrInvalidFileKey{
 			path: str,
 			key:  "X",
@@ -99,7 +225,7 @@ func ParseKey(str string) (*Key, error) {
 	// trim the extension if it exists
 	yParts := strings.Split(zxy[2], ".")
 	placeholder, err = strconv.ParseUint(yParts[0], 10, 64)
-	if err != nil || placeholder > maxXYatZ {
+	if err != nil || placeholder >= uint64(maxY) {
 		err = ErrInvalidFileKey{
 			path: str,
 			key:  "Y",
@@ -117,20 +243,54 @@ func ParseKey(str string) (*Key, error) {
 type Key struct {
 	MapName   string
 	LayerName string
-	Z         uint
-	X         uint
-	Y         uint
+	// Version is the version of the tiles of the map, empty for maps
+	// without a cache version
+	Version string
+	// ParamsHash is the canonical hash of the query parameters of the map
+	// the tile was generated with, empty for maps without parameters
+	ParamsHash string
+	Z          uint
+	X          uint
+	Y          uint
 }
 
 func (k Key) String() string {
 	return filepath.Join(
 		k.MapName,
+		k.VersionDir(),
+		k.ParamsDir(),
 		k.LayerName,
 		strconv.FormatUint(uint64(k.Z), 10),
 		strconv.FormatUint(uint64(k.X), 10),
 		strconv.FormatUint(uint64(k.Y), 10))
 }
 
+// MatchesPrefix reports if the tile of the key is one of the tiles removed by
+// PurgePrefix called with the same arguments
+func (k Key) MatchesPrefix(mapName, layerName string, minZoom, maxZoom uint) bool {
+	return k.MapName == mapName &&
+		(layerName == "" || k.LayerName == "" || k.LayerName == layerName) &&
+		minZoom <= k.Z && k.Z <= maxZoom
+}
+
+// VersionDir returns the path element holding the tiles of the version of the
+// key, empty if the key has no version
+func (k Key) VersionDir() string {
+	if k.Version == "" {
+		return ""
+	}
+	return "~" + k.Version
+}
+
+// ParamsDir returns the path element holding the tiles of the query
+// parameters of the key, empty if the key has no parameters
+func (k Key) ParamsDir() string {
+	if k.ParamsHash == "" {
+		return ""
+	}
+	return "@" + k.ParamsHash
+}
+
 // InitFunc initialize a cache given a config map.
 // The InitFunc should validate the config map, and report any errors.
 // This is called by the For function.
@@ -138,6 +298,13 @@ type InitFunc func(dict.Dicter) (Interface, error)
 
 var cache map[string]InitFunc
 
+var (
+	// instances are the caches created by For. they are tracked so
+	// they can be closed by Cleanup
+	instances     []Interface
+	instancesLock sync.Mutex
+)
+
 // Register is called by the init functions of the cache.
 func Register(cacheType string, init InitFunc) error {
 	if cache == nil {
@@ -173,5 +340,56 @@ func For(cacheType string, config dict.Dicter) (Interface, error) {
 		return nil, fmt.Errorf("No cache backends registered by the cache type: (%v)", cacheType)
 	}
 
-	return c(config)
+	ci, err := c(config)
+	if err != nil {
+		return nil, err
+	}
+
+	instancesLock.Lock()
+	instances = append(instances, ci)
+	instancesLock.Unlock()
+
+	return ci, nil
+}
+
+// Close closes a cache created by For which is no longer used, like the
+// cache of a config which has been reloaded. Wrapped caches are unwrapped.
+// Caches that implement io.Closer will be closed, once: the caches already
+// closed, or being closed by Cleanup, are skipped.
+func Close(c Interface) error {
+	if w, ok := c.(Wrapped); ok {
+		c = w.Original()
+	}
+
+	instancesLock.Lock()
+	i := slices.Index(instances, c)
+	if i >= 0 {
+		instances = slices.Delete(instances, i, i+1)
+	}
+	instancesLock.Unlock()
+
+	closer, ok := c.(io.Closer)
+	if i < 0 || !ok {
+		return nil
+	}
+	return closer.Close()
+}
+
+// Cleanup is called at the end of the run to allow caches to clean up.
+// Caches that implement io.Closer will be closed.
+func Cleanup() {
+	instancesLock.Lock()
+	closing := instances
+	instances = nil
+	instancesLock.Unlock()
+
+	for _, c := range closing {
+		closer, ok := c.(io.Closer)
+		if !ok {
+			continue
+		}
+		if err := closer.Close(); err != nil {
+			log.Printf("cache: error cleaning up cache: %v", err)
+		}
+	}
 }
@@ -1,6 +1,7 @@
 package cache_test
 
 import (
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -39,6 +40,59 @@ func TestParseKey(t *testing.T) {
 				LayerName: "buildings",
 			},
 		},
+		{
+			input: "/osm/@0123abcd/12/11/123",
+			expected: &cache.Key{
+				Z:          12,
+				X:          11,
+				Y:          123,
+				MapName:    "osm",
+				ParamsHash: "0123abcd",
+			},
+		},
+		{
+			input: "/osm/@0123abcd/buildings/12/11/123",
+			expected: &cache.Key{
+				Z:          12,
+				X:          11,
+				Y:          123,
+				MapName:    "osm",
+				LayerName:  "buildings",
+				ParamsHash: "0123abcd",
+			},
+		},
+		{
+			input: "/osm/~2024-05/@0123abcd/buildings/12/11/123",
+			expected: &cache.Key{
+				Z:          12,
+				X:          11,
+				Y:          123,
+				MapName:    "osm",
+				LayerName:  "buildings",
+				Version:    "2024-05",
+				ParamsHash: "0123abcd",
+			},
+		},
+		{
+			input: "/osm/~2024-05/12/11/123",
+			expected: &cache.Key{
+				Z:       12,
+				X:       11,
+				Y:       123,
+				MapName: "osm",
+				Version: "2024-05",
+			},
+		},
+		{
+			// the second column of zoom 0 of WorldCRS84Quad
+			input: "/osm/0/1/0",
+			expected: &cache.Key{
+				Z:       0,
+				X:       1,
+				Y:       0,
+				MapName: "osm",
+			},
+		},
 	}
 
 	for i, tc := range testcases {
@@ -54,3 +108,178 @@ func TestParseKey(t *testing.T) {
 		}
 	}
 }
+
+func TestParseKeyInvalid(t *testing.T) {
+	tests := map[string]string{
+		"zoom above max":     "/osm/23/0/0",
+		"x out of range":     "/osm/1/4/0",
+		"y out of range":     "/osm/1/0/2",
+		"y out of range ext": "/osm/1/0/2.pbf",
+		"x not a number":     "/osm/1/a/0",
+		"too many parts":     "/osm/a/b/c/1/0/0",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if key, err := cache.ParseKey(input); err == nil {
+				t.Errorf("expected an error, got key %+v", key)
+			}
+		})
+	}
+}
+
+func TestKeyString(t *testing.T) {
+	tests := map[string]struct {
+		key      cache.Key
+		expected string
+	}{
+		"map": {
+			key:      cache.Key{MapName: "osm", Z: 1, X: 2, Y: 3},
+			expected: filepath.Join("osm", "1", "2", "3"),
+		},
+		"map layer": {
+			key:      cache.Key{MapName: "osm", LayerName: "roads", Z: 1, X: 2, Y: 3},
+			expected: filepath.Join("osm", "roads", "1", "2", "3"),
+		},
+		"map layer params": {
+			key:      cache.Key{MapName: "osm", LayerName: "roads", ParamsHash: "0123abcd", Z: 1, X: 2, Y: 3},
+			expected: filepath.Join("osm", "@0123abcd", "roads", "1", "2", "3"),
+		},
+		"map version params": {
+			key:      cache.Key{MapName: "osm", Version: "v2", ParamsHash: "0123abcd", Z: 1, X: 2, Y: 3},
+			expected: filepath.Join("osm", "~v2", "@0123abcd", "1", "2", "3"),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.key.String(); got != tc.expected {
+				t.Errorf("expected %v got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestKeyMatchesPrefix(t *testing.T) {
+	type tcase struct {
+		key       cache.Key
+		mapName   string
+		layerName string
+		minZoom   uint
+		maxZoom   uint
+		expected  bool
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			if got := tc.key.MatchesPrefix(tc.mapName, tc.layerName, tc.minZoom, tc.maxZoom); got != tc.expected {
+				t.Errorf("expected %v got %v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"map": {
+			key:      cache.Key{MapName: "osm", Z: 3},
+			mapName:  "osm",
+			maxZoom:  20,
+			expected: true,
+		},
+		"map layer and params": {
+			key:      cache.Key{MapName: "osm", LayerName: "roads", ParamsHash: "0123abcd", Z: 3},
+			mapName:  "osm",
+			maxZoom:  20,
+			expected: true,
+		},
+		"other map": {
+			key:     cache.Key{MapName: "osm2", Z: 3},
+			mapName: "osm",
+			maxZoom: 20,
+		},
+		"layer": {
+			key:       cache.Key{MapName: "osm", LayerName: "roads", Z: 3},
+			mapName:   "osm",
+			layerName: "roads",
+			maxZoom:   20,
+			expected:  true,
+		},
+		"map tile of layer": {
+			key:       cache.Key{MapName: "osm", Z: 3},
+			mapName:   "osm",
+			layerName: "roads",
+			maxZoom:   20,
+			expected:  true,
+		},
+		"other layer": {
+			key:       cache.Key{MapName: "osm", LayerName: "water", Z: 3},
+			mapName:   "osm",
+			layerName: "roads",
+			maxZoom:   20,
+		},
+		"zoom in range": {
+			key:      cache.Key{MapName: "osm", Z: 5},
+			mapName:  "osm",
+			minZoom:  5,
+			maxZoom:  5,
+			expected: true,
+		},
+		"zoom below range": {
+			key:     cache.Key{MapName: "osm", Z: 4},
+			mapName: "osm",
+			minZoom: 5,
+			maxZoom: 10,
+		},
+		"zoom above range": {
+			key:     cache.Key{MapName: "osm", Z: 11},
+			mapName: "osm",
+			minZoom: 5,
+			maxZoom: 10,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestIsValidVersion(t *testing.T) {
+	tests := map[string]bool{
+		"2024-05":  true,
+		"v1.2.3":   true,
+		"_next":    true,
+		"":         false,
+		".":        false,
+		"..":       false,
+		"-v1":      false,
+		"v1/v2":    false,
+		`v1\v2`:    false,
+		"v1 v2":    false,
+		"@0123abc": false,
+	}
+
+	for version, expected := range tests {
+		t.Run(version, func(t *testing.T) {
+			if got := cache.IsValidVersion(version); got != expected {
+				t.Errorf("expected %v got %v", expected, got)
+			}
+		})
+	}
+}
+
+func TestIsValidLayerName(t *testing.T) {
+	tests := map[string]bool{
+		"roads":     true,
+		"roads~v2":  true,
+		"roads@abc": true,
+		"~roads":    false,
+		"@roads":    false,
+	}
+
+	for name, expected := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := cache.IsValidLayerName(name); got != expected {
+				t.Errorf("expected %v got %v", expected, got)
+			}
+		})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-spatial/tegola"
@@ -21,9 +23,13 @@ const (
 	ConfigKeyBasepath = "basepath"
 	ConfigKeyMaxZoom  = "max_zoom"
 	ConfigKeyTTL      = "ttl"
+	ConfigKeySWR      = "stale_while_revalidate"
 )
 
-var defaultTTL = 0
+var (
+	defaultTTL = 0
+	defaultSWR = 0
+)
 
 func init() {
 	cache.Register(CacheType, New) //nolint:errcheck
@@ -34,6 +40,7 @@ func init() {
 //	basepath (string): a path to where the cache will be written
 //	max_zoom (int): max zoom to use the cache. beyond this zoom cache Set() calls will be ignored
 //	ttl (int): lazy expiration ttl, if not set defaults to 0 = no ttl
+//	stale_while_revalidate (int): seconds expired tiles are still served while they are regenerated, defaults to 0
 func New(config dict.Dicter) (cache.Interface, error) {
 	var err error
 
@@ -57,6 +64,12 @@ func New(config dict.Dicter) (cache.Interface, error) {
 	}
 	fc.Expiration = time.Duration(ttl) * time.Second
 
+	swr, err := config.Int(ConfigKeySWR, &defaultSWR)
+	if err != nil {
+		return nil, err
+	}
+	fc.StaleWhileRevalidate = time.Duration(swr) * time.Second
+
 	if fc.Basepath == "" {
 		return nil, ErrMissingBasepath
 	}
@@ -78,6 +91,10 @@ type Cache struct {
 	MaxZoom uint
 	// time to live in seconds for cached tiles. Defaults to 0 (never expires). TTL is evaluated lazily on Get() operations - expired tiles are deleted when accessed but may remain on disk if never requested.
 	Expiration time.Duration
+	// StaleWhileRevalidate is how long tiles are still served by GetInfo
+	// once they expired, so they can be regenerated in the background.
+	// Expired tiles are only deleted past this period.
+	StaleWhileRevalidate time.Duration
 }
 
 func isExpired(mtime time.Time, ttl time.Duration) bool {
@@ -90,43 +107,61 @@ func isExpired(mtime time.Time, ttl time.Duration) bool {
 // if there is a hit. the second argument denotes a hit or miss
 // so the consumer does not need to sniff errors for cache read misses
 func (fc *Cache) Get(ctx context.Context, key *cache.Key) ([]byte, bool, error) {
+	val, info, hit, err := fc.GetInfo(ctx, key)
+	if err != nil || !hit || info.Stale {
+		return nil, false, err
+	}
+
+	return val, true, nil
+}
+
+// GetInfo reads a z,x,y entry from the cache along with the time it was
+// written at. Expired tiles are returned as stale hits during the
+// StaleWhileRevalidate period and deleted afterwards.
+func (fc *Cache) GetInfo(ctx context.Context, key *cache.Key) ([]byte, cache.TileInfo, bool, error) {
+	var info cache.TileInfo
+
 	path := filepath.Join(fc.Basepath, key.String())
 
 	f, err := os.Open(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, false, nil
+			return nil, info, false, nil
 		}
 
-		return nil, false, err
+		return nil, info, false, err
 	}
 	defer f.Close() //nolint:errcheck
 
-	if fc.Expiration > 0 { // don't bother with this path if there's no ttl
-		s, err := f.Stat()
-		if err != nil {
-			return nil, false, err
-		}
+	s, err := f.Stat()
+	if err != nil {
+		return nil, info, false, err
+	}
+	info.Modified = s.ModTime()
+
+	if fc.Expiration > 0 {
+		info.Expires = info.Modified.Add(fc.Expiration)
 
-		if isExpired(s.ModTime(), fc.Expiration) {
+		if isExpired(s.ModTime(), fc.Expiration+fc.StaleWhileRevalidate) {
 			pErr := fc.Purge(ctx, key)
 			if pErr != nil {
-				return nil, false, err
+				return nil, info, false, err
 			}
-			return nil, false, nil
+			return nil, cache.TileInfo{}, false, nil
 		}
+		info.Stale = isExpired(s.ModTime(), fc.Expiration)
 	}
 
 	if err := ctx.Err(); err != nil {
-		return nil, false, err
+		return nil, info, false, err
 	}
 
 	val, err := io.ReadAll(f)
 	if err != nil {
-		return nil, false, err
+		return nil, info, false, err
 	}
 
-	return val, true, nil
+	return val, info, true, nil
 }
 
 func (fc *Cache) Set(ctx context.Context, key *cache.Key, val []byte) error {
@@ -191,3 +226,146 @@ func (fc *Cache) Purge(ctx context.Context, key *cache.Key) error {
 	// remove the locker key on purge
 	return os.Remove(path)
 }
+
+// PurgePrefix removes the zoom directories holding the tiles of the map, or
+// of the layer of the map, between minZoom and maxZoom. The directory of the
+// map is removed at once when all its tiles are purged.
+func (fc *Cache) PurgePrefix(ctx context.Context, mapName, layerName string, minZoom, maxZoom uint) error {
+	mapPath := filepath.Join(fc.Basepath, mapName)
+	if layerName == "" && minZoom == 0 && maxZoom >= tegola.MaxZ {
+		return os.RemoveAll(mapPath)
+	}
+
+	// the tiles of each version and query parameters of the map are in a directory of their own
+	dirs, err := tileDirs(mapPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, dir := range dirs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// the tiles of the map, then the tiles of its layers
+		if err := purgeZooms(dir, minZoom, maxZoom); err != nil {
+			return err
+		}
+		if layerName != "" {
+			if err := purgeZooms(filepath.Join(dir, layerName), minZoom, maxZoom); err != nil {
+				return err
+			}
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if !e.IsDir() || isKeyDir(e.Name()) || isZoomDir(e.Name()) {
+				continue
+			}
+			if err := purgeZooms(filepath.Join(dir, e.Name()), minZoom, maxZoom); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// PurgeVersions removes the directories of the versions of the map other than
+// keepVersion. When keepVersion is set, the tiles of the map without a version
+// are removed too.
+func (fc *Cache) PurgeVersions(ctx context.Context, mapName, keepVersion string) error {
+	mapPath := filepath.Join(fc.Basepath, mapName)
+	keep := cache.Key{Version: keepVersion}.VersionDir()
+
+	entries, err := os.ReadDir(mapPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		versioned := strings.HasPrefix(e.Name(), "~")
+		if (versioned && e.Name() != keep) || (!versioned && keep != "") {
+			if err := os.RemoveAll(filepath.Join(mapPath, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// tileDirs returns the directory of the map and the directories of its
+// versions and query parameters, which are laid out as the directory of the map
+func tileDirs(mapPath string) ([]string, error) {
+	entries, err := os.ReadDir(mapPath)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []string{mapPath}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(e.Name(), "~"):
+			versionDirs, err := tileDirs(filepath.Join(mapPath, e.Name()))
+			if err != nil {
+				return nil, err
+			}
+			dirs = append(dirs, versionDirs...)
+		case strings.HasPrefix(e.Name(), "@"):
+			dirs = append(dirs, filepath.Join(mapPath, e.Name()))
+		}
+	}
+	return dirs, nil
+}
+
+// isKeyDir reports if the directory name is the version or the query
+// parameters of the tiles it holds
+func isKeyDir(name string) bool {
+	return strings.HasPrefix(name, "~") || strings.HasPrefix(name, "@")
+}
+
+// isZoomDir reports if the directory name is the zoom of the tiles it holds
+func isZoomDir(name string) bool {
+	_, err := strconv.ParseUint(name, 10, 32)
+	return err == nil
+}
+
+// purgeZooms removes the zoom directories of dir between minZoom and maxZoom
+func purgeZooms(dir string, minZoom, maxZoom uint) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, e := range entries {
+		z, err := strconv.ParseUint(e.Name(), 10, 32)
+		if err != nil || !e.IsDir() || uint(z) < minZoom || uint(z) > maxZoom {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
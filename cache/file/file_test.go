@@ -78,6 +78,20 @@ func TestNew(t *testing.T) {
 			},
 			err: nil,
 		},
+		"valid basepath, ttl and stale while revalidate": {
+			config: map[string]any{
+				"basepath":               "testfiles/tegola-cache",
+				"ttl":                    9,
+				"stale_while_revalidate": 30,
+			},
+			expected: &file.Cache{
+				Basepath:             "testfiles/tegola-cache",
+				MaxZoom:              tegola.MaxZ,
+				Expiration:           time.Duration(9) * time.Second,
+				StaleWhileRevalidate: time.Duration(30) * time.Second,
+			},
+			err: nil,
+		},
 		"missing basepath": {
 			config:   map[string]any{},
 			expected: nil,
@@ -414,3 +428,229 @@ func TestExpiration(t *testing.T) {
 		t.Run(name, fn(tc))
 	}
 }
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	type tcase struct {
+		age       time.Duration
+		expectHit bool
+		stale     bool
+	}
+
+	ctx := t.Context()
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			fc, err := file.New(dict.Dict{
+				"basepath":               t.TempDir(),
+				"ttl":                    60,
+				"stale_while_revalidate": 60,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			key := cache.Key{MapName: "osm", Z: 1, X: 2, Y: 3}
+			if err := fc.Set(ctx, &key, []byte("tile")); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			// age the tile
+			path := filepath.Join(fc.(*file.Cache).Basepath, key.String())
+			mtime := time.Now().Add(-tc.age)
+			if err := os.Chtimes(path, mtime, mtime); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			_, info, hit, err := fc.(cache.InfoGetter).GetInfo(ctx, &key)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if hit != tc.expectHit {
+				t.Fatalf("hit, expected %v got %v", tc.expectHit, hit)
+			}
+			if !hit {
+				if _, err := os.Stat(path); !os.IsNotExist(err) {
+					t.Errorf("expected the tile past the stale period to be deleted")
+				}
+				return
+			}
+			if info.Stale != tc.stale {
+				t.Errorf("stale, expected %v got %v", tc.stale, info.Stale)
+			}
+			if !info.Modified.Equal(mtime) {
+				t.Errorf("modified, expected %v got %v", mtime, info.Modified)
+			}
+			if !info.Expires.Equal(mtime.Add(time.Minute)) {
+				t.Errorf("expires, expected %v got %v", mtime.Add(time.Minute), info.Expires)
+			}
+
+			// Get reports stale tiles as misses without deleting them
+			_, hit, err = fc.Get(ctx, &key)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if hit == tc.stale {
+				t.Errorf("Get hit, expected %v got %v", !tc.stale, hit)
+			}
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("expected the tile to be kept, got %v", err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"fresh": {
+			age:       30 * time.Second,
+			expectHit: true,
+		},
+		"stale": {
+			age:       90 * time.Second,
+			expectHit: true,
+			stale:     true,
+		},
+		"past the stale period": {
+			age: 150 * time.Second,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestPurgePrefix(t *testing.T) {
+	type tcase struct {
+		layerName string
+		minZoom   uint
+		maxZoom   uint
+	}
+
+	keys := []cache.Key{
+		{MapName: "osm", Z: 3, X: 1, Y: 1},
+		{MapName: "osm", Z: 10, X: 1, Y: 1},
+		{MapName: "osm", LayerName: "roads", Z: 3, X: 1, Y: 1},
+		{MapName: "osm", LayerName: "roads", Z: 10, X: 1, Y: 1},
+		{MapName: "osm", LayerName: "water", Z: 10, X: 1, Y: 1},
+		{MapName: "osm", ParamsHash: "0123abcd", Z: 10, X: 1, Y: 1},
+		{MapName: "osm", ParamsHash: "0123abcd", LayerName: "roads", Z: 3, X: 1, Y: 1},
+		{MapName: "osm", Version: "v2", Z: 10, X: 1, Y: 1},
+		{MapName: "osm", Version: "v2", ParamsHash: "0123abcd", LayerName: "roads", Z: 3, X: 1, Y: 1},
+		{MapName: "other", Z: 3, X: 1, Y: 1},
+	}
+
+	ctx := t.Context()
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			fc, err := file.New(dict.Dict{"basepath": t.TempDir()})
+			if err != nil {
+				t.Fatalf("new, expected nil got %v", err)
+			}
+			for _, key := range keys {
+				if err := fc.Set(ctx, &key, []byte("tile")); err != nil {
+					t.Fatalf("set, expected nil got %v", err)
+				}
+			}
+
+			err = fc.(cache.PrefixPurger).PurgePrefix(ctx, "osm", tc.layerName, tc.minZoom, tc.maxZoom)
+			if err != nil {
+				t.Fatalf("purge prefix, expected nil got %v", err)
+			}
+
+			for _, key := range keys {
+				_, hit, err := fc.Get(ctx, &key)
+				if err != nil {
+					t.Fatalf("get, expected nil got %v", err)
+				}
+				if purged := key.MatchesPrefix("osm", tc.layerName, tc.minZoom, tc.maxZoom); hit == purged {
+					t.Errorf("key %v, expected hit %v got %v", key, !purged, hit)
+				}
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"map": {
+			maxZoom: tegola.MaxZ,
+		},
+		"map zoom range": {
+			minZoom: 5,
+			maxZoom: tegola.MaxZ,
+		},
+		"layer": {
+			layerName: "roads",
+			maxZoom:   tegola.MaxZ,
+		},
+		"layer zoom range": {
+			layerName: "roads",
+			maxZoom:   5,
+		},
+		"missing layer": {
+			layerName: "buildings",
+			maxZoom:   tegola.MaxZ,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestPurgeVersions(t *testing.T) {
+	type tcase struct {
+		keepVersion string
+	}
+
+	keys := []cache.Key{
+		{MapName: "osm", Z: 3, X: 1, Y: 1},
+		{MapName: "osm", LayerName: "roads", Z: 3, X: 1, Y: 1},
+		{MapName: "osm", ParamsHash: "0123abcd", Z: 3, X: 1, Y: 1},
+		{MapName: "osm", Version: "v1", Z: 3, X: 1, Y: 1},
+		{MapName: "osm", Version: "v2", Z: 3, X: 1, Y: 1},
+		{MapName: "osm", Version: "v2", ParamsHash: "0123abcd", LayerName: "roads", Z: 3, X: 1, Y: 1},
+		{MapName: "other", Version: "v1", Z: 3, X: 1, Y: 1},
+	}
+
+	ctx := t.Context()
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			fc, err := file.New(dict.Dict{"basepath": t.TempDir()})
+			if err != nil {
+				t.Fatalf("new, expected nil got %v", err)
+			}
+			for _, key := range keys {
+				if err := fc.Set(ctx, &key, []byte("tile")); err != nil {
+					t.Fatalf("set, expected nil got %v", err)
+				}
+			}
+
+			if err = fc.(cache.VersionPurger).PurgeVersions(ctx, "osm", tc.keepVersion); err != nil {
+				t.Fatalf("purge versions, expected nil got %v", err)
+			}
+
+			for _, key := range keys {
+				_, hit, err := fc.Get(ctx, &key)
+				if err != nil {
+					t.Fatalf("get, expected nil got %v", err)
+				}
+				if kept := key.MapName != "osm" || key.Version == tc.keepVersion; hit != kept {
+					t.Errorf("key %v, expected hit %v got %v", key, kept, hit)
+				}
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"keep version": {
+			keepVersion: "v2",
+		},
+		"keep no version": {
+			keepVersion: "",
+		},
+		"keep missing version": {
+			keepVersion: "v3",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
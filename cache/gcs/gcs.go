@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"path/filepath"
+	"strings"
 
 	"github.com/go-spatial/tegola"
 	"github.com/go-spatial/tegola/cache"
@@ -12,6 +13,7 @@ import (
 	"github.com/go-spatial/tegola/internal/log"
 
 	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
 )
 
 const CacheType = "gcs"
@@ -188,3 +190,60 @@ func (gcsCache *GCSCache) Purge(ctx context.Context, key *cache.Key) error {
 
 	return nil
 }
+
+// PurgePrefix deletes the tiles of the map, or of the layer of the map,
+// between minZoom and maxZoom.
+func (gcsCache *GCSCache) PurgePrefix(ctx context.Context, mapName, layerName string, minZoom, maxZoom uint) error {
+	err := gcsCache.purgeMatching(ctx, mapName, func(key *cache.Key) bool {
+		return key.MatchesPrefix(mapName, layerName, minZoom, maxZoom)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("PURGE PREFIX %s/%s z%d-%d\n", mapName, layerName, minZoom, maxZoom)
+
+	return nil
+}
+
+// PurgeVersions deletes the tiles of the map which are not of keepVersion.
+func (gcsCache *GCSCache) PurgeVersions(ctx context.Context, mapName, keepVersion string) error {
+	err := gcsCache.purgeMatching(ctx, mapName, func(key *cache.Key) bool {
+		return key.Version != keepVersion
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("PURGE VERSIONS %s keeping (%s)\n", mapName, keepVersion)
+
+	return nil
+}
+
+// purgeMatching lists the objects under the prefix of the map and deletes the
+// tiles matching match.
+func (gcsCache *GCSCache) purgeMatching(ctx context.Context, mapName string, match func(key *cache.Key) bool) error {
+	it := gcsCache.Bucket.Objects(ctx, &storage.Query{
+		Prefix: filepath.Join(gcsCache.Basepath, mapName) + "/",
+	})
+
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		key, err := cache.ParseKey(strings.TrimPrefix(attrs.Name, gcsCache.Basepath))
+		if err != nil || !match(key) {
+			continue
+		}
+		if err := gcsCache.Bucket.Object(attrs.Name).Delete(ctx); err != nil && err != storage.ErrObjectNotExist {
+			return err
+		}
+	}
+
+	return nil
+}
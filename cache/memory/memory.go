@@ -1,8 +1,11 @@
 package memory
 
 import (
+	"container/list"
 	"context"
+	"errors"
 	"sync"
+	"time"
 
 	"github.com/go-spatial/tegola/cache"
 	"github.com/go-spatial/tegola/dict"
@@ -10,39 +13,185 @@ import (
 
 const CacheType = "memory"
 
+const (
+	ConfigKeyMaxBytes   = "max_bytes"
+	ConfigKeyMaxEntries = "max_entries"
+	ConfigKeyTTL        = "ttl"
+	ConfigKeySWR        = "stale_while_revalidate"
+)
+
+var (
+	ErrInvalidMaxBytes   = errors.New("memory cache: max_bytes must be 0 (no limit) or greater")
+	ErrInvalidMaxEntries = errors.New("memory cache: max_entries must be 0 (no limit) or greater")
+	ErrInvalidTTL        = errors.New("memory cache: ttl must be 0 (no expiration) or greater")
+	ErrInvalidSWR        = errors.New("memory cache: stale_while_revalidate must be 0 or greater")
+)
+
 func init() {
 	cache.Register(CacheType, New)
 }
 
-func New(_ dict.Dicter) (cache.Interface, error) {
-	return &MemoryCache{
-		keyVals: map[string][]byte{},
-	}, nil
+// New instantiates a memory cache. The config is optional, without it the
+// cache has no limits.
+func New(config dict.Dicter) (cache.Interface, error) {
+	mc := MemoryCache{
+		keyVals: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+	if config == nil {
+		return &mc, nil
+	}
+
+	defaultLimit := 0
+
+	maxBytes, err := config.Int(ConfigKeyMaxBytes, &defaultLimit)
+	if err != nil {
+		return nil, err
+	}
+	if maxBytes < 0 {
+		return nil, ErrInvalidMaxBytes
+	}
+	mc.MaxBytes = int64(maxBytes)
+
+	if mc.MaxEntries, err = config.Int(ConfigKeyMaxEntries, &defaultLimit); err != nil {
+		return nil, err
+	}
+	if mc.MaxEntries < 0 {
+		return nil, ErrInvalidMaxEntries
+	}
+
+	ttl, err := config.Int(ConfigKeyTTL, &defaultLimit)
+	if err != nil {
+		return nil, err
+	}
+	if ttl < 0 {
+		return nil, ErrInvalidTTL
+	}
+	mc.TTL = time.Duration(ttl) * time.Second
+
+	swr, err := config.Int(ConfigKeySWR, &defaultLimit)
+	if err != nil {
+		return nil, err
+	}
+	if swr < 0 {
+		return nil, ErrInvalidSWR
+	}
+	mc.StaleWhileRevalidate = time.Duration(swr) * time.Second
+
+	return &mc, nil
+}
+
+// entry is a tile held by the cache
+type entry struct {
+	key      cache.Key
+	keyStr   string
+	val      []byte
+	modified time.Time
+	expires  time.Time
 }
 
-// test cacher, implements the cache.Interface
+// MemoryCache holds the tiles in memory, implements the cache.Interface.
+// When a limit is reached the least recently used tiles are evicted.
 type MemoryCache struct {
-	keyVals map[string][]byte
-	sync.RWMutex
+	// MaxBytes is the max size of the tiles held, 0 is no limit
+	MaxBytes int64
+	// MaxEntries is the max number of tiles held, 0 is no limit
+	MaxEntries int
+	// TTL is how long a tile is held, 0 is no expiration
+	TTL time.Duration
+	// StaleWhileRevalidate is how long expired tiles are still served by
+	// GetInfo, 0 evicts tiles as soon as they expire
+	StaleWhileRevalidate time.Duration
+
+	sync.Mutex
+	keyVals map[string]*list.Element
+	// lru has the most recently used tiles at the front
+	lru     *list.List
+	bytes   int64
+	onEvict func(key *cache.Key)
+}
+
+// NotifyEviction sets fn to be called with the key of each tile evicted to
+// stay within the limits or because it expired. fn is called while the cache
+// is locked and must not use the cache.
+func (mc *MemoryCache) NotifyEviction(fn func(key *cache.Key)) {
+	mc.Lock()
+	defer mc.Unlock()
+
+	mc.onEvict = fn
 }
 
 func (mc *MemoryCache) Get(ctx context.Context, key *cache.Key) ([]byte, bool, error) {
-	mc.RLock()
-	defer mc.RUnlock()
+	val, info, hit, err := mc.GetInfo(ctx, key)
+	if !hit || info.Stale {
+		return nil, false, err
+	}
+
+	return val, true, nil
+}
+
+// GetInfo reads the tile of the key along with the times it was set at and
+// expires. Expired tiles are stale hits during the StaleWhileRevalidate
+// period and evicted afterwards.
+func (mc *MemoryCache) GetInfo(ctx context.Context, key *cache.Key) ([]byte, cache.TileInfo, bool, error) {
+	mc.Lock()
+	defer mc.Unlock()
 
-	val, ok := mc.keyVals[key.String()]
+	el, ok := mc.keyVals[key.String()]
 	if !ok {
-		return nil, false, nil
+		return nil, cache.TileInfo{}, false, nil
 	}
 
-	return val, true, nil
+	e := el.Value.(*entry)
+	info := cache.TileInfo{
+		Modified: e.modified,
+		Expires:  e.expires,
+	}
+	if !e.expires.IsZero() {
+		now := time.Now()
+		if now.After(e.expires.Add(mc.StaleWhileRevalidate)) {
+			mc.evict(el)
+			return nil, cache.TileInfo{}, false, nil
+		}
+		info.Stale = now.After(e.expires)
+	}
+
+	// stale tiles are about to be replaced, they don't need to be kept around
+	if !info.Stale {
+		mc.lru.MoveToFront(el)
+	}
+	return e.val, info, true, nil
 }
 
 func (mc *MemoryCache) Set(ctx context.Context, key *cache.Key, val []byte) error {
 	mc.Lock()
 	defer mc.Unlock()
 
-	mc.keyVals[key.String()] = val
+	keyStr := key.String()
+	if el, ok := mc.keyVals[keyStr]; ok {
+		mc.remove(el)
+	}
+
+	// a tile larger than the cache would evict everything and not fit anyway
+	if mc.MaxBytes > 0 && int64(len(val)) > mc.MaxBytes {
+		return nil
+	}
+
+	e := &entry{
+		key:      *key,
+		keyStr:   keyStr,
+		val:      val,
+		modified: time.Now(),
+	}
+	if mc.TTL > 0 {
+		e.expires = e.modified.Add(mc.TTL)
+	}
+	mc.keyVals[keyStr] = mc.lru.PushFront(e)
+	mc.bytes += int64(len(val))
+
+	for (mc.MaxBytes > 0 && mc.bytes > mc.MaxBytes) || (mc.MaxEntries > 0 && mc.lru.Len() > mc.MaxEntries) {
+		mc.evict(mc.lru.Back())
+	}
 
 	return nil
 }
@@ -51,7 +200,73 @@ func (mc *MemoryCache) Purge(ctx context.Context, key *cache.Key) error {
 	mc.Lock()
 	defer mc.Unlock()
 
-	delete(mc.keyVals, key.String())
+	if el, ok := mc.keyVals[key.String()]; ok {
+		mc.remove(el)
+	}
+
+	return nil
+}
+
+// PurgePrefix removes the tiles of the map, or of the layer of the map,
+// between minZoom and maxZoom
+func (mc *MemoryCache) PurgePrefix(ctx context.Context, mapName, layerName string, minZoom, maxZoom uint) error {
+	mc.Lock()
+	defer mc.Unlock()
+
+	for el := mc.lru.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*entry).key.MatchesPrefix(mapName, layerName, minZoom, maxZoom) {
+			mc.remove(el)
+		}
+		el = next
+	}
+
+	return nil
+}
+
+// PurgeVersions removes the tiles of the map which are not of keepVersion
+func (mc *MemoryCache) PurgeVersions(ctx context.Context, mapName, keepVersion string) error {
+	mc.Lock()
+	defer mc.Unlock()
+
+	for el := mc.lru.Front(); el != nil; {
+		next := el.Next()
+		if key := el.Value.(*entry).key; key.MapName == mapName && key.Version != keepVersion {
+			mc.remove(el)
+		}
+		el = next
+	}
 
 	return nil
 }
+
+// Len returns the number of tiles held
+func (mc *MemoryCache) Len() int {
+	mc.Lock()
+	defer mc.Unlock()
+
+	return mc.lru.Len()
+}
+
+// Size returns the size in bytes of the tiles held
+func (mc *MemoryCache) Size() int64 {
+	mc.Lock()
+	defer mc.Unlock()
+
+	return mc.bytes
+}
+
+// remove drops the tile of the element from the cache. mc must be locked.
+func (mc *MemoryCache) remove(el *list.Element) {
+	e := mc.lru.Remove(el).(*entry)
+	delete(mc.keyVals, e.keyStr)
+	mc.bytes -= int64(len(e.val))
+}
+
+// evict removes the tile of the element and reports it. mc must be locked.
+func (mc *MemoryCache) evict(el *list.Element) {
+	mc.remove(el)
+	if mc.onEvict != nil {
+		mc.onEvict(&el.Value.(*entry).key)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/go-spatial/tegola/cache"
 	"github.com/go-spatial/tegola/cache/memory"
@@ -165,3 +166,288 @@ func TestSetOverwrite(t *testing.T) {
 		t.Run(name, fn(tc))
 	}
 }
+
+func TestNew(t *testing.T) {
+	type tcase struct {
+		config dict.Dict
+		err    error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			_, err := memory.New(tc.config)
+			if tc.err == nil && err != nil {
+				t.Errorf("error, expected nil got %v", err)
+				return
+			}
+			if tc.err != nil && err != tc.err {
+				t.Errorf("error, expected %v got %v", tc.err, err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"no limits": {
+			config: dict.Dict{},
+		},
+		"limits": {
+			config: dict.Dict{"max_bytes": 1024, "max_entries": 10, "ttl": 60, "stale_while_revalidate": 30},
+		},
+		"negative max_bytes": {
+			config: dict.Dict{"max_bytes": -1},
+			err:    memory.ErrInvalidMaxBytes,
+		},
+		"negative max_entries": {
+			config: dict.Dict{"max_entries": -1},
+			err:    memory.ErrInvalidMaxEntries,
+		},
+		"negative ttl": {
+			config: dict.Dict{"ttl": -1},
+			err:    memory.ErrInvalidTTL,
+		},
+		"negative stale_while_revalidate": {
+			config: dict.Dict{"stale_while_revalidate": -1},
+			err:    memory.ErrInvalidSWR,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestEviction(t *testing.T) {
+	ctx := context.Background()
+
+	type tcase struct {
+		config dict.Dict
+		// sizes of the tiles set at 0/0/i, in order
+		sizes []int
+		// the tile read between the sets, -1 for none
+		read    int
+		kept    []uint
+		evicted []uint
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			c, err := memory.New(tc.config)
+			if err != nil {
+				t.Fatalf("new, expected nil got %v", err)
+			}
+			mc := c.(*memory.MemoryCache)
+
+			var notified []uint
+			mc.NotifyEviction(func(key *cache.Key) {
+				notified = append(notified, key.Y)
+			})
+
+			for i, size := range tc.sizes {
+				if err = mc.Set(ctx, &cache.Key{Y: uint(i)}, make([]byte, size)); err != nil {
+					t.Fatalf("set %v, expected nil got %v", i, err)
+				}
+				if i == 1 && tc.read >= 0 {
+					mc.Get(ctx, &cache.Key{Y: uint(tc.read)})
+				}
+			}
+
+			for _, y := range tc.kept {
+				if _, hit, _ := mc.Get(ctx, &cache.Key{Y: y}); !hit {
+					t.Errorf("tile %v, expected hit got miss", y)
+				}
+			}
+			for _, y := range tc.evicted {
+				if _, hit, _ := mc.Get(ctx, &cache.Key{Y: y}); hit {
+					t.Errorf("tile %v, expected miss got hit", y)
+				}
+			}
+			if !reflect.DeepEqual(notified, tc.evicted) {
+				t.Errorf("evictions, expected %v got %v", tc.evicted, notified)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"max entries": {
+			config:  dict.Dict{"max_entries": 2},
+			sizes:   []int{1, 1, 1},
+			read:    -1,
+			kept:    []uint{1, 2},
+			evicted: []uint{0},
+		},
+		"max entries least recently used": {
+			config:  dict.Dict{"max_entries": 2},
+			sizes:   []int{1, 1, 1},
+			read:    0,
+			kept:    []uint{0, 2},
+			evicted: []uint{1},
+		},
+		"max bytes": {
+			config:  dict.Dict{"max_bytes": 10},
+			sizes:   []int{4, 4, 4},
+			read:    -1,
+			kept:    []uint{1, 2},
+			evicted: []uint{0},
+		},
+		"max bytes evicts several tiles": {
+			config:  dict.Dict{"max_bytes": 10},
+			sizes:   []int{4, 4, 9},
+			read:    -1,
+			kept:    []uint{2},
+			evicted: []uint{0, 1},
+		},
+		"tile larger than max bytes": {
+			config:  dict.Dict{"max_bytes": 10},
+			sizes:   []int{4, 4, 11},
+			read:    -1,
+			kept:    []uint{0, 1},
+			evicted: nil,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestTTL(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := memory.New(dict.Dict{})
+	if err != nil {
+		t.Fatalf("new, expected nil got %v", err)
+	}
+	mc := c.(*memory.MemoryCache)
+	mc.TTL = 10 * time.Millisecond
+
+	evicted := 0
+	mc.NotifyEviction(func(*cache.Key) { evicted++ })
+
+	key := cache.Key{MapName: "test", Z: 1}
+	if err = mc.Set(ctx, &key, []byte("tile")); err != nil {
+		t.Fatalf("set, expected nil got %v", err)
+	}
+	if _, hit, _ := mc.Get(ctx, &key); !hit {
+		t.Fatalf("get before ttl, expected hit got miss")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, hit, _ := mc.Get(ctx, &key); hit {
+		t.Errorf("get after ttl, expected miss got hit")
+	}
+	if evicted != 1 {
+		t.Errorf("evictions, expected 1 got %v", evicted)
+	}
+	if mc.Len() != 0 || mc.Size() != 0 {
+		t.Errorf("len / size, expected 0 / 0 got %v / %v", mc.Len(), mc.Size())
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := memory.New(dict.Dict{})
+	if err != nil {
+		t.Fatalf("new, expected nil got %v", err)
+	}
+	mc := c.(*memory.MemoryCache)
+	mc.TTL = 10 * time.Millisecond
+	mc.StaleWhileRevalidate = 40 * time.Millisecond
+
+	key := cache.Key{MapName: "test", Z: 1}
+	if err = mc.Set(ctx, &key, []byte("tile")); err != nil {
+		t.Fatalf("set, expected nil got %v", err)
+	}
+	_, info, hit, _ := mc.GetInfo(ctx, &key)
+	if !hit || info.Stale {
+		t.Fatalf("get before ttl, expected fresh hit got hit %v stale %v", hit, info.Stale)
+	}
+	if !info.Expires.Equal(info.Modified.Add(mc.TTL)) {
+		t.Errorf("expires, expected %v got %v", info.Modified.Add(mc.TTL), info.Expires)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, info, hit, _ = mc.GetInfo(ctx, &key); !hit || !info.Stale {
+		t.Errorf("get info after ttl, expected stale hit got hit %v stale %v", hit, info.Stale)
+	}
+	if _, hit, _ = mc.Get(ctx, &key); hit {
+		t.Errorf("get after ttl, expected miss got hit")
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	if _, _, hit, _ = mc.GetInfo(ctx, &key); hit {
+		t.Errorf("get info after the stale period, expected miss got hit")
+	}
+	if mc.Len() != 0 {
+		t.Errorf("len, expected 0 got %v", mc.Len())
+	}
+}
+
+func TestPurgePrefix(t *testing.T) {
+	ctx := context.Background()
+	keys := []cache.Key{
+		{MapName: "osm", Z: 3},
+		{MapName: "osm", LayerName: "roads", Z: 3},
+		{MapName: "osm", LayerName: "roads", Z: 10},
+		{MapName: "osm", ParamsHash: "0123abcd", LayerName: "roads", Z: 3},
+		{MapName: "other", LayerName: "roads", Z: 3},
+	}
+
+	c, err := memory.New(nil)
+	if err != nil {
+		t.Fatalf("new, expected nil got %v", err)
+	}
+	for _, key := range keys {
+		if err := c.Set(ctx, &key, []byte("tile")); err != nil {
+			t.Fatalf("set, expected nil got %v", err)
+		}
+	}
+
+	if err := c.(cache.PrefixPurger).PurgePrefix(ctx, "osm", "roads", 0, 5); err != nil {
+		t.Fatalf("purge prefix, expected nil got %v", err)
+	}
+
+	for _, key := range keys {
+		_, hit, _ := c.Get(ctx, &key)
+		if purged := key.MatchesPrefix("osm", "roads", 0, 5); hit == purged {
+			t.Errorf("key %v, expected hit %v got %v", key, !purged, hit)
+		}
+	}
+	if size := c.(*memory.MemoryCache).Size(); size != 2*int64(len("tile")) {
+		t.Errorf("size, expected %v got %v", 2*len("tile"), size)
+	}
+}
+
+func TestPurgeVersions(t *testing.T) {
+	ctx := context.Background()
+	keys := []cache.Key{
+		{MapName: "osm", Z: 3},
+		{MapName: "osm", Version: "v1", Z: 3},
+		{MapName: "osm", Version: "v2", LayerName: "roads", Z: 3},
+		{MapName: "other", Version: "v1", Z: 3},
+	}
+
+	c, err := memory.New(nil)
+	if err != nil {
+		t.Fatalf("new, expected nil got %v", err)
+	}
+	for _, key := range keys {
+		if err := c.Set(ctx, &key, []byte("tile")); err != nil {
+			t.Fatalf("set, expected nil got %v", err)
+		}
+	}
+
+	if err := c.(cache.VersionPurger).PurgeVersions(ctx, "osm", "v2"); err != nil {
+		t.Fatalf("purge versions, expected nil got %v", err)
+	}
+
+	for _, key := range keys {
+		_, hit, _ := c.Get(ctx, &key)
+		if kept := key.MapName != "osm" || key.Version == "v2"; hit != kept {
+			t.Errorf("key %v, expected hit %v got %v", key, kept, hit)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Package multi implements a tiered cache backend, chaining other cache
+// backends from the fastest to the slowest.
+package multi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+const CacheType = "multi"
+
+const (
+	ConfigKeyTiers = "tiers"
+	ConfigKeyType  = "type"
+)
+
+var ErrNoTiers = errors.New("multi cache: at least one tier is required")
+
+// ErrTierType is returned when the type of a tier is missing or invalid
+type ErrTierType struct {
+	Tier int
+	Err  error
+}
+
+func (e ErrTierType) Error() string {
+	return fmt.Sprintf("multi cache: tier (%v) 'type' parameter: %v", e.Tier, e.Err)
+}
+
+func (e ErrTierType) Unwrap() error { return e.Err }
+
+func init() {
+	cache.Register(CacheType, New)
+}
+
+// New instantiates a multi cache. Each tier is configured as a cache of its
+// own, with its type, and is created with cache.For.
+func New(config dict.Dicter) (cache.Interface, error) {
+	tierConfigs, err := config.MapSlice(ConfigKeyTiers)
+	if err != nil {
+		return nil, err
+	}
+	if len(tierConfigs) == 0 {
+		return nil, ErrNoTiers
+	}
+
+	mc := Cache{Tiers: make([]cache.Interface, 0, len(tierConfigs))}
+	for i, tc := range tierConfigs {
+		cType, err := tc.String(ConfigKeyType, nil)
+		if err != nil {
+			mc.closeTiers()
+			return nil, ErrTierType{Tier: i, Err: err}
+		}
+
+		tier, err := cache.For(cType, tc)
+		if err != nil {
+			mc.closeTiers()
+			return nil, fmt.Errorf("multi cache: tier (%v) of type (%v): %w", i, cType, err)
+		}
+		mc.Tiers = append(mc.Tiers, tier)
+	}
+
+	return &mc, nil
+}
+
+// closeTiers closes the tiers built before a tier failed to build
+func (mc *Cache) closeTiers() {
+	if err := mc.Close(); err != nil {
+		log.Warnf("multi cache: closing the tiers: %v", err)
+	}
+}
+
+// Cache chains cache backends, implements the cache.Interface.
+// Tiles are read from the first tier holding them and written to all the
+// tiers. The tiers are closed along with the cache, or by cache.Cleanup,
+// which tracks them as they are created by cache.For.
+type Cache struct {
+	// Tiers from the fastest to the slowest
+	Tiers []cache.Interface
+}
+
+// Get reads the tile from the tiers in order. On a hit, the tile is written
+// to the faster tiers which missed it. A tier failing is logged and skipped,
+// its error is only returned if no tier holds the tile.
+func (mc *Cache) Get(ctx context.Context, key *cache.Key) ([]byte, bool, error) {
+	val, info, hit, err := mc.GetInfo(ctx, key)
+	if !hit || info.Stale {
+		return nil, false, err
+	}
+	return val, true, nil
+}
+
+// GetInfo reads the tile along with its info from the tiers in order, as Get
+// does. A stale tile is only returned when none of the tiers holds a fresh
+// one and is not written to the faster tiers.
+func (mc *Cache) GetInfo(ctx context.Context, key *cache.Key) ([]byte, cache.TileInfo, bool, error) {
+	var (
+		firstErr   error
+		staleVal   []byte
+		staleInfo  cache.TileInfo
+		staleFound bool
+	)
+
+	for i, tier := range mc.Tiers {
+		val, info, hit, err := cache.GetInfo(ctx, tier, key)
+		if err != nil {
+			log.Warnf("multi cache: reading tile (%v) from tier (%v): %v", key, i, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if !hit {
+			continue
+		}
+		if info.Stale {
+			if !staleFound {
+				staleVal, staleInfo, staleFound = val, info, true
+			}
+			continue
+		}
+
+		// back fill the faster tiers
+		for j := 0; j < i; j++ {
+			if err := mc.Tiers[j].Set(ctx, key, val); err != nil {
+				log.Warnf("multi cache: back filling tile (%v) to tier (%v): %v", key, j, err)
+			}
+		}
+		return val, info, true, nil
+	}
+
+	if staleFound {
+		return staleVal, staleInfo, true, nil
+	}
+	return nil, cache.TileInfo{}, false, firstErr
+}
+
+// Set writes the tile to all the tiers
+func (mc *Cache) Set(ctx context.Context, key *cache.Key, val []byte) error {
+	var errs []error
+	for i, tier := range mc.Tiers {
+		if err := tier.Set(ctx, key, val); err != nil {
+			errs = append(errs, fmt.Errorf("tier (%v): %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Purge removes the tile from all the tiers
+func (mc *Cache) Purge(ctx context.Context, key *cache.Key) error {
+	var errs []error
+	for i, tier := range mc.Tiers {
+		if err := tier.Purge(ctx, key); err != nil {
+			errs = append(errs, fmt.Errorf("tier (%v): %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PurgePrefix removes the tiles of the map, or of the layer of the map,
+// between minZoom and maxZoom from all the tiers. All the tiers need to
+// support purging by prefix.
+func (mc *Cache) PurgePrefix(ctx context.Context, mapName, layerName string, minZoom, maxZoom uint) error {
+	var errs []error
+	for i, tier := range mc.Tiers {
+		if err := cache.PurgePrefix(ctx, tier, mapName, layerName, minZoom, maxZoom); err != nil {
+			errs = append(errs, fmt.Errorf("tier (%v): %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PurgeVersions removes the tiles of the map which are not of keepVersion
+// from all the tiers. All the tiers need to support purging versions.
+func (mc *Cache) PurgeVersions(ctx context.Context, mapName, keepVersion string) error {
+	var errs []error
+	for i, tier := range mc.Tiers {
+		if err := cache.PurgeVersions(ctx, tier, mapName, keepVersion); err != nil {
+			errs = append(errs, fmt.Errorf("tier (%v): %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyEviction sets fn to be called for the tiles evicted by any of the
+// tiers evicting tiles on their own
+func (mc *Cache) NotifyEviction(fn func(key *cache.Key)) {
+	for _, tier := range mc.Tiers {
+		if n, ok := tier.(cache.EvictionNotifier); ok {
+			n.NotifyEviction(fn)
+		}
+	}
+}
+
+// Close closes the tiers with cache.Close, which skips the tiers already
+// closed by cache.Cleanup
+func (mc *Cache) Close() error {
+	var errs []error
+	for i, tier := range mc.Tiers {
+		if err := cache.Close(tier); err != nil {
+			errs = append(errs, fmt.Errorf("tier (%v): %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
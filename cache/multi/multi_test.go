@@ -0,0 +1,302 @@
+package multi_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/cache/multi"
+	"github.com/go-spatial/tegola/dict"
+)
+
+// failingCache fails all the operations
+type failingCache struct{}
+
+var errFailing = errors.New("failing cache")
+
+func (failingCache) Get(context.Context, *cache.Key) ([]byte, bool, error) {
+	return nil, false, errFailing
+}
+func (failingCache) Set(context.Context, *cache.Key, []byte) error { return errFailing }
+func (failingCache) Purge(context.Context, *cache.Key) error       { return errFailing }
+
+// closerCache is a memory cache counting its closes
+type closerCache struct {
+	cache.Interface
+}
+
+// closerCloses counts the closes of the closer caches
+var closerCloses atomic.Int32
+
+func (*closerCache) Close() error {
+	closerCloses.Add(1)
+	return nil
+}
+
+func init() {
+	cache.Register("multi_test_closer", func(config dict.Dicter) (cache.Interface, error) {
+		c, err := memory.New(config)
+		if err != nil {
+			return nil, err
+		}
+		return &closerCache{Interface: c}, nil
+	})
+}
+
+func TestNew(t *testing.T) {
+	type tcase struct {
+		config dict.Dict
+		tiers  int
+		err    error
+		// closed is the number of closer tiers closed on error
+		closed int
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			closerCloses.Store(0)
+			c, err := multi.New(tc.config)
+			if tc.err != nil {
+				if err == nil || err.Error() != tc.err.Error() {
+					t.Errorf("error, expected %v got %v", tc.err, err)
+				}
+				// the tiers built before the failing one are closed
+				if n := closerCloses.Load(); n != int32(tc.closed) {
+					t.Errorf("closed tiers, expected %v got %v", tc.closed, n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error, expected nil got %v", err)
+			}
+			if tiers := len(c.(*multi.Cache).Tiers); tiers != tc.tiers {
+				t.Errorf("tiers, expected %v got %v", tc.tiers, tiers)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"tiers": {
+			config: dict.Dict{
+				"tiers": []map[string]interface{}{
+					{"type": "memory", "max_entries": 10},
+					{"type": "memory"},
+				},
+			},
+			tiers: 2,
+		},
+		"no tiers": {
+			config: dict.Dict{},
+			err:    multi.ErrNoTiers,
+		},
+		"missing tier type": {
+			config: dict.Dict{
+				"tiers": []map[string]interface{}{
+					{"type": "memory"},
+					{"max_entries": 10},
+				},
+			},
+			err: multi.ErrTierType{Tier: 1, Err: dict.ErrKeyRequired("type")},
+		},
+		"failing tier after built tiers": {
+			config: dict.Dict{
+				"tiers": []map[string]interface{}{
+					{"type": "multi_test_closer"},
+					{"type": "multi_test_closer"},
+					{"type": "memory", "max_entries": -1},
+				},
+			},
+			err:    errors.New("multi cache: tier (2) of type (memory): memory cache: max_entries must be 0 (no limit) or greater"),
+			closed: 2,
+		},
+		"missing tier type after built tiers": {
+			config: dict.Dict{
+				"tiers": []map[string]interface{}{
+					{"type": "multi_test_closer"},
+					{"max_entries": 10},
+				},
+			},
+			err:    multi.ErrTierType{Tier: 1, Err: dict.ErrKeyRequired("type")},
+			closed: 1,
+		},
+		"invalid tier config": {
+			config: dict.Dict{
+				"tiers": []map[string]interface{}{
+					{"type": "memory", "max_entries": -1},
+				},
+			},
+			err: errors.New("multi cache: tier (0) of type (memory): memory cache: max_entries must be 0 (no limit) or greater"),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+// newTiers returns a multi cache of memory tiers and the tiers
+func newTiers(t *testing.T, n int) (*multi.Cache, []cache.Interface) {
+	t.Helper()
+
+	configs := make([]map[string]interface{}, n)
+	for i := range configs {
+		configs[i] = map[string]interface{}{"type": "memory"}
+	}
+	c, err := multi.New(dict.Dict{"tiers": configs})
+	if err != nil {
+		t.Fatalf("new, expected nil got %v", err)
+	}
+	mc := c.(*multi.Cache)
+	return mc, mc.Tiers
+}
+
+func TestGetBackFill(t *testing.T) {
+	ctx := context.Background()
+	key := cache.Key{MapName: "test", Z: 1, X: 1, Y: 1}
+	val := []byte("tile")
+
+	mc, tiers := newTiers(t, 3)
+	if err := tiers[2].Set(ctx, &key, val); err != nil {
+		t.Fatalf("set, expected nil got %v", err)
+	}
+
+	got, hit, err := mc.Get(ctx, &key)
+	if err != nil {
+		t.Fatalf("get, expected nil got %v", err)
+	}
+	if !hit || !reflect.DeepEqual(got, val) {
+		t.Fatalf("get, expected hit %s got %v %s", val, hit, got)
+	}
+
+	for i, tier := range tiers {
+		if _, hit, _ := tier.Get(ctx, &key); !hit {
+			t.Errorf("tier %v, expected hit got miss", i)
+		}
+	}
+
+	other := cache.Key{MapName: "test", Z: 2}
+	if _, hit, err := mc.Get(ctx, &other); hit || err != nil {
+		t.Errorf("get missing tile, expected miss and nil got %v and %v", hit, err)
+	}
+}
+
+func TestGetInfoStale(t *testing.T) {
+	ctx := context.Background()
+	key := cache.Key{MapName: "test", Z: 1, X: 1, Y: 1}
+
+	mc, tiers := newTiers(t, 2)
+	fast := tiers[0].(*memory.MemoryCache)
+	fast.TTL = 10 * time.Millisecond
+	fast.StaleWhileRevalidate = time.Minute
+
+	if err := fast.Set(ctx, &key, []byte("stale")); err != nil {
+		t.Fatalf("set, expected nil got %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// only the fast tier holds the tile, stale
+	got, info, hit, err := mc.GetInfo(ctx, &key)
+	if err != nil || !hit || !info.Stale {
+		t.Fatalf("get info, expected stale hit got %v %v %v", hit, info.Stale, err)
+	}
+	if _, hit, _ := mc.Get(ctx, &key); hit {
+		t.Errorf("get, expected miss got hit")
+	}
+
+	// a fresh tile of a slower tier is preferred
+	if err := tiers[1].Set(ctx, &key, []byte("fresh")); err != nil {
+		t.Fatalf("set, expected nil got %v", err)
+	}
+	got, info, hit, err = mc.GetInfo(ctx, &key)
+	if err != nil || !hit || info.Stale {
+		t.Fatalf("get info, expected fresh hit got %v %v %v", hit, info.Stale, err)
+	}
+	if string(got) != "fresh" {
+		t.Errorf("get info, expected fresh got %s", got)
+	}
+}
+
+func TestSetPurge(t *testing.T) {
+	ctx := context.Background()
+	key := cache.Key{MapName: "test", Z: 1, X: 1, Y: 1}
+
+	mc, tiers := newTiers(t, 2)
+	if err := mc.Set(ctx, &key, []byte("tile")); err != nil {
+		t.Fatalf("set, expected nil got %v", err)
+	}
+	for i, tier := range tiers {
+		if _, hit, _ := tier.Get(ctx, &key); !hit {
+			t.Errorf("tier %v after set, expected hit got miss", i)
+		}
+	}
+
+	if err := mc.Purge(ctx, &key); err != nil {
+		t.Fatalf("purge, expected nil got %v", err)
+	}
+	for i, tier := range tiers {
+		if _, hit, _ := tier.Get(ctx, &key); hit {
+			t.Errorf("tier %v after purge, expected miss got hit", i)
+		}
+	}
+}
+
+func TestFailingTier(t *testing.T) {
+	ctx := context.Background()
+	key := cache.Key{MapName: "test", Z: 1, X: 1, Y: 1}
+
+	mc, tiers := newTiers(t, 1)
+	mc.Tiers = []cache.Interface{failingCache{}, tiers[0]}
+
+	// the tile is still written to the healthy tier
+	if err := mc.Set(ctx, &key, []byte("tile")); !errors.Is(err, errFailing) {
+		t.Errorf("set, expected %v got %v", errFailing, err)
+	}
+
+	// the failing tier is skipped
+	if _, hit, err := mc.Get(ctx, &key); !hit || err != nil {
+		t.Errorf("get, expected hit and nil got %v and %v", hit, err)
+	}
+
+	// no tier holds the tile, the error is reported
+	other := cache.Key{MapName: "test", Z: 2}
+	if _, hit, err := mc.Get(ctx, &other); hit || !errors.Is(err, errFailing) {
+		t.Errorf("get missing tile, expected miss and %v got %v and %v", errFailing, hit, err)
+	}
+
+	if err := mc.Purge(ctx, &key); !errors.Is(err, errFailing) {
+		t.Errorf("purge, expected %v got %v", errFailing, err)
+	}
+	if _, hit, _ := tiers[0].Get(ctx, &key); hit {
+		t.Errorf("healthy tier after purge, expected miss got hit")
+	}
+}
+
+func TestPurgePrefix(t *testing.T) {
+	ctx := context.Background()
+	key := cache.Key{MapName: "test", Z: 1, X: 1, Y: 1}
+
+	mc, tiers := newTiers(t, 2)
+	if err := mc.Set(ctx, &key, []byte("tile")); err != nil {
+		t.Fatalf("set, expected nil got %v", err)
+	}
+
+	if err := mc.PurgePrefix(ctx, "test", "", 0, 20); err != nil {
+		t.Fatalf("purge prefix, expected nil got %v", err)
+	}
+	for i, tier := range tiers {
+		if _, hit, _ := tier.Get(ctx, &key); hit {
+			t.Errorf("tier %v after purge prefix, expected miss got hit", i)
+		}
+	}
+
+	// the tiers which can't purge by prefix are reported
+	mc.Tiers = []cache.Interface{failingCache{}, tiers[0]}
+	if err := mc.PurgePrefix(ctx, "test", "", 0, 20); !errors.Is(err, cache.ErrPurgePrefixNotSupported) {
+		t.Errorf("purge prefix, expected %v got %v", cache.ErrPurgePrefixNotSupported, err)
+	}
+}
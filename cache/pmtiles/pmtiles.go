@@ -0,0 +1,537 @@
+package pmtiles
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/internal/pmtiles"
+)
+
+var ErrMissingBasepath = errors.New("pmtilescache: missing required param 'basepath'")
+
+const CacheType = "pmtiles"
+
+const (
+	ConfigKeyBasepath      = "basepath"
+	ConfigKeyMaxZoom       = "max_zoom"
+	ConfigKeyFlushInterval = "flush_interval"
+	ConfigKeyFlushTiles    = "flush_tiles"
+)
+
+// Extension is the file extension of the archives written by the cache
+const Extension = ".pmtiles"
+
+func init() {
+	cache.Register(CacheType, New) //nolint:errcheck
+}
+
+// New instantiates a Cache. The config expects the following params:
+//
+//	basepath (string): a path to the directory the archives will be written to
+//	max_zoom (int): max zoom to use the cache. beyond this zoom cache Set() calls will be ignored
+//	flush_interval (int): seconds between the writes of the staged tiles to the archives. 0 (default) only writes them on Close
+//	flush_tiles (int): number of staged tiles of an archive after which it's written. 0 (default) is no limit
+func New(config dict.Dicter) (cache.Interface, error) {
+	var err error
+
+	pc := Cache{
+		archives: make(map[string]*archive),
+	}
+
+	defaultMaxZoom := uint(tegola.MaxZ)
+	pc.MaxZoom, err = config.Uint(ConfigKeyMaxZoom, &defaultMaxZoom)
+	if err != nil {
+		return nil, err
+	}
+
+	var flushInterval uint
+	flushInterval, err = config.Uint(ConfigKeyFlushInterval, &flushInterval)
+	if err != nil {
+		return nil, err
+	}
+	pc.FlushInterval = time.Duration(flushInterval) * time.Second
+
+	pc.FlushTiles, err = config.Uint(ConfigKeyFlushTiles, &pc.FlushTiles)
+	if err != nil {
+		return nil, err
+	}
+
+	pc.Basepath, err = config.String(ConfigKeyBasepath, nil)
+	if err != nil || pc.Basepath == "" {
+		return nil, ErrMissingBasepath
+	}
+
+	// make our basepath if it does not exist
+	if err = os.MkdirAll(pc.Basepath, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	if pc.FlushInterval > 0 || pc.FlushTiles > 0 {
+		pc.startFlushing()
+	}
+
+	return &pc, nil
+}
+
+// Cache stores tiles in PMTiles archives. Every map is written to its own
+// archive at <basepath>/<map>.pmtiles and every map layer to an archive at
+// <basepath>/<map>/<layer>.pmtiles.
+//
+// As archives can not be updated in place, tiles that are set or purged are
+// staged and the archives are rewritten when the cache is closed, and every
+// FlushInterval or FlushTiles staged tiles when set by New.
+type Cache struct {
+	// Basepath is the directory the archives are written to
+	Basepath string
+	// MaxZoom determines the max zoom the cache to persist. Beyond this
+	// zoom, cache Set() calls will be ignored.
+	MaxZoom uint
+	// FlushInterval is the time between the writes of the staged tiles,
+	// 0 only writes them on Close
+	FlushInterval time.Duration
+	// FlushTiles is the number of staged tiles of an archive after which the
+	// archive is written, 0 is no limit
+	FlushTiles uint
+
+	lock     sync.Mutex
+	archives map[string]*archive
+
+	// flush requests the archives to be written by the flushing goroutine
+	flush    chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	flushing sync.WaitGroup
+}
+
+// archivePath returns the location of the archive holding the tiles for the key
+func (pc *Cache) archivePath(key *cache.Key) string {
+	return filepath.Join(pc.Basepath, key.MapName, key.VersionDir(), key.ParamsDir(), key.LayerName) + Extension
+}
+
+// archive returns the archive holding the tiles for key, opening the
+// archive if needed
+func (pc *Cache) archive(key *cache.Key) (*archive, error) {
+	path := pc.archivePath(key)
+
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+
+	if pc.archives == nil {
+		pc.archives = make(map[string]*archive)
+	}
+
+	if a, ok := pc.archives[path]; ok {
+		return a, nil
+	}
+
+	a, err := openArchive(path)
+	if err != nil {
+		return nil, err
+	}
+	pc.archives[path] = a
+
+	return a, nil
+}
+
+// Get reads a z,x,y entry from the cache and returns the contents
+// if there is a hit. the second argument denotes a hit or miss
+// so the consumer does not need to sniff errors for cache read misses
+func (pc *Cache) Get(ctx context.Context, key *cache.Key) ([]byte, bool, error) {
+	if key.MapName == "" {
+		return nil, false, nil
+	}
+
+	a, err := pc.archive(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	return a.get(pmtiles.TileID(key.Z, key.X, key.Y))
+}
+
+func (pc *Cache) Set(ctx context.Context, key *cache.Key, val []byte) error {
+	// check for maxzoom
+	if key.Z > pc.MaxZoom || key.MapName == "" {
+		return nil
+	}
+
+	a, err := pc.archive(key)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	staged, err := a.set(pmtiles.TileID(key.Z, key.X, key.Y), val)
+	if err != nil {
+		return err
+	}
+	pc.stagedTiles(staged)
+	return nil
+}
+
+func (pc *Cache) Purge(ctx context.Context, key *cache.Key) error {
+	if key.MapName == "" {
+		return nil
+	}
+
+	a, err := pc.archive(key)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	pc.stagedTiles(a.purge(pmtiles.TileID(key.Z, key.X, key.Y)))
+	return nil
+}
+
+// stagedTiles requests the archives to be written once an archive has
+// FlushTiles staged tiles
+func (pc *Cache) stagedTiles(n uint) {
+	if pc.FlushTiles == 0 || n < pc.FlushTiles {
+		return
+	}
+	select {
+	case pc.flush <- struct{}{}:
+	default:
+		// already requested
+	}
+}
+
+// startFlushing starts the goroutine writing the staged tiles to the archives
+// every FlushInterval, or when requested, until the cache is closed
+func (pc *Cache) startFlushing() {
+	pc.flush = make(chan struct{}, 1)
+	pc.stop = make(chan struct{})
+
+	pc.flushing.Add(1)
+	go func() {
+		defer pc.flushing.Done()
+
+		var tick <-chan time.Time
+		if pc.FlushInterval > 0 {
+			ticker := time.NewTicker(pc.FlushInterval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for {
+			select {
+			case <-pc.stop:
+				return
+			case <-tick:
+			case <-pc.flush:
+			}
+			if err := pc.Flush(); err != nil {
+				log.Errorf("pmtilescache: writing archives: %v", err)
+			}
+		}
+	}()
+}
+
+// openArchives returns the archives opened by the cache
+func (pc *Cache) openArchives() []*archive {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+
+	archives := make([]*archive, 0, len(pc.archives))
+	for _, a := range pc.archives {
+		archives = append(archives, a)
+	}
+	return archives
+}
+
+// Flush writes the staged tiles of all archives to disk
+func (pc *Cache) Flush() error {
+	var errs []error
+	for _, a := range pc.openArchives() {
+		if err := a.flush(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close flushes the staged tiles and closes all archives
+func (pc *Cache) Close() error {
+	if pc.stop != nil {
+		pc.stopOnce.Do(func() { close(pc.stop) })
+		pc.flushing.Wait()
+	}
+
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+
+	var errs []error
+	for path, a := range pc.archives {
+		log.Infof("pmtilescache: writing archive %v", path)
+		if err := a.flush(); err != nil {
+			errs = append(errs, err)
+		}
+		if err := a.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	pc.archives = make(map[string]*archive)
+
+	return errors.Join(errs...)
+}
+
+// archive is a single PMTiles archive along with the changes that
+// have not yet been written to it
+type archive struct {
+	path string
+
+	// flushLock serializes the flushes of the archive and its close. the
+	// archive is rewritten holding flushLock only, so the tiles can be read
+	// and set meanwhile.
+	flushLock sync.Mutex
+
+	lock sync.RWMutex
+	// reader is nil if the archive does not exist yet
+	reader *pmtiles.Reader
+	// staged holds tiles set since the last flush. it's created on the first Set
+	staged *pmtiles.Writer
+	// purged holds the tile ids purged since the last flush
+	purged map[uint64]struct{}
+	// changes is the number of tiles set or purged since the last flush
+	changes uint
+	// flushing and flushPurged are the tiles staged and the tile ids purged
+	// before the flush in progress, until the archive is swapped in
+	flushing    *pmtiles.Writer
+	flushPurged map[uint64]struct{}
+}
+
+func openArchive(path string) (*archive, error) {
+	a := archive{
+		path:   path,
+		purged: make(map[uint64]struct{}),
+	}
+
+	r, err := pmtiles.Open(path)
+	switch {
+	case err == nil:
+		a.reader = r
+	case os.IsNotExist(err):
+	default:
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+func (a *archive) get(id uint64) ([]byte, bool, error) {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	if a.staged != nil {
+		if data, ok, err := a.staged.Get(id); err != nil || ok {
+			return data, ok, err
+		}
+	}
+	if _, ok := a.purged[id]; ok {
+		return nil, false, nil
+	}
+
+	if a.flushing != nil {
+		if data, ok, err := a.flushing.Get(id); err != nil || ok {
+			return data, ok, err
+		}
+	}
+	if _, ok := a.flushPurged[id]; ok || a.reader == nil {
+		return nil, false, nil
+	}
+
+	return a.reader.TileByID(id)
+}
+
+// set stages the tile and returns the number of tiles staged since the last flush
+func (a *archive) set(id uint64, val []byte) (uint, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.staged == nil {
+		w, err := pmtiles.NewWriter(filepath.Dir(a.path))
+		if err != nil {
+			// the directory of a layer archive may not exist yet
+			if err = os.MkdirAll(filepath.Dir(a.path), os.ModePerm); err != nil {
+				return a.changes, err
+			}
+			if w, err = pmtiles.NewWriter(filepath.Dir(a.path)); err != nil {
+				return a.changes, err
+			}
+		}
+		a.staged = w
+	}
+
+	delete(a.purged, id)
+	if err := a.staged.Add(id, val); err != nil {
+		return a.changes, err
+	}
+	a.changes++
+	return a.changes, nil
+}
+
+// purge stages the removal of the tile and returns the number of tiles
+// staged since the last flush
+func (a *archive) purge(id uint64) uint {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.staged != nil {
+		a.staged.Remove(id)
+	}
+	if a.reader != nil || a.flushing != nil {
+		a.purged[id] = struct{}{}
+	}
+	a.changes++
+	return a.changes
+}
+
+// flush rewrites the archive with the staged changes applied. The staged
+// changes are taken over at once, and the new archive is written next to the
+// archive without holding the lock, which is only taken again to swap it in.
+func (a *archive) flush() error {
+	a.flushLock.Lock()
+	defer a.flushLock.Unlock()
+
+	a.lock.Lock()
+	if a.staged == nil && len(a.purged) == 0 {
+		a.lock.Unlock()
+		return nil
+	}
+
+	w := a.staged
+	if w == nil {
+		var err error
+		if w, err = pmtiles.NewWriter(filepath.Dir(a.path)); err != nil {
+			a.lock.Unlock()
+			return err
+		}
+	}
+	// the staged tiles are served from w until the new archive is swapped
+	// in, the changes made meanwhile are staged for the next flush
+	a.flushing, a.flushPurged = w, a.purged
+	a.staged, a.purged = nil, make(map[uint64]struct{})
+	a.changes = 0
+	reader, purged := a.reader, a.flushPurged
+	a.lock.Unlock()
+
+	tmpPath := a.path + ".flush"
+	defer os.Remove(tmpPath)
+
+	err := writeArchive(w, reader, purged, a.path, tmpPath)
+
+	a.lock.Lock()
+	if err == nil {
+		err = a.swap(tmpPath)
+	}
+	if err != nil {
+		// the staged tiles are lost, the purged tiles stay purged
+		for id := range a.flushPurged {
+			a.purged[id] = struct{}{}
+		}
+	}
+	a.flushing, a.flushPurged = nil, nil
+	a.lock.Unlock()
+
+	w.Close()
+	return err
+}
+
+// writeArchive writes the tiles of w, along with the tiles of the archive of
+// reader which were not purged or replaced, as an archive to tmpPath. reader
+// is nil if the archive at path does not exist yet.
+func writeArchive(w *pmtiles.Writer, reader *pmtiles.Reader, purged map[uint64]struct{}, path, tmpPath string) error {
+	if reader != nil {
+		if md, err := reader.Metadata(); err == nil {
+			w.Metadata = md
+		}
+
+		err := reader.Entries(func(e pmtiles.Entry) error {
+			var data []byte
+			for id := e.TileID; id < e.TileID+uint64(e.RunLength); id++ {
+				if _, ok := purged[id]; ok || w.Has(id) {
+					continue
+				}
+				if data == nil {
+					var err error
+					if data, err = reader.ReadEntry(e); err != nil {
+						return err
+					}
+				}
+				if err := w.Add(id, data); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, ok := w.Metadata["name"]; !ok {
+		w.Metadata["name"] = strings.TrimSuffix(filepath.Base(path), Extension)
+	}
+
+	return w.WriteFile(tmpPath)
+}
+
+// swap moves the archive written to tmpPath into place and reads it. The
+// lock must be held.
+func (a *archive) swap(tmpPath string) error {
+	if err := os.Rename(tmpPath, a.path); err != nil {
+		return err
+	}
+
+	if a.reader != nil {
+		a.reader.Close()
+	}
+	r, err := pmtiles.Open(a.path)
+	if err != nil {
+		a.reader = nil
+		return err
+	}
+	a.reader = r
+	return nil
+}
+
+func (a *archive) close() error {
+	a.flushLock.Lock()
+	defer a.flushLock.Unlock()
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	var err error
+	if a.staged != nil {
+		err = a.staged.Close()
+		a.staged = nil
+	}
+	if a.reader != nil {
+		if rErr := a.reader.Close(); err == nil {
+			err = rErr
+		}
+		a.reader = nil
+	}
+	return err
+}
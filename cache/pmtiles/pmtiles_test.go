@@ -0,0 +1,397 @@
+package pmtiles_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/pmtiles"
+	"github.com/go-spatial/tegola/dict"
+)
+
+func TestNew(t *testing.T) {
+	type tcase struct {
+		config   dict.Dict
+		expected *pmtiles.Cache
+		err      error
+	}
+
+	dir := t.TempDir()
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			output, err := pmtiles.New(tc.config)
+			if tc.err != nil {
+				if err == nil || err.Error() != tc.err.Error() {
+					t.Errorf("error, expected %v got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+				return
+			}
+
+			pc := output.(*pmtiles.Cache)
+			if pc.Basepath != tc.expected.Basepath || pc.MaxZoom != tc.expected.MaxZoom {
+				t.Errorf("expected %+v got %+v", tc.expected, pc)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"valid basepath": {
+			config: map[string]any{
+				"basepath": dir,
+			},
+			expected: &pmtiles.Cache{
+				Basepath: dir,
+				MaxZoom:  tegola.MaxZ,
+			},
+		},
+		"valid basepath and max zoom": {
+			config: map[string]any{
+				"basepath": dir,
+				"max_zoom": uint(9),
+			},
+			expected: &pmtiles.Cache{
+				Basepath: dir,
+				MaxZoom:  9,
+			},
+		},
+		"missing basepath": {
+			config: map[string]any{},
+			err:    pmtiles.ErrMissingBasepath,
+		},
+		"invalid zoom": {
+			config: map[string]any{
+				"basepath": dir,
+				"max_zoom": "foo",
+			},
+			err: fmt.Errorf(`config: value mapped to "max_zoom" is string not uint`),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestSetGetPurge(t *testing.T) {
+	type tcase struct {
+		keys []cache.Key
+		// purge is the index of a key in keys to purge after the first flush
+		purge int
+	}
+
+	ctx := t.Context()
+
+	tileData := func(key cache.Key) []byte {
+		return []byte(key.String())
+	}
+
+	get := func(t *testing.T, c cache.Interface, key cache.Key, expectHit bool) {
+		t.Helper()
+
+		output, hit, err := c.Get(ctx, &key)
+		if err != nil {
+			t.Fatalf("read failed. err: %v", err)
+		}
+		if hit != expectHit {
+			t.Fatalf("key %v, hit expected %v got %v", key, expectHit, hit)
+		}
+		if hit && !reflect.DeepEqual(output, tileData(key)) {
+			t.Fatalf("key %v, expected %s got %s", key, tileData(key), output)
+		}
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			config := dict.Dict{
+				"basepath": t.TempDir(),
+			}
+
+			c, err := pmtiles.New(config)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			pc := c.(*pmtiles.Cache)
+
+			for _, key := range tc.keys {
+				if err = pc.Set(ctx, &key, tileData(key)); err != nil {
+					t.Fatalf("write failed. err: %v", err)
+				}
+			}
+
+			// staged tiles are served before they are written out
+			for _, key := range tc.keys {
+				get(t, pc, key, true)
+			}
+
+			if err = pc.Flush(); err != nil {
+				t.Fatalf("flush failed. err: %v", err)
+			}
+			for _, key := range tc.keys {
+				get(t, pc, key, true)
+			}
+
+			purged := tc.keys[tc.purge]
+			if err = pc.Purge(ctx, &purged); err != nil {
+				t.Fatalf("purge failed. err: %v", err)
+			}
+			get(t, pc, purged, false)
+
+			if err = pc.Close(); err != nil {
+				t.Fatalf("close failed. err: %v", err)
+			}
+
+			// reopen the archives to make sure the purge was persisted
+			c, err = pmtiles.New(config)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			defer c.(*pmtiles.Cache).Close()
+
+			for i, key := range tc.keys {
+				get(t, c, key, i != tc.purge)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"map tiles": {
+			keys: []cache.Key{
+				{MapName: "osm", Z: 0, X: 0, Y: 0},
+				{MapName: "osm", Z: 1, X: 1, Y: 0},
+				{MapName: "osm", Z: 10, X: 163, Y: 395},
+			},
+			purge: 1,
+		},
+		"map and layer tiles": {
+			keys: []cache.Key{
+				{MapName: "osm", Z: 2, X: 1, Y: 3},
+				{MapName: "osm", LayerName: "water", Z: 2, X: 1, Y: 3},
+				{MapName: "other", Z: 2, X: 1, Y: 3},
+			},
+			purge: 1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestArchivePaths(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+
+	c, err := pmtiles.New(dict.Dict{"basepath": dir})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	keys := []cache.Key{
+		{MapName: "osm", Z: 0, X: 0, Y: 0},
+		{MapName: "osm", LayerName: "water", Z: 0, X: 0, Y: 0},
+	}
+	for _, key := range keys {
+		if err = c.Set(ctx, &key, []byte("tile")); err != nil {
+			t.Fatalf("write failed. err: %v", err)
+		}
+	}
+
+	if err = c.(*pmtiles.Cache).Close(); err != nil {
+		t.Fatalf("close failed. err: %v", err)
+	}
+
+	for _, path := range []string{"osm.pmtiles", filepath.Join("osm", "water.pmtiles")} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Errorf("expected archive %v to exist: %v", path, err)
+		}
+	}
+
+	// only the archives should be left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(entries) != 2 {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("expected 2 entries in basepath got %v", names)
+	}
+}
+
+func TestMaxZoom(t *testing.T) {
+	ctx := t.Context()
+
+	c, err := pmtiles.New(dict.Dict{
+		"basepath": t.TempDir(),
+		"max_zoom": uint(5),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer c.(*pmtiles.Cache).Close()
+
+	key := cache.Key{MapName: "osm", Z: 6, X: 1, Y: 1}
+	if err = c.Set(ctx, &key, []byte("tile")); err != nil {
+		t.Fatalf("write failed. err: %v", err)
+	}
+
+	_, hit, err := c.Get(ctx, &key)
+	if err != nil {
+		t.Fatalf("read failed. err: %v", err)
+	}
+	if hit {
+		t.Errorf("expected miss for tile beyond max zoom")
+	}
+}
+
+func TestFlush(t *testing.T) {
+	type tcase struct {
+		config dict.Dict
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			dir := t.TempDir()
+			tc.config["basepath"] = dir
+
+			c, err := pmtiles.New(tc.config)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			defer c.(*pmtiles.Cache).Close()
+
+			for x := uint(0); x < 2; x++ {
+				key := cache.Key{MapName: "osm", Z: 1, X: x, Y: 0}
+				if err = c.Set(t.Context(), &key, []byte(key.String())); err != nil {
+					t.Fatalf("write failed. err: %v", err)
+				}
+			}
+
+			// the archive is written in the background, before the cache is closed
+			path := filepath.Join(dir, "osm"+pmtiles.Extension)
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				if _, err = os.Stat(path); err == nil {
+					return
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("archive %v not written: %v", path, err)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"flush interval": {
+			config: dict.Dict{"flush_interval": uint(1)},
+		},
+		"flush tiles": {
+			config: dict.Dict{"flush_tiles": uint(2)},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestFlushConcurrent(t *testing.T) {
+	c, err := pmtiles.New(dict.Dict{"basepath": t.TempDir()})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	pc := c.(*pmtiles.Cache)
+	defer pc.Close()
+
+	ctx := t.Context()
+	key := func(x uint) *cache.Key { return &cache.Key{MapName: "osm", Z: 8, X: x, Y: 0} }
+
+	// the tiles 0-99 are in the archive, 100-199 are staged
+	for x := uint(0); x < 200; x++ {
+		if err = c.Set(ctx, key(x), []byte(key(x).String())); err != nil {
+			t.Fatalf("write failed. err: %v", err)
+		}
+		if x == 99 {
+			if err = pc.Flush(); err != nil {
+				t.Fatalf("flush failed. err: %v", err)
+			}
+		}
+	}
+
+	// the tiles are read and changed while the archive is rewritten
+	flushed := make(chan error)
+	go func() { flushed <- pc.Flush() }()
+
+	for x := uint(0); x < 200; x++ {
+		if _, hit, err := c.Get(ctx, key(x)); err != nil || !hit {
+			t.Errorf("tile %v during flush, expected hit got %v (err %v)", x, hit, err)
+		}
+	}
+	if err = c.Purge(ctx, key(0)); err != nil {
+		t.Fatalf("purge failed. err: %v", err)
+	}
+	if err = c.Purge(ctx, key(150)); err != nil {
+		t.Fatalf("purge failed. err: %v", err)
+	}
+	if err = c.Set(ctx, key(200), []byte(key(200).String())); err != nil {
+		t.Fatalf("write failed. err: %v", err)
+	}
+
+	if err = <-flushed; err != nil {
+		t.Fatalf("flush failed. err: %v", err)
+	}
+
+	// the changes made during the flush are kept, before and after the next one
+	for i := 0; i < 2; i++ {
+		for x := uint(0); x <= 200; x++ {
+			expected := x != 0 && x != 150
+			val, hit, err := c.Get(ctx, key(x))
+			if err != nil {
+				t.Fatalf("read failed. err: %v", err)
+			}
+			if hit != expected {
+				t.Errorf("flush %v, tile %v, expected hit %v got %v", i, x, expected, hit)
+			}
+			if hit && string(val) != key(x).String() {
+				t.Errorf("flush %v, tile %v, expected %q got %q", i, x, key(x).String(), val)
+			}
+		}
+		if err = pc.Flush(); err != nil {
+			t.Fatalf("flush failed. err: %v", err)
+		}
+	}
+}
+
+func TestCorruptArchive(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "osm.pmtiles"), []byte("not an archive"), 0644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	c, err := pmtiles.New(dict.Dict{"basepath": dir})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	key := cache.Key{MapName: "osm", Z: 0, X: 0, Y: 0}
+	if _, _, err = c.Get(t.Context(), &key); err == nil {
+		t.Errorf("expected error reading corrupt archive")
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected corrupt archive error got %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -26,6 +27,7 @@ const (
 	ConfigKeyTTL      = "ttl"
 	ConfigKeySSL      = "ssl"
 	ConfigKeyURI      = "uri"
+	ConfigKeySWR      = "stale_while_revalidate"
 )
 
 var (
@@ -38,6 +40,7 @@ var (
 	defaultMaxZoom  = uint(tegola.MaxZ)
 	defaultTTL      = 0
 	defaultSSL      = false
+	defaultSWR      = 0
 )
 
 func init() {
@@ -141,10 +144,16 @@ func New(c dict.Dicter) (rcache cache.Interface, err error) {
 		return nil, err
 	}
 
+	swr, err := c.Int(ConfigKeySWR, &defaultSWR)
+	if err != nil {
+		return nil, err
+	}
+
 	return &RedisCache{
-		Redis:      client,
-		MaxZoom:    maxZoom,
-		Expiration: time.Duration(ttl) * time.Second,
+		Redis:                client,
+		MaxZoom:              maxZoom,
+		Expiration:           time.Duration(ttl) * time.Second,
+		StaleWhileRevalidate: time.Duration(swr) * time.Second,
 	}, nil
 }
 
@@ -152,6 +161,9 @@ type RedisCache struct {
 	Redis      *redis.Client
 	Expiration time.Duration
 	MaxZoom    uint
+	// StaleWhileRevalidate is how long tiles are kept once they expired, so
+	// GetInfo can still serve them while they are regenerated
+	StaleWhileRevalidate time.Duration
 }
 
 func (rdc *RedisCache) Set(ctx context.Context, key *cache.Key, val []byte) error {
@@ -159,12 +171,26 @@ func (rdc *RedisCache) Set(ctx context.Context, key *cache.Key, val []byte) erro
 		return nil
 	}
 
+	// redis drops the tiles once they can no longer be served stale
+	expiration := rdc.Expiration
+	if expiration > 0 {
+		expiration += rdc.StaleWhileRevalidate
+	}
+
 	return rdc.Redis.
-		Set(ctx, key.String(), val, rdc.Expiration).
+		Set(ctx, key.String(), val, expiration).
 		Err()
 }
 
 func (rdc *RedisCache) Get(ctx context.Context, key *cache.Key) (val []byte, hit bool, err error) {
+	if rdc.Expiration > 0 && rdc.StaleWhileRevalidate > 0 {
+		val, info, hit, err := rdc.GetInfo(ctx, key)
+		if !hit || info.Stale {
+			return nil, false, err
+		}
+		return val, true, nil
+	}
+
 	val, err = rdc.Redis.Get(ctx, key.String()).Bytes()
 
 	switch err {
@@ -177,6 +203,101 @@ func (rdc *RedisCache) Get(ctx context.Context, key *cache.Key) (val []byte, hit
 	}
 }
 
+// GetInfo reads the tile of the key along with the times it was set at and
+// expires, which are derived from the time to live of the key when the cache
+// has a ttl. Tiles are stale hits during the StaleWhileRevalidate period.
+func (rdc *RedisCache) GetInfo(ctx context.Context, key *cache.Key) ([]byte, cache.TileInfo, bool, error) {
+	var info cache.TileInfo
+
+	if rdc.Expiration <= 0 {
+		val, hit, err := rdc.Get(ctx, key)
+		return val, info, hit, err
+	}
+
+	var (
+		get *redis.StringCmd
+		ttl *redis.DurationCmd
+	)
+	_, err := rdc.Redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key.String())
+		ttl = pipe.PTTL(ctx, key.String())
+		return nil
+	})
+	switch err {
+	case nil: // cache hit
+	case redis.Nil: // cache miss
+		return nil, info, false, nil
+	default: // error
+		return nil, info, false, err
+	}
+
+	val, err := get.Bytes()
+	if err != nil {
+		return nil, info, false, err
+	}
+
+	// a negative ttl is a key without expiration, i.e. set before the cache had a ttl
+	if remaining := ttl.Val(); remaining >= 0 {
+		info.Expires = time.Now().Add(remaining - rdc.StaleWhileRevalidate)
+		info.Modified = info.Expires.Add(-rdc.Expiration)
+		info.Stale = remaining < rdc.StaleWhileRevalidate
+	}
+
+	return val, info, true, nil
+}
+
 func (rdc *RedisCache) Purge(ctx context.Context, key *cache.Key) (err error) {
 	return rdc.Redis.Del(ctx, key.String()).Err()
 }
+
+// scanCount is the number of keys asked to redis for each SCAN of PurgePrefix
+const scanCount = 1000
+
+// PurgePrefix unlinks the tiles of the map, or of the layer of the map,
+// between minZoom and maxZoom.
+func (rdc *RedisCache) PurgePrefix(ctx context.Context, mapName, layerName string, minZoom, maxZoom uint) error {
+	return rdc.purgeMatching(ctx, mapName, func(key *cache.Key) bool {
+		return key.MatchesPrefix(mapName, layerName, minZoom, maxZoom)
+	})
+}
+
+// PurgeVersions unlinks the tiles of the map which are not of keepVersion.
+func (rdc *RedisCache) PurgeVersions(ctx context.Context, mapName, keepVersion string) error {
+	return rdc.purgeMatching(ctx, mapName, func(key *cache.Key) bool {
+		return key.Version != keepVersion
+	})
+}
+
+// purgeMatching scans the keys of the map and unlinks the tiles matching match.
+func (rdc *RedisCache) purgeMatching(ctx context.Context, mapName string, match func(key *cache.Key) bool) error {
+	pattern := globEscaper.Replace(mapName) + "/*"
+
+	var cursor uint64
+	for {
+		keys, next, err := rdc.Redis.Scan(ctx, cursor, pattern, scanCount).Result()
+		if err != nil {
+			return err
+		}
+
+		purge := keys[:0]
+		for _, k := range keys {
+			key, err := cache.ParseKey(k)
+			if err != nil || !match(key) {
+				continue
+			}
+			purge = append(purge, k)
+		}
+		if len(purge) > 0 {
+			if err := rdc.Redis.Unlink(ctx, purge...).Err(); err != nil {
+				return err
+			}
+		}
+
+		if cursor = next; cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// globEscaper escapes the special characters of the redis glob patterns
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"syscall"
 	"testing"
+	"time"
 
 	goredis "github.com/redis/go-redis/v9"
 
@@ -623,3 +624,66 @@ func TestMaxZoom(t *testing.T) {
 		t.Run(name, fn(tc))
 	}
 }
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	ttools.ShouldSkip(t, TESTENV)
+
+	ctx := context.Background()
+	type tcase struct {
+		remaining time.Duration
+		stale     bool
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			c, err := redis.New(dict.Dict{
+				"ttl":                    60,
+				"stale_while_revalidate": 60,
+			})
+			if err != nil {
+				t.Fatalf("unexpected err, expected %v got %v", nil, err)
+			}
+			rc := c.(*redis.RedisCache)
+
+			key := cache.Key{MapName: "swr", Z: 1, X: 2, Y: 3}
+			if err = rc.Set(ctx, &key, []byte("tile")); err != nil {
+				t.Fatalf("unexpected err, expected %v got %v", nil, err)
+			}
+			defer rc.Purge(ctx, &key) //nolint:errcheck
+
+			// age the tile
+			if err = rc.Redis.PExpire(ctx, key.String(), tc.remaining).Err(); err != nil {
+				t.Fatalf("unexpected err, expected %v got %v", nil, err)
+			}
+
+			_, info, hit, err := rc.GetInfo(ctx, &key)
+			if err != nil {
+				t.Fatalf("unexpected err, expected %v got %v", nil, err)
+			}
+			if !hit {
+				t.Fatalf("get info, expected hit got miss")
+			}
+			if info.Stale != tc.stale {
+				t.Errorf("stale, expected %v got %v", tc.stale, info.Stale)
+			}
+
+			if _, hit, _ = rc.Get(ctx, &key); hit == tc.stale {
+				t.Errorf("get hit, expected %v got %v", !tc.stale, hit)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"fresh": {
+			remaining: 90 * time.Second,
+		},
+		"stale": {
+			remaining: 30 * time.Second,
+			stale:     true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
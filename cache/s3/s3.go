@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -389,3 +390,64 @@ func (s3c *Cache) Purge(ctx context.Context, key *cache.Key) error {
 
 	return nil
 }
+
+// PurgePrefix deletes the tiles of the map, or of the layer of the map,
+// between minZoom and maxZoom.
+func (s3c *Cache) PurgePrefix(ctx context.Context, mapName, layerName string, minZoom, maxZoom uint) error {
+	return s3c.purgeMatching(ctx, mapName, func(key *cache.Key) bool {
+		return key.MatchesPrefix(mapName, layerName, minZoom, maxZoom)
+	})
+}
+
+// PurgeVersions deletes the tiles of the map which are not of keepVersion.
+func (s3c *Cache) PurgeVersions(ctx context.Context, mapName, keepVersion string) error {
+	return s3c.purgeMatching(ctx, mapName, func(key *cache.Key) bool {
+		return key.Version != keepVersion
+	})
+}
+
+// purgeMatching lists the objects under the prefix of the map and deletes the
+// tiles matching match. The objects are deleted in batches of a listed page.
+func (s3c *Cache) purgeMatching(ctx context.Context, mapName string, match func(key *cache.Key) bool) error {
+	input := s3.ListObjectsV2Input{
+		Bucket: aws.String(s3c.Bucket),
+		Prefix: aws.String(filepath.Join(s3c.Basepath, mapName) + "/"),
+	}
+
+	var delErr error
+	err := s3c.Client.ListObjectsV2PagesWithContext(ctx, &input, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		var objects []*s3.ObjectIdentifier
+		for _, obj := range page.Contents {
+			key, err := cache.ParseKey(strings.TrimPrefix(aws.StringValue(obj.Key), s3c.Basepath))
+			if err != nil || !match(key) {
+				continue
+			}
+			objects = append(objects, &s3.ObjectIdentifier{Key: obj.Key})
+		}
+		if len(objects) == 0 {
+			return true
+		}
+
+		out, err := s3c.Client.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(s3c.Bucket),
+			Delete: &s3.Delete{
+				Objects: objects,
+				Quiet:   aws.Bool(true),
+			},
+		})
+		if err != nil {
+			delErr = err
+			return false
+		}
+		if len(out.Errors) > 0 {
+			delErr = fmt.Errorf("s3cache: deleting (%v): %v", aws.StringValue(out.Errors[0].Key), aws.StringValue(out.Errors[0].Message))
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	return delErr
+}
@@ -347,3 +347,47 @@ func TestMaxZoom(t *testing.T) {
 		t.Run(name, fn(tc))
 	}
 }
+
+func TestPurgePrefix(t *testing.T) {
+	skipS3Tests(t)
+
+	ctx := context.Background()
+	keys := []cache.Key{
+		{MapName: "test-purge-prefix", Z: 3, X: 1, Y: 1},
+		{MapName: "test-purge-prefix", LayerName: "roads", Z: 3, X: 1, Y: 1},
+		{MapName: "test-purge-prefix", LayerName: "roads", Z: 10, X: 1, Y: 1},
+		{MapName: "test-purge-prefix", ParamsHash: "0123abcd", LayerName: "roads", Z: 3, X: 1, Y: 1},
+	}
+
+	c, err := s3.New(dict.Dict{
+		"bucket":   os.Getenv("AWS_TEST_BUCKET"),
+		"basepath": "cache",
+	})
+	if err != nil {
+		t.Fatalf("new, expected nil got %v", err)
+	}
+	for _, key := range keys {
+		if err := c.Set(ctx, &key, testData); err != nil {
+			t.Fatalf("set, expected nil got %v", err)
+		}
+	}
+
+	if err := c.(cache.PrefixPurger).PurgePrefix(ctx, "test-purge-prefix", "roads", 0, 5); err != nil {
+		t.Fatalf("purge prefix, expected nil got %v", err)
+	}
+
+	for _, key := range keys {
+		_, hit, err := c.Get(ctx, &key)
+		if err != nil {
+			t.Fatalf("get, expected nil got %v", err)
+		}
+		if purged := key.MatchesPrefix("test-purge-prefix", "roads", 0, 5); hit == purged {
+			t.Errorf("key %v, expected hit %v got %v", key, !purged, hit)
+		}
+	}
+
+	// clean up
+	if err := c.(cache.PrefixPurger).PurgePrefix(ctx, "test-purge-prefix", "", 0, 20); err != nil {
+		t.Fatalf("purge prefix, expected nil got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package register
+
+import (
+	"fmt"
+
+	"github.com/go-spatial/tegola/auth"
+	_ "github.com/go-spatial/tegola/auth/apikey"
+	_ "github.com/go-spatial/tegola/auth/jwt"
+	_ "github.com/go-spatial/tegola/auth/signedurl"
+	"github.com/go-spatial/tegola/dict"
+)
+
+// ErrAuthTypeMissing is returned when an authenticator has no 'type' or its type is not a string
+type ErrAuthTypeMissing struct {
+	// Index of the authenticator in the config
+	Index int
+}
+
+func (e ErrAuthTypeMissing) Error() string {
+	return fmt.Sprintf("register: webserver auth (%v) 'type' parameter missing", e.Index)
+}
+
+// Authenticators registers the authenticators of the webserver, in order
+func Authenticators(configs []dict.Dicter) ([]auth.Authenticator, error) {
+	authenticators := make([]auth.Authenticator, 0, len(configs))
+	for i, config := range configs {
+		aType, err := config.String("type", nil)
+		if err != nil {
+			return nil, ErrAuthTypeMissing{Index: i}
+		}
+
+		a, err := auth.For(aType, config)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, a)
+	}
+
+	return authenticators, nil
+}
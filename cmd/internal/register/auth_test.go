@@ -0,0 +1,65 @@
+package register_test
+
+import (
+	"testing"
+
+	"github.com/go-spatial/tegola/cmd/internal/register"
+	"github.com/go-spatial/tegola/dict"
+)
+
+func TestAuthenticators(t *testing.T) {
+	type tcase struct {
+		configs     []dict.Dicter
+		expected    int
+		expectedErr error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			authenticators, err := register.Authenticators(tc.configs)
+			if tc.expectedErr != nil {
+				if err == nil || err.Error() != tc.expectedErr.Error() {
+					t.Errorf("invalid error. expected: %v, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected err: %v", err)
+				return
+			}
+			if len(authenticators) != tc.expected {
+				t.Errorf("authenticators, expected %v got %v", tc.expected, len(authenticators))
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"none": {},
+		"api key and signed url": {
+			configs: []dict.Dicter{
+				dict.Dict{
+					"type": "api_key",
+					"keys": []map[string]interface{}{
+						{"key": "secret", "principal": "acme"},
+					},
+				},
+				dict.Dict{
+					"type":   "signed_url",
+					"secret": "secret",
+				},
+			},
+			expected: 2,
+		},
+		"missing type": {
+			configs: []dict.Dicter{
+				dict.Dict{"type": "signed_url", "secret": "secret"},
+				dict.Dict{},
+			},
+			expectedErr: register.ErrAuthTypeMissing{Index: 1},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
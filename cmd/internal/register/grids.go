@@ -0,0 +1,21 @@
+package register
+
+import (
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/grid"
+)
+
+// TileMatrixSets registers the custom tile grids maps can reference
+func TileMatrixSets(sets []dict.Dicter) error {
+	for _, s := range sets {
+		g, err := grid.NewGrid(s)
+		if err != nil {
+			return err
+		}
+
+		if err = grid.Register(g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/go-spatial/geom"
 	"github.com/go-spatial/tegola/atlas"
 	"github.com/go-spatial/tegola/config"
+	"github.com/go-spatial/tegola/grid"
 	"github.com/go-spatial/tegola/provider"
 )
 
@@ -15,6 +16,13 @@ func webMercatorMapFromConfigMap(cfg provider.Map) (newMap atlas.Map) {
 	newMap = atlas.NewWebMercatorMap(string(cfg.Name))
 	newMap.Attribution = SanitizeAttribution(string(cfg.Attribution))
 	newMap.Params = cfg.Parameters
+	newMap.CacheVersion = string(cfg.CacheVersion)
+	for _, p := range cfg.Principals {
+		newMap.Principals = append(newMap.Principals, string(p))
+	}
+	for _, s := range cfg.Scopes {
+		newMap.Scopes = append(newMap.Scopes, string(s))
+	}
 
 	// convert from env package
 	for i, v := range cfg.Center {
@@ -134,6 +142,14 @@ func Maps(a *atlas.Atlas, maps []provider.Map, providers map[string]provider.Til
 	for _, m := range maps {
 		newMap := webMercatorMapFromConfigMap(m)
 
+		if tms := string(m.TileMatrixSet); tms != "" {
+			g, err := grid.For(tms)
+			if err != nil {
+				return err
+			}
+			newMap.SetGrid(g)
+		}
+
 		// iterate our layers
 		for _, l := range m.Layers {
 			providerName, _, err := l.ProviderLayerName()
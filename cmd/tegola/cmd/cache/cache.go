@@ -26,7 +26,8 @@ var Cmd = &cobra.Command{
 	Short: "command to manage the cache",
 	Long:  "command to manage the cache",
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		RequireCache = true
+		// seeding or purging an MBTiles file does not need a configured cache
+		RequireCache = cacheMBTiles == ""
 		if cmd.HasParent() {
 			// run the parents Persistent Run commands.
 			pcmd := cmd.Parent()
@@ -42,6 +43,8 @@ var Cmd = &cobra.Command{
 
 func init() {
 	Cmd.AddCommand(SeedPurgeCmd)
+	Cmd.AddCommand(ExportCmd)
+	Cmd.AddCommand(GCCmd)
 	Cmd.SetUsageTemplate(`Usage: {{.CommandPath}} [command]{{if .HasExample}}
 
 Examples:
@@ -49,7 +52,9 @@ Examples:
 
 Available Commands:
   {{rpad "seed" .NamePadding}} seed tiles to the cache
-  {{rpad "purge" .NamePadding}} purge tiles from the cache{{if .HasAvailableLocalFlags}}
+  {{rpad "purge" .NamePadding}} purge tiles from the cache
+  {{rpad "export" .NamePadding}} export the tiles of a map to a tile archive
+  {{rpad "gc" .NamePadding}} remove the tiles of the previous cache versions of the maps{{if .HasAvailableLocalFlags}}
 
 Flags:
 {{.LocalFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasAvailableInheritedFlags}}
@@ -160,20 +165,10 @@ func doWork(ctx context.Context, tileChannel *TileChannel, maps []atlas.Map, con
 		}(i)
 	}
 
-	nonParamMaps := make([]atlas.Map, 0)
-
-	for _, m := range maps {
-		// we don't support caching for maps with custom parameters
-		if m.Params != nil || len(m.Params) > 0 {
-			log.Warnf("caching is disabled for map %s as it has custom parameters configures", m.Name)
-		}
-		nonParamMaps = append(nonParamMaps, m)
-	}
-
 	// run through the incoming tiles, and generate the mapTiles as needed.
 TileChannelLoop:
 	for tile := range tileChannel.Channel() {
-		for _, m := range nonParamMaps {
+		for _, m := range maps {
 			if ctx.Err() != nil {
 				cleanup = true
 				break
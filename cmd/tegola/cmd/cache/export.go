@@ -0,0 +1,252 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/go-spatial/cobra"
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/slippy"
+	"github.com/go-spatial/proj"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/internal/build"
+	gdcmd "github.com/go-spatial/tegola/internal/cmd"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/internal/pmtiles"
+	"github.com/go-spatial/tegola/observability"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// ExportFormatPMTiles is the format for exporting to a PMTiles archive
+const ExportFormatPMTiles = "pmtiles"
+
+// flag parameters
+var (
+	// exportFormat is the format of the export
+	exportFormat string
+	// exportOutput is the path of the file to export to
+	exportOutput string
+	// exportMap is the name of the map to export
+	exportMap string
+	// exportBounds is the lng/lat bounds to export. defaults to the bounds of the map
+	exportBounds string
+	// exportConcurrency is the amount of concurrency to use. defaults to the number of CPUs on the machine
+	exportConcurrency int
+)
+
+var ExportCmd = &cobra.Command{
+	Use:     "export",
+	Short:   "export the tiles of a map to a tile archive",
+	Long:    "command to render the tiles of a map into a single tile archive. a cache does not need to be configured",
+	Example: "tegola cache export --map osm --format pmtiles --output osm.pmtiles --max-zoom 10",
+}
+
+func init() {
+	setupMinMaxZoomFlags(ExportCmd, 0, atlas.MaxZoom)
+	ExportCmd.Flags().StringVarP(&exportFormat, "format", "", ExportFormatPMTiles, "the format of the archive. supported formats: pmtiles")
+	ExportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "path to write the archive to. defaults to <map name>.<format>")
+	ExportCmd.Flags().StringVarP(&exportMap, "map", "", "", "map name as defined in the config")
+	ExportCmd.Flags().StringVarP(&exportBounds, "bounds", "", "", "lng/lat bounds to export in the format: minx, miny, maxx, maxy. defaults to the map bounds")
+	ExportCmd.Flags().IntVarP(&exportConcurrency, "concurrency", "", runtime.NumCPU(), "the amount of concurrency to use. defaults to the number of CPUs on the machine")
+
+	ExportCmd.PersistentPreRunE = exportCmdValidatePersistent
+	ExportCmd.PreRunE = exportCmdValidate
+	ExportCmd.RunE = exportCommand
+
+	ExportCmd.SetUsageTemplate(defaultUsage)
+}
+
+// exportCmdValidatePersistent skips the cache command's persistent pre run,
+// as exporting renders tiles straight from the providers and does not need a cache.
+func exportCmdValidatePersistent(cmd *cobra.Command, args []string) error {
+	root := cmd.Root()
+	if root != cmd && root.PersistentPreRunE != nil {
+		if err := root.PersistentPreRunE(cmd, args); err != nil {
+			return err
+		}
+	}
+
+	build.Commands = append(build.Commands, "cache", "export")
+	return nil
+}
+
+func exportCmdValidate(cmd *cobra.Command, args []string) (err error) {
+	exportFormat = strings.ToLower(strings.TrimSpace(exportFormat))
+	if exportFormat != ExportFormatPMTiles {
+		return fmt.Errorf("unsupported export format (%v). supported formats: %v", exportFormat, ExportFormatPMTiles)
+	}
+
+	if exportMap == "" {
+		return errors.New("a map must be provided with --map")
+	}
+
+	if exportOutput == "" {
+		exportOutput = exportMap + "." + exportFormat
+	}
+
+	if exportBounds != "" {
+		if _, err = parseBounds(exportBounds); err != nil {
+			return err
+		}
+	}
+
+	if exportConcurrency < 1 {
+		return fmt.Errorf("invalid concurrency (%v). must be at least 1", exportConcurrency)
+	}
+
+	// get the zoom ranges
+	return minMaxZoomValidate(cmd, args)
+}
+
+func exportCommand(_ *cobra.Command, _ []string) (err error) {
+	m, err := atlas.GetMap(exportMap)
+	if err != nil {
+		return err
+	}
+
+	bounds := m.Bounds
+	if exportBounds != "" {
+		b, err := parseBounds(exportBounds)
+		if err != nil {
+			return err
+		}
+		bounds = geom.NewExtent([2]float64{b[0], b[1]}, [2]float64{b[2], b[3]})
+	}
+	if bounds == nil {
+		return fmt.Errorf("map (%v) has no bounds. provide bounds with --bounds", m.Name)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer gdcmd.New().Complete()
+	gdcmd.OnComplete(provider.Cleanup)
+	gdcmd.OnComplete(observability.Cleanup)
+	atlas.StartSubProcesses()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-gdcmd.Cancelled():
+			cancel()
+		}
+	}()
+
+	// stage the tile data next to the output so the final write is a rename
+	w, err := pmtiles.NewWriter(filepath.Dir(exportOutput))
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	w.Bounds = bounds
+	if m.Center != [3]float64{} {
+		center := m.Center
+		w.Center = &center
+	}
+	w.Metadata = exportMetadata(m, zooms)
+
+	log.Infof("exporting map (%v) zooms %v to %v", m.Name, zooms, exportOutput)
+
+	grid := slippy.NewGrid(proj.EPSG4326, 0)
+	tileChannel := generateTilesForBounds(ctx, [4]float64(*bounds), zooms, grid)
+
+	if err = doWork(ctx, tileChannel, []atlas.Map{m}, exportConcurrency, exportWorker(w)); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		log.Info("export cancelled, not writing archive")
+		return nil
+	}
+
+	log.Infof("writing %v tiles to %v", w.Len(), exportOutput)
+	return w.WriteFile(exportOutput)
+}
+
+// exportWorker renders the map tiles and adds them to the archive writer
+func exportWorker(w *pmtiles.Writer) func(ctx context.Context, mt MapTile) error {
+	return func(ctx context.Context, mt MapTile) error {
+		m, err := atlas.GetMap(mt.MapName)
+		if err != nil {
+			return err
+		}
+
+		z, x, y := mt.Tile.ZXY()
+
+		// filter down the layers we need for this zoom
+		m = m.FilterLayersByZoom(z)
+		if len(m.Layers) == 0 {
+			return nil
+		}
+
+		b, err := m.Encode(ctx, mt.Tile, nil)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return err
+			}
+			return fmt.Errorf("error exporting tile (%v/%v/%v): %w", z, x, y, err)
+		}
+
+		log.Debugf("exported map (%v) tile (%v/%v/%v)", mt.MapName, z, x, y)
+
+		return w.Add(pmtiles.TileID(uint(z), x, y), b)
+	}
+}
+
+// exportMetadata builds the archive metadata for the map, following the
+// TileJSON fields used by PMTiles readers
+func exportMetadata(m atlas.Map, zooms []uint) map[string]interface{} {
+	md := map[string]interface{}{
+		"name":          m.Name,
+		"format":        "pbf",
+		"type":          "overlay",
+		"generator":     "tegola " + build.Version,
+		"vector_layers": vectorLayers(m),
+	}
+	if m.Attribution != "" {
+		md["attribution"] = m.Attribution
+	}
+	if len(zooms) > 0 {
+		md["minzoom"] = zooms[0]
+		md["maxzoom"] = zooms[len(zooms)-1]
+	}
+
+	return md
+}
+
+// vectorLayers returns the TileJSON vector_layers entries of the map. Map layers
+// that are encoded under the same name are reported as a single layer covering
+// the zoom range of all of them.
+func vectorLayers(m atlas.Map) []map[string]interface{} {
+	var (
+		layers []map[string]interface{}
+		byName = make(map[string]map[string]interface{})
+	)
+	for _, l := range m.Layers {
+		name := l.MVTName()
+		if vl, ok := byName[name]; ok {
+			if l.MinZoom < vl["minzoom"].(uint) {
+				vl["minzoom"] = l.MinZoom
+			}
+			if l.MaxZoom > vl["maxzoom"].(uint) {
+				vl["maxzoom"] = l.MaxZoom
+			}
+			continue
+		}
+
+		vl := map[string]interface{}{
+			"id":      name,
+			"minzoom": l.MinZoom,
+			"maxzoom": l.MaxZoom,
+			"fields":  map[string]interface{}{},
+		}
+		byName[name] = vl
+		layers = append(layers, vl)
+	}
+
+	return layers
+}
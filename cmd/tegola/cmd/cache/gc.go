@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-spatial/cobra"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/internal/build"
+	gdcmd "github.com/go-spatial/tegola/internal/cmd"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/observability"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// flag parameters
+var (
+	// gcMap is the name of the map to remove the previous versions of
+	gcMap string
+	// gcCacheVersion is the cache version to keep in place of the configured one
+	gcCacheVersion string
+)
+
+var GCCmd = &cobra.Command{
+	Use:     "gc",
+	Short:   "remove the tiles of the previous cache versions of the maps",
+	Long:    "command to remove the cached tiles of the maps which are not of the cache_version of the maps, i.e. the tiles of the previous data releases. the tiles of the maps without a cache_version are kept by maps without one and removed by maps with one",
+	Example: "tegola cache gc --map osm",
+	PreRunE: gcCmdValidate,
+	RunE:    gcCommand,
+}
+
+func init() {
+	GCCmd.Flags().StringVarP(&gcMap, "map", "", "", "map name as defined in the config. defaults to all the maps")
+	GCCmd.Flags().StringVarP(&gcCacheVersion, "cache-version", "", "", "cache version of the tiles to keep instead of the cache_version of the maps")
+
+	GCCmd.SetUsageTemplate(defaultUsage)
+}
+
+func gcCmdValidate(cmd *cobra.Command, args []string) error {
+	if gcCacheVersion != "" && !cache.IsValidVersion(gcCacheVersion) {
+		return fmt.Errorf("invalid cache version (%v). versions are made of letters, digits, '.', '_' and '-' and don't start with '.' or '-'", gcCacheVersion)
+	}
+
+	build.Commands = append(build.Commands, "cache", "gc")
+	return nil
+}
+
+func gcCommand(_ *cobra.Command, _ []string) error {
+	maps := atlas.AllMaps()
+	if gcMap != "" {
+		m, err := atlas.GetMap(gcMap)
+		if err != nil {
+			return err
+		}
+		maps = []atlas.Map{m}
+	}
+	if len(maps) == 0 {
+		return fmt.Errorf("expected at least one map to be defined. check your config")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer gdcmd.New().Complete()
+	gdcmd.OnComplete(provider.Cleanup)
+	gdcmd.OnComplete(observability.Cleanup)
+	gdcmd.OnComplete(cache.Cleanup)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-gdcmd.Cancelled():
+			cancel()
+		}
+	}()
+
+	return purgeVersions(ctx, maps, gcCacheVersion)
+}
+
+// purgeVersions removes the tiles of the cache versions of the maps other than
+// keepVersion, or than the cache version of each map if keepVersion is empty
+func purgeVersions(ctx context.Context, maps []atlas.Map, keepVersion string) error {
+	for _, m := range maps {
+		if keepVersion != "" {
+			m.CacheVersion = keepVersion
+		}
+
+		log.Infof("removing the tiles of map (%v) not of cache version (%v)", m.Name, m.CacheVersion)
+
+		if err := atlas.PurgeMapVersions(ctx, m); err != nil {
+			if errors.Is(err, cache.ErrPurgeVersionsNotSupported) {
+				return fmt.Errorf("%w, purge the tiles of the previous versions with the tools of the cache backend instead", err)
+			}
+			return fmt.Errorf("error removing the previous versions of map (%v): %w", m.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/geojson"
+	"github.com/go-spatial/geom/encoding/wkt"
+	"github.com/go-spatial/geom/slippy"
+	"github.com/go-spatial/proj"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/basic"
+	"github.com/go-spatial/tegola/grid"
+)
+
+// maxWebMercatorLat is the latitude at which the WebMercatorQuad grid ends
+const maxWebMercatorLat = 85.0511
+
+// readGeometryFile reads the polygons of a GeoJSON or WKT file. GeoJSON may
+// be a Polygon, MultiPolygon or a Feature or FeatureCollection of them. WKT
+// may be a POLYGON, MULTIPOLYGON or GEOMETRYCOLLECTION of them.
+func readGeometryFile(filename string) ([]geom.Polygon, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	polygons, err := parseGeometry(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid geometry file (%v): %w", filename, err)
+	}
+	return polygons, nil
+}
+
+// parseGeometry parses the polygons of a GeoJSON or WKT document
+func parseGeometry(b []byte) ([]geom.Polygon, error) {
+	b = bytes.TrimSpace(b)
+
+	var g geom.Geometry
+	if bytes.HasPrefix(b, []byte("{")) {
+		var gj geojson.Geometry
+		if err := json.Unmarshal(b, &gj); err != nil {
+			return nil, err
+		}
+		g = gj.Geometry
+	} else {
+		var err error
+		if g, err = wkt.DecodeBytes(b); err != nil {
+			return nil, err
+		}
+	}
+
+	polygons, err := grid.Polygons(g)
+	if err != nil {
+		return nil, err
+	}
+	if len(polygons) == 0 {
+		return nil, fmt.Errorf("geometry has no polygons")
+	}
+	return polygons, nil
+}
+
+// seedPurgeGeometryGrid returns the grid the tiles of the maps are generated
+// in, with the polygons reprojected from srid to the coordinates of the grid.
+// All maps need to share the same grid.
+func seedPurgeGeometryGrid(maps []atlas.Map, polygons []geom.Polygon, srid proj.EPSGCode) (*grid.Grid, []geom.Polygon, error) {
+	g := maps[0].Grid()
+	for _, m := range maps[1:] {
+		if m.Grid().ID != g.ID {
+			return nil, nil, fmt.Errorf("maps (%v) and (%v) use different tile matrix sets, they need to be seeded separately", maps[0].Name, m.Name)
+		}
+	}
+
+	reprojected := make([]geom.Polygon, 0, len(polygons))
+	for _, poly := range polygons {
+		if srid == proj.EPSG4326 && g.SRID() == proj.WebMercator {
+			// the poles can't be projected to web mercator
+			poly = clampLat(poly, maxWebMercatorLat)
+		}
+
+		rp, err := basic.Reproject(uint64(srid), uint64(g.SRID()), poly)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to convert geometry to the tile matrix set (%v): %w", g, err)
+		}
+		reprojected = append(reprojected, rp.(geom.Polygon))
+	}
+
+	return g, reprojected, nil
+}
+
+// clampLat returns a copy of the polygon with its latitudes limited to +/- maxLat
+func clampLat(poly geom.Polygon, maxLat float64) geom.Polygon {
+	clamped := make(geom.Polygon, len(poly))
+	for i, ring := range poly {
+		clamped[i] = make([][2]float64, len(ring))
+		for j, pt := range ring {
+			clamped[i][j] = [2]float64{pt[0], min(max(pt[1], -maxLat), maxLat)}
+		}
+	}
+	return clamped
+}
+
+// generateTilesForGeometry will return a channel where the tiles intersecting
+// the polygons at each of the zooms will be published. The polygons are in the
+// coordinates of the grid.
+func generateTilesForGeometry(ctx context.Context, polygons []geom.Polygon, zooms []uint, g *grid.Grid) *TileChannel {
+	tce := &TileChannel{
+		channel: make(chan slippy.Tile),
+	}
+
+	go func() {
+		defer tce.Close()
+
+		for _, z := range zooms {
+			err := g.Cover(slippy.Zoom(z), polygons, func(tile slippy.Tile) error {
+				select {
+				case tce.channel <- tile:
+					return nil
+				case <-ctx.Done():
+					// we have been cancelled
+					return ctx.Err()
+				}
+			})
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				tce.setError(fmt.Errorf("got error trying to get tiles: %w", err))
+				return
+			}
+		}
+	}()
+	return tce
+}
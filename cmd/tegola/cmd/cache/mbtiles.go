@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/internal/mbtiles"
+)
+
+// writeMBTilesMetadata fills the metadata table of the tile set from the map.
+// If bounds is nil the bounds of the map are used. The zoom range is read from
+// the tiles in the tile set, so it stays correct over multiple seed and purge runs.
+func writeMBTilesMetadata(ctx context.Context, db *mbtiles.DB, m atlas.Map, bounds *geom.Extent) error {
+	if bounds == nil {
+		bounds = m.Bounds
+	}
+	if bounds == nil {
+		bounds = tegola.WGS84Bounds
+	}
+
+	md, err := mbtilesMetadata(m, bounds)
+	if err != nil {
+		return err
+	}
+
+	minZoom, maxZoom, ok, err := db.ZoomRange(ctx)
+	if err != nil {
+		return err
+	}
+	if ok {
+		md["minzoom"] = strconv.FormatUint(uint64(minZoom), 10)
+		md["maxzoom"] = strconv.FormatUint(uint64(maxZoom), 10)
+		if m.Center == [3]float64{} {
+			md["center"] = formatFloats(
+				bounds.MinX()+bounds.XSpan()/2,
+				bounds.MinY()+bounds.YSpan()/2,
+				float64(minZoom),
+			)
+		}
+	}
+
+	return db.SetMetadata(ctx, md)
+}
+
+// mbtilesMetadata returns the metadata table rows for the map covering bounds
+// as described by the MBTiles 1.3 spec
+func mbtilesMetadata(m atlas.Map, bounds *geom.Extent) (map[string]string, error) {
+	layersJSON, err := json.Marshal(map[string]interface{}{
+		"vector_layers": vectorLayers(m),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding vector_layers for map (%v): %w", m.Name, err)
+	}
+
+	md := map[string]string{
+		"name":   m.Name,
+		"format": "pbf",
+		"type":   "overlay",
+		"bounds": formatFloats(bounds.MinX(), bounds.MinY(), bounds.MaxX(), bounds.MaxY()),
+		"json":   string(layersJSON),
+	}
+	if m.Center != [3]float64{} {
+		md["center"] = formatFloats(m.Center[:]...)
+	}
+	if m.Attribution != "" {
+		md["attribution"] = m.Attribution
+	}
+
+	return md, nil
+}
+
+// formatFloats formats the values as a comma separated list
+func formatFloats(vals ...float64) string {
+	strs := make([]string, len(vals))
+	for i, v := range vals {
+		strs[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strings.Join(strs, ",")
+}
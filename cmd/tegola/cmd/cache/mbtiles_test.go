@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola/atlas"
+)
+
+func TestMBTilesMetadata(t *testing.T) {
+	type tcase struct {
+		m        atlas.Map
+		bounds   *geom.Extent
+		expected map[string]string
+		// expectedLayers is the decoded vector_layers of the json entry
+		expectedLayers []map[string]interface{}
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			md, err := mbtilesMetadata(tc.m, tc.bounds)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var layers struct {
+				VectorLayers []map[string]interface{} `json:"vector_layers"`
+			}
+			if err = json.Unmarshal([]byte(md["json"]), &layers); err != nil {
+				t.Fatalf("unexpected error decoding json entry: %v", err)
+			}
+			if !reflect.DeepEqual(layers.VectorLayers, tc.expectedLayers) {
+				t.Errorf("vector_layers, expected %v got %v", tc.expectedLayers, layers.VectorLayers)
+			}
+
+			delete(md, "json")
+			if !reflect.DeepEqual(md, tc.expected) {
+				t.Errorf("expected %v got %v", tc.expected, md)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"map fields": {
+			m: atlas.Map{
+				Name:        "osm",
+				Attribution: "OpenStreetMap",
+				Center:      [3]float64{-122.4, 37.7, 10},
+				Layers: []atlas.Layer{
+					{Name: "water", ProviderLayerName: "water_low", MinZoom: 0, MaxZoom: 6},
+					{Name: "water", ProviderLayerName: "water_high", MinZoom: 7, MaxZoom: 14},
+					{ProviderLayerName: "roads", MinZoom: 10, MaxZoom: 20},
+				},
+			},
+			bounds: geom.NewExtent([2]float64{-123, 37}, [2]float64{-122, 38.5}),
+			expected: map[string]string{
+				"name":        "osm",
+				"format":      "pbf",
+				"type":        "overlay",
+				"bounds":      "-123,37,-122,38.5",
+				"center":      "-122.4,37.7,10",
+				"attribution": "OpenStreetMap",
+			},
+			expectedLayers: []map[string]interface{}{
+				{"id": "water", "minzoom": float64(0), "maxzoom": float64(14), "fields": map[string]interface{}{}},
+				{"id": "roads", "minzoom": float64(10), "maxzoom": float64(20), "fields": map[string]interface{}{}},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
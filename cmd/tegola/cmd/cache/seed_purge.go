@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"runtime"
 	"strings"
 
@@ -12,9 +13,13 @@ import (
 	"github.com/go-spatial/geom/slippy"
 	"github.com/go-spatial/proj"
 	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/basic"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/grid"
 	"github.com/go-spatial/tegola/internal/build"
 	gdcmd "github.com/go-spatial/tegola/internal/cmd"
 	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/internal/mbtiles"
 	"github.com/go-spatial/tegola/observability"
 	"github.com/go-spatial/tegola/provider"
 )
@@ -55,13 +60,27 @@ var (
 	cacheMap string
 	// cacheLogThreshold is cache threshold while seeding, to log output for tiles that take longer than this (in milliseconds) to render
 	cacheLogThreshold int64
+	// cacheMBTiles is the path of an MBTiles file to seed or purge in place of the configured cache
+	cacheMBTiles string
+	// cacheParams are the query parameters of the maps, in the format name=value
+	cacheParams []string
+	// cacheGeometryFile is the path of a GeoJSON or WKT file with the polygons to seed or purge in place of the bounds
+	cacheGeometryFile string
+	// cacheAll purges all the tiles of the maps within the zoom range at once
+	cacheAll bool
+	// cacheLayer is the name of the layer to purge the tiles of with cacheAll
+	cacheLayer string
+	// cacheVersion is the cache version of the maps to seed or purge in place of the configured one
+	cacheVersion string
 )
 
 // variables that are not flags but set by the command.
 var (
-	seedPurgeWorker func(context.Context, MapTile) error
-	seedPurgeBounds [4]float64
-	seedPurgeMaps   []atlas.Map
+	seedPurgeWorker   func(context.Context, MapTile) error
+	seedPurgeBounds   [4]float64
+	seedPurgeGeometry []geom.Polygon
+	seedPurgeMaps     []atlas.Map
+	seedPurgeParams   url.Values
 )
 
 var SeedPurgeCmd = &cobra.Command{
@@ -69,7 +88,7 @@ var SeedPurgeCmd = &cobra.Command{
 	Aliases: []string{"purge"},
 	Short:   "seed or purge tiles from the cache",
 	Long:    "command to seed or purge tiles from the cache",
-	Example: "tegola cache seed --bounds lng,lat,lng,lat",
+	Example: "tegola cache seed --bounds lng,lat,lng,lat\n  tegola cache seed --geometry-file country.geojson\n  tegola cache purge --all --map osm --min-zoom 10",
 }
 
 func init() {
@@ -77,10 +96,16 @@ func init() {
 	SeedPurgeCmd.PersistentFlags().StringVarP(&cacheMap, "map", "", "", "map name as defined in the config")
 	SeedPurgeCmd.PersistentFlags().IntVarP(&cacheConcurrency, "concurrency", "", runtime.NumCPU(), "the amount of concurrency to use. defaults to the number of CPUs on the machine")
 	SeedPurgeCmd.PersistentFlags().BoolVarP(&cacheOverwrite, "overwrite", "", false, "overwrite the cache if a tile already exists (default false)")
+	SeedPurgeCmd.PersistentFlags().StringArrayVarP(&cacheParams, "param", "", nil, "query parameter of the maps to seed or purge the tiles of, in the format name=value. can be repeated. the defaults of the maps are used for the missing params")
+	SeedPurgeCmd.PersistentFlags().StringVarP(&cacheVersion, "cache-version", "", "", "cache version of the maps to seed or purge the tiles of instead of the cache_version of the maps. used to seed the next version of the tiles while the current one is served")
 	SeedPurgeCmd.PersistentFlags().Int64VarP(&cacheLogThreshold, "log-threshold", "", 0, "during seeding, only log tiles that take this number of milliseconds or longer to render (default all tiles)")
 
 	SeedPurgeCmd.Flags().StringVarP(&cacheBounds, "bounds", "", "-180,-85.0511,180,85.0511", "lng/lat bounds to seed the cache with in the format: minx, miny, maxx, maxy")
 	SeedPurgeCmd.Flags().IntVarP(&cacheBoundsSRID, "bounds-srid", "", int(proj.EPSG4326), "the srid of the grid system for bounds.")
+	SeedPurgeCmd.Flags().StringVarP(&cacheGeometryFile, "geometry-file", "", "", "path of a GeoJSON or WKT file with the polygons to seed or purge instead of the bounds. only the tiles intersecting the polygons are used. the coordinates are in the bounds-srid")
+	SeedPurgeCmd.Flags().BoolVarP(&cacheAll, "all", "", false, "purge all the tiles of the maps within the zoom range at once, for all the query parameters of the maps. max-zoom defaults to the max zoom tegola supports. the cache backend needs to support purging by prefix")
+	SeedPurgeCmd.Flags().StringVarP(&cacheLayer, "layer", "", "", "with all, only purge the tiles of this layer and the tiles of the maps, which include the layer")
+	SeedPurgeCmd.Flags().StringVarP(&cacheMBTiles, "mbtiles", "", "", "path of an MBTiles file to seed or purge instead of the configured cache. the file is created if it does not exist")
 
 	SeedPurgeCmd.PersistentPreRunE = seedPurgeCmdValidatePersistent
 	SeedPurgeCmd.PreRunE = seedPurgeCmdValidate
@@ -120,6 +145,24 @@ func seedPurgeCmdValidatePersistent(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	var err error
+	if seedPurgeParams, err = parseParams(cacheParams, seedPurgeMaps); err != nil {
+		return err
+	}
+
+	if cacheVersion != "" {
+		if seedPurgeMaps, err = overrideCacheVersion(seedPurgeMaps, cacheVersion); err != nil {
+			return err
+		}
+	}
+
+	if cacheMBTiles != "" && len(seedPurgeMaps) != 1 {
+		return fmt.Errorf("an MBTiles file holds the tiles of a single map, select the map with --map")
+	}
+	if cacheMBTiles != "" && seedPurgeMaps[0].Grid().ID != grid.WebMercatorQuadID {
+		return fmt.Errorf("MBTiles only supports the %v tile matrix set, map (%v) uses %v", grid.WebMercatorQuadID, seedPurgeMaps[0].Name, seedPurgeMaps[0].Grid().ID)
+	}
+
 	// Find the seed command and find out what it was called as.
 	seedcmd := cmd
 	cmdName := ""
@@ -141,12 +184,64 @@ func seedPurgeCmdValidatePersistent(cmd *cobra.Command, args []string) error {
 
 		return fmt.Errorf("expected purge/seed got (%v) for command name", cmdName)
 	}
+	if cacheAll && cmdName != "purge" {
+		return fmt.Errorf("all can only be used to purge the cache")
+	}
+	// the tiles of maps with params are cached per params, purging tile by tile
+	// only purges the tiles of a single combination of values
+	if cmdName == "purge" && !cacheAll && len(cacheParams) == 0 {
+		for _, m := range seedPurgeMaps {
+			if m.HasCacheKeyParams() {
+				return fmt.Errorf("map (%v) caches its tiles per query parameter. select the tiles to purge with --param, or purge the tiles of all the params with --all", m.Name)
+			}
+		}
+	}
 	build.Commands = append(build.Commands, "cache", cmdName)
 
 	return nil
 
 }
 
+// overrideCacheVersion sets the cache version of the maps to version. The
+// workers look the maps up in the atlas, the maps are replaced there too.
+func overrideCacheVersion(maps []atlas.Map, version string) ([]atlas.Map, error) {
+	if !cache.IsValidVersion(version) {
+		return nil, fmt.Errorf("invalid cache version (%v). versions are made of letters, digits, '.', '_' and '-' and don't start with '.' or '-'", version)
+	}
+
+	for i := range maps {
+		maps[i].CacheVersion = version
+		atlas.AddMap(maps[i])
+	}
+	return maps, nil
+}
+
+// parseParams parses query parameters in the format name=value. Each param
+// needs to be declared by at least one of the maps.
+func parseParams(strs []string, maps []atlas.Map) (url.Values, error) {
+	values := url.Values{}
+	for _, str := range strs {
+		name, value, ok := strings.Cut(str, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid value for param (%v). expecting name=value", str)
+		}
+
+		declared := false
+		for _, m := range maps {
+			if m.HasParam(name) {
+				declared = true
+				break
+			}
+		}
+		if !declared {
+			return nil, fmt.Errorf("param (%v) is not declared by the maps", name)
+		}
+
+		values.Set(name, value)
+	}
+	return values, nil
+}
+
 func IsKnownSrcConversionSRID(code proj.EPSGCode) bool {
 	return code == proj.EPSG3395 ||
 		code == proj.WebMercator ||
@@ -175,24 +270,36 @@ func seedPurgeCmdValidate(cmd *cobra.Command, args []string) (err error) {
 	}
 
 	// validate and set bounds flag
-	boundsParts := strings.Split(strings.TrimSpace(cacheBounds), ",")
-	if len(boundsParts) != 4 {
-		return fmt.Errorf("invalid value for bounds (%v). expecting minx, miny, maxx, maxy", cacheBounds)
+	if seedPurgeBounds, err = parseBounds(cacheBounds); err != nil {
+		return err
 	}
 
-	var ok bool
-
-	if seedPurgeBounds[0], ok = IsValidLngString(boundsParts[0]); !ok {
-		return fmt.Errorf("invalid lng value(%v) for bounds (%v)", boundsParts[0], cacheBounds)
-	}
-	if seedPurgeBounds[1], ok = IsValidLatString(boundsParts[1]); !ok {
-		return fmt.Errorf("invalid lat value(%v) for bounds (%v)", boundsParts[1], cacheBounds)
-	}
-	if seedPurgeBounds[2], ok = IsValidLngString(boundsParts[2]); !ok {
-		return fmt.Errorf("invalid lng value(%v) for bounds (%v)", boundsParts[2], cacheBounds)
+	if cacheGeometryFile != "" {
+		if cmd.Flags().Changed("bounds") {
+			return fmt.Errorf("only one of bounds and geometry-file can be set")
+		}
+		if seedPurgeGeometry, err = readGeometryFile(cacheGeometryFile); err != nil {
+			return err
+		}
 	}
-	if seedPurgeBounds[3], ok = IsValidLatString(boundsParts[3]); !ok {
-		return fmt.Errorf("invalid lat value(%v) for bounds (%v)", boundsParts[3], cacheBounds)
+
+	if cacheAll {
+		for _, name := range []string{"bounds", "geometry-file", "param", "overwrite"} {
+			if cmd.Flags().Changed(name) {
+				return fmt.Errorf("%v can not be used with all, the tiles of the maps are purged for all the areas and query parameters", name)
+			}
+		}
+		if cacheLayer != "" {
+			if err = validateLayer(seedPurgeMaps, cacheLayer); err != nil {
+				return err
+			}
+		}
+		// all the cached zooms are purged unless limited
+		if !cmd.Flags().Changed("max-zoom") {
+			maxZoom = atlas.MaxZoom
+		}
+	} else if cacheLayer != "" {
+		return fmt.Errorf("layer can only be used with all")
 	}
 
 	// get the zoom ranges
@@ -203,6 +310,41 @@ func seedPurgeCmdValidate(cmd *cobra.Command, args []string) (err error) {
 	return nil
 }
 
+// validateLayer checks that at least one of the maps has a layer named layerName
+func validateLayer(maps []atlas.Map, layerName string) error {
+	for _, m := range maps {
+		if len(m.FilterLayersByName(layerName).Layers) > 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("layer (%v) is not a layer of the maps", layerName)
+}
+
+// parseBounds parses a lng/lat bounds string in the format: minx, miny, maxx, maxy
+func parseBounds(str string) (bounds [4]float64, err error) {
+	boundsParts := strings.Split(strings.TrimSpace(str), ",")
+	if len(boundsParts) != 4 {
+		return bounds, fmt.Errorf("invalid value for bounds (%v). expecting minx, miny, maxx, maxy", str)
+	}
+
+	var ok bool
+
+	if bounds[0], ok = IsValidLngString(boundsParts[0]); !ok {
+		return bounds, fmt.Errorf("invalid lng value(%v) for bounds (%v)", boundsParts[0], str)
+	}
+	if bounds[1], ok = IsValidLatString(boundsParts[1]); !ok {
+		return bounds, fmt.Errorf("invalid lat value(%v) for bounds (%v)", boundsParts[1], str)
+	}
+	if bounds[2], ok = IsValidLngString(boundsParts[2]); !ok {
+		return bounds, fmt.Errorf("invalid lng value(%v) for bounds (%v)", boundsParts[2], str)
+	}
+	if bounds[3], ok = IsValidLatString(boundsParts[3]); !ok {
+		return bounds, fmt.Errorf("invalid lat value(%v) for bounds (%v)", boundsParts[3], str)
+	}
+
+	return bounds, nil
+}
+
 func seedPurgeCommand(_ *cobra.Command, _ []string) (err error) {
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -210,6 +352,7 @@ func seedPurgeCommand(_ *cobra.Command, _ []string) (err error) {
 	defer gdcmd.New().Complete()
 	gdcmd.OnComplete(provider.Cleanup)
 	gdcmd.OnComplete(observability.Cleanup)
+	gdcmd.OnComplete(cache.Cleanup)
 	atlas.StartSubProcesses()
 
 	go func() {
@@ -221,14 +364,100 @@ func seedPurgeCommand(_ *cobra.Command, _ []string) (err error) {
 		}
 	}()
 
-	grid := slippy.NewGrid(proj.EPSGCode(cacheBoundsSRID), 0)
+	var mbtilesDB *mbtiles.DB
+	if cacheMBTiles != "" {
+		if mbtilesDB, err = mbtiles.Open(cacheMBTiles); err != nil {
+			return err
+		}
+		defer mbtilesDB.Close()
+
+		// the workers go through the atlas cache
+		atlas.SetCache(mbtilesDB)
+	}
+
+	if cacheAll {
+		err = purgeAll(ctx, seedPurgeMaps, cacheLayer, minZoom, maxZoom)
+	} else {
+		err = seedPurgeTiles(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	if mbtilesDB != nil {
+		var bounds *geom.Extent
+		switch {
+		case seedPurgeGeometry != nil:
+			for _, poly := range seedPurgeGeometry {
+				if bounds == nil {
+					bounds = geom.NewExtent(poly[0]...)
+					continue
+				}
+				bounds.AddPoints(poly[0]...)
+			}
+		case SeedPurgeCmd.Flags().Changed("bounds"):
+			bounds = geom.NewExtent([2]float64{seedPurgeBounds[0], seedPurgeBounds[1]}, [2]float64{seedPurgeBounds[2], seedPurgeBounds[3]})
+		}
+		return writeMBTilesMetadata(context.Background(), mbtilesDB, seedPurgeMaps[0], bounds)
+	}
+
+	return nil
+}
+
+// seedPurgeTiles seeds or purges the tiles of the maps within the bounds or
+// the geometry, one tile at a time
+func seedPurgeTiles(ctx context.Context) error {
+	var tileChannel *TileChannel
+	if seedPurgeGeometry != nil {
+		tileGrid, polygons, err := seedPurgeGeometryGrid(seedPurgeMaps, seedPurgeGeometry, proj.EPSGCode(cacheBoundsSRID))
+		if err != nil {
+			return err
+		}
+
+		log.Info("zoom list: ", zooms)
+		tileChannel = generateTilesForGeometry(ctx, polygons, zooms, tileGrid)
+	} else {
+		tileGrid, bounds, err := seedPurgeGrid(seedPurgeMaps, seedPurgeBounds, SeedPurgeCmd.Flags().Changed("bounds"))
+		if err != nil {
+			return err
+		}
 
-	log.Info("zoom list: ", zooms)
-	tileChannel := generateTilesForBounds(ctx, seedPurgeBounds, zooms, grid)
+		log.Info("zoom list: ", zooms)
+		tileChannel = generateTilesForBounds(ctx, bounds, zooms, tileGrid)
+	}
 
 	return doWork(ctx, tileChannel, seedPurgeMaps, cacheConcurrency, seedPurgeWorker)
 }
 
+// seedPurgeGrid returns the grid the tiles of the maps are generated in, with
+// the bounds in the coordinates of the grid. All maps need to share the same grid.
+// If tegola can't reproject the bounds to the grid, the grid's full extent is
+// used, unless the bounds were set explicitly.
+func seedPurgeGrid(maps []atlas.Map, bounds [4]float64, explicit bool) (slippy.TileGridder, [4]float64, error) {
+	g := maps[0].Grid()
+	for _, m := range maps[1:] {
+		if m.Grid().ID != g.ID {
+			return nil, bounds, fmt.Errorf("maps (%v) and (%v) use different tile matrix sets, they need to be seeded separately", maps[0].Name, m.Name)
+		}
+	}
+
+	if g.ID == grid.WebMercatorQuadID {
+		return slippy.NewGrid(proj.EPSGCode(cacheBoundsSRID), 0), bounds, nil
+	}
+
+	ext := geom.Extent(bounds)
+	pts, err := basic.Reproject(uint64(cacheBoundsSRID), uint64(g.SRID()), geom.MultiPoint(ext.Vertices()))
+	if err != nil {
+		if explicit {
+			return nil, bounds, fmt.Errorf("unable to convert bounds to the tile matrix set (%v): %w", g, err)
+		}
+		log.Infof("unable to convert bounds to the tile matrix set (%v), using the extent of the tile matrix set", g)
+		return g, [4]float64(*g.Extent), nil
+	}
+
+	return g, [4]float64(*geom.NewExtent(pts.(geom.MultiPoint)...)), nil
+}
+
 func generateTilesForBounds(ctx context.Context, bounds [4]float64, zooms []uint, grid slippy.TileGridder) *TileChannel {
 
 	tce := &TileChannel{
@@ -4,11 +4,16 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net/url"
+	"reflect"
 	"sort"
 	"testing"
 
 	"github.com/go-spatial/geom/slippy"
 	"github.com/go-spatial/proj"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/grid"
+	"github.com/go-spatial/tegola/provider"
 )
 
 type sTiles []slippy.Tile
@@ -158,3 +163,161 @@ func TestGenerateTilesForBounds(t *testing.T) {
 	}
 
 }
+
+func TestGenerateTilesForGeometry(t *testing.T) {
+	type tcase struct {
+		zooms    []uint
+		geometry string
+		grid     *grid.Grid
+		tiles    sTiles
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			polygons, err := parseGeometry([]byte(tc.geometry))
+			if err != nil {
+				t.Fatalf("parse error, expected nil got %v", err)
+			}
+
+			m := atlas.NewWebMercatorMap("test-map")
+			m.SetGrid(tc.grid)
+			g, polygons, err := seedPurgeGeometryGrid([]atlas.Map{m}, polygons, proj.EPSG4326)
+			if err != nil {
+				t.Fatalf("grid error, expected nil got %v", err)
+			}
+
+			tilechannel := generateTilesForGeometry(context.Background(), polygons, tc.zooms, g)
+			tiles := make(sTiles, 0, len(tc.tiles))
+			for tile := range tilechannel.Channel() {
+				tiles = append(tiles, tile)
+			}
+			if err := tilechannel.Err(); err != nil {
+				t.Fatalf("error, expected nil got %v", err)
+			}
+
+			sort.Sort(tiles)
+			if !tc.tiles.IsEqual(tiles) {
+				t.Errorf("unexpected tile list generated, expected %v got %v", tc.tiles, tiles)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"geojson world": {
+			zooms:    []uint{0, 1},
+			geometry: `{"type":"Polygon","coordinates":[[[-180,-90],[180,-90],[180,90],[-180,90],[-180,-90]]]}`,
+			grid:     grid.WebMercatorQuad,
+			tiles: sTiles{
+				slippy.Tile{},
+				slippy.Tile{Z: 1},
+				slippy.Tile{Z: 1, Y: 1},
+				slippy.Tile{Z: 1, X: 1},
+				slippy.Tile{Z: 1, X: 1, Y: 1},
+			},
+		},
+		"geojson feature in a quadrant": {
+			zooms:    []uint{1, 2},
+			geometry: `{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[10,10],[20,10],[20,20],[10,10]]]}}`,
+			grid:     grid.WebMercatorQuad,
+			tiles: sTiles{
+				slippy.Tile{Z: 1, X: 1},
+				slippy.Tile{Z: 2, X: 2, Y: 1},
+			},
+		},
+		"wkt triangle skips the envelope's corner": {
+			zooms:    []uint{1},
+			geometry: `POLYGON ((-170 80, -10 80, -170 -60, -170 80))`,
+			grid:     grid.WorldCRS84Quad,
+			tiles: sTiles{
+				slippy.Tile{Z: 1},
+				slippy.Tile{Z: 1, Y: 1},
+				slippy.Tile{Z: 1, X: 1},
+			},
+		},
+		"wkt multipolygon": {
+			zooms:    []uint{0},
+			geometry: `MULTIPOLYGON (((-170 10, -160 10, -160 20, -170 10)), ((160 10, 170 10, 170 20, 160 10)))`,
+			grid:     grid.WorldCRS84Quad,
+			tiles: sTiles{
+				slippy.Tile{},
+				slippy.Tile{X: 1},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestParseGeometry(t *testing.T) {
+	tests := map[string]string{
+		"invalid geojson":    `{"type":"Polygon"`,
+		"invalid wkt":        `POLYGON ((`,
+		"not a polygon":      `POINT (1 2)`,
+		"empty feature list": `{"type":"FeatureCollection","features":[]}`,
+	}
+
+	for name, geometry := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseGeometry([]byte(geometry)); err == nil {
+				t.Errorf("error, expected an error got nil")
+			}
+		})
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	type tcase struct {
+		params   []string
+		expected url.Values
+		err      bool
+	}
+
+	m := atlas.NewWebMercatorMap("test-map")
+	m.Params = []provider.QueryParameter{{Name: "year", Token: "!YEAR!", Type: "int"}}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			values, err := parseParams(tc.params, []atlas.Map{m})
+			if tc.err {
+				if err == nil {
+					t.Errorf("error, expected an error got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error, expected nil got %v", err)
+			}
+			if !reflect.DeepEqual(values, tc.expected) {
+				t.Errorf("values, expected %v got %v", tc.expected, values)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"none": {
+			expected: url.Values{},
+		},
+		"param": {
+			params:   []string{"year=2021"},
+			expected: url.Values{"year": {"2021"}},
+		},
+		"value with equal sign": {
+			params:   []string{"year=a=b"},
+			expected: url.Values{"year": {"a=b"}},
+		},
+		"missing value": {
+			params: []string{"year"},
+			err:    true,
+		},
+		"undeclared param": {
+			params: []string{"month=1"},
+			err:    true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/go-spatial/cobra"
 	"github.com/go-spatial/geom/slippy"
+	"github.com/go-spatial/tegola/cache"
 	gdcmd "github.com/go-spatial/tegola/internal/cmd"
 	"github.com/go-spatial/tegola/internal/log"
 	"github.com/go-spatial/tegola/provider"
@@ -73,6 +74,7 @@ func tileListCommand(cmd *cobra.Command, args []string) (err error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer gdcmd.New().Complete()
 	gdcmd.OnComplete(provider.Cleanup)
+	gdcmd.OnComplete(cache.Cleanup)
 	go func() {
 		select {
 		case <-ctx.Done():
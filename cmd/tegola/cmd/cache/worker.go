@@ -47,6 +47,15 @@ func seedWorker(overwrite bool, logThresholdMs int64) func(ctx context.Context,
 		//	filter down the layers we need for this zoom
 		m = m.FilterLayersByZoom(z)
 
+		//	resolve the query parameters of the map
+		params, err := m.ResolveParams(seedPurgeParams)
+		if err != nil {
+			return seedPurgeWorkerTileError{
+				Tile: mt.Tile,
+				Err:  err,
+			}
+		}
+
 		//	check if overwriting the cache is not ok
 		if !overwrite {
 			//	lookup our cache
@@ -57,10 +66,12 @@ func seedWorker(overwrite bool, logThresholdMs int64) func(ctx context.Context,
 
 			//	cache key
 			key := cache.Key{
-				MapName: mt.MapName,
-				Z:       uint(z),
-				X:       x,
-				Y:       y,
+				MapName:    mt.MapName,
+				Version:    m.CacheVersion,
+				ParamsHash: m.ParamsHash(params),
+				Z:          uint(z),
+				X:          x,
+				Y:          y,
 			}
 
 			//	read the tile from the cache
@@ -76,7 +87,7 @@ func seedWorker(overwrite bool, logThresholdMs int64) func(ctx context.Context,
 		}
 
 		//	seed the tile
-		if err = atlas.SeedMapTile(ctx, m, uint(z), x, y); err != nil {
+		if err = atlas.SeedMapTile(ctx, m, uint(z), x, y, params); err != nil {
 			if errors.Is(err, context.Canceled) {
 				return err
 			}
@@ -116,10 +127,20 @@ func purgeWorker(ctx context.Context, mt MapTile) error {
 		}
 	}
 
+	//	resolve the query parameters of the map
+	params, err := m.ResolveParams(seedPurgeParams)
+	if err != nil {
+		return seedPurgeWorkerTileError{
+			Purge: true,
+			Tile:  mt.Tile,
+			Err:   err,
+		}
+	}
+
 	//	purge the tile
 	ttile := tegola.TileFromSlippyTile(mt.Tile)
 
-	if err = atlas.PurgeMapTile(ctx, m, ttile); err != nil {
+	if err = atlas.PurgeMapTile(ctx, m, ttile, params); err != nil {
 		return seedPurgeWorkerTileError{
 			Purge: true,
 			Tile:  mt.Tile,
@@ -129,3 +150,23 @@ func purgeWorker(ctx context.Context, mt MapTile) error {
 
 	return nil
 }
+
+// purgeAll purges all the tiles of the maps, or of a layer of the maps, between
+// minZoom and maxZoom at once. Maps without the layer are skipped.
+func purgeAll(ctx context.Context, maps []atlas.Map, layerName string, minZoom, maxZoom uint) error {
+	for _, m := range maps {
+		if layerName != "" && len(m.FilterLayersByName(layerName).Layers) == 0 {
+			continue
+		}
+
+		log.Infof("purging map (%v) layer (%v) zooms (%v-%v)", m.Name, layerName, minZoom, maxZoom)
+
+		if err := atlas.PurgeMapTiles(ctx, m, layerName, minZoom, maxZoom); err != nil {
+			if errors.Is(err, cache.ErrPurgePrefixNotSupported) {
+				return fmt.Errorf("%w, purge the tiles with bounds or a tile list instead", err)
+			}
+			return fmt.Errorf("error purging map (%v): %w", m.Name, err)
+		}
+	}
+	return nil
+}
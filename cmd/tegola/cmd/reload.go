@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/config"
+	gdcmd "github.com/go-spatial/tegola/internal/cmd"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/server"
+)
+
+// configWatchInterval is how often the config file is checked for changes
+var configWatchInterval = 2 * time.Second
+
+// servedAtlas is the atlas being served, nil being the default atlas
+var servedAtlas *atlas.Atlas
+
+// watchConfig reloads the config when the process receives a SIGHUP or, if
+// watch is set, when the config file is modified. Reloading stops once the
+// server is shutting down.
+func watchConfig(srv *http.Server, watch bool) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var changed <-chan time.Time
+	if watch {
+		if isLocalConfig(configFile) {
+			changed = pollConfigFile(configFile)
+		} else {
+			log.Warnf("config (%v) is not a local file, it can only be reloaded with SIGHUP", configFile)
+		}
+	}
+
+	go func() {
+		defer signal.Stop(hup)
+
+		for {
+			select {
+			case <-gdcmd.Cancelled():
+				return
+			case <-hup:
+				log.Info("received SIGHUP, reloading config")
+			case <-changed:
+				log.Infof("config (%v) changed, reloading", configFile)
+			}
+
+			if err := reloadConfig(srv); err != nil {
+				log.Errorf("could not reload config, still serving the previous config: %v", err)
+			}
+		}
+	}()
+}
+
+// isLocalConfig reports if the config location is a file that can be watched
+func isLocalConfig(location string) bool {
+	return location != "-" && !strings.HasPrefix(location, "http")
+}
+
+// pollConfigFile sends on the returned channel each time the modification
+// time or size of the file changes
+func pollConfigFile(filename string) <-chan time.Time {
+	changed := make(chan time.Time)
+
+	stat := func() (time.Time, int64) {
+		fi, err := os.Stat(filename)
+		if err != nil {
+			// the file is likely being replaced, check again on the next tick
+			return time.Time{}, -1
+		}
+		return fi.ModTime(), fi.Size()
+	}
+
+	go func() {
+		ticker := time.NewTicker(configWatchInterval)
+		defer ticker.Stop()
+
+		modTime, size := stat()
+		for {
+			select {
+			case <-gdcmd.Cancelled():
+				return
+			case <-ticker.C:
+			}
+
+			mt, s := stat()
+			if s < 0 || (mt.Equal(modTime) && s == size) {
+				continue
+			}
+			modTime, size = mt, s
+
+			select {
+			case changed <- mt:
+			case <-gdcmd.Cancelled():
+				return
+			}
+		}
+	}()
+
+	return changed
+}
+
+// reloadConfig loads and validates the config, builds a new atlas from it and
+// swaps it in for the atlas being served. Once the requests in flight on the
+// previous atlas, and their background work, have completed, its providers
+// and cache are released. If anything fails the previous atlas keeps being
+// served.
+func reloadConfig(srv *http.Server) error {
+	newConf, err := config.LoadAndValidate(configFile)
+	if err != nil {
+		return err
+	}
+
+	prevCache := servedAtlas.GetCache()
+
+	a := &atlas.Atlas{}
+	providers, err := loadAtlas(a, newConf, false)
+	if err != nil {
+		return err
+	}
+	a.StartSubProcesses()
+
+	if err = server.Reload(srv, a); err != nil {
+		for name, p := range providers {
+			if cerr := p.Close(); cerr != nil {
+				log.Errorf("error closing provider (%v): %v", name, cerr)
+			}
+		}
+		return fmt.Errorf("swapping atlas: %w", err)
+	}
+
+	// the previous atlas is no longer served, release it
+	for name, p := range atlasProviders {
+		if err := p.Close(); err != nil {
+			log.Errorf("error closing provider (%v) of the previous config: %v", name, err)
+		}
+	}
+	if o := servedAtlas.Observer(); o != nil {
+		o.Shutdown()
+	}
+	if err := cache.Close(prevCache); err != nil {
+		log.Errorf("error closing the cache of the previous config: %v", err)
+	}
+
+	if !reflect.DeepEqual(conf.Webserver, newConf.Webserver) {
+		log.Warn("changes to the webserver config require a restart to take effect")
+	}
+
+	servedAtlas, atlasProviders = a, providers
+	conf = newConf
+
+	log.Infof("config (%v) reloaded", configFile)
+	return nil
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/server"
+)
+
+// reloadCacheCloses counts the closes of the caches of the reload tests
+var reloadCacheCloses atomic.Int32
+
+// closerCache is a memory cache counting its closes
+type closerCache struct {
+	cache.Interface
+}
+
+func (c *closerCache) Close() error {
+	reloadCacheCloses.Add(1)
+	return nil
+}
+
+func init() {
+	cache.Register("reload_test", func(config dict.Dicter) (cache.Interface, error) {
+		c, err := memory.New(config)
+		if err != nil {
+			return nil, err
+		}
+		return &closerCache{Interface: c}, nil
+	})
+}
+
+const reloadConfigTOML = `
+[observer]
+type = "prometheus"
+
+[cache]
+type = "reload_test"
+max_entries = 100
+
+[[providers]]
+name = "places"
+type = "geojson"
+filepath = "%DIR%/points.geojson"
+
+  [[providers.layers]]
+  name = "points"
+
+[[maps]]
+name = "places"
+
+  [[maps.layers]]
+  provider_layer = "places.points"
+`
+
+const reloadPoints = `{
+	"type": "FeatureCollection",
+	"features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [10, 10]}, "properties": {"name": "Lomé"}}
+	]
+}`
+
+func TestReloadConfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "points.geojson"), []byte(reloadPoints), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	configFile = filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(configFile, []byte(strings.ReplaceAll(reloadConfigTOML, "%DIR%", dir)), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reloadCacheCloses.Store(0)
+	if err := initConfig(configFile, false, "ERROR"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	srv := server.Start(servedAtlas, "127.0.0.1:0")
+	defer srv.Close()
+
+	get := func(t *testing.T, url string, expected string) {
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%v: status, expected %v got %v", url, http.StatusOK, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), expected) {
+			t.Errorf("%v: expected the body to contain %q", url, expected)
+		}
+	}
+
+	get(t, "/maps/places/0/0/0.pbf", "points")
+
+	// the prometheus observer is created again on each reload, and the cache
+	// of the previous config, wrapped by the observer, is closed once
+	for i := 0; i < 2; i++ {
+		if err := reloadConfig(srv); err != nil {
+			t.Fatalf("reload %v: unexpected error: %v", i, err)
+		}
+		get(t, "/maps/places/0/0/0.pbf", "points")
+		get(t, "/metrics", "tegola_build_info")
+		if n := reloadCacheCloses.Load(); n != int32(i+1) {
+			t.Errorf("reload %v: cache closes, expected %v got %v", i, i+1, n)
+		}
+	}
+}
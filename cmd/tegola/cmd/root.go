@@ -13,6 +13,7 @@ import (
 	"github.com/go-spatial/tegola/dict"
 	"github.com/go-spatial/tegola/internal/build"
 	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
 )
 
 var (
@@ -20,6 +21,9 @@ var (
 	configFile string
 	// parsed config
 	conf config.Config
+	// the providers of the atlas being served, released when the
+	// config is reloaded
+	atlasProviders map[string]provider.TilerUnion
 
 	// RequireCache in this instance
 	RequireCache bool
@@ -35,6 +39,7 @@ func init() {
 	// server
 	serverCmd.Flags().StringVarP(&serverPort, "port", "p", ":8080", "port to bind tile server to")
 	serverCmd.Flags().BoolVarP(&serverNoCache, "no-cache", "n", false, "turn off the cache")
+	serverCmd.Flags().BoolVarP(&serverWatch, "watch", "w", false, "reload the config when the config file changes")
 
 	RootCmd.AddCommand(serverCmd)
 	// cache seed / purge
@@ -82,6 +87,26 @@ func initConfig(configFile string, cacheRequired bool, logLevel string) (err err
 		return err
 	}
 
+	atlasProviders, err = loadAtlas(nil, conf, cacheRequired)
+	return err
+}
+
+// loadAtlas registers the providers, tile matrix sets, maps, cache and observer
+// of the config with the atlas, nil being the default atlas. The registered
+// providers are returned so they can be released once the atlas is replaced.
+func loadAtlas(a *atlas.Atlas, conf config.Config, cacheRequired bool) (providers map[string]provider.TilerUnion, err error) {
+	defer func() {
+		// release the providers of the atlas we could not load
+		if err == nil {
+			return
+		}
+		for name, p := range providers {
+			if cerr := p.Close(); cerr != nil {
+				log.Errorf("error closing provider (%v): %v", name, cerr)
+			}
+		}
+	}()
+
 	// init our providers
 	// but first convert []env.Map -> []dict.Dicter
 	provArr := make([]dict.Dicter, len(conf.Providers))
@@ -89,17 +114,26 @@ func initConfig(configFile string, cacheRequired bool, logLevel string) (err err
 		provArr[i] = conf.Providers[i]
 	}
 
-	providers, err := register.Providers(provArr, conf.Maps)
+	providers, err = register.Providers(provArr, conf.Maps)
 	if err != nil {
-		return fmt.Errorf("could not register providers: %v", err)
+		return providers, fmt.Errorf("could not register providers: %v", err)
+	}
+
+	// init our custom tile grids
+	tmsArr := make([]dict.Dicter, len(conf.TileMatrixSets))
+	for i := range tmsArr {
+		tmsArr[i] = conf.TileMatrixSets[i]
+	}
+	if err = register.TileMatrixSets(tmsArr); err != nil {
+		return providers, fmt.Errorf("could not register tile matrix sets: %v", err)
 	}
 
 	// init our maps
-	if err = register.Maps(nil, conf.Maps, providers); err != nil {
-		return fmt.Errorf("could not register maps: %v", err)
+	if err = register.Maps(a, conf.Maps, providers); err != nil {
+		return providers, fmt.Errorf("could not register maps: %v", err)
 	}
 	if len(conf.Cache) == 0 && cacheRequired {
-		return fmt.Errorf("no cache defined in config, please check your config (%v)", configFile)
+		return providers, fmt.Errorf("no cache defined in config, please check your config (%v)", conf.LocationName)
 	}
 	if serverNoCache {
 		log.Info("Cache explicitly turned off by user via command line")
@@ -107,16 +141,16 @@ func initConfig(configFile string, cacheRequired bool, logLevel string) (err err
 		// init cache backends
 		cache, err := register.Cache(conf.Cache)
 		if err != nil {
-			return fmt.Errorf("could not register cache: %v", err)
+			return providers, fmt.Errorf("could not register cache: %v", err)
 		}
 		if cache != nil {
-			atlas.SetCache(cache)
+			a.SetCache(cache)
 		}
 	}
 	observer, err := register.Observer(conf.Observer)
 	if err != nil {
-		return err
+		return providers, err
 	}
-	atlas.SetObservability(observer)
-	return nil
+	a.SetObservability(observer)
+	return providers, nil
 }
@@ -10,6 +10,9 @@ import (
 
 	"github.com/go-spatial/cobra"
 	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cmd/internal/register"
+	"github.com/go-spatial/tegola/dict"
 	"github.com/go-spatial/tegola/internal/build"
 	gdcmd "github.com/go-spatial/tegola/internal/cmd"
 	"github.com/go-spatial/tegola/internal/log"
@@ -21,6 +24,7 @@ import (
 var (
 	serverPort      string
 	serverNoCache   bool
+	serverWatch     bool
 	defaultHTTPPort = ":8080"
 )
 
@@ -33,6 +37,7 @@ var serverCmd = &cobra.Command{
 		gdcmd.New()
 		gdcmd.OnComplete(provider.Cleanup)
 		gdcmd.OnComplete(observability.Cleanup)
+		gdcmd.OnComplete(cache.Cleanup)
 
 		// check config for server port setting
 		// if you set the port via the command line it will override the port setting in the config
@@ -67,6 +72,33 @@ var serverCmd = &cobra.Command{
 			server.URIPrefix = string(conf.Webserver.URIPrefix)
 		}
 
+		if conf.Webserver.AdminToken != "" {
+			server.AdminToken = string(conf.Webserver.AdminToken)
+		}
+
+		// init our authenticators
+		// but first convert []env.Dict -> []dict.Dicter
+		authArr := make([]dict.Dicter, len(conf.Webserver.Auth))
+		for i := range authArr {
+			authArr[i] = conf.Webserver.Auth[i]
+		}
+		authenticators, err := register.Authenticators(authArr)
+		if err != nil {
+			log.Errorf("could not register authenticators: %v", err)
+			os.Exit(1)
+		}
+		server.Authenticators = authenticators
+
+		if rl := conf.Webserver.RateLimit; rl.RequestsPerSecond > 0 {
+			server.RateLimit = server.NewRateLimiter(float64(rl.RequestsPerSecond), int(rl.Burst))
+			server.RateLimit.ClientIPHeader = string(rl.ClientIPHeader)
+			server.RateLimit.TrustedProxies = int(rl.TrustedProxies)
+		}
+
+		if tc := conf.Webserver.TileConcurrency; tc.Max > 0 {
+			server.TileConcurrency = server.NewTileLimiter(int(tc.Max), time.Duration(tc.QueueTimeout)*time.Second)
+		}
+
 		if conf.Webserver.ProxyProtocol != "" {
 			server.ProxyProtocol = string(conf.Webserver.ProxyProtocol)
 		}
@@ -91,6 +123,7 @@ var serverCmd = &cobra.Command{
 		// start our webserver
 		srv := server.Start(nil, serverPort)
 		shutdown(srv)
+		watchConfig(srv, serverWatch)
 		<-gdcmd.Cancelled()
 		gdcmd.Complete()
 	},
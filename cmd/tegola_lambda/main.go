@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"time"
 
 	"github.com/akrylysov/algnhsa"
 	"github.com/dimfeld/httptreemux"
@@ -72,6 +73,16 @@ func init() {
 		os.Exit(1)
 	}
 
+	// register the custom tile grids
+	tmsArr := make([]dict.Dicter, len(conf.TileMatrixSets))
+	for i := range tmsArr {
+		tmsArr[i] = conf.TileMatrixSets[i]
+	}
+	if err = register.TileMatrixSets(tmsArr); err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
 	// register the maps
 	if err = register.Maps(nil, conf.Maps, providers); err != nil {
 		log.Error(err)
@@ -115,6 +126,29 @@ func init() {
 		server.URIPrefix = string(conf.Webserver.URIPrefix)
 	}
 
+	if conf.Webserver.AdminToken != "" {
+		server.AdminToken = string(conf.Webserver.AdminToken)
+	}
+
+	// register the authenticators
+	authArr := make([]dict.Dicter, len(conf.Webserver.Auth))
+	for i := range authArr {
+		authArr[i] = conf.Webserver.Auth[i]
+	}
+	if server.Authenticators, err = register.Authenticators(authArr); err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	if rl := conf.Webserver.RateLimit; rl.RequestsPerSecond > 0 {
+		server.RateLimit = server.NewRateLimiter(float64(rl.RequestsPerSecond), int(rl.Burst))
+		server.RateLimit.ClientIPHeader = string(rl.ClientIPHeader)
+	}
+
+	if tc := conf.Webserver.TileConcurrency; tc.Max > 0 {
+		server.TileConcurrency = server.NewTileLimiter(int(tc.Max), time.Duration(tc.QueueTimeout)*time.Second)
+	}
+
 	// http route setup
 	mux = server.NewRouter(nil)
 }
@@ -11,6 +11,8 @@ import (
 
 	"github.com/BurntSushi/toml"
 	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/grid"
 	"github.com/go-spatial/tegola/internal/env"
 	"github.com/go-spatial/tegola/internal/log"
 	"github.com/go-spatial/tegola/provider"
@@ -67,6 +69,9 @@ type Config struct {
 	// Note: Use the type to figure out if the provider is a mvt or std provider
 	Providers []env.Dict     `toml:"providers"`
 	Maps      []provider.Map `toml:"maps"`
+	// Custom tile grids maps can reference with their tile_matrix_set
+	// param. Each entry needs at least a name, srid and extent.
+	TileMatrixSets []env.Dict `toml:"tile_matrix_sets"`
 }
 
 // Webserver represents the config options for the webserver part of Tegola
@@ -78,6 +83,58 @@ type Webserver struct {
 	SSLCert       env.String `toml:"ssl_cert"`
 	SSLKey        env.String `toml:"ssl_key"`
 	ProxyProtocol env.String `toml:"proxy_protocol"`
+	AdminToken    env.String `toml:"admin_token"`
+	// Auth configures the authenticators of the requests, tried in order.
+	// Each needs at least a type.
+	Auth []env.Dict `toml:"auth"`
+	// RateLimit limits the rate of the requests of each client
+	RateLimit RateLimit `toml:"rate_limit"`
+	// TileConcurrency caps the number of tiles rendered at once
+	TileConcurrency TileConcurrency `toml:"tile_concurrency"`
+}
+
+// RateLimit configures the token buckets limiting the requests of each
+// client. Clients are identified by their principal when authenticated, by
+// their IP otherwise.
+type RateLimit struct {
+	// RequestsPerSecond refills the bucket of each client, 0 disables rate limiting
+	RequestsPerSecond env.Float `toml:"requests_per_second"`
+	// Burst is the size of the buckets, defaults to the requests per second
+	Burst env.Int `toml:"burst"`
+	// ClientIPHeader is the request header with the client IP (i.e. X-Forwarded-For)
+	// when tegola is behind a proxy, the remote address is used when not set
+	ClientIPHeader env.String `toml:"client_ip_header"`
+	// TrustedProxies is the number of proxies appending to the client IP
+	// header, the client IP is read from the right. Defaults to 1.
+	TrustedProxies env.Int `toml:"trusted_proxies"`
+}
+
+// TileConcurrency configures the cap on the tiles rendered at once
+type TileConcurrency struct {
+	// Max is the number of tiles rendered at once, 0 is no limit
+	Max env.Int `toml:"max"`
+	// QueueTimeout is the seconds a tile waits for its turn to be rendered
+	QueueTimeout env.Int `toml:"queue_timeout"`
+}
+
+// validateLimits checks the rate limit and tile concurrency are not negative
+func (w Webserver) validateLimits() error {
+	limits := []struct {
+		param string
+		value float64
+	}{
+		{"rate_limit.requests_per_second", float64(w.RateLimit.RequestsPerSecond)},
+		{"rate_limit.burst", float64(w.RateLimit.Burst)},
+		{"rate_limit.trusted_proxies", float64(w.RateLimit.TrustedProxies)},
+		{"tile_concurrency.max", float64(w.TileConcurrency.Max)},
+		{"tile_concurrency.queue_timeout", float64(w.TileConcurrency.QueueTimeout)},
+	}
+	for _, l := range limits {
+		if l.value < 0 {
+			return ErrInvalidWebserverLimit{Param: l.param, Value: l.value}
+		}
+	}
+	return nil
 }
 
 // ValidateAndRegisterParams ensures configured params don't conflict with existing
@@ -115,6 +172,13 @@ func ValidateAndRegisterParams(mapName string, params []provider.QueryParameter)
 			}
 		}
 
+		if provider.IsReservedQueryParam(param.Name) {
+			return ErrParamNameReserved{
+				MapName:   string(mapName),
+				Parameter: param,
+			}
+		}
+
 		if _, ok := ReservedTokens[param.Token]; ok {
 			return ErrParamTokenReserved{
 				MapName:   string(mapName),
@@ -158,6 +222,10 @@ func ValidateAndRegisterParams(mapName string, params []provider.QueryParameter)
 // Validate checks the config for issues
 func (c *Config) Validate() error {
 
+	if err := c.Webserver.validateLimits(); err != nil {
+		return err
+	}
+
 	var knownTypes []string
 	drivers := make(map[string]int)
 	for _, name := range provider.Drivers(provider.TypeStd) {
@@ -194,11 +262,25 @@ func (c *Config) Validate() error {
 		}
 		mvtproviders[name] = drv == int(provider.TypeMvt)
 	}
+	// names of the tile grids maps can use
+	tileMatrixSets := map[string]bool{
+		grid.WebMercatorQuadID: true,
+		grid.WorldCRS84QuadID:  true,
+	}
+	for i, tms := range c.TileMatrixSets {
+		name, _ := tms.String("name", nil)
+		if name == "" {
+			return ErrTileMatrixSetNameRequired{Pos: i}
+		}
+		if tileMatrixSets[name] {
+			return ErrTileMatrixSetNameDuplicate{Pos: i, Name: name}
+		}
+		tileMatrixSets[name] = true
+	}
+
 	// check for map layer name / zoom collisions
 	// map of layers to providers
 	mapLayers := map[string]map[string]provider.MapLayer{}
-	// maps with configured parameters for logging
-	mapsWithCustomParams := []string{}
 	for mapKey, m := range c.Maps {
 
 		// validate any declared query parameters
@@ -206,8 +288,22 @@ func (c *Config) Validate() error {
 			return err
 		}
 
-		if len(m.Parameters) > 0 {
-			mapsWithCustomParams = append(mapsWithCustomParams, string(m.Name))
+		if version := string(m.CacheVersion); version != "" && !cache.IsValidVersion(version) {
+			return ErrInvalidCacheVersion{
+				MapName: string(m.Name),
+				Version: version,
+			}
+		}
+
+		if (len(m.Principals) > 0 || len(m.Scopes) > 0) && len(c.Webserver.Auth) == 0 {
+			return ErrMapAuthWithoutAuthenticators(string(m.Name))
+		}
+
+		if tms := string(m.TileMatrixSet); tms != "" && !tileMatrixSets[tms] {
+			return ErrUnknownTileMatrixSet{
+				MapName:       string(m.Name),
+				TileMatrixSet: tms,
+			}
 		}
 
 		if _, ok := mapLayers[string(m.Name)]; !ok {
@@ -263,6 +359,12 @@ func (c *Config) Validate() error {
 			if err != nil {
 				return err
 			}
+			if !cache.IsValidLayerName(name) {
+				return ErrInvalidLayerName{
+					MapName:   string(m.Name),
+					LayerName: name,
+				}
+			}
 
 			// MaxZoom default
 			if l.MaxZoom == nil {
@@ -307,13 +409,6 @@ func (c *Config) Validate() error {
 		}
 	}
 
-	if len(mapsWithCustomParams) > 0 {
-		log.Infof(
-			"Caching is disabled for these maps, since they have configured custom parameters: %s",
-			strings.Join(mapsWithCustomParams, ", "),
-		)
-	}
-
 	// check for blacklisted headers
 	for k := range c.Webserver.Headers {
 		for _, v := range blacklistHeaders {
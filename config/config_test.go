@@ -124,6 +124,14 @@ func TestParse(t *testing.T) {
 						"Access-Control-Allow-Origin":  "*",
 						"Access-Control-Allow-Methods": "GET, OPTIONS",
 					},
+					RateLimit: config.RateLimit{
+						RequestsPerSecond: 2.5,
+						Burst:             10,
+					},
+					TileConcurrency: config.TileConcurrency{
+						Max:          8,
+						QueueTimeout: 5,
+					},
 				},
 				Cache: env.Dict{
 					"type":     "file",
@@ -1157,6 +1165,54 @@ func TestValidate(t *testing.T) {
 				},
 			},
 		},
+		"reserved parameter name": {
+			config: config.Config{
+				Maps: []provider.Map{
+					{
+						Name: "bad_param",
+						Parameters: []provider.QueryParameter{
+							{
+								Name:  "fields[water]",
+								Token: "!FIELDS!",
+								Type:  "string",
+							},
+						},
+					},
+				},
+			},
+			expectedErr: config.ErrParamNameReserved{
+				MapName: "bad_param",
+				Parameter: provider.QueryParameter{
+					Name:  "fields[water]",
+					Token: "!FIELDS!",
+					Type:  "string",
+				},
+			},
+		},
+		"reserved auth parameter name": {
+			config: config.Config{
+				Maps: []provider.Map{
+					{
+						Name: "bad_param",
+						Parameters: []provider.QueryParameter{
+							{
+								Name:  "signature",
+								Token: "!SIGNATURE!",
+								Type:  "string",
+							},
+						},
+					},
+				},
+			},
+			expectedErr: config.ErrParamNameReserved{
+				MapName: "bad_param",
+				Parameter: provider.QueryParameter{
+					Name:  "signature",
+					Token: "!SIGNATURE!",
+					Type:  "string",
+				},
+			},
+		},
 		"duplicate parameter name": {
 			config: config.Config{
 				Maps: []provider.Map{
@@ -1318,6 +1374,161 @@ func TestValidate(t *testing.T) {
 				},
 			},
 		},
+		"tile matrix set name required": {
+			config: config.Config{
+				TileMatrixSets: []env.Dict{
+					{
+						"srid": int64(3035),
+					},
+				},
+			},
+			expectedErr: config.ErrTileMatrixSetNameRequired{Pos: 0},
+		},
+		"tile matrix set name duplicate of built in": {
+			config: config.Config{
+				TileMatrixSets: []env.Dict{
+					{
+						"name": "WorldCRS84Quad",
+					},
+				},
+			},
+			expectedErr: config.ErrTileMatrixSetNameDuplicate{Pos: 0, Name: "WorldCRS84Quad"},
+		},
+		"invalid cache version": {
+			config: config.Config{
+				Maps: []provider.Map{
+					{
+						Name:         "osm",
+						CacheVersion: "2024/05",
+					},
+				},
+			},
+			expectedErr: config.ErrInvalidCacheVersion{
+				MapName: "osm",
+				Version: "2024/05",
+			},
+		},
+		"layer name starting with ~": {
+			config: config.Config{
+				Providers: []env.Dict{
+					{
+						"name": "provider1",
+						"type": "postgis",
+					},
+				},
+				Maps: []provider.Map{
+					{
+						Name: "osm",
+						Layers: []provider.MapLayer{
+							{
+								Name:          "~water",
+								ProviderLayer: "provider1.water",
+							},
+						},
+					},
+				},
+			},
+			expectedErr: config.ErrInvalidLayerName{
+				MapName:   "osm",
+				LayerName: "~water",
+			},
+		},
+		"layer name starting with @": {
+			config: config.Config{
+				Providers: []env.Dict{
+					{
+						"name": "provider1",
+						"type": "postgis",
+					},
+				},
+				Maps: []provider.Map{
+					{
+						Name: "osm",
+						Layers: []provider.MapLayer{
+							{
+								Name:          "@water",
+								ProviderLayer: "provider1.water",
+							},
+						},
+					},
+				},
+			},
+			expectedErr: config.ErrInvalidLayerName{
+				MapName:   "osm",
+				LayerName: "@water",
+			},
+		},
+		"negative rate limit": {
+			config: config.Config{
+				Webserver: config.Webserver{
+					RateLimit: config.RateLimit{
+						RequestsPerSecond: -1,
+					},
+				},
+			},
+			expectedErr: config.ErrInvalidWebserverLimit{
+				Param: "rate_limit.requests_per_second",
+				Value: -1,
+			},
+		},
+		"negative tile concurrency": {
+			config: config.Config{
+				Webserver: config.Webserver{
+					TileConcurrency: config.TileConcurrency{
+						Max: -4,
+					},
+				},
+			},
+			expectedErr: config.ErrInvalidWebserverLimit{
+				Param: "tile_concurrency.max",
+				Value: -4,
+			},
+		},
+		"map auth without authenticators": {
+			config: config.Config{
+				Maps: []provider.Map{
+					{
+						Name:   "osm",
+						Scopes: []env.String{"osm:read"},
+					},
+				},
+			},
+			expectedErr: config.ErrMapAuthWithoutAuthenticators("osm"),
+		},
+		"map auth with authenticators": {
+			config: config.Config{
+				Webserver: config.Webserver{
+					Auth: []env.Dict{
+						{"type": "api_key"},
+					},
+				},
+				Maps: []provider.Map{
+					{
+						Name:       "osm",
+						Principals: []env.String{"acme"},
+					},
+				},
+			},
+		},
+		"unknown tile matrix set": {
+			config: config.Config{
+				TileMatrixSets: []env.Dict{
+					{
+						"name": "LAEA",
+					},
+				},
+				Maps: []provider.Map{
+					{
+						Name:          "europe",
+						TileMatrixSet: "EuropeanETRS89_LAEAQuad",
+					},
+				},
+			},
+			expectedErr: config.ErrUnknownTileMatrixSet{
+				MapName:       "europe",
+				TileMatrixSet: "EuropeanETRS89_LAEAQuad",
+			},
+		},
 	}
 
 	for name, tc := range tests {
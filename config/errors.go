@@ -25,6 +25,16 @@ func (e ErrParamTokenReserved) Error() string {
 		e.MapName, e.Parameter.Name, e.Parameter.Token)
 }
 
+type ErrParamNameReserved struct {
+	MapName   string
+	Parameter provider.QueryParameter
+}
+
+func (e ErrParamNameReserved) Error() string {
+	return fmt.Sprintf("config: map %s parameter %s uses a name reserved for the layer and field selections",
+		e.MapName, e.Parameter.Name)
+}
+
 type ErrParamDuplicateName struct {
 	MapName   string
 	Parameter provider.QueryParameter
@@ -227,3 +237,72 @@ type ErrProviderTypeRequired struct {
 func (e ErrProviderTypeRequired) Error() string {
 	return fmt.Sprintf("config: type field required for provider at position %d", e.Pos)
 }
+
+// ErrTileMatrixSetNameRequired is returned when the name of a tile matrix set is missing from the tile matrix set list
+type ErrTileMatrixSetNameRequired struct {
+	Pos int
+}
+
+func (e ErrTileMatrixSetNameRequired) Error() string {
+	return fmt.Sprintf("config: name field required for tile matrix set at position %d", e.Pos)
+}
+
+// ErrTileMatrixSetNameDuplicate is returned when the name of a tile matrix set is duplicated or
+// is the name of a built in tile matrix set
+type ErrTileMatrixSetNameDuplicate struct {
+	Pos  int
+	Name string
+}
+
+func (e ErrTileMatrixSetNameDuplicate) Error() string {
+	return fmt.Sprintf("config: name (%s) for tile matrix set at position %d is a duplicate", e.Name, e.Pos)
+}
+
+// ErrUnknownTileMatrixSet is returned when a map references a tile matrix set that is not defined
+type ErrUnknownTileMatrixSet struct {
+	MapName       string
+	TileMatrixSet string
+}
+
+func (e ErrUnknownTileMatrixSet) Error() string {
+	return fmt.Sprintf("config: map (%s) references unknown tile matrix set (%s)", e.MapName, e.TileMatrixSet)
+}
+
+// ErrInvalidCacheVersion is returned when the cache version of a map can not be used in the cache keys
+type ErrInvalidCacheVersion struct {
+	MapName string
+	Version string
+}
+
+func (e ErrInvalidCacheVersion) Error() string {
+	return fmt.Sprintf("config: map (%s) has an invalid cache_version (%s). versions are made of letters, digits, '.', '_' and '-' and don't start with '.' or '-'", e.MapName, e.Version)
+}
+
+// ErrInvalidLayerName is returned when the name of a layer of a map can not be used in the cache keys
+type ErrInvalidLayerName struct {
+	MapName   string
+	LayerName string
+}
+
+func (e ErrInvalidLayerName) Error() string {
+	return fmt.Sprintf("config: map (%s) has a layer with an invalid name (%s). layer names can't start with '~' or '@'", e.MapName, e.LayerName)
+}
+
+// ErrMapAuthWithoutAuthenticators is returned when a map restricts its access
+// to principals or scopes while no authenticators are configured
+type ErrMapAuthWithoutAuthenticators string
+
+func (e ErrMapAuthWithoutAuthenticators) Error() string {
+	return fmt.Sprintf("config: map (%s) lists principals or scopes but no [[webserver.auth]] is configured", string(e))
+}
+
+// ErrInvalidWebserverLimit is returned when a rate limit or tile concurrency
+// param of the webserver is negative
+type ErrInvalidWebserverLimit struct {
+	Param string
+	Value float64
+}
+
+func (e ErrInvalidWebserverLimit) Error() string {
+	return fmt.Sprintf("config: webserver %s (%v) can not be negative", e.Param, e.Value)
+}
@@ -171,12 +171,15 @@ func (d Dict) Map(key string) (r Dicter, err error) {
 		return Dict{}, nil
 	}
 
-	r, ok = v.(Dict)
-	if !ok {
-		return r, ErrKeyType{Key: key, Value: v, T: reflect.TypeOf(r)}
+	switch m := v.(type) {
+	case Dict:
+		return m, nil
+	// nested tables of the layers of the providers are not parsed as Dicts
+	case map[string]interface{}:
+		return Dict(m), nil
+	default:
+		return r, ErrKeyType{Key: key, Value: v, T: reflect.TypeOf(Dict{})}
 	}
-
-	return r, nil
 }
 
 func (d Dict) MapSlice(key string) (r []Dicter, err error) {
@@ -0,0 +1,205 @@
+package grid
+
+import (
+	"fmt"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/geojson"
+	"github.com/go-spatial/geom/slippy"
+)
+
+// Cover calls fn for each tile of zoom z intersecting any of the polygons,
+// which are in the coordinates of the grid. Each tile is reported once.
+// Iteration stops at the first error returned by fn.
+func (g *Grid) Cover(z slippy.Zoom, polygons []geom.Polygon, fn func(slippy.Tile) error) error {
+	minTile, maxTile, ok, err := g.TileRange(z, polygonsExtent(polygons))
+	if err != nil || !ok {
+		return err
+	}
+
+	for x := minTile.X; x <= maxTile.X; x++ {
+		for y := minTile.Y; y <= maxTile.Y; y++ {
+			tile := slippy.Tile{Z: z, X: x, Y: y}
+			tileExt, err := slippy.Extent(g, tile)
+			if err != nil {
+				return err
+			}
+
+			for _, poly := range polygons {
+				if !polygonIntersects(poly, tileExt) {
+					continue
+				}
+				if err = fn(tile); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// TileRange returns the top left and bottom right tiles of zoom z covering
+// the extent, which is in the coordinates of the grid. ok is false if the
+// extent is outside of the grid.
+func (g *Grid) TileRange(z slippy.Zoom, ext *geom.Extent) (minTile, maxTile slippy.Tile, ok bool, err error) {
+	if ext == nil {
+		return minTile, maxTile, false, nil
+	}
+	if ext, ok = ext.Intersect(g.Extent); !ok {
+		return minTile, maxTile, false, nil
+	}
+
+	// FromNative numbers the rows from the top of the grid
+	if minTile, err = g.FromNative(z, geom.Point{ext.MinX(), ext.MaxY()}); err != nil {
+		return minTile, maxTile, false, err
+	}
+	if maxTile, err = g.FromNative(z, geom.Point{ext.MaxX(), ext.MinY()}); err != nil {
+		return minTile, maxTile, false, err
+	}
+	return minTile, maxTile, true, nil
+}
+
+// Polygons collects the polygons of a geometry, which may be a Polygon, a
+// MultiPolygon, a Collection of them, or a decoded GeoJSON Feature or
+// FeatureCollection of them
+func Polygons(g geom.Geometry) ([]geom.Polygon, error) {
+	switch g := g.(type) {
+	case geom.Polygon:
+		return []geom.Polygon{g}, nil
+	case geom.MultiPolygon:
+		polygons := make([]geom.Polygon, 0, len(g))
+		for _, p := range g {
+			polygons = append(polygons, p)
+		}
+		return polygons, nil
+	case geojson.Feature:
+		return Polygons(g.Geometry.Geometry)
+	case geojson.FeatureCollection:
+		var polygons []geom.Polygon
+		for _, f := range g.Features {
+			p, err := Polygons(f.Geometry.Geometry)
+			if err != nil {
+				return nil, err
+			}
+			polygons = append(polygons, p...)
+		}
+		return polygons, nil
+	case geom.Collection:
+		var polygons []geom.Polygon
+		for _, cg := range g.Geometries() {
+			p, err := Polygons(cg)
+			if err != nil {
+				return nil, err
+			}
+			polygons = append(polygons, p...)
+		}
+		return polygons, nil
+	default:
+		return nil, fmt.Errorf("unsupported geometry type (%T), expecting polygons", g)
+	}
+}
+
+// polygonsExtent returns the extent of the polygons, nil if they are empty
+func polygonsExtent(polygons []geom.Polygon) *geom.Extent {
+	var ext *geom.Extent
+	for _, poly := range polygons {
+		pe, err := geom.NewExtentFromGeometry(poly)
+		if err != nil || pe == nil {
+			continue
+		}
+		if ext == nil {
+			ext = pe
+			continue
+		}
+		ext.Add(pe)
+	}
+	return ext
+}
+
+// polygonIntersects reports if the polygon and the extent share any point
+func polygonIntersects(poly geom.Polygon, ext *geom.Extent) bool {
+	// a vertex of the polygon within the extent
+	for _, ring := range poly {
+		for _, pt := range ring {
+			if ext.ContainsPoint(pt) {
+				return true
+			}
+		}
+	}
+
+	// the extent within the polygon
+	if polygonContains(poly, ext.Min()) {
+		return true
+	}
+
+	// an edge of the polygon crossing the extent
+	edges := ext.Edges(nil)
+	for _, ring := range poly {
+		for i := range ring {
+			seg := [2][2]float64{ring[i], ring[(i+1)%len(ring)]}
+			for _, edge := range edges {
+				if segmentsIntersect(seg, edge) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// polygonContains reports if pt is within the polygon, outside of its holes,
+// using the even-odd rule
+func polygonContains(poly geom.Polygon, pt [2]float64) bool {
+	in := false
+	for _, ring := range poly {
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			a, b := ring[i], ring[j]
+			if (a[1] > pt[1]) != (b[1] > pt[1]) &&
+				pt[0] < (b[0]-a[0])*(pt[1]-a[1])/(b[1]-a[1])+a[0] {
+				in = !in
+			}
+		}
+	}
+	return in
+}
+
+// segmentsIntersect reports if the segments share any point
+func segmentsIntersect(s1, s2 [2][2]float64) bool {
+	o1 := orientation(s1[0], s1[1], s2[0])
+	o2 := orientation(s1[0], s1[1], s2[1])
+	o3 := orientation(s2[0], s2[1], s1[0])
+	o4 := orientation(s2[0], s2[1], s1[1])
+
+	if o1 != o2 && o3 != o4 {
+		return true
+	}
+
+	// collinear points lying on the other segment
+	return (o1 == 0 && onSegment(s1, s2[0])) ||
+		(o2 == 0 && onSegment(s1, s2[1])) ||
+		(o3 == 0 && onSegment(s2, s1[0])) ||
+		(o4 == 0 && onSegment(s2, s1[1]))
+}
+
+// orientation returns 1 for a counter clockwise turn from a to b to c,
+// -1 for a clockwise turn and 0 if the points are collinear
+func orientation(a, b, c [2]float64) int {
+	v := (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// onSegment reports if the collinear point pt is within the bounds of the segment
+func onSegment(s [2][2]float64, pt [2]float64) bool {
+	return pt[0] >= min(s[0][0], s[1][0]) && pt[0] <= max(s[0][0], s[1][0]) &&
+		pt[1] >= min(s[0][1], s[1][1]) && pt[1] <= max(s[0][1], s[1][1])
+}
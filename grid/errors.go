@@ -0,0 +1,63 @@
+package grid
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-spatial/geom/slippy"
+	"github.com/go-spatial/proj"
+)
+
+var ErrMissingName = errors.New("grid: missing required param 'name'")
+
+type ErrNotFound string
+
+func (e ErrNotFound) Error() string {
+	return fmt.Sprintf("grid: tile matrix set (%v) not found", string(e))
+}
+
+type ErrAlreadyRegistered string
+
+func (e ErrAlreadyRegistered) Error() string {
+	return fmt.Sprintf("grid: tile matrix set (%v) already registered", string(e))
+}
+
+// ErrInvalidConfig is returned when a param of a custom grid is missing or invalid
+type ErrInvalidConfig struct {
+	Grid string
+	Key  string
+	Err  error
+}
+
+func (e ErrInvalidConfig) Unwrap() error { return e.Err }
+func (e ErrInvalidConfig) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("grid: invalid param '%v' for tile matrix set (%v): %v", e.Key, e.Grid, e.Err)
+	}
+	return fmt.Sprintf("grid: invalid param '%v' for tile matrix set (%v)", e.Key, e.Grid)
+}
+
+type ErrInvalidZoom struct {
+	Grid string
+	Zoom uint
+}
+
+func (e ErrInvalidZoom) Error() string {
+	return fmt.Sprintf("grid: zoom (%v) is not part of tile matrix set (%v)", e.Zoom, e.Grid)
+}
+
+type ErrTileOutOfRange struct {
+	Grid string
+	Tile slippy.Tile
+}
+
+func (e ErrTileOutOfRange) Error() string {
+	return fmt.Sprintf("grid: tile (%v/%v/%v) is not part of tile matrix set (%v)", e.Tile.Z, e.Tile.X, e.Tile.Y, e.Grid)
+}
+
+// ErrUnsupportedSRID is returned when an extent can't be reprojected as the srid is unknown to tegola
+type ErrUnsupportedSRID proj.EPSGCode
+
+func (e ErrUnsupportedSRID) Error() string {
+	return fmt.Sprintf("grid: unable to reproject EPSG:%v, the projection is not known", int(e))
+}
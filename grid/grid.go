@@ -0,0 +1,218 @@
+// Package grid provides the tile grids (tile matrix sets) maps can be tiled
+// in. A grid starts with a matrix of tiles at zoom 0 covering its extent.
+// Each following zoom splits every tile into four, as is the case for the
+// slippy map tiles of WebMercatorQuad.
+package grid
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/slippy"
+	"github.com/go-spatial/proj"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/basic"
+)
+
+const (
+	// WebMercatorQuadID is the id of the default grid
+	WebMercatorQuadID = "WebMercatorQuad"
+	// WorldCRS84QuadID is the id of the WGS84 grid
+	WorldCRS84QuadID = "WorldCRS84Quad"
+
+	// DefaultTileSize is the width and height of a tile in pixels, used to
+	// calculate the cell size and scale of the zooms
+	DefaultTileSize = 256
+)
+
+const webMercatorOrigin = 20037508.3427892
+
+// metersPerDegree is the length of a degree at the equator, used to
+// calculate the scale of grids using degrees
+const metersPerDegree = 2 * math.Pi * 6378137 / 360
+
+// WebMercatorQuad is the grid of the slippy map tiles. It's the grid maps
+// are tiled in unless configured otherwise.
+var WebMercatorQuad = &Grid{
+	ID:                WebMercatorQuadID,
+	Title:             "Google Maps Compatible for the World",
+	URI:               "http://www.opengis.net/def/tilematrixset/OGC/1.0/WebMercatorQuad",
+	CRS:               "http://www.opengis.net/def/crs/EPSG/0/3857",
+	WellKnownScaleSet: "http://www.opengis.net/def/wkss/OGC/1.0/GoogleMapsCompatible",
+	Srid:              tegola.WebMercator,
+	Extent:            &geom.Extent{-webMercatorOrigin, -webMercatorOrigin, webMercatorOrigin, webMercatorOrigin},
+	MatrixWidth:       1,
+	MatrixHeight:      1,
+	TileSize:          DefaultTileSize,
+	MaxZoom:           tegola.MaxZ,
+}
+
+// WorldCRS84Quad is the grid covering the world in WGS84 longitude / latitude,
+// with two tiles at zoom 0
+var WorldCRS84Quad = &Grid{
+	ID:                WorldCRS84QuadID,
+	Title:             "CRS84 for the World",
+	URI:               "http://www.opengis.net/def/tilematrixset/OGC/1.0/WorldCRS84Quad",
+	CRS:               "http://www.opengis.net/def/crs/OGC/1.3/CRS84",
+	WellKnownScaleSet: "http://www.opengis.net/def/wkss/OGC/1.0/GoogleCRS84Quad",
+	Srid:              tegola.WGS84,
+	Extent:            &geom.Extent{-180, -90, 180, 90},
+	MatrixWidth:       2,
+	MatrixHeight:      1,
+	TileSize:          DefaultTileSize,
+	MaxZoom:           tegola.MaxZ,
+}
+
+// Grid is a quad tree tile grid. Tiles are numbered from the top left corner
+// of the extent. Grid implements slippy.TileGridder.
+type Grid struct {
+	// ID identifies the grid in the config and the tile matrix set endpoints
+	ID string
+	// Title is a human readable description of the grid
+	Title string
+	// URI of the OGC definition of the grid, empty for custom grids
+	URI string
+	// CRS is the URI of the coordinate reference system of the grid
+	CRS string
+	// WellKnownScaleSet is the URI of the scale set the grid is based on, if any
+	WellKnownScaleSet string
+	// Srid of the coordinates of the grid
+	Srid proj.EPSGCode
+	// Extent covered by the tiles, in the coordinates of the grid
+	Extent *geom.Extent
+	// MatrixWidth and MatrixHeight are the number of tiles at zoom 0
+	MatrixWidth  uint
+	MatrixHeight uint
+	// TileSize is the width and height of a tile in pixels
+	TileSize uint
+	// MaxZoom is the highest zoom of the grid
+	MaxZoom uint
+}
+
+// SRID returns the SRID of the coordinates of the grid
+func (g *Grid) SRID() proj.EPSGCode { return g.Srid }
+
+// MatrixSize returns the number of columns and rows of tiles at zoom z
+func (g *Grid) MatrixSize(z uint) (width, height uint) {
+	return g.MatrixWidth << z, g.MatrixHeight << z
+}
+
+// TileSpan returns the width and height of a tile at zoom z, in the
+// coordinates of the grid
+func (g *Grid) TileSpan(z uint) (x, y float64) {
+	width, height := g.MatrixSize(z)
+	return g.Extent.XSpan() / float64(width), g.Extent.YSpan() / float64(height)
+}
+
+// CellSize returns the width of a pixel at zoom z, in the coordinates of the grid
+func (g *Grid) CellSize(z uint) float64 {
+	x, _ := g.TileSpan(z)
+	return x / float64(g.TileSize)
+}
+
+// ScaleDenominator returns the scale of zoom z, based on the standardized
+// pixel size of 0.28mm
+func (g *Grid) ScaleDenominator(z uint) float64 {
+	cellSize := g.CellSize(z)
+	if g.Srid == tegola.WGS84 {
+		cellSize *= metersPerDegree
+	}
+	return cellSize / 0.00028
+}
+
+// Size returns a tile where X and Y are the number of columns and rows of
+// tiles at zoom z
+func (g *Grid) Size(z slippy.Zoom) (slippy.Tile, bool) {
+	if uint(z) > g.MaxZoom {
+		return slippy.Tile{}, false
+	}
+	width, height := g.MatrixSize(uint(z))
+	return slippy.Tile{Z: z, X: width, Y: height}, true
+}
+
+// Contains reports if the tile is part of the grid
+func (g *Grid) Contains(t slippy.Tile) bool {
+	size, ok := g.Size(t.Z)
+	return ok && t.X < size.X && t.Y < size.Y
+}
+
+// FromNative returns the tile at zoom z containing pt. Points outside of the
+// extent of the grid return the closest tile.
+func (g *Grid) FromNative(z slippy.Zoom, pt geom.Point) (slippy.Tile, error) {
+	size, ok := g.Size(z)
+	if !ok {
+		return slippy.Tile{}, ErrInvalidZoom{Grid: g.ID, Zoom: uint(z)}
+	}
+
+	spanX, spanY := g.TileSpan(uint(z))
+	return slippy.Tile{
+		Z: z,
+		X: clamp((pt.X()-g.Extent.MinX())/spanX, size.X),
+		Y: clamp((g.Extent.MaxY()-pt.Y())/spanY, size.Y),
+	}, nil
+}
+
+// ToNative returns the top left point of the tile. The tile one past the
+// last column or row is accepted, to get the bottom right of the grid.
+func (g *Grid) ToNative(t slippy.Tile) (geom.Point, error) {
+	size, ok := g.Size(t.Z)
+	if !ok || t.X > size.X || t.Y > size.Y {
+		return geom.Point{}, ErrTileOutOfRange{Grid: g.ID, Tile: t}
+	}
+
+	spanX, spanY := g.TileSpan(uint(t.Z))
+	return geom.Point{
+		g.Extent.MinX() + float64(t.X)*spanX,
+		g.Extent.MaxY() - float64(t.Y)*spanY,
+	}, nil
+}
+
+// ToWGS84 transforms an extent in the coordinates of the grid to WGS84
+func (g *Grid) ToWGS84(ext *geom.Extent) (*geom.Extent, error) {
+	return g.reproject(uint64(g.Srid), tegola.WGS84, ext)
+}
+
+// FromWGS84 transforms an extent in WGS84 to the coordinates of the grid
+func (g *Grid) FromWGS84(ext *geom.Extent) (*geom.Extent, error) {
+	return g.reproject(tegola.WGS84, uint64(g.Srid), ext)
+}
+
+func (g *Grid) reproject(src, dst uint64, ext *geom.Extent) (*geom.Extent, error) {
+	if src == dst {
+		return ext, nil
+	}
+	if !CanReproject(g.Srid) {
+		return nil, ErrUnsupportedSRID(g.Srid)
+	}
+
+	// transform all corners, the edges of the extent may not stay straight
+	pts, err := basic.Reproject(src, dst, geom.MultiPoint(ext.Vertices()))
+	if err != nil {
+		return nil, err
+	}
+	return geom.NewExtent(pts.(geom.MultiPoint)...), nil
+}
+
+// CanReproject reports if tegola knows how to transform geometries from and
+// to the srid
+func CanReproject(srid proj.EPSGCode) bool {
+	return srid == proj.WGS84 || proj.IsKnownConversionSRID(srid)
+}
+
+// clamp converts v to a column or row of a matrix of the given size
+func clamp(v float64, size uint) uint {
+	switch {
+	case v < 0:
+		return 0
+	case v >= float64(size):
+		return size - 1
+	default:
+		return uint(v)
+	}
+}
+
+func (g *Grid) String() string {
+	return fmt.Sprintf("%v (EPSG:%v)", g.ID, int(g.Srid))
+}
@@ -0,0 +1,328 @@
+package grid_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/cmp"
+	"github.com/go-spatial/geom/slippy"
+
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/grid"
+)
+
+func TestGridExtent(t *testing.T) {
+	type tcase struct {
+		grid     *grid.Grid
+		tile     slippy.Tile
+		expected geom.Extent
+		err      error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			ext, err := slippy.Extent(tc.grid, tc.tile)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Errorf("error, expected %v got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error, expected nil got %v", err)
+			}
+			if !cmp.GeomExtent(&tc.expected, ext) {
+				t.Errorf("extent, expected %v got %v", tc.expected, ext)
+			}
+
+			// the center of the tile should map back to the tile
+			tile, err := tc.grid.FromNative(tc.tile.Z, geom.Point{
+				(ext.MinX() + ext.MaxX()) / 2,
+				(ext.MinY() + ext.MaxY()) / 2,
+			})
+			if err != nil {
+				t.Fatalf("from native error, expected nil got %v", err)
+			}
+			if tile != tc.tile {
+				t.Errorf("from native, expected %v got %v", tc.tile, tile)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"web mercator 0/0/0": {
+			grid:     grid.WebMercatorQuad,
+			tile:     slippy.Tile{},
+			expected: geom.Extent{-20037508.3427892, -20037508.3427892, 20037508.3427892, 20037508.3427892},
+		},
+		"web mercator 1/1/0": {
+			grid:     grid.WebMercatorQuad,
+			tile:     slippy.Tile{Z: 1, X: 1, Y: 0},
+			expected: geom.Extent{0, 0, 20037508.3427892, 20037508.3427892},
+		},
+		"crs84 0/0/0": {
+			grid:     grid.WorldCRS84Quad,
+			tile:     slippy.Tile{},
+			expected: geom.Extent{-180, -90, 0, 90},
+		},
+		"crs84 0/1/0": {
+			grid:     grid.WorldCRS84Quad,
+			tile:     slippy.Tile{Z: 0, X: 1, Y: 0},
+			expected: geom.Extent{0, -90, 180, 90},
+		},
+		"crs84 2/7/3": {
+			grid:     grid.WorldCRS84Quad,
+			tile:     slippy.Tile{Z: 2, X: 7, Y: 3},
+			expected: geom.Extent{135, -90, 180, -45},
+		},
+		"crs84 out of range": {
+			grid: grid.WorldCRS84Quad,
+			tile: slippy.Tile{Z: 0, X: 2, Y: 1},
+			err:  grid.ErrTileOutOfRange{Grid: grid.WorldCRS84QuadID, Tile: slippy.Tile{Z: 0, X: 3, Y: 2}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestNewGrid(t *testing.T) {
+	type tcase struct {
+		config   dict.Dict
+		expected grid.Grid
+		err      error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			g, err := grid.NewGrid(tc.config)
+			if tc.err != nil {
+				if err == nil || err.Error() != tc.err.Error() {
+					t.Errorf("error, expected %v got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error, expected nil got %v", err)
+			}
+
+			if g.ID != tc.expected.ID || g.CRS != tc.expected.CRS || g.Srid != tc.expected.Srid {
+				t.Errorf("grid, expected %v (%v) got %v (%v)", tc.expected.String(), tc.expected.CRS, g.String(), g.CRS)
+			}
+			if !cmp.GeomExtent(tc.expected.Extent, g.Extent) {
+				t.Errorf("extent, expected %v got %v", tc.expected.Extent, g.Extent)
+			}
+			if g.MatrixWidth != tc.expected.MatrixWidth || g.MatrixHeight != tc.expected.MatrixHeight {
+				t.Errorf("matrix size, expected %vx%v got %vx%v", tc.expected.MatrixWidth, tc.expected.MatrixHeight, g.MatrixWidth, g.MatrixHeight)
+			}
+			if g.TileSize != tc.expected.TileSize || g.MaxZoom != tc.expected.MaxZoom {
+				t.Errorf("tile size / max zoom, expected %v / %v got %v / %v", tc.expected.TileSize, tc.expected.MaxZoom, g.TileSize, g.MaxZoom)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"defaults": {
+			config: dict.Dict{
+				"name":   "LAEA",
+				"srid":   3035,
+				"extent": []float64{2000000, 1000000, 6500000, 5500000},
+			},
+			expected: grid.Grid{
+				ID:           "LAEA",
+				CRS:          "http://www.opengis.net/def/crs/EPSG/0/3035",
+				Srid:         3035,
+				Extent:       &geom.Extent{2000000, 1000000, 6500000, 5500000},
+				MatrixWidth:  1,
+				MatrixHeight: 1,
+				TileSize:     256,
+				MaxZoom:      22,
+			},
+		},
+		"all params": {
+			config: dict.Dict{
+				"name":          "custom",
+				"title":         "custom grid",
+				"srid":          4326,
+				"extent":        []float64{-180, -90, 180, 90},
+				"matrix_width":  4,
+				"matrix_height": 2,
+				"tile_size":     512,
+				"max_zoom":      10,
+			},
+			expected: grid.Grid{
+				ID:           "custom",
+				CRS:          "http://www.opengis.net/def/crs/EPSG/0/4326",
+				Srid:         4326,
+				Extent:       &geom.Extent{-180, -90, 180, 90},
+				MatrixWidth:  4,
+				MatrixHeight: 2,
+				TileSize:     512,
+				MaxZoom:      10,
+			},
+		},
+		"missing name": {
+			config: dict.Dict{
+				"srid":   3035,
+				"extent": []float64{2000000, 1000000, 6500000, 5500000},
+			},
+			err: grid.ErrMissingName,
+		},
+		"missing extent": {
+			config: dict.Dict{
+				"name": "LAEA",
+				"srid": 3035,
+			},
+			err: grid.ErrInvalidConfig{Grid: "LAEA", Key: "extent"},
+		},
+		"inverted extent": {
+			config: dict.Dict{
+				"name":   "LAEA",
+				"srid":   3035,
+				"extent": []float64{6500000, 1000000, 2000000, 5500000},
+			},
+			err: grid.ErrInvalidConfig{Grid: "LAEA", Key: "extent"},
+		},
+		"zero matrix width": {
+			config: dict.Dict{
+				"name":         "LAEA",
+				"srid":         3035,
+				"extent":       []float64{2000000, 1000000, 6500000, 5500000},
+				"matrix_width": 0,
+			},
+			err: grid.ErrInvalidConfig{Grid: "LAEA", Key: "matrix_width"},
+		},
+		"max zoom too high": {
+			config: dict.Dict{
+				"name":     "LAEA",
+				"srid":     3035,
+				"extent":   []float64{2000000, 1000000, 6500000, 5500000},
+				"max_zoom": 30,
+			},
+			err: grid.ErrInvalidConfig{Grid: "LAEA", Key: "max_zoom"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestRegister(t *testing.T) {
+	if err := grid.Register(&grid.Grid{ID: grid.WebMercatorQuadID}); err != grid.ErrAlreadyRegistered(grid.WebMercatorQuadID) {
+		t.Errorf("register builtin, expected %v got %v", grid.ErrAlreadyRegistered(grid.WebMercatorQuadID), err)
+	}
+
+	custom := &grid.Grid{ID: "test-grid", Srid: 3035, Extent: &geom.Extent{0, 0, 1, 1}, MatrixWidth: 1, MatrixHeight: 1}
+	if err := grid.Register(custom); err != nil {
+		t.Fatalf("register, expected nil got %v", err)
+	}
+	g, err := grid.For("test-grid")
+	if err != nil {
+		t.Fatalf("for, expected nil got %v", err)
+	}
+	if g != custom {
+		t.Errorf("for, expected %v got %v", custom, g)
+	}
+
+	if _, err = grid.For("missing"); err != grid.ErrNotFound("missing") {
+		t.Errorf("for missing, expected %v got %v", grid.ErrNotFound("missing"), err)
+	}
+}
+
+func TestMaxMatrixSize(t *testing.T) {
+	custom := &grid.Grid{ID: "test-grid-wide", Srid: 3035, Extent: &geom.Extent{0, 0, 3, 5}, MatrixWidth: 3, MatrixHeight: 5, MaxZoom: 2}
+	if err := grid.Register(custom); err != nil {
+		t.Fatalf("register, expected nil got %v", err)
+	}
+
+	tests := map[string]struct {
+		z                   uint
+		expWidth, expHeight uint
+	}{
+		"custom grid":                {z: 1, expWidth: 6, expHeight: 10},
+		"above the custom max zoom":  {z: 3, expWidth: 16, expHeight: 8},
+		"above the builtin max zoom": {z: 23},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			width, height := grid.MaxMatrixSize(tc.z)
+			if width != tc.expWidth || height != tc.expHeight {
+				t.Errorf("expected %vx%v got %vx%v", tc.expWidth, tc.expHeight, width, height)
+			}
+		})
+	}
+}
+
+func TestCover(t *testing.T) {
+	type tcase struct {
+		grid     *grid.Grid
+		z        slippy.Zoom
+		polygons []geom.Polygon
+		expected []slippy.Tile
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			var tiles []slippy.Tile
+			err := tc.grid.Cover(tc.z, tc.polygons, func(tile slippy.Tile) error {
+				tiles = append(tiles, tile)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("error, expected nil got %v", err)
+			}
+			if len(tiles) != len(tc.expected) {
+				t.Fatalf("tiles, expected %v got %v", tc.expected, tiles)
+			}
+			for i := range tiles {
+				if tiles[i] != tc.expected[i] {
+					t.Errorf("tile %v, expected %v got %v", i, tc.expected[i], tiles[i])
+				}
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"no polygons": {
+			grid: grid.WorldCRS84Quad,
+			z:    1,
+		},
+		"outside of the grid": {
+			grid:     grid.WorldCRS84Quad,
+			z:        1,
+			polygons: []geom.Polygon{(&geom.Extent{200, 0, 210, 10}).AsPolygon()},
+		},
+		"within a tile": {
+			grid:     grid.WorldCRS84Quad,
+			z:        1,
+			polygons: []geom.Polygon{(&geom.Extent{10, 10, 20, 20}).AsPolygon()},
+			expected: []slippy.Tile{{Z: 1, X: 2, Y: 0}},
+		},
+		"triangle skipping a corner tile": {
+			grid: grid.WorldCRS84Quad,
+			z:    1,
+			polygons: []geom.Polygon{{{
+				{-170, 80}, {-10, 80}, {-170, -60},
+			}}},
+			expected: []slippy.Tile{
+				{Z: 1, X: 0, Y: 0},
+				{Z: 1, X: 0, Y: 1},
+				{Z: 1, X: 1, Y: 0},
+			},
+		},
+		"whole grid": {
+			grid:     grid.WorldCRS84Quad,
+			z:        0,
+			polygons: []geom.Polygon{grid.WorldCRS84Quad.Extent.AsPolygon()},
+			expected: []slippy.Tile{{Z: 0, X: 0, Y: 0}, {Z: 0, X: 1, Y: 0}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,174 @@
+package grid
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/proj"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+)
+
+const (
+	ConfigKeyName         = "name"
+	ConfigKeyTitle        = "title"
+	ConfigKeySRID         = "srid"
+	ConfigKeyProj         = "proj"
+	ConfigKeyExtent       = "extent"
+	ConfigKeyMatrixWidth  = "matrix_width"
+	ConfigKeyMatrixHeight = "matrix_height"
+	ConfigKeyTileSize     = "tile_size"
+	ConfigKeyMaxZoom      = "max_zoom"
+)
+
+var (
+	gridsLock sync.RWMutex
+	grids     = map[string]*Grid{
+		WebMercatorQuad.ID: WebMercatorQuad,
+		WorldCRS84Quad.ID:  WorldCRS84Quad,
+	}
+)
+
+// Register makes the grid available by its ID. Registering a grid with the
+// ID of a custom grid that is already registered replaces it, the built in
+// grids can not be replaced.
+func Register(g *Grid) error {
+	if g == nil || g.ID == "" {
+		return ErrMissingName
+	}
+
+	gridsLock.Lock()
+	defer gridsLock.Unlock()
+
+	if g.ID == WebMercatorQuadID || g.ID == WorldCRS84QuadID {
+		return ErrAlreadyRegistered(g.ID)
+	}
+	grids[g.ID] = g
+	return nil
+}
+
+// For returns the registered grid with the given ID
+func For(id string) (*Grid, error) {
+	gridsLock.RLock()
+	defer gridsLock.RUnlock()
+
+	g, ok := grids[id]
+	if !ok {
+		return nil, ErrNotFound(id)
+	}
+	return g, nil
+}
+
+// Registered returns the registered grids, sorted by ID
+func Registered() []*Grid {
+	gridsLock.RLock()
+	defer gridsLock.RUnlock()
+
+	ret := make([]*Grid, 0, len(grids))
+	for _, g := range grids {
+		ret = append(ret, g)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].ID < ret[j].ID })
+	return ret
+}
+
+// MaxMatrixSize returns the largest number of columns and rows of tiles at
+// zoom z of the registered grids
+func MaxMatrixSize(z uint) (width, height uint) {
+	gridsLock.RLock()
+	defer gridsLock.RUnlock()
+
+	for _, g := range grids {
+		if z > g.MaxZoom {
+			continue
+		}
+		w, h := g.MatrixSize(z)
+		width, height = max(width, w), max(height, h)
+	}
+	return width, height
+}
+
+// NewGrid creates a custom grid from the config. The config expects the following params:
+//
+//	name (string): [Required] the id of the grid, used by the tile_matrix_set param of maps
+//	srid (int): [Required] the srid of the grid
+//	extent ([]float): [Required] the extent covered by the tiles: min x, min y, max x, max y
+//	title (string): [Optional] a description of the grid
+//	proj (string): [Optional] proj definition of the srid, needed to reproject geometries
+//	               to an srid tegola doesn't know
+//	matrix_width (int): [Optional] number of tile columns at zoom 0. Defaults to 1
+//	matrix_height (int): [Optional] number of tile rows at zoom 0. Defaults to 1
+//	tile_size (int): [Optional] tile width and height in pixels. Defaults to 256
+//	max_zoom (int): [Optional] the highest zoom of the grid. Defaults to 22
+func NewGrid(config dict.Dicter) (*Grid, error) {
+	name, err := config.String(ConfigKeyName, nil)
+	if err != nil || name == "" {
+		return nil, ErrMissingName
+	}
+
+	g := Grid{
+		ID: name,
+	}
+
+	defaultTitle := ""
+	if g.Title, err = config.String(ConfigKeyTitle, &defaultTitle); err != nil {
+		return nil, err
+	}
+
+	srid, err := config.Int(ConfigKeySRID, nil)
+	if err != nil {
+		return nil, ErrInvalidConfig{Grid: name, Key: ConfigKeySRID, Err: err}
+	}
+	if srid <= 0 {
+		return nil, ErrInvalidConfig{Grid: name, Key: ConfigKeySRID}
+	}
+	g.Srid = proj.EPSGCode(srid)
+	g.CRS = "http://www.opengis.net/def/crs/EPSG/0/" + strconv.Itoa(srid)
+
+	defaultProj := ""
+	projStr, err := config.String(ConfigKeyProj, &defaultProj)
+	if err != nil {
+		return nil, ErrInvalidConfig{Grid: name, Key: ConfigKeyProj, Err: err}
+	}
+	if projStr != "" {
+		proj.CustomProjection(g.Srid, projStr)
+	}
+
+	extent, err := config.FloatSlice(ConfigKeyExtent)
+	if err != nil {
+		return nil, ErrInvalidConfig{Grid: name, Key: ConfigKeyExtent, Err: err}
+	}
+	if len(extent) != 4 || extent[0] >= extent[2] || extent[1] >= extent[3] {
+		return nil, ErrInvalidConfig{Grid: name, Key: ConfigKeyExtent}
+	}
+	g.Extent = &geom.Extent{extent[0], extent[1], extent[2], extent[3]}
+
+	one, tileSize, maxZoom := 1, DefaultTileSize, int(tegola.MaxZ)
+	for _, v := range []struct {
+		key string
+		def *int
+		min int
+		max int
+		val *uint
+	}{
+		{ConfigKeyMatrixWidth, &one, 1, math.MaxInt32, &g.MatrixWidth},
+		{ConfigKeyMatrixHeight, &one, 1, math.MaxInt32, &g.MatrixHeight},
+		{ConfigKeyTileSize, &tileSize, 1, math.MaxInt32, &g.TileSize},
+		{ConfigKeyMaxZoom, &maxZoom, 0, tegola.MaxZ, &g.MaxZoom},
+	} {
+		i, err := config.Int(v.key, v.def)
+		if err != nil {
+			return nil, ErrInvalidConfig{Grid: name, Key: v.key, Err: err}
+		}
+		if i < v.min || i > v.max {
+			return nil, ErrInvalidConfig{Grid: name, Key: v.key}
+		}
+		*v.val = uint(i)
+	}
+
+	return &g, nil
+}
@@ -0,0 +1,12 @@
+//go:build noShapefileProvider
+// +build noShapefileProvider
+
+// This file was autogenerated DO NOT EDIT
+// the file was generated with the following command "internal/build/tags.go"
+
+package build
+
+func init() {
+	// add noShapefileProvider to the Tags
+	Tags = append(Tags, "noShapefileProvider")
+}
@@ -0,0 +1,12 @@
+//go:build !noFlatgeobufProvider
+// +build !noFlatgeobufProvider
+
+// This file was autogenerated DO NOT EDIT
+// the file was generated with the following command "internal/build/tags.go"
+
+package build
+
+func init() {
+	// add !noFlatgeobufProvider to the Tags
+	Tags = append(Tags, "!noFlatgeobufProvider")
+}
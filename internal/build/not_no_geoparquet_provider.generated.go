@@ -0,0 +1,12 @@
+//go:build !noGeoparquetProvider
+// +build !noGeoparquetProvider
+
+// This file was autogenerated DO NOT EDIT
+// the file was generated with the following command "internal/build/tags.go"
+
+package build
+
+func init() {
+	// add !noGeoparquetProvider to the Tags
+	Tags = append(Tags, "!noGeoparquetProvider")
+}
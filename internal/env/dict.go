@@ -414,6 +414,15 @@ func (d Dict) Map(key string) (r dict.Dicter, err error) {
 		return Dict{}, nil
 	}
 
+	// nested tables of the layers of the providers are not parsed as Dicts
+	if m, ok := v.(map[string]interface{}); ok {
+		d, err := ParseDict(m)
+		if err != nil {
+			return r, err
+		}
+		return *d, nil
+	}
+
 	r, ok = v.(Dict)
 	if !ok {
 		switch err.(type) {
@@ -0,0 +1,226 @@
+// Package mbtiles reads and writes MBTiles 1.3 tile sets. MBTiles is an
+// SQLite database holding the tiles of a single tile set along with its
+// metadata. The spec can be found at:
+// https://github.com/mapbox/mbtiles-spec/blob/master/1.3/spec.md
+package mbtiles
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/go-spatial/tegola/cache"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// schema creates the tables and indexes of the spec if they don't exist
+const schema = `
+CREATE TABLE IF NOT EXISTS metadata (name TEXT, value TEXT);
+CREATE UNIQUE INDEX IF NOT EXISTS metadata_name ON metadata (name);
+CREATE TABLE IF NOT EXISTS tiles (zoom_level INTEGER, tile_column INTEGER, tile_row INTEGER, tile_data BLOB);
+CREATE UNIQUE INDEX IF NOT EXISTS tile_index ON tiles (zoom_level, tile_column, tile_row);
+`
+
+// DB is an MBTiles tile set. DB implements cache.Interface, keyed on the
+// z/x/y of the key, so it can be used where tegola expects a cache. Keys for
+// individual layers are ignored as a tile set only holds complete tiles.
+type DB struct {
+	path string
+	db   *sql.DB
+	// readOnly is set for tile sets opened with OpenReadOnly
+	readOnly bool
+}
+
+// Open opens the MBTiles file at path, creating it if it does not exist.
+// The database is written in WAL mode without syncing every commit, seeding
+// writes a tile per transaction and a rollback journal fsyncs each of them.
+func Open(path string) (*DB, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_journal_mode=WAL&_synchronous=NORMAL")
+	if err != nil {
+		return nil, fmt.Errorf("mbtiles: opening %v: %w", path, err)
+	}
+	// sqlite only supports a single writer; funnel all access through one
+	// connection instead of fighting over the database lock.
+	db.SetMaxOpenConns(1)
+
+	if _, err = db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("mbtiles: creating schema in %v: %w", path, err)
+	}
+
+	return &DB{
+		path: path,
+		db:   db,
+	}, nil
+}
+
+// OpenReadOnly opens the existing MBTiles file at path for reading. Unlike
+// Open, the file is not created if it does not exist and reads are not
+// serialized over a single connection.
+func OpenReadOnly(path string) (*DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("mbtiles: opening %v: %w", path, err)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("mbtiles: opening %v: %w", path, err)
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("mbtiles: opening %v: %w", path, err)
+	}
+
+	return &DB{
+		path:     path,
+		db:       db,
+		readOnly: true,
+	}, nil
+}
+
+// Path returns the location of the tile set
+func (mb *DB) Path() string { return mb.path }
+
+// tileRow converts a y from the XYZ scheme tegola uses into the TMS scheme
+// of the spec, which counts rows from the bottom.
+func tileRow(z, y uint) uint {
+	return (1 << z) - 1 - y
+}
+
+// Get returns the tile data for the key. The data is returned as stored,
+// tiles set by tegola are gzip compressed.
+func (mb *DB) Get(ctx context.Context, key *cache.Key) ([]byte, bool, error) {
+	if key.LayerName != "" {
+		return nil, false, nil
+	}
+
+	var data []byte
+	err := mb.db.QueryRowContext(ctx,
+		"SELECT tile_data FROM tiles WHERE zoom_level = ? AND tile_column = ? AND tile_row = ?",
+		key.Z, key.X, tileRow(key.Z, key.Y),
+	).Scan(&data)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, false, nil
+	case err != nil:
+		return nil, false, fmt.Errorf("mbtiles: reading tile (%v/%v/%v): %w", key.Z, key.X, key.Y, err)
+	}
+
+	return data, true, nil
+}
+
+// Set writes the tile data for the key, replacing an existing tile
+func (mb *DB) Set(ctx context.Context, key *cache.Key, val []byte) error {
+	if key.LayerName != "" {
+		return nil
+	}
+
+	_, err := mb.db.ExecContext(ctx,
+		"INSERT OR REPLACE INTO tiles (zoom_level, tile_column, tile_row, tile_data) VALUES (?, ?, ?, ?)",
+		key.Z, key.X, tileRow(key.Z, key.Y), val,
+	)
+	if err != nil {
+		return fmt.Errorf("mbtiles: writing tile (%v/%v/%v): %w", key.Z, key.X, key.Y, err)
+	}
+	return nil
+}
+
+// Purge removes the tile for the key
+func (mb *DB) Purge(ctx context.Context, key *cache.Key) error {
+	if key.LayerName != "" {
+		return nil
+	}
+
+	_, err := mb.db.ExecContext(ctx,
+		"DELETE FROM tiles WHERE zoom_level = ? AND tile_column = ? AND tile_row = ?",
+		key.Z, key.X, tileRow(key.Z, key.Y),
+	)
+	if err != nil {
+		return fmt.Errorf("mbtiles: purging tile (%v/%v/%v): %w", key.Z, key.X, key.Y, err)
+	}
+	return nil
+}
+
+// PurgePrefix removes the tiles between minZoom and maxZoom. The file holds
+// the tiles of a single map, mapName is not checked. The tiles of the map
+// include all the layers, they are removed for any layerName.
+func (mb *DB) PurgePrefix(ctx context.Context, mapName, layerName string, minZoom, maxZoom uint) error {
+	_, err := mb.db.ExecContext(ctx,
+		"DELETE FROM tiles WHERE zoom_level >= ? AND zoom_level <= ?",
+		minZoom, maxZoom,
+	)
+	if err != nil {
+		return fmt.Errorf("mbtiles: purging zooms (%v-%v): %w", minZoom, maxZoom, err)
+	}
+	return nil
+}
+
+// ZoomRange returns the lowest and highest zoom of the stored tiles.
+// ok is false if the tile set has no tiles.
+func (mb *DB) ZoomRange(ctx context.Context) (min, max uint, ok bool, err error) {
+	var zmin, zmax sql.NullInt64
+	err = mb.db.QueryRowContext(ctx, "SELECT MIN(zoom_level), MAX(zoom_level) FROM tiles").Scan(&zmin, &zmax)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("mbtiles: reading zoom range: %w", err)
+	}
+	if !zmin.Valid || !zmax.Valid {
+		return 0, 0, false, nil
+	}
+	return uint(zmin.Int64), uint(zmax.Int64), true, nil
+}
+
+// Metadata returns the name/value pairs of the metadata table
+func (mb *DB) Metadata(ctx context.Context) (map[string]string, error) {
+	rows, err := mb.db.QueryContext(ctx, "SELECT name, value FROM metadata")
+	if err != nil {
+		return nil, fmt.Errorf("mbtiles: reading metadata: %w", err)
+	}
+	defer rows.Close()
+
+	md := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err = rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("mbtiles: reading metadata: %w", err)
+		}
+		md[name] = value
+	}
+
+	return md, rows.Err()
+}
+
+// SetMetadata writes the name/value pairs to the metadata table, replacing
+// existing values of the same name
+func (mb *DB) SetMetadata(ctx context.Context, md map[string]string) error {
+	tx, err := mb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("mbtiles: writing metadata: %w", err)
+	}
+
+	for name, value := range md {
+		if _, err = tx.ExecContext(ctx, "INSERT OR REPLACE INTO metadata (name, value) VALUES (?, ?)", name, value); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("mbtiles: writing metadata (%v): %w", name, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("mbtiles: writing metadata: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying database. A tile set opened for writing is
+// switched back to a rollback journal first, checkpointing the WAL into the
+// file so it can be copied and read on its own.
+func (mb *DB) Close() error {
+	if !mb.readOnly {
+		if _, err := mb.db.Exec("PRAGMA journal_mode=DELETE"); err != nil {
+			mb.db.Close()
+			return fmt.Errorf("mbtiles: closing %v: %w", mb.path, err)
+		}
+	}
+	return mb.db.Close()
+}
@@ -0,0 +1,197 @@
+//go:build cgo
+// +build cgo
+
+package mbtiles_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/internal/mbtiles"
+)
+
+func TestSetGetPurge(t *testing.T) {
+	type tcase struct {
+		key      cache.Key
+		expected []byte
+		// expectHit is false for keys the tile set does not store
+		expectHit bool
+	}
+
+	ctx := t.Context()
+
+	db, err := mbtiles.Open(filepath.Join(t.TempDir(), "test.mbtiles"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			if err := db.Set(ctx, &tc.key, tc.expected); err != nil {
+				t.Fatalf("write failed. err: %v", err)
+			}
+
+			output, hit, err := db.Get(ctx, &tc.key)
+			if err != nil {
+				t.Fatalf("read failed. err: %v", err)
+			}
+			if hit != tc.expectHit {
+				t.Fatalf("hit, expected %v got %v", tc.expectHit, hit)
+			}
+			if !tc.expectHit {
+				return
+			}
+			if !reflect.DeepEqual(output, tc.expected) {
+				t.Errorf("expected %v got %v", tc.expected, output)
+			}
+
+			if err = db.Purge(ctx, &tc.key); err != nil {
+				t.Fatalf("purge failed. err: %v", err)
+			}
+			if _, hit, err = db.Get(ctx, &tc.key); err != nil || hit {
+				t.Errorf("after purge, expected miss got hit %v err %v", hit, err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"map tile": {
+			key:       cache.Key{MapName: "osm", Z: 3, X: 2, Y: 1},
+			expected:  []byte{0x53, 0x69, 0x6c, 0x61, 0x73},
+			expectHit: true,
+		},
+		"zoom 0": {
+			key:       cache.Key{Z: 0, X: 0, Y: 0},
+			expected:  []byte{0x66, 0x6f, 0x6f},
+			expectHit: true,
+		},
+		"layer tile": {
+			key:      cache.Key{MapName: "osm", LayerName: "water", Z: 3, X: 2, Y: 1},
+			expected: []byte{0x66, 0x6f, 0x6f},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestTMSRows(t *testing.T) {
+	ctx := t.Context()
+	path := filepath.Join(t.TempDir(), "test.mbtiles")
+
+	db, err := mbtiles.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	// tile 2/1/0 in XYZ is row 3 in TMS
+	if err = db.Set(ctx, &cache.Key{Z: 2, X: 1, Y: 0}, []byte("tile")); err != nil {
+		t.Fatalf("write failed. err: %v", err)
+	}
+
+	_, hit, err := db.Get(ctx, &cache.Key{Z: 2, X: 1, Y: 3})
+	if err != nil {
+		t.Fatalf("read failed. err: %v", err)
+	}
+	if hit {
+		t.Errorf("expected the y of 2/1/3 to not collide with 2/1/0")
+	}
+
+	min, max, ok, err := db.ZoomRange(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok || min != 2 || max != 2 {
+		t.Errorf("zoom range, expected 2, 2, true got %v, %v, %v", min, max, ok)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	ctx := t.Context()
+	path := filepath.Join(t.TempDir(), "test.mbtiles")
+
+	db, err := mbtiles.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, _, ok, err := db.ZoomRange(ctx); err != nil || ok {
+		t.Errorf("zoom range of empty tile set, expected not ok got %v err %v", ok, err)
+	}
+
+	expected := map[string]string{
+		"name":   "osm",
+		"format": "pbf",
+	}
+	if err = db.SetMetadata(ctx, expected); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// values are replaced by name
+	expected["name"] = "osm-bright"
+	if err = db.SetMetadata(ctx, map[string]string{"name": "osm-bright"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = db.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// reopening an existing tile set keeps its contents
+	db, err = mbtiles.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	md, err := db.Metadata(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(md, expected) {
+		t.Errorf("expected %v got %v", expected, md)
+	}
+}
+
+func TestJournal(t *testing.T) {
+	ctx := t.Context()
+	path := filepath.Join(t.TempDir(), "test.mbtiles")
+
+	db, err := mbtiles.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = db.Set(ctx, &cache.Key{Z: 1, X: 1, Y: 1}, []byte{0x1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// writes go through the write-ahead log
+	if _, err = os.Stat(path + "-wal"); err != nil {
+		t.Errorf("write-ahead log, expected it to exist got %v", err)
+	}
+
+	if err = db.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// closing leaves a single file behind
+	if _, err = os.Stat(path + "-wal"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("write-ahead log, expected it to be removed got %v", err)
+	}
+
+	db, err = mbtiles.OpenReadOnly(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	if _, hit, err := db.Get(ctx, &cache.Key{Z: 1, X: 1, Y: 1}); err != nil || !hit {
+		t.Errorf("tile, expected hit got %v err %v", hit, err)
+	}
+}
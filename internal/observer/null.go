@@ -31,3 +31,6 @@ func (Null) InstrumentedViewerHttpHandler(_, _ string, handler http.Handler) htt
 func (Null) Name() string { return "none" }
 
 func (Null) InstrumentedCache(c cache.Interface) cache.Interface { return c }
+
+// ObserveCoalescedTile does not do anything
+func (Null) ObserveCoalescedTile(_ *cache.Key) {}
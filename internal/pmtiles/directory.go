@@ -0,0 +1,178 @@
+package pmtiles
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Entry is a single directory entry. An entry with a RunLength of 0
+// points to a leaf directory, otherwise it points to tile data and
+// covers RunLength consecutive tile ids starting at TileID.
+type Entry struct {
+	TileID    uint64
+	Offset    uint64
+	Length    uint32
+	RunLength uint32
+}
+
+// IsLeaf reports if the entry points to a leaf directory
+func (e Entry) IsLeaf() bool { return e.RunLength == 0 }
+
+// findEntry returns the entry covering the tile id. If the tile id is
+// not covered by the entries, ok will be false. Leaf directory entries
+// are returned if the tile id falls within the range of the leaf.
+func findEntry(entries []Entry, id uint64) (entry Entry, ok bool) {
+	// find the first entry with a tile id greater than the requested id.
+	// the entry before it is the only candidate.
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].TileID > id })
+	if i == 0 {
+		return entry, false
+	}
+	entry = entries[i-1]
+	if entry.IsLeaf() {
+		return entry, true
+	}
+	if id-entry.TileID < uint64(entry.RunLength) {
+		return entry, true
+	}
+	return entry, false
+}
+
+// serializeEntries encodes and compresses a directory
+func serializeEntries(entries []Entry, compression Compression) ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		tmp = make([]byte, binary.MaxVarintLen64)
+	)
+
+	putVarint := func(v uint64) {
+		n := binary.PutUvarint(tmp, v)
+		buf.Write(tmp[:n])
+	}
+
+	putVarint(uint64(len(entries)))
+
+	var lastID uint64
+	for i := range entries {
+		putVarint(entries[i].TileID - lastID)
+		lastID = entries[i].TileID
+	}
+	for i := range entries {
+		putVarint(uint64(entries[i].RunLength))
+	}
+	for i := range entries {
+		putVarint(uint64(entries[i].Length))
+	}
+	for i := range entries {
+		// an offset of 0 denotes the data directly follows the previous entry
+		if i > 0 && entries[i].Offset == entries[i-1].Offset+uint64(entries[i-1].Length) {
+			putVarint(0)
+			continue
+		}
+		putVarint(entries[i].Offset + 1)
+	}
+
+	return compress(buf.Bytes(), compression)
+}
+
+// deserializeEntries decompresses and decodes a directory
+func deserializeEntries(data []byte, compression Compression) ([]Entry, error) {
+	raw, err := decompress(data, compression)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(bytes.NewReader(raw))
+
+	num, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("pmtiles: reading directory entry count: %w", err)
+	}
+	// every entry takes at least 4 bytes so we can catch corrupt counts
+	// before making a large allocation
+	if num > uint64(len(raw))/4 {
+		return nil, fmt.Errorf("pmtiles: invalid directory entry count (%v)", num)
+	}
+
+	entries := make([]Entry, num)
+
+	var lastID uint64
+	for i := range entries {
+		v, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("pmtiles: reading directory tile ids: %w", err)
+		}
+		lastID += v
+		entries[i].TileID = lastID
+	}
+	for i := range entries {
+		v, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("pmtiles: reading directory run lengths: %w", err)
+		}
+		entries[i].RunLength = uint32(v)
+	}
+	for i := range entries {
+		v, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("pmtiles: reading directory lengths: %w", err)
+		}
+		entries[i].Length = uint32(v)
+	}
+	for i := range entries {
+		v, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("pmtiles: reading directory offsets: %w", err)
+		}
+		if v == 0 {
+			if i == 0 {
+				return nil, fmt.Errorf("pmtiles: invalid offset for first directory entry")
+			}
+			entries[i].Offset = entries[i-1].Offset + uint64(entries[i-1].Length)
+			continue
+		}
+		entries[i].Offset = v - 1
+	}
+
+	return entries, nil
+}
+
+func compress(data []byte, compression Compression) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, ErrUnsupportedCompression(compression)
+	}
+}
+
+func decompress(data []byte, compression Compression) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	default:
+		return nil, ErrUnsupportedCompression(compression)
+	}
+}
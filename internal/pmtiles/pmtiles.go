@@ -0,0 +1,297 @@
+// Package pmtiles implements reading and writing of PMTiles v3 archives.
+// PMTiles is a single file archive format for tiled data addressed by
+// z/x/y coordinates. The spec can be found at:
+// https://github.com/protomaps/PMTiles/blob/main/spec/v3/spec.md
+package pmtiles
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/slippy"
+)
+
+const (
+	// HeaderLength is the fixed length of a v3 header in bytes
+	HeaderLength = 127
+	// MaxRootLength is the max length of the header and root directory combined.
+	// Clients fetch the first 16KiB of an archive in a single request.
+	MaxRootLength = 16384
+	// Version is the spec version this package reads and writes
+	Version = 3
+
+	magic = "PMTiles"
+	// maxZoom is the highest zoom a tile id can be computed for without
+	// overflowing a uint64
+	maxZoom = 31
+)
+
+var (
+	ErrInvalidMagic   = errors.New("pmtiles: invalid magic number")
+	ErrInvalidHeader  = errors.New("pmtiles: header is too short")
+	ErrInvalidTileID  = errors.New("pmtiles: invalid tile id")
+	ErrWriterFinished = errors.New("pmtiles: writer already finalized")
+)
+
+type ErrUnsupportedVersion uint8
+
+func (e ErrUnsupportedVersion) Error() string {
+	return fmt.Sprintf("pmtiles: unsupported spec version (%v)", uint8(e))
+}
+
+type ErrUnsupportedCompression Compression
+
+func (e ErrUnsupportedCompression) Error() string {
+	return fmt.Sprintf("pmtiles: unsupported internal compression (%v)", uint8(e))
+}
+
+// Compression is the compression type used for the directories and metadata (internal)
+// or the tile data of an archive.
+type Compression uint8
+
+const (
+	CompressionUnknown Compression = iota
+	CompressionNone
+	CompressionGzip
+	CompressionBrotli
+	CompressionZstd
+)
+
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionGzip:
+		return "gzip"
+	case CompressionBrotli:
+		return "brotli"
+	case CompressionZstd:
+		return "zstd"
+	default:
+		return "unknown"
+	}
+}
+
+// TileType is the format of the tile data of an archive
+type TileType uint8
+
+const (
+	TileTypeUnknown TileType = iota
+	TileTypeMVT
+	TileTypePNG
+	TileTypeJPEG
+	TileTypeWebP
+	TileTypeAVIF
+)
+
+// Header is the fixed length section at the start of every archive
+type Header struct {
+	RootOffset          uint64
+	RootLength          uint64
+	MetadataOffset      uint64
+	MetadataLength      uint64
+	LeafDirsOffset      uint64
+	LeafDirsLength      uint64
+	TileDataOffset      uint64
+	TileDataLength      uint64
+	AddressedTilesCount uint64
+	TileEntriesCount    uint64
+	TileContentsCount   uint64
+	Clustered           bool
+	InternalCompression Compression
+	TileCompression     Compression
+	TileType            TileType
+	MinZoom             uint8
+	MaxZoom             uint8
+	// MinLonE7, MinLatE7, MaxLonE7 and MaxLatE7 are WGS84 coordinates
+	// multiplied by 10,000,000
+	MinLonE7    int32
+	MinLatE7    int32
+	MaxLonE7    int32
+	MaxLatE7    int32
+	CenterZoom  uint8
+	CenterLonE7 int32
+	CenterLatE7 int32
+}
+
+// Bounds returns the bounds of the archive in WGS84
+func (h Header) Bounds() *geom.Extent {
+	return geom.NewExtent(
+		[2]float64{fromE7(h.MinLonE7), fromE7(h.MinLatE7)},
+		[2]float64{fromE7(h.MaxLonE7), fromE7(h.MaxLatE7)},
+	)
+}
+
+// SetBounds sets the bounds of the archive from a WGS84 extent
+func (h *Header) SetBounds(ext *geom.Extent) {
+	h.MinLonE7, h.MinLatE7 = toE7(ext.MinX()), toE7(ext.MinY())
+	h.MaxLonE7, h.MaxLatE7 = toE7(ext.MaxX()), toE7(ext.MaxY())
+}
+
+// Center returns the center of the archive as lon, lat, zoom
+func (h Header) Center() [3]float64 {
+	return [3]float64{fromE7(h.CenterLonE7), fromE7(h.CenterLatE7), float64(h.CenterZoom)}
+}
+
+// SetCenter sets the center of the archive from a lon, lat, zoom triple
+func (h *Header) SetCenter(center [3]float64) {
+	h.CenterLonE7 = toE7(center[0])
+	h.CenterLatE7 = toE7(center[1])
+	h.CenterZoom = uint8(center[2])
+}
+
+func toE7(f float64) int32   { return int32(math.Round(f * 10000000)) }
+func fromE7(i int32) float64 { return float64(i) / 10000000 }
+
+// MarshalBinary encodes the header into its 127 byte representation
+func (h Header) MarshalBinary() ([]byte, error) {
+	b := make([]byte, HeaderLength)
+	copy(b[0:7], magic)
+	b[7] = Version
+	binary.LittleEndian.PutUint64(b[8:16], h.RootOffset)
+	binary.LittleEndian.PutUint64(b[16:24], h.RootLength)
+	binary.LittleEndian.PutUint64(b[24:32], h.MetadataOffset)
+	binary.LittleEndian.PutUint64(b[32:40], h.MetadataLength)
+	binary.LittleEndian.PutUint64(b[40:48], h.LeafDirsOffset)
+	binary.LittleEndian.PutUint64(b[48:56], h.LeafDirsLength)
+	binary.LittleEndian.PutUint64(b[56:64], h.TileDataOffset)
+	binary.LittleEndian.PutUint64(b[64:72], h.TileDataLength)
+	binary.LittleEndian.PutUint64(b[72:80], h.AddressedTilesCount)
+	binary.LittleEndian.PutUint64(b[80:88], h.TileEntriesCount)
+	binary.LittleEndian.PutUint64(b[88:96], h.TileContentsCount)
+	if h.Clustered {
+		b[96] = 1
+	}
+	b[97] = uint8(h.InternalCompression)
+	b[98] = uint8(h.TileCompression)
+	b[99] = uint8(h.TileType)
+	b[100] = h.MinZoom
+	b[101] = h.MaxZoom
+	binary.LittleEndian.PutUint32(b[102:106], uint32(h.MinLonE7))
+	binary.LittleEndian.PutUint32(b[106:110], uint32(h.MinLatE7))
+	binary.LittleEndian.PutUint32(b[110:114], uint32(h.MaxLonE7))
+	binary.LittleEndian.PutUint32(b[114:118], uint32(h.MaxLatE7))
+	b[118] = h.CenterZoom
+	binary.LittleEndian.PutUint32(b[119:123], uint32(h.CenterLonE7))
+	binary.LittleEndian.PutUint32(b[123:127], uint32(h.CenterLatE7))
+	return b, nil
+}
+
+// UnmarshalBinary decodes a header from its 127 byte representation
+func (h *Header) UnmarshalBinary(b []byte) error {
+	if len(b) < HeaderLength {
+		return ErrInvalidHeader
+	}
+	if string(b[0:7]) != magic {
+		return ErrInvalidMagic
+	}
+	if b[7] != Version {
+		return ErrUnsupportedVersion(b[7])
+	}
+	h.RootOffset = binary.LittleEndian.Uint64(b[8:16])
+	h.RootLength = binary.LittleEndian.Uint64(b[16:24])
+	h.MetadataOffset = binary.LittleEndian.Uint64(b[24:32])
+	h.MetadataLength = binary.LittleEndian.Uint64(b[32:40])
+	h.LeafDirsOffset = binary.LittleEndian.Uint64(b[40:48])
+	h.LeafDirsLength = binary.LittleEndian.Uint64(b[48:56])
+	h.TileDataOffset = binary.LittleEndian.Uint64(b[56:64])
+	h.TileDataLength = binary.LittleEndian.Uint64(b[64:72])
+	h.AddressedTilesCount = binary.LittleEndian.Uint64(b[72:80])
+	h.TileEntriesCount = binary.LittleEndian.Uint64(b[80:88])
+	h.TileContentsCount = binary.LittleEndian.Uint64(b[88:96])
+	h.Clustered = b[96] == 1
+	h.InternalCompression = Compression(b[97])
+	h.TileCompression = Compression(b[98])
+	h.TileType = TileType(b[99])
+	h.MinZoom = b[100]
+	h.MaxZoom = b[101]
+	h.MinLonE7 = int32(binary.LittleEndian.Uint32(b[102:106]))
+	h.MinLatE7 = int32(binary.LittleEndian.Uint32(b[106:110]))
+	h.MaxLonE7 = int32(binary.LittleEndian.Uint32(b[110:114]))
+	h.MaxLatE7 = int32(binary.LittleEndian.Uint32(b[114:118]))
+	h.CenterZoom = b[118]
+	h.CenterLonE7 = int32(binary.LittleEndian.Uint32(b[119:123]))
+	h.CenterLatE7 = int32(binary.LittleEndian.Uint32(b[123:127]))
+	return nil
+}
+
+// zoomOffset returns the first tile id of the zoom level z
+func zoomOffset(z uint) uint64 {
+	return ((uint64(1) << (2 * z)) - 1) / 3
+}
+
+// rotate is the quadrant rotation step of the hilbert curve
+func rotate(n uint64, x, y *uint64, rx, ry uint64) {
+	if ry == 0 {
+		if rx == 1 {
+			*x = n - 1 - *x
+			*y = n - 1 - *y
+		}
+		*x, *y = *y, *x
+	}
+}
+
+// TileID returns the tile id of a z/x/y tile. Tile ids order tiles by
+// zoom and then along a hilbert curve within the zoom.
+func TileID(z, x, y uint) uint64 {
+	var (
+		n      = uint64(1) << z
+		tx, ty = uint64(x), uint64(y)
+		d      uint64
+	)
+	for s := n / 2; s > 0; s /= 2 {
+		var rx, ry uint64
+		if tx&s > 0 {
+			rx = 1
+		}
+		if ty&s > 0 {
+			ry = 1
+		}
+		d += s * s * ((3 * rx) ^ ry)
+		rotate(n, &tx, &ty, rx, ry)
+	}
+	return zoomOffset(z) + d
+}
+
+// ZXY returns the z/x/y tile for the provided tile id
+func ZXY(id uint64) (z, x, y uint, err error) {
+	for z = 0; z <= maxZoom; z++ {
+		if id >= zoomOffset(z+1) {
+			continue
+		}
+		var (
+			t      = id - zoomOffset(z)
+			n      = uint64(1) << z
+			tx, ty uint64
+		)
+		for s := uint64(1); s < n; s *= 2 {
+			rx := 1 & (t / 2)
+			ry := 1 & (t ^ rx)
+			rotate(s, &tx, &ty, rx, ry)
+			tx += s * rx
+			ty += s * ry
+			t /= 4
+		}
+		return z, uint(tx), uint(ty), nil
+	}
+	return 0, 0, 0, ErrInvalidTileID
+}
+
+// Zoom returns the zoom level of the provided tile id
+func Zoom(id uint64) uint {
+	var z uint
+	for z = 0; z < maxZoom && id >= zoomOffset(z+1); z++ {
+	}
+	return z
+}
+
+// webMercatorGrid is used to compute the WGS84 extent of tiles
+var webMercatorGrid = slippy.NewGrid(4326, 0)
+
+// tileBounds returns the WGS84 extent of the tile
+func tileBounds(z, x, y uint) (*geom.Extent, error) {
+	return slippy.Extent(webMercatorGrid, slippy.Tile{Z: slippy.Zoom(z), X: x, Y: y})
+}
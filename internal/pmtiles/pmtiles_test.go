@@ -0,0 +1,239 @@
+package pmtiles_test
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola/internal/pmtiles"
+)
+
+func TestTileID(t *testing.T) {
+	type tcase struct {
+		z, x, y uint
+		id      uint64
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			id := pmtiles.TileID(tc.z, tc.x, tc.y)
+			if id != tc.id {
+				t.Errorf("id, expected %v got %v", tc.id, id)
+				return
+			}
+
+			z, x, y, err := pmtiles.ZXY(id)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if z != tc.z || x != tc.x || y != tc.y {
+				t.Errorf("zxy, expected %v/%v/%v got %v/%v/%v", tc.z, tc.x, tc.y, z, x, y)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"0/0/0":   {z: 0, x: 0, y: 0, id: 0},
+		"1/0/0":   {z: 1, x: 0, y: 0, id: 1},
+		"1/0/1":   {z: 1, x: 0, y: 1, id: 2},
+		"1/1/1":   {z: 1, x: 1, y: 1, id: 3},
+		"1/1/0":   {z: 1, x: 1, y: 0, id: 4},
+		"2/0/0":   {z: 2, x: 0, y: 0, id: 5},
+		"3/0/0":   {z: 3, x: 0, y: 0, id: 21},
+		"3/7/0":   {z: 3, x: 7, y: 0, id: 84},
+		"20/0/0":  {z: 20, x: 0, y: 0, id: 366503875925},
+		"12/3423": {z: 12, x: 3423, y: 1763, id: 19078479},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestZXYRoundTrip(t *testing.T) {
+	for z := uint(0); z <= 5; z++ {
+		n := uint(1) << z
+		for x := uint(0); x < n; x++ {
+			for y := uint(0); y < n; y++ {
+				gz, gx, gy, err := pmtiles.ZXY(pmtiles.TileID(z, x, y))
+				if err != nil {
+					t.Fatalf("%v/%v/%v: unexpected error: %v", z, x, y, err)
+				}
+				if gz != z || gx != x || gy != y {
+					t.Fatalf("expected %v/%v/%v got %v/%v/%v", z, x, y, gz, gx, gy)
+				}
+			}
+		}
+	}
+}
+
+func TestHeaderRoundTrip(t *testing.T) {
+	h := pmtiles.Header{
+		RootOffset:          127,
+		RootLength:          300,
+		MetadataOffset:      427,
+		MetadataLength:      20,
+		TileDataOffset:      447,
+		TileDataLength:      1000,
+		AddressedTilesCount: 10,
+		TileEntriesCount:    8,
+		TileContentsCount:   7,
+		Clustered:           true,
+		InternalCompression: pmtiles.CompressionGzip,
+		TileCompression:     pmtiles.CompressionGzip,
+		TileType:            pmtiles.TileTypeMVT,
+		MinZoom:             1,
+		MaxZoom:             14,
+	}
+	h.SetBounds(geom.NewExtent([2]float64{-122.5, 37.7}, [2]float64{-122.3, 37.8}))
+	h.SetCenter([3]float64{-122.4, 37.75, 10})
+
+	b, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(b) != pmtiles.HeaderLength {
+		t.Fatalf("length, expected %v got %v", pmtiles.HeaderLength, len(b))
+	}
+
+	var got pmtiles.Header
+	if err = got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(h, got) {
+		t.Errorf("expected %+v got %+v", h, got)
+	}
+
+	b[0] = 'X'
+	if err = got.UnmarshalBinary(b); err != pmtiles.ErrInvalidMagic {
+		t.Errorf("error, expected %v got %v", pmtiles.ErrInvalidMagic, err)
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	type tcase struct {
+		// tiles are added for all tiles from zoom 0 up to and including maxZoom
+		maxZoom uint
+		// if set, all tiles share the same data
+		same bool
+	}
+
+	tileData := func(tc tcase, z, x, y uint) []byte {
+		if tc.same {
+			return []byte("ocean")
+		}
+		return []byte(fmt.Sprintf("%v/%v/%v", z, x, y))
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			dir := t.TempDir()
+			w, err := pmtiles.NewWriter(dir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer w.Close()
+
+			w.Metadata["name"] = "test"
+
+			var count uint64
+			for z := uint(0); z <= tc.maxZoom; z++ {
+				n := uint(1) << z
+				for x := uint(0); x < n; x++ {
+					for y := uint(0); y < n; y++ {
+						if err = w.Add(pmtiles.TileID(z, x, y), tileData(tc, z, x, y)); err != nil {
+							t.Fatalf("unexpected error: %v", err)
+						}
+						count++
+					}
+				}
+			}
+
+			path := filepath.Join(dir, "test.pmtiles")
+			if err = w.WriteFile(path); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			r, err := pmtiles.Open(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer r.Close()
+
+			h := r.Header()
+			if h.AddressedTilesCount != count {
+				t.Errorf("addressed tiles, expected %v got %v", count, h.AddressedTilesCount)
+			}
+			if h.MaxZoom != uint8(tc.maxZoom) {
+				t.Errorf("max zoom, expected %v got %v", tc.maxZoom, h.MaxZoom)
+			}
+			if tc.same && h.TileContentsCount != 1 {
+				t.Errorf("tile contents, expected 1 got %v", h.TileContentsCount)
+			}
+
+			md, err := r.Metadata()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if md["name"] != "test" {
+				t.Errorf("metadata name, expected test got %v", md["name"])
+			}
+
+			for z := uint(0); z <= tc.maxZoom; z++ {
+				n := uint(1) << z
+				for x := uint(0); x < n; x++ {
+					for y := uint(0); y < n; y++ {
+						data, ok, err := r.Tile(z, x, y)
+						if err != nil {
+							t.Fatalf("unexpected error: %v", err)
+						}
+						if !ok {
+							t.Fatalf("%v/%v/%v: expected tile to exist", z, x, y)
+						}
+						if expected := tileData(tc, z, x, y); !bytes.Equal(data, expected) {
+							t.Fatalf("%v/%v/%v: expected %s got %s", z, x, y, expected, data)
+						}
+					}
+				}
+			}
+
+			if _, ok, err := r.Tile(tc.maxZoom+1, 0, 0); err != nil || ok {
+				t.Errorf("tile outside the archive, expected not found got ok %v err %v", ok, err)
+			}
+
+			var entries uint64
+			err = r.Entries(func(e pmtiles.Entry) error {
+				entries += uint64(e.RunLength)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if entries != count {
+				t.Errorf("entries, expected %v tiles got %v", count, entries)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"small": {
+			maxZoom: 3,
+		},
+		"run length": {
+			maxZoom: 4,
+			same:    true,
+		},
+		"leaf directories": {
+			// 87381 tiles will not fit in the root directory
+			maxZoom: 8,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
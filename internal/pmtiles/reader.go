@@ -0,0 +1,213 @@
+package pmtiles
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// maxLeafCacheSize is the number of leaf directories a Reader keeps in memory
+const maxLeafCacheSize = 64
+
+// maxDirDepth is the deepest directory nesting a Reader will follow.
+// The spec allows for a root directory and leaf directories which can
+// point to other leaf directories; anything deeper is considered corrupt.
+const maxDirDepth = 4
+
+// Reader provides random access to the tiles of an archive.
+// A Reader is safe for concurrent use.
+type Reader struct {
+	r      io.ReaderAt
+	closer io.Closer
+
+	header Header
+	root   []Entry
+
+	leafLock sync.Mutex
+	leaves   map[uint64][]Entry
+}
+
+// Open opens the archive at the provided path
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+
+	return r, nil
+}
+
+// NewReader reads the header and root directory of the archive provided by r
+func NewReader(r io.ReaderAt) (*Reader, error) {
+	buf := make([]byte, HeaderLength)
+	if _, err := r.ReadAt(buf, 0); err != nil {
+		return nil, fmt.Errorf("pmtiles: reading header: %w", err)
+	}
+
+	reader := Reader{
+		r:      r,
+		leaves: make(map[uint64][]Entry),
+	}
+	if err := reader.header.UnmarshalBinary(buf); err != nil {
+		return nil, err
+	}
+
+	root, err := reader.readDirectory(reader.header.RootOffset, reader.header.RootLength)
+	if err != nil {
+		return nil, err
+	}
+	reader.root = root
+
+	return &reader, nil
+}
+
+// Header returns the header of the archive
+func (r *Reader) Header() Header { return r.header }
+
+// Metadata returns the decoded JSON metadata of the archive
+func (r *Reader) Metadata() (map[string]interface{}, error) {
+	md := make(map[string]interface{})
+	if r.header.MetadataLength == 0 {
+		return md, nil
+	}
+
+	buf := make([]byte, r.header.MetadataLength)
+	if _, err := r.r.ReadAt(buf, int64(r.header.MetadataOffset)); err != nil {
+		return nil, fmt.Errorf("pmtiles: reading metadata: %w", err)
+	}
+
+	raw, err := decompress(buf, r.header.InternalCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(raw, &md); err != nil {
+		return nil, fmt.Errorf("pmtiles: decoding metadata: %w", err)
+	}
+
+	return md, nil
+}
+
+// Tile returns the (possibly compressed) data of the z/x/y tile. If the
+// archive does not contain the tile, ok will be false.
+func (r *Reader) Tile(z, x, y uint) (data []byte, ok bool, err error) {
+	return r.TileByID(TileID(z, x, y))
+}
+
+// TileByID returns the (possibly compressed) data of the tile with the
+// provided tile id. If the archive does not contain the tile, ok will be false.
+func (r *Reader) TileByID(id uint64) (data []byte, ok bool, err error) {
+	entries := r.root
+	for depth := 0; depth < maxDirDepth; depth++ {
+		entry, found := findEntry(entries, id)
+		if !found {
+			return nil, false, nil
+		}
+
+		if !entry.IsLeaf() {
+			data, err = r.ReadEntry(entry)
+			if err != nil {
+				return nil, false, err
+			}
+			return data, true, nil
+		}
+
+		if entries, err = r.leaf(entry); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return nil, false, fmt.Errorf("pmtiles: directory depth exceeds %v looking up tile id (%v)", maxDirDepth, id)
+}
+
+// ReadEntry returns the tile data an entry points to
+func (r *Reader) ReadEntry(entry Entry) ([]byte, error) {
+	buf := make([]byte, entry.Length)
+	if _, err := r.r.ReadAt(buf, int64(r.header.TileDataOffset+entry.Offset)); err != nil {
+		return nil, fmt.Errorf("pmtiles: reading tile data: %w", err)
+	}
+	return buf, nil
+}
+
+// Entries calls fn for every tile entry of the archive in tile id order.
+// Leaf directories are resolved, so fn is never called with a leaf entry.
+// If fn returns an error, the iteration stops and the error is returned.
+func (r *Reader) Entries(fn func(Entry) error) error {
+	return r.walk(r.root, 0, fn)
+}
+
+func (r *Reader) walk(entries []Entry, depth int, fn func(Entry) error) error {
+	if depth >= maxDirDepth {
+		return fmt.Errorf("pmtiles: directory depth exceeds %v", maxDirDepth)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsLeaf() {
+			if err := fn(entry); err != nil {
+				return err
+			}
+			continue
+		}
+
+		leaf, err := r.readDirectory(r.header.LeafDirsOffset+entry.Offset, uint64(entry.Length))
+		if err != nil {
+			return err
+		}
+		if err = r.walk(leaf, depth+1, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close closes the underlying file if the Reader was created with Open
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// leaf returns the leaf directory an entry points to, making use of the leaf cache
+func (r *Reader) leaf(entry Entry) ([]Entry, error) {
+	r.leafLock.Lock()
+	entries, ok := r.leaves[entry.Offset]
+	r.leafLock.Unlock()
+	if ok {
+		return entries, nil
+	}
+
+	entries, err := r.readDirectory(r.header.LeafDirsOffset+entry.Offset, uint64(entry.Length))
+	if err != nil {
+		return nil, err
+	}
+
+	r.leafLock.Lock()
+	// the cache is small and leaf lookups are cheap, so rather than tracking
+	// usage we start over once the cache is full.
+	if len(r.leaves) >= maxLeafCacheSize {
+		r.leaves = make(map[uint64][]Entry)
+	}
+	r.leaves[entry.Offset] = entries
+	r.leafLock.Unlock()
+
+	return entries, nil
+}
+
+func (r *Reader) readDirectory(offset, length uint64) ([]Entry, error) {
+	buf := make([]byte, length)
+	if _, err := r.r.ReadAt(buf, int64(offset)); err != nil {
+		return nil, fmt.Errorf("pmtiles: reading directory: %w", err)
+	}
+	return deserializeEntries(buf, r.header.InternalCompression)
+}
@@ -0,0 +1,403 @@
+package pmtiles
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+)
+
+// leafSize is the initial number of entries per leaf directory when the
+// entries do not fit in the root directory
+const leafSize = 4096
+
+type dataRef struct {
+	offset uint64
+	length uint32
+}
+
+// Writer collects tiles and writes them out as an archive. Tiles are staged
+// in a temporary file until WriteFile is called, so tiles can be added in any
+// order and by multiple go routines. Adding a tile id that was already added
+// replaces the previous tile. Tiles with identical contents are only stored once.
+type Writer struct {
+	// TileType is the format of the tiles. Defaults to TileTypeMVT
+	TileType TileType
+	// TileCompression is the compression of the tile data. Defaults to CompressionGzip
+	// as that's the format tegola encodes tiles in.
+	TileCompression Compression
+	// Metadata is encoded as JSON into the metadata section of the archive
+	Metadata map[string]interface{}
+	// Bounds of the archive in WGS84. If nil, the bounds of the added tiles are used.
+	Bounds *geom.Extent
+	// Center of the archive as lon, lat, zoom. If nil, the center of the bounds
+	// at the min zoom is used.
+	Center *[3]float64
+
+	lock    sync.RWMutex
+	tmp     *os.File
+	size    uint64
+	entries map[uint64]dataRef
+	hashes  map[[sha256.Size]byte]dataRef
+}
+
+// NewWriter returns a Writer that stages tile data in a temporary file
+// created in dir. If dir is empty, the default temp directory is used.
+func NewWriter(dir string) (*Writer, error) {
+	tmp, err := os.CreateTemp(dir, "tegola-pmtiles-*")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{
+		TileType:        TileTypeMVT,
+		TileCompression: CompressionGzip,
+		Metadata:        map[string]interface{}{},
+		tmp:             tmp,
+		entries:         make(map[uint64]dataRef),
+		hashes:          make(map[[sha256.Size]byte]dataRef),
+	}, nil
+}
+
+// Add stages the tile data for the tile id
+func (w *Writer) Add(id uint64, data []byte) error {
+	hash := sha256.Sum256(data)
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.tmp == nil {
+		return ErrWriterFinished
+	}
+
+	if ref, ok := w.hashes[hash]; ok {
+		w.entries[id] = ref
+		return nil
+	}
+
+	if _, err := w.tmp.Write(data); err != nil {
+		return err
+	}
+
+	ref := dataRef{
+		offset: w.size,
+		length: uint32(len(data)),
+	}
+	w.size += uint64(len(data))
+	w.hashes[hash] = ref
+	w.entries[id] = ref
+
+	return nil
+}
+
+// Get returns the staged tile data for the tile id
+func (w *Writer) Get(id uint64) (data []byte, ok bool, err error) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.tmp == nil {
+		return nil, false, ErrWriterFinished
+	}
+
+	ref, ok := w.entries[id]
+	if !ok {
+		return nil, false, nil
+	}
+
+	data = make([]byte, ref.length)
+	if _, err = w.tmp.ReadAt(data, int64(ref.offset)); err != nil {
+		return nil, false, err
+	}
+
+	return data, true, nil
+}
+
+// Has reports if a tile has been staged for the tile id
+func (w *Writer) Has(id uint64) bool {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	_, ok := w.entries[id]
+	return ok
+}
+
+// Remove removes the staged tile for the tile id
+func (w *Writer) Remove(id uint64) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	delete(w.entries, id)
+}
+
+// Len returns the number of staged tiles
+func (w *Writer) Len() int {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	return len(w.entries)
+}
+
+// WriteFile writes the staged tiles as an archive to path. The archive is first
+// written to a temporary file next to path and then moved into place, so readers
+// of path never observe a partially written archive. The Writer can continue to
+// be used after WriteFile.
+func (w *Writer) WriteFile(path string) error {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.tmp == nil {
+		return ErrWriterFinished
+	}
+
+	ids := make([]uint64, 0, len(w.entries))
+	for id := range w.entries {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	// lay out the tile data in tile id order. tiles sharing data with a
+	// previous tile point to the data that was already laid out.
+	var (
+		entries  []Entry
+		order    []dataRef
+		newRefs  = make(map[uint64]uint64)
+		dataSize uint64
+	)
+	for _, id := range ids {
+		ref := w.entries[id]
+
+		offset, ok := newRefs[ref.offset]
+		if !ok {
+			offset = dataSize
+			newRefs[ref.offset] = offset
+			order = append(order, ref)
+			dataSize += uint64(ref.length)
+		}
+
+		// extend the previous entry's run if this tile has the same contents
+		// and directly follows it
+		if n := len(entries); n > 0 {
+			last := &entries[n-1]
+			if last.TileID+uint64(last.RunLength) == id && last.Offset == offset {
+				last.RunLength++
+				continue
+			}
+		}
+
+		entries = append(entries, Entry{
+			TileID:    id,
+			Offset:    offset,
+			Length:    ref.length,
+			RunLength: 1,
+		})
+	}
+
+	root, leaves, err := buildDirectories(entries)
+	if err != nil {
+		return err
+	}
+
+	md, err := json.Marshal(w.Metadata)
+	if err != nil {
+		return fmt.Errorf("pmtiles: encoding metadata: %w", err)
+	}
+	if md, err = compress(md, CompressionGzip); err != nil {
+		return err
+	}
+
+	header := Header{
+		RootOffset:          HeaderLength,
+		RootLength:          uint64(len(root)),
+		MetadataOffset:      HeaderLength + uint64(len(root)),
+		MetadataLength:      uint64(len(md)),
+		LeafDirsOffset:      HeaderLength + uint64(len(root)) + uint64(len(md)),
+		LeafDirsLength:      uint64(len(leaves)),
+		TileDataOffset:      HeaderLength + uint64(len(root)) + uint64(len(md)) + uint64(len(leaves)),
+		TileDataLength:      dataSize,
+		AddressedTilesCount: uint64(len(ids)),
+		TileEntriesCount:    uint64(len(entries)),
+		TileContentsCount:   uint64(len(order)),
+		Clustered:           true,
+		InternalCompression: CompressionGzip,
+		TileCompression:     w.TileCompression,
+		TileType:            w.TileType,
+	}
+
+	if len(ids) > 0 {
+		header.MinZoom = uint8(Zoom(ids[0]))
+		header.MaxZoom = uint8(Zoom(ids[len(ids)-1]))
+	}
+
+	bounds := w.Bounds
+	if bounds == nil {
+		if bounds, err = idsBounds(ids); err != nil {
+			return err
+		}
+	}
+	header.SetBounds(bounds)
+
+	if w.Center != nil {
+		header.SetCenter(*w.Center)
+	} else {
+		header.SetCenter([3]float64{
+			bounds.MinX() + bounds.XSpan()/2,
+			bounds.MinY() + bounds.YSpan()/2,
+			float64(header.MinZoom),
+		})
+	}
+
+	hb, err := header.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + "-tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	if err = w.writeArchive(f, [][]byte{hb, root, md, leaves}, order); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// writeArchive writes the sections followed by the tile data for refs
+func (w *Writer) writeArchive(out io.Writer, sections [][]byte, refs []dataRef) error {
+	bw := bufio.NewWriter(out)
+
+	for _, section := range sections {
+		if _, err := bw.Write(section); err != nil {
+			return err
+		}
+	}
+
+	var buf []byte
+	for _, ref := range refs {
+		if cap(buf) < int(ref.length) {
+			buf = make([]byte, ref.length)
+		}
+		buf = buf[:ref.length]
+
+		if _, err := w.tmp.ReadAt(buf, int64(ref.offset)); err != nil {
+			return fmt.Errorf("pmtiles: reading staged tile data: %w", err)
+		}
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Close removes the staged tile data. The Writer can not be used after Close.
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.tmp == nil {
+		return nil
+	}
+
+	name := w.tmp.Name()
+	err := w.tmp.Close()
+	w.tmp = nil
+	w.entries = nil
+	w.hashes = nil
+
+	if rmErr := os.Remove(name); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// buildDirectories serializes the entries into a root directory, moving entries
+// into leaf directories if they don't fit in the root.
+func buildDirectories(entries []Entry) (root, leaves []byte, err error) {
+	root, err = serializeEntries(entries, CompressionGzip)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(root) <= MaxRootLength-HeaderLength {
+		return root, nil, nil
+	}
+
+	for size := leafSize; ; size *= 2 {
+		var (
+			rootEntries []Entry
+			leafBuf     bytes.Buffer
+		)
+
+		for i := 0; i < len(entries); i += size {
+			end := i + size
+			if end > len(entries) {
+				end = len(entries)
+			}
+
+			leaf, err := serializeEntries(entries[i:end], CompressionGzip)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			rootEntries = append(rootEntries, Entry{
+				TileID: entries[i].TileID,
+				Offset: uint64(leafBuf.Len()),
+				Length: uint32(len(leaf)),
+			})
+			leafBuf.Write(leaf)
+		}
+
+		root, err = serializeEntries(rootEntries, CompressionGzip)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(root) <= MaxRootLength-HeaderLength {
+			return root, leafBuf.Bytes(), nil
+		}
+	}
+}
+
+// idsBounds returns the WGS84 extent covering all the tile ids
+func idsBounds(ids []uint64) (*geom.Extent, error) {
+	if len(ids) == 0 {
+		return tegola.WGS84Bounds.Clone(), nil
+	}
+
+	var bounds *geom.Extent
+	for _, id := range ids {
+		z, x, y, err := ZXY(id)
+		if err != nil {
+			return nil, err
+		}
+
+		ext, err := tileBounds(z, x, y)
+		if err != nil {
+			return nil, err
+		}
+
+		if bounds == nil {
+			bounds = ext
+			continue
+		}
+		bounds.Add(ext)
+	}
+
+	return bounds, nil
+}
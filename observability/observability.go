@@ -65,6 +65,7 @@ type Interface interface {
 	APIObserver
 	ViewerObserver
 	CacheObserver
+	TileObserver
 }
 
 type APIObserver interface {
@@ -83,6 +84,12 @@ type CacheObserver interface {
 	InstrumentedCache(cacheObject tegolaCache.Interface) tegolaCache.Interface
 }
 
+type TileObserver interface {
+	// ObserveCoalescedTile records a request for the tile of the key which was
+	// served by the encoding of the tile for a concurrent request
+	ObserveCoalescedTile(key *tegolaCache.Key)
+}
+
 type Cache interface {
 	tegolaCache.Interface
 	tegolaCache.Wrapped
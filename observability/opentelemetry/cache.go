@@ -0,0 +1,113 @@
+package opentelemetry
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	tegolaCache "github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/observability"
+)
+
+// cache traces the requests to the wrapped cache and counts them by result
+type cache struct {
+	cache    tegolaCache.Interface
+	requests *prometheus.CounterVec
+}
+
+// keyAttributes returns the attributes of the span of a request for the key
+func keyAttributes(key *tegolaCache.Key) []observability.Attribute {
+	attrs := []observability.Attribute{
+		observability.Attr(observability.AttrMapName, key.MapName),
+		observability.Attr(observability.AttrTileZ, key.Z),
+		observability.Attr(observability.AttrTileX, key.X),
+		observability.Attr(observability.AttrTileY, key.Y),
+	}
+	if key.LayerName != "" {
+		attrs = append(attrs, observability.Attr(observability.AttrLayerName, key.LayerName))
+	}
+	return attrs
+}
+
+// observe ends the span of a request to the cache and counts it. Gets are
+// counted as hits or misses, the other commands as ok.
+func (c *cache) observe(span observability.Span, cmd, mapName string, hit bool, err error) {
+	result := "ok"
+	if cmd == "get" {
+		span.SetAttributes(observability.Attr("tegola.cache.hit", hit))
+		result = "miss"
+		if hit {
+			result = "hit"
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		result = "error"
+	}
+	span.End()
+
+	c.requests.WithLabelValues(cmd, result, mapName).Inc()
+}
+
+func (c *cache) Get(ctx context.Context, key *tegolaCache.Key) ([]byte, bool, error) {
+	ctx, span := observability.StartSpan(ctx, "tegola.cache.get", keyAttributes(key)...)
+	body, hit, err := c.cache.Get(ctx, key)
+	c.observe(span, "get", key.MapName, hit, err)
+	return body, hit, err
+}
+
+// GetInfo traces getting the tile and its info from the wrapped cache.
+// Stale tiles are counted as hits.
+func (c *cache) GetInfo(ctx context.Context, key *tegolaCache.Key) ([]byte, tegolaCache.TileInfo, bool, error) {
+	ctx, span := observability.StartSpan(ctx, "tegola.cache.get", keyAttributes(key)...)
+	body, info, hit, err := tegolaCache.GetInfo(ctx, c.cache, key)
+	if hit {
+		span.SetAttributes(observability.Attr("tegola.cache.stale", info.Stale))
+	}
+	c.observe(span, "get", key.MapName, hit, err)
+	return body, info, hit, err
+}
+
+func (c *cache) Set(ctx context.Context, key *tegolaCache.Key, body []byte) error {
+	ctx, span := observability.StartSpan(ctx, "tegola.cache.set", keyAttributes(key)...)
+	span.SetAttributes(observability.Attr("tegola.cache.size", len(body)))
+	err := c.cache.Set(ctx, key, body)
+	c.observe(span, "set", key.MapName, false, err)
+	return err
+}
+
+func (c *cache) Purge(ctx context.Context, key *tegolaCache.Key) error {
+	ctx, span := observability.StartSpan(ctx, "tegola.cache.purge", keyAttributes(key)...)
+	err := c.cache.Purge(ctx, key)
+	c.observe(span, "purge", key.MapName, false, err)
+	return err
+}
+
+func (c *cache) PurgePrefix(ctx context.Context, mapName, layerName string, minZoom, maxZoom uint) error {
+	ctx, span := observability.StartSpan(ctx, "tegola.cache.purge_prefix",
+		observability.Attr(observability.AttrMapName, mapName),
+		observability.Attr(observability.AttrLayerName, layerName),
+	)
+	err := tegolaCache.PurgePrefix(ctx, c.cache, mapName, layerName, minZoom, maxZoom)
+	c.observe(span, "purge_prefix", mapName, false, err)
+	return err
+}
+
+func (c *cache) PurgeVersions(ctx context.Context, mapName, keepVersion string) error {
+	ctx, span := observability.StartSpan(ctx, "tegola.cache.purge_versions",
+		observability.Attr(observability.AttrMapName, mapName),
+	)
+	err := tegolaCache.PurgeVersions(ctx, c.cache, mapName, keepVersion)
+	c.observe(span, "purge_versions", mapName, false, err)
+	return err
+}
+
+// Original returns the first cache backend to be wrapped
+func (c *cache) Original() tegolaCache.Interface {
+	if w, ok := c.cache.(tegolaCache.Wrapped); ok {
+		return w.Original()
+	}
+	return c.cache
+}
+
+func (c *cache) IsObserver() bool { return true }
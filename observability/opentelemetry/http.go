@@ -0,0 +1,96 @@
+package opentelemetry
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dimfeld/httptreemux"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/go-spatial/tegola/observability"
+)
+
+// routeParamAttributes maps the route params to the attributes of the spans
+var routeParamAttributes = map[string]string{
+	"map_name":   observability.AttrMapName,
+	"layer_name": observability.AttrLayerName,
+	"z":          observability.AttrTileZ,
+	"x":          observability.AttrTileX,
+	"y":          observability.AttrTileY,
+}
+
+// instrumentedHttpHandler starts a server span for each request of the route,
+// which is the parent of the spans started while handling the request. The
+// span joins the trace of the caller when the request has a traceparent
+// header. The tracer is put in the context of the request for the code
+// handling it to start spans.
+func (obs *observer) instrumentedHttpHandler(handler, method, route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		ctx := observability.ContextWithTracer(r.Context(), obs.tracer)
+		ctx = propagation.TraceContext{}.Extract(ctx, propagation.HeaderCarrier(r.Header))
+
+		attrs := []observability.Attribute{
+			observability.Attr("http.request.method", method),
+			observability.Attr("http.route", route),
+			observability.Attr("url.path", r.URL.Path),
+		}
+		for param, value := range httptreemux.ContextParams(r.Context()) {
+			attr, ok := routeParamAttributes[param]
+			if !ok {
+				continue
+			}
+			if n, err := strconv.ParseUint(value, 10, 64); err == nil {
+				attrs = append(attrs, observability.Attr(attr, n))
+			} else {
+				attrs = append(attrs, observability.Attr(attr, value))
+			}
+		}
+
+		ctx, span := obs.tracer.Start(ctx, observability.SpanKindServer, method+" "+route, attrs...)
+
+		sw := statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(&sw, r.WithContext(ctx))
+
+		span.SetAttributes(observability.Attr("http.response.status_code", sw.status))
+		if sw.status >= http.StatusInternalServerError {
+			trace.SpanFromContext(ctx).SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+		span.End()
+
+		obs.httpRequests.WithLabelValues(handler, method, route, strconv.Itoa(sw.status)).Inc()
+		obs.httpDuration.WithLabelValues(handler, method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusWriter records the status code of the response
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if !sw.wroteHeader {
+		sw.status, sw.wroteHeader = status, true
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	sw.wroteHeader = true
+	return sw.ResponseWriter.Write(b)
+}
+
+func (sw *statusWriter) Flush() {
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController
+func (sw *statusWriter) Unwrap() http.ResponseWriter { return sw.ResponseWriter }
@@ -0,0 +1,41 @@
+package opentelemetry
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var httpDurationBuckets = []float64{.05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// registerMetrics registers the metrics of the observer. They are collected
+// like the metrics of the providers, with the registry of the observer.
+func (obs *observer) registerMetrics() {
+	obs.httpRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tegola_http_requests_total",
+			Help: "A counter of the requests to the server.",
+		},
+		[]string{"handler", "method", "route", "code"},
+	)
+	obs.httpDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "tegola_http_duration_seconds",
+			Help:    "A histogram of the latencies of the requests to the server.",
+			Buckets: httpDurationBuckets,
+		},
+		[]string{"handler", "method", "route"},
+	)
+	obs.cacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tegola_cache_requests_total",
+			Help: "A counter of the requests to the cache by result (hit, miss, ok or error).",
+		},
+		[]string{"sub_command", "result", "map_name"},
+	)
+	obs.coalescedTiles = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tegola_tile_coalesced_total",
+			Help: "A counter of the tile requests served by the encoding of the tile for a concurrent request.",
+		},
+		[]string{"map_name"},
+	)
+
+	obs.registry.MustRegister(obs.httpRequests, obs.httpDuration, obs.cacheRequests, obs.coalescedTiles)
+}
@@ -0,0 +1,296 @@
+// Package opentelemetry is an observer exporting traces and metrics to an
+// OpenTelemetry collector with the OTLP/HTTP protocol.
+package opentelemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	otelprom "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	tegolaCache "github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/build"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/observability"
+)
+
+const Name = "opentelemetry"
+
+// instrumentationScope is the name of the tracer of the spans
+const instrumentationScope = "github.com/go-spatial/tegola"
+
+const (
+	ConfigKeyEndpoint             = "endpoint"
+	ConfigKeyHeaders              = "headers"
+	ConfigKeyServiceName          = "service_name"
+	ConfigKeySampleRatio          = "sample_ratio"
+	ConfigKeyTraceExportInterval  = "trace_export_interval"
+	ConfigKeyMetricExportInterval = "metric_export_interval"
+	ConfigKeyExportTimeout        = "export_timeout"
+)
+
+var (
+	defaultEndpoint             = "http://localhost:4318"
+	defaultServiceName          = "tegola"
+	defaultSampleRatio          = 1.0
+	defaultTraceExportInterval  = 5
+	defaultMetricExportInterval = 60
+	defaultExportTimeout        = 10
+)
+
+var ErrInvalidSampleRatio = errors.New("opentelemetry: sample_ratio must be between 0 and 1")
+
+// ErrInvalidParam is returned when a param of the config is invalid
+type ErrInvalidParam struct {
+	Param string
+	Err   error
+}
+
+func (e ErrInvalidParam) Error() string {
+	return fmt.Sprintf("opentelemetry: invalid param (%v): %v", e.Param, e.Err)
+}
+
+func (e ErrInvalidParam) Unwrap() error { return e.Err }
+
+func init() {
+	err := observability.Register(Name, New, cleanUp)
+	if err != nil {
+		panic(err)
+	}
+}
+
+type observer struct {
+	tracer         *tracer
+	tracerProvider *sdktrace.TracerProvider
+	meterProvider  *sdkmetric.MeterProvider
+
+	// registry holds the metrics of the observer and the collectors
+	// registered by the providers. It's exported on every metric export
+	// interval.
+	registry      *prometheus.Registry
+	exportTimeout time.Duration
+
+	httpRequests   *prometheus.CounterVec
+	httpDuration   *prometheus.HistogramVec
+	cacheRequests  *prometheus.CounterVec
+	coalescedTiles *prometheus.CounterVec
+
+	shutdown sync.Once
+}
+
+// New instantiates an OpenTelemetry observer. The config expects the following params:
+//
+//	endpoint (string): the base URL of the OTLP/HTTP receiver, defaults to http://localhost:4318
+//	headers ([]string): headers added to the exports, as "Name=value"
+//	service_name (string): the service.name of the resource, defaults to tegola
+//	sample_ratio (float): the ratio of the traces sampled when the request is not part of a trace, defaults to 1
+//	trace_export_interval (int): the seconds between exports of the spans, defaults to 5
+//	metric_export_interval (int): the seconds between exports of the metrics, defaults to 60
+//	export_timeout (int): the seconds an export can take, defaults to 10
+func New(config dict.Dicter) (observability.Interface, error) {
+	endpoint, err := config.String(ConfigKeyEndpoint, &defaultEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u, err := url.Parse(endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, ErrInvalidParam{Param: ConfigKeyEndpoint, Err: fmt.Errorf("expected an absolute URL, got (%v)", endpoint)}
+	}
+
+	headerPairs, err := config.StringSlice(ConfigKeyHeaders)
+	if err != nil {
+		return nil, err
+	}
+	headers := make(map[string]string, len(headerPairs))
+	for _, pair := range headerPairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, ErrInvalidParam{Param: ConfigKeyHeaders, Err: fmt.Errorf("expected Name=value, got (%v)", pair)}
+		}
+		headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	serviceName, err := config.String(ConfigKeyServiceName, &defaultServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	ratio, err := config.Float(ConfigKeySampleRatio, &defaultSampleRatio)
+	if err != nil {
+		return nil, err
+	}
+	if ratio < 0 || ratio > 1 {
+		return nil, ErrInvalidSampleRatio
+	}
+
+	var intervals [3]time.Duration
+	for i, param := range []struct {
+		key string
+		def *int
+	}{
+		{ConfigKeyTraceExportInterval, &defaultTraceExportInterval},
+		{ConfigKeyMetricExportInterval, &defaultMetricExportInterval},
+		{ConfigKeyExportTimeout, &defaultExportTimeout},
+	} {
+		secs, err := config.Int(param.key, param.def)
+		if err != nil {
+			return nil, err
+		}
+		if secs <= 0 {
+			return nil, ErrInvalidParam{Param: param.key, Err: fmt.Errorf("expected a positive number of seconds, got (%v)", secs)}
+		}
+		intervals[i] = time.Duration(secs) * time.Second
+	}
+
+	traceInterval, metricInterval, exportTimeout := intervals[0], intervals[1], intervals[2]
+	endpoint = strings.TrimSuffix(endpoint, "/")
+
+	res := resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+		attribute.String("service.version", build.Version),
+	)
+
+	// the exporters don't connect to the receiver until the first export
+	ctx := context.Background()
+	traceExporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(endpoint+"/v1/traces"),
+		otlptracehttp.WithHeaders(headers),
+		otlptracehttp.WithTimeout(exportTimeout),
+	)
+	if err != nil {
+		return nil, ErrInvalidParam{Param: ConfigKeyEndpoint, Err: err}
+	}
+	metricExporter, err := otlpmetrichttp.New(ctx,
+		otlpmetrichttp.WithEndpointURL(endpoint+"/v1/metrics"),
+		otlpmetrichttp.WithHeaders(headers),
+		otlpmetrichttp.WithTimeout(exportTimeout),
+	)
+	if err != nil {
+		return nil, ErrInvalidParam{Param: ConfigKeyEndpoint, Err: err}
+	}
+
+	obs := observer{
+		registry:      prometheus.NewRegistry(),
+		exportTimeout: exportTimeout,
+	}
+	obs.registerMetrics()
+
+	obs.tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithResource(res),
+		// requests which are part of a trace follow the sampling decision
+		// of the caller
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithBatcher(traceExporter,
+			sdktrace.WithBatchTimeout(traceInterval),
+			sdktrace.WithExportTimeout(exportTimeout),
+		),
+	)
+	obs.tracer = &tracer{tracer: obs.tracerProvider.Tracer(instrumentationScope)}
+
+	// the metrics are collected with the registry and converted by the
+	// prometheus bridge, as the providers register prometheus collectors
+	obs.meterProvider = sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter,
+			sdkmetric.WithInterval(metricInterval),
+			sdkmetric.WithTimeout(exportTimeout),
+			sdkmetric.WithProducer(otelprom.NewMetricProducer(otelprom.WithGatherer(obs.registry))),
+		)),
+	)
+
+	observersLck.Lock()
+	observers = append(observers, &obs)
+	observersLck.Unlock()
+
+	return &obs, nil
+}
+
+func (*observer) Name() string { return Name }
+
+// Handler returns nil, as the metrics are pushed to the collector instead
+// of being scraped
+func (*observer) Handler(string) http.Handler { return nil }
+
+func (obs *observer) MustRegister(collectors ...observability.Collector) {
+	obs.registry.MustRegister(collectors...)
+}
+
+func (*observer) CollectorConfig(string) map[string]interface{} {
+	return make(map[string]interface{})
+}
+
+// Init is a no-op, the exports start when the observer is instantiated
+func (*observer) Init() {}
+
+// Shutdown exports the spans and metrics which were not exported yet and
+// stops the exports. Spans ended afterwards are dropped.
+func (obs *observer) Shutdown() {
+	obs.shutdown.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), obs.exportTimeout)
+		defer cancel()
+
+		if err := obs.tracerProvider.Shutdown(ctx); err != nil {
+			log.Errorf("opentelemetry: exporting spans: %v", err)
+		}
+		if err := obs.meterProvider.Shutdown(ctx); err != nil {
+			log.Errorf("opentelemetry: exporting metrics: %v", err)
+		}
+	})
+}
+
+func (obs *observer) InstrumentedAPIHttpHandler(method, route string, next http.Handler) http.Handler {
+	if obs == nil {
+		return next
+	}
+	return obs.instrumentedHttpHandler("api", method, route, next)
+}
+
+func (obs *observer) InstrumentedViewerHttpHandler(method, route string, next http.Handler) http.Handler {
+	if obs == nil {
+		return next
+	}
+	return obs.instrumentedHttpHandler("viewer", method, route, next)
+}
+
+func (obs *observer) InstrumentedCache(cacheObject tegolaCache.Interface) tegolaCache.Interface {
+	if obs == nil {
+		// if we are nil assume no tracing is going to happen
+		return cacheObject
+	}
+	return &cache{cache: cacheObject, requests: obs.cacheRequests}
+}
+
+func (obs *observer) ObserveCoalescedTile(key *tegolaCache.Key) {
+	if obs == nil {
+		return
+	}
+	obs.coalescedTiles.WithLabelValues(key.MapName).Inc()
+}
+
+var (
+	observersLck sync.Mutex
+	observers    []*observer
+)
+
+// cleanUp exports what's left of the observers before the process ends
+func cleanUp() {
+	observersLck.Lock()
+	defer observersLck.Unlock()
+	for _, obs := range observers {
+		obs.Shutdown()
+	}
+	observers = observers[:0]
+}
@@ -0,0 +1,395 @@
+package opentelemetry_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/dimfeld/httptreemux"
+	"github.com/prometheus/client_golang/prometheus"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	tegolaCache "github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/observability"
+	"github.com/go-spatial/tegola/observability/opentelemetry"
+)
+
+// receiver is an OTLP/HTTP receiver recording the exports
+type receiver struct {
+	mu      sync.Mutex
+	spans   []*tracepb.Span
+	metrics []*metricpb.Metric
+	headers http.Header
+}
+
+func (rcv *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.headers = r.Header.Clone()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v1/traces":
+		var req coltracepb.ExportTraceServiceRequest
+		if err := proto.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, rs := range req.GetResourceSpans() {
+			for _, ss := range rs.GetScopeSpans() {
+				rcv.spans = append(rcv.spans, ss.GetSpans()...)
+			}
+		}
+		resp, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Write(resp)
+	case "/v1/metrics":
+		var req colmetricpb.ExportMetricsServiceRequest
+		if err := proto.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, rm := range req.GetResourceMetrics() {
+			for _, sm := range rm.GetScopeMetrics() {
+				rcv.metrics = append(rcv.metrics, sm.GetMetrics()...)
+			}
+		}
+		resp, _ := proto.Marshal(&colmetricpb.ExportMetricsServiceResponse{})
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Write(resp)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (rcv *receiver) span(name string) (*tracepb.Span, bool) {
+	for _, s := range rcv.spans {
+		if s.GetName() == name {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+func (rcv *receiver) metric(name string) (*metricpb.Metric, bool) {
+	for _, m := range rcv.metrics {
+		if m.GetName() == name {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// attr returns the value of the attribute of the span, nil if it has none
+func attr(s *tracepb.Span, key string) *commonpb.AnyValue {
+	for _, kv := range s.GetAttributes() {
+		if kv.GetKey() == key {
+			return kv.GetValue()
+		}
+	}
+	return nil
+}
+
+func intValue(v int64) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v}}
+}
+
+const tileRoute = "/maps/:map_name/:z/:x/:y"
+
+func TestInstrumentedAPIHttpHandler(t *testing.T) {
+	type tcase struct {
+		config      dict.Dict
+		traceparent string
+		status      int
+		// expSpans is the number of spans exported, 0 for none
+		expSpans   int
+		expTraceID string
+		expParent  string
+		expStatus  tracepb.Status_StatusCode
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			var rcv receiver
+			srv := httptest.NewServer(&rcv)
+			defer srv.Close()
+
+			config := dict.Dict{"endpoint": srv.URL, "headers": []string{"Authorization=Bearer token"}}
+			for k, v := range tc.config {
+				config[k] = v
+			}
+			o, err := opentelemetry.New(config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			mc, err := memory.New(dict.Dict{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			c := o.InstrumentedCache(mc)
+
+			tile := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				key := &tegolaCache.Key{MapName: "osm", Z: 1, X: 2, Y: 3}
+				if _, _, err := c.Get(r.Context(), key); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+
+				_, span := observability.StartSpan(r.Context(), "tegola.layer.fetch",
+					observability.Attr(observability.AttrLayerName, "roads"),
+				)
+				span.RecordError(errors.New("boom"))
+				span.End()
+
+				w.WriteHeader(tc.status)
+			})
+
+			router := httptreemux.New()
+			router.UsingContext().Handler(http.MethodGet, tileRoute, o.InstrumentedAPIHttpHandler(http.MethodGet, tileRoute, tile))
+
+			req := httptest.NewRequest(http.MethodGet, "/maps/osm/1/2/3", nil)
+			if tc.traceparent != "" {
+				req.Header.Set("traceparent", tc.traceparent)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			// exports what's left
+			o.Shutdown()
+
+			rcv.mu.Lock()
+			defer rcv.mu.Unlock()
+
+			if len(rcv.spans) != tc.expSpans {
+				t.Fatalf("spans, expected %v got %v", tc.expSpans, len(rcv.spans))
+			}
+			if tc.expSpans == 0 {
+				return
+			}
+
+			if got := rcv.headers.Get("Authorization"); got != "Bearer token" {
+				t.Errorf("authorization header, expected %q got %q", "Bearer token", got)
+			}
+
+			server, ok := rcv.span("GET " + tileRoute)
+			if !ok {
+				t.Fatalf("expected a server span, got %+v", rcv.spans)
+			}
+			if server.GetKind() != tracepb.Span_SPAN_KIND_SERVER {
+				t.Errorf("server span kind, expected %v got %v", tracepb.Span_SPAN_KIND_SERVER, server.GetKind())
+			}
+			traceID := hex.EncodeToString(server.GetTraceId())
+			if tc.expTraceID != "" && traceID != tc.expTraceID {
+				t.Errorf("trace id, expected %v got %v", tc.expTraceID, traceID)
+			}
+			if parent := hex.EncodeToString(server.GetParentSpanId()); parent != tc.expParent {
+				t.Errorf("parent span id, expected %q got %q", tc.expParent, parent)
+			}
+			if server.GetStatus().GetCode() != tc.expStatus {
+				t.Errorf("server span status, expected %v got %v", tc.expStatus, server.GetStatus().GetCode())
+			}
+			for key, exp := range map[string]*commonpb.AnyValue{
+				observability.AttrMapName:   {Value: &commonpb.AnyValue_StringValue{StringValue: "osm"}},
+				observability.AttrTileZ:     intValue(1),
+				observability.AttrTileX:     intValue(2),
+				observability.AttrTileY:     intValue(3),
+				"http.response.status_code": intValue(int64(tc.status)),
+			} {
+				if got := attr(server, key); !proto.Equal(got, exp) {
+					t.Errorf("server span attribute %v, expected %v got %v", key, exp, got)
+				}
+			}
+
+			for _, name := range []string{"tegola.cache.get", "tegola.layer.fetch"} {
+				child, ok := rcv.span(name)
+				if !ok {
+					t.Errorf("expected a %v span, got %+v", name, rcv.spans)
+					continue
+				}
+				if string(child.GetTraceId()) != string(server.GetTraceId()) || string(child.GetParentSpanId()) != string(server.GetSpanId()) {
+					t.Errorf("%v span, expected to be a child of %x/%x got %x/%x", name, server.GetTraceId(), server.GetSpanId(), child.GetTraceId(), child.GetParentSpanId())
+				}
+			}
+
+			get, _ := rcv.span("tegola.cache.get")
+			hit := &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: false}}
+			if got := attr(get, "tegola.cache.hit"); !proto.Equal(got, hit) {
+				t.Errorf("cache hit attribute, expected %v got %v", hit, got)
+			}
+			fetch, _ := rcv.span("tegola.layer.fetch")
+			if fetch.GetStatus().GetCode() != tracepb.Status_STATUS_CODE_ERROR {
+				t.Errorf("layer span status, expected %v got %v", tracepb.Status_STATUS_CODE_ERROR, fetch.GetStatus().GetCode())
+			}
+
+			if _, ok := rcv.metric("tegola_http_requests_total"); !ok {
+				t.Errorf("expected the tegola_http_requests_total metric, got %+v", rcv.metrics)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"root": {
+			status:   http.StatusOK,
+			expSpans: 3,
+		},
+		"remote parent": {
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			status:      http.StatusOK,
+			expSpans:    3,
+			expTraceID:  "4bf92f3577b34da6a3ce929d0e0e4736",
+			expParent:   "00f067aa0ba902b7",
+		},
+		"remote parent not sampled": {
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			status:      http.StatusOK,
+		},
+		"remote parent sampled with ratio 0": {
+			config:      dict.Dict{"sample_ratio": 0.0},
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			status:      http.StatusOK,
+			expSpans:    3,
+			expTraceID:  "4bf92f3577b34da6a3ce929d0e0e4736",
+			expParent:   "00f067aa0ba902b7",
+		},
+		"invalid traceparent": {
+			traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			status:      http.StatusOK,
+			expSpans:    3,
+		},
+		"ratio 0": {
+			config: dict.Dict{"sample_ratio": 0.0},
+			status: http.StatusOK,
+		},
+		"server error": {
+			status:    http.StatusInternalServerError,
+			expSpans:  3,
+			expStatus: tracepb.Status_STATUS_CODE_ERROR,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	var rcv receiver
+	srv := httptest.NewServer(&rcv)
+	defer srv.Close()
+
+	o, err := opentelemetry.New(dict.Dict{"endpoint": srv.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_counter_total", Help: "counter"})
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_gauge", Help: "gauge"})
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_histogram", Help: "histogram", Buckets: []float64{1, 2}})
+	o.MustRegister(counter, gauge, histogram)
+
+	counter.Add(2)
+	gauge.Set(4)
+	for _, v := range []float64{0.5, 0.5, 1.5, 3} {
+		histogram.Observe(v)
+	}
+
+	o.Shutdown()
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+
+	if m, ok := rcv.metric("test_counter_total"); !ok || !m.GetSum().GetIsMonotonic() {
+		t.Errorf("test_counter_total, expected a monotonic sum got %v", m)
+	}
+	if m, ok := rcv.metric("test_gauge"); !ok || m.GetGauge() == nil {
+		t.Errorf("test_gauge, expected a gauge got %v", m)
+	}
+
+	m, ok := rcv.metric("test_histogram")
+	if !ok || len(m.GetHistogram().GetDataPoints()) != 1 {
+		t.Fatalf("test_histogram, expected a histogram with a data point got %v", m)
+	}
+	dp := m.GetHistogram().GetDataPoints()[0]
+	if dp.GetCount() != 4 {
+		t.Errorf("count, expected 4 got %v", dp.GetCount())
+	}
+	expBuckets := []uint64{2, 1, 1}
+	if !slices.Equal(dp.GetBucketCounts(), expBuckets) {
+		t.Errorf("bucket counts, expected %v got %v", expBuckets, dp.GetBucketCounts())
+	}
+	if expBounds := []float64{1, 2}; !slices.Equal(dp.GetExplicitBounds(), expBounds) {
+		t.Errorf("explicit bounds, expected %v got %v", expBounds, dp.GetExplicitBounds())
+	}
+}
+
+func TestNew(t *testing.T) {
+	type tcase struct {
+		config dict.Dict
+		expErr bool
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			o, err := opentelemetry.New(tc.config)
+			if tc.expErr {
+				if err == nil {
+					t.Errorf("expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if o.Name() != opentelemetry.Name {
+				t.Errorf("name, expected %v got %v", opentelemetry.Name, o.Name())
+			}
+			if o.Handler("/metrics") != nil {
+				t.Errorf("handler, expected nil")
+			}
+			// nothing was recorded, so nothing is exported
+			o.Shutdown()
+		}
+	}
+
+	tests := map[string]tcase{
+		"defaults": {
+			config: dict.Dict{},
+		},
+		"relative endpoint": {
+			config: dict.Dict{"endpoint": "localhost:4318"},
+			expErr: true,
+		},
+		"invalid header": {
+			config: dict.Dict{"headers": []string{"Authorization"}},
+			expErr: true,
+		},
+		"sample ratio above 1": {
+			config: dict.Dict{"sample_ratio": 1.5},
+			expErr: true,
+		},
+		"zero export interval": {
+			config: dict.Dict{"trace_export_interval": 0},
+			expErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,104 @@
+package opentelemetry
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/go-spatial/tegola/observability"
+)
+
+// tracer starts the spans of the observer with the tracer of the
+// OpenTelemetry SDK
+type tracer struct {
+	tracer trace.Tracer
+}
+
+func (t *tracer) Start(ctx context.Context, kind observability.SpanKind, name string, attrs ...observability.Attribute) (context.Context, observability.Span) {
+	ctx, s := t.tracer.Start(ctx, name,
+		trace.WithSpanKind(spanKind(kind)),
+		trace.WithAttributes(attributes(attrs)...),
+	)
+	return ctx, span{span: s}
+}
+
+// span adapts the spans of the SDK to the spans of tegola
+type span struct {
+	span trace.Span
+}
+
+func (s span) SetAttributes(attrs ...observability.Attribute) {
+	s.span.SetAttributes(attributes(attrs)...)
+}
+
+func (s span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s span) End() { s.span.End() }
+
+func spanKind(kind observability.SpanKind) trace.SpanKind {
+	switch kind {
+	case observability.SpanKindServer:
+		return trace.SpanKindServer
+	case observability.SpanKindClient:
+		return trace.SpanKindClient
+	default:
+		return trace.SpanKindInternal
+	}
+}
+
+// attributes converts the attributes to the attributes of the SDK. Values
+// of other types than strings, bools, integers and floats are formatted as
+// strings.
+func attributes(attrs []observability.Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, len(attrs))
+	for i, attr := range attrs {
+		kvs[i] = keyValue(attr.Key, attr.Value)
+	}
+	return kvs
+}
+
+func keyValue(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int8:
+		return attribute.Int64(key, int64(v))
+	case int16:
+		return attribute.Int64(key, int64(v))
+	case int32:
+		return attribute.Int64(key, int64(v))
+	case int64:
+		return attribute.Int64(key, v)
+	case uint:
+		return attribute.Int64(key, int64(v))
+	case uint8:
+		return attribute.Int64(key, int64(v))
+	case uint16:
+		return attribute.Int64(key, int64(v))
+	case uint32:
+		return attribute.Int64(key, int64(v))
+	case uint64:
+		return attribute.Int64(key, int64(v))
+	case float32:
+		return attribute.Float64(key, float64(v))
+	case float64:
+		return attribute.Float64(key, v)
+	case fmt.Stringer:
+		return attribute.String(key, v.String())
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
@@ -34,7 +34,7 @@ func NewBuildInfo(registry prometheus.Registerer) {
 			},
 		)
 	}
-	registry.MustRegister(BuildInfo)
+	BuildInfo = register(registry, BuildInfo)
 }
 
 func PublishBuildInfo() {
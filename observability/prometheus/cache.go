@@ -19,6 +19,8 @@ type cache struct {
 	durationSeconds   *prometheus.HistogramVec
 	responseSizeBytes *prometheus.HistogramVec
 	errors            *prometheus.CounterVec
+	// evictions is only set for caches evicting tiles on their own
+	evictions *prometheus.CounterVec
 }
 
 func newCache(registry prometheus.Registerer, prefix string, observeVars []string, subCache tegolaCache.Interface) *cache {
@@ -74,14 +76,27 @@ func newCache(registry prometheus.Registerer, prefix string, observeVars []strin
 	)
 
 	// Register our variables
-	registry.MustRegister(
-		c.inFlightGauge,
-		c.hitsCounter,
-		c.missesCounter,
-		c.durationSeconds,
-		c.responseSizeBytes,
-		c.errors,
-	)
+	c.inFlightGauge = register(registry, c.inFlightGauge)
+	c.hitsCounter = register(registry, c.hitsCounter)
+	c.missesCounter = register(registry, c.missesCounter)
+	c.durationSeconds = register(registry, c.durationSeconds)
+	c.responseSizeBytes = register(registry, c.responseSizeBytes)
+	c.errors = register(registry, c.errors)
+
+	if notifier, ok := subCache.(tegolaCache.EvictionNotifier); ok {
+		c.evictions = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: prefix + "_evictions_total",
+				Help: "A counter of the number of tiles evicted by the cache",
+			},
+			names,
+		)
+		c.evictions = register(registry, c.evictions)
+
+		notifier.NotifyEviction(func(key *tegolaCache.Key) {
+			c.evictions.With(c.labels("evict", key)).Inc()
+		})
+	}
 
 	return &c
 }
@@ -124,11 +139,29 @@ func (co *cache) Get(ctx context.Context, key *tegolaCache.Key) ([]byte, bool, e
 	lbs := co.labels("get", key)
 	now := time.Now()
 	body, ok, err := co.cache.Get(ctx, key)
-	co.durationSeconds.With(lbs).Observe(time.Since(now).Seconds())
+	co.observeGet(lbs, now, body, ok, err)
+	co.inFlightGauge.Dec()
+	return body, ok, err
+}
+
+// GetInfo will record metrics around the getting the tile and its info from
+// the sub cache. Stale tiles are counted as hits.
+func (co *cache) GetInfo(ctx context.Context, key *tegolaCache.Key) ([]byte, tegolaCache.TileInfo, bool, error) {
+	co.inFlightGauge.Inc()
+	lbs := co.labels("get", key)
+	now := time.Now()
+	body, info, ok, err := tegolaCache.GetInfo(ctx, co.cache, key)
+	co.observeGet(lbs, now, body, ok, err)
+	co.inFlightGauge.Dec()
+	return body, info, ok, err
+}
+
+// observeGet records the metrics of getting a tile which started at start
+func (co *cache) observeGet(lbs prometheus.Labels, start time.Time, body []byte, ok bool, err error) {
+	co.durationSeconds.With(lbs).Observe(time.Since(start).Seconds())
 	if err != nil {
 		co.errors.With(lbs).Add(1)
-		co.inFlightGauge.Dec()
-		return body, ok, err
+		return
 	}
 	if ok {
 		co.hitsCounter.With(lbs).Add(1)
@@ -137,8 +170,6 @@ func (co *cache) Get(ctx context.Context, key *tegolaCache.Key) ([]byte, bool, e
 	}
 
 	co.responseSizeBytes.With(lbs).Observe(float64(len(body)))
-	co.inFlightGauge.Dec()
-	return body, ok, nil
 }
 
 // Set will observe metrics around setting the tile via the sub cache.
@@ -172,5 +203,45 @@ func (co *cache) Purge(ctx context.Context, key *tegolaCache.Key) error {
 	return nil
 }
 
+// PurgePrefix will record the metrics around purging the tiles by prefix from
+// the sub cache.
+func (co *cache) PurgePrefix(ctx context.Context, mapName, layerName string, minZoom, maxZoom uint) error {
+	co.inFlightGauge.Inc()
+	defer co.inFlightGauge.Dec()
+
+	lbs := co.labels("purge_prefix", &tegolaCache.Key{MapName: mapName, LayerName: layerName})
+	now := time.Now()
+	err := tegolaCache.PurgePrefix(ctx, co.cache, mapName, layerName, minZoom, maxZoom)
+	co.durationSeconds.With(lbs).Observe(time.Since(now).Seconds())
+	if err != nil {
+		co.errors.With(lbs).Add(1)
+	}
+	return err
+}
+
+// PurgeVersions will record the metrics around purging the versions of a map
+// from the sub cache.
+func (co *cache) PurgeVersions(ctx context.Context, mapName, keepVersion string) error {
+	co.inFlightGauge.Inc()
+	defer co.inFlightGauge.Dec()
+
+	lbs := co.labels("purge_versions", &tegolaCache.Key{MapName: mapName})
+	now := time.Now()
+	err := tegolaCache.PurgeVersions(ctx, co.cache, mapName, keepVersion)
+	co.durationSeconds.With(lbs).Observe(time.Since(now).Seconds())
+	if err != nil {
+		co.errors.With(lbs).Add(1)
+	}
+	return err
+}
+
+// Original returns the first cache backend to be wrapped
+func (co *cache) Original() tegolaCache.Interface {
+	if w, ok := co.cache.(tegolaCache.Wrapped); ok {
+		return w.Original()
+	}
+	return co.cache
+}
+
 func (co cache) Wrapped() tegolaCache.Interface { return co.cache }
 func (co cache) IsObserver() bool               { return true }
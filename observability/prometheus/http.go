@@ -64,12 +64,10 @@ func newHttpHandler(registry prometheus.Registerer, prefix string, URLPrefix str
 		[]string{},
 	)
 
-	registry.MustRegister(
-		handler.inFlightGauge,
-		handler.counter,
-		handler.durationSeconds,
-		handler.responseSizeBytes,
-	)
+	handler.inFlightGauge = register(registry, handler.inFlightGauge)
+	handler.counter = register(registry, handler.counter)
+	handler.durationSeconds = register(registry, handler.durationSeconds)
+	handler.responseSizeBytes = register(registry, handler.responseSizeBytes)
 
 	return &handler
 }
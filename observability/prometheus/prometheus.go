@@ -2,7 +2,10 @@ package prometheus
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -54,6 +57,10 @@ type observer struct {
 	httpHandlers map[string]*httpHandler
 	registry     prometheus.Registerer
 
+	// coalescedTiles is registered on the first coalesced request
+	coalescedTiles     *tiles
+	coalescedTilesInit sync.Once
+
 	publishedBuildInfo sync.Once
 	initCall           sync.Once
 	pushURL            string
@@ -79,6 +86,9 @@ func New(config dict.Dicter) (observability.Interface, error) {
 	if len(obs.observeVars) == 0 {
 		obs.observeVars = []string{":map_name", ":layer_name", ":z"}
 	}
+	if err := checkObserveVars(obs.observeVars); err != nil {
+		return nil, err
+	}
 
 	NewBuildInfo(obs.registry)
 
@@ -156,8 +166,38 @@ func (obs *observer) Shutdown() {
 	cleanUpFunctionsLck.Unlock()
 }
 
+// MustRegister registers the collectors, replacing the equal collectors
+// registered by the observer of a previous config, like the collectors of the
+// providers of a config which has been reloaded.
 func (obs *observer) MustRegister(collectors ...observability.Collector) {
-	obs.registry.MustRegister(collectors...)
+	for _, c := range collectors {
+		err := obs.registry.Register(c)
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			obs.registry.Unregister(are.ExistingCollector)
+			err = obs.registry.Register(c)
+		}
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// register registers the collector with the registry. The observers of the
+// configs the server reloads share the default registry, so when an equal
+// collector is already registered, it's returned for its metrics to carry on.
+func register[C prometheus.Collector](registry prometheus.Registerer, c C) C {
+	err := registry.Register(c)
+	if err == nil {
+		return c
+	}
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(C); ok {
+			return existing
+		}
+	}
+	panic(err)
 }
 
 func (_ *observer) CollectorConfig(_ string) map[string]interface{} {
@@ -200,6 +240,38 @@ func (obs *observer) InstrumentedCache(cacheObject tegolaCache.Interface) tegola
 	return newCache(obs.registry, "tegola_cache", obs.observeVars, cacheObject)
 }
 
+func (obs *observer) ObserveCoalescedTile(key *tegolaCache.Key) {
+	if obs == nil {
+		return
+	}
+	obs.coalescedTilesInit.Do(func() {
+		obs.coalescedTiles = newTiles(obs.registry, "tegola_tile", obs.observeVars)
+	})
+	obs.coalescedTiles.coalesced(key)
+}
+
+var (
+	registeredVarsLck sync.Mutex
+	registeredVars    []string
+)
+
+// checkObserveVars checks the variables are the ones of the observers created
+// before, like the observer of a config which has been reloaded. The label
+// names of the metrics of a registry can't change.
+func checkObserveVars(observeVars []string) error {
+	registeredVarsLck.Lock()
+	defer registeredVarsLck.Unlock()
+
+	if registeredVars == nil {
+		registeredVars = observeVars
+		return nil
+	}
+	if !slices.Equal(registeredVars, observeVars) {
+		return fmt.Errorf("prometheus: variables can not be changed from %v to %v without restarting tegola", registeredVars, observeVars)
+	}
+	return nil
+}
+
 var (
 	cleanUpFunctionsLck sync.Mutex
 	cleanUpFunctions    []func()
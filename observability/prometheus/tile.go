@@ -0,0 +1,65 @@
+package prometheus
+
+import (
+	"strconv"
+
+	tegolaCache "github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/observability"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// tiles records the metrics of the encoding of the tiles
+type tiles struct {
+	observeVars      []string
+	coalescedCounter *prometheus.CounterVec
+}
+
+func newTiles(registry prometheus.Registerer, prefix string, observeVars []string) *tiles {
+	t := tiles{
+		observeVars: observeVars,
+	}
+
+	var names []string
+	for _, key := range observeVars {
+		if name := observability.LabelForObserveVar(key); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	t.coalescedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prefix + "_coalesced_requests_total",
+			Help: "A counter of the number of tile requests served by the encoding of the tile for a concurrent request",
+		},
+		names,
+	)
+
+	t.coalescedCounter = register(registry, t.coalescedCounter)
+
+	return &t
+}
+
+// labels returns prometheus.Labels based on the configured observeVars
+func (t *tiles) labels(key *tegolaCache.Key) prometheus.Labels {
+	lbs := make(prometheus.Labels)
+	for _, keyName := range t.observeVars {
+		switch keyName {
+		case observability.ObserveVarMapName:
+			lbs["map_name"] = key.MapName
+		case observability.ObserveVarLayerName:
+			lbs["layer_name"] = key.LayerName
+		case observability.ObserveVarTileX:
+			lbs["x"] = strconv.FormatInt(int64(key.X), 10)
+		case observability.ObserveVarTileY:
+			lbs["y"] = strconv.FormatInt(int64(key.Y), 10)
+		case observability.ObserveVarTileZ:
+			lbs["z"] = strconv.FormatInt(int64(key.Z), 10)
+		}
+	}
+	return lbs
+}
+
+// coalesced records a request coalesced with the encoding of the tile of the key
+func (t *tiles) coalesced(key *tegolaCache.Key) {
+	t.coalescedCounter.With(t.labels(key)).Inc()
+}
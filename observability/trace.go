@@ -0,0 +1,93 @@
+package observability
+
+import "context"
+
+// The attributes describing the tile being worked on by a span
+const (
+	AttrMapName   = "tegola.map"
+	AttrLayerName = "tegola.layer"
+	AttrTileZ     = "tegola.z"
+	AttrTileX     = "tegola.x"
+	AttrTileY     = "tegola.y"
+)
+
+// SpanKind describes the relationship of a span to its parent and children
+type SpanKind int
+
+const (
+	// SpanKindInternal is an operation within tegola
+	SpanKindInternal SpanKind = iota
+	// SpanKindServer is the handling of a request to tegola
+	SpanKindServer
+	// SpanKindClient is a request made by tegola, i.e. a SQL query
+	SpanKindClient
+)
+
+// Attribute is a key value pair describing a span. Values are expected to be
+// strings, bools, integers or floats.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attr is a convenience function to build an Attribute
+func Attr(key string, value interface{}) Attribute { return Attribute{Key: key, Value: value} }
+
+// Span is a timed operation of a trace
+type Span interface {
+	// SetAttributes adds the attributes to the span, overwriting the
+	// attributes with the same keys
+	SetAttributes(attrs ...Attribute)
+	// RecordError records the err and marks the span as failed. nil errors
+	// are ignored.
+	RecordError(err error)
+	// End completes the span. Calls after the first one are ignored.
+	End()
+}
+
+// Tracer starts spans. Observers which trace requests put their Tracer in
+// the context of the requests they instrument.
+type Tracer interface {
+	// Start starts a span which is a child of the span of ctx, if any, and
+	// returns a copy of ctx holding the new span
+	Start(ctx context.Context, kind SpanKind, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+type tracerKey struct{}
+
+// ContextWithTracer returns a copy of ctx holding the tracer
+func ContextWithTracer(ctx context.Context, t Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, t)
+}
+
+// TracerFromContext returns the tracer held by ctx, nil if there is none
+func TracerFromContext(ctx context.Context) Tracer {
+	t, _ := ctx.Value(tracerKey{}).(Tracer)
+	return t
+}
+
+// StartSpan starts an internal span with the tracer of ctx. When ctx holds no
+// tracer the span does nothing, so it's cheap to call when tracing is off.
+func StartSpan(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	return start(ctx, SpanKindInternal, name, attrs)
+}
+
+// StartClientSpan starts a span for a request made by tegola, i.e. a SQL
+// query, with the tracer of ctx
+func StartClientSpan(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	return start(ctx, SpanKindClient, name, attrs)
+}
+
+func start(ctx context.Context, kind SpanKind, name string, attrs []Attribute) (context.Context, Span) {
+	t := TracerFromContext(ctx)
+	if t == nil {
+		return ctx, noopSpan{}
+	}
+	return t.Start(ctx, kind, name, attrs...)
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
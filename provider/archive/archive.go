@@ -0,0 +1,281 @@
+// Package archive provides MVT providers serving pre-built tiles from
+// MBTiles and PMTiles archives.
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/mbtiles"
+	"github.com/go-spatial/tegola/internal/pmtiles"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/internal/mvtlayers"
+)
+
+const (
+	MBTilesName = "mbtiles"
+	PMTilesName = "pmtiles"
+)
+
+const (
+	ConfigKeyFilePath = "filepath"
+)
+
+var (
+	ErrMissingFilePath = errors.New("archive: missing required param 'filepath'")
+	// ErrMissingVectorLayers is returned when an archive does not list its layers in its metadata
+	ErrMissingVectorLayers = errors.New("archive: metadata does not contain vector_layers, unable to determine the layers of the archive")
+)
+
+// ErrUnsupportedTileCompression is returned when the tiles of a PMTiles
+// archive are compressed with another compression than gzip
+type ErrUnsupportedTileCompression struct {
+	FilePath    string
+	Compression pmtiles.Compression
+}
+
+func (e ErrUnsupportedTileCompression) Error() string {
+	return fmt.Sprintf("archive: the tiles of %v are compressed with %v, only gzip compressed and uncompressed tiles are supported", e.FilePath, e.Compression)
+}
+
+func init() {
+	provider.MVTRegister(provider.TypeMvt.Prefix()+MBTilesName, NewMBTilesProvider, Cleanup)
+	provider.MVTRegister(provider.TypeMvt.Prefix()+PMTilesName, NewPMTilesProvider, Cleanup)
+}
+
+// source is an archive the provider reads tiles from
+type source interface {
+	// tile returns the (possibly gzipped) tile data
+	tile(ctx context.Context, z, x, y uint) (data []byte, ok bool, err error)
+	// vectorLayers returns the vector_layers entry of the metadata as raw json
+	vectorLayers(ctx context.Context) (json.RawMessage, error)
+	close() error
+}
+
+// providers are the providers that have been instantiated. they are closed by Cleanup
+var (
+	providersLock sync.Mutex
+	providers     []*Provider
+)
+
+// NewMBTilesProvider instantiates a provider serving the tiles of an MBTiles
+// file. The config expects the following params:
+//
+//	filepath (string): [Required] the path to the MBTiles file
+func NewMBTilesProvider(config dict.Dicter, _ []provider.Map) (provider.MVTTiler, error) {
+	path, err := config.String(ConfigKeyFilePath, nil)
+	if err != nil || path == "" {
+		return nil, ErrMissingFilePath
+	}
+
+	db, err := mbtiles.OpenReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return newProvider(mbtilesSource{db: db})
+}
+
+// NewPMTilesProvider instantiates a provider serving the tiles of a PMTiles
+// archive. The config expects the following params:
+//
+//	filepath (string): [Required] the path to the PMTiles archive
+func NewPMTilesProvider(config dict.Dicter, _ []provider.Map) (provider.MVTTiler, error) {
+	path, err := config.String(ConfigKeyFilePath, nil)
+	if err != nil || path == "" {
+		return nil, ErrMissingFilePath
+	}
+
+	r, err := pmtiles.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if tt := r.Header().TileType; tt != pmtiles.TileTypeMVT {
+		r.Close()
+		return nil, fmt.Errorf("archive: %v does not contain vector tiles (tile type %v)", path, tt)
+	}
+	switch tc := r.Header().TileCompression; tc {
+	case pmtiles.CompressionUnknown, pmtiles.CompressionNone, pmtiles.CompressionGzip:
+		// gzipped tiles are recognized by their header
+	default:
+		r.Close()
+		return nil, ErrUnsupportedTileCompression{FilePath: path, Compression: tc}
+	}
+
+	return newProvider(pmtilesSource{r: r})
+}
+
+func newProvider(src source) (*Provider, error) {
+	raw, err := src.vectorLayers(context.Background())
+	if err != nil {
+		src.close()
+		return nil, err
+	}
+
+	var vectorLayers []struct {
+		ID string `json:"id"`
+	}
+	if err = json.Unmarshal(raw, &vectorLayers); err != nil {
+		src.close()
+		return nil, fmt.Errorf("archive: decoding vector_layers: %w", err)
+	}
+
+	p := Provider{
+		src: src,
+	}
+	for _, vl := range vectorLayers {
+		p.layers = append(p.layers, Layer{name: vl.ID})
+	}
+
+	providersLock.Lock()
+	providers = append(providers, &p)
+	providersLock.Unlock()
+
+	return &p, nil
+}
+
+// Cleanup closes all the archives opened by the providers
+func Cleanup() {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+
+	for _, p := range providers {
+		p.src.close()
+	}
+	providers = nil
+}
+
+// Provider serves the tiles of an archive
+type Provider struct {
+	src    source
+	layers []Layer
+}
+
+// Close closes the archive, once: providers already closed, or closed by
+// Cleanup, are skipped.
+func (p *Provider) Close() error {
+	providersLock.Lock()
+	i := slices.Index(providers, p)
+	if i >= 0 {
+		providers = slices.Delete(providers, i, i+1)
+	}
+	providersLock.Unlock()
+
+	if i < 0 {
+		return nil
+	}
+	return p.src.close()
+}
+
+// Layers returns the layers listed in the metadata of the archive
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, len(p.layers))
+	for i := range p.layers {
+		ls[i] = p.layers[i]
+	}
+	return ls, nil
+}
+
+// MVTForLayers returns the tile from the archive, reduced to the requested
+// layers in the requested order. Layers are renamed to their MVTName. If the
+// archive does not contain the tile, an empty tile is returned.
+func (p *Provider) MVTForLayers(ctx context.Context, tile provider.Tile, _ provider.Params, layers []provider.Layer) ([]byte, error) {
+	z, x, y := tile.ZXY()
+
+	data, ok, err := p.src.tile(ctx, uint(z), x, y)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || len(data) == 0 {
+		return []byte{}, nil
+	}
+
+	if data, err = mvtlayers.Gunzip(data); err != nil {
+		return nil, fmt.Errorf("archive: decompressing tile (%v/%v/%v): %w", z, x, y, err)
+	}
+
+	if data, err = mvtlayers.Select(data, layers); err != nil {
+		return nil, fmt.Errorf("archive: decoding tile (%v/%v/%v): %w", z, x, y, err)
+	}
+
+	return data, nil
+}
+
+// Layer is a layer of an archive
+type Layer struct {
+	name string
+}
+
+func (l Layer) Name() string { return l.name }
+
+// GeomType returns nil as archives don't record the geometry type of their layers
+func (l Layer) GeomType() geom.Geometry { return nil }
+
+func (l Layer) SRID() uint64 { return tegola.WebMercator }
+
+type mbtilesSource struct {
+	db *mbtiles.DB
+}
+
+func (s mbtilesSource) tile(ctx context.Context, z, x, y uint) ([]byte, bool, error) {
+	return s.db.Get(ctx, &cache.Key{Z: z, X: x, Y: y})
+}
+
+func (s mbtilesSource) vectorLayers(ctx context.Context) (json.RawMessage, error) {
+	md, err := s.db.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var layers struct {
+		VectorLayers json.RawMessage `json:"vector_layers"`
+	}
+	if md["json"] == "" {
+		return nil, ErrMissingVectorLayers
+	}
+	if err = json.Unmarshal([]byte(md["json"]), &layers); err != nil {
+		return nil, fmt.Errorf("archive: decoding metadata json: %w", err)
+	}
+	if layers.VectorLayers == nil {
+		return nil, ErrMissingVectorLayers
+	}
+
+	return layers.VectorLayers, nil
+}
+
+func (s mbtilesSource) close() error { return s.db.Close() }
+
+type pmtilesSource struct {
+	r *pmtiles.Reader
+}
+
+func (s pmtilesSource) tile(ctx context.Context, z, x, y uint) ([]byte, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	return s.r.Tile(z, x, y)
+}
+
+func (s pmtilesSource) vectorLayers(_ context.Context) (json.RawMessage, error) {
+	md, err := s.r.Metadata()
+	if err != nil {
+		return nil, err
+	}
+
+	vl, ok := md["vector_layers"]
+	if !ok {
+		return nil, ErrMissingVectorLayers
+	}
+	return json.Marshal(vl)
+}
+
+func (s pmtilesSource) close() error { return s.r.Close() }
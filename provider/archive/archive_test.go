@@ -0,0 +1,237 @@
+package archive_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/pmtiles"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/archive"
+	"github.com/golang/protobuf/proto"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+)
+
+// testTile returns a gzipped tile with an empty layer for each of the names
+func testTile(t *testing.T, names ...string) []byte {
+	t.Helper()
+
+	var vtile vectorTile.Tile
+	for _, name := range names {
+		vtile.Layers = append(vtile.Layers, &vectorTile.Tile_Layer{
+			Version: proto.Uint32(2),
+			Name:    proto.String(name),
+			Extent:  proto.Uint32(4096),
+		})
+	}
+
+	data, err := proto.Marshal(&vtile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(data)
+	gz.Close()
+
+	return buf.Bytes()
+}
+
+// layerNames decodes the tile and returns the names of its layers
+func layerNames(t *testing.T, data []byte) (names []string) {
+	t.Helper()
+
+	var vtile vectorTile.Tile
+	if err := proto.Unmarshal(data, &vtile); err != nil {
+		t.Fatalf("unexpected error decoding tile: %v", err)
+	}
+	for _, l := range vtile.Layers {
+		names = append(names, l.GetName())
+	}
+	return names
+}
+
+// writePMTiles writes an archive holding tile 1/0/0 to a temp dir
+func writePMTiles(t *testing.T, metadata map[string]interface{}) string {
+	t.Helper()
+	return writePMTilesCompression(t, metadata, pmtiles.CompressionGzip)
+}
+
+// writePMTilesCompression writes an archive holding tile 1/0/0, with the
+// tile compression of its header set to compression, to a temp dir
+func writePMTilesCompression(t *testing.T, metadata map[string]interface{}, compression pmtiles.Compression) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	w, err := pmtiles.NewWriter(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Close()
+
+	w.TileCompression = compression
+	w.Metadata = metadata
+	if err = w.Add(pmtiles.TileID(1, 0, 0), testTile(t, "water", "roads", "land")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	path := filepath.Join(dir, "test.pmtiles")
+	if err = w.WriteFile(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+func TestPMTilesProvider(t *testing.T) {
+	type tcase struct {
+		tile     provider.Tile
+		layers   []provider.Layer
+		expected []string
+	}
+
+	path := writePMTiles(t, map[string]interface{}{
+		"vector_layers": []map[string]interface{}{
+			{"id": "water"},
+			{"id": "roads"},
+			{"id": "land"},
+		},
+	})
+
+	p, err := archive.NewPMTilesProvider(dict.Dict{archive.ConfigKeyFilePath: path}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer archive.Cleanup()
+
+	layers, err := p.Layers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, l := range layers {
+		names = append(names, l.Name())
+		if l.SRID() != tegola.WebMercator {
+			t.Errorf("layer %v srid, expected %v got %v", l.Name(), tegola.WebMercator, l.SRID())
+		}
+	}
+	if expected := []string{"water", "roads", "land"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("layers, expected %v got %v", expected, names)
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			data, err := p.MVTForLayers(context.Background(), tc.tile, nil, tc.layers)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := layerNames(t, data); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v got %v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"filter and order": {
+			tile: provider.NewTile(1, 0, 0, 64, tegola.WebMercator),
+			layers: []provider.Layer{
+				{Name: "land", MVTName: "land"},
+				{Name: "water", MVTName: "water"},
+			},
+			expected: []string{"land", "water"},
+		},
+		"rename": {
+			tile: provider.NewTile(1, 0, 0, 64, tegola.WebMercator),
+			layers: []provider.Layer{
+				{Name: "roads", MVTName: "streets"},
+			},
+			expected: []string{"streets"},
+		},
+		"unknown layer": {
+			tile: provider.NewTile(1, 0, 0, 64, tegola.WebMercator),
+			layers: []provider.Layer{
+				{Name: "buildings", MVTName: "buildings"},
+			},
+		},
+		"missing tile": {
+			tile: provider.NewTile(1, 1, 1, 64, tegola.WebMercator),
+			layers: []provider.Layer{
+				{Name: "water", MVTName: "water"},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestNewPMTilesProvider(t *testing.T) {
+	type tcase struct {
+		config      dict.Dict
+		expectedErr error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			_, err := archive.NewPMTilesProvider(tc.config, nil)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected err %v got %v", tc.expectedErr, err)
+			}
+		}
+	}
+
+	zstdPath := writePMTilesCompression(t, map[string]interface{}{"vector_layers": []interface{}{}}, pmtiles.CompressionZstd)
+
+	tests := map[string]tcase{
+		"missing filepath": {
+			config:      dict.Dict{},
+			expectedErr: archive.ErrMissingFilePath,
+		},
+		"missing vector_layers": {
+			config: dict.Dict{
+				archive.ConfigKeyFilePath: writePMTiles(t, map[string]interface{}{"name": "test"}),
+			},
+			expectedErr: archive.ErrMissingVectorLayers,
+		},
+		"zstd tiles": {
+			config: dict.Dict{
+				archive.ConfigKeyFilePath: zstdPath,
+			},
+			expectedErr: archive.ErrUnsupportedTileCompression{FilePath: zstdPath, Compression: pmtiles.CompressionZstd},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestProviderClose(t *testing.T) {
+	path := writePMTiles(t, map[string]interface{}{
+		"vector_layers": []map[string]interface{}{{"id": "water"}},
+	})
+
+	p, err := archive.NewPMTilesProvider(dict.Dict{archive.ConfigKeyFilePath: path}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	closer := p.(interface{ Close() error })
+	if err = closer.Close(); err != nil {
+		t.Fatalf("close, unexpected error: %v", err)
+	}
+	// the provider is no longer tracked, it is not closed again
+	if err = closer.Close(); err != nil {
+		t.Errorf("second close, expected nil got %v", err)
+	}
+	archive.Cleanup()
+}
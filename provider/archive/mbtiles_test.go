@@ -0,0 +1,54 @@
+//go:build cgo
+// +build cgo
+
+package archive_test
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/mbtiles"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/archive"
+)
+
+func TestMBTilesProvider(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.mbtiles")
+
+	db, err := mbtiles.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = db.Set(ctx, &cache.Key{Z: 1, X: 0, Y: 0}, testTile(t, "water", "roads")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = db.SetMetadata(ctx, map[string]string{
+		"json": `{"vector_layers":[{"id":"water"},{"id":"roads"}]}`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db.Close()
+
+	p, err := archive.NewMBTilesProvider(dict.Dict{archive.ConfigKeyFilePath: path}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer archive.Cleanup()
+
+	data, err := p.MVTForLayers(ctx, provider.NewTile(1, 0, 0, 64, tegola.WebMercator), nil, []provider.Layer{
+		{Name: "roads", MVTName: "streets"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, expected := layerNames(t, data), []string{"streets"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v got %v", expected, got)
+	}
+}
@@ -0,0 +1,138 @@
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var errInvalidBuffer = errors.New("flatgeobuf: invalid flatbuffer")
+
+// table is a flatbuffers table at pos in buf. The accessors panic on
+// invalid buffers, the panics are recovered by the decoding functions.
+type table struct {
+	buf []byte
+	pos int
+}
+
+// rootTable returns the root table of buf
+func rootTable(buf []byte) table {
+	return table{buf: buf, pos: int(binary.LittleEndian.Uint32(buf))}
+}
+
+// field returns the position of the field in the table, 0 when the field is
+// not set
+func (t table) field(id int) int {
+	le := binary.LittleEndian
+	vtable := t.pos - int(int32(le.Uint32(t.buf[t.pos:])))
+	vtableLen := int(le.Uint16(t.buf[vtable:]))
+	slot := 4 + id*2
+	if slot+2 > vtableLen {
+		return 0
+	}
+	if off := int(le.Uint16(t.buf[vtable+slot:])); off != 0 {
+		return t.pos + off
+	}
+	return 0
+}
+
+func (t table) uint8(id int, def uint8) uint8 {
+	if pos := t.field(id); pos != 0 {
+		return t.buf[pos]
+	}
+	return def
+}
+
+func (t table) bool(id int) bool { return t.uint8(id, 0) != 0 }
+
+func (t table) uint16(id int, def uint16) uint16 {
+	if pos := t.field(id); pos != 0 {
+		return binary.LittleEndian.Uint16(t.buf[pos:])
+	}
+	return def
+}
+
+func (t table) int32(id int, def int32) int32 {
+	if pos := t.field(id); pos != 0 {
+		return int32(binary.LittleEndian.Uint32(t.buf[pos:]))
+	}
+	return def
+}
+
+func (t table) uint64(id int, def uint64) uint64 {
+	if pos := t.field(id); pos != 0 {
+		return binary.LittleEndian.Uint64(t.buf[pos:])
+	}
+	return def
+}
+
+// indirect returns the position an offset field points to, 0 when it's not
+// set
+func (t table) indirect(id int) int {
+	pos := t.field(id)
+	if pos == 0 {
+		return 0
+	}
+	return pos + int(binary.LittleEndian.Uint32(t.buf[pos:]))
+}
+
+func (t table) table(id int) (table, bool) {
+	pos := t.indirect(id)
+	return table{buf: t.buf, pos: pos}, pos != 0
+}
+
+func (t table) string(id int) string {
+	return string(t.bytes(id))
+}
+
+// vector returns the position of the elements of a vector of elements of
+// size bytes and its length
+func (t table) vector(id, size int) (start, n int) {
+	pos := t.indirect(id)
+	if pos == 0 {
+		return 0, 0
+	}
+	n = int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	if n*size > len(t.buf)-pos-4 {
+		panic(errInvalidBuffer)
+	}
+	return pos + 4, n
+}
+
+// bytes returns a ubyte vector or a string
+func (t table) bytes(id int) []byte {
+	start, n := t.vector(id, 1)
+	if n == 0 {
+		return nil
+	}
+	return t.buf[start : start+n : start+n]
+}
+
+func (t table) uint32s(id int) []uint32 {
+	start, n := t.vector(id, 4)
+	v := make([]uint32, n)
+	for i := range v {
+		v[i] = binary.LittleEndian.Uint32(t.buf[start+i*4:])
+	}
+	return v
+}
+
+func (t table) float64s(id int) []float64 {
+	start, n := t.vector(id, 8)
+	v := make([]float64, n)
+	for i := range v {
+		v[i] = math.Float64frombits(binary.LittleEndian.Uint64(t.buf[start+i*8:]))
+	}
+	return v
+}
+
+// tables returns a vector of tables
+func (t table) tables(id int) []table {
+	start, n := t.vector(id, 4)
+	v := make([]table, n)
+	for i := range v {
+		pos := start + i*4
+		v[i] = table{buf: t.buf, pos: pos + int(binary.LittleEndian.Uint32(t.buf[pos:]))}
+	}
+	return v
+}
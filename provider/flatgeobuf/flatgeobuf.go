@@ -0,0 +1,22 @@
+// Package flatgeobuf provides a standard provider reading the features of
+// FlatGeobuf files. The features in a tile are found with the packed Hilbert
+// R-tree of the file when it has one.
+package flatgeobuf
+
+import "github.com/go-spatial/tegola/provider/internal/fileprovider"
+
+const Name = "flatgeobuf"
+
+// NewTileProvider instantiates a provider reading the layers from FlatGeobuf
+// files. The config expects the following params:
+//
+//	filepath (string): [Required] the path to a .fgb file, or to a directory of FlatGeobuf files
+//	layers ([]map[string]interface{}): [Required] the layers of the provider, with the params:
+//		name (string): [Required] the name of the layer
+//		filename (string): [Optional] the file of the layer in the directory, defaults to the name of the layer followed by .fgb
+//		fields ([]string): [Optional] the columns to include as feature tags, defaults to all the columns but id_fieldname
+//		id_fieldname (string): [Optional] the column holding the ids of the features, defaults to the position of the features in the file
+//		srid (int): [Optional] the SRID of the geometries, when the file has no EPSG code
+//
+// Cleanup closes the files opened by the providers.
+var NewTileProvider, Cleanup = fileprovider.Register(fileprovider.Format{Name: Name, Ext: ".fgb", Open: Open})
@@ -0,0 +1,171 @@
+package flatgeobuf_test
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/flatgeobuf"
+)
+
+type feature struct {
+	ID       uint64
+	Geometry geom.Geometry
+	Tags     map[string]interface{}
+}
+
+func TestTileFeatures(t *testing.T) {
+	type tcase struct {
+		layer    dict.Dict
+		tile     provider.Tile
+		expected []feature
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			p, err := flatgeobuf.NewTileProvider(dict.Dict{
+				"filepath": "testdata",
+				"layers":   []map[string]interface{}{tc.layer},
+			}, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer flatgeobuf.Cleanup()
+
+			var features []feature
+			err = p.TileFeatures(context.Background(), tc.layer["name"].(string), tc.tile, nil, func(f *provider.Feature) error {
+				if f.SRID != tegola.WGS84 {
+					t.Errorf("srid, expected %v got %v", tegola.WGS84, f.SRID)
+				}
+				features = append(features, feature{ID: f.ID, Geometry: f.Geometry, Tags: f.Tags})
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(features, tc.expected) {
+				t.Errorf("features, expected %v got %v", tc.expected, features)
+			}
+		}
+	}
+
+	lome := feature{ID: 1, Geometry: geom.Point{10, 10}, Tags: map[string]interface{}{
+		"id": int64(100), "name": "Lomé", "pop": uint64(837437), "ratio": 0.5, "capital": true,
+	}}
+	kansas := feature{ID: 2, Geometry: geom.Point{-100, 40}, Tags: map[string]interface{}{
+		"id": int64(200), "name": "Kansas", "capital": false,
+	}}
+	perth := feature{ID: 3, Geometry: geom.Point{120, -30}, Tags: map[string]interface{}{
+		"id": int64(300), "name": "Perth", "ratio": 1.25,
+	}}
+	ulaanbaatar := feature{ID: 5, Geometry: geom.Point{100, 50}, Tags: map[string]interface{}{
+		"id": int64(500), "name": "Ulaanbaatar",
+	}}
+	polygon := feature{ID: 1, Geometry: geom.Polygon{
+		{{1, 1}, {20, 1}, {20, 20}, {1, 20}, {1, 1}},
+		{{5, 5}, {5, 15}, {15, 15}, {15, 5}, {5, 5}},
+	}, Tags: map[string]interface{}{}}
+	multiPolygon := feature{ID: 3, Geometry: geom.MultiPolygon{
+		{{{-50, 10}, {-40, 10}, {-40, 20}, {-50, 10}}},
+		{{{-30, 10}, {-20, 10}, {-20, 20}, {-30, 10}}},
+	}, Tags: map[string]interface{}{}}
+
+	tests := map[string]tcase{
+		"index all": {
+			layer:    dict.Dict{"name": "places"},
+			tile:     provider.NewTile(0, 0, 0, 64, tegola.WebMercator),
+			expected: []feature{lome, kansas, perth, {ID: 4, Geometry: geom.Point{-60, -30}, Tags: map[string]interface{}{"id": int64(400)}}, ulaanbaatar},
+		},
+		"index north east": {
+			layer:    dict.Dict{"name": "places"},
+			tile:     provider.NewTile(1, 1, 0, 64, tegola.WebMercator),
+			expected: []feature{lome, ulaanbaatar},
+		},
+		"index south east": {
+			layer:    dict.Dict{"name": "places", "filename": "places_node16.fgb"},
+			tile:     provider.NewTile(1, 1, 1, 64, tegola.WebMercator),
+			expected: []feature{perth},
+		},
+		"no index north west": {
+			layer:    dict.Dict{"name": "places", "filename": "places_noindex.fgb"},
+			tile:     provider.NewTile(1, 0, 0, 64, tegola.WebMercator),
+			expected: []feature{kansas},
+		},
+		"fields and id": {
+			layer: dict.Dict{"name": "cities", "filename": "places.fgb", "fields": []string{"name"}, "id_fieldname": "id"},
+			tile:  provider.NewTile(1, 1, 0, 64, tegola.WebMercator),
+			expected: []feature{
+				{ID: 100, Geometry: geom.Point{10, 10}, Tags: map[string]interface{}{"name": "Lomé"}},
+				{ID: 500, Geometry: geom.Point{100, 50}, Tags: map[string]interface{}{"name": "Ulaanbaatar"}},
+			},
+		},
+		"geometry types": {
+			layer: dict.Dict{"name": "shapes"},
+			tile:  provider.NewTile(0, 0, 0, 64, tegola.WebMercator),
+			expected: []feature{
+				polygon,
+				{ID: 2, Geometry: geom.MultiLineString{{{-50, -50}, {-40, -40}}, {{-30, -30}, {-20, -20}}}, Tags: map[string]interface{}{}},
+				multiPolygon,
+				{ID: 4, Geometry: geom.LineString{{30, -10}, {40, -20}}, Tags: map[string]interface{}{}},
+			},
+		},
+		"geometry types north": {
+			layer:    dict.Dict{"name": "shapes"},
+			tile:     provider.NewTile(1, 0, 0, 0, tegola.WebMercator),
+			expected: []feature{multiPolygon},
+		},
+		"geometry types no index": {
+			layer:    dict.Dict{"name": "shapes", "filename": "shapes_noindex.fgb"},
+			tile:     provider.NewTile(2, 2, 1, 0, tegola.WebMercator),
+			expected: []feature{polygon},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestLayers(t *testing.T) {
+	path := filepath.Join("testdata", "places_3857.fgb")
+
+	p, err := flatgeobuf.NewTileProvider(dict.Dict{
+		"filepath": path,
+		"layers":   []map[string]interface{}{{"name": "places"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer flatgeobuf.Cleanup()
+
+	layers, err := p.Layers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(layers) != 1 {
+		t.Fatalf("layers, expected 1 got %v", len(layers))
+	}
+	if name := layers[0].Name(); name != "places" {
+		t.Errorf("name, expected places got %v", name)
+	}
+	if _, ok := layers[0].GeomType().(geom.Point); !ok {
+		t.Errorf("geom type, expected geom.Point got %T", layers[0].GeomType())
+	}
+	if srid := layers[0].SRID(); srid != tegola.WebMercator {
+		t.Errorf("srid, expected %v got %v", tegola.WebMercator, srid)
+	}
+
+	// binary columns can't be tags
+	_, err = flatgeobuf.NewTileProvider(dict.Dict{
+		"filepath": path,
+		"layers":   []map[string]interface{}{{"name": "places", "fields": []string{"blob"}}},
+	}, nil)
+	if err == nil {
+		t.Errorf("expected an error for the binary column")
+	}
+}
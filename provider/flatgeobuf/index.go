@@ -0,0 +1,130 @@
+package flatgeobuf
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola/provider/internal/fileprovider"
+)
+
+// nodeByteSize is the size of the nodes of the index: their bounding box
+// and their offset
+const nodeByteSize = 40
+
+// packedRTree is the packed Hilbert R-tree of a FlatGeobuf file, read from
+// the file while searching. The nodes are stored by level from the root to
+// the leaves, which are the features in the order of the file. The offset of
+// the other nodes is the index of their first child.
+type packedRTree struct {
+	r        io.ReaderAt
+	offset   int64
+	numItems uint64
+	nodeSize uint64
+	numNodes uint64
+	// levelBounds are the indexes of the first and after the last node of
+	// the levels, from the leaves to the root
+	levelBounds [][2]uint64
+}
+
+func newPackedRTree(r io.ReaderAt, offset int64, numItems, nodeSize uint64) *packedRTree {
+	t := packedRTree{r: r, offset: offset, numItems: numItems, nodeSize: nodeSize}
+
+	// the number of nodes of the levels, from the leaves to the root
+	n := numItems
+	levelNumNodes := []uint64{n}
+	t.numNodes = n
+	for n != 1 {
+		n = (n + nodeSize - 1) / nodeSize
+		levelNumNodes = append(levelNumNodes, n)
+		t.numNodes += n
+	}
+
+	end := t.numNodes
+	for _, size := range levelNumNodes {
+		t.levelBounds = append(t.levelBounds, [2]uint64{end - size, end})
+		end -= size
+	}
+	return &t
+}
+
+// size returns the size of the index in the file
+func (t *packedRTree) size() int64 { return int64(t.numNodes) * nodeByteSize }
+
+// item is a feature found in the index
+type item struct {
+	// offset of the feature in the features section
+	offset uint64
+	// index of the feature in the file
+	index uint64
+}
+
+// search returns the features with a bounding box intersecting extent, in
+// the order of the file
+func (t *packedRTree) search(ctx context.Context, extent *geom.Extent) ([]item, error) {
+	type entry struct {
+		node  uint64
+		level int
+	}
+
+	var (
+		items []item
+		buf   []byte
+		leafs = t.numNodes - t.numItems
+		queue = []entry{{node: 0, level: len(t.levelBounds) - 1}}
+	)
+	for len(queue) > 0 {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		e := queue[0]
+		queue = queue[1:]
+		if e.level < 0 {
+			return nil, fmt.Errorf("flatgeobuf: invalid index")
+		}
+
+		// the children of a node are consecutive and in a single level
+		end := e.node + t.nodeSize
+		if levelEnd := t.levelBounds[e.level][1]; end > levelEnd {
+			end = levelEnd
+		}
+		if e.node >= end {
+			return nil, fmt.Errorf("flatgeobuf: invalid index")
+		}
+
+		n := int(end-e.node) * nodeByteSize
+		if cap(buf) < n {
+			buf = make([]byte, n)
+		}
+		buf = buf[:n]
+		if _, err := t.r.ReadAt(buf, t.offset+int64(e.node)*nodeByteSize); err != nil {
+			return nil, fmt.Errorf("flatgeobuf: reading index: %w", err)
+		}
+
+		isLeaf := e.node >= leafs
+		for i := 0; i < n; i += nodeByteSize {
+			minx := math.Float64frombits(binary.LittleEndian.Uint64(buf[i:]))
+			miny := math.Float64frombits(binary.LittleEndian.Uint64(buf[i+8:]))
+			maxx := math.Float64frombits(binary.LittleEndian.Uint64(buf[i+16:]))
+			maxy := math.Float64frombits(binary.LittleEndian.Uint64(buf[i+24:]))
+			if !fileprovider.Intersects(extent, minx, miny, maxx, maxy) {
+				continue
+			}
+
+			offset := binary.LittleEndian.Uint64(buf[i+32:])
+			if isLeaf {
+				items = append(items, item{offset: offset, index: e.node + uint64(i/nodeByteSize) - leafs})
+			} else {
+				queue = append(queue, entry{node: offset, level: e.level - 1})
+			}
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].offset < items[j].offset })
+	return items, nil
+}
@@ -0,0 +1,535 @@
+package flatgeobuf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider/internal/fileprovider"
+)
+
+// magic are the first bytes of the files, the 4th one is the major version
+var magic = []byte{'f', 'g', 'b', 3, 'f', 'g', 'b'}
+
+// geometry types
+const (
+	geometryUnknown            = 0
+	geometryPoint              = 1
+	geometryLineString         = 2
+	geometryPolygon            = 3
+	geometryMultiPoint         = 4
+	geometryMultiLineString    = 5
+	geometryMultiPolygon       = 6
+	geometryGeometryCollection = 7
+)
+
+// column types
+const (
+	columnByte     = 0
+	columnUByte    = 1
+	columnBool     = 2
+	columnShort    = 3
+	columnUShort   = 4
+	columnInt      = 5
+	columnUInt     = 6
+	columnLong     = 7
+	columnULong    = 8
+	columnFloat    = 9
+	columnDouble   = 10
+	columnString   = 11
+	columnJSON     = 12
+	columnDateTime = 13
+	columnBinary   = 14
+)
+
+// fields of the tables
+const (
+	headerGeometryType  = 2
+	headerColumns       = 7
+	headerFeaturesCount = 8
+	headerIndexNodeSize = 9
+	headerCrs           = 10
+
+	columnName = 0
+	columnType = 1
+
+	crsOrg  = 0
+	crsCode = 1
+
+	geometryEnds  = 0
+	geometryXY    = 1
+	geometryType  = 6
+	geometryParts = 7
+
+	featureGeometry   = 0
+	featureProperties = 1
+	featureColumns    = 2
+)
+
+// maxFeatureSize bounds the size of the features read from a file
+const maxFeatureSize = 1 << 30
+
+var errInvalidFeature = errors.New("flatgeobuf: invalid feature")
+
+type column struct {
+	name string
+	typ  uint8
+}
+
+// source reads the features of a FlatGeobuf file. The features in a tile are
+// found with the packed Hilbert R-tree of the file when it has one, the
+// features of the files without an index are all read.
+type source struct {
+	f             *os.File
+	geometryType  uint8
+	columns       []column
+	featuresCount uint64
+	srid          uint64
+
+	// the index and the features sections of the file
+	index          *packedRTree
+	featuresOffset int64
+}
+
+// Open opens the FlatGeobuf file at path
+func Open(path string) (fileprovider.Source, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := source{f: f}
+	if err = s.readHeader(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if s.index == nil && s.featuresCount > 0 {
+		log.Warnf("flatgeobuf: %v has no spatial index, its features are all read for each tile", path)
+	}
+	return &s, nil
+}
+
+func (s *source) readHeader() (err error) {
+	var prefix [12]byte
+	if _, err = io.ReadFull(s.f, prefix[:]); err != nil {
+		return fmt.Errorf("flatgeobuf: reading header: %w", err)
+	}
+	if !bytes.Equal(prefix[:len(magic)], magic) {
+		return errors.New("flatgeobuf: not a FlatGeobuf file, or an unsupported version")
+	}
+
+	size := binary.LittleEndian.Uint32(prefix[8:])
+	if size < 4 || size > maxFeatureSize {
+		return errors.New("flatgeobuf: invalid header size")
+	}
+	buf := make([]byte, size)
+	if _, err = io.ReadFull(s.f, buf); err != nil {
+		return fmt.Errorf("flatgeobuf: reading header: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("flatgeobuf: invalid header")
+		}
+	}()
+
+	header := rootTable(buf)
+	s.geometryType = header.uint8(headerGeometryType, geometryUnknown)
+	s.columns = readColumns(header.tables(headerColumns))
+	s.featuresCount = header.uint64(headerFeaturesCount, 0)
+
+	if crs, ok := header.table(headerCrs); ok {
+		// the organization defaults to EPSG
+		org := crs.string(crsOrg)
+		if code := crs.int32(crsCode, 0); code > 0 && (org == "" || strings.EqualFold(org, "EPSG")) {
+			s.srid = uint64(code)
+		}
+	}
+
+	indexOffset := int64(len(prefix)) + int64(size)
+	s.featuresOffset = indexOffset
+
+	nodeSize := header.uint16(headerIndexNodeSize, 16)
+	if nodeSize > 0 && s.featuresCount > 0 {
+		if nodeSize < 2 {
+			return errors.New("flatgeobuf: invalid index node size")
+		}
+		s.index = newPackedRTree(s.f, indexOffset, s.featuresCount, uint64(nodeSize))
+		s.featuresOffset += s.index.size()
+	}
+	return nil
+}
+
+func readColumns(tables []table) []column {
+	columns := make([]column, len(tables))
+	for i, t := range tables {
+		columns[i] = column{name: t.string(columnName), typ: t.uint8(columnType, 0)}
+	}
+	return columns
+}
+
+func (s *source) Fields() []string {
+	names := make([]string, 0, len(s.columns))
+	for _, c := range s.columns {
+		if c.typ != columnBinary {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
+
+func (s *source) GeomType() geom.Geometry {
+	switch s.geometryType {
+	case geometryPoint:
+		return geom.Point{}
+	case geometryLineString:
+		return geom.LineString{}
+	case geometryPolygon:
+		return geom.Polygon{}
+	case geometryMultiPoint:
+		return geom.MultiPoint{}
+	case geometryMultiLineString:
+		return geom.MultiLineString{}
+	case geometryMultiPolygon:
+		return geom.MultiPolygon{}
+	case geometryGeometryCollection:
+		return geom.Collection{}
+	default:
+		return nil
+	}
+}
+
+func (s *source) SRID() uint64 { return s.srid }
+
+func (s *source) Features(ctx context.Context, extent *geom.Extent, fields []string, fn func(fileprovider.Feature) error) error {
+	if s.featuresCount == 0 {
+		return nil
+	}
+	if s.index == nil {
+		return s.scan(ctx, extent, fields, fn)
+	}
+
+	items, err := s.index.search(ctx, extent)
+	if err != nil {
+		return err
+	}
+
+	var buf []byte
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var size [4]byte
+		if _, err := s.f.ReadAt(size[:], s.featuresOffset+int64(item.offset)); err != nil {
+			return fmt.Errorf("flatgeobuf: reading feature %d: %w", item.index+1, err)
+		}
+		n := binary.LittleEndian.Uint32(size[:])
+		if n > maxFeatureSize {
+			return errInvalidFeature
+		}
+		if cap(buf) < int(n) {
+			buf = make([]byte, n)
+		}
+		buf = buf[:n]
+		if _, err := s.f.ReadAt(buf, s.featuresOffset+int64(item.offset)+4); err != nil {
+			return fmt.Errorf("flatgeobuf: reading feature %d: %w", item.index+1, err)
+		}
+
+		g, props, err := s.decodeFeature(buf, nil, fields)
+		if err != nil {
+			return fmt.Errorf("flatgeobuf: feature %d: %w", item.index+1, err)
+		}
+		if g == nil {
+			continue
+		}
+		if err = fn(fileprovider.Feature{Number: item.index + 1, Geometry: g, Properties: props}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scan reads all the features, for the files without an index
+func (s *source) scan(ctx context.Context, extent *geom.Extent, fields []string, fn func(fileprovider.Feature) error) error {
+	r := bufio.NewReader(io.NewSectionReader(s.f, s.featuresOffset, math.MaxInt64-s.featuresOffset))
+
+	var buf []byte
+	for i := uint64(0); i < s.featuresCount; i++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return fmt.Errorf("flatgeobuf: reading feature %d: %w", i+1, err)
+		}
+		n := binary.LittleEndian.Uint32(size[:])
+		if n > maxFeatureSize {
+			return errInvalidFeature
+		}
+		if cap(buf) < int(n) {
+			buf = make([]byte, n)
+		}
+		buf = buf[:n]
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return fmt.Errorf("flatgeobuf: reading feature %d: %w", i+1, err)
+		}
+
+		g, props, err := s.decodeFeature(buf, extent, fields)
+		if err != nil {
+			return fmt.Errorf("flatgeobuf: feature %d: %w", i+1, err)
+		}
+		if g == nil {
+			continue
+		}
+		if err = fn(fileprovider.Feature{Number: i + 1, Geometry: g, Properties: props}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *source) Close() error { return s.f.Close() }
+
+// decodeFeature decodes the geometry and the properties fields of a feature.
+// The geometry is nil when the feature has none, or when extent is not nil
+// and does not intersect the geometry.
+func (s *source) decodeFeature(buf []byte, extent *geom.Extent, fields []string) (g geom.Geometry, props map[string]interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			g, props, err = nil, nil, errInvalidFeature
+		}
+	}()
+
+	if len(buf) < 4 {
+		return nil, nil, errInvalidFeature
+	}
+	feature := rootTable(buf)
+	if geometry, ok := feature.table(featureGeometry); ok {
+		if g, err = decodeGeometry(geometry, s.geometryType, 0); err != nil {
+			return nil, nil, err
+		}
+	}
+	if g == nil {
+		return nil, nil, nil
+	}
+	if extent != nil {
+		ext, err := geom.NewExtentFromGeometry(g)
+		if err != nil || !fileprovider.Intersects(extent, ext.MinX(), ext.MinY(), ext.MaxX(), ext.MaxY()) {
+			return nil, nil, err
+		}
+	}
+
+	if len(fields) == 0 {
+		return g, nil, nil
+	}
+
+	// the features can have their own columns
+	columns := s.columns
+	if _, n := feature.vector(featureColumns, 4); n > 0 {
+		columns = readColumns(feature.tables(featureColumns))
+	}
+
+	wanted := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		wanted[f] = true
+	}
+	props, err = decodeProperties(feature.bytes(featureProperties), columns, wanted)
+	return g, props, err
+}
+
+// decodeProperties decodes the values of the wanted columns
+func decodeProperties(b []byte, columns []column, wanted map[string]bool) (map[string]interface{}, error) {
+	le := binary.LittleEndian
+	props := make(map[string]interface{}, len(wanted))
+	for len(b) > 0 {
+		if len(b) < 2 {
+			return nil, errInvalidFeature
+		}
+		i := int(le.Uint16(b))
+		b = b[2:]
+		if i >= len(columns) {
+			return nil, fmt.Errorf("flatgeobuf: invalid column index (%d)", i)
+		}
+		c := columns[i]
+
+		var (
+			v    interface{}
+			size int
+		)
+		switch c.typ {
+		case columnByte, columnUByte, columnBool:
+			size = 1
+		case columnShort, columnUShort:
+			size = 2
+		case columnInt, columnUInt, columnFloat:
+			size = 4
+		case columnLong, columnULong, columnDouble:
+			size = 8
+		case columnString, columnJSON, columnDateTime, columnBinary:
+			if len(b) < 4 {
+				return nil, errInvalidFeature
+			}
+			size = 4 + int(le.Uint32(b))
+		default:
+			return nil, fmt.Errorf("flatgeobuf: unsupported column type (%d)", c.typ)
+		}
+		if size < 0 || len(b) < size {
+			return nil, errInvalidFeature
+		}
+
+		if wanted[c.name] {
+			switch c.typ {
+			case columnByte:
+				v = int64(int8(b[0]))
+			case columnUByte:
+				v = uint64(b[0])
+			case columnBool:
+				v = b[0] != 0
+			case columnShort:
+				v = int64(int16(le.Uint16(b)))
+			case columnUShort:
+				v = uint64(le.Uint16(b))
+			case columnInt:
+				v = int64(int32(le.Uint32(b)))
+			case columnUInt:
+				v = uint64(le.Uint32(b))
+			case columnLong:
+				v = int64(le.Uint64(b))
+			case columnULong:
+				v = le.Uint64(b)
+			case columnFloat:
+				v = float64(math.Float32frombits(le.Uint32(b)))
+			case columnDouble:
+				v = math.Float64frombits(le.Uint64(b))
+			case columnString, columnJSON, columnDateTime:
+				v = string(b[4:size])
+			}
+			if v != nil {
+				props[c.name] = v
+			}
+		}
+		b = b[size:]
+	}
+	return props, nil
+}
+
+// maxGeometryDepth bounds the nesting of the parts of the geometries
+const maxGeometryDepth = 8
+
+// decodeGeometry decodes a geometry of the type typ, or of its own type when
+// typ is unknown. Empty geometries are returned as nil.
+func decodeGeometry(t table, typ uint8, depth int) (geom.Geometry, error) {
+	if typ == geometryUnknown {
+		typ = t.uint8(geometryType, geometryUnknown)
+	}
+
+	xy := t.float64s(geometryXY)
+	points := make([][2]float64, len(xy)/2)
+	for i := range points {
+		points[i] = [2]float64{xy[i*2], xy[i*2+1]}
+	}
+
+	switch typ {
+	case geometryPoint:
+		if len(points) == 0 {
+			return nil, nil
+		}
+		return geom.Point(points[0]), nil
+
+	case geometryMultiPoint:
+		if len(points) == 0 {
+			return nil, nil
+		}
+		return geom.MultiPoint(points), nil
+
+	case geometryLineString:
+		if len(points) == 0 {
+			return nil, nil
+		}
+		return geom.LineString(points), nil
+
+	case geometryMultiLineString, geometryPolygon:
+		parts, err := splitParts(points, t.uint32s(geometryEnds))
+		if err != nil || len(parts) == 0 {
+			return nil, err
+		}
+		if typ == geometryPolygon {
+			return geom.Polygon(parts), nil
+		}
+		return geom.MultiLineString(parts), nil
+
+	case geometryMultiPolygon, geometryGeometryCollection:
+		if depth >= maxGeometryDepth {
+			return nil, errInvalidFeature
+		}
+
+		var (
+			polygons   geom.MultiPolygon
+			collection geom.Collection
+		)
+		for _, part := range t.tables(geometryParts) {
+			partType := uint8(geometryPolygon)
+			if typ == geometryGeometryCollection {
+				partType = geometryUnknown
+			}
+			g, err := decodeGeometry(part, partType, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if g == nil {
+				continue
+			}
+			if typ == geometryMultiPolygon {
+				polygons = append(polygons, g.(geom.Polygon))
+			} else {
+				collection = append(collection, g)
+			}
+		}
+		if typ == geometryMultiPolygon {
+			if len(polygons) == 0 {
+				return nil, nil
+			}
+			return polygons, nil
+		}
+		if len(collection) == 0 {
+			return nil, nil
+		}
+		return collection, nil
+
+	default:
+		return nil, fmt.Errorf("flatgeobuf: unsupported geometry type (%d)", typ)
+	}
+}
+
+// splitParts splits the points at the ends, the indexes of the points after
+// the parts. There is a single part when there are no ends.
+func splitParts(points [][2]float64, ends []uint32) ([][][2]float64, error) {
+	if len(points) == 0 {
+		return nil, nil
+	}
+	if len(ends) == 0 {
+		return [][][2]float64{points}, nil
+	}
+
+	parts := make([][][2]float64, 0, len(ends))
+	start := uint32(0)
+	for _, end := range ends {
+		if end < start || end > uint32(len(points)) {
+			return nil, errInvalidFeature
+		}
+		parts = append(parts, points[start:end:end])
+		start = end
+	}
+	return parts, nil
+}
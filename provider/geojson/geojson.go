@@ -0,0 +1,71 @@
+// Package geojson provides a standard provider serving the features of
+// GeoJSON files from memory. The features are loaded when the provider is
+// instantiated, with an R-tree of their bounding boxes for each file.
+package geojson
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/internal/fileprovider"
+)
+
+const Name = "geojson"
+
+const ConfigKeyReloadInterval = "reload_interval"
+
+func init() {
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, Cleanup)
+}
+
+// providers are the providers that have been instantiated. they are closed by Cleanup
+var (
+	providersLock sync.Mutex
+	providers     []*fileprovider.Provider
+)
+
+// NewTileProvider instantiates a provider loading the layers from GeoJSON
+// files. The geometries must be in WGS84, as required by RFC 7946. The
+// config expects the following params:
+//
+//	filepath (string): [Required] the path to a GeoJSON file, or to a directory of GeoJSON files
+//	reload_interval (int): [Optional] the number of seconds between the checks for changes of the files, the files are not reloaded when it's 0. defaults to 0
+//	layers ([]map[string]interface{}): [Required] the layers of the provider, with the params:
+//		name (string): [Required] the name of the layer
+//		filename (string): [Optional] the file of the layer in the directory, defaults to the name of the layer followed by .geojson
+//		fields ([]string): [Optional] the properties to include as feature tags, defaults to all the properties but id_fieldname
+//		id_fieldname (string): [Optional] the property holding the ids of the features, defaults to the integer ids of the features or their position in the file
+func NewTileProvider(config dict.Dicter, _ []provider.Map) (provider.Tiler, error) {
+	defaultReloadInterval := 0
+	interval, err := config.Int(ConfigKeyReloadInterval, &defaultReloadInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	openFile := func(path string) (fileprovider.Source, error) {
+		return open(path, time.Duration(interval)*time.Second)
+	}
+	p, err := fileprovider.New(fileprovider.Format{Name: Name, Ext: ".geojson", Open: openFile}, config)
+	if err != nil {
+		return nil, err
+	}
+
+	providersLock.Lock()
+	providers = append(providers, p)
+	providersLock.Unlock()
+
+	return p, nil
+}
+
+// Cleanup stops watching the files of the providers
+func Cleanup() {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+
+	for _, p := range providers {
+		p.Close()
+	}
+	providers = nil
+}
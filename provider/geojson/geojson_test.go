@@ -0,0 +1,293 @@
+package geojson_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/basic"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/geojson"
+)
+
+const places = `{
+	"type": "FeatureCollection",
+	"features": [
+		{"type": "Feature", "id": 100, "geometry": {"type": "Point", "coordinates": [10, 10]}, "properties": {"name": "Lomé", "pop": 837437, "ratio": 0.5, "capital": true}},
+		{"type": "Feature", "id": 200, "geometry": {"type": "Point", "coordinates": [-100, 40]}, "properties": {"name": "Kansas", "capital": false, "tags": {"state": true}}},
+		{"type": "Feature", "id": "perth", "geometry": {"type": "Point", "coordinates": [120, -30, 15]}, "properties": {"name": "Perth", "ratio": 1.25, "code": null}},
+		{"type": "Feature", "id": 400, "geometry": null, "properties": {"name": "Nowhere"}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [100, 50]}, "properties": {"name": "Ulaanbaatar", "ids": [1, 2]}}
+	]
+}`
+
+// newline-delimited, with a GeoJSON text sequence separator
+const shapes = `{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [[[1, 1], [20, 1], [20, 20], [1, 20], [1, 1]], [[5, 5], [5, 15], [15, 15], [15, 5], [5, 5]]]}, "properties": {"kind": "a", "fid": 7}}
+{"type": "Feature", "geometry": {"type": "MultiLineString", "coordinates": [[[-50, -50], [-40, -40]], [[-30, -30], [-20, -20]]]}, "properties": {"kind": "b", "fid": 8}}
+` + "\x1e" + `{"type": "Feature", "geometry": {"type": "GeometryCollection", "geometries": [{"type": "Point", "coordinates": [-45, 15]}, {"type": "LineString", "coordinates": [[-50, 10], [-40, 20]]}]}, "properties": {"kind": "c", "fid": 9}}
+`
+
+// mercator converts the geometry to web mercator
+func mercator(t *testing.T, g geom.Geometry) geom.Geometry {
+	if coll, ok := g.(geom.Collection); ok {
+		converted := make(geom.Collection, len(coll))
+		for i := range coll {
+			converted[i] = mercator(t, coll[i])
+		}
+		return converted
+	}
+	g, err := basic.ToWebMercator(tegola.WGS84, g)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return g
+}
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+type feature struct {
+	ID       uint64
+	Geometry geom.Geometry
+	Tags     map[string]interface{}
+}
+
+// tileFeatures returns the features of the layer in the tile
+func tileFeatures(t *testing.T, p provider.Tiler, layer string, tile provider.Tile) []feature {
+	var features []feature
+	err := p.TileFeatures(context.Background(), layer, tile, nil, func(f *provider.Feature) error {
+		if f.SRID != tegola.WebMercator {
+			t.Errorf("srid, expected %v got %v", tegola.WebMercator, f.SRID)
+		}
+		features = append(features, feature{ID: f.ID, Geometry: f.Geometry, Tags: f.Tags})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return features
+}
+
+func TestTileFeatures(t *testing.T) {
+	type tcase struct {
+		layer    dict.Dict
+		tile     provider.Tile
+		expected []feature
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "places.geojson"), places)
+			writeFile(t, filepath.Join(dir, "shapes.geojsonl"), shapes)
+
+			p, err := geojson.NewTileProvider(dict.Dict{
+				"filepath": dir,
+				"layers":   []map[string]interface{}{tc.layer},
+			}, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer geojson.Cleanup()
+
+			features := tileFeatures(t, p, tc.layer["name"].(string), tc.tile)
+			if !reflect.DeepEqual(features, tc.expected) {
+				t.Errorf("features, expected %v got %v", tc.expected, features)
+			}
+		}
+	}
+
+	lome := feature{ID: 100, Geometry: mercator(t, geom.Point{10, 10}), Tags: map[string]interface{}{
+		"name": "Lomé", "pop": int64(837437), "ratio": 0.5, "capital": true,
+	}}
+	kansas := feature{ID: 200, Geometry: mercator(t, geom.Point{-100, 40}), Tags: map[string]interface{}{
+		"name": "Kansas", "capital": false, "tags": `{"state":true}`,
+	}}
+	perth := feature{ID: 3, Geometry: mercator(t, geom.Point{120, -30}), Tags: map[string]interface{}{
+		"name": "Perth", "ratio": 1.25,
+	}}
+	ulaanbaatar := feature{ID: 5, Geometry: mercator(t, geom.Point{100, 50}), Tags: map[string]interface{}{
+		"name": "Ulaanbaatar", "ids": "[1,2]",
+	}}
+	polygon := feature{ID: 7, Geometry: mercator(t, geom.Polygon{
+		{{1, 1}, {20, 1}, {20, 20}, {1, 20}, {1, 1}},
+		{{5, 5}, {5, 15}, {15, 15}, {15, 5}, {5, 5}},
+	}), Tags: map[string]interface{}{"kind": "a"}}
+	collection := feature{ID: 9, Geometry: mercator(t, geom.Collection{
+		geom.Point{-45, 15},
+		geom.LineString{{-50, 10}, {-40, 20}},
+	}), Tags: map[string]interface{}{"kind": "c"}}
+
+	tests := map[string]tcase{
+		"all": {
+			layer:    dict.Dict{"name": "places"},
+			tile:     provider.NewTile(0, 0, 0, 64, tegola.WebMercator),
+			expected: []feature{lome, kansas, perth, ulaanbaatar},
+		},
+		"north east": {
+			layer:    dict.Dict{"name": "places"},
+			tile:     provider.NewTile(1, 1, 0, 64, tegola.WebMercator),
+			expected: []feature{lome, ulaanbaatar},
+		},
+		"south east wgs84 tile": {
+			layer:    dict.Dict{"name": "places"},
+			tile:     provider.NewTile(1, 1, 1, 64, tegola.WGS84),
+			expected: []feature{perth},
+		},
+		"fields": {
+			layer: dict.Dict{"name": "cities", "filename": "places.geojson", "fields": []string{"name"}},
+			tile:  provider.NewTile(1, 1, 0, 64, tegola.WebMercator),
+			expected: []feature{
+				{ID: 100, Geometry: lome.Geometry, Tags: map[string]interface{}{"name": "Lomé"}},
+				{ID: 5, Geometry: ulaanbaatar.Geometry, Tags: map[string]interface{}{"name": "Ulaanbaatar"}},
+			},
+		},
+		"newline-delimited": {
+			layer: dict.Dict{"name": "shapes", "filename": "shapes.geojsonl", "id_fieldname": "fid"},
+			tile:  provider.NewTile(0, 0, 0, 64, tegola.WebMercator),
+			expected: []feature{
+				polygon,
+				{ID: 8, Geometry: mercator(t, geom.MultiLineString{{{-50, -50}, {-40, -40}}, {{-30, -30}, {-20, -20}}}), Tags: map[string]interface{}{"kind": "b"}},
+				collection,
+			},
+		},
+		"newline-delimited north west": {
+			layer:    dict.Dict{"name": "shapes", "filename": "shapes.geojsonl", "id_fieldname": "fid"},
+			tile:     provider.NewTile(1, 0, 0, 0, tegola.WebMercator),
+			expected: []feature{collection},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestNewTileProvider(t *testing.T) {
+	type tcase struct {
+		content string
+		layer   dict.Dict
+		err     bool
+		// geomType is the expected geometry type of the layer
+		geomType geom.Geometry
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.geojson")
+			writeFile(t, path, tc.content)
+
+			p, err := geojson.NewTileProvider(dict.Dict{
+				"filepath": path,
+				"layers":   []map[string]interface{}{tc.layer},
+			}, nil)
+			defer geojson.Cleanup()
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			layers, err := p.Layers()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(layers) != 1 {
+				t.Fatalf("layers, expected 1 got %v", len(layers))
+			}
+			if !reflect.DeepEqual(layers[0].GeomType(), tc.geomType) {
+				t.Errorf("geom type, expected %T got %T", tc.geomType, layers[0].GeomType())
+			}
+			if srid := layers[0].SRID(); srid != tegola.WebMercator {
+				t.Errorf("srid, expected %v got %v", tegola.WebMercator, srid)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"points": {
+			content:  places,
+			layer:    dict.Dict{"name": "places"},
+			geomType: geom.Point{},
+		},
+		"mixed": {
+			content: shapes,
+			layer:   dict.Dict{"name": "shapes"},
+		},
+		"empty": {
+			content: `{"type": "FeatureCollection", "features": []}`,
+			layer:   dict.Dict{"name": "empty"},
+		},
+		"unknown field": {
+			content: places,
+			layer:   dict.Dict{"name": "places", "fields": []string{"population"}},
+			err:     true,
+		},
+		"invalid json": {
+			content: `{"type": "FeatureCollection", "features": [`,
+			layer:   dict.Dict{"name": "places"},
+			err:     true,
+		},
+		"geometry": {
+			content: `{"type": "Point", "coordinates": [10, 10]}`,
+			layer:   dict.Dict{"name": "places"},
+			err:     true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "places.geojson")
+	writeFile(t, path, places)
+
+	p, err := geojson.NewTileProvider(dict.Dict{
+		"filepath":        path,
+		"reload_interval": 1,
+		"layers":          []map[string]interface{}{{"name": "places"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer geojson.Cleanup()
+
+	tile := provider.NewTile(0, 0, 0, 64, tegola.WebMercator)
+	if features := tileFeatures(t, p, "places", tile); len(features) != 4 {
+		t.Fatalf("features, expected 4 got %v", len(features))
+	}
+
+	// an invalid file keeps the features
+	writeFile(t, path, `{"type": "FeatureCollection", "features": [`)
+	time.Sleep(1500 * time.Millisecond)
+	if features := tileFeatures(t, p, "places", tile); len(features) != 4 {
+		t.Fatalf("features, expected 4 got %v", len(features))
+	}
+
+	writeFile(t, path, `{"type": "Feature", "geometry": {"type": "Point", "coordinates": [1, 2]}, "properties": {"name": "Null Island"}}`)
+	expected := []feature{{ID: 1, Geometry: mercator(t, geom.Point{1, 2}), Tags: map[string]interface{}{"name": "Null Island"}}}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(100 * time.Millisecond) {
+		features := tileFeatures(t, p, "places", tile)
+		if reflect.DeepEqual(features, expected) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("features, expected %v got %v", expected, features)
+		}
+	}
+}
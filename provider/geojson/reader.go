@@ -0,0 +1,331 @@
+package geojson
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/geojson"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/basic"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider/internal/fileprovider"
+)
+
+// object is a GeoJSON object: a feature collection or a feature
+type object struct {
+	Type       string                 `json:"type"`
+	Features   []object               `json:"features"`
+	ID         interface{}            `json:"id"`
+	Geometry   json.RawMessage        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// content is the content of a file, it's not modified once it's loaded
+type content struct {
+	features []fileprovider.Feature
+	index    *rtree
+	fields   []string
+	geomType geom.Geometry
+}
+
+// source holds the features of a GeoJSON file in memory, with their
+// geometries in web mercator. The file is loaded again when it changes if
+// the source watches it.
+type source struct {
+	path string
+
+	mu      sync.RWMutex
+	content *content
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// open loads the GeoJSON file at path. It's checked for changes every
+// interval, when interval is not 0.
+func open(path string, interval time.Duration) (*source, error) {
+	c, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := source{path: path, content: c, done: make(chan struct{})}
+	if interval > 0 {
+		go s.watch(interval)
+	}
+	return &s, nil
+}
+
+// watch loads the file again each time its modification time or size
+// changes, until the source is closed. The features are kept when the file
+// can't be loaded.
+func (s *source) watch(interval time.Duration) {
+	stat := func() (time.Time, int64) {
+		fi, err := os.Stat(s.path)
+		if err != nil {
+			// the file is likely being replaced, check again on the next tick
+			return time.Time{}, -1
+		}
+		return fi.ModTime(), fi.Size()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	modTime, size := stat()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		mt, sz := stat()
+		if sz < 0 || (mt.Equal(modTime) && sz == size) {
+			continue
+		}
+		modTime, size = mt, sz
+
+		c, err := load(s.path)
+		if err != nil {
+			log.Errorf("geojson: could not reload %v, still serving the previous features: %v", s.path, err)
+			continue
+		}
+		s.mu.Lock()
+		s.content = c
+		s.mu.Unlock()
+		log.Infof("geojson: reloaded %v, %d features", s.path, len(c.features))
+	}
+}
+
+func (s *source) current() *content {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.content
+}
+
+func (s *source) Fields() []string { return s.current().fields }
+
+func (s *source) GeomType() geom.Geometry { return s.current().geomType }
+
+// SRID returns web mercator, the geometries are converted when they're loaded
+func (s *source) SRID() uint64 { return tegola.WebMercator }
+
+func (s *source) Features(ctx context.Context, extent *geom.Extent, fields []string, fn func(fileprovider.Feature) error) error {
+	c := s.current()
+	for _, i := range c.index.search(extent) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		f := c.features[i]
+		// the features are shared by the tiles, the geometries are copied
+		// as they can be modified by the callers
+		g, err := mapGeometry(f.Geometry, basic.CloneGeometry)
+		if err != nil {
+			return err
+		}
+		props := make(map[string]interface{}, len(fields))
+		for _, name := range fields {
+			if v, ok := f.Properties[name]; ok {
+				props[name] = v
+			}
+		}
+		if err = fn(fileprovider.Feature{Number: f.Number, Geometry: g, Properties: props}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *source) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return nil
+}
+
+// rsReader replaces the record separators of GeoJSON text sequences with
+// spaces, so they can be read like newline-delimited GeoJSON
+type rsReader struct {
+	r io.Reader
+}
+
+func (r rsReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	for i := range p[:n] {
+		if p[i] == 0x1e {
+			p[i] = ' '
+		}
+	}
+	return n, err
+}
+
+// load reads the features of a file. The file can be a GeoJSON feature
+// collection or feature, or a sequence of them: newline-delimited GeoJSON
+// or a GeoJSON text sequence.
+func load(path string) (*content, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		c      content
+		bboxes [][4]float64
+		fields = make(map[string]bool)
+		number uint64
+		typ    reflect.Type
+		mixed  bool
+	)
+
+	add := func(o object) error {
+		number++
+		if o.Type != "Feature" {
+			return fmt.Errorf("geojson: %v: feature %d: unsupported object type (%v)", path, number, o.Type)
+		}
+
+		feature := fileprovider.Feature{Number: number}
+		// integer ids are the ids of the features
+		if id, ok := o.ID.(json.Number); ok {
+			if n, err := strconv.ParseUint(id.String(), 10, 64); err == nil {
+				feature.Number = n
+			}
+		}
+
+		var err error
+		if feature.Geometry, err = decodeGeometry(o.Geometry); err != nil {
+			return fmt.Errorf("geojson: %v: feature %d: %w", path, number, err)
+		}
+		if feature.Geometry == nil {
+			return nil
+		}
+		ext, err := geom.NewExtentFromGeometry(feature.Geometry)
+		if err != nil {
+			// empty geometries
+			return nil
+		}
+
+		feature.Properties = make(map[string]interface{}, len(o.Properties))
+		for k, v := range o.Properties {
+			fields[k] = true
+			if v, err = propertyValue(v); err != nil {
+				return fmt.Errorf("geojson: %v: feature %d: property %v: %w", path, number, k, err)
+			}
+			if v != nil {
+				feature.Properties[k] = v
+			}
+		}
+
+		switch t := reflect.TypeOf(feature.Geometry); {
+		case typ == nil:
+			typ = t
+		case typ != t:
+			mixed = true
+		}
+
+		c.features = append(c.features, feature)
+		bboxes = append(bboxes, [4]float64{ext.MinX(), ext.MinY(), ext.MaxX(), ext.MaxY()})
+		return nil
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(rsReader{f}))
+	dec.UseNumber()
+	for {
+		var o object
+		if err := dec.Decode(&o); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("geojson: decoding %v: %w", path, err)
+		}
+
+		if o.Type != "FeatureCollection" {
+			if err := add(o); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for _, f := range o.Features {
+			if err := add(f); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	c.index = newRTree(bboxes)
+	for k := range fields {
+		c.fields = append(c.fields, k)
+	}
+	sort.Strings(c.fields)
+	if typ != nil && !mixed {
+		c.geomType = reflect.Zero(typ).Interface().(geom.Geometry)
+	}
+	return &c, nil
+}
+
+// decodeGeometry decodes a GeoJSON geometry and converts it to web mercator,
+// null geometries are nil
+func decodeGeometry(b json.RawMessage) (geom.Geometry, error) {
+	if len(b) == 0 || string(b) == "null" {
+		return nil, nil
+	}
+
+	var g geojson.Geometry
+	if err := json.Unmarshal(b, &g); err != nil {
+		return nil, err
+	}
+	switch g.Geometry.(type) {
+	case geojson.Feature, geojson.FeatureCollection:
+		return nil, errors.New("invalid geometry")
+	}
+
+	return mapGeometry(g.Geometry, func(g geom.Geometry) (geom.Geometry, error) {
+		return basic.ToWebMercator(tegola.WGS84, g)
+	})
+}
+
+// mapGeometry applies fn to a geometry, or to each geometry of a collection
+func mapGeometry(g geom.Geometry, fn func(geom.Geometry) (geom.Geometry, error)) (geom.Geometry, error) {
+	coll, ok := g.(geom.Collection)
+	if !ok {
+		return fn(g)
+	}
+
+	mapped := make(geom.Collection, len(coll))
+	for i := range coll {
+		var err error
+		if mapped[i], err = mapGeometry(coll[i], fn); err != nil {
+			return nil, err
+		}
+	}
+	return mapped, nil
+}
+
+// propertyValue converts the value of a property to the value of a tag. The
+// integers are int64, the other numbers float64, and the objects and arrays
+// are encoded to JSON.
+func propertyValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+		return v.Float64()
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(v)
+		return string(b), err
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,103 @@
+package geojson
+
+import (
+	"math"
+	"sort"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola/provider/internal/fileprovider"
+)
+
+// rtreeNodeSize is the maximum number of children of the nodes of an rtree
+const rtreeNodeSize = 16
+
+type rtreeNode struct {
+	bbox [4]float64
+	// children of the node, nil for the leaves
+	children []rtreeNode
+	// item is the index of the bounding box of a leaf
+	item int
+}
+
+// rtree is a static R-tree of bounding boxes, packed with the Sort-Tile-
+// Recursive algorithm
+type rtree struct {
+	root *rtreeNode
+}
+
+// newRTree builds the R-tree of the bounding boxes, their indexes are the
+// items of the tree
+func newRTree(bboxes [][4]float64) *rtree {
+	if len(bboxes) == 0 {
+		return &rtree{}
+	}
+
+	nodes := make([]rtreeNode, len(bboxes))
+	for i, b := range bboxes {
+		nodes[i] = rtreeNode{bbox: b, item: i}
+	}
+	for len(nodes) > 1 {
+		nodes = packNodes(nodes)
+	}
+	return &rtree{root: &nodes[0]}
+}
+
+// packNodes groups the nodes in parents of up to rtreeNodeSize children. The
+// nodes are sorted in vertical slices by the x of their centers, then by the y
+// of their centers in each slice.
+func packNodes(nodes []rtreeNode) []rtreeNode {
+	center := func(n rtreeNode, axis int) float64 { return n.bbox[axis] + n.bbox[axis+2] }
+
+	parents := (len(nodes) + rtreeNodeSize - 1) / rtreeNodeSize
+	perSlice := int(math.Ceil(math.Sqrt(float64(parents)))) * rtreeNodeSize
+
+	sort.Slice(nodes, func(i, j int) bool { return center(nodes[i], 0) < center(nodes[j], 0) })
+
+	packed := make([]rtreeNode, 0, parents)
+	for i := 0; i < len(nodes); i += perSlice {
+		slice := nodes[i:min(i+perSlice, len(nodes))]
+		sort.Slice(slice, func(i, j int) bool { return center(slice[i], 1) < center(slice[j], 1) })
+
+		for j := 0; j < len(slice); j += rtreeNodeSize {
+			children := slice[j:min(j+rtreeNodeSize, len(slice))]
+			parent := rtreeNode{bbox: children[0].bbox, children: children}
+			for _, c := range children[1:] {
+				parent.bbox = [4]float64{
+					math.Min(parent.bbox[0], c.bbox[0]), math.Min(parent.bbox[1], c.bbox[1]),
+					math.Max(parent.bbox[2], c.bbox[2]), math.Max(parent.bbox[3], c.bbox[3]),
+				}
+			}
+			packed = append(packed, parent)
+		}
+	}
+	return packed
+}
+
+// search returns the sorted items with a bounding box intersecting extent,
+// all the items when extent is nil
+func (t *rtree) search(extent *geom.Extent) []int {
+	if t.root == nil {
+		return nil
+	}
+
+	var (
+		items []int
+		visit func(n *rtreeNode)
+	)
+	visit = func(n *rtreeNode) {
+		if !fileprovider.Intersects(extent, n.bbox[0], n.bbox[1], n.bbox[2], n.bbox[3]) {
+			return
+		}
+		if n.children == nil {
+			items = append(items, n.item)
+			return
+		}
+		for i := range n.children {
+			visit(&n.children[i])
+		}
+	}
+	visit(t.root)
+
+	sort.Ints(items)
+	return items
+}
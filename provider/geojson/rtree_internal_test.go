@@ -0,0 +1,58 @@
+package geojson
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+)
+
+func TestRTreeSearch(t *testing.T) {
+	type tcase struct {
+		size int
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			r := rand.New(rand.NewSource(int64(tc.size)))
+			bboxes := make([][4]float64, tc.size)
+			for i := range bboxes {
+				x, y := r.Float64()*1000, r.Float64()*1000
+				bboxes[i] = [4]float64{x, y, x + r.Float64()*50, y + r.Float64()*50}
+			}
+			tree := newRTree(bboxes)
+
+			for i := 0; i < 50; i++ {
+				x, y := r.Float64()*1000, r.Float64()*1000
+				extent := geom.NewExtent([2]float64{x, y}, [2]float64{x + 100, y + 100})
+
+				var expected []int
+				for j, b := range bboxes {
+					if b[0] <= extent.MaxX() && b[2] >= extent.MinX() && b[1] <= extent.MaxY() && b[3] >= extent.MinY() {
+						expected = append(expected, j)
+					}
+				}
+				if got := tree.search(extent); !reflect.DeepEqual(got, expected) {
+					t.Fatalf("search %v, expected %v got %v", extent, expected, got)
+				}
+			}
+
+			if got := tree.search(nil); len(got) != tc.size {
+				t.Errorf("search all, expected %v items got %v", tc.size, len(got))
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"empty":      {size: 0},
+		"one":        {size: 1},
+		"one node":   {size: rtreeNodeSize},
+		"two levels": {size: rtreeNodeSize * rtreeNodeSize},
+		"many":       {size: 5000},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,22 @@
+// Package geoparquet provides a standard provider reading the features of
+// GeoParquet files. The row groups which are outside of a tile are skipped
+// using the bounding box columns of the file when it has them.
+package geoparquet
+
+import "github.com/go-spatial/tegola/provider/internal/fileprovider"
+
+const Name = "geoparquet"
+
+// NewTileProvider instantiates a provider reading the layers from GeoParquet
+// files. The config expects the following params:
+//
+//	filepath (string): [Required] the path to a .parquet file, or to a directory of GeoParquet files
+//	layers ([]map[string]interface{}): [Required] the layers of the provider, with the params:
+//		name (string): [Required] the name of the layer
+//		filename (string): [Optional] the file of the layer in the directory, defaults to the name of the layer followed by .parquet
+//		fields ([]string): [Optional] the columns to include as feature tags, defaults to all the columns but id_fieldname
+//		id_fieldname (string): [Optional] the column holding the ids of the features, defaults to the position of the rows in the file
+//		srid (int): [Optional] the SRID of the geometries, when the CRS of the file has no EPSG code
+//
+// Cleanup closes the files opened by the providers.
+var NewTileProvider, Cleanup = fileprovider.Register(fileprovider.Format{Name: Name, Ext: ".parquet", Open: Open})
@@ -0,0 +1,230 @@
+package geoparquet_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/geoparquet"
+)
+
+type feature struct {
+	ID       uint64
+	Geometry geom.Geometry
+	Tags     map[string]interface{}
+}
+
+func TestTileFeatures(t *testing.T) {
+	type tcase struct {
+		layer    dict.Dict
+		tile     provider.Tile
+		expected []feature
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			p, err := geoparquet.NewTileProvider(dict.Dict{
+				"filepath": "testdata",
+				"layers":   []map[string]interface{}{tc.layer},
+			}, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer geoparquet.Cleanup()
+
+			var features []feature
+			err = p.TileFeatures(context.Background(), tc.layer["name"].(string), tc.tile, nil, func(f *provider.Feature) error {
+				if f.SRID != tegola.WGS84 {
+					t.Errorf("srid, expected %v got %v", tegola.WGS84, f.SRID)
+				}
+				features = append(features, feature{ID: f.ID, Geometry: f.Geometry, Tags: f.Tags})
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(features, tc.expected) {
+				t.Errorf("features, expected %v got %v", tc.expected, features)
+			}
+		}
+	}
+
+	lome := feature{ID: 1, Geometry: geom.Point{10, 10}, Tags: map[string]interface{}{
+		"id": int64(100), "name": "Lomé", "pop": int64(837437), "ratio": 0.5, "capital": true,
+		"founded": "2000-01-01", "updated": "2000-01-01T00:00:00Z", "price": 12.34,
+	}}
+	kansas := feature{ID: 2, Geometry: geom.Point{-100, 40}, Tags: map[string]interface{}{
+		"id": int64(200), "name": "Kansas", "capital": false,
+	}}
+	perth := feature{ID: 3, Geometry: geom.Point{120, -30}, Tags: map[string]interface{}{
+		"id": int64(300), "name": "Perth", "ratio": 1.25,
+	}}
+	ulaanbaatar := feature{ID: 5, Geometry: geom.Point{100, 50}, Tags: map[string]interface{}{
+		"id": int64(500), "name": "Ulaanbaatar", "pop": int64(1612000), "capital": true,
+	}}
+	polygon := feature{ID: 1, Geometry: geom.Polygon{
+		{{1, 1}, {20, 1}, {20, 20}, {1, 20}, {1, 1}},
+		{{5, 5}, {5, 15}, {15, 15}, {15, 5}, {5, 5}},
+	}, Tags: map[string]interface{}{"kind": "a"}}
+
+	tests := map[string]tcase{
+		"all": {
+			layer:    dict.Dict{"name": "places", "filename": "places_nocovering.parquet"},
+			tile:     provider.NewTile(0, 0, 0, 64, tegola.WebMercator),
+			expected: []feature{lome, kansas, perth, {ID: 4, Geometry: geom.Point{-60, -30}, Tags: map[string]interface{}{"id": int64(400)}}, ulaanbaatar},
+		},
+		"north east": {
+			layer:    dict.Dict{"name": "places", "filename": "places_nocovering.parquet"},
+			tile:     provider.NewTile(1, 1, 0, 64, tegola.WebMercator),
+			expected: []feature{lome, ulaanbaatar},
+		},
+		"covering snappy": {
+			layer:    dict.Dict{"name": "places", "filename": "places_snappy.parquet"},
+			tile:     provider.NewTile(1, 1, 0, 64, tegola.WebMercator),
+			expected: []feature{lome, ulaanbaatar},
+		},
+		"dictionary v2": {
+			layer:    dict.Dict{"name": "places", "filename": "places_dictionary_v2.parquet"},
+			tile:     provider.NewTile(1, 1, 1, 64, tegola.WebMercator),
+			expected: []feature{perth},
+		},
+		"dictionary snappy v2": {
+			layer:    dict.Dict{"name": "places", "filename": "places_dictionary_v2_snappy.parquet"},
+			tile:     provider.NewTile(1, 0, 0, 64, tegola.WebMercator),
+			expected: []feature{kansas},
+		},
+		"fields and id": {
+			layer: dict.Dict{"name": "cities", "filename": "places.parquet", "fields": []string{"name"}, "id_fieldname": "id"},
+			tile:  provider.NewTile(1, 1, 0, 64, tegola.WebMercator),
+			expected: []feature{
+				{ID: 100, Geometry: geom.Point{10, 10}, Tags: map[string]interface{}{"name": "Lomé"}},
+				{ID: 500, Geometry: geom.Point{100, 50}, Tags: map[string]interface{}{"name": "Ulaanbaatar"}},
+			},
+		},
+		"geometry types": {
+			layer: dict.Dict{"name": "shapes"},
+			tile:  provider.NewTile(0, 0, 0, 64, tegola.WebMercator),
+			expected: []feature{
+				polygon,
+				{ID: 3, Geometry: geom.MultiLineString{{{-50, -50}, {-40, -40}}, {{-30, -30}, {-20, -20}}}, Tags: map[string]interface{}{"kind": "c"}},
+				{ID: 4, Geometry: geom.Point{-45, 15}, Tags: map[string]interface{}{"kind": "d"}},
+				{ID: 5, Geometry: geom.LineString{{30, -10}, {40, -20}}, Tags: map[string]interface{}{"kind": "e"}},
+			},
+		},
+		"geometry types covering": {
+			layer:    dict.Dict{"name": "shapes", "filename": "shapes_covering_snappy.parquet"},
+			tile:     provider.NewTile(2, 2, 1, 0, tegola.WebMercator),
+			expected: []feature{polygon},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestNewTileProvider(t *testing.T) {
+	type tcase struct {
+		filename string
+		layer    dict.Dict
+		err      bool
+		// expectedErr is the type of the error, when it's checked
+		expectedErr error
+		srid        uint64
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			p, err := geoparquet.NewTileProvider(dict.Dict{
+				"filepath": filepath.Join("testdata", tc.filename),
+				"layers":   []map[string]interface{}{tc.layer},
+			}, nil)
+			defer geoparquet.Cleanup()
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				if tc.expectedErr != nil && !errors.As(err, reflect.New(reflect.TypeOf(tc.expectedErr)).Interface()) {
+					t.Fatalf("error, expected %T got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			layers, err := p.Layers()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(layers) != 1 {
+				t.Fatalf("layers, expected 1 got %v", len(layers))
+			}
+			if _, ok := layers[0].GeomType().(geom.Point); !ok {
+				t.Errorf("geom type, expected geom.Point got %T", layers[0].GeomType())
+			}
+			if srid := layers[0].SRID(); srid != tc.srid {
+				t.Errorf("srid, expected %v got %v", tc.srid, srid)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"crs": {
+			filename: "places_3857.parquet",
+			layer:    dict.Dict{"name": "places"},
+			srid:     tegola.WebMercator,
+		},
+		"crs84": {
+			filename: "places_crs84.parquet",
+			layer:    dict.Dict{"name": "places"},
+			srid:     tegola.WGS84,
+		},
+		"unknown crs": {
+			filename: "places_unknown_crs.parquet",
+			layer:    dict.Dict{"name": "places"},
+			err:      true,
+		},
+		"configured srid": {
+			filename: "places_unknown_crs.parquet",
+			layer:    dict.Dict{"name": "places", "srid": 3857},
+			srid:     tegola.WebMercator,
+		},
+		"no geo metadata": {
+			filename: "places_no_geo.parquet",
+			layer:    dict.Dict{"name": "places"},
+			err:      true,
+		},
+		"geoarrow encoding": {
+			filename: "places_geoarrow.parquet",
+			layer:    dict.Dict{"name": "places"},
+			err:      true,
+		},
+		"unsupported codec": {
+			filename:    "places_zstd.parquet",
+			layer:       dict.Dict{"name": "places"},
+			err:         true,
+			expectedErr: geoparquet.ErrUnsupportedCodec{},
+		},
+		"binary field": {
+			filename: "places.parquet",
+			layer:    dict.Dict{"name": "places", "fields": []string{"blob"}},
+			err:      true,
+		},
+		"covering field": {
+			filename: "places.parquet",
+			layer:    dict.Dict{"name": "places", "fields": []string{"bbox.xmin"}},
+			err:      true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,121 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var errInvalidSnappy = errors.New("parquet: invalid snappy data")
+
+// decompress decompresses the data of a page, of size bytes once decompressed
+func decompress(codec Codec, data []byte, size int) ([]byte, error) {
+	switch codec {
+	case Uncompressed:
+		return data, nil
+
+	case Snappy:
+		return decodeSnappy(data)
+
+	case Gzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("parquet: gzip: %w", err)
+		}
+		buf := bytes.NewBuffer(make([]byte, 0, size))
+		// the size of the page bounds the decompressed data
+		if _, err := io.Copy(buf, io.LimitReader(r, int64(size)+1)); err != nil {
+			return nil, fmt.Errorf("parquet: gzip: %w", err)
+		}
+		if buf.Len() != size {
+			return nil, fmt.Errorf("parquet: gzip: decompressed %d bytes, expected %d", buf.Len(), size)
+		}
+		return buf.Bytes(), nil
+
+	default:
+		return nil, fmt.Errorf("parquet: unsupported codec %v", codec)
+	}
+}
+
+// decodeSnappy decodes a snappy block, the format used by Parquet which has
+// no framing
+func decodeSnappy(src []byte) ([]byte, error) {
+	n, l := binary.Uvarint(src)
+	// the literals are at most the size of the block
+	if l <= 0 || n > 1<<31 || n > uint64(len(src))*255 {
+		return nil, errInvalidSnappy
+	}
+	src = src[l:]
+	dst := make([]byte, 0, n)
+
+	for len(src) > 0 {
+		tag := src[0]
+		var length, offset int
+
+		switch tag & 0x03 {
+		case 0:
+			// literal
+			length = int(tag >> 2)
+			src = src[1:]
+			if length >= 60 {
+				extra := length - 59
+				if len(src) < extra {
+					return nil, errInvalidSnappy
+				}
+				length = 0
+				for i := 0; i < extra; i++ {
+					length |= int(src[i]) << (8 * i)
+				}
+				src = src[extra:]
+			}
+			length++
+			if length <= 0 || length > len(src) || len(dst)+length > int(n) {
+				return nil, errInvalidSnappy
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+			continue
+
+		case 1:
+			if len(src) < 2 {
+				return nil, errInvalidSnappy
+			}
+			length = 4 + int(tag>>2)&0x07
+			offset = int(tag&0xe0)<<3 | int(src[1])
+			src = src[2:]
+
+		case 2:
+			if len(src) < 3 {
+				return nil, errInvalidSnappy
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[1:]))
+			src = src[3:]
+
+		case 3:
+			if len(src) < 5 {
+				return nil, errInvalidSnappy
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[1:]))
+			src = src[5:]
+		}
+
+		// copy, which can overlap its source
+		if offset <= 0 || offset > len(dst) || len(dst)+length > int(n) {
+			return nil, errInvalidSnappy
+		}
+		start := len(dst) - offset
+		for i := 0; i < length; i++ {
+			dst = append(dst, dst[start+i])
+		}
+	}
+
+	if len(dst) != int(n) {
+		return nil, errInvalidSnappy
+	}
+	return dst, nil
+}
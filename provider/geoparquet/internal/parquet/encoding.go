@@ -0,0 +1,357 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// encodings
+const (
+	encodingPlain                = 0
+	encodingPlainDictionary      = 2
+	encodingRLE                  = 3
+	encodingDeltaBinaryPacked    = 5
+	encodingDeltaLengthByteArray = 6
+	encodingDeltaByteArray       = 7
+	encodingRLEDictionary        = 8
+	encodingByteStreamSplit      = 9
+)
+
+var errInvalidPage = errors.New("parquet: invalid page")
+
+// decodeValues decodes n values of the column
+func decodeValues(data []byte, c Column, encoding, n int, dict []interface{}) ([]interface{}, error) {
+	switch encoding {
+	case encodingPlain:
+		values, _, err := decodePlain(data, c, n)
+		return values, err
+
+	case encodingPlainDictionary, encodingRLEDictionary:
+		if len(data) == 0 {
+			if n == 0 {
+				return nil, nil
+			}
+			return nil, errInvalidPage
+		}
+		indexes, err := decodeHybrid(data[1:], int(data[0]), n)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, n)
+		for i, idx := range indexes[:n] {
+			if idx >= uint64(len(dict)) {
+				return nil, fmt.Errorf("parquet: invalid dictionary index (%d)", idx)
+			}
+			values[i] = dict[idx]
+		}
+		return values, nil
+
+	case encodingRLE:
+		if c.Type != Boolean || len(data) < 4 {
+			return nil, fmt.Errorf("parquet: unsupported RLE encoding of %v", c.Name())
+		}
+		size := int(binary.LittleEndian.Uint32(data))
+		if size < 0 || size > len(data)-4 {
+			return nil, errInvalidPage
+		}
+		bits, err := decodeHybrid(data[4:4+size], 1, n)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, n)
+		for i, b := range bits[:n] {
+			values[i] = b == 1
+		}
+		return values, nil
+
+	case encodingDeltaBinaryPacked:
+		deltas, _, err := decodeDeltaBinaryPacked(data, n)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, n)
+		for i, v := range deltas {
+			switch c.Type {
+			case Int32:
+				values[i] = int32(v)
+			case Int64:
+				values[i] = v
+			default:
+				return nil, fmt.Errorf("parquet: unsupported DELTA_BINARY_PACKED encoding of %v", c.Name())
+			}
+		}
+		return values, nil
+
+	case encodingDeltaLengthByteArray:
+		lengths, pos, err := decodeDeltaBinaryPacked(data, n)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, n)
+		for i, l := range lengths {
+			if l < 0 || l > int64(len(data)-pos) {
+				return nil, errInvalidPage
+			}
+			values[i] = data[pos : pos+int(l) : pos+int(l)]
+			pos += int(l)
+		}
+		return values, nil
+
+	case encodingDeltaByteArray:
+		prefixes, pos, err := decodeDeltaBinaryPacked(data, n)
+		if err != nil {
+			return nil, err
+		}
+		suffixes, n2, err := decodeDeltaBinaryPacked(data[pos:], n)
+		if err != nil {
+			return nil, err
+		}
+		pos += n2
+
+		values := make([]interface{}, n)
+		var prev []byte
+		for i := range values {
+			p, s := prefixes[i], suffixes[i]
+			if p < 0 || p > int64(len(prev)) || s < 0 || s > int64(len(data)-pos) {
+				return nil, errInvalidPage
+			}
+			v := make([]byte, 0, int(p+s))
+			v = append(v, prev[:p]...)
+			v = append(v, data[pos:pos+int(s)]...)
+			pos += int(s)
+			values[i], prev = v, v
+		}
+		return values, nil
+
+	case encodingByteStreamSplit:
+		size := 0
+		switch c.Type {
+		case Int32, Float:
+			size = 4
+		case Int64, Double:
+			size = 8
+		case FixedLenByteArray:
+			size = c.TypeLength
+		}
+		if size <= 0 || n*size > len(data) {
+			return nil, errInvalidPage
+		}
+		// the k-th bytes of the values are in the k-th stream
+		joined := make([]byte, n*size)
+		for i := 0; i < n; i++ {
+			for k := 0; k < size; k++ {
+				joined[i*size+k] = data[k*(len(data)/size)+i]
+			}
+		}
+		values, _, err := decodePlain(joined, c, n)
+		return values, err
+
+	default:
+		return nil, fmt.Errorf("parquet: unsupported encoding (%d) of %v", encoding, c.Name())
+	}
+}
+
+// decodePlain decodes n plain values of the column and returns them with the
+// number of bytes read
+func decodePlain(data []byte, c Column, n int) ([]interface{}, int, error) {
+	le := binary.LittleEndian
+
+	size := 0
+	switch c.Type {
+	case Boolean:
+		if (n+7)/8 > len(data) {
+			return nil, 0, errInvalidPage
+		}
+		values := make([]interface{}, n)
+		for i := range values {
+			values[i] = data[i/8]>>(i%8)&1 == 1
+		}
+		return values, (n + 7) / 8, nil
+	case Int32, Float:
+		size = 4
+	case Int64, Double:
+		size = 8
+	case Int96:
+		size = 12
+	case FixedLenByteArray:
+		size = c.TypeLength
+	case ByteArray:
+		values := make([]interface{}, n)
+		pos := 0
+		for i := range values {
+			if len(data)-pos < 4 {
+				return nil, 0, errInvalidPage
+			}
+			l := int(le.Uint32(data[pos:]))
+			pos += 4
+			if l < 0 || l > len(data)-pos {
+				return nil, 0, errInvalidPage
+			}
+			values[i] = data[pos : pos+l : pos+l]
+			pos += l
+		}
+		return values, pos, nil
+	default:
+		return nil, 0, fmt.Errorf("parquet: unknown type (%d) of %v", c.Type, c.Name())
+	}
+
+	if size <= 0 || n > len(data)/size {
+		return nil, 0, errInvalidPage
+	}
+	values := make([]interface{}, n)
+	for i := range values {
+		b := data[i*size : (i+1)*size : (i+1)*size]
+		switch c.Type {
+		case Int32:
+			values[i] = int32(le.Uint32(b))
+		case Int64:
+			values[i] = int64(le.Uint64(b))
+		case Float:
+			values[i] = math.Float32frombits(le.Uint32(b))
+		case Double:
+			values[i] = math.Float64frombits(le.Uint64(b))
+		default:
+			values[i] = b
+		}
+	}
+	return values, n * size, nil
+}
+
+// decodeHybrid decodes n values of the RLE / bit-packing hybrid encoding
+func decodeHybrid(data []byte, width, n int) ([]uint64, error) {
+	if width < 0 || width > 64 {
+		return nil, errInvalidPage
+	}
+
+	values := make([]uint64, 0, n)
+	pos := 0
+	for len(values) < n {
+		header, l := binary.Uvarint(data[pos:])
+		if l <= 0 {
+			return nil, errInvalidPage
+		}
+		pos += l
+
+		if header&1 == 1 {
+			// bit-packed groups of 8 values
+			count := int(header>>1) * 8
+			size := int(header>>1) * width
+			if count < 0 || size < 0 || size > len(data)-pos {
+				// the last run can be truncated
+				size = len(data) - pos
+				count = size * 8 / max(width, 1)
+			}
+			for i := 0; i < count && len(values) < n; i++ {
+				values = append(values, readBits(data[pos:pos+size], uint64(i*width), width))
+			}
+			pos += size
+			continue
+		}
+
+		count := header >> 1
+		size := (width + 7) / 8
+		if size > len(data)-pos {
+			return nil, errInvalidPage
+		}
+		var v uint64
+		for i := 0; i < size; i++ {
+			v |= uint64(data[pos+i]) << (8 * i)
+		}
+		pos += size
+		for i := uint64(0); i < count && len(values) < n; i++ {
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// readBits reads the width bits at bit of b, least significant first
+func readBits(b []byte, bit uint64, width int) uint64 {
+	var v uint64
+	for i := 0; i < width; {
+		byteIdx, off := bit>>3, int(bit&7)
+		if byteIdx >= uint64(len(b)) {
+			break
+		}
+		n := 8 - off
+		if n > width-i {
+			n = width - i
+		}
+		v |= (uint64(b[byteIdx]) >> off) & (1<<n - 1) << i
+		i += n
+		bit += uint64(n)
+	}
+	return v
+}
+
+// decodeDeltaBinaryPacked decodes n values of the DELTA_BINARY_PACKED
+// encoding and returns them with the number of bytes read
+func decodeDeltaBinaryPacked(data []byte, n int) ([]int64, int, error) {
+	pos := 0
+	uvarint := func() (uint64, error) {
+		v, l := binary.Uvarint(data[pos:])
+		if l <= 0 {
+			return 0, errInvalidPage
+		}
+		pos += l
+		return v, nil
+	}
+	varint := func() (int64, error) {
+		v, err := uvarint()
+		return int64(v>>1) ^ -int64(v&1), err
+	}
+
+	blockSize, err := uvarint()
+	if err != nil {
+		return nil, 0, err
+	}
+	miniBlocks, err := uvarint()
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := uvarint()
+	if err != nil {
+		return nil, 0, err
+	}
+	first, err := varint()
+	if err != nil {
+		return nil, 0, err
+	}
+	if miniBlocks == 0 || blockSize%miniBlocks != 0 || blockSize > 1<<16 || total < uint64(n) {
+		return nil, 0, errInvalidPage
+	}
+	perMiniBlock := int(blockSize / miniBlocks)
+
+	values := make([]int64, 0, n)
+	if total > 0 {
+		values = append(values, first)
+	}
+	last := first
+	for uint64(len(values)) < total {
+		minDelta, err := varint()
+		if err != nil {
+			return nil, 0, err
+		}
+		if uint64(len(data)-pos) < miniBlocks {
+			return nil, 0, errInvalidPage
+		}
+		widths := data[pos : pos+int(miniBlocks)]
+		pos += int(miniBlocks)
+
+		for m := 0; m < len(widths) && uint64(len(values)) < total; m++ {
+			width := int(widths[m])
+			size := perMiniBlock * width / 8
+			if width > 64 || size > len(data)-pos {
+				return nil, 0, errInvalidPage
+			}
+			for i := 0; i < perMiniBlock && uint64(len(values)) < total; i++ {
+				last += minDelta + int64(readBits(data[pos:pos+size], uint64(i*width), width))
+				values = append(values, last)
+			}
+			pos += size
+		}
+	}
+	return values[:n], pos, nil
+}
@@ -0,0 +1,106 @@
+package parquet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeValues(t *testing.T) {
+	type tcase struct {
+		data     []byte
+		column   Column
+		encoding int
+		n        int
+		dict     []interface{}
+		expected []interface{}
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			values, err := decodeValues(tc.data, tc.column, tc.encoding, tc.n, tc.dict)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(values, tc.expected) {
+				t.Errorf("values, expected %v got %v", tc.expected, values)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"dictionary bit-packed": {
+			// a run of 2 and a bit-packed group of 8 values of 3 bits
+			data:     []byte{3, 0x04, 0x02, 0x03, 0x88, 0xc6, 0xfa},
+			column:   Column{Type: Int32},
+			encoding: encodingRLEDictionary,
+			n:        10,
+			dict:     []interface{}{"a", "b", "c", "d", "e", "f", "g", "h"},
+			expected: []interface{}{"c", "c", "a", "b", "c", "d", "e", "f", "g", "h"},
+		},
+		"boolean rle": {
+			data:     []byte{4, 0, 0, 0, 0x06, 0x01, 0x02, 0x00},
+			column:   Column{Type: Boolean},
+			encoding: encodingRLE,
+			n:        4,
+			expected: []interface{}{true, true, true, false},
+		},
+		"delta binary packed": {
+			// the deltas are the min delta
+			data:     []byte{0x80, 0x01, 0x04, 0x05, 0x02, 0x02, 0x00, 0x00, 0x00, 0x00},
+			column:   Column{Type: Int64},
+			encoding: encodingDeltaBinaryPacked,
+			n:        5,
+			expected: []interface{}{int64(1), int64(2), int64(3), int64(4), int64(5)},
+		},
+		"delta binary packed width": {
+			data: []byte{
+				0x80, 0x01, 0x04, 0x08, 0x0e,
+				0x03, 0x02, 0x00, 0x00, 0x00,
+				0xc0, 0x3f, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			},
+			column:   Column{Type: Int32},
+			encoding: encodingDeltaBinaryPacked,
+			n:        8,
+			expected: []interface{}{int32(7), int32(5), int32(3), int32(1), int32(2), int32(3), int32(4), int32(5)},
+		},
+		"delta byte array": {
+			// the prefix lengths 0, 2 and the suffix lengths 2, 1
+			data: []byte{
+				0x80, 0x01, 0x04, 0x02, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00,
+				0x80, 0x01, 0x04, 0x02, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00,
+				'a', 'b', 'c',
+			},
+			column:   Column{Type: ByteArray},
+			encoding: encodingDeltaByteArray,
+			n:        2,
+			expected: []interface{}{[]byte("ab"), []byte("abc")},
+		},
+		"byte stream split": {
+			data:     []byte{0x00, 0x00, 0x00, 0x00, 0x80, 0x00, 0x3f, 0x40},
+			column:   Column{Type: Float},
+			encoding: encodingByteStreamSplit,
+			n:        2,
+			expected: []interface{}{float32(1), float32(2)},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestDecodeSnappy(t *testing.T) {
+	// a literal, a copy overlapping its source and a literal
+	data := []byte{0x0a, 0x08, 'a', 'b', 'c', 0x09, 0x03, 0x00, 'd'}
+	b, err := decodeSnappy(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(b) != "abcabcabcd" {
+		t.Errorf("expected abcabcabcd got %q", b)
+	}
+
+	if _, err = decodeSnappy(data[:len(data)-1]); err == nil {
+		t.Errorf("expected an error for the truncated block")
+	}
+}
@@ -0,0 +1,277 @@
+// Package parquet reads the columns of Apache Parquet files. It supports the
+// columns which are not repeated, the data pages of both versions with their
+// common encodings, and the uncompressed, snappy and gzip chunks.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var magic = []byte("PAR1")
+
+// maxFooterSize bounds the size of the metadata read from a file
+const maxFooterSize = 64 << 20
+
+// File is an open Parquet file
+type File struct {
+	r         io.ReaderAt
+	Columns   []Column
+	RowGroups []RowGroup
+	NumRows   int64
+	// KeyValue is the key value metadata of the file
+	KeyValue map[string]string
+}
+
+// Open reads the metadata of the Parquet file of r, of size bytes
+func Open(r io.ReaderAt, size int64) (*File, error) {
+	if size < 12 {
+		return nil, errors.New("parquet: file too small")
+	}
+
+	var footer [8]byte
+	if _, err := r.ReadAt(footer[:], size-8); err != nil {
+		return nil, fmt.Errorf("parquet: reading footer: %w", err)
+	}
+	if !bytes.Equal(footer[4:], magic) {
+		return nil, errors.New("parquet: not a Parquet file, or an encrypted one")
+	}
+	n := int64(binary.LittleEndian.Uint32(footer[:]))
+	if n > maxFooterSize || n > size-12 {
+		return nil, errors.New("parquet: invalid metadata size")
+	}
+
+	buf := make([]byte, n)
+	if _, err := r.ReadAt(buf, size-8-n); err != nil {
+		return nil, fmt.Errorf("parquet: reading metadata: %w", err)
+	}
+	md, _, err := readStruct(buf)
+	if err != nil {
+		return nil, fmt.Errorf("parquet: decoding metadata: %w", err)
+	}
+
+	f := File{r: r, KeyValue: make(map[string]string)}
+	f.NumRows, _ = md.int(3)
+	if f.Columns, err = parseSchema(md.list(2)); err != nil {
+		return nil, err
+	}
+	for _, v := range md.list(4) {
+		s, ok := v.(tstruct)
+		if !ok {
+			return nil, errInvalidThrift
+		}
+		rg, err := parseRowGroup(s)
+		if err != nil {
+			return nil, err
+		}
+		if len(rg.Columns) != len(f.Columns) {
+			return nil, fmt.Errorf("parquet: row group with %d columns, the schema has %d", len(rg.Columns), len(f.Columns))
+		}
+		f.RowGroups = append(f.RowGroups, rg)
+	}
+	for _, v := range md.list(5) {
+		if kv, ok := v.(tstruct); ok {
+			f.KeyValue[kv.string(1)] = kv.string(2)
+		}
+	}
+	return &f, nil
+}
+
+// Column returns the column with the name, see Column.Name
+func (f *File) Column(name string) (Column, bool) {
+	for _, c := range f.Columns {
+		if c.Name() == name {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+// page types
+const (
+	dataPage       = 0
+	indexPage      = 1
+	dictionaryPage = 2
+	dataPageV2     = 3
+)
+
+// ReadColumn reads the values of a column in a row group, a value per row.
+// The values are bool, int32, int64, float32, float64 and []byte for the
+// byte arrays and the INT96 values, nil for the null values.
+func (f *File) ReadColumn(rowGroup int, c Column) ([]interface{}, error) {
+	if c.maxRep > 0 {
+		return nil, fmt.Errorf("parquet: column %v is repeated, which is not supported", c.Name())
+	}
+
+	rg := f.RowGroups[rowGroup]
+	chunk := rg.Columns[c.Index]
+	if !chunk.Codec.Supported() {
+		return nil, fmt.Errorf("parquet: column %v is compressed with %v, which is not supported", c.Name(), chunk.Codec)
+	}
+
+	offset := chunk.DataPageOffset
+	if chunk.DictionaryPageOffset > 0 && chunk.DictionaryPageOffset < offset {
+		offset = chunk.DictionaryPageOffset
+	}
+	if chunk.TotalCompressedSize <= 0 || chunk.TotalCompressedSize > 1<<31 {
+		return nil, fmt.Errorf("parquet: invalid size of column %v", c.Name())
+	}
+	buf := make([]byte, chunk.TotalCompressedSize)
+	if _, err := f.r.ReadAt(buf, offset); err != nil {
+		return nil, fmt.Errorf("parquet: reading column %v: %w", c.Name(), err)
+	}
+
+	values := make([]interface{}, 0, rg.NumRows)
+	var dict []interface{}
+	for pos := 0; pos < len(buf) && int64(len(values)) < rg.NumRows; {
+		header, n, err := readStruct(buf[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("parquet: column %v: decoding page header: %w", c.Name(), err)
+		}
+		pos += n
+
+		typ, _ := header.int(1)
+		uncompressedSize, _ := header.int(2)
+		compressedSize, _ := header.int(3)
+		if compressedSize < 0 || compressedSize > int64(len(buf)-pos) || uncompressedSize < 0 || uncompressedSize > 1<<31 {
+			return nil, fmt.Errorf("parquet: column %v: invalid page size", c.Name())
+		}
+		page := buf[pos : pos+int(compressedSize)]
+		pos += int(compressedSize)
+
+		switch typ {
+		case dictionaryPage:
+			h := header.strct(7)
+			data, err := decompress(chunk.Codec, page, int(uncompressedSize))
+			if err != nil {
+				return nil, fmt.Errorf("parquet: column %v: %w", c.Name(), err)
+			}
+			n, _ := h.int(1)
+			if dict, _, err = decodePlain(data, c, int(n)); err != nil {
+				return nil, fmt.Errorf("parquet: column %v: dictionary page: %w", c.Name(), err)
+			}
+
+		case dataPage:
+			h := header.strct(5)
+			data, err := decompress(chunk.Codec, page, int(uncompressedSize))
+			if err != nil {
+				return nil, fmt.Errorf("parquet: column %v: %w", c.Name(), err)
+			}
+			n, _ := h.int(1)
+			encoding, _ := h.int(2)
+
+			var defs []uint64
+			if c.maxDef > 0 {
+				if len(data) < 4 {
+					return nil, fmt.Errorf("parquet: column %v: invalid data page", c.Name())
+				}
+				size := int(binary.LittleEndian.Uint32(data))
+				if size < 0 || size > len(data)-4 {
+					return nil, fmt.Errorf("parquet: column %v: invalid definition levels", c.Name())
+				}
+				if defs, err = decodeHybrid(data[4:4+size], bitWidth(c.maxDef), int(n)); err != nil {
+					return nil, fmt.Errorf("parquet: column %v: definition levels: %w", c.Name(), err)
+				}
+				data = data[4+size:]
+			}
+			if values, err = appendValues(values, data, c, int(encoding), int(n), defs, dict); err != nil {
+				return nil, fmt.Errorf("parquet: column %v: %w", c.Name(), err)
+			}
+
+		case dataPageV2:
+			h := header.strct(8)
+			n, _ := h.int(1)
+			encoding, _ := h.int(4)
+			defSize, _ := h.int(5)
+			repSize, _ := h.int(6)
+			compressed, ok := h.bool(7)
+			if !ok {
+				compressed = true
+			}
+			if defSize < 0 || repSize < 0 || defSize+repSize > int64(len(page)) {
+				return nil, fmt.Errorf("parquet: column %v: invalid data page", c.Name())
+			}
+
+			var defs []uint64
+			if c.maxDef > 0 {
+				var err error
+				if defs, err = decodeHybrid(page[repSize:repSize+defSize], bitWidth(c.maxDef), int(n)); err != nil {
+					return nil, fmt.Errorf("parquet: column %v: definition levels: %w", c.Name(), err)
+				}
+			}
+
+			data := page[repSize+defSize:]
+			if compressed {
+				var err error
+				if data, err = decompress(chunk.Codec, data, int(uncompressedSize-repSize-defSize)); err != nil {
+					return nil, fmt.Errorf("parquet: column %v: %w", c.Name(), err)
+				}
+			}
+			var err error
+			if values, err = appendValues(values, data, c, int(encoding), int(n), defs, dict); err != nil {
+				return nil, fmt.Errorf("parquet: column %v: %w", c.Name(), err)
+			}
+
+		case indexPage:
+		default:
+			return nil, fmt.Errorf("parquet: column %v: unknown page type (%d)", c.Name(), typ)
+		}
+	}
+
+	if int64(len(values)) != rg.NumRows {
+		return nil, fmt.Errorf("parquet: column %v: read %d values, the row group has %d rows", c.Name(), len(values), rg.NumRows)
+	}
+	return values, nil
+}
+
+// appendValues decodes the n values of a data page and appends them to
+// values, with nil for the values which are not defined
+func appendValues(values []interface{}, data []byte, c Column, encoding, n int, defs []uint64, dict []interface{}) ([]interface{}, error) {
+	if n < 0 || (defs != nil && len(defs) < n) {
+		return nil, errInvalidPage
+	}
+
+	count := n
+	if defs != nil {
+		count = 0
+		for _, d := range defs[:n] {
+			if int(d) == c.maxDef {
+				count++
+			}
+		}
+	}
+
+	decoded, err := decodeValues(data, c, encoding, count, dict)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) < count {
+		return nil, errInvalidPage
+	}
+
+	if defs == nil {
+		return append(values, decoded[:count]...), nil
+	}
+	j := 0
+	for _, d := range defs[:n] {
+		if int(d) == c.maxDef {
+			values = append(values, decoded[j])
+			j++
+		} else {
+			values = append(values, nil)
+		}
+	}
+	return values, nil
+}
+
+// bitWidth returns the number of bits needed for the values up to max
+func bitWidth(max int) int {
+	w := 0
+	for ; max > 0; max >>= 1 {
+		w++
+	}
+	return w
+}
@@ -0,0 +1,301 @@
+package parquet
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Type is the physical type of a column
+type Type int32
+
+const (
+	Boolean           Type = 0
+	Int32             Type = 1
+	Int64             Type = 2
+	Int96             Type = 3
+	Float             Type = 4
+	Double            Type = 5
+	ByteArray         Type = 6
+	FixedLenByteArray Type = 7
+)
+
+// ConvertedType is the legacy logical type of a column
+type ConvertedType int32
+
+const (
+	ConvertedNone            ConvertedType = -1
+	ConvertedUTF8            ConvertedType = 0
+	ConvertedEnum            ConvertedType = 4
+	ConvertedDecimal         ConvertedType = 5
+	ConvertedDate            ConvertedType = 6
+	ConvertedTimestampMillis ConvertedType = 9
+	ConvertedTimestampMicros ConvertedType = 10
+	ConvertedUint8           ConvertedType = 11
+	ConvertedUint16          ConvertedType = 12
+	ConvertedUint32          ConvertedType = 13
+	ConvertedUint64          ConvertedType = 14
+	ConvertedJSON            ConvertedType = 19
+)
+
+// Codec is the compression codec of a column chunk
+type Codec int32
+
+const (
+	Uncompressed Codec = 0
+	Snappy       Codec = 1
+	Gzip         Codec = 2
+	LZO          Codec = 3
+	Brotli       Codec = 4
+	LZ4          Codec = 5
+	Zstd         Codec = 6
+	LZ4Raw       Codec = 7
+)
+
+func (c Codec) String() string {
+	switch c {
+	case Uncompressed:
+		return "UNCOMPRESSED"
+	case Snappy:
+		return "SNAPPY"
+	case Gzip:
+		return "GZIP"
+	case LZO:
+		return "LZO"
+	case Brotli:
+		return "BROTLI"
+	case LZ4:
+		return "LZ4"
+	case Zstd:
+		return "ZSTD"
+	case LZ4Raw:
+		return "LZ4_RAW"
+	default:
+		return fmt.Sprintf("codec(%d)", int32(c))
+	}
+}
+
+// Supported reports whether the chunks compressed with the codec can be read
+func (c Codec) Supported() bool {
+	return c == Uncompressed || c == Snappy || c == Gzip
+}
+
+// repetition types
+const (
+	required = 0
+	optional = 1
+	repeated = 2
+)
+
+// TimeUnit is the unit of a timestamp
+type TimeUnit int
+
+const (
+	Millis TimeUnit = iota + 1
+	Micros
+	Nanos
+)
+
+// Column is a leaf column of the schema
+type Column struct {
+	// Index of the column in the column chunks of the row groups
+	Index int
+	// Path of the column in the schema, the name of the column for the
+	// columns which are not nested
+	Path       []string
+	Type       Type
+	TypeLength int
+	Converted  ConvertedType
+	// String is set for the byte arrays holding text
+	String bool
+	// Unsigned is set for the unsigned integers
+	Unsigned bool
+	// Scale of the decimals, -1 for the other types
+	Scale int
+	// Date is set for the dates, as days since the epoch
+	Date bool
+	// Timestamp is the unit of the timestamps, 0 for the other types
+	Timestamp TimeUnit
+
+	maxDef int
+	maxRep int
+}
+
+// Name returns the path of the column joined with dots
+func (c Column) Name() string { return strings.Join(c.Path, ".") }
+
+// Repeated reports whether the column is a list, or is nested in one
+func (c Column) Repeated() bool { return c.maxRep > 0 }
+
+// Statistics are the statistics of a column chunk. Min and Max are encoded
+// as plain values, they are nil when they're not known.
+type Statistics struct {
+	Min, Max []byte
+}
+
+// ColumnChunk is the metadata of the chunk of a column in a row group
+type ColumnChunk struct {
+	Codec                Codec
+	NumValues            int64
+	TotalCompressedSize  int64
+	DataPageOffset       int64
+	DictionaryPageOffset int64
+	Statistics           Statistics
+}
+
+// RowGroup is the metadata of a row group
+type RowGroup struct {
+	NumRows int64
+	Columns []ColumnChunk
+}
+
+func parseRowGroup(s tstruct) (RowGroup, error) {
+	rg := RowGroup{}
+	rg.NumRows, _ = s.int(3)
+	for _, v := range s.list(1) {
+		cc, ok := v.(tstruct)
+		if !ok {
+			return rg, errInvalidThrift
+		}
+		if path := cc.string(1); path != "" {
+			return rg, fmt.Errorf("parquet: column chunks in other files (%v) are not supported", path)
+		}
+		md := cc.strct(3)
+		if md == nil {
+			return rg, fmt.Errorf("parquet: column chunk without metadata")
+		}
+
+		chunk := ColumnChunk{}
+		codec, _ := md.int(4)
+		chunk.Codec = Codec(codec)
+		chunk.NumValues, _ = md.int(5)
+		chunk.TotalCompressedSize, _ = md.int(7)
+		chunk.DataPageOffset, _ = md.int(9)
+		chunk.DictionaryPageOffset, _ = md.int(11)
+		if stats := md.strct(12); stats != nil {
+			// min_value and max_value, or the deprecated min and max
+			chunk.Statistics.Min, chunk.Statistics.Max = stats.binary(6), stats.binary(5)
+			if chunk.Statistics.Min == nil || chunk.Statistics.Max == nil {
+				chunk.Statistics.Min, chunk.Statistics.Max = stats.binary(2), stats.binary(1)
+			}
+		}
+		rg.Columns = append(rg.Columns, chunk)
+	}
+	return rg, nil
+}
+
+// parseSchema returns the leaf columns of the schema elements
+func parseSchema(elements []interface{}) ([]Column, error) {
+	var (
+		columns []Column
+		pos     = 1
+	)
+
+	var walk func(path []string, n int64, maxDef, maxRep int) error
+	walk = func(path []string, n int64, maxDef, maxRep int) error {
+		for i := int64(0); i < n; i++ {
+			if pos >= len(elements) {
+				return fmt.Errorf("parquet: invalid schema")
+			}
+			e, ok := elements[pos].(tstruct)
+			if !ok {
+				return errInvalidThrift
+			}
+			pos++
+
+			def, rep := maxDef, maxRep
+			switch repetition, _ := e.int(3); repetition {
+			case optional:
+				def++
+			case repeated:
+				def++
+				rep++
+			}
+
+			childPath := append(path[:len(path):len(path)], e.string(4))
+			if children, _ := e.int(5); children > 0 {
+				if err := walk(childPath, children, def, rep); err != nil {
+					return err
+				}
+				continue
+			}
+
+			columns = append(columns, newColumn(e, len(columns), childPath, def, rep))
+		}
+		return nil
+	}
+
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("parquet: empty schema")
+	}
+	root, ok := elements[0].(tstruct)
+	if !ok {
+		return nil, errInvalidThrift
+	}
+	children, _ := root.int(5)
+	if err := walk(nil, children, 0, 0); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+func newColumn(e tstruct, index int, path []string, maxDef, maxRep int) Column {
+	typ, _ := e.int(1)
+	typeLength, _ := e.int(2)
+	c := Column{
+		Index:      index,
+		Path:       path,
+		Type:       Type(typ),
+		TypeLength: int(typeLength),
+		Converted:  ConvertedNone,
+		Scale:      -1,
+		maxDef:     maxDef,
+		maxRep:     maxRep,
+	}
+
+	if converted, ok := e.int(6); ok {
+		c.Converted = ConvertedType(converted)
+		switch c.Converted {
+		case ConvertedUTF8, ConvertedEnum, ConvertedJSON:
+			c.String = true
+		case ConvertedUint8, ConvertedUint16, ConvertedUint32, ConvertedUint64:
+			c.Unsigned = true
+		case ConvertedDecimal:
+			scale, _ := e.int(7)
+			c.Scale = int(scale)
+		case ConvertedDate:
+			c.Date = true
+		case ConvertedTimestampMillis:
+			c.Timestamp = Millis
+		case ConvertedTimestampMicros:
+			c.Timestamp = Micros
+		}
+	}
+
+	// the logical type is a union
+	if logical := e.strct(10); logical != nil {
+		switch {
+		case logical.strct(1) != nil, logical.strct(4) != nil, logical.strct(12) != nil:
+			// STRING, ENUM and JSON
+			c.String = true
+		case logical.strct(5) != nil:
+			scale, _ := logical.strct(5).int(1)
+			c.Scale = int(scale)
+		case logical.strct(6) != nil:
+			c.Date = true
+		case logical.strct(8) != nil:
+			unit := logical.strct(8).strct(2)
+			switch {
+			case unit.strct(1) != nil:
+				c.Timestamp = Millis
+			case unit.strct(2) != nil:
+				c.Timestamp = Micros
+			case unit.strct(3) != nil:
+				c.Timestamp = Nanos
+			}
+		case logical.strct(10) != nil:
+			signed, ok := logical.strct(10).bool(2)
+			c.Unsigned = ok && !signed
+		}
+	}
+	return c
+}
@@ -0,0 +1,229 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// types of the thrift compact protocol
+const (
+	thriftBoolTrue  = 1
+	thriftBoolFalse = 2
+	thriftByte      = 3
+	thriftI16       = 4
+	thriftI32       = 5
+	thriftI64       = 6
+	thriftDouble    = 7
+	thriftBinary    = 8
+	thriftList      = 9
+	thriftSet       = 10
+	thriftMap       = 11
+	thriftStruct    = 12
+)
+
+// maxThriftDepth bounds the nesting of the decoded structs
+const maxThriftDepth = 16
+
+var errInvalidThrift = errors.New("parquet: invalid thrift data")
+
+// tstruct is a struct decoded with the thrift compact protocol: the values of
+// its fields by id. Values are bool, int64 for the integers, float64, []byte,
+// []interface{} for lists and sets, and tstruct. Maps are skipped.
+type tstruct map[int16]interface{}
+
+func (s tstruct) int(id int16) (int64, bool) {
+	v, ok := s[id].(int64)
+	return v, ok
+}
+
+func (s tstruct) bool(id int16) (bool, bool) {
+	v, ok := s[id].(bool)
+	return v, ok
+}
+
+func (s tstruct) binary(id int16) []byte {
+	v, _ := s[id].([]byte)
+	return v
+}
+
+func (s tstruct) string(id int16) string { return string(s.binary(id)) }
+
+func (s tstruct) list(id int16) []interface{} {
+	v, _ := s[id].([]interface{})
+	return v
+}
+
+func (s tstruct) strct(id int16) tstruct {
+	v, _ := s[id].(tstruct)
+	return v
+}
+
+// compactReader decodes the thrift compact protocol
+type compactReader struct {
+	b   []byte
+	pos int
+}
+
+// readStruct decodes a struct and returns it with the number of bytes read
+func readStruct(b []byte) (tstruct, int, error) {
+	r := compactReader{b: b}
+	s, err := r.readStruct(0)
+	return s, r.pos, err
+}
+
+func (r *compactReader) byte() (byte, error) {
+	if r.pos >= len(r.b) {
+		return 0, errInvalidThrift
+	}
+	c := r.b[r.pos]
+	r.pos++
+	return c, nil
+}
+
+func (r *compactReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	if n <= 0 {
+		return 0, errInvalidThrift
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *compactReader) varint() (int64, error) {
+	v, err := r.uvarint()
+	// zigzag
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+func (r *compactReader) readStruct(depth int) (tstruct, error) {
+	if depth > maxThriftDepth {
+		return nil, errInvalidThrift
+	}
+
+	s := make(tstruct)
+	var id int16
+	for {
+		header, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if header == 0 {
+			return s, nil
+		}
+
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+
+		typ := header & 0x0f
+		switch typ {
+		// the values of booleans fields are their type
+		case thriftBoolTrue:
+			s[id] = true
+		case thriftBoolFalse:
+			s[id] = false
+		default:
+			v, err := r.readValue(typ, depth)
+			if err != nil {
+				return nil, err
+			}
+			if v != nil {
+				s[id] = v
+			}
+		}
+	}
+}
+
+func (r *compactReader) readValue(typ byte, depth int) (interface{}, error) {
+	switch typ {
+	case thriftBoolTrue, thriftBoolFalse:
+		// booleans of lists are a byte
+		c, err := r.byte()
+		return c == thriftBoolTrue, err
+
+	case thriftByte:
+		c, err := r.byte()
+		return int64(int8(c)), err
+
+	case thriftI16, thriftI32, thriftI64:
+		return r.varint()
+
+	case thriftDouble:
+		if len(r.b)-r.pos < 8 {
+			return nil, errInvalidThrift
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(r.b[r.pos:]))
+		r.pos += 8
+		return v, nil
+
+	case thriftBinary:
+		n, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(r.b)-r.pos) {
+			return nil, errInvalidThrift
+		}
+		v := r.b[r.pos : r.pos+int(n) : r.pos+int(n)]
+		r.pos += int(n)
+		return v, nil
+
+	case thriftList, thriftSet:
+		header, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		n := uint64(header >> 4)
+		if n == 15 {
+			if n, err = r.uvarint(); err != nil {
+				return nil, err
+			}
+		}
+		// each element is at least a byte
+		if n > uint64(len(r.b)-r.pos) {
+			return nil, errInvalidThrift
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			if list[i], err = r.readValue(header&0x0f, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+
+	case thriftMap:
+		n, err := r.uvarint()
+		if err != nil || n == 0 {
+			return nil, err
+		}
+		types, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(r.b)-r.pos) {
+			return nil, errInvalidThrift
+		}
+		for i := uint64(0); i < n; i++ {
+			if _, err = r.readValue(types>>4, depth+1); err != nil {
+				return nil, err
+			}
+			if _, err = r.readValue(types&0x0f, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+
+	case thriftStruct:
+		return r.readStruct(depth + 1)
+
+	default:
+		return nil, errInvalidThrift
+	}
+}
@@ -0,0 +1,446 @@
+package geoparquet
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider/geoparquet/internal/parquet"
+	"github.com/go-spatial/tegola/provider/internal/fileprovider"
+)
+
+// geoKey is the key of the GeoParquet metadata in the metadata of the file
+const geoKey = "geo"
+
+// ErrUnsupportedCodec is returned when a GeoParquet file is compressed with
+// another codec than snappy or gzip, i.e. zstd
+type ErrUnsupportedCodec struct {
+	FilePath string
+	Codec    parquet.Codec
+}
+
+func (e ErrUnsupportedCodec) Error() string {
+	return fmt.Sprintf("geoparquet: %v is compressed with %v, only snappy, gzip and uncompressed files are supported", e.FilePath, e.Codec)
+}
+
+// metadata is the GeoParquet metadata of a file
+type metadata struct {
+	PrimaryColumn string                    `json:"primary_column"`
+	Columns       map[string]geometryColumn `json:"columns"`
+}
+
+type geometryColumn struct {
+	Encoding      string   `json:"encoding"`
+	GeometryTypes []string `json:"geometry_types"`
+	// CRS is PROJJSON, or null when the CRS is unknown. A missing CRS is
+	// OGC:CRS84.
+	CRS      json.RawMessage `json:"crs"`
+	Covering *struct {
+		// BBox has the paths of the xmin, ymin, xmax and ymax columns
+		BBox map[string][]string `json:"bbox"`
+	} `json:"covering"`
+}
+
+// source reads the features of a GeoParquet file. The row groups are skipped
+// when their bounding box does not intersect the tile, the bounding boxes are
+// found with the statistics of the bbox covering columns, or are computed
+// when the file is opened.
+type source struct {
+	f        *os.File
+	file     *parquet.File
+	geometry parquet.Column
+	// columns are the columns which can be fields, by name
+	columns  map[string]parquet.Column
+	fields   []string
+	geomType geom.Geometry
+	srid     uint64
+	// bboxes of the row groups, nil for the row groups without geometries
+	bboxes []*geom.Extent
+}
+
+// Open opens the GeoParquet file at path
+func Open(path string) (fileprovider.Source, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := source{f: f}
+	if err = s.open(path); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *source) open(path string) error {
+	info, err := s.f.Stat()
+	if err != nil {
+		return err
+	}
+	if s.file, err = parquet.Open(s.f, info.Size()); err != nil {
+		return err
+	}
+
+	geo, ok := s.file.KeyValue[geoKey]
+	if !ok {
+		return errors.New("geoparquet: the file has no GeoParquet metadata")
+	}
+	var md metadata
+	if err = json.Unmarshal([]byte(geo), &md); err != nil {
+		return fmt.Errorf("geoparquet: decoding the GeoParquet metadata: %w", err)
+	}
+	gc, ok := md.Columns[md.PrimaryColumn]
+	if !ok {
+		return fmt.Errorf("geoparquet: no metadata for the primary column (%v)", md.PrimaryColumn)
+	}
+	if !strings.EqualFold(gc.Encoding, "WKB") {
+		return fmt.Errorf("geoparquet: geometry column %v is encoded as %v, only WKB is supported", md.PrimaryColumn, gc.Encoding)
+	}
+	if s.geometry, ok = s.file.Column(md.PrimaryColumn); !ok || s.geometry.Type != parquet.ByteArray {
+		return fmt.Errorf("geoparquet: invalid geometry column (%v)", md.PrimaryColumn)
+	}
+	s.geomType = geomType(gc.GeometryTypes)
+	s.srid = crsSRID(gc.CRS)
+
+	for _, rg := range s.file.RowGroups {
+		for _, c := range rg.Columns {
+			if !c.Codec.Supported() {
+				return ErrUnsupportedCodec{FilePath: path, Codec: c.Codec}
+			}
+		}
+	}
+
+	// the columns of the covering are not fields
+	skip := map[string]bool{s.geometry.Name(): true}
+	var covering [4]parquet.Column
+	hasCovering := gc.Covering != nil
+	for i, key := range []string{"xmin", "ymin", "xmax", "ymax"} {
+		if !hasCovering {
+			break
+		}
+		c, ok := s.file.Column(strings.Join(gc.Covering.BBox[key], "."))
+		if !ok || (c.Type != parquet.Double && c.Type != parquet.Float) {
+			hasCovering = false
+			break
+		}
+		covering[i] = c
+		skip[c.Name()] = true
+	}
+
+	s.columns = make(map[string]parquet.Column)
+	for _, c := range s.file.Columns {
+		if skip[c.Name()] || c.Repeated() || !taggable(c) {
+			continue
+		}
+		s.columns[c.Name()] = c
+		s.fields = append(s.fields, c.Name())
+	}
+
+	s.bboxes = make([]*geom.Extent, len(s.file.RowGroups))
+	scanned := false
+	for i, rg := range s.file.RowGroups {
+		if rg.NumRows == 0 {
+			continue
+		}
+		if hasCovering {
+			if s.bboxes[i], ok = coveringBBox(rg, covering); ok {
+				continue
+			}
+		}
+		if s.bboxes[i], err = s.scanBBox(i); err != nil {
+			return err
+		}
+		scanned = true
+	}
+	if scanned {
+		log.Infof("geoparquet: row groups of %v have no bbox covering statistics, their bounding boxes were computed", path)
+	}
+	return nil
+}
+
+// geomType returns the geometry of the geometry types of the metadata, nil
+// when there is more than one
+func geomType(types []string) geom.Geometry {
+	if len(types) != 1 {
+		return nil
+	}
+	switch strings.TrimSuffix(types[0], " Z") {
+	case "Point":
+		return geom.Point{}
+	case "LineString":
+		return geom.LineString{}
+	case "Polygon":
+		return geom.Polygon{}
+	case "MultiPoint":
+		return geom.MultiPoint{}
+	case "MultiLineString":
+		return geom.MultiLineString{}
+	case "MultiPolygon":
+		return geom.MultiPolygon{}
+	case "GeometryCollection":
+		return geom.Collection{}
+	default:
+		return nil
+	}
+}
+
+// crsSRID returns the EPSG code of the PROJJSON CRS, 0 when it's unknown
+func crsSRID(crs json.RawMessage) uint64 {
+	if crs == nil {
+		// OGC:CRS84, the longitude and latitude of WGS 84
+		return 4326
+	}
+
+	var projjson struct {
+		ID *struct {
+			Authority string      `json:"authority"`
+			Code      interface{} `json:"code"`
+		} `json:"id"`
+	}
+	if err := json.Unmarshal(crs, &projjson); err != nil || projjson.ID == nil {
+		return 0
+	}
+
+	code := fmt.Sprint(projjson.ID.Code)
+	switch {
+	case strings.EqualFold(projjson.ID.Authority, "OGC") && code == "CRS84":
+		return 4326
+	case strings.EqualFold(projjson.ID.Authority, "EPSG"):
+		srid, _ := strconv.ParseUint(code, 10, 64)
+		return srid
+	default:
+		return 0
+	}
+}
+
+// taggable reports whether the values of the column can be tags
+func taggable(c parquet.Column) bool {
+	switch c.Type {
+	case parquet.Int96:
+		return false
+	case parquet.ByteArray, parquet.FixedLenByteArray:
+		return c.String || c.Scale >= 0
+	default:
+		return true
+	}
+}
+
+// coveringBBox returns the bounding box of a row group from the statistics of
+// its covering columns
+func coveringBBox(rg parquet.RowGroup, covering [4]parquet.Column) (*geom.Extent, bool) {
+	var bbox [4]float64
+	for i, c := range covering {
+		stats := rg.Columns[c.Index].Statistics
+		v := stats.Max
+		if i < 2 {
+			v = stats.Min
+		}
+		switch {
+		case c.Type == parquet.Double && len(v) == 8:
+			bbox[i] = math.Float64frombits(binary.LittleEndian.Uint64(v))
+		case c.Type == parquet.Float && len(v) == 4:
+			bbox[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(v)))
+		default:
+			return nil, false
+		}
+		if math.IsNaN(bbox[i]) {
+			return nil, false
+		}
+	}
+	return geom.NewExtent([2]float64{bbox[0], bbox[1]}, [2]float64{bbox[2], bbox[3]}), true
+}
+
+// scanBBox computes the bounding box of the geometries of a row group
+func (s *source) scanBBox(rowGroup int) (*geom.Extent, error) {
+	values, err := s.file.ReadColumn(rowGroup, s.geometry)
+	if err != nil {
+		return nil, err
+	}
+
+	var bbox *geom.Extent
+	for i, v := range values {
+		g, err := s.decodeGeometry(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w, row group %d row %d", err, rowGroup, i)
+		}
+		if g == nil {
+			continue
+		}
+		ext, err := geom.NewExtentFromGeometry(g)
+		if err != nil {
+			continue
+		}
+		if bbox == nil {
+			bbox = ext
+			continue
+		}
+		bbox.Add(ext)
+	}
+	return bbox, nil
+}
+
+func (s *source) decodeGeometry(v interface{}) (geom.Geometry, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, errInvalidWKB
+	}
+	return decodeWKB(b)
+}
+
+func (s *source) Fields() []string { return s.fields }
+
+func (s *source) GeomType() geom.Geometry { return s.geomType }
+
+func (s *source) SRID() uint64 { return s.srid }
+
+func (s *source) Features(ctx context.Context, extent *geom.Extent, fields []string, fn func(fileprovider.Feature) error) error {
+	var first uint64
+	for i, rg := range s.file.RowGroups {
+		first += uint64(rg.NumRows)
+		bbox := s.bboxes[i]
+		if bbox == nil || !fileprovider.Intersects(extent, bbox.MinX(), bbox.MinY(), bbox.MaxX(), bbox.MaxY()) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := s.rowGroupFeatures(i, first-uint64(rg.NumRows), extent, fields, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rowGroupFeatures calls fn for the features of a row group. first is the
+// index of the first row of the row group in the file.
+func (s *source) rowGroupFeatures(rowGroup int, first uint64, extent *geom.Extent, fields []string, fn func(fileprovider.Feature) error) error {
+	values, err := s.file.ReadColumn(rowGroup, s.geometry)
+	if err != nil {
+		return err
+	}
+
+	var (
+		rows  []int
+		geoms []geom.Geometry
+	)
+	for i, v := range values {
+		g, err := s.decodeGeometry(v)
+		if err != nil {
+			return fmt.Errorf("%w, row %d", err, first+uint64(i)+1)
+		}
+		if g == nil {
+			continue
+		}
+		if extent != nil {
+			ext, err := geom.NewExtentFromGeometry(g)
+			if err != nil || !fileprovider.Intersects(extent, ext.MinX(), ext.MinY(), ext.MaxX(), ext.MaxY()) {
+				continue
+			}
+		}
+		rows = append(rows, i)
+		geoms = append(geoms, g)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	columns := make([][]interface{}, len(fields))
+	for i, name := range fields {
+		c, ok := s.columns[name]
+		if !ok {
+			continue
+		}
+		if columns[i], err = s.file.ReadColumn(rowGroup, c); err != nil {
+			return err
+		}
+	}
+
+	for i, row := range rows {
+		props := make(map[string]interface{}, len(fields))
+		for j, name := range fields {
+			if columns[j] == nil {
+				continue
+			}
+			if v := tagValue(s.columns[name], columns[j][row]); v != nil {
+				props[name] = v
+			}
+		}
+		if err = fn(fileprovider.Feature{Number: first + uint64(row) + 1, Geometry: geoms[i], Properties: props}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *source) Close() error { return s.f.Close() }
+
+// tagValue converts a value of the column to a value of a tag
+func tagValue(c parquet.Column, v interface{}) interface{} {
+	switch v := v.(type) {
+	case bool, float64:
+		return v
+	case float32:
+		return float64(v)
+	case int32:
+		switch {
+		case c.Date:
+			return time.Unix(int64(v)*24*60*60, 0).UTC().Format("2006-01-02")
+		case c.Scale >= 0:
+			return float64(v) / math.Pow10(c.Scale)
+		case c.Unsigned:
+			return uint64(uint32(v))
+		default:
+			return int64(v)
+		}
+	case int64:
+		switch {
+		case c.Timestamp != 0:
+			var t time.Time
+			switch c.Timestamp {
+			case parquet.Millis:
+				t = time.UnixMilli(v)
+			case parquet.Micros:
+				t = time.UnixMicro(v)
+			default:
+				t = time.Unix(0, v)
+			}
+			return t.UTC().Format(time.RFC3339Nano)
+		case c.Scale >= 0:
+			return float64(v) / math.Pow10(c.Scale)
+		case c.Unsigned:
+			return uint64(v)
+		default:
+			return v
+		}
+	case []byte:
+		if c.String {
+			return string(v)
+		}
+		if c.Scale >= 0 {
+			// the big-endian two's complement of the unscaled value
+			n := new(big.Int).SetBytes(v)
+			if len(v) > 0 && v[0]&0x80 != 0 {
+				n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(v)*8)))
+			}
+			f, _ := new(big.Float).SetInt(n).Float64()
+			return f / math.Pow10(c.Scale)
+		}
+		return nil
+	default:
+		return nil
+	}
+}
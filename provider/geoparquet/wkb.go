@@ -0,0 +1,233 @@
+package geoparquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/go-spatial/geom"
+)
+
+// maxWKBDepth bounds the nesting of the geometry collections
+const maxWKBDepth = 8
+
+var errInvalidWKB = errors.New("geoparquet: invalid WKB")
+
+// wkb geometry types
+const (
+	wkbPoint              = 1
+	wkbLineString         = 2
+	wkbPolygon            = 3
+	wkbMultiPoint         = 4
+	wkbMultiLineString    = 5
+	wkbMultiPolygon       = 6
+	wkbGeometryCollection = 7
+)
+
+// wkbReader decodes WKB to 2D geometries. The Z and M values of the
+// coordinates are dropped, both the ISO codes of their types and the EWKB
+// flags are supported.
+type wkbReader struct {
+	b     []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+// decodeWKB decodes a geometry. Empty geometries are nil.
+func decodeWKB(b []byte) (geom.Geometry, error) {
+	r := wkbReader{b: b}
+	return r.geometry(0)
+}
+
+func (r *wkbReader) uint32() (uint32, error) {
+	if len(r.b)-r.pos < 4 {
+		return 0, errInvalidWKB
+	}
+	v := r.order.Uint32(r.b[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+// count reads the number of elements which are at least size bytes
+func (r *wkbReader) count(size int) (int, error) {
+	n, err := r.uint32()
+	if err != nil {
+		return 0, err
+	}
+	if uint64(n)*uint64(size) > uint64(len(r.b)-r.pos) {
+		return 0, errInvalidWKB
+	}
+	return int(n), nil
+}
+
+// point reads the x and y values of a point with dims values
+func (r *wkbReader) point(dims int) ([2]float64, error) {
+	if len(r.b)-r.pos < 8*dims {
+		return [2]float64{}, errInvalidWKB
+	}
+	p := [2]float64{
+		math.Float64frombits(r.order.Uint64(r.b[r.pos:])),
+		math.Float64frombits(r.order.Uint64(r.b[r.pos+8:])),
+	}
+	r.pos += 8 * dims
+	return p, nil
+}
+
+func (r *wkbReader) points(dims int) ([][2]float64, error) {
+	n, err := r.count(8 * dims)
+	if err != nil {
+		return nil, err
+	}
+	points := make([][2]float64, n)
+	for i := range points {
+		if points[i], err = r.point(dims); err != nil {
+			return nil, err
+		}
+	}
+	return points, nil
+}
+
+func (r *wkbReader) rings(dims int) ([][][2]float64, error) {
+	n, err := r.count(4)
+	if err != nil {
+		return nil, err
+	}
+	rings := make([][][2]float64, 0, n)
+	for i := 0; i < n; i++ {
+		ring, err := r.points(dims)
+		if err != nil {
+			return nil, err
+		}
+		rings = append(rings, ring)
+	}
+	return rings, nil
+}
+
+func (r *wkbReader) geometry(depth int) (geom.Geometry, error) {
+	if depth > maxWKBDepth || len(r.b)-r.pos < 5 {
+		return nil, errInvalidWKB
+	}
+	switch r.b[r.pos] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return nil, errInvalidWKB
+	}
+	r.pos++
+
+	typ, err := r.uint32()
+	if err != nil {
+		return nil, err
+	}
+	dims := 2
+	// EWKB
+	if typ&0x80000000 != 0 {
+		dims++
+	}
+	if typ&0x40000000 != 0 {
+		dims++
+	}
+	if typ&0x20000000 != 0 {
+		// the SRID
+		if _, err = r.uint32(); err != nil {
+			return nil, err
+		}
+	}
+	typ &= 0x0fffffff
+	// ISO
+	switch typ / 1000 {
+	case 1, 2:
+		dims++
+	case 3:
+		dims += 2
+	}
+	typ %= 1000
+
+	switch typ {
+	case wkbPoint:
+		p, err := r.point(dims)
+		if err != nil {
+			return nil, err
+		}
+		// the empty points have NaN coordinates
+		if math.IsNaN(p[0]) && math.IsNaN(p[1]) {
+			return nil, nil
+		}
+		return geom.Point(p), nil
+
+	case wkbLineString:
+		points, err := r.points(dims)
+		if err != nil || len(points) == 0 {
+			return nil, err
+		}
+		return geom.LineString(points), nil
+
+	case wkbPolygon:
+		rings, err := r.rings(dims)
+		if err != nil || len(rings) == 0 {
+			return nil, err
+		}
+		return geom.Polygon(rings), nil
+
+	case wkbMultiPoint, wkbMultiLineString, wkbMultiPolygon, wkbGeometryCollection:
+		n, err := r.count(9)
+		if err != nil {
+			return nil, err
+		}
+		var (
+			mpoint   geom.MultiPoint
+			mline    geom.MultiLineString
+			mpolygon geom.MultiPolygon
+			coll     geom.Collection
+		)
+		for i := 0; i < n; i++ {
+			g, err := r.geometry(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if g == nil {
+				continue
+			}
+			switch g := g.(type) {
+			case geom.Point:
+				if typ == wkbMultiPoint {
+					mpoint = append(mpoint, g)
+					continue
+				}
+			case geom.LineString:
+				if typ == wkbMultiLineString {
+					mline = append(mline, g)
+					continue
+				}
+			case geom.Polygon:
+				if typ == wkbMultiPolygon {
+					mpolygon = append(mpolygon, g)
+					continue
+				}
+			}
+			if typ != wkbGeometryCollection {
+				return nil, fmt.Errorf("geoparquet: invalid part (%T) of WKB geometry type %d", g, typ)
+			}
+			coll = append(coll, g)
+		}
+
+		switch {
+		case len(mpoint) > 0:
+			return mpoint, nil
+		case len(mline) > 0:
+			return mline, nil
+		case len(mpolygon) > 0:
+			return mpolygon, nil
+		case len(coll) > 0:
+			return coll, nil
+		default:
+			return nil, nil
+		}
+
+	default:
+		return nil, fmt.Errorf("geoparquet: unsupported WKB geometry type (%d)", typ)
+	}
+}
@@ -16,3 +16,13 @@ type ErrInvalidFilePath struct {
 func (e ErrInvalidFilePath) Error() string {
 	return fmt.Sprintf("gpkg: invalid filepath: %v", e.FilePath)
 }
+
+// ErrUnknownField is returned when a field of the config of a layer is not a
+// column of its table or query
+type ErrUnknownField struct {
+	Field string
+}
+
+func (e ErrUnknownField) Error() string {
+	return fmt.Sprintf("gpkg: field (%v) is not a column of the table or query of the layer", e.Field)
+}
@@ -0,0 +1,186 @@
+package gpkg
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field types the values of the columns can be converted to, set per column
+// with the field_types config of the layers
+const (
+	FieldTypeString   = "string"
+	FieldTypeInt      = "int"
+	FieldTypeFloat    = "float"
+	FieldTypeBool     = "bool"
+	FieldTypeDate     = "date"
+	FieldTypeDateTime = "datetime"
+	FieldTypeBase64   = "base64"
+	FieldTypeHex      = "hex"
+)
+
+var fieldTypes = map[string]bool{
+	FieldTypeString:   true,
+	FieldTypeInt:      true,
+	FieldTypeFloat:    true,
+	FieldTypeBool:     true,
+	FieldTypeDate:     true,
+	FieldTypeDateTime: true,
+	FieldTypeBase64:   true,
+	FieldTypeHex:      true,
+}
+
+// the formats of the dates and datetimes of the GeoPackage spec, and of the
+// SQLite date and time functions
+const (
+	dateFormat     = "2006-01-02"
+	dateTimeFormat = "2006-01-02T15:04:05.000Z"
+)
+
+var timeFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	dateFormat,
+}
+
+// tagValue converts the value of a column to the value of a tag. declType is
+// the declared type of the column, which the SQLite driver uses to decode
+// booleans, dates and datetimes, and fieldType the type hint of the column.
+// Without a hint the blobs are encoded to base64 and the dates and datetimes
+// are formatted like the GeoPackage spec stores them. A nil value is
+// returned for the values which should be left out of the tags.
+func tagValue(v interface{}, declType, fieldType string) (interface{}, error) {
+	switch fieldType {
+	case "":
+		switch v := v.(type) {
+		case int64, float64, string, bool:
+			return v, nil
+		case []byte:
+			return base64.StdEncoding.EncodeToString(v), nil
+		case time.Time:
+			// the driver decodes the dates which can't be parsed to the zero time
+			if v.IsZero() {
+				return nil, nil
+			}
+			if strings.EqualFold(declType, "date") {
+				return v.Format(dateFormat), nil
+			}
+			return v.UTC().Format(dateTimeFormat), nil
+		}
+
+	case FieldTypeString:
+		switch v := v.(type) {
+		case string:
+			return v, nil
+		case []byte:
+			return string(v), nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		case time.Time:
+			return tagValue(v, declType, "")
+		}
+
+	case FieldTypeInt:
+		switch v := v.(type) {
+		case int64:
+			return v, nil
+		case float64:
+			return int64(v), nil
+		case bool:
+			if v {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		case string:
+			return strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		}
+
+	case FieldTypeFloat:
+		switch v := v.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case string:
+			return strconv.ParseFloat(strings.TrimSpace(v), 64)
+		}
+
+	case FieldTypeBool:
+		switch v := v.(type) {
+		case bool:
+			return v, nil
+		case int64:
+			return v != 0, nil
+		case float64:
+			return v != 0, nil
+		case string:
+			return strconv.ParseBool(strings.TrimSpace(v))
+		}
+
+	case FieldTypeDate, FieldTypeDateTime:
+		var t time.Time
+		switch v := v.(type) {
+		case time.Time:
+			t = v
+		case int64:
+			// unix timestamps, like the driver decodes the integer dates
+			t = time.Unix(v, 0).UTC()
+		case string:
+			var err error
+			if t, err = parseTime(v); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unexpected type for %v field: %T", fieldType, v)
+		}
+		if fieldType == FieldTypeDate {
+			return tagValue(t, "date", "")
+		}
+		return tagValue(t, "datetime", "")
+
+	case FieldTypeBase64, FieldTypeHex:
+		var b []byte
+		switch v := v.(type) {
+		case []byte:
+			b = v
+		case string:
+			b = []byte(v)
+		default:
+			return nil, fmt.Errorf("unexpected type for %v field: %T", fieldType, v)
+		}
+		if fieldType == FieldTypeHex {
+			return hex.EncodeToString(b), nil
+		}
+		return base64.StdEncoding.EncodeToString(b), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported field type: %v", fieldType)
+	}
+
+	if fieldType == "" {
+		return nil, fmt.Errorf("unexpected type for sqlite column data: %T", v)
+	}
+	return nil, fmt.Errorf("unexpected type for %v field: %T", fieldType, v)
+}
+
+// parseTime parses the text representations of dates and datetimes
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, format := range timeFormats {
+		if t, err := time.ParseInLocation(format, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date or datetime: %q", s)
+}
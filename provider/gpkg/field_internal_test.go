@@ -0,0 +1,65 @@
+package gpkg
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTagValue(t *testing.T) {
+	type tcase struct {
+		value     interface{}
+		declType  string
+		fieldType string
+		expected  interface{}
+		err       bool
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			v, err := tagValue(tc.value, tc.declType, tc.fieldType)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error, got %v", v)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(v, tc.expected) {
+				t.Errorf("expected %v (%T) got %v (%T)", tc.expected, tc.expected, v, v)
+			}
+		}
+	}
+
+	datetime := time.Date(2021, 3, 4, 5, 6, 7, 890000000, time.UTC)
+
+	tests := map[string]tcase{
+		"int":                {value: int64(7), expected: int64(7)},
+		"bool":               {value: true, declType: "BOOLEAN", expected: true},
+		"blob":               {value: []byte{0x01, 0xff}, declType: "BLOB", expected: "Af8="},
+		"date":               {value: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), declType: "DATE", expected: "2020-01-02"},
+		"datetime":           {value: datetime, declType: "DATETIME", expected: "2021-03-04T05:06:07.890Z"},
+		"invalid date":       {value: time.Time{}, declType: "DATE", expected: nil},
+		"unexpected type":    {value: int32(1), err: true},
+		"string from blob":   {value: []byte("café"), fieldType: FieldTypeString, expected: "café"},
+		"string from float":  {value: 1.5, fieldType: FieldTypeString, expected: "1.5"},
+		"int from string":    {value: " 42", fieldType: FieldTypeInt, expected: int64(42)},
+		"int from bool":      {value: true, fieldType: FieldTypeInt, expected: int64(1)},
+		"invalid int":        {value: "forty two", fieldType: FieldTypeInt, err: true},
+		"float from int":     {value: int64(3), fieldType: FieldTypeFloat, expected: 3.0},
+		"bool from int":      {value: int64(0), fieldType: FieldTypeBool, expected: false},
+		"bool from string":   {value: "t", fieldType: FieldTypeBool, expected: true},
+		"date from string":   {value: "2021-03-04 05:06:07", fieldType: FieldTypeDate, expected: "2021-03-04"},
+		"datetime from unix": {value: int64(1614834367), fieldType: FieldTypeDateTime, expected: "2021-03-04T05:06:07.000Z"},
+		"invalid datetime":   {value: "yesterday", fieldType: FieldTypeDateTime, err: true},
+		"hex":                {value: []byte{0x01, 0xff}, fieldType: FieldTypeHex, expected: "01ff"},
+		"base64 from string": {value: "tegola", fieldType: FieldTypeBase64, expected: "dGVnb2xh"},
+		"unsupported type":   {value: "tegola", fieldType: "uuid", err: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
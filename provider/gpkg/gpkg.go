@@ -33,6 +33,13 @@ const (
 	ConfigKeySQL         = "sql"
 	ConfigKeyGeomIDField = "id_fieldname"
 	ConfigKeyFields      = "fields"
+	// ConfigKeyWhere is the filter of the features of tablename layers
+	ConfigKeyWhere = "where"
+	// ConfigKeyFieldTypes maps the columns to the types of their tags
+	ConfigKeyFieldTypes = "field_types"
+	// ConfigKeyColumnAliases names the tags of tablename layers after the
+	// names of their columns in the gpkg_data_columns table
+	ConfigKeyColumnAliases = "column_aliases"
 )
 
 func decodeGeometry(bytes []byte) (*BinaryHeader, geom.Geometry, error) {
@@ -84,15 +91,14 @@ func (p *Provider) TileFeatures(ctx context.Context, layer string, tile provider
 	// read the tile extent
 	tileBBox, tileSRID := tile.BufferedExtent()
 
-	// TODO(arolek): reimplement once the geom package has reprojection
-	// check if the SRID of the layer differs from that of the tile. tileSRID is assumed to always be WebMercator
+	// check if the SRID of the layer differs from that of the tile
 	if pLayer.srid != tileSRID {
-		minGeo, err := basic.FromWebMercator(pLayer.srid, geom.Point{tileBBox.MinX(), tileBBox.MinY()})
+		minGeo, err := basic.Reproject(tileSRID, pLayer.srid, geom.Point{tileBBox.MinX(), tileBBox.MinY()})
 		if err != nil {
 			return fmt.Errorf("error converting point: %v ", err)
 		}
 
-		maxGeo, err := basic.FromWebMercator(pLayer.srid, geom.Point{tileBBox.MaxX(), tileBBox.MaxY()})
+		maxGeo, err := basic.Reproject(tileSRID, pLayer.srid, geom.Point{tileBBox.MaxX(), tileBBox.MaxY()})
 		if err != nil {
 			return fmt.Errorf("error converting point: %v ", err)
 		}
@@ -113,11 +119,17 @@ func (p *Provider) TileFeatures(ctx context.Context, layer string, tile provider
 			selectClause += fmt.Sprintf(", l.`%v`", tf)
 		}
 
+		whereClause := fmt.Sprintf("l.`%v` IS NOT NULL AND !BBOX!", pLayer.geomFieldname)
+		if pLayer.where != "" {
+			whereClause += fmt.Sprintf(" AND (%v)", pLayer.where)
+		}
+
 		// l - layer table, si - spatial index
-		qtext = fmt.Sprintf("%v FROM `%v` l JOIN `%v` si ON l.`%v` = si.id WHERE l.`%v` IS NOT NULL AND !BBOX! ORDER BY l.`%v`", selectClause, pLayer.tablename, rtreeTablename, pLayer.idFieldname, pLayer.geomFieldname, pLayer.idFieldname)
+		qtext = fmt.Sprintf("%v FROM `%v` l JOIN `%v` si ON l.`%v` = si.id WHERE %v ORDER BY l.`%v`", selectClause, pLayer.tablename, rtreeTablename, pLayer.idFieldname, whereClause, pLayer.idFieldname)
 
 		z, _, _ := tile.ZXY()
 		qtext = replaceTokens(qtext, uint(z), tileBBox)
+		qtext = queryParams.ReplaceParams(qtext, &args)
 	} else {
 		// If layer was specified via "sql" in config, collect it
 		z, _, _ := tile.ZXY()
@@ -134,10 +146,14 @@ func (p *Provider) TileFeatures(ctx context.Context, layer string, tile provider
 	}
 	defer rows.Close()
 
-	cols, err := rows.Columns()
+	colTypes, err := rows.ColumnTypes()
 	if err != nil {
 		return err
 	}
+	cols := make([]string, len(colTypes))
+	for i := range colTypes {
+		cols[i] = colTypes[i].Name()
+	}
 
 	for rows.Next() {
 		// check if the context cancelled or timed out
@@ -199,25 +215,21 @@ func (p *Provider) TileFeatures(ctx context.Context, layer string, tile provider
 
 			default:
 				// Grab any non-nil, non-id, non-bounding box, & non-geometry column as a tag
-				switch v := vals[i].(type) {
-				case []uint8:
-					asBytes := make([]byte, len(v))
-					for j := 0; j < len(v); j++ {
-						asBytes[j] = v[j]
-					}
-
-					feature.Tags[cols[i]] = string(asBytes)
-				case int64:
-					feature.Tags[cols[i]] = v
-				case string:
-					feature.Tags[cols[i]] = v
-				case float64:
-					feature.Tags[cols[i]] = v
-
-				default:
+				v, err := tagValue(vals[i], colTypes[i].DatabaseTypeName(), pLayer.fieldTypes[cols[i]])
+				if err != nil {
 					// TODO(arolek): return this error?
-					log.Errorf("unexpected type for sqlite column data: %v: %T", cols[i], v)
+					log.Errorf("layer %v, column %v: %v", layer, cols[i], err)
+					continue
+				}
+				if v == nil {
+					continue
+				}
+
+				name := cols[i]
+				if alias, ok := pLayer.aliases[name]; ok {
+					name = alias
 				}
+				feature.Tags[name] = v
 			}
 		}
 
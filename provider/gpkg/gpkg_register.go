@@ -8,8 +8,10 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 
@@ -19,6 +21,7 @@ import (
 	"github.com/go-spatial/geom"
 	"github.com/go-spatial/tegola"
 	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/env"
 	"github.com/go-spatial/tegola/internal/log"
 	"github.com/go-spatial/tegola/provider"
 )
@@ -27,6 +30,7 @@ var colFinder *regexp.Regexp
 
 func init() {
 	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, Cleanup)
+	provider.MVTRegister(provider.TypeMvt.Prefix()+Name, NewMVTTileProvider, Cleanup)
 	colFinder = regexp.MustCompile(`^(([a-zA-Z_][a-zA-Z0-9_]*)|"([^"]+)")\s`)
 }
 
@@ -221,6 +225,88 @@ func featureTableMetaData(gpkg *sql.DB) (map[string]featureTableDetails, error)
 	return geomTableDetails, nil
 }
 
+// columnFieldTypes reads the field types of the columns from the field_types
+// config of a layer. Field types of fields which are not columns are rejected.
+func columnFieldTypes(conf dict.Dicter, cols []string) (map[string]string, error) {
+	for _, field := range dictKeys(conf) {
+		if !slices.Contains(cols, field) {
+			return nil, ErrUnknownField{Field: field}
+		}
+	}
+
+	types := make(map[string]string)
+	for _, col := range cols {
+		var typ string
+		typ, err := conf.String(col, &typ)
+		if err != nil {
+			return nil, err
+		}
+		if typ == "" {
+			continue
+		}
+		if !fieldTypes[typ] {
+			return nil, fmt.Errorf("unsupported field type (%v) for column %v", typ, col)
+		}
+		types[col] = typ
+	}
+	return types, nil
+}
+
+// dictKeys returns the sorted keys of a config table
+func dictKeys(d dict.Dicter) []string {
+	var keys []string
+	switch d := d.(type) {
+	case dict.Dict:
+		keys = slices.Collect(maps.Keys(d))
+	case env.Dict:
+		keys = slices.Collect(maps.Keys(d))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// queryColumns returns the names of the columns of an SQL query
+func queryColumns(db *sql.DB, qtext string) ([]string, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT * FROM (%v) LIMIT 0;", qtext))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return rows.Columns()
+}
+
+// dataColumnAliases reads the names of the columns of a table in the
+// gpkg_data_columns table of the schema extension. No aliases are returned
+// when the GeoPackage doesn't use the extension.
+func dataColumnAliases(db *sql.DB, tablename string) (map[string]string, error) {
+	var n int
+	err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'gpkg_data_columns';").Scan(&n)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, nil
+	}
+
+	rows, err := db.Query("SELECT column_name, name FROM gpkg_data_columns WHERE table_name = ? AND name IS NOT NULL AND name != '';", tablename)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := make(map[string]string)
+	for rows.Next() {
+		var col, name string
+		if err = rows.Scan(&col, &name); err != nil {
+			return nil, err
+		}
+		aliases[col] = name
+	}
+
+	return aliases, rows.Err()
+}
+
 func NewTileProvider(config dict.Dicter, maps []provider.Map) (provider.Tiler, error) {
 
 	log.Debugf("config: %v", config)
@@ -305,9 +391,34 @@ func NewTileProvider(config dict.Dicter, maps []provider.Map) (provider.Tiler, e
 			return nil, fmt.Errorf("for layer (%v) %v, %q field had the following error: %v", i, layerName, ConfigKeyFields, err)
 		}
 
+		var where string
+		where, err = layerConf.String(ConfigKeyWhere, &where)
+		if err != nil {
+			return nil, fmt.Errorf("for layer (%v) %v : %v", i, layerName, err)
+		}
+		if where != "" && errTable != nil {
+			return nil, fmt.Errorf("for layer (%v) %v, %q is only supported with %q", i, layerName, ConfigKeyWhere, ConfigKeyTableName)
+		}
+
+		var columnAliases bool
+		columnAliases, err = layerConf.Bool(ConfigKeyColumnAliases, &columnAliases)
+		if err != nil {
+			return nil, fmt.Errorf("for layer (%v) %v : %v", i, layerName, err)
+		}
+		if columnAliases && errTable != nil {
+			return nil, fmt.Errorf("for layer (%v) %v, %q is only supported with %q", i, layerName, ConfigKeyColumnAliases, ConfigKeyTableName)
+		}
+
+		fieldTypesConf, err := layerConf.Map(ConfigKeyFieldTypes)
+		if err != nil {
+			return nil, fmt.Errorf("for layer (%v) %v, %q field had the following error: %v", i, layerName, ConfigKeyFieldTypes, err)
+		}
+		_, hasFieldTypes := layerConf.Interface(ConfigKeyFieldTypes)
+
 		// layer container. will be added to the provider after it's configured
 		layer := Layer{
-			name: layerName,
+			name:  layerName,
+			where: where,
 		}
 
 		if errTable == nil { // layerConf[ConfigKeyTableName] exists
@@ -329,6 +440,18 @@ func NewTileProvider(config dict.Dicter, maps []provider.Map) (provider.Tiler, e
 			layer.srid = d.srid
 			layer.bbox = *d.bbox
 
+			if hasFieldTypes {
+				if layer.fieldTypes, err = columnFieldTypes(fieldTypesConf, d.colNames); err != nil {
+					return nil, fmt.Errorf("for layer (%v) %v, %q field had the following error: %v", i, layerName, ConfigKeyFieldTypes, err)
+				}
+			}
+
+			if columnAliases {
+				if layer.aliases, err = dataColumnAliases(db, tablename); err != nil {
+					return nil, fmt.Errorf("for layer (%v) %v, reading the column aliases: %v", i, layerName, err)
+				}
+			}
+
 		} else { // layerConf[ConfigKeySQL] exists
 			var customSQL string
 			customSQL, err = layerConf.String(ConfigKeySQL, &customSQL)
@@ -389,6 +512,16 @@ func NewTileProvider(config dict.Dicter, maps []provider.Map) (provider.Tiler, e
 			layer.srid = uint64(h.SRSId())
 			layer.geomFieldname = DefaultGeomFieldName
 			layer.idFieldname = DefaultIDFieldName
+
+			if hasFieldTypes {
+				cols, err := queryColumns(db, customSQL)
+				if err != nil {
+					return nil, fmt.Errorf("layer '%v' problem executing custom SQL: %v", layerName, err)
+				}
+				if layer.fieldTypes, err = columnFieldTypes(fieldTypesConf, cols); err != nil {
+					return nil, fmt.Errorf("for layer (%v) %v, %q field had the following error: %v", i, layerName, ConfigKeyFieldTypes, err)
+				}
+			}
 		}
 
 		p.layers[layer.name] = layer
@@ -401,11 +534,14 @@ func NewTileProvider(config dict.Dicter, maps []provider.Map) (provider.Tiler, e
 }
 
 // reference to all instantiated providers
-var providers []Provider
+var (
+	providers    []Provider
+	mvtProviders []*MVTProvider
+)
 
-// Cleanup will close all database connections and destroy all previously instantiated Provider instances
+// Cleanup will close all database connections and destroy all previously instantiated Provider and MVTProvider instances
 func Cleanup() {
-	if len(providers) > 0 {
+	if len(providers) > 0 || len(mvtProviders) > 0 {
 		log.Infof("cleaning up gpkg providers")
 	}
 
@@ -414,6 +550,12 @@ func Cleanup() {
 			log.Errorf("err closing connection: %v", err)
 		}
 	}
+	for i := range mvtProviders {
+		if err := mvtProviders[i].Close(); err != nil {
+			log.Errorf("err closing connection: %v", err)
+		}
+	}
 
 	providers = make([]Provider, 0)
+	mvtProviders = nil
 }
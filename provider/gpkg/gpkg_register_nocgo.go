@@ -8,4 +8,8 @@ func NewTileProvider(config map[string]interface{}) (provider.Tiler, error) {
 	return nil, provider.ErrUnsupported
 }
 
+func NewMVTTileProvider(config map[string]interface{}) (provider.MVTTiler, error) {
+	return nil, provider.ErrUnsupported
+}
+
 func Cleanup() {}
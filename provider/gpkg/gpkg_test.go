@@ -5,14 +5,18 @@ package gpkg_test
 
 import (
 	"context"
+	"database/sql"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"math"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
 	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/wkb"
 	"github.com/go-spatial/geom/slippy"
 	"github.com/go-spatial/tegola"
 	"github.com/go-spatial/tegola/dict"
@@ -623,3 +627,197 @@ func TestOpenNonExistantFile(t *testing.T) {
 	}
 
 }
+
+// gpkgGeometry encodes a point to a GeoPackage geometry blob, without envelope
+func gpkgGeometry(t *testing.T, p geom.Point, srid int32) []byte {
+	b, err := wkb.EncodeBytes(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h := []byte{'G', 'P', 0, 0x01, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(h[4:], uint32(srid))
+	return append(h, b...)
+}
+
+// createShopsGPKG creates a GeoPackage with a shops feature table, whose
+// columns have aliases in the gpkg_data_columns table
+func createShopsGPKG(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "shops.gpkg")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	stmts := []string{
+		`CREATE TABLE gpkg_contents (table_name TEXT NOT NULL PRIMARY KEY, data_type TEXT NOT NULL, identifier TEXT, min_x DOUBLE, min_y DOUBLE, max_x DOUBLE, max_y DOUBLE, srs_id INTEGER)`,
+		`CREATE TABLE gpkg_geometry_columns (table_name TEXT NOT NULL, column_name TEXT NOT NULL, geometry_type_name TEXT NOT NULL, srs_id INTEGER NOT NULL, z TINYINT NOT NULL, m TINYINT NOT NULL)`,
+		`CREATE TABLE gpkg_data_columns (table_name TEXT NOT NULL, column_name TEXT NOT NULL, name TEXT, title TEXT, description TEXT, mime_type TEXT, constraint_name TEXT)`,
+		`CREATE TABLE shops (fid INTEGER PRIMARY KEY AUTOINCREMENT, geom POINT, name TEXT, kind TEXT, is_open BOOLEAN, opened DATE, updated DATETIME, logo BLOB, rating TEXT)`,
+		`CREATE VIRTUAL TABLE rtree_shops_geom USING rtree(id, minx, maxx, miny, maxy)`,
+		`INSERT INTO gpkg_contents VALUES ('shops', 'features', 'shops', 1, 1, 3, 3, 4326)`,
+		`INSERT INTO gpkg_geometry_columns VALUES ('shops', 'geom', 'POINT', 4326, 0, 0)`,
+		`INSERT INTO gpkg_data_columns VALUES ('shops', 'kind', 'Kind of shop', NULL, NULL, NULL, NULL), ('shops', 'is_open', '', NULL, NULL, NULL, NULL)`,
+	}
+	for _, stmt := range stmts {
+		if _, err = db.Exec(stmt); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	shops := []struct {
+		x, y float64
+		vals []interface{}
+	}{
+		{1, 1, []interface{}{"Bakery", "food", 1, "2020-01-02", "2021-03-04T05:06:07.890Z", []byte{0x01, 0xff}, "4.5"}},
+		{2, 2, []interface{}{"Books", "books", 0, "soon", nil, nil, "3"}},
+		{3, 3, []interface{}{"Grocer", "food", 1, nil, nil, nil, nil}},
+	}
+	for i, shop := range shops {
+		args := append([]interface{}{i + 1, gpkgGeometry(t, geom.Point{shop.x, shop.y}, 4326)}, shop.vals...)
+		if _, err = db.Exec(`INSERT INTO shops VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`, args...); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err = db.Exec(`INSERT INTO rtree_shops_geom VALUES (?, ?, ?, ?, ?)`, i+1, shop.x, shop.x, shop.y, shop.y); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	return path
+}
+
+func TestTileFeaturesAttributes(t *testing.T) {
+	type tcase struct {
+		layer        map[string]interface{}
+		params       provider.Params
+		expectedTags map[uint64]map[string]interface{}
+		err          bool
+	}
+
+	path := createShopsGPKG(t)
+	tile := MockTile{
+		Z:    1,
+		srid: tegola.WGS84,
+		bufferedExtent: geom.NewExtent(
+			[2]float64{0, 0},
+			[2]float64{4, 4},
+		),
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			tc.layer["name"] = "shops"
+			p, err := gpkg.NewTileProvider(dict.Dict{
+				"filepath": path,
+				"layers":   []map[string]interface{}{tc.layer},
+			}, nil)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer gpkg.Cleanup()
+
+			tags := make(map[uint64]map[string]interface{})
+			err = p.TileFeatures(context.TODO(), "shops", &tile, tc.params, func(f *provider.Feature) error {
+				tags[f.ID] = f.Tags
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(tags, tc.expectedTags) {
+				t.Errorf("tags, expected %v got %v", tc.expectedTags, tags)
+			}
+		}
+	}
+
+	kindParam := provider.Params{
+		"!KIND!": provider.QueryParameterValue{Token: "!KIND!", SQL: "?", Value: "books"},
+	}
+
+	tests := map[string]tcase{
+		"declared types": {
+			layer: map[string]interface{}{"tablename": "shops", "fields": []string{"is_open", "opened", "updated", "logo", "rating"}},
+			expectedTags: map[uint64]map[string]interface{}{
+				1: {"is_open": true, "opened": "2020-01-02", "updated": "2021-03-04T05:06:07.890Z", "logo": "Af8=", "rating": "4.5"},
+				2: {"is_open": false, "rating": "3"},
+				3: {"is_open": true},
+			},
+		},
+		"field types": {
+			layer: map[string]interface{}{
+				"tablename":   "shops",
+				"fields":      []string{"is_open", "logo", "rating"},
+				"field_types": map[string]interface{}{"is_open": "int", "logo": "hex", "rating": "float"},
+			},
+			expectedTags: map[uint64]map[string]interface{}{
+				1: {"is_open": int64(1), "logo": "01ff", "rating": 4.5},
+				2: {"is_open": int64(0), "rating": 3.0},
+				3: {"is_open": int64(1)},
+			},
+		},
+		"sql field types": {
+			layer: map[string]interface{}{
+				"sql":         "SELECT fid, geom, rating, is_open + 0 AS open FROM shops WHERE fid = 1",
+				"field_types": dict.Dict{"open": "bool", "rating": "float"},
+			},
+			expectedTags: map[uint64]map[string]interface{}{
+				1: {"open": true, "rating": 4.5},
+			},
+		},
+		"column aliases": {
+			layer: map[string]interface{}{"tablename": "shops", "fields": []string{"name", "kind", "is_open"}, "column_aliases": true},
+			expectedTags: map[uint64]map[string]interface{}{
+				1: {"name": "Bakery", "Kind of shop": "food", "is_open": true},
+				2: {"name": "Books", "Kind of shop": "books", "is_open": false},
+				3: {"name": "Grocer", "Kind of shop": "food", "is_open": true},
+			},
+		},
+		"where": {
+			layer: map[string]interface{}{"tablename": "shops", "fields": []string{"name"}, "where": "l.kind = 'food' AND !ZOOM! > 0"},
+			expectedTags: map[uint64]map[string]interface{}{
+				1: {"name": "Bakery"},
+				3: {"name": "Grocer"},
+			},
+		},
+		"where with params": {
+			layer:  map[string]interface{}{"tablename": "shops", "fields": []string{"name"}, "where": "l.kind = !KIND!"},
+			params: kindParam,
+			expectedTags: map[uint64]map[string]interface{}{
+				2: {"name": "Books"},
+			},
+		},
+		"where with sql": {
+			layer: map[string]interface{}{"sql": "SELECT fid, geom FROM shops", "where": "kind = 'food'"},
+			err:   true,
+		},
+		"column aliases with sql": {
+			layer: map[string]interface{}{"sql": "SELECT fid, geom FROM shops", "column_aliases": true},
+			err:   true,
+		},
+		"unsupported field type": {
+			layer: map[string]interface{}{"tablename": "shops", "field_types": map[string]interface{}{"name": "uuid"}},
+			err:   true,
+		},
+		"unknown field type column": {
+			layer: map[string]interface{}{"tablename": "shops", "field_types": map[string]interface{}{"ratting": "float"}},
+			err:   true,
+		},
+		"unknown sql field type column": {
+			layer: map[string]interface{}{
+				"sql":         "SELECT fid, geom, rating FROM shops",
+				"field_types": dict.Dict{"is_open": "bool"},
+			},
+			err: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -13,6 +13,12 @@ type Layer struct {
 	srid          uint64
 	bbox          geom.Extent
 	sql           string
+	// where is the filter of the features of tablename layers
+	where string
+	// fieldTypes maps the columns to the types their values are converted to
+	fieldTypes map[string]string
+	// aliases maps the columns to the names of their tags
+	aliases map[string]string
 }
 
 func (l Layer) Name() string            { return l.name }
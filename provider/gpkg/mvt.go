@@ -0,0 +1,356 @@
+//go:build cgo
+// +build cgo
+
+package gpkg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/internal/mvtlayers"
+)
+
+// the data type of the vector tile tables in gpkg_contents
+const dataTypeVectorTiles = "vector-tiles"
+
+// tolerance is the relative tolerance used to match the tiles of the maps
+// with the tiles of the tile matrices
+const tolerance = 1e-6
+
+// tileMatrix is a zoom level of the tile matrix set of a tile table
+type tileMatrix struct {
+	zoomLevel     int64
+	width, height int64
+	// the width and height of the tiles, in the units of the srs
+	spanX, spanY float64
+}
+
+// MVTProvider serves the tiles of a tile table of the GeoPackage vector
+// tiles extension
+type MVTProvider struct {
+	// path to the geopackage file
+	Filepath string
+	// the tile table and its layers
+	tablename string
+	layers    []Layer
+	srid      uint64
+	// the bounds of the tile matrix set, the tile matrices start at the top
+	// left corner
+	minX, maxY float64
+	matrices   []tileMatrix
+	// reference to the database connection
+	db *sql.DB
+}
+
+// NewMVTTileProvider instantiates a provider serving the tiles of a tile
+// table of the GeoPackage vector tiles extension. The tiles of the maps are
+// matched with the tiles of the tile matrices of the table, so the tile
+// matrix set must be in the SRID of the tile grid of the maps, and aligned
+// with it. The config expects the following params:
+//
+//	filepath (string): [Required] the path to the GeoPackage file
+//	tablename (string): [Optional] the tile table, defaults to the only vector tiles table of the GeoPackage
+func NewMVTTileProvider(config dict.Dicter, _ []provider.Map) (provider.MVTTiler, error) {
+	filepath, err := config.String(ConfigKeyFilePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if filepath == "" {
+		return nil, ErrInvalidFilePath{filepath}
+	}
+
+	// check the file exists
+	if _, err := os.Stat(filepath); os.IsNotExist(err) {
+		return nil, ErrInvalidFilePath{filepath}
+	}
+
+	var tablename string
+	tablename, err = config.String(ConfigKeyTableName, &tablename)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := newMVTProvider(db, tablename)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	p.Filepath = filepath
+
+	// track the provider so we can clean it up later
+	mvtProviders = append(mvtProviders, p)
+
+	return p, nil
+}
+
+// newMVTProvider reads the tile matrix set and the layers of a tile table
+func newMVTProvider(db *sql.DB, tablename string) (*MVTProvider, error) {
+	if tablename == "" {
+		var err error
+		if tablename, err = vectorTilesTable(db); err != nil {
+			return nil, err
+		}
+	}
+
+	p := MVTProvider{
+		tablename: tablename,
+		db:        db,
+	}
+
+	var srsID int64
+	var organization sql.NullString
+	var coordsysID sql.NullInt64
+	err := db.QueryRow(`
+		SELECT
+			tms.srs_id, tms.min_x, tms.max_y, srs.organization, srs.organization_coordsys_id
+		FROM
+			gpkg_tile_matrix_set tms LEFT JOIN gpkg_spatial_ref_sys srs ON tms.srs_id = srs.srs_id
+		WHERE
+			tms.table_name = ?;`, tablename).Scan(&srsID, &p.minX, &p.maxY, &organization, &coordsysID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("gpkg: table %q has no tile matrix set", tablename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gpkg: reading the tile matrix set of %q: %v", tablename, err)
+	}
+
+	// the srs ids of a GeoPackage are not necessarily EPSG codes
+	p.srid = uint64(srsID)
+	if strings.EqualFold(organization.String, "EPSG") && coordsysID.Valid {
+		p.srid = uint64(coordsysID.Int64)
+	}
+
+	if p.matrices, err = tileMatrices(db, tablename); err != nil {
+		return nil, err
+	}
+	if len(p.matrices) == 0 {
+		return nil, fmt.Errorf("gpkg: table %q has no tile matrix", tablename)
+	}
+
+	if err = checkTileFormat(db, tablename); err != nil {
+		return nil, err
+	}
+
+	names, err := vectorTilesLayers(db, tablename)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		p.layers = append(p.layers, Layer{name: name, srid: p.srid})
+	}
+
+	return &p, nil
+}
+
+// vectorTilesTable returns the vector tiles table of a GeoPackage, which
+// must have only one
+func vectorTilesTable(db *sql.DB) (string, error) {
+	rows, err := db.Query("SELECT table_name FROM gpkg_contents WHERE data_type = ?;", dataTypeVectorTiles)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err = rows.Scan(&table); err != nil {
+			return "", err
+		}
+		tables = append(tables, table)
+	}
+	if err = rows.Err(); err != nil {
+		return "", err
+	}
+
+	switch len(tables) {
+	case 0:
+		return "", fmt.Errorf("gpkg: no %v table in gpkg_contents", dataTypeVectorTiles)
+	case 1:
+		return tables[0], nil
+	default:
+		return "", fmt.Errorf("gpkg: %q is required to choose one of the %v tables: %v", ConfigKeyTableName, dataTypeVectorTiles, strings.Join(tables, ", "))
+	}
+}
+
+// tileMatrices reads the tile matrices of a tile table
+func tileMatrices(db *sql.DB, tablename string) ([]tileMatrix, error) {
+	rows, err := db.Query(`
+		SELECT
+			zoom_level, matrix_width, matrix_height, tile_width, tile_height, pixel_x_size, pixel_y_size
+		FROM
+			gpkg_tile_matrix
+		WHERE
+			table_name = ?
+		ORDER BY
+			zoom_level;`, tablename)
+	if err != nil {
+		return nil, fmt.Errorf("gpkg: reading the tile matrices of %q: %v", tablename, err)
+	}
+	defer rows.Close()
+
+	var matrices []tileMatrix
+	for rows.Next() {
+		var (
+			m                      tileMatrix
+			tileWidth, tileHeight  int64
+			pixelXSize, pixelYSize float64
+		)
+		if err = rows.Scan(&m.zoomLevel, &m.width, &m.height, &tileWidth, &tileHeight, &pixelXSize, &pixelYSize); err != nil {
+			return nil, err
+		}
+		m.spanX = float64(tileWidth) * pixelXSize
+		m.spanY = float64(tileHeight) * pixelYSize
+		matrices = append(matrices, m)
+	}
+
+	return matrices, rows.Err()
+}
+
+// checkTileFormat checks the tiles of a table are Mapbox vector tiles when
+// the extensions of the table are registered. GeoJSON tiles are not supported.
+func checkTileFormat(db *sql.DB, tablename string) error {
+	var n int
+	err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'gpkg_extensions';").Scan(&n)
+	if err != nil || n == 0 {
+		return err
+	}
+
+	var mapbox, geojson int
+	err = db.QueryRow(`
+		SELECT
+			count(CASE WHEN extension_name LIKE '%_vector_tiles_mapbox' THEN 1 END),
+			count(CASE WHEN extension_name LIKE '%_vector_tiles_geojson' THEN 1 END)
+		FROM
+			gpkg_extensions
+		WHERE
+			table_name = ?;`, tablename).Scan(&mapbox, &geojson)
+	if err != nil {
+		return err
+	}
+	if geojson > 0 && mapbox == 0 {
+		return fmt.Errorf("gpkg: the tiles of %q are GeoJSON vector tiles, only Mapbox vector tiles are supported", tablename)
+	}
+
+	return nil
+}
+
+// vectorTilesLayers reads the names of the layers of a tile table from the
+// gpkgext_vt_layers table
+func vectorTilesLayers(db *sql.DB, tablename string) ([]string, error) {
+	rows, err := db.Query("SELECT name FROM gpkgext_vt_layers WHERE table_name = ? ORDER BY id;", tablename)
+	if err != nil {
+		return nil, fmt.Errorf("gpkg: reading the layers of %q from gpkgext_vt_layers: %v", tablename, err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("gpkg: table %q has no layers in gpkgext_vt_layers", tablename)
+	}
+
+	return names, nil
+}
+
+// Layers returns the layers of the tile table
+func (p *MVTProvider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, len(p.layers))
+	for i := range p.layers {
+		ls[i] = p.layers[i]
+	}
+	return ls, nil
+}
+
+// tileIndex finds the tile of the tile matrices with the extent of the tile
+func (p *MVTProvider) tileIndex(tile provider.Tile) (zoomLevel, column, row int64, ok bool) {
+	extent, srid := tile.Extent()
+	if srid != p.srid {
+		return 0, 0, 0, false
+	}
+
+	width, height := extent.MaxX()-extent.MinX(), extent.MaxY()-extent.MinY()
+	for _, m := range p.matrices {
+		if math.Abs(m.spanX-width) > tolerance*width || math.Abs(m.spanY-height) > tolerance*height {
+			continue
+		}
+
+		// the tile matrices start at the top left corner of the tile matrix set
+		col := (extent.MinX() - p.minX) / m.spanX
+		r := (p.maxY - extent.MaxY()) / m.spanY
+		if math.Abs(col-math.Round(col)) > tolerance || math.Abs(r-math.Round(r)) > tolerance {
+			// the tile matrix is not aligned with the grid of the tile
+			return 0, 0, 0, false
+		}
+
+		column, row = int64(math.Round(col)), int64(math.Round(r))
+		if column < 0 || row < 0 || column >= m.width || row >= m.height {
+			return 0, 0, 0, false
+		}
+		return m.zoomLevel, column, row, true
+	}
+
+	return 0, 0, 0, false
+}
+
+// MVTForLayers returns the tile from the tile table, reduced to the
+// requested layers in the requested order. Layers are renamed to their
+// MVTName. If the table does not contain the tile, an empty tile is returned.
+func (p *MVTProvider) MVTForLayers(ctx context.Context, tile provider.Tile, _ provider.Params, layers []provider.Layer) ([]byte, error) {
+	z, x, y := tile.ZXY()
+
+	zoomLevel, column, row, ok := p.tileIndex(tile)
+	if !ok {
+		log.Debugf("gpkg: no tile of %v matches the tile (%v/%v/%v)", p.tablename, z, x, y)
+		return []byte{}, nil
+	}
+
+	var data []byte
+	qtext := fmt.Sprintf("SELECT tile_data FROM `%v` WHERE zoom_level = ? AND tile_column = ? AND tile_row = ?;", p.tablename)
+	err := p.db.QueryRowContext(ctx, qtext, zoomLevel, column, row).Scan(&data)
+	if err == sql.ErrNoRows {
+		return []byte{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return []byte{}, nil
+	}
+
+	if data, err = mvtlayers.Gunzip(data); err != nil {
+		return nil, fmt.Errorf("gpkg: decompressing tile (%v/%v/%v): %w", z, x, y, err)
+	}
+
+	if data, err = mvtlayers.Select(data, layers); err != nil {
+		return nil, fmt.Errorf("gpkg: decoding tile (%v/%v/%v): %w", z, x, y, err)
+	}
+
+	return data, nil
+}
+
+// Close will close the MVTProvider's database connection
+func (p *MVTProvider) Close() error {
+	return p.db.Close()
+}
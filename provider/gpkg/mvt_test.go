@@ -0,0 +1,306 @@
+//go:build cgo
+// +build cgo
+
+package gpkg_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/gpkg"
+	"github.com/golang/protobuf/proto"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+)
+
+// webMercatorMax is the max x and y of the web mercator tile grid
+const webMercatorMax = 20037508.342789244
+
+// vectorTileData returns a tile with an empty layer for each of the names
+func vectorTileData(t *testing.T, gzipped bool, names ...string) []byte {
+	var vtile vectorTile.Tile
+	for _, name := range names {
+		vtile.Layers = append(vtile.Layers, &vectorTile.Tile_Layer{
+			Version: proto.Uint32(2),
+			Name:    proto.String(name),
+			Extent:  proto.Uint32(4096),
+		})
+	}
+
+	data, err := proto.Marshal(&vtile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !gzipped {
+		return data
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(data)
+	gz.Close()
+	return buf.Bytes()
+}
+
+// layerNames decodes the tile and returns the names of its layers
+func layerNames(t *testing.T, data []byte) (names []string) {
+	var vtile vectorTile.Tile
+	if err := proto.Unmarshal(data, &vtile); err != nil {
+		t.Fatalf("unexpected error decoding tile: %v", err)
+	}
+	for _, l := range vtile.Layers {
+		names = append(names, l.GetName())
+	}
+	return names
+}
+
+// tileTable is a vector tiles table, with the water and roads layers
+type tileTable struct {
+	name  string
+	srsID int
+	// the bounds of the tile matrix set
+	minX, minY, maxX, maxY float64
+	// the zoom levels of the tile matrices, with their number of tiles
+	// along each axis
+	matrices map[int]int
+	// the tiles keyed by zoom level, column and row
+	tiles     map[[3]int][]byte
+	extension string
+}
+
+// createTilesGPKG creates a GeoPackage with the tile tables
+func createTilesGPKG(t *testing.T, tables ...tileTable) string {
+	path := filepath.Join(t.TempDir(), "tiles.gpkg")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	exec := func(qtext string, args ...interface{}) {
+		if _, err := db.Exec(qtext, args...); err != nil {
+			t.Fatalf("unexpected error: %v: %v", qtext, err)
+		}
+	}
+
+	exec(`CREATE TABLE gpkg_spatial_ref_sys (srs_name TEXT NOT NULL, srs_id INTEGER NOT NULL PRIMARY KEY, organization TEXT NOT NULL, organization_coordsys_id INTEGER NOT NULL, definition TEXT NOT NULL, description TEXT)`)
+	// the srs ids of a GeoPackage can differ from the EPSG codes
+	exec(`INSERT INTO gpkg_spatial_ref_sys VALUES ('WGS 84 / Pseudo-Mercator', 3857, 'EPSG', 3857, '', NULL), ('WGS 84 / Pseudo-Mercator', 100, 'epsg', 3857, '', NULL)`)
+	exec(`CREATE TABLE gpkg_contents (table_name TEXT NOT NULL PRIMARY KEY, data_type TEXT NOT NULL, identifier TEXT, min_x DOUBLE, min_y DOUBLE, max_x DOUBLE, max_y DOUBLE, srs_id INTEGER)`)
+	exec(`CREATE TABLE gpkg_tile_matrix_set (table_name TEXT NOT NULL PRIMARY KEY, srs_id INTEGER NOT NULL, min_x DOUBLE NOT NULL, min_y DOUBLE NOT NULL, max_x DOUBLE NOT NULL, max_y DOUBLE NOT NULL)`)
+	exec(`CREATE TABLE gpkg_tile_matrix (table_name TEXT NOT NULL, zoom_level INTEGER NOT NULL, matrix_width INTEGER NOT NULL, matrix_height INTEGER NOT NULL, tile_width INTEGER NOT NULL, tile_height INTEGER NOT NULL, pixel_x_size DOUBLE NOT NULL, pixel_y_size DOUBLE NOT NULL)`)
+	exec(`CREATE TABLE gpkg_extensions (table_name TEXT, column_name TEXT, extension_name TEXT NOT NULL, definition TEXT NOT NULL, scope TEXT NOT NULL)`)
+	exec(`CREATE TABLE gpkgext_vt_layers (id INTEGER PRIMARY KEY AUTOINCREMENT, table_name TEXT NOT NULL, name TEXT NOT NULL, description TEXT, minzoom INTEGER, maxzoom INTEGER, attributes_table_name TEXT)`)
+
+	for _, tbl := range tables {
+		exec(fmt.Sprintf(`CREATE TABLE %v (id INTEGER PRIMARY KEY AUTOINCREMENT, zoom_level INTEGER NOT NULL, tile_column INTEGER NOT NULL, tile_row INTEGER NOT NULL, tile_data BLOB NOT NULL)`, tbl.name))
+		exec(`INSERT INTO gpkg_contents (table_name, data_type, srs_id) VALUES (?, 'vector-tiles', ?)`, tbl.name, tbl.srsID)
+		exec(`INSERT INTO gpkg_tile_matrix_set VALUES (?, ?, ?, ?, ?, ?)`, tbl.name, tbl.srsID, tbl.minX, tbl.minY, tbl.maxX, tbl.maxY)
+		for zoom, n := range tbl.matrices {
+			// 512 pixels tiles
+			exec(`INSERT INTO gpkg_tile_matrix VALUES (?, ?, ?, ?, 512, 512, ?, ?)`, tbl.name, zoom, n, n, (tbl.maxX-tbl.minX)/float64(n)/512, (tbl.maxY-tbl.minY)/float64(n)/512)
+		}
+		for zxy, data := range tbl.tiles {
+			exec(fmt.Sprintf(`INSERT INTO %v (zoom_level, tile_column, tile_row, tile_data) VALUES (?, ?, ?, ?)`, tbl.name), zxy[0], zxy[1], zxy[2], data)
+		}
+		exec(`INSERT INTO gpkg_extensions VALUES (?, 'tile_data', ?, '', 'read-write')`, tbl.name, tbl.extension)
+		exec(`INSERT INTO gpkgext_vt_layers (table_name, name) VALUES (?, 'water'), (?, 'roads')`, tbl.name, tbl.name)
+	}
+
+	return path
+}
+
+func TestMVTForLayers(t *testing.T) {
+	type tcase struct {
+		table    tileTable
+		tile     provider.Tile
+		expected []string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			path := createTilesGPKG(t, tc.table)
+
+			p, err := gpkg.NewMVTTileProvider(dict.Dict{"filepath": path}, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer gpkg.Cleanup()
+
+			data, err := p.MVTForLayers(context.Background(), tc.tile, nil, []provider.Layer{
+				{Name: "roads", MVTName: "streets"},
+				{Name: "water"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(tc.expected) == 0 {
+				if len(data) != 0 {
+					t.Errorf("expected an empty tile, got %v bytes", len(data))
+				}
+				return
+			}
+			if got := layerNames(t, data); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("layers, expected %v got %v", tc.expected, got)
+			}
+		}
+	}
+
+	world := tileTable{
+		name:  "world",
+		srsID: 3857,
+		minX:  -webMercatorMax, minY: -webMercatorMax, maxX: webMercatorMax, maxY: webMercatorMax,
+		matrices: map[int]int{0: 1, 1: 2, 2: 4},
+		// the tiles hold different layers to tell them apart
+		tiles: map[[3]int][]byte{
+			{1, 0, 0}: vectorTileData(t, true, "water", "roads", "land"),
+			{2, 3, 1}: vectorTileData(t, false, "roads"),
+		},
+		extension: "im_vector_tiles_mapbox",
+	}
+	// the north east quarter of the world, with zoom levels starting at 0
+	northEast := tileTable{
+		name:  "north_east",
+		srsID: 100,
+		minX:  0, minY: 0, maxX: webMercatorMax, maxY: webMercatorMax,
+		matrices: map[int]int{0: 1, 1: 2},
+		tiles: map[[3]int][]byte{
+			{0, 0, 0}: vectorTileData(t, false, "roads", "water"),
+			{1, 1, 1}: vectorTileData(t, true, "water"),
+		},
+		extension: "gpkg_vector_tiles_mapbox",
+	}
+
+	tests := map[string]tcase{
+		"gzipped": {
+			table:    world,
+			tile:     provider.NewTile(1, 0, 0, 64, tegola.WebMercator),
+			expected: []string{"streets", "water"},
+		},
+		"uncompressed": {
+			table:    world,
+			tile:     provider.NewTile(2, 3, 1, 64, tegola.WebMercator),
+			expected: []string{"streets"},
+		},
+		"missing tile": {
+			table: world,
+			tile:  provider.NewTile(1, 1, 1, 64, tegola.WebMercator),
+		},
+		"zoom without tile matrix": {
+			table: world,
+			tile:  provider.NewTile(3, 0, 0, 64, tegola.WebMercator),
+		},
+		"offset tile matrix set": {
+			table:    northEast,
+			tile:     provider.NewTile(1, 1, 0, 64, tegola.WebMercator),
+			expected: []string{"streets", "water"},
+		},
+		"offset tile matrix set zoom": {
+			table:    northEast,
+			tile:     provider.NewTile(2, 3, 1, 64, tegola.WebMercator),
+			expected: []string{"water"},
+		},
+		"outside the tile matrix set": {
+			table: northEast,
+			tile:  provider.NewTile(1, 0, 0, 64, tegola.WebMercator),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestNewMVTTileProvider(t *testing.T) {
+	type tcase struct {
+		tables    []tileTable
+		tablename string
+		err       bool
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			path := createTilesGPKG(t, tc.tables...)
+
+			config := dict.Dict{"filepath": path}
+			if tc.tablename != "" {
+				config["tablename"] = tc.tablename
+			}
+			p, err := gpkg.NewMVTTileProvider(config, nil)
+			defer gpkg.Cleanup()
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			layers, err := p.Layers()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var names []string
+			for _, l := range layers {
+				if l.SRID() != tegola.WebMercator {
+					t.Errorf("srid, expected %v got %v", tegola.WebMercator, l.SRID())
+				}
+				names = append(names, l.Name())
+			}
+			if expected := []string{"water", "roads"}; !reflect.DeepEqual(names, expected) {
+				t.Errorf("layers, expected %v got %v", expected, names)
+			}
+		}
+	}
+
+	table := func(name, extension string) tileTable {
+		return tileTable{
+			name:  name,
+			srsID: 3857,
+			minX:  -webMercatorMax, minY: -webMercatorMax, maxX: webMercatorMax, maxY: webMercatorMax,
+			matrices:  map[int]int{0: 1},
+			extension: extension,
+		}
+	}
+
+	tests := map[string]tcase{
+		"one table": {
+			tables: []tileTable{table("tiles", "im_vector_tiles_mapbox")},
+		},
+		"tablename": {
+			tables:    []tileTable{table("tiles", "im_vector_tiles_mapbox"), table("other", "im_vector_tiles_mapbox")},
+			tablename: "other",
+		},
+		"several tables": {
+			tables: []tileTable{table("tiles", "im_vector_tiles_mapbox"), table("other", "im_vector_tiles_mapbox")},
+			err:    true,
+		},
+		"no table": {
+			err: true,
+		},
+		"unknown tablename": {
+			tables:    []tileTable{table("tiles", "im_vector_tiles_mapbox")},
+			tablename: "other",
+			err:       true,
+		},
+		"geojson tiles": {
+			tables: []tileTable{table("tiles", "im_vector_tiles_geojson")},
+			err:    true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,53 @@
+package fileprovider
+
+import "fmt"
+
+type ErrMissingLayerName struct {
+	Provider string
+}
+
+func (e ErrMissingLayerName) Error() string {
+	return fmt.Sprintf("%v: layer is missing 'name'", e.Provider)
+}
+
+type ErrInvalidFilePath struct {
+	Provider string
+	FilePath string
+}
+
+func (e ErrInvalidFilePath) Error() string {
+	return fmt.Sprintf("%v: invalid filepath: %v", e.Provider, e.FilePath)
+}
+
+// ErrUnknownField is returned when a field of the config of a layer is not a
+// field of its file
+type ErrUnknownField struct {
+	Provider string
+	Layer    string
+	Field    string
+	FilePath string
+}
+
+func (e ErrUnknownField) Error() string {
+	return fmt.Sprintf("%v: field (%v) of layer (%v) does not exist in %v", e.Provider, e.Field, e.Layer, e.FilePath)
+}
+
+// ErrUnknownSRID is returned when a file does not declare the SRID of its
+// geometries, or declares one which can't be mapped to an EPSG code, and the
+// config of the layer does not set it
+type ErrUnknownSRID struct {
+	Provider string
+	FilePath string
+}
+
+func (e ErrUnknownSRID) Error() string {
+	return fmt.Sprintf("%v: unable to determine the SRID of %v, set 'srid' on the layer", e.Provider, e.FilePath)
+}
+
+type ErrLayerNotFound struct {
+	Layer string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("layer (%v) not found", e.Layer)
+}
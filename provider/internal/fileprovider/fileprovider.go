@@ -0,0 +1,336 @@
+// Package fileprovider implements what the standard providers reading their
+// features from files have in common: the config of the layers, the
+// reprojection of the tile extent to the SRID of the file and the selection
+// of the fields which become the tags of the features. The formats are read
+// by the providers, with a Source for each file.
+package fileprovider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola/basic"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// config keys
+const (
+	ConfigKeyFilePath    = "filepath"
+	ConfigKeyLayers      = "layers"
+	ConfigKeyLayerName   = "name"
+	ConfigKeyFileName    = "filename"
+	ConfigKeyGeomIDField = "id_fieldname"
+	ConfigKeyFields      = "fields"
+	ConfigKeySRID        = "srid"
+)
+
+// Feature is a feature read from a file
+type Feature struct {
+	// Number is the id of the feature when the layer has no id field, the
+	// position of the feature in the file starting at 1 for most formats
+	Number   uint64
+	Geometry geom.Geometry
+	// Properties holds the values of the fields requested from the source.
+	// Null values are nil or left out.
+	Properties map[string]interface{}
+}
+
+// Source reads the features of a file. It must be safe for concurrent use.
+type Source interface {
+	// Fields returns the names of the properties of the features
+	Fields() []string
+	// GeomType returns the type of the geometries, nil when it's unknown
+	GeomType() geom.Geometry
+	// SRID returns the SRID of the geometries, 0 when it's unknown
+	SRID() uint64
+	// Features calls fn for the features with a bounding box intersecting
+	// extent, in the order of the file, until fn returns an error. The
+	// properties of the features are limited to fields.
+	Features(ctx context.Context, extent *geom.Extent, fields []string, fn func(Feature) error) error
+	Close() error
+}
+
+// OpenFunc opens the file at path
+type OpenFunc func(path string) (Source, error)
+
+// Format describes a file format to New
+type Format struct {
+	// Name of the provider, used in the errors
+	Name string
+	// Ext is the extension of the files, i.e. ".shp". It's added to the
+	// name of a layer to find its file when filepath is a directory.
+	Ext  string
+	Open OpenFunc
+}
+
+// Provider is a standard provider with the layers of one or more files
+type Provider struct {
+	// Filepath is the path of the file or the directory of the files
+	Filepath string
+	layers   map[string]Layer
+	sources  []Source
+}
+
+// Layer is a layer of a Provider
+type Layer struct {
+	name     string
+	path     string
+	src      Source
+	geomType geom.Geometry
+	srid     uint64
+	// tagFieldnames are the fields which become the tags of the features
+	tagFieldnames []string
+	idFieldname   string
+	// properties are the fields read from the file
+	properties []string
+}
+
+func (l Layer) Name() string            { return l.name }
+func (l Layer) GeomType() geom.Geometry { return l.geomType }
+func (l Layer) SRID() uint64            { return l.srid }
+
+// New instantiates a provider for the files of format. The config expects
+// the following params:
+//
+//	filepath (string): [Required] the path to a file, or to a directory of files
+//	layers ([]map[string]interface{}): [Required] the layers of the provider, with the params:
+//		name (string): [Required] the name of the layer
+//		filename (string): [Optional] the file of the layer, relative to filepath when it's a directory. Defaults to the name of the layer followed by the extension of the format. Not allowed when filepath is a file.
+//		fields ([]string): [Optional] the fields to include as feature tags. Defaults to all the fields but id_fieldname.
+//		id_fieldname (string): [Optional] the field holding the ids of the features. Defaults to the position of the features in the file.
+//		srid (int): [Optional] the SRID of the geometries, to use when the file does not declare it or to override it
+func New(format Format, config dict.Dicter) (*Provider, error) {
+	path, err := config.String(ConfigKeyFilePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, ErrInvalidFilePath{Provider: format.Name, FilePath: path}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, ErrInvalidFilePath{Provider: format.Name, FilePath: path}
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		return nil, err
+	}
+	if len(layers) == 0 {
+		return nil, fmt.Errorf("%v: at least one layer is required", format.Name)
+	}
+
+	p := Provider{
+		Filepath: path,
+		layers:   make(map[string]Layer, len(layers)),
+	}
+
+	// the sources by path, as several layers can read the same file
+	sources := make(map[string]Source)
+	fail := func(err error) (*Provider, error) {
+		p.Close()
+		return nil, err
+	}
+
+	lyrsSeen := make(map[string]int)
+	for i, layerConf := range layers {
+		layerName := ""
+		layerName, err = layerConf.String(ConfigKeyLayerName, &layerName)
+		if err != nil {
+			return fail(fmt.Errorf("for layer (%v) we got the following error trying to get the layer's name field: %v", i, err))
+		}
+		if layerName == "" {
+			return fail(ErrMissingLayerName{Provider: format.Name})
+		}
+
+		// check if we have already seen this layer
+		if j, ok := lyrsSeen[layerName]; ok {
+			return fail(fmt.Errorf("layer name (%v) is duplicated in both layer %v and layer %v", layerName, i, j))
+		}
+		lyrsSeen[layerName] = i
+
+		filename := ""
+		filename, err = layerConf.String(ConfigKeyFileName, &filename)
+		if err != nil {
+			return fail(fmt.Errorf("for layer (%v) %v : %v", i, layerName, err))
+		}
+
+		layerPath := path
+		switch {
+		case info.IsDir() && filename == "":
+			layerPath = filepath.Join(path, layerName+format.Ext)
+		case info.IsDir():
+			layerPath = filepath.Join(path, filename)
+		case filename != "":
+			return fail(fmt.Errorf("for layer (%v) %v : %q is only allowed when %q is a directory", i, layerName, ConfigKeyFileName, ConfigKeyFilePath))
+		}
+
+		src, ok := sources[layerPath]
+		if !ok {
+			if _, err := os.Stat(layerPath); err != nil {
+				return fail(ErrInvalidFilePath{Provider: format.Name, FilePath: layerPath})
+			}
+			if src, err = format.Open(layerPath); err != nil {
+				return fail(fmt.Errorf("%v: opening %v: %w", format.Name, layerPath, err))
+			}
+			sources[layerPath] = src
+			p.sources = append(p.sources, src)
+		}
+
+		idFieldname := ""
+		idFieldname, err = layerConf.String(ConfigKeyGeomIDField, &idFieldname)
+		if err != nil {
+			return fail(fmt.Errorf("for layer (%v) %v : %v", i, layerName, err))
+		}
+
+		tagFieldnames, err := layerConf.StringSlice(ConfigKeyFields)
+		if err != nil { // empty slices are okay
+			return fail(fmt.Errorf("for layer (%v) %v, %q field had the following error: %v", i, layerName, ConfigKeyFields, err))
+		}
+
+		fields := make(map[string]bool)
+		for _, f := range src.Fields() {
+			fields[f] = true
+		}
+		if idFieldname != "" && !fields[idFieldname] {
+			return fail(ErrUnknownField{Provider: format.Name, Layer: layerName, Field: idFieldname, FilePath: layerPath})
+		}
+		for _, f := range tagFieldnames {
+			if !fields[f] {
+				return fail(ErrUnknownField{Provider: format.Name, Layer: layerName, Field: f, FilePath: layerPath})
+			}
+		}
+		if tagFieldnames == nil {
+			for _, f := range src.Fields() {
+				if f != idFieldname {
+					tagFieldnames = append(tagFieldnames, f)
+				}
+			}
+		}
+
+		srid := 0
+		srid, err = layerConf.Int(ConfigKeySRID, &srid)
+		if err != nil {
+			return fail(fmt.Errorf("for layer (%v) %v : %v", i, layerName, err))
+		}
+		if srid < 0 {
+			return fail(fmt.Errorf("for layer (%v) %v : invalid %q (%v)", i, layerName, ConfigKeySRID, srid))
+		}
+		if srid == 0 {
+			if srid = int(src.SRID()); srid == 0 {
+				return fail(ErrUnknownSRID{Provider: format.Name, FilePath: layerPath})
+			}
+		}
+
+		properties := append([]string(nil), tagFieldnames...)
+		if idFieldname != "" {
+			properties = append(properties, idFieldname)
+		}
+		sort.Strings(properties)
+
+		p.layers[layerName] = Layer{
+			name:          layerName,
+			src:           src,
+			geomType:      src.GeomType(),
+			srid:          uint64(srid),
+			tagFieldnames: tagFieldnames,
+			idFieldname:   idFieldname,
+			properties:    properties,
+		}
+	}
+
+	return &p, nil
+}
+
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+func (p *Provider) TileFeatures(ctx context.Context, layer string, tile provider.Tile, _ provider.Params, fn func(f *provider.Feature) error) error {
+	pLayer, ok := p.layers[layer]
+	if !ok {
+		return ErrLayerNotFound{Layer: layer}
+	}
+
+	// read the tile extent
+	tileBBox, tileSRID := tile.BufferedExtent()
+
+	// check if the SRID of the layer differs from that of the tile
+	if pLayer.srid != tileSRID {
+		minGeo, err := basic.Reproject(tileSRID, pLayer.srid, geom.Point{tileBBox.MinX(), tileBBox.MinY()})
+		if err != nil {
+			return fmt.Errorf("error converting point: %v ", err)
+		}
+
+		maxGeo, err := basic.Reproject(tileSRID, pLayer.srid, geom.Point{tileBBox.MaxX(), tileBBox.MaxY()})
+		if err != nil {
+			return fmt.Errorf("error converting point: %v ", err)
+		}
+
+		tileBBox = geom.NewExtent(minGeo.(geom.Point), maxGeo.(geom.Point))
+	}
+
+	return pLayer.src.Features(ctx, tileBBox, pLayer.properties, func(f Feature) error {
+		// check if the context cancelled or timed out
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		feature := provider.Feature{
+			ID:       f.Number,
+			Geometry: f.Geometry,
+			SRID:     pLayer.srid,
+			Tags:     make(map[string]interface{}, len(pLayer.tagFieldnames)),
+		}
+
+		if pLayer.idFieldname != "" {
+			id, err := provider.ConvertFeatureID(f.Properties[pLayer.idFieldname])
+			if err != nil {
+				log.Errorf("layer (%v) feature %v: invalid id: %v", layer, f.Number, err)
+				return err
+			}
+			feature.ID = id
+		}
+
+		for _, name := range pLayer.tagFieldnames {
+			if v := f.Properties[name]; v != nil {
+				feature.Tags[name] = v
+			}
+		}
+
+		return fn(&feature)
+	})
+}
+
+// Close closes the files of the provider
+func (p *Provider) Close() error {
+	var err error
+	for _, src := range p.sources {
+		if cerr := src.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	p.sources = nil
+	return err
+}
+
+// Intersects reports whether the bounding box of (minx, miny, maxx, maxy)
+// intersects extent. Touching boxes intersect, and a nil extent intersects
+// everything.
+func Intersects(extent *geom.Extent, minx, miny, maxx, maxy float64) bool {
+	if extent == nil {
+		return true
+	}
+	return minx <= extent.MaxX() && maxx >= extent.MinX() &&
+		miny <= extent.MaxY() && maxy >= extent.MinY()
+}
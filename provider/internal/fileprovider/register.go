@@ -0,0 +1,49 @@
+package fileprovider
+
+import (
+	"sync"
+
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// Register registers the standard provider of format, named after the
+// format. The functions the provider is registered with are returned for
+// the package of the format to export.
+func Register(format Format) (provider.InitFunc, provider.CleanupFunc) {
+	r := registry{format: format}
+	provider.Register(provider.TypeStd.Prefix()+format.Name, r.newTileProvider, r.cleanup) //nolint:errcheck
+	return r.newTileProvider, r.cleanup
+}
+
+// registry tracks the providers instantiated for a format
+type registry struct {
+	format Format
+	// providers that have been instantiated. they are closed by cleanup
+	providersLock sync.Mutex
+	providers     []*Provider
+}
+
+func (r *registry) newTileProvider(config dict.Dicter, _ []provider.Map) (provider.Tiler, error) {
+	p, err := New(r.format, config)
+	if err != nil {
+		return nil, err
+	}
+
+	r.providersLock.Lock()
+	r.providers = append(r.providers, p)
+	r.providersLock.Unlock()
+
+	return p, nil
+}
+
+// cleanup closes the files opened by the providers
+func (r *registry) cleanup() {
+	r.providersLock.Lock()
+	defer r.providersLock.Unlock()
+
+	for _, p := range r.providers {
+		p.Close()
+	}
+	r.providers = nil
+}
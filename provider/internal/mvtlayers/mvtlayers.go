@@ -0,0 +1,64 @@
+// Package mvtlayers implements what the MVT providers serving pre-built
+// tiles have in common: the selection of the layers of the maps in the
+// stored tiles.
+package mvtlayers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/go-spatial/tegola/provider"
+	"github.com/golang/protobuf/proto"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+)
+
+// Gunzip decompresses data if it's gzip compressed, otherwise data is returned as is
+func Gunzip(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// Select decodes an uncompressed tile and encodes it again reduced to the
+// requested layers, in the requested order. Layers are renamed to their
+// MVTName.
+func Select(data []byte, layers []provider.Layer) ([]byte, error) {
+	var vtile vectorTile.Tile
+	if err := proto.Unmarshal(data, &vtile); err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*vectorTile.Tile_Layer, len(vtile.Layers))
+	for _, l := range vtile.Layers {
+		byName[l.GetName()] = l
+	}
+
+	var filtered vectorTile.Tile
+	for _, l := range layers {
+		tl, ok := byName[l.Name]
+		if !ok {
+			continue
+		}
+
+		// copy the layer so the rename does not affect other requested layers
+		renamed := *tl
+		name := l.MVTName
+		if name == "" {
+			name = l.Name
+		}
+		renamed.Name = proto.String(name)
+		filtered.Layers = append(filtered.Layers, &renamed)
+	}
+
+	return proto.Marshal(&filtered)
+}
@@ -11,4 +11,13 @@ type Map struct {
 	Layers      []MapLayer       `toml:"layers"`
 	Parameters  []QueryParameter `toml:"params"`
 	TileBuffer  *env.Int         `toml:"tile_buffer"`
+	// TileMatrixSet is the name of the tile grid of the map. Defaults to WebMercatorQuad
+	TileMatrixSet env.String `toml:"tile_matrix_set"`
+	// CacheVersion is the version of the cached tiles of the map. Changing it
+	// switches the map to a new set of cached tiles
+	CacheVersion env.String `toml:"cache_version"`
+	// Principals and Scopes restrict the access to the map to the callers
+	// authenticated as one of the principals or granted one of the scopes
+	Principals []env.String `toml:"principals"`
+	Scopes     []env.String `toml:"scopes"`
 }
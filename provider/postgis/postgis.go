@@ -211,10 +211,23 @@ func (p Provider) TileFeatures(
 		return err
 	}
 
+	z, x, y := tile.ZXY()
+	// the statement is recorded as configured, before the tokens and the
+	// query params are replaced with the values of the request
+	ctx, span := observability.StartClientSpan(ctx, "postgis.query",
+		observability.Attr("db.system", "postgresql"),
+		observability.Attr("db.statement", plyr.sql),
+		observability.Attr(observability.AttrMapName, mapName),
+		observability.Attr(observability.AttrLayerName, layer),
+		observability.Attr(observability.AttrTileZ, uint(z)),
+		observability.Attr(observability.AttrTileX, x),
+		observability.Attr(observability.AttrTileY, y),
+	)
+	defer span.End()
+
 	now := time.Now()
 	rows, err := p.pool.Query(ctx, sql, args...)
 	if p.queryHistogramSeconds != nil {
-		z, _, _ := tile.ZXY()
 		lbls := prometheus.Labels{
 			"z":          strconv.FormatUint(uint64(z), 10),
 			"map_name":   mapName,
@@ -229,6 +242,7 @@ func (p Provider) TileFeatures(
 	// trying to clean itself up.
 	defer rows.Close()
 	if err := ctxErr(ctx, err); err != nil {
+		span.RecordError(err)
 		return fmt.Errorf(
 			"error running layer (%v) SQL (%v) with args %v: %w",
 			layer,
@@ -328,7 +342,9 @@ func (p Provider) TileFeatures(
 		}
 	}
 
-	return rows.Err()
+	err = rows.Err()
+	span.RecordError(err)
+	return err
 }
 
 func (p Provider) MVTForLayers(
@@ -352,8 +368,10 @@ func (p Provider) MVTForLayers(
 	}
 
 	args := make([]any, 0)
+	layerNames := make([]string, 0, len(layers))
 
 	for i := range layers {
+		layerNames = append(layerNames, layers[i].MVTName)
 		if debug {
 			log.Debugf("looking for layer: %v", layers[i])
 		}
@@ -405,10 +423,23 @@ func (p Provider) MVTForLayers(
 		log.Debugf("%s:%s: %v", EnvSQLDebugName, EnvSQLDebugExecute, fsql)
 	}
 	{
+		z, x, y := tile.ZXY()
+		// the statement of the tile holds the values of the request, the
+		// layers it queries are recorded instead
+		ctx, span := observability.StartClientSpan(ctx, "postgis.mvt_query",
+			observability.Attr("db.system", "postgresql"),
+			observability.Attr(observability.AttrMapName, mapName),
+			observability.Attr(observability.AttrLayerName, strings.Join(layerNames, ",")),
+			observability.Attr(observability.AttrTileZ, uint(z)),
+			observability.Attr(observability.AttrTileX, x),
+			observability.Attr(observability.AttrTileY, y),
+		)
+
 		now := time.Now()
 		err = p.pool.QueryRow(ctx, fsql, args...).Scan(&data)
+		span.RecordError(err)
+		span.End()
 		if p.mvtProviderQueryHistogramSeconds != nil {
-			z, _, _ := tile.ZXY()
 			lbls := prometheus.Labels{
 				"z":        strconv.FormatUint(uint64(z), 10),
 				"map_name": mapName,
@@ -149,20 +149,20 @@ func replaceTokens(sql string, lyr *Layer, tile provider.Tile, withBuffer bool)
 	}
 	srid := lyr.SRID()
 
+	var tileSRID uint64
 	if withBuffer {
-		extent, _ = tile.BufferedExtent()
+		extent, tileSRID = tile.BufferedExtent()
 	} else {
-		extent, _ = tile.Extent()
+		extent, tileSRID = tile.Extent()
 	}
 
 	// TODO: leverage helper functions for minx / miny to make this easier to follow
-	// TODO: it's currently assumed the tile will always be in WebMercator. Need to support different projections
-	minGeo, err := basic.FromWebMercator(srid, geom.Point{extent.MinX(), extent.MinY()})
+	minGeo, err := basic.Reproject(tileSRID, srid, geom.Point{extent.MinX(), extent.MinY()})
 	if err != nil {
 		return "", fmt.Errorf("Error trying to convert tile point: %w ", err)
 	}
 
-	maxGeo, err := basic.FromWebMercator(srid, geom.Point{extent.MaxX(), extent.MaxY()})
+	maxGeo, err := basic.Reproject(tileSRID, srid, geom.Point{extent.MaxX(), extent.MaxY()})
 	if err != nil {
 		return "", fmt.Errorf("Error trying to convert tile point: %w ", err)
 	}
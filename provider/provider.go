@@ -77,6 +77,8 @@ func (pf providerFilter) Is(ps ...providerType) bool {
 type tile_t struct {
 	slippy.Tile
 	buffer uint
+	// grid the tile is part of, nil is web mercator
+	grid slippy.TileGridder
 }
 
 // NewTile creates a new slippy tile with a Buffer
@@ -91,22 +93,44 @@ func NewTile(z slippy.Zoom, x uint, y uint, buf, srid uint) Tile {
 	}
 }
 
+// NewTileInGrid creates a new tile with a Buffer, laid out in the given grid.
+// The extents of the tile are in the coordinates of the grid.
+func NewTileInGrid(g slippy.TileGridder, z slippy.Zoom, x uint, y uint, buf uint) Tile {
+	return &tile_t{
+		Tile: slippy.Tile{
+			Z: z,
+			X: x,
+			Y: y,
+		},
+		buffer: buf,
+		grid:   g,
+	}
+}
+
+func (tile *tile_t) gridder() (g slippy.TileGridder, srid uint64) {
+	if tile.grid == nil {
+		return webmercatorGrid, 3857
+	}
+	return tile.grid, uint64(tile.grid.SRID())
+}
+
 // Extent returns the extent of the tile
 func (tile *tile_t) Extent() (ext *geom.Extent, srid uint64) {
-	var err error
-	ext, err = slippy.Extent(webmercatorGrid, tile.Tile)
+	g, srid := tile.gridder()
+	ext, err := slippy.Extent(g, tile.Tile)
 	if err != nil {
 		log.Error("Could not generate valid extent for tile.", tile, err)
-		return &geom.Extent{}, 3857
+		return &geom.Extent{}, srid
 	}
-	return ext, 3857
+	return ext, srid
 
 }
 
 // BufferedExtent returns an extent of the tile, with the define buffer
 func (tile *tile_t) BufferedExtent() (ext *geom.Extent, srid uint64) {
+	g, srid := tile.gridder()
 	ext, _ = tile.Extent()
-	return ext.ExpandBy(slippy.MvtPixelRationForZoom(webmercatorGrid, tile.Z) * float64(tile.buffer)), 3857
+	return ext.ExpandBy(slippy.MvtPixelRationForZoom(g, tile.Z) * float64(tile.buffer)), srid
 }
 
 // Tile is an interface used by Tiler, it is an unnecessary abstraction and is
@@ -150,6 +174,25 @@ func (tu TilerUnion) Layers() ([]LayerInfo, error) {
 	return nil, ErrNilInitFunc
 }
 
+// Close releases the resources, such as database connections, held by the
+// Tiler if it supports it. Unlike the CleanupFunc of a driver, which releases
+// all the providers of the driver, only this provider is affected. This is
+// used to release the providers replaced when the config is reloaded.
+func (tu TilerUnion) Close() error {
+	var p interface{} = tu.Std
+	if tu.Mvt != nil {
+		p = tu.Mvt
+	}
+
+	switch c := p.(type) {
+	case interface{ Close() error }:
+		return c.Close()
+	case interface{ Close() }:
+		c.Close()
+	}
+	return nil
+}
+
 // InitFunc initialize a provider given a config map. The init function should validate the config map, and report any errors. This is called by the For function.
 type InitFunc func(dicter dict.Dicter, maps []Map) (Tiler, error)
 
@@ -2,9 +2,44 @@ package provider
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+
+	"github.com/go-spatial/tegola/auth/apikey"
+	"github.com/go-spatial/tegola/auth/signedurl"
+)
+
+// The query params of the tile endpoints
+const (
+	// QueryParamDebug requests debug layers, the value is a boolean
+	QueryParamDebug = "debug"
+	// QueryParamLayers selects the layers of a tile, the value is a comma
+	// separated list of layer names
+	QueryParamLayers = "layers"
+	// QueryParamFields is the prefix of the params selecting the fields of
+	// the layers of a tile, i.e. fields[roads]=name,class
+	QueryParamFields = "fields"
 )
 
+// ReservedQueryParams are the query params read by the server and the
+// authenticators, which map params can't be named after
+var ReservedQueryParams = []string{
+	QueryParamDebug,
+	QueryParamLayers,
+	QueryParamFields,
+	apikey.QueryParamAPIKey,
+	signedurl.QueryParamPrincipal,
+	signedurl.QueryParamScopes,
+	signedurl.QueryParamExpires,
+	signedurl.QueryParamSignature,
+}
+
+// IsReservedQueryParam reports if name is one of the ReservedQueryParams or
+// selects the fields of a layer (fields[layer_name])
+func IsReservedQueryParam(name string) bool {
+	return slices.Contains(ReservedQueryParams, name) || strings.HasPrefix(name, QueryParamFields+"[")
+}
+
 // QueryParameter represents an HTTP query parameter specified for use with
 // a given map instance.
 type QueryParameter struct {
@@ -16,6 +51,15 @@ type QueryParameter struct {
 	// default_value can be specified
 	DefaultSQL   string `toml:"default_sql"`
 	DefaultValue string `toml:"default_value"`
+	// CacheKey sets if the value of the param is part of the cache key of the
+	// tiles. Defaults to true, params which don't change the tiles (i.e. used
+	// for logging) can be left out so the tiles are shared.
+	CacheKey *bool `toml:"cache_key"`
+}
+
+// InCacheKey reports if the value of the param is part of the cache key
+func (param *QueryParameter) InCacheKey() bool {
+	return param.CacheKey == nil || *param.CacheKey
 }
 
 // Normalize normalizes param and sets default values
@@ -0,0 +1,211 @@
+package shapefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// dbfField is the descriptor of a field of a .dbf file
+type dbfField struct {
+	name     string
+	typ      byte
+	offset   int
+	length   int
+	decimals int
+}
+
+// dbfFile reads the attributes of the records of a shapefile
+type dbfFile struct {
+	f          *os.File
+	numRecords int
+	headerLen  int64
+	recordLen  int
+	fields     []dbfField
+	byName     map[string]int
+}
+
+func openDBF(path string) (*dbfFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := readDBFHeader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("shapefile: %v: %w", path, err)
+	}
+	d.f = f
+	return d, nil
+}
+
+func readDBFHeader(r io.Reader) (*dbfFile, error) {
+	var header [32]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	le := binary.LittleEndian
+	d := dbfFile{
+		numRecords: int(le.Uint32(header[4:])),
+		headerLen:  int64(le.Uint16(header[8:])),
+		recordLen:  int(le.Uint16(header[10:])),
+		byName:     make(map[string]int),
+	}
+	if d.headerLen < 33 {
+		return nil, errors.New("invalid header length")
+	}
+
+	descriptors := make([]byte, d.headerLen-32)
+	if _, err := io.ReadFull(r, descriptors); err != nil {
+		return nil, fmt.Errorf("reading field descriptors: %w", err)
+	}
+
+	// the first byte of the records is the deletion flag
+	offset := 1
+	for b := descriptors; len(b) >= 32 && b[0] != 0x0D; b = b[32:] {
+		name := b[:11]
+		if i := bytes.IndexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+		field := dbfField{
+			name:     decodeString(bytes.TrimSpace(name)),
+			typ:      b[11],
+			offset:   offset,
+			length:   int(b[16]),
+			decimals: int(b[17]),
+		}
+		// character fields longer than 255 bytes use the decimal count
+		// as the high byte of their length
+		if field.typ == 'C' {
+			field.length += field.decimals << 8
+		}
+		offset += field.length
+
+		d.byName[field.name] = len(d.fields)
+		d.fields = append(d.fields, field)
+	}
+	if offset > d.recordLen {
+		return nil, fmt.Errorf("fields are larger (%d) than the records (%d)", offset, d.recordLen)
+	}
+
+	return &d, nil
+}
+
+func (d *dbfFile) fieldNames() []string {
+	names := make([]string, len(d.fields))
+	for i, f := range d.fields {
+		names[i] = f.name
+	}
+	return names
+}
+
+// record reads the values of the fields names of the i-th record. The null
+// values are left out.
+func (d *dbfFile) record(i int, names []string) (map[string]interface{}, error) {
+	props := make(map[string]interface{}, len(names))
+	if i >= d.numRecords {
+		return props, nil
+	}
+
+	buf := make([]byte, d.recordLen)
+	if _, err := d.f.ReadAt(buf, d.headerLen+int64(i)*int64(d.recordLen)); err != nil {
+		return nil, fmt.Errorf("shapefile: reading attributes of record %d: %w", i+1, err)
+	}
+
+	for _, name := range names {
+		j, ok := d.byName[name]
+		if !ok {
+			continue
+		}
+		field := d.fields[j]
+		if v := field.decode(buf[field.offset : field.offset+field.length]); v != nil {
+			props[name] = v
+		}
+	}
+	return props, nil
+}
+
+func (d *dbfFile) close() error { return d.f.Close() }
+
+// decode decodes a value of the field. Blank values are null, as are the
+// values of the types which can't be tags, i.e. memos.
+func (f dbfField) decode(b []byte) interface{} {
+	switch f.typ {
+	case 'C':
+		s := strings.TrimRight(string(b), " \x00")
+		if s == "" {
+			return nil
+		}
+		return decodeString([]byte(s))
+
+	case 'N', 'F':
+		s := strings.TrimSpace(strings.Trim(string(b), "\x00"))
+		// overflowing values are filled with asterisks
+		if s == "" || strings.HasPrefix(s, "*") {
+			return nil
+		}
+		if f.decimals == 0 {
+			if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return n
+			}
+		}
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+		return nil
+
+	case 'L':
+		switch strings.TrimSpace(string(b)) {
+		case "T", "t", "Y", "y":
+			return true
+		case "F", "f", "N", "n":
+			return false
+		}
+		return nil
+
+	case 'D':
+		s := strings.TrimSpace(string(b))
+		if len(s) != 8 || s == "00000000" {
+			return nil
+		}
+		return s[0:4] + "-" + s[4:6] + "-" + s[6:8]
+
+	case 'I':
+		if len(b) != 4 {
+			return nil
+		}
+		return int64(int32(binary.LittleEndian.Uint32(b)))
+
+	case 'O':
+		if len(b) != 8 {
+			return nil
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+
+	default:
+		return nil
+	}
+}
+
+// decodeString returns the text of b. The encoding of .dbf files is rarely
+// declared: b is taken as UTF-8 when it's valid UTF-8 and as Latin-1
+// otherwise.
+func decodeString(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
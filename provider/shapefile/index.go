@@ -0,0 +1,219 @@
+package shapefile
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola/provider/internal/fileprovider"
+)
+
+// spatialIndex finds the records which may intersect an extent
+type spatialIndex interface {
+	// search returns the indexes of the records, in ascending order. The
+	// records are all returned for a nil extent.
+	search(extent *geom.Extent) []int
+}
+
+// bboxIndex holds the bounding boxes of all the records, for the shapefiles
+// without an index
+type bboxIndex [][4]float64
+
+func (idx bboxIndex) search(extent *geom.Extent) []int {
+	var ids []int
+	for i, b := range idx {
+		if fileprovider.Intersects(extent, b[0], b[1], b[2], b[3]) {
+			ids = append(ids, i)
+		}
+	}
+	return ids
+}
+
+// qixIndex is a quadtree index of MapServer / shapelib (.qix), read in
+// memory. The file is a 16 byte header followed by the nodes, depth first:
+//
+//	offset (int32): the size of the subnodes
+//	bounds (4 float64): minx, miny, maxx, maxy
+//	numShapes (int32), followed by the indexes of the shapes (int32)
+//	numSubnodes (int32), followed by the subnodes
+type qixIndex struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func openQIX(path string, numRecords int) (*qixIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 16 || string(data[:3]) != "SQT" {
+		return nil, errors.New("not a quadtree index")
+	}
+
+	idx := qixIndex{data: data[16:]}
+	switch data[3] {
+	case 1:
+		idx.order = binary.LittleEndian
+	case 2:
+		idx.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("unsupported byte order (%d)", data[3])
+	}
+	if version := data[4]; version != 1 {
+		return nil, fmt.Errorf("unsupported version (%d)", version)
+	}
+	if n := int(idx.order.Uint32(data[8:])); n != numRecords {
+		return nil, fmt.Errorf("indexes %d shapes, the shapefile has %d", n, numRecords)
+	}
+	return &idx, nil
+}
+
+func (idx *qixIndex) search(extent *geom.Extent) []int {
+	var ids []int
+	idx.searchNode(0, extent, &ids)
+	sort.Ints(ids)
+	return ids
+}
+
+// searchNode adds the shapes of the node at pos and of its subnodes to ids
+// and returns the position after the node. A truncated node ends the search.
+func (idx *qixIndex) searchNode(pos int, extent *geom.Extent, ids *[]int) int {
+	const nodeHeader = 4 + 32 + 4
+
+	d := idx.data
+	if pos < 0 || len(d)-pos < nodeHeader {
+		return len(d)
+	}
+	subnodesSize := int(int32(idx.order.Uint32(d[pos:])))
+	minx := math.Float64frombits(idx.order.Uint64(d[pos+4:]))
+	miny := math.Float64frombits(idx.order.Uint64(d[pos+12:]))
+	maxx := math.Float64frombits(idx.order.Uint64(d[pos+20:]))
+	maxy := math.Float64frombits(idx.order.Uint64(d[pos+28:]))
+	numShapes := int(int32(idx.order.Uint32(d[pos+36:])))
+	pos += nodeHeader
+
+	if numShapes < 0 || (len(d)-pos)/4 < numShapes+1 {
+		return len(d)
+	}
+
+	// skip the node and its subnodes when it does not overlap
+	if !fileprovider.Intersects(extent, minx, miny, maxx, maxy) {
+		return pos + numShapes*4 + 4 + subnodesSize
+	}
+
+	for i := 0; i < numShapes; i++ {
+		*ids = append(*ids, int(int32(idx.order.Uint32(d[pos+i*4:]))))
+	}
+	pos += numShapes * 4
+
+	numSubnodes := int(int32(idx.order.Uint32(d[pos:])))
+	pos += 4
+	for i := 0; i < numSubnodes && pos < len(d); i++ {
+		pos = idx.searchNode(pos, extent, ids)
+	}
+	return pos
+}
+
+// sbnEntry is a shape of a bin of a .sbn index, with its bounding box in a
+// 256 x 256 grid laid over the extent of the shapefile
+type sbnEntry struct {
+	minx, miny, maxx, maxy uint8
+	// id of the shape, starting at 1
+	id int32
+}
+
+// sbnIndex is an ESRI spatial index (.sbn), read in memory. The format is
+// not published: the entries of the bins are read and filtered by their
+// bounding boxes, without relying on the layout of the tree.
+type sbnIndex struct {
+	bbox    [4]float64
+	entries []sbnEntry
+}
+
+func openSBN(path string, numRecords int) (*sbnIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	be, le := binary.BigEndian, binary.LittleEndian
+	if len(data) < 108 || be.Uint32(data) != fileCode {
+		return nil, errors.New("not a spatial index")
+	}
+	if n := int(be.Uint32(data[28:])); n != numRecords {
+		return nil, fmt.Errorf("indexes %d shapes, the shapefile has %d", n, numRecords)
+	}
+
+	idx := sbnIndex{}
+	for i := range idx.bbox {
+		idx.bbox[i] = math.Float64frombits(le.Uint64(data[32+i*8:]))
+	}
+
+	// the first bin holds the descriptors of the nodes of the tree
+	pos := 108 + int(be.Uint32(data[104:]))*2
+	for pos+8 <= len(data) {
+		size := int(be.Uint32(data[pos+4:])) * 2
+		pos += 8
+		if size < 0 || size%8 != 0 || size > len(data)-pos {
+			return nil, errors.New("invalid bin")
+		}
+		for end := pos + size; pos < end; pos += 8 {
+			idx.entries = append(idx.entries, sbnEntry{
+				minx: data[pos],
+				miny: data[pos+1],
+				maxx: data[pos+2],
+				maxy: data[pos+3],
+				id:   int32(be.Uint32(data[pos+4:])),
+			})
+		}
+	}
+	return &idx, nil
+}
+
+func (idx *sbnIndex) search(extent *geom.Extent) []int {
+	var (
+		minx, miny uint8 = 0, 0
+		maxx, maxy uint8 = 255, 255
+	)
+	if extent != nil {
+		var ok bool
+		if minx, maxx, ok = idx.gridRange(extent.MinX(), extent.MaxX(), idx.bbox[0], idx.bbox[2]); !ok {
+			return nil
+		}
+		if miny, maxy, ok = idx.gridRange(extent.MinY(), extent.MaxY(), idx.bbox[1], idx.bbox[3]); !ok {
+			return nil
+		}
+	}
+
+	seen := make(map[int32]bool)
+	var ids []int
+	for _, e := range idx.entries {
+		if e.minx > maxx || e.maxx < minx || e.miny > maxy || e.maxy < miny || seen[e.id] {
+			continue
+		}
+		seen[e.id] = true
+		ids = append(ids, int(e.id)-1)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// gridRange maps the range from, to in the range min, max of the extent of
+// the shapefile to the grid of the index. It's widened by a cell, as the
+// writers round the bounding boxes differently.
+func (idx *sbnIndex) gridRange(from, to, min, max float64) (uint8, uint8, bool) {
+	if to < min || from > max {
+		return 0, 0, false
+	}
+	if max <= min {
+		return 0, 255, true
+	}
+	scale := 255 / (max - min)
+	lo := math.Floor((from-min)*scale) - 1
+	hi := math.Ceil((to-min)*scale) + 1
+	return uint8(math.Max(lo, 0)), uint8(math.Min(hi, 255)), true
+}
@@ -0,0 +1,58 @@
+package shapefile
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-spatial/tegola"
+)
+
+var authorityRegexp = regexp.MustCompile(`^(?:AUTHORITY|ID)\s*[\[(]\s*"EPSG"\s*,\s*"?(\d+)"?`)
+
+// sridFromWKT returns the EPSG code of the coordinate system of a .prj file,
+// from the authority of its root element. The .prj files written by ESRI
+// software have no authority: the coordinate systems of tegola, WGS 84 and
+// Web Mercator, are recognized by name. It returns 0 for the other ones.
+func sridFromWKT(wkt string) uint64 {
+	wkt = strings.TrimSpace(wkt)
+
+	depth, last := 0, byte(0)
+	for i := 0; i < len(wkt); i++ {
+		c := wkt[i]
+		switch {
+		case c == '"':
+			// skip the quoted text
+			if j := strings.IndexByte(wkt[i+1:], '"'); j >= 0 {
+				i += j + 1
+			}
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case depth == 1 && last == ',' && (c == 'A' || c == 'I'):
+			if m := authorityRegexp.FindStringSubmatch(wkt[i:]); m != nil {
+				if srid, err := strconv.ParseUint(m[1], 10, 64); err == nil {
+					return srid
+				}
+			}
+		}
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			last = c
+		}
+	}
+
+	root, _, _ := strings.Cut(wkt, "[")
+	name := strings.ToUpper(wkt)
+	switch strings.ToUpper(strings.TrimSpace(root)) {
+	case "GEOGCS", "GEOGCRS", "GEODCRS":
+		if strings.Contains(name, "WGS_1984") || strings.Contains(name, `"WGS 84"`) {
+			return tegola.WGS84
+		}
+	case "PROJCS", "PROJCRS":
+		if strings.Contains(name, "WEB_MERCATOR") || strings.Contains(name, "PSEUDO-MERCATOR") || strings.Contains(name, "PSEUDO_MERCATOR") || strings.Contains(name, "MERCATOR_AUXILIARY_SPHERE") {
+			return tegola.WebMercator
+		}
+	}
+	return 0
+}
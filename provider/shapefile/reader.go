@@ -0,0 +1,529 @@
+package shapefile
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider/internal/fileprovider"
+)
+
+// shape types. The Z and M variants of a type are read like the type, as
+// their x and y values come first.
+const (
+	shapeNull       = 0
+	shapePoint      = 1
+	shapePolyLine   = 3
+	shapePolygon    = 5
+	shapeMultiPoint = 8
+	shapeMultiPatch = 31
+)
+
+const (
+	fileCode   = 9994
+	headerSize = 100
+)
+
+// ErrUnsupportedShapeType is returned for the shape types which have no
+// equivalent geometry, i.e. MultiPatch
+type ErrUnsupportedShapeType uint32
+
+func (e ErrUnsupportedShapeType) Error() string {
+	return fmt.Sprintf("shapefile: unsupported shape type (%d)", uint32(e))
+}
+
+var errInvalidRecord = errors.New("shapefile: invalid record")
+
+// baseShapeType returns the 2D type of a shape type
+func baseShapeType(t uint32) uint32 {
+	if t > 10 && t < 30 {
+		return t % 10
+	}
+	return t
+}
+
+// record is the location of a record in the .shp file
+type record struct {
+	// offset of the content of the record, after its header
+	offset int64
+	// length of the content
+	length int32
+}
+
+// source reads the features of a shapefile. The geometries are read from the
+// .shp file, at the offsets of the .shx file, and their attributes from the
+// .dbf file.
+type source struct {
+	shp       *os.File
+	shapeType uint32
+	records   []record
+	index     spatialIndex
+	dbf       *dbfFile
+	srid      uint64
+}
+
+// sidecar returns the path of the file of the shapefile at path with the
+// extension ext, in lower or upper case
+func sidecar(path, ext string) (string, bool) {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, p := range []string{base + ext, base + strings.ToUpper(ext)} {
+		if _, err := os.Stat(p); err == nil {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// Open opens the shapefile at path, the path of its .shp file. The .dbf,
+// .prj and index files next to it are used when they exist.
+func Open(path string) (fileprovider.Source, error) {
+	shp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := source{shp: shp}
+	if err = s.open(path); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *source) open(path string) error {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(s.shp, header[:]); err != nil {
+		return fmt.Errorf("shapefile: reading header: %w", err)
+	}
+	if code := binary.BigEndian.Uint32(header[0:]); code != fileCode {
+		return fmt.Errorf("shapefile: invalid file code (%d)", code)
+	}
+	s.shapeType = baseShapeType(binary.LittleEndian.Uint32(header[32:]))
+	switch s.shapeType {
+	case shapeNull, shapePoint, shapePolyLine, shapePolygon, shapeMultiPoint:
+	default:
+		return ErrUnsupportedShapeType(binary.LittleEndian.Uint32(header[32:]))
+	}
+
+	// the bounding boxes of the records, when they had to be read from
+	// the .shp file
+	var bboxes [][4]float64
+
+	if shxPath, ok := sidecar(path, ".shx"); ok {
+		shx, err := os.ReadFile(shxPath)
+		if err != nil {
+			return err
+		}
+		if s.records, err = readIndex(shx); err != nil {
+			return err
+		}
+	} else {
+		log.Warnf("shapefile: %v has no .shx file, reading the offsets of the records from the .shp file", path)
+		var err error
+		if s.records, bboxes, err = s.scan(); err != nil {
+			return err
+		}
+	}
+
+	if dbfPath, ok := sidecar(path, ".dbf"); ok {
+		dbf, err := openDBF(dbfPath)
+		if err != nil {
+			return err
+		}
+		s.dbf = dbf
+	}
+
+	if prjPath, ok := sidecar(path, ".prj"); ok {
+		wkt, err := os.ReadFile(prjPath)
+		if err != nil {
+			return err
+		}
+		s.srid = sridFromWKT(string(wkt))
+	}
+
+	s.index = s.openIndex(path)
+	if s.index == nil {
+		if bboxes == nil {
+			var err error
+			if _, bboxes, err = s.scan(); err != nil {
+				return err
+			}
+		}
+		s.index = bboxIndex(bboxes)
+	}
+
+	return nil
+}
+
+// openIndex opens the .qix or the .sbn index of the shapefile. The indexes
+// which don't match the records are not used.
+func (s *source) openIndex(path string) spatialIndex {
+	if qixPath, ok := sidecar(path, ".qix"); ok {
+		idx, err := openQIX(qixPath, len(s.records))
+		if err == nil {
+			return idx
+		}
+		log.Warnf("shapefile: not using %v: %v", qixPath, err)
+	}
+	if sbnPath, ok := sidecar(path, ".sbn"); ok {
+		idx, err := openSBN(sbnPath, len(s.records))
+		if err == nil {
+			return idx
+		}
+		log.Warnf("shapefile: not using %v: %v", sbnPath, err)
+	}
+	return nil
+}
+
+// readIndex reads the records of a .shx file
+func readIndex(shx []byte) ([]record, error) {
+	if len(shx) < headerSize || binary.BigEndian.Uint32(shx) != fileCode {
+		return nil, errors.New("shapefile: invalid .shx file")
+	}
+	n := (len(shx) - headerSize) / 8
+	records := make([]record, n)
+	for i := range records {
+		b := shx[headerSize+i*8:]
+		// offsets and lengths are in 16 bit words. the offset is the one
+		// of the header of the record.
+		records[i] = record{
+			offset: int64(binary.BigEndian.Uint32(b))*2 + 8,
+			length: int32(binary.BigEndian.Uint32(b[4:])) * 2,
+		}
+	}
+	return records, nil
+}
+
+// scan reads the records and their bounding boxes from the .shp file
+func (s *source) scan() ([]record, [][4]float64, error) {
+	r := bufio.NewReader(io.NewSectionReader(s.shp, headerSize, math.MaxInt64-headerSize))
+
+	var (
+		records []record
+		bboxes  [][4]float64
+		offset  int64 = headerSize
+		buf     [8 + 36]byte
+	)
+	for {
+		if _, err := io.ReadFull(r, buf[:8]); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("shapefile: reading record header: %w", err)
+		}
+
+		length := int32(binary.BigEndian.Uint32(buf[4:])) * 2
+		if length < 4 {
+			return nil, nil, errInvalidRecord
+		}
+		rec := record{offset: offset + 8, length: length}
+
+		// the type and the bounding box, or the point
+		n := 36
+		if int(length) < n {
+			n = int(length)
+		}
+		if _, err := io.ReadFull(r, buf[8:8+n]); err != nil {
+			return nil, nil, fmt.Errorf("shapefile: reading record: %w", err)
+		}
+		if _, err := r.Discard(int(length) - n); err != nil {
+			return nil, nil, fmt.Errorf("shapefile: reading record: %w", err)
+		}
+
+		records = append(records, rec)
+		bboxes = append(bboxes, recordBBox(buf[8:8+n]))
+		offset += 8 + int64(length)
+	}
+	return records, bboxes, nil
+}
+
+// emptyBBox is the bounding box of the null shapes, which intersects nothing
+var emptyBBox = [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+
+// recordBBox returns the bounding box of the content of a record, from its
+// start
+func recordBBox(content []byte) [4]float64 {
+	if len(content) < 4 {
+		return emptyBBox
+	}
+	le := binary.LittleEndian
+	switch baseShapeType(le.Uint32(content)) {
+	case shapePoint:
+		if len(content) < 20 {
+			return emptyBBox
+		}
+		x, y := readFloat(content[4:]), readFloat(content[12:])
+		return [4]float64{x, y, x, y}
+	case shapePolyLine, shapePolygon, shapeMultiPoint:
+		if len(content) < 36 {
+			return emptyBBox
+		}
+		return [4]float64{readFloat(content[4:]), readFloat(content[12:]), readFloat(content[20:]), readFloat(content[28:])}
+	default:
+		return emptyBBox
+	}
+}
+
+func readFloat(b []byte) float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+func (s *source) Fields() []string {
+	if s.dbf == nil {
+		return nil
+	}
+	return s.dbf.fieldNames()
+}
+
+func (s *source) GeomType() geom.Geometry {
+	switch s.shapeType {
+	case shapePoint:
+		return geom.Point{}
+	case shapeMultiPoint:
+		return geom.MultiPoint{}
+	case shapePolyLine:
+		return geom.MultiLineString{}
+	case shapePolygon:
+		return geom.MultiPolygon{}
+	default:
+		return nil
+	}
+}
+
+func (s *source) SRID() uint64 { return s.srid }
+
+func (s *source) Features(ctx context.Context, extent *geom.Extent, fields []string, fn func(fileprovider.Feature) error) error {
+	var content []byte
+	for _, i := range s.index.search(extent) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if i < 0 || i >= len(s.records) {
+			continue
+		}
+
+		rec := s.records[i]
+		if rec.length < 4 {
+			continue
+		}
+		if cap(content) < int(rec.length) {
+			content = make([]byte, rec.length)
+		}
+		content = content[:rec.length]
+		if _, err := s.shp.ReadAt(content, rec.offset); err != nil {
+			return fmt.Errorf("shapefile: reading record %d: %w", i+1, err)
+		}
+
+		bbox := recordBBox(content)
+		if !fileprovider.Intersects(extent, bbox[0], bbox[1], bbox[2], bbox[3]) {
+			continue
+		}
+
+		g, err := decodeShape(content)
+		if err != nil {
+			return fmt.Errorf("shapefile: record %d: %w", i+1, err)
+		}
+		if g == nil {
+			continue
+		}
+
+		var props map[string]interface{}
+		if s.dbf != nil && len(fields) > 0 {
+			if props, err = s.dbf.record(i, fields); err != nil {
+				return err
+			}
+		}
+
+		if err = fn(fileprovider.Feature{Number: uint64(i) + 1, Geometry: g, Properties: props}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *source) Close() error {
+	var err error
+	if s.shp != nil {
+		err = s.shp.Close()
+	}
+	if s.dbf != nil {
+		if derr := s.dbf.close(); err == nil {
+			err = derr
+		}
+	}
+	return err
+}
+
+// decodeShape decodes the geometry of the content of a record. Null shapes
+// are returned as nil.
+func decodeShape(content []byte) (geom.Geometry, error) {
+	le := binary.LittleEndian
+	switch baseShapeType(le.Uint32(content)) {
+	case shapeNull:
+		return nil, nil
+
+	case shapePoint:
+		if len(content) < 20 {
+			return nil, errInvalidRecord
+		}
+		return geom.Point{readFloat(content[4:]), readFloat(content[12:])}, nil
+
+	case shapeMultiPoint:
+		if len(content) < 40 {
+			return nil, errInvalidRecord
+		}
+		points, err := readPoints(content[40:], le.Uint32(content[36:]))
+		if err != nil {
+			return nil, err
+		}
+		return geom.MultiPoint(points), nil
+
+	case shapePolyLine, shapePolygon:
+		if len(content) < 44 {
+			return nil, errInvalidRecord
+		}
+		numParts, numPoints := le.Uint32(content[36:]), le.Uint32(content[40:])
+		if uint64(len(content)-44) < uint64(numParts)*4 {
+			return nil, errInvalidRecord
+		}
+		points, err := readPoints(content[44+numParts*4:], numPoints)
+		if err != nil {
+			return nil, err
+		}
+
+		parts := make([][][2]float64, 0, numParts)
+		for i := uint32(0); i < numParts; i++ {
+			start, end := le.Uint32(content[44+i*4:]), numPoints
+			if i+1 < numParts {
+				end = le.Uint32(content[44+(i+1)*4:])
+			}
+			if start > end || end > numPoints {
+				return nil, errInvalidRecord
+			}
+			if start < end {
+				parts = append(parts, points[start:end:end])
+			}
+		}
+		if len(parts) == 0 {
+			return nil, nil
+		}
+
+		if baseShapeType(le.Uint32(content)) == shapePolyLine {
+			return geom.MultiLineString(parts), nil
+		}
+		return assemblePolygons(parts), nil
+
+	default:
+		return nil, ErrUnsupportedShapeType(le.Uint32(content))
+	}
+}
+
+func readPoints(b []byte, n uint32) ([][2]float64, error) {
+	if uint64(len(b)) < uint64(n)*16 {
+		return nil, errInvalidRecord
+	}
+	points := make([][2]float64, n)
+	for i := range points {
+		points[i] = [2]float64{readFloat(b[i*16:]), readFloat(b[i*16+8:])}
+	}
+	return points, nil
+}
+
+// assemblePolygons groups the rings of a polygon shape in polygons. The
+// outer rings of shapefiles are clockwise and the holes counter clockwise.
+// Each hole goes to the smallest outer ring containing it. The rings are
+// all taken as outer rings when none of them is clockwise.
+func assemblePolygons(rings [][][2]float64) geom.MultiPolygon {
+	type outer struct {
+		area    float64
+		polygon [][][2]float64
+	}
+
+	var (
+		outers []outer
+		holes  [][][2]float64
+	)
+	for _, ring := range rings {
+		if a := signedArea(ring); a < 0 {
+			outers = append(outers, outer{area: -a, polygon: [][][2]float64{ring}})
+		} else {
+			holes = append(holes, ring)
+		}
+	}
+
+	if len(outers) == 0 {
+		mp := make(geom.MultiPolygon, len(holes))
+		for i, ring := range holes {
+			mp[i] = [][][2]float64{ring}
+		}
+		return mp
+	}
+
+	for _, hole := range holes {
+		best := -1
+		for i, o := range outers {
+			if (best == -1 || o.area < outers[best].area) && containsRing(o.polygon[0], hole) {
+				best = i
+			}
+		}
+		if best == -1 {
+			// holes outside of the outer rings go to the last outer
+			// ring, as they follow it in the record
+			best = len(outers) - 1
+		}
+		outers[best].polygon = append(outers[best].polygon, hole)
+	}
+
+	mp := make(geom.MultiPolygon, len(outers))
+	for i, o := range outers {
+		mp[i] = o.polygon
+	}
+	return mp
+}
+
+// signedArea returns the area of a ring, negative when it's clockwise
+func signedArea(ring [][2]float64) float64 {
+	var a float64
+	for i := range ring {
+		j := (i + 1) % len(ring)
+		a += ring[i][0]*ring[j][1] - ring[j][0]*ring[i][1]
+	}
+	return a / 2
+}
+
+// containsRing reports whether ring is inside outer, by testing its first
+// vertex which is not on the boundary of outer
+func containsRing(outer, ring [][2]float64) bool {
+	for _, pt := range ring {
+		if inside, onBoundary := pointInRing(outer, pt); !onBoundary {
+			return inside
+		}
+	}
+	return false
+}
+
+// pointInRing tests whether pt is inside ring with the even odd rule
+func pointInRing(ring [][2]float64, pt [2]float64) (inside, onBoundary bool) {
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		// on the segment
+		if (pt[0]-a[0])*(b[1]-a[1]) == (pt[1]-a[1])*(b[0]-a[0]) &&
+			pt[0] >= math.Min(a[0], b[0]) && pt[0] <= math.Max(a[0], b[0]) &&
+			pt[1] >= math.Min(a[1], b[1]) && pt[1] <= math.Max(a[1], b[1]) {
+			return false, true
+		}
+		if (a[1] > pt[1]) != (b[1] > pt[1]) &&
+			pt[0] < (b[0]-a[0])*(pt[1]-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside, false
+}
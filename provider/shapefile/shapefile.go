@@ -0,0 +1,22 @@
+// Package shapefile provides a standard provider reading the features of
+// ESRI shapefiles. The records in a tile are found with the .qix or .sbn
+// index of the shapefile when it has one.
+package shapefile
+
+import "github.com/go-spatial/tegola/provider/internal/fileprovider"
+
+const Name = "shapefile"
+
+// NewTileProvider instantiates a provider reading the layers from shapefiles.
+// The config expects the following params:
+//
+//	filepath (string): [Required] the path to a .shp file, or to a directory of shapefiles
+//	layers ([]map[string]interface{}): [Required] the layers of the provider, with the params:
+//		name (string): [Required] the name of the layer
+//		filename (string): [Optional] the .shp file of the layer in the directory, defaults to the name of the layer followed by .shp
+//		fields ([]string): [Optional] the attributes to include as feature tags, defaults to all the attributes but id_fieldname
+//		id_fieldname (string): [Optional] the attribute holding the ids of the features, defaults to the record numbers
+//		srid (int): [Optional] the SRID of the geometries, when the .prj file is missing or can't be mapped to an EPSG code
+//
+// Cleanup closes the files opened by the providers.
+var NewTileProvider, Cleanup = fileprovider.Register(fileprovider.Format{Name: Name, Ext: ".shp", Open: Open})
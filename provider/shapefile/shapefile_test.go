@@ -0,0 +1,228 @@
+package shapefile_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/internal/fileprovider"
+	"github.com/go-spatial/tegola/provider/shapefile"
+)
+
+type feature struct {
+	ID       uint64
+	Geometry geom.Geometry
+	Tags     map[string]interface{}
+}
+
+func tileFeatures(t *testing.T, p provider.Tiler, layer string, tile provider.Tile) ([]feature, error) {
+	t.Helper()
+
+	var features []feature
+	err := p.TileFeatures(context.Background(), layer, tile, nil, func(f *provider.Feature) error {
+		if f.SRID != tegola.WGS84 {
+			t.Errorf("srid, expected %v got %v", tegola.WGS84, f.SRID)
+		}
+		features = append(features, feature{ID: f.ID, Geometry: f.Geometry, Tags: f.Tags})
+		return nil
+	})
+	return features, err
+}
+
+func TestTileFeatures(t *testing.T) {
+	type tcase struct {
+		// dir of the shapefiles in testdata, the places shapefile is
+		// indexed with a .qix in qix and a .sbn in sbn
+		dir      string
+		layer    dict.Dict
+		tile     provider.Tile
+		expected []feature
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			p, err := shapefile.NewTileProvider(dict.Dict{
+				"filepath": filepath.Join("testdata", tc.dir),
+				"layers":   []map[string]interface{}{tc.layer},
+			}, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer shapefile.Cleanup()
+
+			features, err := tileFeatures(t, p, tc.layer["name"].(string), tc.tile)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(features, tc.expected) {
+				t.Errorf("features, expected %v got %v", tc.expected, features)
+			}
+		}
+	}
+
+	lome := feature{ID: 1, Geometry: geom.Point{10, 10}, Tags: map[string]interface{}{
+		"id": int64(100), "name": "Lomé", "pop": int64(837437), "ratio": 0.5, "capital": true, "founded": "1960-04-27",
+	}}
+	kansas := feature{ID: 2, Geometry: geom.Point{-100, 40}, Tags: map[string]interface{}{
+		"id": int64(200), "name": "Kansas", "ratio": 1.25, "capital": false,
+	}}
+	perth := feature{ID: 4, Geometry: geom.Point{120, -30}, Tags: map[string]interface{}{
+		"id": int64(400), "name": "Perth", "pop": int64(2100000),
+	}}
+
+	tests := map[string]tcase{
+		"all": {
+			layer:    dict.Dict{"name": "places"},
+			tile:     provider.NewTile(0, 0, 0, 64, tegola.WebMercator),
+			expected: []feature{lome, kansas, perth},
+		},
+		"north east": {
+			layer:    dict.Dict{"name": "places"},
+			tile:     provider.NewTile(1, 1, 0, 64, tegola.WebMercator),
+			expected: []feature{lome},
+		},
+		"qix": {
+			dir:      "qix",
+			layer:    dict.Dict{"name": "places"},
+			tile:     provider.NewTile(1, 1, 1, 64, tegola.WebMercator),
+			expected: []feature{perth},
+		},
+		"qix all": {
+			dir:      "qix",
+			layer:    dict.Dict{"name": "places"},
+			tile:     provider.NewTile(0, 0, 0, 64, tegola.WebMercator),
+			expected: []feature{lome, kansas, perth},
+		},
+		"sbn": {
+			dir:      "sbn",
+			layer:    dict.Dict{"name": "places"},
+			tile:     provider.NewTile(1, 0, 0, 64, tegola.WebMercator),
+			expected: []feature{kansas},
+		},
+		"fields and id": {
+			layer: dict.Dict{"name": "places", "fields": []string{"name"}, "id_fieldname": "id"},
+			tile:  provider.NewTile(1, 1, 0, 64, tegola.WebMercator),
+			expected: []feature{
+				{ID: 100, Geometry: geom.Point{10, 10}, Tags: map[string]interface{}{"name": "Lomé"}},
+			},
+		},
+		"filename": {
+			layer: dict.Dict{"name": "cities", "filename": "places.shp", "fields": []string{}},
+			tile:  provider.NewTile(1, 0, 0, 64, tegola.WebMercator),
+			expected: []feature{
+				{ID: 2, Geometry: geom.Point{-100, 40}, Tags: map[string]interface{}{}},
+			},
+		},
+		"polygons": {
+			layer: dict.Dict{"name": "areas"},
+			tile:  provider.NewTile(0, 0, 0, 64, tegola.WebMercator),
+			expected: []feature{
+				{ID: 1, Geometry: geom.MultiPolygon{{
+					{{1, 1}, {1, 20}, {20, 20}, {20, 1}, {1, 1}},
+					{{5, 5}, {15, 5}, {15, 15}, {5, 15}, {5, 5}},
+				}}, Tags: map[string]interface{}{"kind": "hole"}},
+				{ID: 2, Geometry: geom.MultiPolygon{
+					{{{-50, -50}, {-50, -40}, {-40, -40}, {-40, -50}, {-50, -50}}},
+					{{{-30, -30}, {-30, -20}, {-20, -20}, {-20, -30}, {-30, -30}}},
+				}, Tags: map[string]interface{}{"kind": "multi"}},
+			},
+		},
+		"polygons south west": {
+			layer: dict.Dict{"name": "areas", "fields": []string{}},
+			tile:  provider.NewTile(2, 1, 2, 0, tegola.WebMercator),
+			expected: []feature{
+				{ID: 2, Geometry: geom.MultiPolygon{
+					{{{-50, -50}, {-50, -40}, {-40, -40}, {-40, -50}, {-50, -50}}},
+					{{{-30, -30}, {-30, -20}, {-20, -20}, {-20, -30}, {-30, -30}}},
+				}, Tags: map[string]interface{}{}},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestNewTileProvider(t *testing.T) {
+	type tcase struct {
+		config      dict.Dict
+		expectedErr error
+		// expected layers and their geometry types
+		expected map[string]geom.Geometry
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			p, err := shapefile.NewTileProvider(tc.config, nil)
+			defer shapefile.Cleanup()
+			if tc.expectedErr != nil {
+				if err == nil || !errors.As(err, reflect.New(reflect.TypeOf(tc.expectedErr)).Interface()) {
+					t.Fatalf("error, expected %v got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			layers, err := p.Layers()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			sort.Slice(layers, func(i, j int) bool { return layers[i].Name() < layers[j].Name() })
+			got := make(map[string]geom.Geometry)
+			for _, l := range layers {
+				got[l.Name()] = l.GeomType()
+				if l.SRID() != tegola.WGS84 {
+					t.Errorf("srid of %v, expected %v got %v", l.Name(), tegola.WGS84, l.SRID())
+				}
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("layers, expected %v got %v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"directory": {
+			config:   dict.Dict{"filepath": "testdata", "layers": []map[string]interface{}{{"name": "places"}, {"name": "areas"}}},
+			expected: map[string]geom.Geometry{"places": geom.Point{}, "areas": geom.MultiPolygon{}},
+		},
+		"file": {
+			config:   dict.Dict{"filepath": filepath.Join("testdata", "areas.shp"), "layers": []map[string]interface{}{{"name": "parcels"}}},
+			expected: map[string]geom.Geometry{"parcels": geom.MultiPolygon{}},
+		},
+		"missing file": {
+			config:      dict.Dict{"filepath": "testdata", "layers": []map[string]interface{}{{"name": "roads"}}},
+			expectedErr: fileprovider.ErrInvalidFilePath{},
+		},
+		"missing layer name": {
+			config:      dict.Dict{"filepath": "testdata", "layers": []map[string]interface{}{{"filename": "places.shp"}}},
+			expectedErr: fileprovider.ErrMissingLayerName{},
+		},
+		"unknown field": {
+			config:      dict.Dict{"filepath": "testdata", "layers": []map[string]interface{}{{"name": "places", "fields": []string{"population"}}}},
+			expectedErr: fileprovider.ErrUnknownField{},
+		},
+		"unknown srid": {
+			config:      dict.Dict{"filepath": filepath.Join("testdata", "noprj"), "layers": []map[string]interface{}{{"name": "places"}}},
+			expectedErr: fileprovider.ErrUnknownSRID{},
+		},
+		"configured srid": {
+			config:   dict.Dict{"filepath": filepath.Join("testdata", "noprj"), "layers": []map[string]interface{}{{"name": "places", "srid": 4326}}},
+			expected: map[string]geom.Geometry{"places": geom.Point{}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,288 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/geojson"
+	"github.com/go-spatial/geom/slippy"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/basic"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/grid"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// maxAdminRequestSize is the largest request body accepted by the admin endpoints
+const maxAdminRequestSize = 10 << 20
+
+// MaxAdminPurgeTiles is the largest number of tiles a single purge request
+// may cover, larger purges should be split into smaller areas or zoom ranges
+var MaxAdminPurgeTiles uint64 = 1 << 20
+
+// AdminCachePurgeRequest is the body of a cache purge request. Without
+// bounds or geometry, all the tiles of the zoom range are purged.
+type AdminCachePurgeRequest struct {
+	// Map is the name of the map to purge the tiles of
+	Map string `json:"map"`
+	// Layer limits the purge to the tiles of a single layer of the map.
+	// The tiles of the map are purged too, as they include the layer.
+	Layer string `json:"layer,omitempty"`
+	// MinZoom and MaxZoom default to the zoom range of the layers
+	MinZoom *uint `json:"min_zoom,omitempty"`
+	MaxZoom *uint `json:"max_zoom,omitempty"`
+	// Bounds in WGS84: min lng, min lat, max lng, max lat
+	Bounds *[4]float64 `json:"bounds,omitempty"`
+	// Geometry is a GeoJSON Polygon or MultiPolygon in WGS84, or a Feature or
+	// FeatureCollection of them
+	Geometry *geojson.Geometry `json:"geometry,omitempty"`
+	// Params are the values of the query parameters of the map the tiles were
+	// generated with, the defaults are used for the missing params. Without
+	// params, the tiles of all the params of the map are purged, which needs
+	// a cache backend implementing cache.PrefixPurger and can't be limited to
+	// an area.
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// AdminCachePurgeResponse reports the number of tiles purged
+type AdminCachePurgeResponse struct {
+	Map   string `json:"map"`
+	Layer string `json:"layer,omitempty"`
+	Tiles int    `json:"tiles"`
+}
+
+// HandleAdminCachePurge purges the cached tiles of a map within a zoom range
+// and an area
+//
+// URI scheme: POST /admin/cache/purge
+type HandleAdminCachePurge struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+}
+
+func (req HandleAdminCachePurge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body AdminCachePurgeRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxAdminRequestSize)).Decode(&body); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if body.Map == "" {
+		http.Error(w, "map is required", http.StatusBadRequest)
+		return
+	}
+
+	m, err := req.Atlas.Map(body.Map)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("map (%v) not configured. check your config file", body.Map), http.StatusNotFound)
+		return
+	}
+	if body.Layer != "" {
+		if m = m.FilterLayersByName(body.Layer); len(m.Layers) == 0 {
+			http.Error(w, fmt.Sprintf("map (%v) has no layer (%v)", body.Map, body.Layer), http.StatusNotFound)
+			return
+		}
+	}
+	values := url.Values{}
+	for name, value := range body.Params {
+		if !m.HasParam(name) {
+			http.Error(w, fmt.Sprintf("map (%v) has no param (%v)", body.Map, name), http.StatusBadRequest)
+			return
+		}
+		values.Set(name, value)
+	}
+	params, err := m.ResolveParams(values)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid params: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.Atlas.GetCache() == nil {
+		http.Error(w, "no cache configured", http.StatusBadRequest)
+		return
+	}
+
+	tileGrid := m.Grid()
+
+	minZoom, maxZoom := ogcZoomRange(m)
+	if body.MinZoom != nil {
+		minZoom = *body.MinZoom
+	}
+	if body.MaxZoom != nil {
+		maxZoom = *body.MaxZoom
+	}
+	if minZoom > maxZoom || maxZoom > tileGrid.MaxZoom {
+		http.Error(w, fmt.Sprintf("invalid zoom range (%v - %v)", minZoom, maxZoom), http.StatusBadRequest)
+		return
+	}
+
+	// the tiles of maps with params are cached per params, without params the
+	// tiles of all the params are purged at once, which can't be limited to an area
+	allParams := len(body.Params) == 0 && m.HasCacheKeyParams()
+	if allParams && (body.Bounds != nil || body.Geometry != nil) {
+		http.Error(w, fmt.Sprintf("map (%v) caches its tiles per params. set params to purge an area, or leave out bounds and geometry to purge the tiles of all the params", m.Name), http.StatusBadRequest)
+		return
+	}
+
+	polygons, err := purgePolygons(body, tileGrid)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n, err := purgeTileCount(tileGrid, minZoom, maxZoom, polygons)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := AdminCachePurgeResponse{
+		Map:   m.Name,
+		Layer: body.Layer,
+	}
+
+	if allParams {
+		if err = req.Atlas.PurgeMapTiles(r.Context(), m, body.Layer, minZoom, maxZoom); err != nil {
+			if errors.Is(err, cache.ErrPurgePrefixNotSupported) {
+				http.Error(w, fmt.Sprintf("map (%v) caches its tiles per params and the cache backend can not purge the tiles of all the params: %v. set params", m.Name, err), http.StatusBadRequest)
+				return
+			}
+			msg := fmt.Sprintf("error purging tiles of map (%v): %v", m.Name, err)
+			log.Error(msg)
+			http.Error(w, msg, http.StatusInternalServerError)
+			return
+		}
+
+		resp.Tiles = int(n)
+		log.Infof("purged %v tiles of map (%v) from the cache, for all the params", resp.Tiles, m.Name)
+		writeJSON(w, resp)
+		return
+	}
+
+	if n > MaxAdminPurgeTiles {
+		http.Error(w, fmt.Sprintf("the request covers too many tiles (%v), the max is %v. narrow the zoom range or the area", n, MaxAdminPurgeTiles), http.StatusBadRequest)
+		return
+	}
+
+	for z := minZoom; z <= maxZoom; z++ {
+		// the layer tiles cached at this zoom
+		var layerNames []string
+		for _, l := range m.FilterLayersByZoom(slippy.Zoom(z)).Layers {
+			layerNames = appendUnique(layerNames, l.MVTName())
+		}
+		if len(layerNames) == 0 {
+			continue
+		}
+
+		err = tileGrid.Cover(slippy.Zoom(z), polygons, func(t slippy.Tile) error {
+			tile := tegola.TileFromSlippyTile(t)
+			if err := req.Atlas.PurgeMapTile(r.Context(), m, tile, params); err != nil {
+				return err
+			}
+			for _, name := range layerNames {
+				if err := req.Atlas.PurgeMapLayerTile(r.Context(), m, name, tile, params); err != nil {
+					return err
+				}
+			}
+			resp.Tiles++
+			return nil
+		})
+		if err != nil {
+			msg := fmt.Sprintf("error purging tiles of map (%v) at zoom (%v): %v", m.Name, z, err)
+			log.Error(msg)
+			http.Error(w, msg, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	log.Infof("purged %v tiles of map (%v) from the cache", resp.Tiles, m.Name)
+	writeJSON(w, resp)
+}
+
+// purgePolygons returns the area of the purge request as polygons in the
+// coordinates of the grid
+func purgePolygons(body AdminCachePurgeRequest, g *grid.Grid) ([]geom.Polygon, error) {
+	var polygons []geom.Polygon
+
+	if body.Bounds != nil {
+		b := *body.Bounds
+		if b[0] >= b[2] || b[1] >= b[3] {
+			return nil, fmt.Errorf("invalid bounds (%v), expecting min lng, min lat, max lng, max lat", b)
+		}
+		polygons = append(polygons, (&geom.Extent{b[0], b[1], b[2], b[3]}).AsPolygon())
+	}
+
+	if body.Geometry != nil {
+		geoms, err := grid.Polygons(body.Geometry.Geometry)
+		if err != nil {
+			return nil, err
+		}
+		if len(geoms) == 0 {
+			return nil, errors.New("geometry has no polygons")
+		}
+		polygons = append(polygons, geoms...)
+	}
+
+	if len(polygons) == 0 {
+		return []geom.Polygon{g.Extent.AsPolygon()}, nil
+	}
+
+	if !grid.CanReproject(g.SRID()) {
+		return nil, fmt.Errorf("unable to reproject the area to the tile matrix set (%v)", g)
+	}
+	for i := range polygons {
+		poly, err := basic.Reproject(tegola.WGS84, uint64(g.SRID()), polygons[i])
+		if err != nil {
+			return nil, fmt.Errorf("unable to reproject the area to the tile matrix set (%v): %w", g, err)
+		}
+		polygons[i] = poly.(geom.Polygon)
+	}
+
+	return polygons, nil
+}
+
+// purgeTileCount returns the number of tiles within the extent of the
+// polygons over the zoom range, an upper bound of the tiles to purge
+func purgeTileCount(g *grid.Grid, minZoom, maxZoom uint, polygons []geom.Polygon) (uint64, error) {
+	var ext *geom.Extent
+	for _, poly := range polygons {
+		pe, err := geom.NewExtentFromGeometry(poly)
+		if err != nil {
+			return 0, err
+		}
+		if ext == nil {
+			ext = pe
+			continue
+		}
+		ext.Add(pe)
+	}
+
+	var n uint64
+	for z := minZoom; z <= maxZoom; z++ {
+		minTile, maxTile, ok, err := g.TileRange(slippy.Zoom(z), ext)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			continue
+		}
+		n += uint64(maxTile.X-minTile.X+1) * uint64(maxTile.Y-minTile.Y+1)
+	}
+	return n, nil
+}
+
+// appendUnique appends s to the slice if it's not part of it yet
+func appendUnique(slice []string, s string) []string {
+	for _, v := range slice {
+		if v == s {
+			return slice
+		}
+	}
+	return append(slice, s)
+}
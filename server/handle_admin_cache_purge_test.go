@@ -0,0 +1,211 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandleAdminCachePurge(t *testing.T) {
+	const token = "test-admin-token"
+
+	type tcase struct {
+		token    string
+		body     string
+		maxTiles uint64
+		// the map has a param in the cache key
+		params bool
+		status int
+		// tiles expected in the response body
+		tiles string
+		// keys expected to be purged and kept
+		purged []cache.Key
+		kept   []cache.Key
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			server.AdminToken = token
+			defer func() { server.AdminToken = "" }()
+			if tc.maxTiles != 0 {
+				defer func(max uint64) { server.MaxAdminPurgeTiles = max }(server.MaxAdminPurgeTiles)
+				server.MaxAdminPurgeTiles = tc.maxTiles
+			}
+
+			a := newTestMapWithLayers(testLayer1, testLayer2, testLayer3)
+			if tc.params {
+				m, _ := a.Map(testMapName)
+				m.Params = []provider.QueryParameter{
+					{Name: "year", Token: "!YEAR!", Type: "int", SQL: "AND year = ?", DefaultValue: "2020"},
+				}
+				a.AddMap(m)
+			}
+			c, err := memory.New(nil)
+			if err != nil {
+				t.Fatalf("cache, expected nil got %v", err)
+			}
+			a.SetCache(c)
+
+			ctx := context.Background()
+			for _, keys := range [][]cache.Key{tc.purged, tc.kept} {
+				for i := range keys {
+					if err = c.Set(ctx, &keys[i], []byte("tile")); err != nil {
+						t.Fatalf("cache set, expected nil got %v", err)
+					}
+				}
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/admin/cache/purge", strings.NewReader(tc.body))
+			if tc.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			w := httptest.NewRecorder()
+			server.NewRouter(a).ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Fatalf("status code, expected %v got %v: %v", tc.status, w.Code, w.Body.String())
+			}
+			if tc.tiles != "" && !strings.Contains(w.Body.String(), `"tiles":`+tc.tiles) {
+				t.Errorf("body, expected %v tiles got %v", tc.tiles, w.Body.String())
+			}
+
+			for i := range tc.purged {
+				if _, hit, _ := c.Get(ctx, &tc.purged[i]); hit {
+					t.Errorf("key %v, expected purged", tc.purged[i])
+				}
+			}
+			for i := range tc.kept {
+				if _, hit, _ := c.Get(ctx, &tc.kept[i]); !hit {
+					t.Errorf("key %v, expected kept", tc.kept[i])
+				}
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"missing token": {
+			body:   `{"map":"test-map"}`,
+			status: http.StatusUnauthorized,
+			kept:   []cache.Key{{MapName: "test-map", Z: 4, X: 8, Y: 7}},
+		},
+		"wrong token": {
+			token:  "wrong",
+			body:   `{"map":"test-map"}`,
+			status: http.StatusUnauthorized,
+		},
+		"missing map": {
+			token:  token,
+			body:   `{}`,
+			status: http.StatusBadRequest,
+		},
+		"invalid body": {
+			token:  token,
+			body:   `{"map":`,
+			status: http.StatusBadRequest,
+		},
+		"unknown map": {
+			token:  token,
+			body:   `{"map":"missing"}`,
+			status: http.StatusNotFound,
+		},
+		"unknown layer": {
+			token:  token,
+			body:   `{"map":"test-map","layer":"missing"}`,
+			status: http.StatusNotFound,
+		},
+		"invalid zoom range": {
+			token:  token,
+			body:   `{"map":"test-map","min_zoom":6,"max_zoom":5}`,
+			status: http.StatusBadRequest,
+		},
+		"invalid bounds": {
+			token:  token,
+			body:   `{"map":"test-map","min_zoom":4,"max_zoom":4,"bounds":[1,1,0.5,0.5]}`,
+			status: http.StatusBadRequest,
+		},
+		"too many tiles": {
+			token:    token,
+			body:     `{"map":"test-map","min_zoom":4,"max_zoom":4}`,
+			maxTiles: 10,
+			status:   http.StatusBadRequest,
+		},
+		"bounds": {
+			token:  token,
+			body:   `{"map":"test-map","min_zoom":4,"max_zoom":4,"bounds":[0.5,0.5,1,1]}`,
+			status: http.StatusOK,
+			tiles:  "1",
+			purged: []cache.Key{
+				{MapName: "test-map", Z: 4, X: 8, Y: 7},
+				{MapName: "test-map", LayerName: "test-layer", Z: 4, X: 8, Y: 7},
+			},
+			kept: []cache.Key{
+				{MapName: "test-map", Z: 4, X: 0, Y: 0},
+				{MapName: "test-map", Z: 5, X: 16, Y: 15},
+			},
+		},
+		"geometry": {
+			token:  token,
+			body:   `{"map":"test-map","min_zoom":4,"max_zoom":4,"geometry":{"type":"Polygon","coordinates":[[[0.5,0.5],[1,0.5],[1,1],[0.5,0.5]]]}}`,
+			status: http.StatusOK,
+			tiles:  "1",
+			purged: []cache.Key{{MapName: "test-map", Z: 4, X: 8, Y: 7}},
+			kept:   []cache.Key{{MapName: "test-map", Z: 4, X: 7, Y: 7}},
+		},
+		"whole zoom": {
+			token:  token,
+			body:   `{"map":"test-map","min_zoom":4,"max_zoom":4}`,
+			status: http.StatusOK,
+			tiles:  "256",
+			purged: []cache.Key{
+				{MapName: "test-map", Z: 4, X: 0, Y: 0},
+				{MapName: "test-map", Z: 4, X: 15, Y: 15},
+			},
+		},
+		"layer outside of its zoom range": {
+			token:  token,
+			body:   `{"map":"test-map","layer":"test-layer-2-name","min_zoom":4,"max_zoom":4}`,
+			status: http.StatusOK,
+			tiles:  "0",
+			kept:   []cache.Key{{MapName: "test-map", Z: 4, X: 0, Y: 0}},
+		},
+		"all params": {
+			token:  token,
+			body:   `{"map":"test-map","min_zoom":4,"max_zoom":4}`,
+			params: true,
+			status: http.StatusOK,
+			tiles:  "256",
+			purged: []cache.Key{
+				{MapName: "test-map", ParamsHash: "2020", Z: 4, X: 8, Y: 7},
+				{MapName: "test-map", ParamsHash: "2021", Z: 4, X: 8, Y: 7},
+				{MapName: "test-map", LayerName: "test-layer", ParamsHash: "2021", Z: 4, X: 0, Y: 0},
+			},
+			kept: []cache.Key{{MapName: "test-map", ParamsHash: "2021", Z: 5, X: 16, Y: 15}},
+		},
+		"all params within bounds": {
+			token:  token,
+			body:   `{"map":"test-map","min_zoom":4,"max_zoom":4,"bounds":[0.5,0.5,1,1]}`,
+			params: true,
+			status: http.StatusBadRequest,
+			kept:   []cache.Key{{MapName: "test-map", ParamsHash: "2021", Z: 4, X: 8, Y: 7}},
+		},
+		"params": {
+			token:  token,
+			body:   `{"map":"test-map","min_zoom":4,"max_zoom":4,"bounds":[0.5,0.5,1,1],"params":{"year":"2021"}}`,
+			params: true,
+			status: http.StatusOK,
+			tiles:  "1",
+			kept:   []cache.Key{{MapName: "test-map", ParamsHash: "2020", Z: 4, X: 8, Y: 7}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
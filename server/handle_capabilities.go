@@ -34,7 +34,10 @@ type CapabilitiesLayer struct {
 	MaxZoom uint              `json:"maxzoom"`
 }
 
-type HandleCapabilities struct{}
+type HandleCapabilities struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+}
 
 func (req HandleCapabilities) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// new capabilities struct
@@ -43,7 +46,7 @@ func (req HandleCapabilities) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	}
 
 	// iterate our registered maps
-	for _, m := range atlas.AllMaps() {
+	for _, m := range visibleMaps(r, req.Atlas.AllMaps()) {
 		debugQuery := url.Values{}
 
 		// if we have a debug param add it to our URLs
@@ -15,6 +15,9 @@ import (
 )
 
 type HandleMapCapabilities struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+
 	// required
 	mapName string
 	// the requests extension defaults to "json"
@@ -43,13 +46,17 @@ func (req HandleMapCapabilities) ServeHTTP(w http.ResponseWriter, r *http.Reques
 	}
 
 	// lookup our Map
-	m, err := atlas.GetMap(req.mapName)
+	m, err := req.Atlas.Map(req.mapName)
 	if err != nil {
 		log.Errorf("map (%v) not configured. check your config file", req.mapName)
 		http.Error(w, "map ("+req.mapName+") not configured. check your config file", http.StatusBadRequest)
 		return
 	}
 
+	if !authorizeMap(w, r, m) {
+		return
+	}
+
 	tileJSON := tilejson.TileJSON{
 		Attribution: &m.Attribution,
 		Bounds:      m.Bounds.Extent(),
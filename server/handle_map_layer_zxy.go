@@ -5,28 +5,80 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"path"
 	"strconv"
 	"strings"
 	"log/slog"
 
 	"github.com/dimfeld/httptreemux"
-	"github.com/go-spatial/geom"
 	"github.com/go-spatial/geom/encoding/mvt"
 	"github.com/go-spatial/geom/slippy"
-	"github.com/go-spatial/proj"
 
 	"github.com/go-spatial/tegola"
 	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/grid"
 	"github.com/go-spatial/tegola/internal/log"
-	"github.com/go-spatial/tegola/maths"
 	"github.com/go-spatial/tegola/observability"
 	"github.com/go-spatial/tegola/provider"
 )
 
-var (
-	webmercatorGrid = slippy.NewGrid(3857, 0)
+const (
+	// ExtensionGeoJSON is the tile extension used to request GeoJSON output (i.e. /osm/1/3/4.geojson)
+	ExtensionGeoJSON = "geojson"
+
+	// GeoJSONMimeType is the mimetype of GeoJSON
+	// https://www.iana.org/assignments/media-types/application/geo+json
+	GeoJSONMimeType = "application/geo+json"
 )
 
+// wantsGeoJSON reports if the tile request asks for GeoJSON output, either
+// by the extension of the "y" value or by the Accept header
+func wantsGeoJSON(extension string, r *http.Request) bool {
+	if extension == ExtensionGeoJSON {
+		return true
+	}
+	for _, v := range strings.Split(r.Header.Get("Accept"), ",") {
+		mimeType, params, _ := strings.Cut(v, ";")
+		if strings.TrimSpace(mimeType) == GeoJSONMimeType {
+			return acceptable(params)
+		}
+	}
+	return false
+}
+
+// acceptable reports if the parameters of a media range of an Accept header
+// don't set its quality to 0, which marks the media type as not acceptable
+func acceptable(params string) bool {
+	for _, p := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(p, "=")
+		if strings.TrimSpace(name) != "q" {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return err != nil || q > 0
+	}
+	return true
+}
+
+// varyAccept adds Accept to the Vary header of the tile responses, as the
+// format of the tiles is negotiated with the Accept header
+func varyAccept(w http.ResponseWriter) {
+	vary(w, "Accept")
+}
+
+// vary adds the request header name to the Vary header of the response, once
+func vary(w http.ResponseWriter, name string) {
+	for _, v := range w.Header().Values("Vary") {
+		for _, n := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(n), name) {
+				return
+			}
+		}
+	}
+	w.Header().Add("Vary", name)
+}
+
 type HandleMapLayerZXY struct {
 	// required
 	mapName string
@@ -38,11 +90,17 @@ type HandleMapLayerZXY struct {
 	x uint
 	// column
 	y uint
-	// the requests extension (i.e. pbf or json)
+	// the requests extension (i.e. pbf or geojson)
 	// defaults to "pbf"
 	extension string
+	// geoJSON is set when GeoJSON output was requested, by extension or Accept header
+	geoJSON bool
 	// debug
 	debug bool
+	// layers selected by the layers query param, nil for all the layers
+	layers []string
+	// fields of the layers selected by the fields[layer] query params, keyed by layer name
+	fields map[string][]string
 	// the Atlas to use, nil (default) is the default atlas
 	Atlas *atlas.Atlas
 }
@@ -67,11 +125,11 @@ func (req *HandleMapLayerZXY) parseURI(r *http.Request) error {
 	}
 	req.z = uint(placeholder)
 
-	maxXYatZ := maths.Exp2(placeholder) - 1
-
+	// the upper bound of x and y depends on the grid of the map, they are
+	// checked once the map is known
 	x := params["x"]
 	placeholder, err = strconv.ParseUint(x, 10, 32)
-	if err != nil || placeholder > maxXYatZ {
+	if err != nil {
 		log.Warnf("invalid X value (%v)", x)
 		return fmt.Errorf("invalid X value (%v)", x)
 	}
@@ -81,7 +139,7 @@ func (req *HandleMapLayerZXY) parseURI(r *http.Request) error {
 	y := params["y"]
 	yParts := strings.Split(y, ".")
 	placeholder, err = strconv.ParseUint(yParts[0], 10, 32)
-	if err != nil || placeholder > maxXYatZ {
+	if err != nil {
 		log.Warnf("invalid Y value (%v)", yParts[0])
 		return fmt.Errorf("invalid Y value (%v)", yParts[0])
 	}
@@ -94,13 +152,62 @@ func (req *HandleMapLayerZXY) parseURI(r *http.Request) error {
 	} else {
 		req.extension = "pbf"
 	}
+	// GeoJSON output can be requested with the extension of y
+	if strings.HasSuffix(y, "."+ExtensionGeoJSON) {
+		req.extension = ExtensionGeoJSON
+	}
+
+	req.geoJSON = wantsGeoJSON(req.extension, r)
+
+	query := r.URL.Query()
 
 	// check for debug request
-	if r.URL.Query().Get(QueryKeyDebug) == "true" {
+	if query.Get(QueryKeyDebug) == "true" {
 		req.debug = true
 	}
 
-	return nil
+	// check for layer and field selections
+	req.layers, req.fields, err = parseSelection(query)
+	return err
+}
+
+// isSelectionParam reports if the query param selects layers or fields
+func isSelectionParam(name string) bool {
+	return name == QueryKeyLayers || strings.HasPrefix(name, QueryKeyFields+"[")
+}
+
+// parseSelection parses the layers and fields[layer_name] query params, nil
+// being returned for the params which are not set
+func parseSelection(query url.Values) (layers []string, fields map[string][]string, err error) {
+	if query.Has(QueryKeyLayers) {
+		layers = splitList(query.Get(QueryKeyLayers))
+	}
+	for key, values := range query {
+		layer, ok := strings.CutPrefix(key, QueryKeyFields+"[")
+		if !ok {
+			continue
+		}
+		layer, ok = strings.CutSuffix(layer, "]")
+		if !ok || layer == "" {
+			return nil, nil, fmt.Errorf("invalid fields query param (%v), expected %v[layer_name]", key, QueryKeyFields)
+		}
+		if fields == nil {
+			fields = make(map[string][]string)
+		}
+		fields[layer] = splitList(values[0])
+	}
+	return layers, fields, nil
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 // URI scheme: /maps/:map_name/:layer_name/:z/:x/:y?param=value
@@ -113,7 +220,14 @@ func (req *HandleMapLayerZXY) parseURI(r *http.Request) error {
 //	y - column
 //
 // param - configurable query parameters and their values
+// layers - comma separated names of the layers to include, all by default
+// fields[layer_name] - comma separated names of the fields of the layer to include, all by default
+//
+// the tile is encoded as MVT unless GeoJSON is requested with the ".geojson"
+// extension on y or an Accept header of "application/geo+json".
 func (req HandleMapLayerZXY) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	varyAccept(w)
+
 	// parse our URI
 	if err := req.parseURI(r); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -129,6 +243,31 @@ func (req HandleMapLayerZXY) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorizeMap(w, r, m) {
+		return
+	}
+
+	tileGrid := m.Grid()
+	size, ok := tileGrid.Size(slippy.Zoom(req.z))
+	if !ok {
+		msg := fmt.Sprintf("invalid Z value (%v) for tile matrix set (%v)", req.z, tileGrid.ID)
+		log.Warn(msg)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if req.x >= size.X {
+		msg := fmt.Sprintf("invalid X value (%v)", req.x)
+		log.Warn(msg)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if req.y >= size.Y {
+		msg := fmt.Sprintf("invalid Y value (%v)", req.y)
+		log.Warn(msg)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
 	// filter down the layers we need for this zoom
 	m = m.FilterLayersByZoom(slippy.Zoom(req.z))
 	if len(m.Layers) == 0 {
@@ -149,13 +288,25 @@ func (req HandleMapLayerZXY) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// reduce the tile to the selected layers and fields
+	if req.layers != nil {
+		m = m.FilterLayersByName(req.layers...)
+		if len(m.Layers) == 0 {
+			msg := fmt.Sprintf("map (%v) has none of the layers (%v) at zoom %v", req.mapName, strings.Join(req.layers, ","), req.z)
+			log.Debug(msg)
+			http.Error(w, msg, http.StatusNotFound)
+			return
+		}
+	}
+	if req.fields != nil {
+		m = m.FilterLayerFields(req.fields)
+	}
+
 	tile := slippy.Tile{Z: slippy.Zoom(req.z), X: req.x, Y: req.y}
 
 	{
 		// Check to see that the zxy is within the bounds of the map.
-		// TODO(@ear7h): use a more efficient version of Intersect that doesn't
-		// make a new extent
-		ext3857, err := slippy.Extent(webmercatorGrid, tile)
+		ext, err := slippy.Extent(tileGrid, tile)
 		if err != nil {
 			msg := fmt.Sprintf("map (%v -- %v) does not contains tile at %v/%v/%v. Unable to generate extent.", req.mapName, m.Bounds, req.z, req.x, req.y)
 			log.Debug(msg, err)
@@ -163,21 +314,22 @@ func (req HandleMapLayerZXY) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		points4326, err := proj.Inverse(proj.WebMercator, ext3857[:])
-		if err != nil {
-			msg := fmt.Sprintf("Unable to convert 3857 to 4326 for map (%v -- %v) and tile %v/%v/%v -- %v.", req.mapName, m.Bounds, req.z, req.x, req.y, ext3857)
+		ext4326, err := tileGrid.ToWGS84(ext)
+		switch {
+		case errors.As(err, new(grid.ErrUnsupportedSRID)):
+			// tegola can't reproject the grid, the bounds of the map can't be checked
+		case err != nil:
+			msg := fmt.Sprintf("Unable to convert %v to 4326 for map (%v -- %v) and tile %v/%v/%v -- %v.", tileGrid, req.mapName, m.Bounds, req.z, req.x, req.y, ext)
 			log.Error(msg)
 			http.Error(w, msg, http.StatusNotFound)
 			return
-		}
-
-		ext4326 := &geom.Extent{}
-		copy(ext4326[:], points4326)
-		if _, intersect := m.Bounds.Intersect(ext4326); !intersect {
-			msg := fmt.Sprintf("map (%v -- %v) does not contains tile at %v/%v/%v -- %v", req.mapName, m.Bounds, req.z, req.x, req.y, ext4326)
-			log.Debug(msg)
-			http.Error(w, msg, http.StatusNotFound)
-			return
+		default:
+			if _, intersect := m.Bounds.Intersect(ext4326); !intersect {
+				msg := fmt.Sprintf("map (%v -- %v) does not contains tile at %v/%v/%v -- %v", req.mapName, m.Bounds, req.z, req.x, req.y, ext4326)
+				log.Debug(msg)
+				http.Error(w, msg, http.StatusNotFound)
+				return
+			}
 		}
 	}
 
@@ -189,13 +341,34 @@ func (req HandleMapLayerZXY) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// check for query parameters and populate param map with their values
 	params, err := extractParameters(m, r)
 	if err != nil {
-		log.Error(err)
+		log.Errorf("invalid params for map (%v): %v", m.Name, err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	encodeCtx := context.WithValue(r.Context(), observability.ObserveVarMapName, m.Name)
-	pbyte, err := m.Encode(encodeCtx, tile, params)
+
+	encode, mimeType := m.Encode, mvt.MimeType
+	if req.geoJSON {
+		encode, mimeType = m.EncodeGeoJSON, GeoJSONMimeType
+	}
+
+	if tl := TileConcurrency; tl != nil {
+		release, err := tl.Acquire(encodeCtx)
+		switch {
+		case errors.Is(err, ErrTileQueueTimeout):
+			log.Warnf("map (%v) tile %v/%v/%v: %v", req.mapName, req.z, req.x, req.y, err)
+			w.Header().Set("Retry-After", retryAfterSeconds(tl.QueueTimeout))
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		case err != nil:
+			// the request was canceled while waiting
+			return
+		}
+		defer release()
+	}
+
+	pbyte, err := encode(encodeCtx, tile, params)
 
 	if err != nil {
 		switch {
@@ -216,7 +389,8 @@ func (req HandleMapLayerZXY) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// mimetype for mapbox vector tiles
 	// https://www.iana.org/assignments/media-types/application/vnd.mapbox-vector-tile
-	w.Header().Add("Content-Type", mvt.MimeType)
+	// or GeoJSON
+	w.Header().Add("Content-Type", mimeType)
 	w.Header().Add("Content-Length", fmt.Sprintf("%d", len(pbyte)))
 	w.WriteHeader(http.StatusOK)
 
@@ -238,30 +412,36 @@ func (req HandleMapLayerZXY) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func extractParameters(m atlas.Map, r *http.Request) (provider.Params, error) {
-	var params provider.Params
-	if m.Params != nil && len(m.Params) > 0 {
-		params = make(provider.Params)
-		err := r.ParseForm()
-		if err != nil {
-			return nil, err
-		}
+// tileRequest returns a copy of r requesting the tile of the map (and layer
+// when set) from the /maps endpoints. This lets the other tile APIs hand their
+// requests to HandleMapLayerZXY and share the tile cache. The query is kept.
+func tileRequest(r *http.Request, mapName, layerName, z, x, y string) *http.Request {
+	params := map[string]string{
+		"map_name": mapName,
+		"z":        z,
+		"x":        x,
+		"y":        y,
+	}
+	parts := []string{"/", URIPrefix, "maps", mapName}
+	if layerName != "" {
+		params["layer_name"] = layerName
+		parts = append(parts, layerName)
+	}
+	parts = append(parts, z, x, y)
 
-		for _, param := range m.Params {
-			if r.Form.Has(param.Name) {
-				val, err := param.ToValue(r.Form.Get(param.Name))
-				if err != nil {
-					return nil, err
-				}
-				params[param.Token] = val
-			} else {
-				p, err := param.ToDefaultValue()
-				if err != nil {
-					return nil, err
-				}
-				params[param.Token] = p
-			}
-		}
+	r2 := r.Clone(httptreemux.AddParamsToContext(r.Context(), params))
+	r2.URL.Path = path.Join(parts...)
+	r2.URL.RawPath = ""
+
+	return r2
+}
+
+func extractParameters(m atlas.Map, r *http.Request) (provider.Params, error) {
+	if len(m.Params) == 0 {
+		return nil, nil
+	}
+	if err := r.ParseForm(); err != nil {
+		return nil, err
 	}
-	return params, nil
+	return m.ResolveParams(r.Form)
 }
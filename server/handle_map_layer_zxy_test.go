@@ -1,16 +1,23 @@
 package server_test
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
 	"github.com/golang/protobuf/proto"
 
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/mvt"
 	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
 	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/grid"
+	"github.com/go-spatial/tegola/server"
 )
 
 type MapHandlerTCase struct {
@@ -185,6 +192,26 @@ func TestHandleMapZXY(t *testing.T) {
 			expectedCode:   http.StatusOK,
 			expectedLayers: []string{"test-layer-2-name", "test-layer", "debug-tile-outline", "debug-tile-center"},
 		},
+		"layers": {
+			uri:            "/maps/test-map/10/2/3.pbf?layers=test-layer",
+			expectedCode:   http.StatusOK,
+			expectedLayers: []string{"test-layer"},
+		},
+		"layers list": {
+			uri:            "/maps/test-map/10/2/3.pbf?layers=test-layer,test-layer-2-name",
+			expectedCode:   http.StatusOK,
+			expectedLayers: []string{"test-layer-2-name", "test-layer"},
+		},
+		"layers unknown": {
+			uri:          "/maps/test-map/10/2/3.pbf?layers=unknown",
+			expectedCode: http.StatusNotFound,
+			expectedBody: "map (test-map) has none of the layers (unknown) at zoom 10",
+		},
+		"fields without layer": {
+			uri:          "/maps/test-map/10/2/3.pbf?fields[]=name",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "invalid fields query param (fields[]), expected fields[layer_name]",
+		},
 		"neg row(y) not allowed issue-229": {
 			uri:          "/maps/test-map/1/1/-1.pbf",
 			expectedCode: http.StatusBadRequest,
@@ -216,6 +243,75 @@ func TestHandleMapZXY(t *testing.T) {
 	}
 }
 
+func TestHandleMapZXYFields(t *testing.T) {
+	type tcase struct {
+		uri string
+		// expectedKeys of each layer of the tile
+		expectedKeys map[string][]string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			w, _, err := doRequest(t, newTestMapWithLayers(testLayer1, testLayer2, testLayer3), http.MethodGet, tc.uri, nil)
+			if err != nil {
+				t.Fatalf("doRequest: %v", err)
+			}
+			if w.Code != http.StatusOK {
+				t.Fatalf("status code, expected %v got %v", http.StatusOK, w.Code)
+			}
+
+			var tile vectorTile.Tile
+			if err = proto.Unmarshal(w.Body.Bytes(), &tile); err != nil {
+				t.Fatalf("unmarshalling response body, expected nil got %v", err)
+			}
+
+			keys := make(map[string][]string, len(tile.Layers))
+			for _, l := range tile.Layers {
+				k := append([]string{}, l.Keys...)
+				sort.Strings(k)
+				keys[l.GetName()] = k
+			}
+			if !reflect.DeepEqual(keys, tc.expectedKeys) {
+				t.Errorf("layer keys, expected %v got %v", tc.expectedKeys, keys)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"all fields": {
+			uri: "/maps/test-map/10/2/3.pbf",
+			expectedKeys: map[string][]string{
+				"test-layer":        {"type"},
+				"test-layer-2-name": {"foo", "type"},
+			},
+		},
+		"fields of a layer": {
+			uri: "/maps/test-map/10/2/3.pbf?fields[test-layer-2-name]=foo",
+			expectedKeys: map[string][]string{
+				"test-layer":        {"type"},
+				"test-layer-2-name": {"foo"},
+			},
+		},
+		"no fields": {
+			uri: "/maps/test-map/10/2/3.pbf?fields[test-layer]=&fields[test-layer-2-name]=",
+			expectedKeys: map[string][]string{
+				"test-layer":        {},
+				"test-layer-2-name": {},
+			},
+		},
+		"layers and fields": {
+			uri: "/maps/test-map/10/2/3.pbf?layers=test-layer-2-name&fields[test-layer-2-name]=type,unknown",
+			expectedKeys: map[string][]string{
+				"test-layer-2-name": {"type"},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
 func TestHandleMapLayerCORS(t *testing.T) {
 	tests := map[string]CORSTestCase{
 		"map": {
@@ -230,3 +326,170 @@ func TestHandleMapLayerCORS(t *testing.T) {
 		t.Run(name, CORSTest(tc))
 	}
 }
+
+func TestHandleMapZXYGeoJSON(t *testing.T) {
+	type tcase struct {
+		uri    string
+		accept string
+
+		expectedContentType string
+		expectedLayers      []string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodGet, tc.uri, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.accept != "" {
+				r.Header.Set("Accept", tc.accept)
+			}
+
+			w := httptest.NewRecorder()
+			server.NewRouter(newTestMapWithLayers(testLayer1, testLayer2, testLayer3)).ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status code, expected %v got %v: %v", http.StatusOK, w.Code, w.Body.String())
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tc.expectedContentType {
+				t.Errorf("content type, expected %v got %v", tc.expectedContentType, ct)
+			}
+			if v := w.Header().Values("Vary"); !reflect.DeepEqual(v, []string{"Accept"}) {
+				t.Errorf("header Vary, expected [Accept] got %v", v)
+			}
+			if tc.expectedContentType != server.GeoJSONMimeType {
+				return
+			}
+
+			var layers map[string]struct {
+				Type     string            `json:"type"`
+				Features []json.RawMessage `json:"features"`
+			}
+			if err = json.Unmarshal(w.Body.Bytes(), &layers); err != nil {
+				t.Fatalf("unmarshalling response body, expected nil got %v", err)
+			}
+
+			var names []string
+			for name, fc := range layers {
+				names = append(names, name)
+				if fc.Type != "FeatureCollection" || len(fc.Features) == 0 {
+					t.Errorf("layer %v, expected a FeatureCollection with features got %v with %v features", name, fc.Type, len(fc.Features))
+				}
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tc.expectedLayers) {
+				t.Errorf("layers, expected %v got %v", tc.expectedLayers, names)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"extension": {
+			uri:                 "/maps/test-map/10/2/3.geojson",
+			expectedContentType: server.GeoJSONMimeType,
+			expectedLayers:      []string{"test-layer", "test-layer-2-name"},
+		},
+		"extension layer": {
+			uri:                 "/maps/test-map/test-layer/4/2/3.geojson",
+			expectedContentType: server.GeoJSONMimeType,
+			expectedLayers:      []string{"test-layer"},
+		},
+		"accept header": {
+			uri:                 "/maps/test-map/10/2/3",
+			accept:              "application/geo+json;q=0.9, */*",
+			expectedContentType: server.GeoJSONMimeType,
+			expectedLayers:      []string{"test-layer", "test-layer-2-name"},
+		},
+		"pbf": {
+			uri:                 "/maps/test-map/10/2/3.pbf",
+			accept:              "*/*",
+			expectedContentType: mvt.MimeType,
+		},
+		"accept header not acceptable": {
+			uri:                 "/maps/test-map/10/2/3",
+			accept:              "application/geo+json; q=0, */*",
+			expectedContentType: mvt.MimeType,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestHandleMapZXYTileMatrixSet(t *testing.T) {
+	type tcase struct {
+		uri          string
+		expectedCode int
+		// expected bounding box of the test provider's tile outline
+		expectedExtent [4]float64
+	}
+
+	testMap := atlas.NewWebMercatorMap(testMapName)
+	testMap.Layers = append(testMap.Layers, testLayer1)
+	testMap.SetGrid(grid.WorldCRS84Quad)
+
+	a := &atlas.Atlas{}
+	a.AddMap(testMap)
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			w, _, err := doRequest(t, a, http.MethodGet, tc.uri, nil)
+			if err != nil {
+				t.Fatalf("doRequest: %v", err)
+			}
+			if w.Code != tc.expectedCode {
+				t.Fatalf("status code, expected %v got %v: %v", tc.expectedCode, w.Code, w.Body.String())
+			}
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+
+			var layers map[string]struct {
+				Features []struct {
+					Geometry struct {
+						Coordinates [][][2]float64 `json:"coordinates"`
+					} `json:"geometry"`
+				} `json:"features"`
+			}
+			if err = json.Unmarshal(w.Body.Bytes(), &layers); err != nil {
+				t.Fatalf("unmarshalling response body, expected nil got %v", err)
+			}
+			features := layers[testLayer1.MVTName()].Features
+			if len(features) != 1 {
+				t.Fatalf("features, expected 1 got %v", len(features))
+			}
+
+			ext := geom.NewExtent(features[0].Geometry.Coordinates[0]...)
+			if [4]float64(*ext) != tc.expectedExtent {
+				t.Errorf("extent, expected %v got %v", tc.expectedExtent, *ext)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"first tile": {
+			uri:            "/maps/test-map/4/0/0.geojson",
+			expectedCode:   http.StatusOK,
+			expectedExtent: [4]float64{-180, 78.75, -168.75, 90},
+		},
+		"last tile": {
+			uri:            "/maps/test-map/4/31/15.geojson",
+			expectedCode:   http.StatusOK,
+			expectedExtent: [4]float64{168.75, -90, 180, -78.75},
+		},
+		"column out of range": {
+			uri:          "/maps/test-map/4/32/0.pbf",
+			expectedCode: http.StatusBadRequest,
+		},
+		"row out of range": {
+			uri:          "/maps/test-map/4/0/16.pbf",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -18,6 +18,9 @@ import (
 )
 
 type HandleMapStyle struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+
 	// required
 	mapName string
 	// the requests extension defaults to "json"
@@ -48,13 +51,17 @@ func (req HandleMapStyle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// lookup our Map
-	m, err := atlas.GetMap(req.mapName)
+	m, err := req.Atlas.Map(req.mapName)
 	if err != nil {
 		log.Errorf("map (%v) not configured. check your config file", req.mapName)
 		http.Error(w, "map ("+req.mapName+") not configured. check your config file", http.StatusNotFound)
 		return
 	}
 
+	if !authorizeMap(w, r, m) {
+		return
+	}
+
 	// if we have a debug param add it to our URLs
 	debugQuery := url.Values{}
 	if r.URL.Query().Get(QueryKeyDebug) == "true" {
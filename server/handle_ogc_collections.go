@@ -0,0 +1,119 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// OGCCollections lists the maps of the atlas as OGC API collections
+type OGCCollections struct {
+	Links       []Link          `json:"links"`
+	Collections []OGCCollection `json:"collections"`
+}
+
+// OGCCollection describes a map as an OGC API collection
+type OGCCollection struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title,omitempty"`
+	Attribution string     `json:"attribution,omitempty"`
+	Extent      *OGCExtent `json:"extent,omitempty"`
+	DataType    string     `json:"dataType"`
+	Links       []Link     `json:"links"`
+}
+
+// OGCExtent is the spatial extent of a collection
+type OGCExtent struct {
+	Spatial OGCSpatialExtent `json:"spatial"`
+}
+
+// OGCSpatialExtent holds the bounding boxes of a collection in CRS84
+type OGCSpatialExtent struct {
+	BBox [][4]float64 `json:"bbox"`
+	CRS  string       `json:"crs"`
+}
+
+func ogcCollection(r *http.Request, m atlas.Map) OGCCollection {
+	c := OGCCollection{
+		ID:          m.Name,
+		Title:       m.Name,
+		Attribution: m.Attribution,
+		DataType:    "vector",
+		Links: []Link{
+			{Href: ogcURL(r, "collections", m.Name), Rel: "self", Type: mimeTypeJSON},
+			{Href: ogcURL(r, "collections", m.Name, "tiles"), Rel: OGCRelTilesetsVector, Type: mimeTypeJSON, Title: "vector tilesets of " + m.Name},
+		},
+	}
+	if m.Bounds != nil {
+		c.Extent = &OGCExtent{
+			Spatial: OGCSpatialExtent{
+				BBox: [][4]float64{{m.Bounds.MinX(), m.Bounds.MinY(), m.Bounds.MaxX(), m.Bounds.MaxY()}},
+				CRS:  CRS84,
+			},
+		}
+	}
+	return c
+}
+
+// HandleOGCCollections lists the maps as collections
+//
+// URI scheme: /collections
+type HandleOGCCollections struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+}
+
+func (req HandleOGCCollections) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	collections := OGCCollections{
+		Links: []Link{
+			{Href: ogcURL(r, "collections"), Rel: "self", Type: mimeTypeJSON},
+		},
+		Collections: []OGCCollection{},
+	}
+
+	for _, m := range visibleMaps(r, req.Atlas.AllMaps()) {
+		collections.Collections = append(collections.Collections, ogcCollection(r, m))
+	}
+
+	writeJSON(w, collections)
+}
+
+// HandleOGCCollection describes a single map as a collection
+//
+// URI scheme: /collections/:map_name
+type HandleOGCCollection struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+}
+
+func (req HandleOGCCollection) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m, ok := ogcMap(w, r, req.Atlas)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, ogcCollection(r, m))
+}
+
+// ogcMap looks up the map of the request. If the map is not found a 404
+// is written and ok is false, as is a 401 or 403 if the caller may not
+// access it.
+func ogcMap(w http.ResponseWriter, r *http.Request, a *atlas.Atlas) (m atlas.Map, ok bool) {
+	mapName := httptreemux.ContextParams(r.Context())["map_name"]
+
+	m, err := a.Map(mapName)
+	if err != nil {
+		errMsg := fmt.Sprintf("map (%v) not configured. check your config file", mapName)
+		log.Debug(errMsg)
+		http.Error(w, errMsg, http.StatusNotFound)
+		return m, false
+	}
+	if !authorizeMap(w, r, m) {
+		return m, false
+	}
+	return m, true
+}
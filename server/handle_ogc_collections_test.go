@@ -0,0 +1,97 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandleOGCCollections(t *testing.T) {
+	w, _, err := doRequest(t, newTestMapWithBounds(-10, -20, 30, 40), http.MethodGet, "/collections", nil)
+	if err != nil {
+		t.Fatalf("doRequest: %v", err)
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code, expected %v got %v", http.StatusOK, w.Code)
+	}
+
+	var collections server.OGCCollections
+	if err = json.Unmarshal(w.Body.Bytes(), &collections); err != nil {
+		t.Fatalf("unmarshalling response body, expected nil got %v", err)
+	}
+
+	if len(collections.Collections) != 1 {
+		t.Fatalf("expected 1 collection got %v", len(collections.Collections))
+	}
+
+	c := collections.Collections[0]
+	if c.ID != testMapName {
+		t.Errorf("id, expected %v got %v", testMapName, c.ID)
+	}
+	if c.Attribution != testMapAttribution {
+		t.Errorf("attribution, expected %v got %v", testMapAttribution, c.Attribution)
+	}
+	expectedBBox := [][4]float64{{-10, -20, 30, 40}}
+	if c.Extent == nil || !reflect.DeepEqual(c.Extent.Spatial.BBox, expectedBBox) {
+		t.Errorf("extent, expected %v got %+v", expectedBBox, c.Extent)
+	}
+
+	var tilesets string
+	for _, l := range c.Links {
+		if l.Rel == server.OGCRelTilesetsVector {
+			tilesets = l.Href
+		}
+	}
+	if expected := "/collections/test-map/tiles"; !strings.HasSuffix(tilesets, expected) {
+		t.Errorf("tilesets link, expected %v got %v", expected, tilesets)
+	}
+}
+
+func TestHandleOGCCollection(t *testing.T) {
+	type tcase struct {
+		uri          string
+		expectedCode int
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			w, _, err := doRequest(t, newTestMapWithLayers(testLayer1), http.MethodGet, tc.uri, nil)
+			if err != nil {
+				t.Fatalf("doRequest: %v", err)
+			}
+			if w.Code != tc.expectedCode {
+				t.Fatalf("status code, expected %v got %v", tc.expectedCode, w.Code)
+			}
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+
+			var c server.OGCCollection
+			if err = json.Unmarshal(w.Body.Bytes(), &c); err != nil {
+				t.Fatalf("unmarshalling response body, expected nil got %v", err)
+			}
+			if c.ID != testMapName {
+				t.Errorf("id, expected %v got %v", testMapName, c.ID)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"map": {
+			uri:          "/collections/test-map",
+			expectedCode: http.StatusOK,
+		},
+		"unknown map": {
+			uri:          "/collections/foo",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+)
+
+// OGCLandingPage is the landing page of the OGC API
+type OGCLandingPage struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Links       []Link `json:"links"`
+}
+
+// HandleOGCLandingPage serves the landing page of the OGC API. Requests
+// which don't ask for JSON (with "f=json" or the Accept header) are passed
+// to Next, which serves the viewer when it's included in the build.
+//
+// URI scheme: /
+type HandleOGCLandingPage struct {
+	// optional
+	Next http.Handler
+}
+
+func (req HandleOGCLandingPage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if req.Next != nil && !wantsJSON(r) {
+		req.Next.ServeHTTP(w, r)
+		return
+	}
+
+	writeJSON(w, OGCLandingPage{
+		Title:       "tegola",
+		Description: "Vector tiles served by tegola",
+		Links: []Link{
+			{Href: ogcURL(r) + "?f=json", Rel: "self", Type: mimeTypeJSON, Title: "this document"},
+			{Href: ogcURL(r, "conformance"), Rel: OGCRelConformance, Type: mimeTypeJSON, Title: "conformance classes implemented by this server"},
+			{Href: ogcURL(r, "collections"), Rel: OGCRelData, Type: mimeTypeJSON, Title: "the maps served as collections"},
+			{Href: ogcURL(r, "tileMatrixSets"), Rel: OGCRelTilingSchemes, Type: mimeTypeJSON, Title: "the supported tiling schemes"},
+		},
+	})
+}
+
+// OGCConformance lists the conformance classes implemented by the OGC API
+type OGCConformance struct {
+	ConformsTo []string `json:"conformsTo"`
+}
+
+// HandleOGCConformance serves the conformance classes of the OGC API
+//
+// URI scheme: /conformance
+type HandleOGCConformance struct{}
+
+func (req HandleOGCConformance) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, OGCConformance{
+		ConformsTo: OGCConformanceClasses,
+	})
+}
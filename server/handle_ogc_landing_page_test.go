@@ -0,0 +1,96 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandleOGCLandingPage(t *testing.T) {
+	type tcase struct {
+		uri    string
+		accept string
+
+		expectedRels []string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodGet, tc.uri, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.accept != "" {
+				r.Header.Set("Accept", tc.accept)
+			}
+
+			w := httptest.NewRecorder()
+			server.NewRouter(newTestMapWithLayers(testLayer1)).ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status code, expected %v got %v", http.StatusOK, w.Code)
+			}
+
+			var landingPage server.OGCLandingPage
+			if err = json.Unmarshal(w.Body.Bytes(), &landingPage); err != nil {
+				t.Fatalf("unmarshalling response body, expected nil got %v", err)
+			}
+
+			rels := map[string]bool{}
+			for _, l := range landingPage.Links {
+				rels[l.Rel] = true
+			}
+			for _, rel := range tc.expectedRels {
+				if !rels[rel] {
+					t.Errorf("expected link with rel %v, got %v", rel, landingPage.Links)
+				}
+			}
+		}
+	}
+
+	expectedRels := []string{"self", server.OGCRelConformance, server.OGCRelData, server.OGCRelTilingSchemes}
+
+	tests := map[string]tcase{
+		"f=json": {
+			uri:          "/?f=json",
+			expectedRels: expectedRels,
+		},
+		"accept header": {
+			uri:          "/",
+			accept:       "application/json",
+			expectedRels: expectedRels,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestHandleOGCConformance(t *testing.T) {
+	w, _, err := doRequest(t, newTestMapWithLayers(testLayer1), http.MethodGet, "/conformance", nil)
+	if err != nil {
+		t.Fatalf("doRequest: %v", err)
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code, expected %v got %v", http.StatusOK, w.Code)
+	}
+
+	var conformance server.OGCConformance
+	if err = json.Unmarshal(w.Body.Bytes(), &conformance); err != nil {
+		t.Fatalf("unmarshalling response body, expected nil got %v", err)
+	}
+
+	var found bool
+	for _, c := range conformance.ConformsTo {
+		if c == "http://www.opengis.net/spec/ogcapi-tiles-1/1.0/conf/core" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the tiles core conformance class, got %v", conformance.ConformsTo)
+	}
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola/grid"
+)
+
+// OGCTileMatrixSets lists the supported tile matrix sets
+type OGCTileMatrixSets struct {
+	TileMatrixSets []OGCTileMatrixSetRef `json:"tileMatrixSets"`
+}
+
+// OGCTileMatrixSetRef references a tile matrix set definition
+type OGCTileMatrixSetRef struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+	URI   string `json:"uri,omitempty"`
+	Links []Link `json:"links"`
+}
+
+// HandleOGCTileMatrixSets lists the tile matrix sets tiles can be requested in
+//
+// URI scheme: /tileMatrixSets
+type HandleOGCTileMatrixSets struct{}
+
+func (req HandleOGCTileMatrixSets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var list OGCTileMatrixSets
+	for _, g := range grid.Registered() {
+		list.TileMatrixSets = append(list.TileMatrixSets, OGCTileMatrixSetRef{
+			ID:    g.ID,
+			Title: g.Title,
+			URI:   g.URI,
+			Links: []Link{
+				{Href: ogcURL(r, "tileMatrixSets", g.ID), Rel: OGCRelTilingScheme, Type: mimeTypeJSON},
+			},
+		})
+	}
+
+	writeJSON(w, list)
+}
+
+// HandleOGCTileMatrixSet serves the definition of a tile matrix set
+//
+// URI scheme: /tileMatrixSets/:tms
+type HandleOGCTileMatrixSet struct{}
+
+func (req HandleOGCTileMatrixSet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tms, ok := ogcTileMatrixSet(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, tms)
+}
+
+// ogcTileMatrixSet looks up the tile matrix set of the request. If the tile
+// matrix set is not supported a 404 is written and ok is false.
+func ogcTileMatrixSet(w http.ResponseWriter, r *http.Request) (tms TileMatrixSet, ok bool) {
+	id := httptreemux.ContextParams(r.Context())["tms"]
+
+	g, err := grid.For(id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("tile matrix set (%v) not supported", id), http.StatusNotFound)
+		return tms, false
+	}
+	return ogcTileMatrixSetFor(g), true
+}
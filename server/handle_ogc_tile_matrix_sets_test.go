@@ -0,0 +1,103 @@
+package server_test
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandleOGCTileMatrixSet(t *testing.T) {
+	type tcase struct {
+		uri          string
+		expectedCode int
+		expectedID   string
+		// expected matrix size and scale denominator of zoom 0
+		expectedWidth  uint
+		expectedHeight uint
+		expectedScale  float64
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			w, _, err := doRequest(t, newTestMapWithLayers(testLayer1), http.MethodGet, tc.uri, nil)
+			if err != nil {
+				t.Fatalf("doRequest: %v", err)
+			}
+			if w.Code != tc.expectedCode {
+				t.Fatalf("status code, expected %v got %v", tc.expectedCode, w.Code)
+			}
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+
+			var tms server.TileMatrixSet
+			if err = json.Unmarshal(w.Body.Bytes(), &tms); err != nil {
+				t.Fatalf("unmarshalling response body, expected nil got %v", err)
+			}
+
+			if tms.ID != tc.expectedID {
+				t.Errorf("id, expected %v got %v", tc.expectedID, tms.ID)
+			}
+			if len(tms.TileMatrices) != tegola.MaxZ+1 {
+				t.Fatalf("tile matrices, expected %v got %v", tegola.MaxZ+1, len(tms.TileMatrices))
+			}
+
+			tm := tms.TileMatrices[0]
+			if tm.MatrixWidth != tc.expectedWidth || tm.MatrixHeight != tc.expectedHeight || tm.TileWidth != 256 {
+				t.Errorf("tile matrix 0, got %+v", tm)
+			}
+			if math.Abs(tm.ScaleDenominator-tc.expectedScale) > 1 {
+				t.Errorf("tile matrix 0 scale denominator, expected %v got %v", tc.expectedScale, tm.ScaleDenominator)
+			}
+			if tm := tms.TileMatrices[10]; tm.ID != "10" || tm.MatrixWidth != tc.expectedWidth*1024 {
+				t.Errorf("tile matrix 10, got %+v", tm)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"WebMercatorQuad": {
+			uri:            "/tileMatrixSets/WebMercatorQuad",
+			expectedCode:   http.StatusOK,
+			expectedID:     "WebMercatorQuad",
+			expectedWidth:  1,
+			expectedHeight: 1,
+			expectedScale:  559082264.0287178,
+		},
+		"WorldCRS84Quad": {
+			uri:            "/tileMatrixSets/WorldCRS84Quad",
+			expectedCode:   http.StatusOK,
+			expectedID:     "WorldCRS84Quad",
+			expectedWidth:  2,
+			expectedHeight: 1,
+			expectedScale:  279541132.0143589,
+		},
+		"unknown": {
+			uri:          "/tileMatrixSets/foo",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestHandleOGCTileMatrixSets(t *testing.T) {
+	w, _, err := doRequest(t, newTestMapWithLayers(testLayer1), http.MethodGet, "/tileMatrixSets", nil)
+	if err != nil {
+		t.Fatalf("doRequest: %v", err)
+	}
+
+	var list server.OGCTileMatrixSets
+	if err = json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("unmarshalling response body, expected nil got %v", err)
+	}
+	if len(list.TileMatrixSets) != 2 || list.TileMatrixSets[0].ID != "WebMercatorQuad" || list.TileMatrixSets[1].ID != "WorldCRS84Quad" {
+		t.Errorf("expected WebMercatorQuad and WorldCRS84Quad got %+v", list.TileMatrixSets)
+	}
+}
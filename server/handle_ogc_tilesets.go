@@ -0,0 +1,238 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/dimfeld/httptreemux"
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/slippy"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// OGCTilesets lists the tilesets of a collection
+type OGCTilesets struct {
+	Links    []Link       `json:"links"`
+	Tilesets []OGCTileset `json:"tilesets"`
+}
+
+// OGCTileset describes the tiles of a map in a tile matrix set
+type OGCTileset struct {
+	Title            string                `json:"title,omitempty"`
+	DataType         string                `json:"dataType"`
+	CRS              string                `json:"crs"`
+	TileMatrixSetURI string                `json:"tileMatrixSetURI,omitempty"`
+	Links            []Link                `json:"links"`
+	BoundingBox      *OGCBoundingBox       `json:"boundingBox,omitempty"`
+	Limits           []OGCTileMatrixLimits `json:"tileMatrixSetLimits,omitempty"`
+	Layers           []OGCTilesetLayer     `json:"layers,omitempty"`
+}
+
+// OGCBoundingBox is a bounding box in the given CRS
+type OGCBoundingBox struct {
+	LowerLeft  [2]float64 `json:"lowerLeft"`
+	UpperRight [2]float64 `json:"upperRight"`
+	CRS        string     `json:"crs,omitempty"`
+}
+
+// OGCTileMatrixLimits are the tile matrices a tileset has tiles for
+type OGCTileMatrixLimits struct {
+	TileMatrix string `json:"tileMatrix"`
+	MinTileRow uint   `json:"minTileRow"`
+	MaxTileRow uint   `json:"maxTileRow"`
+	MinTileCol uint   `json:"minTileCol"`
+	MaxTileCol uint   `json:"maxTileCol"`
+}
+
+// OGCTilesetLayer is a layer of a vector tileset
+type OGCTilesetLayer struct {
+	ID            string `json:"id"`
+	DataType      string `json:"dataType"`
+	MinTileMatrix string `json:"minTileMatrix"`
+	MaxTileMatrix string `json:"maxTileMatrix"`
+}
+
+// tilesPath returns the path of the tiles of the map in the tile matrix set
+func tilesPath(mapName, tmsID string) []string {
+	return []string{"collections", mapName, "tiles", tmsID}
+}
+
+func ogcTileset(r *http.Request, m atlas.Map, tms TileMatrixSet) OGCTileset {
+	tilesetURL := ogcURL(r, tilesPath(m.Name, tms.ID)...)
+
+	ts := OGCTileset{
+		Title:            m.Name,
+		DataType:         "vector",
+		CRS:              tms.CRS,
+		TileMatrixSetURI: tms.URI,
+		Links: []Link{
+			{Href: tilesetURL, Rel: "self", Type: mimeTypeJSON},
+			{Href: ogcURL(r, "tileMatrixSets", tms.ID), Rel: OGCRelTilingScheme, Type: mimeTypeJSON},
+			mvtLink(tilesetURL+"/{tileMatrix}/{tileRow}/{tileCol}", "item"),
+		},
+	}
+
+	if m.Bounds != nil {
+		ts.BoundingBox = &OGCBoundingBox{
+			LowerLeft:  [2]float64{m.Bounds.MinX(), m.Bounds.MinY()},
+			UpperRight: [2]float64{m.Bounds.MaxX(), m.Bounds.MaxY()},
+			CRS:        CRS84,
+		}
+	}
+
+	ts.Limits = tileMatrixSetLimits(m, tms)
+
+	// layers can be configured for more zooms than the grid has
+	tileMatrix := func(z uint) string {
+		if last := uint(len(tms.TileMatrices) - 1); z > last {
+			z = last
+		}
+		return tms.TileMatrices[z].ID
+	}
+	for _, l := range ogcLayers(m) {
+		ts.Layers = append(ts.Layers, OGCTilesetLayer{
+			ID:            l.name,
+			DataType:      "vector",
+			MinTileMatrix: tileMatrix(l.minZoom),
+			MaxTileMatrix: tileMatrix(l.maxZoom),
+		})
+	}
+
+	return ts
+}
+
+// tileMatrixSetLimits returns the tiles of each zoom of the map covering the
+// bounds of the map. Zooms the bounds are outside of are left out. Maps
+// without bounds, or with bounds which can't be transformed to the grid,
+// cover the full matrices.
+func tileMatrixSetLimits(m atlas.Map, tms TileMatrixSet) (limits []OGCTileMatrixLimits) {
+	g := m.Grid()
+
+	var ext *geom.Extent
+	if m.Bounds != nil {
+		var err error
+		if ext, err = g.FromWGS84(m.Bounds); err != nil {
+			log.Warnf("map (%v): transforming the bounds to the grid (%v): %v", m.Name, g.ID, err)
+			ext = nil
+		}
+	}
+
+	minZoom, maxZoom := ogcZoomRange(m)
+	for z := minZoom; z <= maxZoom && int(z) < len(tms.TileMatrices); z++ {
+		tm := tms.TileMatrices[z]
+		if ext == nil {
+			limits = append(limits, OGCTileMatrixLimits{
+				TileMatrix: tm.ID,
+				MaxTileRow: tm.MatrixHeight - 1,
+				MaxTileCol: tm.MatrixWidth - 1,
+			})
+			continue
+		}
+
+		minTile, maxTile, ok, err := g.TileRange(slippy.Zoom(z), ext)
+		if err != nil {
+			log.Warnf("map (%v): tile range of zoom %v: %v", m.Name, z, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		limits = append(limits, OGCTileMatrixLimits{
+			TileMatrix: tm.ID,
+			MinTileRow: minTile.Y,
+			MaxTileRow: maxTile.Y,
+			MinTileCol: minTile.X,
+			MaxTileCol: maxTile.X,
+		})
+	}
+	return limits
+}
+
+// HandleOGCTilesets lists the tilesets of a map. A map has a single tileset,
+// in the tile matrix set of its grid.
+//
+// URI scheme: /collections/:map_name/tiles
+type HandleOGCTilesets struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+}
+
+func (req HandleOGCTilesets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m, ok := ogcMap(w, r, req.Atlas)
+	if !ok {
+		return
+	}
+
+	tilesets := OGCTilesets{
+		Links: []Link{
+			{Href: ogcURL(r, "collections", m.Name, "tiles"), Rel: "self", Type: mimeTypeJSON},
+		},
+	}
+	ts := ogcTileset(r, m, ogcTileMatrixSetFor(m.Grid()))
+	// the list only carries the summary of each tileset
+	ts.BoundingBox, ts.Limits, ts.Layers = nil, nil, nil
+	tilesets.Tilesets = append(tilesets.Tilesets, ts)
+
+	writeJSON(w, tilesets)
+}
+
+// HandleOGCTileset describes the tileset of a map in a tile matrix set
+//
+// URI scheme: /collections/:map_name/tiles/:tms
+type HandleOGCTileset struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+}
+
+func (req HandleOGCTileset) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m, ok := ogcMap(w, r, req.Atlas)
+	if !ok {
+		return
+	}
+	tms, ok := ogcMapTileMatrixSet(w, r, m)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, ogcTileset(r, m, tms))
+}
+
+// ogcMapTileMatrixSet looks up the tile matrix set of the request, which has
+// to be the tile matrix set of the map. Otherwise a 404 is written and ok is false.
+func ogcMapTileMatrixSet(w http.ResponseWriter, r *http.Request, m atlas.Map) (tms TileMatrixSet, ok bool) {
+	if tms, ok = ogcTileMatrixSet(w, r); !ok {
+		return tms, false
+	}
+	if tms.ID != m.Grid().ID {
+		http.Error(w, fmt.Sprintf("map (%v) is not tiled in tile matrix set (%v)", m.Name, tms.ID), http.StatusNotFound)
+		return tms, false
+	}
+	return tms, true
+}
+
+// HandleOGCTile serves a tile of a tileset. The request is rewritten to the
+// /maps/:map_name/:z/:x/:y path and handed to Next, so OGC API and /maps
+// requests share the tile cache.
+//
+// URI scheme: /collections/:map_name/tiles/:tms/:z/:y/:x
+type HandleOGCTile struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+	// required
+	Next http.Handler
+}
+
+func (req HandleOGCTile) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m, ok := ogcMap(w, r, req.Atlas)
+	if !ok {
+		return
+	}
+	if _, ok = ogcMapTileMatrixSet(w, r, m); !ok {
+		return
+	}
+
+	params := httptreemux.ContextParams(r.Context())
+	req.Next.ServeHTTP(w, tileRequest(r, params["map_name"], "", params["z"], params["x"], params["y"]))
+}
@@ -0,0 +1,168 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandleOGCTileset(t *testing.T) {
+	w, _, err := doRequest(t, newTestMapWithLayers(testLayer1, testLayer2, testLayer3), http.MethodGet, "/collections/test-map/tiles/WebMercatorQuad", nil)
+	if err != nil {
+		t.Fatalf("doRequest: %v", err)
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code, expected %v got %v", http.StatusOK, w.Code)
+	}
+
+	var ts server.OGCTileset
+	if err = json.Unmarshal(w.Body.Bytes(), &ts); err != nil {
+		t.Fatalf("unmarshalling response body, expected nil got %v", err)
+	}
+
+	// test-layer is configured twice, covering zooms 4 to 20
+	expectedLayers := []server.OGCTilesetLayer{
+		{ID: "test-layer", DataType: "vector", MinTileMatrix: "4", MaxTileMatrix: "20"},
+		{ID: "test-layer-2-name", DataType: "vector", MinTileMatrix: "10", MaxTileMatrix: "15"},
+	}
+	if !reflect.DeepEqual(ts.Layers, expectedLayers) {
+		t.Errorf("layers, expected %+v got %+v", expectedLayers, ts.Layers)
+	}
+
+	if len(ts.Limits) != 17 || ts.Limits[0].TileMatrix != "4" || ts.Limits[0].MaxTileCol != 15 {
+		t.Errorf("limits, expected zooms 4 to 20 got %+v", ts.Limits)
+	}
+
+	var item string
+	for _, l := range ts.Links {
+		if l.Rel == "item" {
+			item = l.Href
+		}
+	}
+	if expected := "/collections/test-map/tiles/WebMercatorQuad/{tileMatrix}/{tileRow}/{tileCol}"; !strings.HasSuffix(item, expected) {
+		t.Errorf("item link, expected %v got %v", expected, item)
+	}
+}
+
+func TestHandleOGCTilesetLimits(t *testing.T) {
+	type tcase struct {
+		bounds [4]float64
+		// expected are the limits of the first and last zooms, 4 and 9
+		expected []server.OGCTileMatrixLimits
+		expCount int
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			a := newTestMapWithBounds(tc.bounds[0], tc.bounds[1], tc.bounds[2], tc.bounds[3])
+			w, _, err := doRequest(t, a, http.MethodGet, "/collections/test-map/tiles/WebMercatorQuad", nil)
+			if err != nil {
+				t.Fatalf("doRequest: %v", err)
+			}
+			if w.Code != http.StatusOK {
+				t.Fatalf("status code, expected %v got %v", http.StatusOK, w.Code)
+			}
+
+			var ts server.OGCTileset
+			if err = json.Unmarshal(w.Body.Bytes(), &ts); err != nil {
+				t.Fatalf("unmarshalling response body, expected nil got %v", err)
+			}
+
+			if len(ts.Limits) != tc.expCount {
+				t.Fatalf("limits, expected %v zooms got %+v", tc.expCount, ts.Limits)
+			}
+			if tc.expCount == 0 {
+				return
+			}
+			got := []server.OGCTileMatrixLimits{ts.Limits[0], ts.Limits[len(ts.Limits)-1]}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("limits, expected %+v got %+v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"world": {
+			bounds: [4]float64{-180, -85.0511, 180, 85.0511},
+			expected: []server.OGCTileMatrixLimits{
+				{TileMatrix: "4", MaxTileRow: 15, MaxTileCol: 15},
+				{TileMatrix: "9", MaxTileRow: 511, MaxTileCol: 511},
+			},
+			expCount: 6,
+		},
+		"north east quarter": {
+			bounds: [4]float64{1, 1, 180, 85.0511},
+			expected: []server.OGCTileMatrixLimits{
+				{TileMatrix: "4", MinTileRow: 0, MaxTileRow: 7, MinTileCol: 8, MaxTileCol: 15},
+				{TileMatrix: "9", MinTileRow: 0, MaxTileRow: 254, MinTileCol: 257, MaxTileCol: 511},
+			},
+			expCount: 6,
+		},
+		"athens": {
+			bounds: [4]float64{23.6, 37.9, 23.8, 38.1},
+			expected: []server.OGCTileMatrixLimits{
+				{TileMatrix: "4", MinTileRow: 6, MaxTileRow: 6, MinTileCol: 9, MaxTileCol: 9},
+				{TileMatrix: "9", MinTileRow: 197, MaxTileRow: 197, MinTileCol: 289, MaxTileCol: 289},
+			},
+			expCount: 6,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestHandleOGCTile(t *testing.T) {
+	tests := map[string]MapHandlerTCase{
+		"tile": {
+			// z/y/x, the same tile as /maps/test-map/10/2/3
+			uri:            "/collections/test-map/tiles/WebMercatorQuad/10/3/2",
+			expectedCode:   http.StatusOK,
+			expectedLayers: []string{"test-layer-2-name", "test-layer"},
+		},
+		"unknown tile matrix set": {
+			uri:          "/collections/test-map/tiles/WorldCRS84Quad/10/3/2",
+			expectedCode: http.StatusNotFound,
+		},
+		"unknown map": {
+			uri:          "/collections/foo/tiles/WebMercatorQuad/10/3/2",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, MapHandlerTester(tc))
+	}
+}
+
+func TestHandleOGCTileCache(t *testing.T) {
+	server.URIPrefix = "/"
+
+	a := newTestMapWithLayers(testLayer1, testLayer2, testLayer3)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+
+	_, router, err := doRequest(t, a, http.MethodGet, "/maps/test-map/10/2/3.pbf", nil)
+	if err != nil {
+		t.Fatalf("doRequest: %v", err)
+	}
+
+	// the OGC API tile is the same tile, and is served from the cache
+	r, err := http.NewRequest(http.MethodGet, "/collections/test-map/tiles/WebMercatorQuad/10/3/2", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	if h := w.Header().Get("Tegola-Cache"); h != "HIT" {
+		t.Errorf("header Tegola-Cache, expected HIT got %v", h)
+	}
+}
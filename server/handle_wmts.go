@@ -0,0 +1,133 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/dimfeld/httptreemux"
+	"github.com/go-spatial/geom/encoding/mvt"
+
+	"github.com/go-spatial/tegola/atlas"
+)
+
+// HandleWMTS serves the KVP encoded WMTS operations GetCapabilities and
+// GetTile. Tile requests are handed to Next as /maps requests.
+//
+// URI scheme: /wmts?SERVICE=WMTS&REQUEST=GetTile&LAYER=...
+type HandleWMTS struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+	// required
+	Next http.Handler
+}
+
+func (req HandleWMTS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// parameter names are case insensitive
+	params := map[string]string{}
+	for k, v := range r.URL.Query() {
+		params[strings.ToUpper(k)] = v[0]
+	}
+
+	if params["SERVICE"] == "" {
+		wmtsError(w, http.StatusBadRequest, WMTSExceptionMissingParameterValue, "SERVICE", "missing SERVICE parameter")
+		return
+	}
+	if params["SERVICE"] != "WMTS" {
+		wmtsError(w, http.StatusBadRequest, WMTSExceptionInvalidParameterValue, "SERVICE", "unsupported service (%v)", params["SERVICE"])
+		return
+	}
+
+	switch params["REQUEST"] {
+	case "":
+		wmtsError(w, http.StatusBadRequest, WMTSExceptionMissingParameterValue, "REQUEST", "missing REQUEST parameter")
+	case "GetCapabilities":
+		writeXML(w, wmtsCapabilities(r, visibleMaps(r, req.Atlas.AllMaps())))
+	case "GetTile":
+		for _, name := range []string{"LAYER", "STYLE", "FORMAT", "TILEMATRIXSET", "TILEMATRIX", "TILEROW", "TILECOL"} {
+			if params[name] == "" {
+				wmtsError(w, http.StatusBadRequest, WMTSExceptionMissingParameterValue, name, "missing %v parameter", name)
+				return
+			}
+		}
+		if params["FORMAT"] != mvt.MimeType {
+			wmtsError(w, http.StatusBadRequest, WMTSExceptionInvalidParameterValue, "FORMAT", "unsupported format (%v)", params["FORMAT"])
+			return
+		}
+
+		r2, ok := wmtsTileRequest(w, r, req.Atlas, params["LAYER"], params["STYLE"], params["TILEMATRIXSET"], params["TILEMATRIX"], params["TILEROW"], params["TILECOL"])
+		if !ok {
+			return
+		}
+		// the WMTS parameters are not map parameters, and would keep the tile out of the cache
+		r2.URL.RawQuery = ""
+
+		req.Next.ServeHTTP(w, r2)
+	default:
+		wmtsError(w, http.StatusBadRequest, WMTSExceptionOperationNotSupported, "REQUEST", "unsupported request (%v)", params["REQUEST"])
+	}
+}
+
+// HandleWMTSCapabilities serves the capabilities document for RESTful clients
+//
+// URI scheme: /wmts/1.0.0/WMTSCapabilities.xml
+type HandleWMTSCapabilities struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+}
+
+func (req HandleWMTSCapabilities) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeXML(w, wmtsCapabilities(r, visibleMaps(r, req.Atlas.AllMaps())))
+}
+
+// HandleWMTSTile serves RESTful GetTile requests. The request is handed to
+// Next as a /maps request.
+//
+// URI scheme: /wmts/1.0.0/:layer/:style/:tms/:z/:y/:x.pbf
+type HandleWMTSTile struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+	// required
+	Next http.Handler
+}
+
+func (req HandleWMTSTile) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := httptreemux.ContextParams(r.Context())
+
+	// trim the extension of the column
+	x, _, _ := strings.Cut(params["x"], ".")
+
+	r2, ok := wmtsTileRequest(w, r, req.Atlas, params["layer"], params["style"], params["tms"], params["z"], params["y"], x)
+	if !ok {
+		return
+	}
+
+	req.Next.ServeHTTP(w, r2)
+}
+
+// wmtsTileRequest validates the GetTile parameters and returns the request for
+// the tile from the /maps endpoints. If a parameter is invalid an exception
+// report is written and ok is false.
+func wmtsTileRequest(w http.ResponseWriter, r *http.Request, a *atlas.Atlas, layer, style, tmsID, z, row, col string) (r2 *http.Request, ok bool) {
+	if style != WMTSDefaultStyle {
+		wmtsError(w, http.StatusBadRequest, WMTSExceptionInvalidParameterValue, "STYLE", "unknown style (%v)", style)
+		return nil, false
+	}
+
+	mapName, layerName := parseWMTSLayer(layer)
+	m, err := a.Map(mapName)
+	if err == nil && layerName != "" {
+		m = m.FilterLayersByName(layerName)
+	}
+	if err != nil || len(m.Layers) == 0 {
+		wmtsError(w, http.StatusBadRequest, WMTSExceptionInvalidParameterValue, "LAYER", "unknown layer (%v)", layer)
+		return nil, false
+	}
+
+	// layers are only offered in the tile matrix set of their map
+	if tmsID != m.Grid().ID {
+		wmtsError(w, http.StatusBadRequest, WMTSExceptionInvalidParameterValue, "TILEMATRIXSET", "unknown tile matrix set (%v)", tmsID)
+		return nil, false
+	}
+
+	return tileRequest(r, mapName, layerName, z, col, row), true
+}
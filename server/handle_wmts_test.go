@@ -0,0 +1,143 @@
+package server_test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHandleWMTSCapabilities(t *testing.T) {
+	type capabilities struct {
+		Layers []struct {
+			Identifier   string `xml:"Identifier"`
+			ResourceURLs []struct {
+				Template string `xml:"template,attr"`
+			} `xml:"ResourceURL"`
+			TileMatrixSetLinks []struct {
+				TileMatrixSet string `xml:"TileMatrixSet"`
+				Limits        []struct {
+					TileMatrix string `xml:"TileMatrix"`
+				} `xml:"TileMatrixSetLimits>TileMatrixLimits"`
+			} `xml:"TileMatrixSetLink"`
+		} `xml:"Contents>Layer"`
+		TileMatrixSets []struct {
+			Identifier   string `xml:"Identifier"`
+			SupportedCRS string `xml:"SupportedCRS"`
+			TileMatrices []struct {
+				Identifier string `xml:"Identifier"`
+			} `xml:"TileMatrix"`
+		} `xml:"Contents>TileMatrixSet"`
+	}
+
+	type tcase struct {
+		uri string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			w, _, err := doRequest(t, newTestMapWithLayers(testLayer1, testLayer2, testLayer3), http.MethodGet, tc.uri, nil)
+			if err != nil {
+				t.Fatalf("doRequest: %v", err)
+			}
+			if w.Code != http.StatusOK {
+				t.Fatalf("status code, expected %v got %v: %v", http.StatusOK, w.Code, w.Body.String())
+			}
+
+			var c capabilities
+			if err = xml.Unmarshal(w.Body.Bytes(), &c); err != nil {
+				t.Fatalf("unmarshalling response body, expected nil got %v", err)
+			}
+
+			var layers []string
+			for _, l := range c.Layers {
+				layers = append(layers, l.Identifier)
+			}
+			expectedLayers := []string{"test-map", "test-map:test-layer", "test-map:test-layer-2-name"}
+			if !reflect.DeepEqual(layers, expectedLayers) {
+				t.Errorf("layers, expected %v got %v", expectedLayers, layers)
+			}
+
+			// the map covers zooms 4 to 20
+			l := c.Layers[0]
+			if len(l.TileMatrixSetLinks) != 1 || len(l.TileMatrixSetLinks[0].Limits) != 17 || l.TileMatrixSetLinks[0].Limits[0].TileMatrix != "4" {
+				t.Errorf("tile matrix set links, got %+v", l.TileMatrixSetLinks)
+			}
+			if len(l.ResourceURLs) != 1 || !strings.HasSuffix(l.ResourceURLs[0].Template, "/wmts/1.0.0/test-map/{Style}/{TileMatrixSet}/{TileMatrix}/{TileRow}/{TileCol}.pbf") {
+				t.Errorf("resource urls, got %+v", l.ResourceURLs)
+			}
+
+			if len(c.TileMatrixSets) != 1 {
+				t.Fatalf("expected 1 tile matrix set got %v", len(c.TileMatrixSets))
+			}
+			if tms := c.TileMatrixSets[0]; tms.Identifier != "WebMercatorQuad" || tms.SupportedCRS != "urn:ogc:def:crs:EPSG::3857" || len(tms.TileMatrices) != 23 {
+				t.Errorf("tile matrix set, got %v %v with %v tile matrices", tms.Identifier, tms.SupportedCRS, len(tms.TileMatrices))
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"kvp": {
+			uri: "/wmts?service=WMTS&request=GetCapabilities",
+		},
+		"restful": {
+			uri: "/wmts/1.0.0/WMTSCapabilities.xml",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestHandleWMTSGetTile(t *testing.T) {
+	const kvp = "/wmts?SERVICE=WMTS&REQUEST=GetTile&STYLE=default&FORMAT=application/vnd.mapbox-vector-tile&TILEMATRIXSET=WebMercatorQuad"
+
+	tests := map[string]MapHandlerTCase{
+		"kvp map": {
+			uri:            kvp + "&LAYER=test-map&TILEMATRIX=10&TILEROW=3&TILECOL=2",
+			expectedCode:   http.StatusOK,
+			expectedLayers: []string{"test-layer-2-name", "test-layer"},
+		},
+		"kvp map layer": {
+			uri:            kvp + "&LAYER=test-map:test-layer-2-name&TILEMATRIX=10&TILEROW=3&TILECOL=2",
+			expectedCode:   http.StatusOK,
+			expectedLayers: []string{"test-layer-2-name"},
+		},
+		"kvp missing parameter": {
+			uri:          kvp + "&LAYER=test-map&TILEMATRIX=10&TILEROW=3",
+			expectedCode: http.StatusBadRequest,
+		},
+		"kvp unknown layer": {
+			uri:          kvp + "&LAYER=foo&TILEMATRIX=10&TILEROW=3&TILECOL=2",
+			expectedCode: http.StatusBadRequest,
+		},
+		"kvp unsupported request": {
+			uri:          "/wmts?SERVICE=WMTS&REQUEST=GetFeatureInfo",
+			expectedCode: http.StatusBadRequest,
+		},
+		"restful map": {
+			uri:            "/wmts/1.0.0/test-map/default/WebMercatorQuad/10/3/2.pbf",
+			expectedCode:   http.StatusOK,
+			expectedLayers: []string{"test-layer-2-name", "test-layer"},
+		},
+		"restful map layer": {
+			uri:            "/wmts/1.0.0/test-map:test-layer/default/WebMercatorQuad/10/3/2.pbf",
+			expectedCode:   http.StatusOK,
+			expectedLayers: []string{"test-layer"},
+		},
+		"restful unknown style": {
+			uri:          "/wmts/1.0.0/test-map/dark/WebMercatorQuad/10/3/2.pbf",
+			expectedCode: http.StatusBadRequest,
+		},
+		"restful unknown tile matrix set": {
+			uri:          "/wmts/1.0.0/test-map/default/WorldCRS84Quad/10/3/2.pbf",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, MapHandlerTester(tc))
+	}
+}
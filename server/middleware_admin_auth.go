@@ -0,0 +1,22 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AdminAuthHandler is middleware which only lets through requests with the
+// AdminToken as bearer token in the Authorization header
+func AdminAuthHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || AdminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="tegola admin"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
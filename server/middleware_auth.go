@@ -0,0 +1,112 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/auth"
+	"github.com/go-spatial/tegola/auth/apikey"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// AuthHandler is middleware which authenticates requests with the
+// Authenticators. The principal of the request is added to its context, and
+// the query params carrying credentials are removed, so they don't keep the
+// tiles out of the cache. Requests with invalid or expired credentials are
+// rejected, requests without credentials are let through as anonymous.
+//
+// With a RateLimit, the rejected credentials take a token from the bucket of
+// the IP of the client, and the credentials of the clients with an empty
+// bucket are not checked, they are answered with a 429.
+func AuthHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(Authenticators) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rl := RateLimit
+		if rl != nil {
+			if ok, retryAfter := rl.Peek(rl.authFailures(r)); !ok {
+				tooManyRequests(w, retryAfter)
+				return
+			}
+		}
+
+		p, err := auth.Authenticate(r, Authenticators)
+		if err != nil {
+			log.Debugf("auth: %v", err)
+			if rl != nil {
+				rl.Allow(rl.authFailures(r))
+			}
+			unauthorized(w)
+			return
+		}
+
+		r2 := r.Clone(auth.NewContext(r.Context(), p))
+		if p != nil {
+			query := r2.URL.Query()
+			for _, a := range Authenticators {
+				if qp, ok := a.(auth.QueryParamer); ok {
+					for _, name := range qp.QueryParams() {
+						query.Del(name)
+					}
+				}
+			}
+			r2.URL.RawQuery = query.Encode()
+		}
+
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// authorizeMap reports if the principal of the request may access the map.
+// If not, a 401 is written for anonymous requests and a 403 otherwise. The
+// responses of restricted maps are marked private, so shared caches (i.e.
+// CDNs) don't serve them to other callers.
+func authorizeMap(w http.ResponseWriter, r *http.Request, m atlas.Map) bool {
+	p := auth.FromContext(r.Context())
+	if m.Allows(p) {
+		if !m.IsPublic() {
+			privateResponse(w)
+		}
+		return true
+	}
+
+	if p == nil {
+		unauthorized(w)
+		return false
+	}
+	http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	return false
+}
+
+// visibleMaps returns the maps the principal of the request may access
+func visibleMaps(r *http.Request, maps []atlas.Map) []atlas.Map {
+	p := auth.FromContext(r.Context())
+
+	visible := make([]atlas.Map, 0, len(maps))
+	for _, m := range maps {
+		if m.Allows(p) {
+			visible = append(visible, m)
+		}
+	}
+	return visible
+}
+
+// cacheControlPrivate is the Cache-Control of the responses of restricted
+// maps. It takes precedence over the webserver headers config.
+const cacheControlPrivate = "private"
+
+// privateResponse marks the response as private to the credentials of the
+// request
+func privateResponse(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", cacheControlPrivate)
+	vary(w, "Authorization")
+	vary(w, apikey.HeaderAPIKey)
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="tegola"`)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
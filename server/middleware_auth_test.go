@@ -0,0 +1,282 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/auth"
+	"github.com/go-spatial/tegola/auth/apikey"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/server"
+)
+
+// newTestAuthAtlas returns an atlas with the public test map and a private
+// map which only the acme principal and the private:read scope can access
+func newTestAuthAtlas(t *testing.T) *atlas.Atlas {
+	t.Helper()
+
+	a := newTestMapWithLayers(testLayer1, testLayer2, testLayer3)
+
+	private := atlas.NewWebMercatorMap("private")
+	private.Layers = append(private.Layers, testLayer1, testLayer2, testLayer3)
+	private.Principals = []string{"acme"}
+	private.Scopes = []string{"private:read"}
+	a.AddMap(private)
+
+	authenticator, err := apikey.New(dict.Dict{
+		"keys": []map[string]interface{}{
+			{"key": "acme-key", "principal": "acme"},
+			{"key": "globex-key", "principal": "globex"},
+			{"key": "initech-key", "principal": "initech", "scopes": []string{"private:read"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.Authenticators = []auth.Authenticator{authenticator}
+	t.Cleanup(func() { server.Authenticators = nil })
+
+	return a
+}
+
+func TestAuthHandler(t *testing.T) {
+	type tcase struct {
+		uri            string
+		apiKey         string
+		expectedStatus int
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			router := server.NewRouter(newTestAuthAtlas(t))
+
+			r := httptest.NewRequest(http.MethodGet, tc.uri, nil)
+			if tc.apiKey != "" {
+				r.Header.Set(apikey.HeaderAPIKey, tc.apiKey)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.expectedStatus {
+				t.Errorf("status code, expected %v got %v", tc.expectedStatus, w.Code)
+				return
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("header WWW-Authenticate, expected a challenge got none")
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"public map anonymous": {
+			uri:            "/maps/test-map/10/2/3.pbf",
+			expectedStatus: http.StatusOK,
+		},
+		"private map anonymous": {
+			uri:            "/maps/private/10/2/3.pbf",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"private map principal": {
+			uri:            "/maps/private/10/2/3.pbf",
+			apiKey:         "acme-key",
+			expectedStatus: http.StatusOK,
+		},
+		"private map scope": {
+			uri:            "/maps/private/10/2/3.pbf",
+			apiKey:         "initech-key",
+			expectedStatus: http.StatusOK,
+		},
+		"private map forbidden": {
+			uri:            "/maps/private/10/2/3.pbf",
+			apiKey:         "globex-key",
+			expectedStatus: http.StatusForbidden,
+		},
+		"invalid key": {
+			uri:            "/maps/test-map/10/2/3.pbf",
+			apiKey:         "unknown-key",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"private map capabilities": {
+			uri:            "/capabilities/private.json",
+			apiKey:         "globex-key",
+			expectedStatus: http.StatusForbidden,
+		},
+		"private map style": {
+			uri:            "/maps/private/style.json",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"private collection": {
+			uri:            "/collections/private",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"private OGC tile": {
+			uri:            "/collections/private/tiles/WebMercatorQuad/10/3/2",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"private OGC tile principal": {
+			uri:            "/collections/private/tiles/WebMercatorQuad/10/3/2",
+			apiKey:         "acme-key",
+			expectedStatus: http.StatusOK,
+		},
+		"private WMTS tile": {
+			uri:            "/wmts/1.0.0/private/default/WebMercatorQuad/10/3/2.pbf",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"private WMTS tile principal": {
+			uri:            "/wmts/1.0.0/private/default/WebMercatorQuad/10/3/2.pbf",
+			apiKey:         "acme-key",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestAuthHandlerCapabilities(t *testing.T) {
+	type tcase struct {
+		apiKey   string
+		expected []string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			router := server.NewRouter(newTestAuthAtlas(t))
+
+			r := httptest.NewRequest(http.MethodGet, "/capabilities", nil)
+			if tc.apiKey != "" {
+				r.Header.Set(apikey.HeaderAPIKey, tc.apiKey)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			var capabilities server.Capabilities
+			if err := json.NewDecoder(w.Body).Decode(&capabilities); err != nil {
+				t.Fatalf("unexpected error decoding response body: %s", err)
+			}
+
+			var maps []string
+			for _, m := range capabilities.Maps {
+				maps = append(maps, m.Name)
+			}
+			sort.Strings(maps)
+			if strings.Join(maps, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("maps, expected %v got %v", tc.expected, maps)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"anonymous": {
+			expected: []string{testMapName},
+		},
+		"forbidden": {
+			apiKey:   "globex-key",
+			expected: []string{testMapName},
+		},
+		"allowed": {
+			apiKey:   "acme-key",
+			expected: []string{"private", testMapName},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestAuthHandlerTileCache(t *testing.T) {
+	a := newTestAuthAtlas(t)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+	router := server.NewRouter(a)
+
+	// the api_key query param is not a map param, but is removed so the tile is cached
+	r := httptest.NewRequest(http.MethodGet, "/maps/private/10/2/3.pbf?api_key=acme-key", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("Tegola-Cache") != "MISS" {
+		t.Fatalf("expected a cache MISS, got status %v Tegola-Cache %q", w.Code, w.Header().Get("Tegola-Cache"))
+	}
+
+	// the cached tile is not served to anonymous callers
+	r = httptest.NewRequest(http.MethodGet, "/maps/private/10/2/3.pbf", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status code, expected %v got %v", http.StatusUnauthorized, w.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/maps/private/10/2/3.pbf", nil)
+	r.Header.Set(apikey.HeaderAPIKey, "acme-key")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Header().Get("Tegola-Cache") != "HIT" {
+		t.Errorf("header Tegola-Cache, expected HIT got %v", w.Header().Get("Tegola-Cache"))
+	}
+}
+
+func TestAuthHandlerPrivateCacheControl(t *testing.T) {
+	a := newTestAuthAtlas(t)
+	cacher, _ := memory.New(dict.Dict{"ttl": 60})
+	a.SetCache(cacher)
+
+	server.Headers["Cache-Control"] = "public, max-age=3600"
+	defer delete(server.Headers, "Cache-Control")
+	router := server.NewRouter(a)
+
+	type tcase struct {
+		uri          string
+		cacheControl string
+		vary         []string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			// the miss, then the hit
+			for _, cached := range []string{"MISS", "HIT"} {
+				r := httptest.NewRequest(http.MethodGet, tc.uri, nil)
+				r.Header.Set(apikey.HeaderAPIKey, "acme-key")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, r)
+
+				if w.Code != http.StatusOK || w.Header().Get("Tegola-Cache") != cached {
+					t.Fatalf("expected a cache %v, got status %v Tegola-Cache %q", cached, w.Code, w.Header().Get("Tegola-Cache"))
+				}
+				if cc := w.Header().Get("Cache-Control"); !strings.HasPrefix(cc, tc.cacheControl) {
+					t.Errorf("%v: header Cache-Control, expected %q got %q", cached, tc.cacheControl, cc)
+				}
+				vary := strings.Join(w.Header().Values("Vary"), ",")
+				for _, name := range tc.vary {
+					if !strings.Contains(vary, name) {
+						t.Errorf("%v: header Vary, expected %v got %q", cached, name, vary)
+					}
+				}
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"public map": {
+			uri:          "/maps/test-map/10/2/3.pbf",
+			cacheControl: "public, max-age=3600",
+		},
+		"restricted map": {
+			uri:          "/maps/private/10/2/3.pbf",
+			cacheControl: "private, max-age=",
+			vary:         []string{"Authorization", apikey.HeaderAPIKey},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -59,6 +59,11 @@ func (w *gzipDecompressResponseWriter) Header() http.Header {
 }
 
 func (w *gzipDecompressResponseWriter) Write(b []byte) (int, error) {
+	// writing without a status is an OK response, like for the cached tiles
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
 	//	check that we have an OK response, if not, don't process the body
 	if w.status != http.StatusOK {
 		return w.resp.Write(b)
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/tegola/auth"
+)
+
+// RateLimiter limits the rate of the requests of each client with a token
+// bucket per client
+type RateLimiter struct {
+	// Rate is the tokens per second the buckets are refilled with
+	Rate float64
+	// Burst is the size of the buckets
+	Burst int
+	// ClientIPHeader is the request header with the IP of the client, i.e.
+	// X-Forwarded-For when tegola is behind a proxy. The remote address of
+	// the request is used when not set.
+	ClientIPHeader string
+	// TrustedProxies is the number of proxies in front of tegola appending
+	// to ClientIPHeader. The address they received the request from, the
+	// TrustedProxies-th from the right, is the client. Defaults to 1.
+	TrustedProxies int
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	// now is replaced by tests
+	now func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a rate limiter letting each client make rate
+// requests per second, with bursts of up to burst requests. burst defaults to
+// the rate when less than 1.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+	return &RateLimiter{
+		Rate:    rate,
+		Burst:   burst,
+		buckets: map[string]*tokenBucket{},
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of the client. If the bucket is empty
+// false is returned with the time until the next token.
+func (rl *RateLimiter) Allow(client string) (bool, time.Duration) {
+	return rl.take(client, 1)
+}
+
+// Peek reports if the bucket of the client holds a token, without taking it.
+// If the bucket is empty false is returned with the time until the next token.
+func (rl *RateLimiter) Peek(client string) (bool, time.Duration) {
+	return rl.take(client, 0)
+}
+
+// take refills the bucket of the client and takes n tokens from it, if it
+// holds at least one
+func (rl *RateLimiter) take(client string, n float64) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	rl.sweep(now)
+
+	b, ok := rl.buckets[client]
+	if !ok && n == 0 {
+		// a missing bucket is full
+		return true, 0
+	}
+	if !ok {
+		b = &tokenBucket{tokens: float64(rl.Burst), last: now}
+		rl.buckets[client] = b
+	}
+
+	b.tokens = math.Min(float64(rl.Burst), b.tokens+now.Sub(b.last).Seconds()*rl.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / rl.Rate * float64(time.Second))
+	}
+
+	b.tokens -= n
+	return true, 0
+}
+
+// sweep drops the buckets which have refilled, once a minute, so the
+// buckets of the clients which went away don't pile up
+func (rl *RateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < time.Minute {
+		return
+	}
+	rl.lastSweep = now
+
+	for client, b := range rl.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rl.Rate >= float64(rl.Burst) {
+			delete(rl.buckets, client)
+		}
+	}
+}
+
+// client identifies the client of the request by its principal when
+// authenticated, by its IP otherwise
+func (rl *RateLimiter) client(r *http.Request) string {
+	if p := auth.FromContext(r.Context()); p != nil && p.Name != "" {
+		return "principal:" + p.Name
+	}
+	return "ip:" + rl.clientIP(r)
+}
+
+// clientIP returns the IP of the client of the request. The addresses of
+// X-Forwarded-For like headers are appended by each proxy, the leftmost ones
+// are set by the client and can't be trusted.
+func (rl *RateLimiter) clientIP(r *http.Request) string {
+	if rl.ClientIPHeader != "" {
+		proxies := rl.TrustedProxies
+		if proxies < 1 {
+			proxies = 1
+		}
+
+		var addrs []string
+		for _, v := range r.Header.Values(rl.ClientIPHeader) {
+			addrs = append(addrs, strings.Split(v, ",")...)
+		}
+		// with fewer addresses, the request did not go through the proxies
+		if len(addrs) >= proxies {
+			if ip := strings.TrimSpace(addrs[len(addrs)-proxies]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return host
+}
+
+// RateLimitHandler is middleware which rejects the requests of the clients
+// exceeding the rate of RateLimit with a 429 and a Retry-After header.
+// It follows the AuthHandler, so authenticated clients are limited by their
+// principal. The AuthHandler limits the rejected credentials by IP.
+func RateLimitHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rl := RateLimit
+		if rl == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if ok, retryAfter := rl.Allow(rl.client(r)); !ok {
+			tooManyRequests(w, retryAfter)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tooManyRequests writes a 429 with the seconds until the next token
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+	http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+}
+
+// authFailures returns the bucket of the credentials the client of the
+// request presented which were rejected
+func (rl *RateLimiter) authFailures(r *http.Request) string {
+	return "auth:" + rl.clientIP(r)
+}
+
+// retryAfterSeconds formats d as the seconds of a Retry-After header, rounded
+// up to at least a second
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Max(1, math.Ceil(d.Seconds()))))
+}
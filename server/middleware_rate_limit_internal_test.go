@@ -0,0 +1,134 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/auth"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	rl := NewRateLimiter(2, 3)
+	rl.now = func() time.Time { return now }
+
+	// the burst is let through at once
+	for i := 0; i < 3; i++ {
+		if ok, _ := rl.Allow("a"); !ok {
+			t.Fatalf("request %v of the burst, expected allowed", i)
+		}
+	}
+	ok, retryAfter := rl.Allow("a")
+	if ok {
+		t.Fatalf("request past the burst, expected rejected")
+	}
+	if retryAfter != 500*time.Millisecond {
+		t.Errorf("retry after, expected %v got %v", 500*time.Millisecond, retryAfter)
+	}
+
+	// other clients have their own bucket
+	if ok, _ := rl.Allow("b"); !ok {
+		t.Errorf("other client, expected allowed")
+	}
+
+	// a token is added every half second
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := rl.Allow("a"); !ok {
+		t.Errorf("after refill, expected allowed")
+	}
+	if ok, _ := rl.Allow("a"); ok {
+		t.Errorf("after refill, expected a single token")
+	}
+
+	// refilled buckets are swept
+	now = now.Add(2 * time.Minute)
+	rl.Allow("c")
+	if len(rl.buckets) != 1 {
+		t.Errorf("buckets after sweep, expected 1 got %v", len(rl.buckets))
+	}
+}
+
+func TestRateLimiterClient(t *testing.T) {
+	type tcase struct {
+		remoteAddr     string
+		header         string
+		clientIPHeader string
+		trustedProxies int
+		principal      *auth.Principal
+		expected       string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			rl := NewRateLimiter(1, 1)
+			rl.ClientIPHeader = tc.clientIPHeader
+			rl.TrustedProxies = tc.trustedProxies
+
+			r := httptest.NewRequest("GET", "/maps/osm/1/2/3.pbf", nil)
+			r.RemoteAddr = tc.remoteAddr
+			if tc.header != "" {
+				r.Header.Set("X-Forwarded-For", tc.header)
+			}
+			if tc.principal != nil {
+				r = r.WithContext(auth.NewContext(r.Context(), tc.principal))
+			}
+
+			if got := rl.client(r); got != tc.expected {
+				t.Errorf("client, expected %v got %v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"remote address": {
+			remoteAddr: "192.0.2.1:5678",
+			expected:   "ip:192.0.2.1",
+		},
+		"header not trusted": {
+			remoteAddr: "192.0.2.1:5678",
+			header:     "198.51.100.7",
+			expected:   "ip:192.0.2.1",
+		},
+		"forwarded for": {
+			remoteAddr:     "192.0.2.1:5678",
+			header:         "198.51.100.7",
+			clientIPHeader: "X-Forwarded-For",
+			expected:       "ip:198.51.100.7",
+		},
+		"forwarded for spoofed": {
+			remoteAddr:     "192.0.2.1:5678",
+			header:         "203.0.113.9, 198.51.100.7",
+			clientIPHeader: "X-Forwarded-For",
+			expected:       "ip:198.51.100.7",
+		},
+		"forwarded for two proxies": {
+			remoteAddr:     "192.0.2.1:5678",
+			header:         "203.0.113.9, 198.51.100.7, 192.0.2.10",
+			clientIPHeader: "X-Forwarded-For",
+			trustedProxies: 2,
+			expected:       "ip:198.51.100.7",
+		},
+		"forwarded for bypassing proxies": {
+			remoteAddr:     "192.0.2.1:5678",
+			header:         "198.51.100.7",
+			clientIPHeader: "X-Forwarded-For",
+			trustedProxies: 2,
+			expected:       "ip:192.0.2.1",
+		},
+		"forwarded for missing": {
+			remoteAddr:     "192.0.2.1:5678",
+			clientIPHeader: "X-Forwarded-For",
+			expected:       "ip:192.0.2.1",
+		},
+		"principal": {
+			remoteAddr: "192.0.2.1:5678",
+			principal:  &auth.Principal{Name: "acme"},
+			expected:   "principal:acme",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
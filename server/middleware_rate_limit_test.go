@@ -0,0 +1,65 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spatial/tegola/auth/apikey"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestRateLimitHandler(t *testing.T) {
+	server.URIPrefix = "/"
+
+	a := newTestAuthAtlas(t)
+	server.RateLimit = server.NewRateLimiter(0.01, 2)
+	defer func() { server.RateLimit = nil }()
+
+	router := server.NewRouter(a)
+
+	type request struct {
+		remoteAddr     string
+		apiKey         string
+		expectedStatus int
+	}
+
+	// requests are played in order against the same limiter
+	requests := []request{
+		{remoteAddr: "192.0.2.1:1234", expectedStatus: http.StatusOK},
+		{remoteAddr: "192.0.2.1:1234", expectedStatus: http.StatusOK},
+		{remoteAddr: "192.0.2.1:1234", expectedStatus: http.StatusTooManyRequests},
+		// other clients are not limited by the first one
+		{remoteAddr: "192.0.2.2:1234", expectedStatus: http.StatusOK},
+		// authenticated clients are limited by their principal, not their IP
+		{remoteAddr: "192.0.2.1:1234", apiKey: "acme-key", expectedStatus: http.StatusOK},
+		{remoteAddr: "192.0.2.3:1234", apiKey: "acme-key", expectedStatus: http.StatusOK},
+		{remoteAddr: "192.0.2.4:1234", apiKey: "acme-key", expectedStatus: http.StatusTooManyRequests},
+		// rejected credentials are limited by IP, apart from the requests
+		{remoteAddr: "192.0.2.6:1234", expectedStatus: http.StatusOK},
+		{remoteAddr: "192.0.2.6:1234", apiKey: "wrong-key", expectedStatus: http.StatusUnauthorized},
+		{remoteAddr: "192.0.2.6:1234", expectedStatus: http.StatusOK},
+		{remoteAddr: "192.0.2.6:1234", apiKey: "wrong-key", expectedStatus: http.StatusUnauthorized},
+		// once limited, the requests of the IP are rejected before their credentials are checked
+		{remoteAddr: "192.0.2.6:1234", apiKey: "wrong-key", expectedStatus: http.StatusTooManyRequests},
+		{remoteAddr: "192.0.2.6:1234", apiKey: "globex-key", expectedStatus: http.StatusTooManyRequests},
+	}
+
+	for i, req := range requests {
+		r := httptest.NewRequest(http.MethodGet, "/maps/test-map/10/2/3.pbf", nil)
+		r.RemoteAddr = req.remoteAddr
+		if req.apiKey != "" {
+			r.Header.Set(apikey.HeaderAPIKey, req.apiKey)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Code != req.expectedStatus {
+			t.Errorf("request %v, status code, expected %v got %v", i, req.expectedStatus, w.Code)
+			continue
+		}
+		if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "100" {
+			t.Errorf("request %v, header Retry-After, expected 100 got %v", i, w.Header().Get("Retry-After"))
+		}
+	}
+}
@@ -2,24 +2,47 @@ package server
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-spatial/geom/encoding/mvt"
+	"github.com/go-spatial/geom/slippy"
 	"github.com/go-spatial/tegola/atlas"
 	"github.com/go-spatial/tegola/cache"
 	"github.com/go-spatial/tegola/internal/log"
+	"golang.org/x/sync/singleflight"
 )
 
 // TileCacheHandler implements a request cache for tiles on requests when the URLs
-// have a /:z/:x/:y scheme suffix (i.e. /osm/1/3/4.pbf)
+// have a /:z/:x/:y scheme suffix (i.e. /osm/1/3/4.pbf). Tiles of maps with
+// query parameters are keyed by the hash of the resolved parameters. Requests
+// with query parameters the map does not declare are not cached. The layer and
+// field selections are served from the tile of the whole map.
+//
+// Tiles are served with an ETag, and with Last-Modified and Cache-Control
+// headers when the cache knows when they were cached and expire, on misses
+// too. Requests with a matching If-None-Match (or If-Modified-Since) are
+// answered with a 304. Expired tiles which the cache still holds in its stale
+// while revalidate period are served at once, marked STALE, while they are
+// regenerated in the background.
+//
+// Concurrent misses of the same tile are coalesced: the tile is encoded once
+// for all the requests and written once to the cache.
 func TileCacheHandler(a *atlas.Atlas, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var err error
 
+		// the cached tiles are MVT tiles, GeoJSON ones are negotiated too
+		varyAccept(w)
+
 		// check if a cache backend exists
 		cacher := a.GetCache()
 		if cacher == nil {
@@ -28,8 +51,8 @@ func TileCacheHandler(a *atlas.Atlas, next http.Handler) http.Handler {
 			return
 		}
 
-		// ignore requests with query parameters
-		if r.URL.RawQuery != "" {
+		// ignore GeoJSON requests, only MVT tiles are cached
+		if wantsGeoJSON(strings.TrimPrefix(path.Ext(r.URL.Path), "."), r) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -42,45 +65,122 @@ func TileCacheHandler(a *atlas.Atlas, next http.Handler) http.Handler {
 			return
 		}
 
-		// use the URL path as the key
-		cachedTile, hit, err := cacher.Get(r.Context(), key)
+		// the version and the params of the tiles are not part of the tile URLs,
+		// the tile handler reports these as unknown layers
+		if key.Version != "" || key.ParamsHash != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		m, err := a.Map(key.MapName)
 		if err != nil {
-			log.Errorf("cache middleware: error reading from cache: %v", err)
+			// the tile handler reports unknown maps
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// cache miss
-		if !hit {
-			// buffer which will hold a copy of the response for writing to the cache
-			var buff bytes.Buffer
+		// cached tiles are only served to the callers allowed to access the map
+		if !authorizeMap(w, r, m) {
+			return
+		}
 
-			// overwrite our current responseWriter with a tileCacheResponseWriter
-			w = newTileCacheResponseWriter(w, &buff)
+		// ignore requests with query parameters which are not map parameters (i.e. debug)
+		query := r.URL.Query()
+		for name := range query {
+			if !m.HasParam(name) && !isSelectionParam(name) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
 
+		sel, selected, err := tileSelection(m, key, query)
+		if err != nil {
+			// the tile handler reports invalid selections
 			next.ServeHTTP(w, r)
+			return
+		}
+		// the tile of the whole map is encoded and cached
+		tileReq := r
+		if selected {
+			tileReq = withoutSelection(r)
+		}
 
-			// check if our request context has been canceled
-			if r.Context().Err() != nil {
-				return
-			}
+		params, err := extractParameters(m, r)
+		if err != nil {
+			// the tile handler reports invalid parameters
+			next.ServeHTTP(w, r)
+			return
+		}
+		key.Version = m.CacheVersion
+		key.ParamsHash = m.ParamsHash(params)
 
-			// if nothing has been written to the buffer, don't write to the cache
-			if buff.Len() == 0 {
-				return
-			}
+		// use the URL path and the params as the key
+		cachedTile, info, hit, err := cache.GetInfo(r.Context(), cacher, key)
+		if err != nil {
+			log.Errorf("cache middleware: error reading from cache: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// concurrent requests of the tile share its encoding
+		flightKey := fmt.Sprintf("%p/%v", a, key)
+
+		// cache miss
+		if !hit {
+			var leader bool
+			flight := encodeInBackground(r, flightKey, func() *bufferResponseWriter {
+				leader = true
+				return encodeTile(cacher, key, next, tileReq)
+			})
 
-			if err := cacher.Set(r.Context(), key, buff.Bytes()); err != nil {
-				log.Warnf("cache response writer err: %v", err)
+			select {
+			case tile := <-flight:
+				if !leader {
+					if o := a.Observer(); o != nil {
+						o.ObserveCoalescedTile(key)
+					}
+				}
+				if selected {
+					tile = tile.selectTile(sel)
+				}
+				tile.writeTo(w, r)
+			case <-r.Context().Done():
+				// the client is gone, the tile is still encoded for the cache
 			}
 			return
 		}
 
+		// regenerate the stale tile in the background, unless it already is
+		if info.Stale {
+			encodeInBackground(r, flightKey, func() *bufferResponseWriter {
+				return encodeTile(cacher, key, next, tileReq)
+			})
+		}
+
+		if selected {
+			if cachedTile, err = sel.SelectMVT(cachedTile); err != nil {
+				log.Errorf("cache middleware: selecting the layers of tile (%v): %v", key, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
 		// mimetype for mapbox vector tiles
 		w.Header().Add("Content-Type", mvt.MimeType)
 
 		// communicate the cache is being used
-		w.Header().Add("Tegola-Cache", "HIT")
+		if info.Stale {
+			w.Header().Add("Tegola-Cache", "STALE")
+		} else {
+			w.Header().Add("Tegola-Cache", "HIT")
+		}
+
+		etag := setCacheHeaders(w.Header(), cachedTile, info)
+		if notModified(r, etag, info.Modified) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
 		w.Header().Add("Content-Length", fmt.Sprintf("%d", len(cachedTile)))
 
 		w.Write(cachedTile)
@@ -88,43 +188,246 @@ func TileCacheHandler(a *atlas.Atlas, next http.Handler) http.Handler {
 	})
 }
 
-func newTileCacheResponseWriter(resp http.ResponseWriter, w io.Writer) http.ResponseWriter {
-	return &tileCacheResponseWriter{
-		resp:  resp,
-		multi: io.MultiWriter(w, resp),
+// tileSelection returns the map reduced to the layers and fields selected by
+// the query params, and if there is a selection. The selection is applied to
+// the tile of the whole map with SelectMVT.
+func tileSelection(m atlas.Map, key *cache.Key, query url.Values) (atlas.Map, bool, error) {
+	layers, fields, err := parseSelection(query)
+	if err != nil || (layers == nil && fields == nil) {
+		return m, false, err
+	}
+
+	m = m.FilterLayersByZoom(slippy.Zoom(key.Z))
+	if key.LayerName != "" {
+		m = m.FilterLayersByName(key.LayerName)
+	}
+	if layers != nil {
+		m = m.FilterLayersByName(layers...)
+		if len(m.Layers) == 0 {
+			return m, false, fmt.Errorf("map (%v) has none of the layers (%v)", m.Name, strings.Join(layers, ","))
+		}
+	}
+	if fields != nil {
+		m = m.FilterLayerFields(fields)
+	}
+	return m, true, nil
+}
+
+// withoutSelection returns a copy of the request for the tile of the whole
+// map, without the layer and field selections
+func withoutSelection(r *http.Request) *http.Request {
+	r = r.Clone(r.Context())
+	query := r.URL.Query()
+	for name := range query {
+		if isSelectionParam(name) {
+			query.Del(name)
+		}
 	}
+	r.URL.RawQuery = query.Encode()
+	return r
 }
 
-// tileCacheResponseWriter wraps http.ResponseWriter (https://golang.org/pkg/net/http/#ResponseWriter)
-// to additionally write the response to a cache when there is a cache MISS
-type tileCacheResponseWriter struct {
-	// status response code
+// tileFlights coalesces the encoding of the tiles requested concurrently, by
+// all the tile endpoints
+var tileFlights singleflight.Group
+
+// encodeInBackground runs encode, unless the tile of flightKey is already
+// being encoded, and sends the tile on the returned channel. The encoding is
+// tracked as background work of the request, as it carries on once the
+// request has completed.
+func encodeInBackground(r *http.Request, flightKey string, encode func() *bufferResponseWriter) <-chan *bufferResponseWriter {
+	tile := make(chan *bufferResponseWriter, 1)
+
+	work := background(r.Context())
+	work.Add(1)
+	go func() {
+		defer work.Done()
+		res, _, _ := tileFlights.Do(flightKey, func() (any, error) {
+			return encode(), nil
+		})
+		tile <- res.(*bufferResponseWriter)
+	}()
+
+	return tile
+}
+
+// encodeTile encodes the tile of the request with next and writes it to the
+// cache. The request is detached from the client, so the tile is encoded for
+// the other requests waiting on it, or for the cache, if the client goes away.
+func encodeTile(cacher cache.Interface, key *cache.Key, next http.Handler, r *http.Request) *bufferResponseWriter {
+	ctx := context.WithoutCancel(r.Context())
+
+	req := r.Clone(ctx)
+	req.Header.Del("If-None-Match")
+	req.Header.Del("If-Modified-Since")
+
+	w := &bufferResponseWriter{}
+	next.ServeHTTP(w, req)
+
+	// only tiles are cached, not errors
+	if w.status != http.StatusOK || w.buff.Len() == 0 {
+		return w
+	}
+
+	if err := cacher.Set(ctx, key, w.buff.Bytes()); err != nil {
+		log.Warnf("cache middleware: writing tile (%v) to the cache: %v", key, err)
+		return w
+	}
+	w.info = cachedInfo(ctx, cacher, key)
+	return w
+}
+
+// cachedInfo returns the info of the tile of the key the cache c holds, for
+// the misses to be served with the cache headers of the hits. The cache is
+// unwrapped so the read is not observed as a hit.
+func cachedInfo(ctx context.Context, c cache.Interface, key *cache.Key) cache.TileInfo {
+	if wc, ok := c.(cache.Wrapped); ok {
+		c = wc.Original()
+	}
+	ig, ok := c.(cache.InfoGetter)
+	if !ok {
+		return cache.TileInfo{}
+	}
+
+	_, info, _, err := ig.GetInfo(ctx, key)
+	if err != nil {
+		log.Warnf("cache middleware: reading the info of tile (%v) from the cache: %v", key, err)
+	}
+	return info
+}
+
+// setCacheHeaders sets the ETag of the tile and, when the cache knows them,
+// the Last-Modified and Cache-Control headers of info. The ETag is returned.
+func setCacheHeaders(h http.Header, tile []byte, info cache.TileInfo) string {
+	// the tile is served compressed or not, the tag is weak
+	etag := tileETag(tile)
+	h.Set("ETag", etag)
+	if !info.Modified.IsZero() {
+		h.Set("Last-Modified", info.Modified.UTC().Format(http.TimeFormat))
+	}
+	if !info.Expires.IsZero() {
+		maxAge := int(time.Until(info.Expires).Seconds())
+		if maxAge < 0 || info.Stale {
+			maxAge = 0
+		}
+		// Cache-Control set in the webserver headers config takes precedence,
+		// the tiles of restricted maps stay private
+		switch h.Get("Cache-Control") {
+		case "":
+			h.Set("Cache-Control", fmt.Sprintf("max-age=%d", maxAge))
+		case cacheControlPrivate:
+			h.Set("Cache-Control", fmt.Sprintf("%v, max-age=%d", cacheControlPrivate, maxAge))
+		}
+	}
+	return etag
+}
+
+// tileETag returns the weak entity tag of the tile
+func tileETag(tile []byte) string {
+	sum := sha256.Sum256(tile)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified reports if the conditional headers of the request match the tile
+// of the etag modified at modified. If-Modified-Since is only evaluated when
+// the request has no If-None-Match.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || modified.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	// Last-Modified has a precision of a second
+	return !modified.Truncate(time.Second).After(t)
+}
+
+// bufferResponseWriter buffers the response encoding a tile, for it to be
+// written to the cache and to the requests waiting on the tile
+type bufferResponseWriter struct {
+	header http.Header
 	status int
-	resp   http.ResponseWriter
-	multi  io.Writer
+	buff   bytes.Buffer
+	// info of the tile written to the cache
+	info cache.TileInfo
 }
 
-func (w *tileCacheResponseWriter) Header() http.Header {
-	// communicate the cache is being used
-	w.resp.Header().Set("Tegola-Cache", "MISS")
+func (w *bufferResponseWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = http.Header{}
+	}
+	return w.header
+}
 
-	return w.resp.Header()
+func (w *bufferResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.buff.Write(b)
 }
 
-func (w *tileCacheResponseWriter) Write(b []byte) (int, error) {
-	// only write to the multi writer when http response == StatusOK
-	if w.status == http.StatusOK {
+func (w *bufferResponseWriter) WriteHeader(i int) {
+	if w.status == 0 {
+		w.status = i
+	}
+}
+
+// selectTile returns the buffered tile reduced to the layers and fields of
+// sel. Responses which are not tiles are returned as is.
+func (w *bufferResponseWriter) selectTile(sel atlas.Map) *bufferResponseWriter {
+	if w.status != http.StatusOK {
+		return w
+	}
 
-		// write to our multi writer
-		return w.multi.Write(b)
+	selected := &bufferResponseWriter{}
+	b, err := sel.SelectMVT(w.buff.Bytes())
+	if err != nil {
+		log.Errorf("cache middleware: selecting the layers of the tile of map (%v): %v", sel.Name, err)
+		http.Error(selected, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return selected
 	}
 
-	// write to the original response writer
-	return w.resp.Write(b)
+	selected.header = w.header.Clone()
+	selected.info = w.info
+	selected.header.Set("Content-Length", strconv.Itoa(len(b)))
+	selected.Write(b)
+	return selected
 }
 
-func (w *tileCacheResponseWriter) WriteHeader(i int) {
-	w.status = i
+// writeTo writes the buffered response to resp, the response of r. Tiles are
+// written with the cache headers of the hits. The buffer is shared by the
+// requests waiting on the tile and is not modified.
+func (w *bufferResponseWriter) writeTo(resp http.ResponseWriter, r *http.Request) {
+	for k, v := range w.header {
+		resp.Header()[k] = v
+	}
+
+	// communicate the cache is being used
+	resp.Header().Set("Tegola-Cache", "MISS")
 
-	w.resp.WriteHeader(i)
+	if w.status == http.StatusOK {
+		etag := setCacheHeaders(resp.Header(), w.buff.Bytes(), w.info)
+		if notModified(r, etag, w.info.Modified) {
+			resp.Header().Del("Content-Length")
+			resp.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	if w.status != 0 {
+		resp.WriteHeader(w.status)
+	}
+	resp.Write(w.buff.Bytes())
 }
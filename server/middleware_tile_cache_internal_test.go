@@ -1,53 +1,69 @@
 package server
 
 import (
-	"bytes"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"testing"
 )
 
-func TestTileCacheResponseWriter(t *testing.T) {
+func TestBufferResponseWriter(t *testing.T) {
 	type tcase struct {
 		data         []byte
 		responseCode int
-		expected     []byte
+		expectedCode int
 	}
 
 	tests := map[string]tcase{
 		"1": {
 			data:         []byte{0x53, 0x69, 0x6c, 0x61, 0x73},
 			responseCode: http.StatusOK,
-			expected:     []byte{0x53, 0x69, 0x6c, 0x61, 0x73},
+			expectedCode: http.StatusOK,
 		},
 		"2": {
 			data:         []byte{0x53, 0x69, 0x6c, 0x61, 0x73},
 			responseCode: http.StatusInternalServerError,
-			expected:     []byte{},
+			expectedCode: http.StatusInternalServerError,
+		},
+		"implicit status": {
+			data:         []byte{0x53, 0x69, 0x6c, 0x61, 0x73},
+			expectedCode: http.StatusOK,
 		},
 	}
 
 	fn := func(tc tcase) func(t *testing.T) {
 		return func(t *testing.T) {
-			var buff bytes.Buffer
-
-			rw := newTileCacheResponseWriter(httptest.NewRecorder(), &buff)
-			rw.WriteHeader(tc.responseCode)
+			rw := &bufferResponseWriter{}
+			rw.Header().Set("Content-Type", "application/test")
+			if tc.responseCode != 0 {
+				rw.WriteHeader(tc.responseCode)
+			}
 			_, err := rw.Write(tc.data)
 			if err != nil {
 				t.Errorf("unable to write to response writer: %v", err)
 				return
 			}
 
-			if buff.Len() != len(tc.expected) {
-				t.Errorf("expected (%v) does not match output (%v)", tc.expected, buff.Bytes())
-				return
-			}
+			// the buffered response is written to each waiting request
+			for i := 0; i < 2; i++ {
+				w := httptest.NewRecorder()
+				rw.writeTo(w, httptest.NewRequest(http.MethodGet, "/", nil))
 
-			if len(tc.expected) > 0 && !reflect.DeepEqual(buff.Bytes(), tc.expected) {
-				t.Errorf("expected (%v) does not match output (%v)", tc.expected, buff.Bytes())
-				return
+				if w.Code != tc.expectedCode {
+					t.Errorf("status, expected %v got %v", tc.expectedCode, w.Code)
+				}
+				if !reflect.DeepEqual(w.Body.Bytes(), tc.data) {
+					t.Errorf("expected (%v) does not match output (%v)", tc.data, w.Body.Bytes())
+				}
+				if h := w.Header().Get("Content-Type"); h != "application/test" {
+					t.Errorf("header Content-Type, expected application/test got %v", h)
+				}
+				if h := w.Header().Get("Tegola-Cache"); h != "MISS" {
+					t.Errorf("header Tegola-Cache, expected MISS got %v", h)
+				}
+				if h := w.Header().Get("ETag"); (h != "") != (tc.expectedCode == http.StatusOK) {
+					t.Errorf("header ETag, unexpected %q for status %v", h, tc.expectedCode)
+				}
 			}
 		}
 	}
@@ -1,11 +1,29 @@
 package server_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/golang/protobuf/proto"
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
 	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/observer"
+	"github.com/go-spatial/tegola/observability/prometheus"
+	"github.com/go-spatial/tegola/provider"
 	"github.com/go-spatial/tegola/server"
 )
 
@@ -55,6 +73,11 @@ func TestMiddlewareTileCacheHandler(t *testing.T) {
 				t.Errorf("Tegoal-Cache, expected HIT got %v", w.Header().Get("Tegola-Cache"))
 				return
 			}
+
+			// the format of the cached tiles is negotiated too
+			if v := w.Header().Values("Vary"); !reflect.DeepEqual(v, []string{"Accept"}) {
+				t.Errorf("header Vary, expected [Accept] got %v", v)
+			}
 		}
 	}
 
@@ -139,3 +162,533 @@ func TestMiddlewareTileCacheHandlerIgnoreParams(t *testing.T) {
 		t.Run(name, fn(tc))
 	}
 }
+
+func TestMiddlewareTileCacheHandlerMapParams(t *testing.T) {
+	type request struct {
+		uri string
+		// expected Tegola-Cache header
+		cache string
+	}
+
+	type tcase struct {
+		requests []request
+	}
+
+	noCacheKey := false
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			server.URIPrefix = "/"
+
+			m := atlas.NewWebMercatorMap(testMapName)
+			m.Layers = append(m.Layers, testLayer1, testLayer2, testLayer3)
+			m.Params = []provider.QueryParameter{
+				{Name: "year", Token: "!YEAR!", Type: "int", SQL: "AND year = ?", DefaultValue: "2020"},
+				{Name: "trace", Token: "!TRACE!", Type: "string", DefaultSQL: " ", CacheKey: &noCacheKey},
+			}
+
+			a := &atlas.Atlas{}
+			a.AddMap(m)
+			cacher, _ := memory.New(nil)
+			a.SetCache(cacher)
+
+			router := server.NewRouter(a)
+			for i, req := range tc.requests {
+				r, err := http.NewRequest(http.MethodGet, req.uri, nil)
+				if err != nil {
+					t.Fatalf("request %v, error making request, expected nil got %v", i, err)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, r)
+
+				if w.Header().Get("Tegola-Cache") != req.cache {
+					t.Errorf("request %v (%v), header Tegola-Cache, expected %q got %q", i, req.uri, req.cache, w.Header().Get("Tegola-Cache"))
+				}
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"same param": {
+			requests: []request{
+				{uri: "/maps/test-map/10/2/3.pbf?year=2021", cache: "MISS"},
+				{uri: "/maps/test-map/10/2/3.pbf?year=2021", cache: "HIT"},
+			},
+		},
+		"different params": {
+			requests: []request{
+				{uri: "/maps/test-map/10/2/3.pbf?year=2021", cache: "MISS"},
+				{uri: "/maps/test-map/10/2/3.pbf?year=2022", cache: "MISS"},
+				{uri: "/maps/test-map/10/2/3.pbf?year=2021", cache: "HIT"},
+			},
+		},
+		"normalized values": {
+			requests: []request{
+				{uri: "/maps/test-map/10/2/3.pbf?year=2021", cache: "MISS"},
+				{uri: "/maps/test-map/10/2/3.pbf?year=02021", cache: "HIT"},
+			},
+		},
+		"default value": {
+			requests: []request{
+				{uri: "/maps/test-map/10/2/3.pbf", cache: "MISS"},
+				{uri: "/maps/test-map/10/2/3.pbf?year=2020", cache: "HIT"},
+			},
+		},
+		"param not in cache key": {
+			requests: []request{
+				{uri: "/maps/test-map/test-layer/4/2/3.pbf?year=2021&trace=a", cache: "MISS"},
+				{uri: "/maps/test-map/test-layer/4/2/3.pbf?trace=b&year=2021", cache: "HIT"},
+			},
+		},
+		"undeclared query parameter": {
+			requests: []request{
+				{uri: "/maps/test-map/10/2/3.pbf?year=2021", cache: "MISS"},
+				{uri: "/maps/test-map/10/2/3.pbf?year=2021&debug=true", cache: ""},
+			},
+		},
+		"invalid param": {
+			requests: []request{
+				{uri: "/maps/test-map/10/2/3.pbf?year=abc", cache: ""},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestMiddlewareTileCacheHandlerCacheVersion(t *testing.T) {
+	server.URIPrefix = "/"
+
+	m := atlas.NewWebMercatorMap(testMapName)
+	m.Layers = append(m.Layers, testLayer1, testLayer2, testLayer3)
+	m.CacheVersion = "v2"
+
+	a := &atlas.Atlas{}
+	a.AddMap(m)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+
+	router := server.NewRouter(a)
+	requests := []struct {
+		uri string
+		// expected Tegola-Cache header
+		cache string
+	}{
+		{uri: "/maps/test-map/10/2/3.pbf", cache: "MISS"},
+		{uri: "/maps/test-map/10/2/3.pbf", cache: "HIT"},
+		// the version and params elements of the cache keys are not tile URLs
+		{uri: "/maps/test-map/~v2/10/2/3.pbf", cache: ""},
+		{uri: "/maps/test-map/@0123abcd/10/2/3.pbf", cache: ""},
+	}
+	for i, req := range requests {
+		r, err := http.NewRequest(http.MethodGet, req.uri, nil)
+		if err != nil {
+			t.Fatalf("request %v, error making request, expected nil got %v", i, err)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Header().Get("Tegola-Cache") != req.cache {
+			t.Errorf("request %v (%v), header Tegola-Cache, expected %q got %q", i, req.uri, req.cache, w.Header().Get("Tegola-Cache"))
+		}
+	}
+
+	key := cache.Key{MapName: testMapName, Version: "v2", Z: 10, X: 2, Y: 3}
+	if _, hit, _ := cacher.Get(context.Background(), &key); !hit {
+		t.Errorf("cache key %v, expected hit got miss", key)
+	}
+}
+
+func TestMiddlewareTileCacheHandlerGeoJSON(t *testing.T) {
+	server.URIPrefix = "/"
+
+	a := newTestMapWithLayers(testLayer1, testLayer2, testLayer3)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+
+	// warm the cache with the MVT tile
+	w, router, err := doRequest(t, a, http.MethodGet, "/maps/test-map/10/2/3.pbf", nil)
+	if err != nil {
+		t.Fatalf("error making request, expected nil got %v", err)
+	}
+	if w.Header().Get("Tegola-Cache") != "MISS" {
+		t.Fatalf("header Tegola-Cache, expected MISS got %v", w.Header().Get("Tegola-Cache"))
+	}
+
+	// GeoJSON requests for the same tile must not be served from the cache
+	r, err := http.NewRequest(http.MethodGet, "/maps/test-map/10/2/3.geojson", nil)
+	if err != nil {
+		t.Fatalf("error making request, expected nil got %v", err)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	if h := w.Header().Get("Tegola-Cache"); h != "" {
+		t.Errorf("header Tegola-Cache, expected none got %v", h)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != server.GeoJSONMimeType {
+		t.Errorf("content type, expected %v got %v", server.GeoJSONMimeType, ct)
+	}
+}
+
+func TestMiddlewareTileCacheHandlerConditional(t *testing.T) {
+	server.URIPrefix = "/"
+
+	a := newTestMapWithLayers(testLayer1, testLayer2, testLayer3)
+	cacher, _ := memory.New(dict.Dict{"ttl": 60})
+	a.SetCache(cacher)
+
+	const uri = "/maps/test-map/10/2/3.pbf"
+
+	// warm the cache
+	w, router, err := doRequest(t, a, http.MethodGet, uri, nil)
+	if err != nil {
+		t.Fatalf("error making request, expected nil got %v", err)
+	}
+	if w.Header().Get("Tegola-Cache") != "MISS" {
+		t.Fatalf("header Tegola-Cache, expected MISS got %v", w.Header().Get("Tegola-Cache"))
+	}
+	// misses are served with the cache headers of the hits
+	missETag, missCacheControl := w.Header().Get("ETag"), w.Header().Get("Cache-Control")
+
+	r := httptest.NewRequest(http.MethodGet, uri, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	etag, lastModified := w.Header().Get("ETag"), w.Header().Get("Last-Modified")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Errorf("header ETag, expected a weak tag got %q", etag)
+	}
+	if lastModified == "" {
+		t.Errorf("header Last-Modified, expected a date got none")
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "max-age=59" && cc != "max-age=60" {
+		t.Errorf("header Cache-Control, expected max-age=60 got %q", cc)
+	}
+	if missETag != etag {
+		t.Errorf("miss header ETag, expected %q got %q", etag, missETag)
+	}
+	if missCacheControl != "max-age=59" && missCacheControl != "max-age=60" {
+		t.Errorf("miss header Cache-Control, expected max-age=60 got %q", missCacheControl)
+	}
+
+	type tcase struct {
+		headers map[string]string
+		status  int
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, uri, nil)
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status, expected %v got %v", tc.status, w.Code)
+			}
+			if tc.status == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("body, expected none got %v bytes", w.Body.Len())
+			}
+			if w.Header().Get("ETag") != etag {
+				t.Errorf("header ETag, expected %q got %q", etag, w.Header().Get("ETag"))
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"if-none-match": {
+			headers: map[string]string{"If-None-Match": etag},
+			status:  http.StatusNotModified,
+		},
+		"if-none-match list": {
+			headers: map[string]string{"If-None-Match": `"other", ` + strings.TrimPrefix(etag, "W/")},
+			status:  http.StatusNotModified,
+		},
+		"if-none-match any": {
+			headers: map[string]string{"If-None-Match": "*"},
+			status:  http.StatusNotModified,
+		},
+		"if-none-match other": {
+			headers: map[string]string{"If-None-Match": `W/"other"`},
+			status:  http.StatusOK,
+		},
+		"if-modified-since": {
+			headers: map[string]string{"If-Modified-Since": lastModified},
+			status:  http.StatusNotModified,
+		},
+		"if-modified-since earlier": {
+			headers: map[string]string{"If-Modified-Since": "Mon, 02 Jan 2006 15:04:05 GMT"},
+			status:  http.StatusOK,
+		},
+		"if-none-match takes precedence": {
+			headers: map[string]string{"If-None-Match": `W/"other"`, "If-Modified-Since": lastModified},
+			status:  http.StatusOK,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestMiddlewareTileCacheHandlerMissMetrics(t *testing.T) {
+	server.URIPrefix = "/"
+
+	o, err := prometheus.New(dict.Dict{})
+	if err != nil {
+		t.Fatalf("observer, expected nil got %v", err)
+	}
+	a := newTestMapWithLayers(testLayer1, testLayer2, testLayer3)
+	a.SetObservability(o)
+	cacher, _ := memory.New(dict.Dict{"ttl": 60})
+	a.SetCache(cacher)
+
+	// the counters are registered with the default registry, shared by the tests
+	count := func(name string) float64 {
+		var n float64
+		mfs, err := prom.DefaultGatherer.Gather()
+		if err != nil {
+			t.Fatalf("gather, expected nil got %v", err)
+		}
+		for _, mf := range mfs {
+			if mf.GetName() != name {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				n += m.GetCounter().GetValue()
+			}
+		}
+		return n
+	}
+	hits, misses := count("tegola_cache_hits_total"), count("tegola_cache_misses_total")
+
+	w, _, err := doRequest(t, a, http.MethodGet, "/maps/test-map/10/2/3.pbf", nil)
+	if err != nil {
+		t.Fatalf("error making request, expected nil got %v", err)
+	}
+	if w.Header().Get("Tegola-Cache") != "MISS" {
+		t.Fatalf("header Tegola-Cache, expected MISS got %v", w.Header().Get("Tegola-Cache"))
+	}
+	if w.Header().Get("Cache-Control") == "" {
+		t.Errorf("header Cache-Control, expected max-age got none")
+	}
+
+	if n := count("tegola_cache_hits_total") - hits; n != 0 {
+		t.Errorf("cache hits, expected 0 got %v", n)
+	}
+	if n := count("tegola_cache_misses_total") - misses; n != 1 {
+		t.Errorf("cache misses, expected 1 got %v", n)
+	}
+}
+
+func TestMiddlewareTileCacheHandlerStale(t *testing.T) {
+	server.URIPrefix = "/"
+
+	a := newTestMapWithLayers(testLayer1, testLayer2, testLayer3)
+	cacher, _ := memory.New(dict.Dict{"stale_while_revalidate": 60})
+	mc := cacher.(*memory.MemoryCache)
+	mc.TTL = 50 * time.Millisecond
+	a.SetCache(cacher)
+
+	const uri = "/maps/test-map/10/2/3.pbf"
+	key := cache.Key{MapName: testMapName, Z: 10, X: 2, Y: 3}
+
+	w, router, err := doRequest(t, a, http.MethodGet, uri, nil)
+	if err != nil {
+		t.Fatalf("error making request, expected nil got %v", err)
+	}
+	if w.Header().Get("Tegola-Cache") != "MISS" {
+		t.Fatalf("header Tegola-Cache, expected MISS got %v", w.Header().Get("Tegola-Cache"))
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	// the expired tile is served while it is regenerated
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, uri, nil))
+	if w.Header().Get("Tegola-Cache") != "STALE" {
+		t.Fatalf("header Tegola-Cache, expected STALE got %v", w.Header().Get("Tegola-Cache"))
+	}
+	if w.Code != http.StatusOK || w.Body.Len() == 0 {
+		t.Errorf("response, expected a tile got status %v with %v bytes", w.Code, w.Body.Len())
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "max-age=0" {
+		t.Errorf("header Cache-Control, expected max-age=0 got %q", cc)
+	}
+
+	// wait for the tile to be regenerated
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, hit, _ := cacher.Get(context.Background(), &key); hit {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the stale tile to be regenerated")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, uri, nil))
+	if w.Header().Get("Tegola-Cache") != "HIT" {
+		t.Errorf("header Tegola-Cache, expected HIT got %v", w.Header().Get("Tegola-Cache"))
+	}
+}
+
+// coalescedObserver counts the coalesced tile requests
+type coalescedObserver struct {
+	observer.Null
+	coalesced atomic.Int32
+}
+
+func (o *coalescedObserver) ObserveCoalescedTile(*cache.Key) { o.coalesced.Add(1) }
+
+func TestMiddlewareTileCacheHandlerCoalesce(t *testing.T) {
+	server.URIPrefix = "/"
+
+	const requests = 5
+
+	a := newTestMapWithLayers(testLayer1, testLayer2, testLayer3)
+	obs := &coalescedObserver{}
+	a.SetObservability(obs)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+
+	var encodes atomic.Int32
+	release := make(chan struct{})
+	handler := server.TileCacheHandler(a, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodes.Add(1)
+		<-release
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("tile"))
+	}))
+
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, requests)
+	for i := range responses {
+		responses[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(responses[i], httptest.NewRequest(http.MethodGet, "/maps/test-map/10/2/3.pbf", nil))
+		}()
+	}
+
+	// let the requests join the encoding of the first one
+	for encodes.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := encodes.Load(); n != 1 {
+		t.Errorf("encodes, expected 1 got %v", n)
+	}
+	if n := obs.coalesced.Load(); n != requests-1 {
+		t.Errorf("coalesced requests, expected %v got %v", requests-1, n)
+	}
+	for i, w := range responses {
+		if w.Code != http.StatusOK || w.Body.String() != "tile" {
+			t.Errorf("response %v, expected tile got status %v body %q", i, w.Code, w.Body.String())
+		}
+		if h := w.Header().Get("Tegola-Cache"); h != "MISS" {
+			t.Errorf("response %v, header Tegola-Cache, expected MISS got %v", i, h)
+		}
+	}
+
+	key := cache.Key{MapName: testMapName, Z: 10, X: 2, Y: 3}
+	if _, hit, _ := cacher.Get(context.Background(), &key); !hit {
+		t.Errorf("cache key %v, expected hit got miss", key)
+	}
+}
+
+func TestMiddlewareTileCacheHandlerSelection(t *testing.T) {
+	server.URIPrefix = "/"
+
+	a := newTestMapWithLayers(testLayer1, testLayer2, testLayer3)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+	router := server.NewRouter(a)
+
+	type request struct {
+		uri string
+		// expected Tegola-Cache header
+		cache string
+		// expectedKeys of each layer of the tile
+		expectedKeys map[string][]string
+	}
+
+	// requests are played in order against the same cache, the selections
+	// are served from the tile of the whole map
+	requests := []request{
+		{
+			uri:          "/maps/test-map/10/2/3.pbf?layers=test-layer",
+			cache:        "MISS",
+			expectedKeys: map[string][]string{"test-layer": {"type"}},
+		},
+		{
+			uri:   "/maps/test-map/10/2/3.pbf",
+			cache: "HIT",
+			expectedKeys: map[string][]string{
+				"test-layer":        {"type"},
+				"test-layer-2-name": {"foo", "type"},
+			},
+		},
+		{
+			uri:          "/maps/test-map/10/2/3.pbf?layers=test-layer-2-name&fields[test-layer-2-name]=foo",
+			cache:        "HIT",
+			expectedKeys: map[string][]string{"test-layer-2-name": {"foo"}},
+		},
+		{
+			uri:   "/maps/test-map/10/2/3.pbf?fields[test-layer]=",
+			cache: "HIT",
+			expectedKeys: map[string][]string{
+				"test-layer":        {},
+				"test-layer-2-name": {"foo", "type"},
+			},
+		},
+	}
+
+	for i, req := range requests {
+		r := httptest.NewRequest(http.MethodGet, req.uri, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %v (%v), status code, expected %v got %v", i, req.uri, http.StatusOK, w.Code)
+		}
+		if h := w.Header().Get("Tegola-Cache"); h != req.cache {
+			t.Errorf("request %v (%v), header Tegola-Cache, expected %q got %q", i, req.uri, req.cache, h)
+		}
+		if cl := w.Header().Get("Content-Length"); cl != strconv.Itoa(w.Body.Len()) {
+			t.Errorf("request %v (%v), header Content-Length, expected %v got %v", i, req.uri, w.Body.Len(), cl)
+		}
+
+		var tile vectorTile.Tile
+		if err := proto.Unmarshal(w.Body.Bytes(), &tile); err != nil {
+			t.Fatalf("request %v (%v), unmarshalling response body, expected nil got %v", i, req.uri, err)
+		}
+		keys := make(map[string][]string, len(tile.Layers))
+		for _, l := range tile.Layers {
+			k := append([]string{}, l.Keys...)
+			sort.Strings(k)
+			keys[l.GetName()] = k
+		}
+		if !reflect.DeepEqual(keys, req.expectedKeys) {
+			t.Errorf("request %v (%v), layer keys, expected %v got %v", i, req.uri, req.expectedKeys, keys)
+		}
+	}
+
+	// unknown layers are reported by the tile handler
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/maps/test-map/10/2/3.pbf?layers=unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown layers, status code, expected %v got %v", http.StatusNotFound, w.Code)
+	}
+}
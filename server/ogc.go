@@ -0,0 +1,206 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/go-spatial/geom/encoding/mvt"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/grid"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// OGC API – Tiles (https://docs.ogc.org/is/20-057/20-057.html) support.
+// The endpoints describe the maps of the atlas as collections with vector
+// tilesets. Tiles are served by the same handler as the /maps endpoints.
+
+// Link relation types used by the OGC API endpoints
+const (
+	OGCRelConformance    = "http://www.opengis.net/def/rel/ogc/1.0/conformance"
+	OGCRelData           = "http://www.opengis.net/def/rel/ogc/1.0/data"
+	OGCRelTilingSchemes  = "http://www.opengis.net/def/rel/ogc/1.0/tiling-schemes"
+	OGCRelTilingScheme   = "http://www.opengis.net/def/rel/ogc/1.0/tiling-scheme"
+	OGCRelTilesetsVector = "http://www.opengis.net/def/rel/ogc/1.0/tilesets-vector"
+)
+
+const (
+	// CRS84 is the identifier of WGS84 with longitude / latitude axis order
+	CRS84 = "http://www.opengis.net/def/crs/OGC/1.3/CRS84"
+
+	mimeTypeJSON = "application/json"
+)
+
+// OGCConformanceClasses are the conformance classes implemented by the OGC API endpoints
+var OGCConformanceClasses = []string{
+	"http://www.opengis.net/spec/ogcapi-common-1/1.0/conf/core",
+	"http://www.opengis.net/spec/ogcapi-common-1/1.0/conf/json",
+	"http://www.opengis.net/spec/ogcapi-common-2/1.0/conf/collections",
+	"http://www.opengis.net/spec/ogcapi-tiles-1/1.0/conf/core",
+	"http://www.opengis.net/spec/ogcapi-tiles-1/1.0/conf/tileset",
+	"http://www.opengis.net/spec/ogcapi-tiles-1/1.0/conf/tilesets-list",
+	"http://www.opengis.net/spec/ogcapi-tiles-1/1.0/conf/geodata-tilesets",
+	"http://www.opengis.net/spec/ogcapi-tiles-1/1.0/conf/mvt",
+	"http://www.opengis.net/spec/tms/2.0/conf/tilematrixset",
+	"http://www.opengis.net/spec/tms/2.0/conf/json-tilematrixset",
+}
+
+// Link is a web link as used throughout the OGC API responses
+type Link struct {
+	Href      string `json:"href"`
+	Rel       string `json:"rel"`
+	Type      string `json:"type,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Templated bool   `json:"templated,omitempty"`
+}
+
+// TileMatrixSet is a tiling scheme as described by the OGC Two Dimensional
+// Tile Matrix Set standard (https://docs.ogc.org/is/17-083r4/17-083r4.html)
+type TileMatrixSet struct {
+	ID                string       `json:"id"`
+	Title             string       `json:"title,omitempty"`
+	URI               string       `json:"uri,omitempty"`
+	CRS               string       `json:"crs"`
+	OrderedAxes       []string     `json:"orderedAxes,omitempty"`
+	WellKnownScaleSet string       `json:"wellKnownScaleSet,omitempty"`
+	TileMatrices      []TileMatrix `json:"tileMatrices"`
+}
+
+// TileMatrix is a single zoom level of a TileMatrixSet
+type TileMatrix struct {
+	ID               string     `json:"id"`
+	ScaleDenominator float64    `json:"scaleDenominator"`
+	CellSize         float64    `json:"cellSize"`
+	CornerOfOrigin   string     `json:"cornerOfOrigin,omitempty"`
+	PointOfOrigin    [2]float64 `json:"pointOfOrigin"`
+	TileWidth        uint       `json:"tileWidth"`
+	TileHeight       uint       `json:"tileHeight"`
+	MatrixWidth      uint       `json:"matrixWidth"`
+	MatrixHeight     uint       `json:"matrixHeight"`
+}
+
+// ogcTileMatrixSetFor returns the definition of the tile grid
+func ogcTileMatrixSetFor(g *grid.Grid) TileMatrixSet {
+	tms := TileMatrixSet{
+		ID:                g.ID,
+		Title:             g.Title,
+		URI:               g.URI,
+		CRS:               g.CRS,
+		WellKnownScaleSet: g.WellKnownScaleSet,
+	}
+	switch g.ID {
+	case grid.WebMercatorQuadID:
+		tms.OrderedAxes = []string{"E", "N"}
+	case grid.WorldCRS84QuadID:
+		tms.OrderedAxes = []string{"Lon", "Lat"}
+	}
+
+	for z := uint(0); z <= g.MaxZoom; z++ {
+		width, height := g.MatrixSize(z)
+		tms.TileMatrices = append(tms.TileMatrices, TileMatrix{
+			ID:               strconv.FormatUint(uint64(z), 10),
+			ScaleDenominator: g.ScaleDenominator(z),
+			CellSize:         g.CellSize(z),
+			CornerOfOrigin:   "topLeft",
+			PointOfOrigin:    [2]float64{g.Extent.MinX(), g.Extent.MaxY()},
+			TileWidth:        g.TileSize,
+			TileHeight:       g.TileSize,
+			MatrixWidth:      width,
+			MatrixHeight:     height,
+		})
+	}
+
+	return tms
+}
+
+// ogcURL builds the absolute URL of an OGC API resource. The parts are
+// joined as is, so they can contain uri template variables.
+func ogcURL(r *http.Request, parts ...string) string {
+	return fmt.Sprintf("%s://%s%s", scheme(r), hostName(r).Host, path.Join(append([]string{"/", URIPrefix}, parts...)...))
+}
+
+// writeJSON encodes v as the JSON response body
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", mimeTypeJSON)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("error trying to encode response (%s)", err)
+	}
+}
+
+// ogcLayer is a layer of a map, merged over all the zooms it's configured for
+type ogcLayer struct {
+	name             string
+	minZoom, maxZoom uint
+}
+
+// ogcLayers returns the layers of the map by their MVT name. Layers sharing a
+// name are reported once, with the widest zoom range.
+func ogcLayers(m atlas.Map) (layers []ogcLayer) {
+LAYERS:
+	for _, l := range m.Layers {
+		for i := range layers {
+			if layers[i].name != l.MVTName() {
+				continue
+			}
+			if layers[i].minZoom > l.MinZoom {
+				layers[i].minZoom = l.MinZoom
+			}
+			if layers[i].maxZoom < l.MaxZoom {
+				layers[i].maxZoom = l.MaxZoom
+			}
+			continue LAYERS
+		}
+
+		layers = append(layers, ogcLayer{
+			name:    l.MVTName(),
+			minZoom: l.MinZoom,
+			maxZoom: l.MaxZoom,
+		})
+	}
+	return layers
+}
+
+// ogcZoomRange returns the zoom range covered by the layers of the map
+func ogcZoomRange(m atlas.Map) (minZoom, maxZoom uint) {
+	minZoom = tegola.MaxZ
+	for _, l := range m.Layers {
+		if l.MinZoom < minZoom {
+			minZoom = l.MinZoom
+		}
+		if l.MaxZoom > maxZoom {
+			maxZoom = l.MaxZoom
+		}
+	}
+	if minZoom > maxZoom {
+		minZoom = 0
+	}
+	if maxZoom > tegola.MaxZ {
+		maxZoom = tegola.MaxZ
+	}
+	return minZoom, maxZoom
+}
+
+// wantsJSON reports if the request asks for a JSON response with the "f"
+// query parameter or the Accept header
+func wantsJSON(r *http.Request) bool {
+	if f := r.URL.Query().Get("f"); f != "" {
+		return f == "json"
+	}
+	return strings.Contains(r.Header.Get("Accept"), mimeTypeJSON)
+}
+
+// mvtLink returns a link to the tiles with the given uri template
+func mvtLink(href, rel string) Link {
+	return Link{
+		Href:      href,
+		Rel:       rel,
+		Type:      mvt.MimeType,
+		Templated: true,
+	}
+}
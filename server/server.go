@@ -2,16 +2,22 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/url"
 	"os"
+	"sync"
+	"sync/atomic"
 
 	"github.com/dimfeld/httptreemux"
 
 	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/auth"
 	"github.com/go-spatial/tegola/internal/build"
 	"github.com/go-spatial/tegola/internal/log"
 	"github.com/go-spatial/tegola/observability"
+	"github.com/go-spatial/tegola/provider"
 )
 
 const (
@@ -21,7 +27,15 @@ const (
 
 	// QueryKeyDebug is a common query string key used throughout the pacakge
 	// the value should always be a boolean
-	QueryKeyDebug = "debug"
+	QueryKeyDebug = provider.QueryParamDebug
+
+	// QueryKeyLayers is the query string key selecting the layers of a tile,
+	// the value is a comma separated list of layer names
+	QueryKeyLayers = provider.QueryParamLayers
+
+	// QueryKeyFields is the query string key prefix selecting the fields of
+	// the layers of a tile, i.e. fields[roads]=name,class
+	QueryKeyFields = provider.QueryParamFields
 )
 
 var (
@@ -57,6 +71,25 @@ var (
 	// (See https://github.com/go-spatial/tegola/pull/967)
 	ProxyProtocol string
 
+	// AdminToken is the bearer token required by the admin endpoints. The
+	// admin endpoints are disabled when it's not set.
+	// configurable via the tegola config.toml file (set in main.go)
+	AdminToken string
+
+	// Authenticators authenticate the requests of the map endpoints, tried in
+	// order. Requests are anonymous when none is set.
+	// configurable via the tegola config.toml file (set in main.go)
+	Authenticators []auth.Authenticator
+
+	// RateLimit limits the requests of each client to the map endpoints,
+	// nil (default) is no limit.
+	// configurable via the tegola config.toml file (set in main.go)
+	RateLimit *RateLimiter
+
+	// TileConcurrency caps the tiles rendered at once, nil (default) is no limit.
+	// configurable via the tegola config.toml file (set in main.go)
+	TileConcurrency *TileLimiter
+
 	// DefaultCORSHeaders define the default CORS response headers added to all requests
 	DefaultCORSHeaders = map[string]string{
 		"Access-Control-Allow-Origin":  "*",
@@ -86,23 +119,59 @@ func NewRouter(a *atlas.Atlas) *httptreemux.TreeMux {
 
 	// capabilities endpoints
 	group.UsingContext().
-		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/capabilities", o, HeadersHandler(HandleCapabilities{})))
+		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/capabilities", o, HeadersHandler(AuthHandler(RateLimitHandler(HandleCapabilities{Atlas: a})))))
 	group.UsingContext().
-		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/capabilities/:map_name", o, HeadersHandler(HandleMapCapabilities{})))
+		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/capabilities/:map_name", o, HeadersHandler(AuthHandler(RateLimitHandler(HandleMapCapabilities{Atlas: a})))))
 
 	// map tiles
 	hMapLayerZXY := HandleMapLayerZXY{Atlas: a}
 	group.UsingContext().
-		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/maps/:map_name/:z/:x/:y", o, HeadersHandler(GZipHandler(TileCacheHandler(a, hMapLayerZXY)))))
+		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/maps/:map_name/:z/:x/:y", o, HeadersHandler(AuthHandler(RateLimitHandler(GZipHandler(TileCacheHandler(a, hMapLayerZXY)))))))
 	group.UsingContext().
-		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/maps/:map_name/:layer_name/:z/:x/:y", o, HeadersHandler(GZipHandler(TileCacheHandler(a, hMapLayerZXY)))))
+		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/maps/:map_name/:layer_name/:z/:x/:y", o, HeadersHandler(AuthHandler(RateLimitHandler(GZipHandler(TileCacheHandler(a, hMapLayerZXY)))))))
 
 	// map style
 	group.UsingContext().
-		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/maps/:map_name/style.json", o, HeadersHandler(HandleMapStyle{})))
+		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/maps/:map_name/style.json", o, HeadersHandler(AuthHandler(RateLimitHandler(HandleMapStyle{Atlas: a})))))
+
+	// OGC API – Tiles endpoints
+	group.UsingContext().
+		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/conformance", o, HeadersHandler(HandleOGCConformance{})))
+	group.UsingContext().
+		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/collections", o, HeadersHandler(AuthHandler(RateLimitHandler(HandleOGCCollections{Atlas: a})))))
+	group.UsingContext().
+		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/collections/:map_name", o, HeadersHandler(AuthHandler(RateLimitHandler(HandleOGCCollection{Atlas: a})))))
+	group.UsingContext().
+		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/collections/:map_name/tiles", o, HeadersHandler(AuthHandler(RateLimitHandler(HandleOGCTilesets{Atlas: a})))))
+	group.UsingContext().
+		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/collections/:map_name/tiles/:tms", o, HeadersHandler(AuthHandler(RateLimitHandler(HandleOGCTileset{Atlas: a})))))
+	group.UsingContext().
+		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/collections/:map_name/tiles/:tms/:z/:y/:x", o, HeadersHandler(AuthHandler(RateLimitHandler(HandleOGCTile{Atlas: a, Next: GZipHandler(TileCacheHandler(a, hMapLayerZXY))})))))
+	group.UsingContext().
+		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/tileMatrixSets", o, HeadersHandler(HandleOGCTileMatrixSets{})))
+	group.UsingContext().
+		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/tileMatrixSets/:tms", o, HeadersHandler(HandleOGCTileMatrixSet{})))
+
+	// WMTS endpoints
+	group.UsingContext().
+		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/wmts", o, HeadersHandler(AuthHandler(RateLimitHandler(HandleWMTS{Atlas: a, Next: GZipHandler(TileCacheHandler(a, hMapLayerZXY))})))))
+	group.UsingContext().
+		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/wmts/1.0.0/WMTSCapabilities.xml", o, HeadersHandler(AuthHandler(RateLimitHandler(HandleWMTSCapabilities{Atlas: a})))))
+	group.UsingContext().
+		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/wmts/1.0.0/:layer/:style/:tms/:z/:y/:x", o, HeadersHandler(AuthHandler(RateLimitHandler(HandleWMTSTile{Atlas: a, Next: GZipHandler(TileCacheHandler(a, hMapLayerZXY))})))))
+
+	// admin endpoints
+	if AdminToken != "" {
+		group.UsingContext().
+			Handler(observability.InstrumentAPIHandler(http.MethodPost, "/admin/cache/purge", o, HeadersHandler(AdminAuthHandler(HandleAdminCachePurge{Atlas: a}))))
+	}
 
 	// setup viewer routes, which can be excluded via build flags
-	setupViewer(o, group)
+	viewer := setupViewer(o, group)
+
+	// the OGC API landing page shares the root with the viewer
+	group.UsingContext().
+		Handler(observability.InstrumentAPIHandler(http.MethodGet, "/", o, HeadersHandler(HandleOGCLandingPage{Next: viewer})))
 
 	return r
 }
@@ -112,7 +181,7 @@ func Start(a *atlas.Atlas, port string) *http.Server {
 	// notify the user the server is starting
 	log.Infof("starting tegola server (%v) on port %v", build.Version, port)
 
-	srv := &http.Server{Addr: port, Handler: NewRouter(a)}
+	srv := &http.Server{Addr: port, Handler: newAtlasRouter(a)}
 
 	// start our server
 	go func() {
@@ -141,6 +210,90 @@ func Start(a *atlas.Atlas, port string) *http.Server {
 	return srv
 }
 
+// ErrNotReloadable is returned by Reload for servers not started by Start
+var ErrNotReloadable = errors.New("server: server was not started by Start, can not reload")
+
+// Reload replaces the atlas served by srv, which must have been started by
+// Start. New requests are routed to the new atlas right away. Reload blocks
+// until the requests in flight on the previous atlas, and the tiles they
+// left encoding in the background, have completed, after which the resources
+// of the previous atlas can be released.
+func Reload(srv *http.Server, a *atlas.Atlas) error {
+	ar, ok := srv.Handler.(*atlasRouter)
+	if !ok {
+		return ErrNotReloadable
+	}
+
+	prev := ar.current.Swap(newRouterGeneration(a))
+
+	// acquiring the write lock waits for the in flight requests to release
+	// their read locks. Requests which picked up the previous generation
+	// after the swap notice it's not current anymore and retry.
+	prev.Lock()
+	prev.Unlock()
+
+	// no request of the previous generation is left to start background work
+	prev.background.Wait()
+
+	return nil
+}
+
+// atlasRouter routes requests to the router of the current atlas, which can
+// be replaced by Reload while the server is running
+type atlasRouter struct {
+	current atomic.Pointer[routerGeneration]
+}
+
+// routerGeneration is the router of an atlas. The read lock is held by the
+// requests in flight.
+type routerGeneration struct {
+	sync.RWMutex
+	router http.Handler
+	// background tracks the work the requests leave running once they have
+	// completed, like the encoding of the tiles for the cache
+	background sync.WaitGroup
+}
+
+func newRouterGeneration(a *atlas.Atlas) *routerGeneration {
+	return &routerGeneration{router: NewRouter(a)}
+}
+
+func newAtlasRouter(a *atlas.Atlas) *atlasRouter {
+	var ar atlasRouter
+	ar.current.Store(newRouterGeneration(a))
+	return &ar
+}
+
+func (ar *atlasRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for {
+		gen := ar.current.Load()
+		gen.RLock()
+		if gen != ar.current.Load() {
+			// replaced while we were waiting for the lock
+			gen.RUnlock()
+			continue
+		}
+
+		defer gen.RUnlock()
+		gen.router.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), backgroundKey{}, &gen.background)))
+		return
+	}
+}
+
+// backgroundKey is the context key of the wait group tracking the background
+// work of the requests
+type backgroundKey struct{}
+
+// background returns the wait group tracking the work the request leaves
+// running in the background. Work is added to it while the request is served.
+func background(ctx context.Context) *sync.WaitGroup {
+	if wg, ok := ctx.Value(backgroundKey{}).(*sync.WaitGroup); ok {
+		return wg
+	}
+	// the router is not served by Start, it's not reloaded
+	return new(sync.WaitGroup)
+}
+
 // hostName determines whether to use an user defined HostName
 // or the host from the incoming request
 func hostName(r *http.Request) *url.URL {
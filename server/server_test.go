@@ -245,3 +245,55 @@ func TestHTTPS(t *testing.T) {
 		t.Run(k, fn(v))
 	}
 }
+
+func TestReload(t *testing.T) {
+	type tcase struct {
+		uri          string
+		expectedCode int
+	}
+
+	otherMap := atlas.NewWebMercatorMap("other-map")
+	otherMap.Layers = append(otherMap.Layers, testLayer1)
+	reloaded := &atlas.Atlas{}
+	reloaded.AddMap(otherMap)
+
+	srv := server.Start(newTestMapWithLayers(testLayer1), "127.0.0.1:0")
+	defer srv.Shutdown(context.Background())
+
+	if err := server.Reload(srv, reloaded); err != nil {
+		t.Fatalf("reload, expected nil got %v", err)
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.uri, nil))
+			if w.Code != tc.expectedCode {
+				t.Errorf("status code, expected %v got %v", tc.expectedCode, w.Code)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"replaced map": {
+			uri:          "/capabilities/test-map.json",
+			expectedCode: http.StatusBadRequest,
+		},
+		"new map": {
+			uri:          "/capabilities/other-map.json",
+			expectedCode: http.StatusOK,
+		},
+		"new map tile": {
+			uri:          "/maps/other-map/4/2/3.pbf",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+
+	if err := server.Reload(&http.Server{}, reloaded); err != server.ErrNotReloadable {
+		t.Errorf("reload of a server not started by Start, expected %v got %v", server.ErrNotReloadable, err)
+	}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTileQueueTimeout is returned by TileLimiter.Acquire when no tile
+// finished rendering within the queue timeout
+var ErrTileQueueTimeout = errors.New("server: timed out waiting to render the tile")
+
+// TileLimiter caps the number of tiles rendered at once, so a burst of
+// requests for uncached tiles can't exhaust the connections of the data
+// providers. Requests wait in line for up to the QueueTimeout.
+type TileLimiter struct {
+	// QueueTimeout is how long a tile waits for its turn, 0 is no limit
+	QueueTimeout time.Duration
+
+	slots chan struct{}
+}
+
+// NewTileLimiter returns a limiter letting max tiles be rendered at once
+func NewTileLimiter(max int, queueTimeout time.Duration) *TileLimiter {
+	return &TileLimiter{
+		QueueTimeout: queueTimeout,
+		slots:        make(chan struct{}, max),
+	}
+}
+
+// Acquire waits for the turn of a tile to be rendered. The returned func
+// must be called once the tile is rendered. ErrTileQueueTimeout is returned
+// when the wait exceeds the QueueTimeout, the error of ctx when it's done.
+func (tl *TileLimiter) Acquire(ctx context.Context) (release func(), err error) {
+	release = func() { <-tl.slots }
+
+	// fast path, no need for a timer
+	select {
+	case tl.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	var timeout <-chan time.Time
+	if tl.QueueTimeout > 0 {
+		timer := time.NewTimer(tl.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case tl.slots <- struct{}{}:
+		return release, nil
+	case <-timeout:
+		return nil, ErrTileQueueTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,55 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestTileLimiter(t *testing.T) {
+	server.URIPrefix = "/"
+
+	tl := server.NewTileLimiter(1, 10*time.Millisecond)
+	server.TileConcurrency = tl
+	defer func() { server.TileConcurrency = nil }()
+
+	a := newTestMapWithLayers(testLayer1, testLayer2, testLayer3)
+
+	// hold the only slot, so the tile request waits in line and times out
+	release, err := tl.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	w, _, err := doRequest(t, a, http.MethodGet, "/maps/test-map/10/2/3.pbf", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status code, expected %v got %v", http.StatusServiceUnavailable, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("header Retry-After, expected 1 got %v", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tl.QueueTimeout = 0
+	if _, err := tl.Acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled acquire, expected %v got %v", context.Canceled, err)
+	}
+
+	release()
+
+	w, _, err = doRequest(t, a, http.MethodGet, "/maps/test-map/10/2/3.pbf", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("status code, expected %v got %v", http.StatusOK, w.Code)
+	}
+}
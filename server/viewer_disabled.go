@@ -4,6 +4,8 @@
 package server
 
 import (
+	"net/http"
+
 	"github.com/dimfeld/httptreemux"
 
 	"github.com/go-spatial/tegola/observability"
@@ -11,4 +13,4 @@ import (
 
 // setupViewer in this file is used for removing the viewer routes when the
 // build flag `noViewer` is set
-func setupViewer(o observability.Interface, group *httptreemux.Group) {}
+func setupViewer(o observability.Interface, group *httptreemux.Group) http.Handler { return nil }
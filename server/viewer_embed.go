@@ -13,10 +13,12 @@ import (
 )
 
 // setupViewer in this file is used for registering the viewer routes when the viewer
-// is included in the build (default)
-func setupViewer(o observability.Interface, group *httptreemux.Group) {
+// is included in the build (default). The viewer index at "/" is returned for the
+// OGC API landing page to fall back to.
+func setupViewer(o observability.Interface, group *httptreemux.Group) http.Handler {
 	// We need to Strip the URIPrefix from the request path before serving the file
 	// This is used when the server sits behind a reverse proxy with a prefix (i.e. /tegola)
-	group.UsingContext().Handler(observability.InstrumentViewerHandler(http.MethodGet, "/", o, http.StripPrefix(URIPrefix, http.FileServer(ui.GetDistFileSystem()))))
 	group.UsingContext().Handler(observability.InstrumentViewerHandler(http.MethodGet, "/*path", o, http.StripPrefix(URIPrefix, http.FileServer(ui.GetDistFileSystem()))))
+
+	return http.StripPrefix(URIPrefix, http.FileServer(ui.GetDistFileSystem()))
 }
@@ -0,0 +1,328 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-spatial/geom/encoding/mvt"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// WMTS 1.0.0 (https://www.ogc.org/standard/wmts/) support. Each map is a
+// WMTS layer, and each layer of a map is a WMTS layer named "map:layer".
+// Tiles are served by the same handler as the /maps endpoints.
+
+const (
+	WMTSVersion = "1.0.0"
+
+	// WMTSDefaultStyle is the only style tiles are available in
+	WMTSDefaultStyle = "default"
+
+	// wmtsLayerSeparator separates the map and layer name in the identifier of a map layer
+	wmtsLayerSeparator = ":"
+
+	mimeTypeXML = "application/xml"
+)
+
+// WMTSCapabilities is the capabilities document of the service
+type WMTSCapabilities struct {
+	XMLName            xml.Name               `xml:"Capabilities"`
+	XMLNS              string                 `xml:"xmlns,attr"`
+	XMLNSOWS           string                 `xml:"xmlns:ows,attr"`
+	XMLNSXLink         string                 `xml:"xmlns:xlink,attr"`
+	Version            string                 `xml:"version,attr"`
+	Title              string                 `xml:"ows:ServiceIdentification>ows:Title"`
+	ServiceType        string                 `xml:"ows:ServiceIdentification>ows:ServiceType"`
+	ServiceTypeVersion string                 `xml:"ows:ServiceIdentification>ows:ServiceTypeVersion"`
+	Operations         []WMTSOperation        `xml:"ows:OperationsMetadata>ows:Operation"`
+	Layers             []WMTSLayer            `xml:"Contents>Layer"`
+	TileMatrixSets     []WMTSTileMatrixSet    `xml:"Contents>TileMatrixSet"`
+	ServiceMetadataURL WMTSServiceMetadataURL `xml:"ServiceMetadataURL"`
+}
+
+// WMTSOperation describes how an operation can be requested
+type WMTSOperation struct {
+	Name string        `xml:"name,attr"`
+	Get  []WMTSHTTPGet `xml:"ows:DCP>ows:HTTP>ows:Get"`
+}
+
+// WMTSHTTPGet is an endpoint of an operation
+type WMTSHTTPGet struct {
+	Href       string         `xml:"xlink:href,attr"`
+	Constraint WMTSConstraint `xml:"ows:Constraint"`
+}
+
+// WMTSConstraint lists the allowed values of a constraint of an endpoint
+type WMTSConstraint struct {
+	Name          string   `xml:"name,attr"`
+	AllowedValues []string `xml:"ows:AllowedValues>ows:Value"`
+}
+
+// wmtsGet returns the endpoint at href using the encoding (KVP or RESTful)
+func wmtsGet(href, encoding string) WMTSHTTPGet {
+	return WMTSHTTPGet{
+		Href: href,
+		Constraint: WMTSConstraint{
+			Name:          "GetEncoding",
+			AllowedValues: []string{encoding},
+		},
+	}
+}
+
+// WMTSLayer is a map or map layer
+type WMTSLayer struct {
+	Title              string                  `xml:"ows:Title"`
+	WGS84BoundingBox   *WMTSBoundingBox        `xml:"ows:WGS84BoundingBox,omitempty"`
+	Identifier         string                  `xml:"ows:Identifier"`
+	Style              WMTSStyle               `xml:"Style"`
+	Format             []string                `xml:"Format"`
+	TileMatrixSetLinks []WMTSTileMatrixSetLink `xml:"TileMatrixSetLink"`
+	ResourceURLs       []WMTSResourceURL       `xml:"ResourceURL"`
+}
+
+// WMTSBoundingBox is a bounding box with corners as "x y"
+type WMTSBoundingBox struct {
+	LowerCorner string `xml:"ows:LowerCorner"`
+	UpperCorner string `xml:"ows:UpperCorner"`
+}
+
+type WMTSStyle struct {
+	IsDefault  bool   `xml:"isDefault,attr"`
+	Identifier string `xml:"ows:Identifier"`
+}
+
+// WMTSTileMatrixSetLink links a layer to a tile matrix set, with the tile matrices it has tiles for
+type WMTSTileMatrixSetLink struct {
+	TileMatrixSet string                 `xml:"TileMatrixSet"`
+	Limits        []WMTSTileMatrixLimits `xml:"TileMatrixSetLimits>TileMatrixLimits"`
+}
+
+type WMTSTileMatrixLimits struct {
+	TileMatrix string `xml:"TileMatrix"`
+	MinTileRow uint   `xml:"MinTileRow"`
+	MaxTileRow uint   `xml:"MaxTileRow"`
+	MinTileCol uint   `xml:"MinTileCol"`
+	MaxTileCol uint   `xml:"MaxTileCol"`
+}
+
+// WMTSResourceURL is the RESTful url template of the tiles of a layer
+type WMTSResourceURL struct {
+	Format       string `xml:"format,attr"`
+	ResourceType string `xml:"resourceType,attr"`
+	Template     string `xml:"template,attr"`
+}
+
+type WMTSTileMatrixSet struct {
+	Identifier        string           `xml:"ows:Identifier"`
+	SupportedCRS      string           `xml:"ows:SupportedCRS"`
+	WellKnownScaleSet string           `xml:"WellKnownScaleSet,omitempty"`
+	TileMatrices      []WMTSTileMatrix `xml:"TileMatrix"`
+}
+
+type WMTSTileMatrix struct {
+	Identifier       string  `xml:"ows:Identifier"`
+	ScaleDenominator float64 `xml:"ScaleDenominator"`
+	TopLeftCorner    string  `xml:"TopLeftCorner"`
+	TileWidth        uint    `xml:"TileWidth"`
+	TileHeight       uint    `xml:"TileHeight"`
+	MatrixWidth      uint    `xml:"MatrixWidth"`
+	MatrixHeight     uint    `xml:"MatrixHeight"`
+}
+
+type WMTSServiceMetadataURL struct {
+	Href string `xml:"xlink:href,attr"`
+}
+
+// ogcURN converts an OGC definition URI (i.e. http://www.opengis.net/def/crs/EPSG/0/3857)
+// to the URN form used by WMTS (i.e. urn:ogc:def:crs:EPSG::3857)
+func ogcURN(uri string) string {
+	parts := strings.Split(strings.TrimPrefix(uri, "http://www.opengis.net/def/"), "/")
+	if len(parts) != 4 {
+		return uri
+	}
+	// version 0 means no version
+	if parts[2] == "0" {
+		parts[2] = ""
+	}
+	return "urn:ogc:def:" + strings.Join(parts, ":")
+}
+
+func wmtsTileMatrixSet(tms TileMatrixSet) WMTSTileMatrixSet {
+	set := WMTSTileMatrixSet{
+		Identifier:        tms.ID,
+		SupportedCRS:      ogcURN(tms.CRS),
+		WellKnownScaleSet: ogcURN(tms.WellKnownScaleSet),
+	}
+	for _, tm := range tms.TileMatrices {
+		set.TileMatrices = append(set.TileMatrices, WMTSTileMatrix{
+			Identifier:       tm.ID,
+			ScaleDenominator: tm.ScaleDenominator,
+			TopLeftCorner:    formatCorner(tm.PointOfOrigin[0], tm.PointOfOrigin[1]),
+			TileWidth:        tm.TileWidth,
+			TileHeight:       tm.TileHeight,
+			MatrixWidth:      tm.MatrixWidth,
+			MatrixHeight:     tm.MatrixHeight,
+		})
+	}
+	return set
+}
+
+func formatCorner(x, y float64) string {
+	return strconv.FormatFloat(x, 'f', -1, 64) + " " + strconv.FormatFloat(y, 'f', -1, 64)
+}
+
+// wmtsLayer builds the WMTS layer for the map, limited to the zooms between minZoom and maxZoom
+func wmtsLayer(r *http.Request, m atlas.Map, identifier string, minZoom, maxZoom uint) WMTSLayer {
+	l := WMTSLayer{
+		Title:      identifier,
+		Identifier: identifier,
+		Style: WMTSStyle{
+			IsDefault:  true,
+			Identifier: WMTSDefaultStyle,
+		},
+		Format: []string{mvt.MimeType},
+	}
+
+	if m.Bounds != nil {
+		l.WGS84BoundingBox = &WMTSBoundingBox{
+			LowerCorner: formatCorner(m.Bounds.MinX(), m.Bounds.MinY()),
+			UpperCorner: formatCorner(m.Bounds.MaxX(), m.Bounds.MaxY()),
+		}
+	}
+
+	tms := ogcTileMatrixSetFor(m.Grid())
+	link := WMTSTileMatrixSetLink{
+		TileMatrixSet: tms.ID,
+	}
+	for z := minZoom; z <= maxZoom && int(z) < len(tms.TileMatrices); z++ {
+		tm := tms.TileMatrices[z]
+		link.Limits = append(link.Limits, WMTSTileMatrixLimits{
+			TileMatrix: tm.ID,
+			MaxTileRow: tm.MatrixHeight - 1,
+			MaxTileCol: tm.MatrixWidth - 1,
+		})
+	}
+	l.TileMatrixSetLinks = append(l.TileMatrixSetLinks, link)
+
+	l.ResourceURLs = []WMTSResourceURL{
+		{
+			Format:       mvt.MimeType,
+			ResourceType: "tile",
+			Template:     ogcURL(r, "wmts", WMTSVersion, identifier, "{Style}", "{TileMatrixSet}", "{TileMatrix}", "{TileRow}", "{TileCol}") + "." + TileURLFileFormat,
+		},
+	}
+
+	return l
+}
+
+// wmtsCapabilities builds the capabilities document for the maps
+func wmtsCapabilities(r *http.Request, maps []atlas.Map) WMTSCapabilities {
+	kvp := ogcURL(r, "wmts") + "?"
+
+	c := WMTSCapabilities{
+		XMLNS:              "http://www.opengis.net/wmts/1.0",
+		XMLNSOWS:           "http://www.opengis.net/ows/1.1",
+		XMLNSXLink:         "http://www.w3.org/1999/xlink",
+		Version:            WMTSVersion,
+		Title:              "tegola",
+		ServiceType:        "OGC WMTS",
+		ServiceTypeVersion: WMTSVersion,
+		Operations: []WMTSOperation{
+			{
+				Name: "GetCapabilities",
+				Get: []WMTSHTTPGet{
+					wmtsGet(kvp, "KVP"),
+					wmtsGet(ogcURL(r, "wmts", WMTSVersion, "WMTSCapabilities.xml"), "RESTful"),
+				},
+			},
+			{
+				Name: "GetTile",
+				Get: []WMTSHTTPGet{
+					wmtsGet(kvp, "KVP"),
+					wmtsGet(ogcURL(r, "wmts", WMTSVersion)+"/", "RESTful"),
+				},
+			},
+		},
+		ServiceMetadataURL: WMTSServiceMetadataURL{
+			Href: ogcURL(r, "wmts", WMTSVersion, "WMTSCapabilities.xml"),
+		},
+	}
+
+	// the tile matrix sets used by the maps
+	seen := make(map[string]bool)
+	for _, m := range maps {
+		if g := m.Grid(); !seen[g.ID] {
+			seen[g.ID] = true
+			c.TileMatrixSets = append(c.TileMatrixSets, wmtsTileMatrixSet(ogcTileMatrixSetFor(g)))
+		}
+
+		minZoom, maxZoom := ogcZoomRange(m)
+		c.Layers = append(c.Layers, wmtsLayer(r, m, m.Name, minZoom, maxZoom))
+
+		for _, l := range ogcLayers(m) {
+			c.Layers = append(c.Layers, wmtsLayer(r, m, m.Name+wmtsLayerSeparator+l.name, l.minZoom, l.maxZoom))
+		}
+	}
+
+	return c
+}
+
+// parseWMTSLayer splits the identifier of a WMTS layer into the map and layer name
+func parseWMTSLayer(identifier string) (mapName, layerName string) {
+	mapName, layerName, _ = strings.Cut(identifier, wmtsLayerSeparator)
+	return mapName, layerName
+}
+
+// WMTS exception codes, as defined by OWS Common and WMTS
+const (
+	WMTSExceptionMissingParameterValue = "MissingParameterValue"
+	WMTSExceptionInvalidParameterValue = "InvalidParameterValue"
+	WMTSExceptionOperationNotSupported = "OperationNotSupported"
+)
+
+// WMTSExceptionReport is the error response of the service
+type WMTSExceptionReport struct {
+	XMLName   xml.Name      `xml:"ows:ExceptionReport"`
+	XMLNSOWS  string        `xml:"xmlns:ows,attr"`
+	Version   string        `xml:"version,attr"`
+	Exception WMTSException `xml:"ows:Exception"`
+}
+
+type WMTSException struct {
+	Code    string `xml:"exceptionCode,attr"`
+	Locator string `xml:"locator,attr,omitempty"`
+	Text    string `xml:"ows:ExceptionText"`
+}
+
+// wmtsError writes an exception report
+func wmtsError(w http.ResponseWriter, status int, code, locator, format string, args ...interface{}) {
+	w.Header().Set("Content-Type", mimeTypeXML)
+	w.WriteHeader(status)
+
+	writeXML(w, WMTSExceptionReport{
+		XMLNSOWS: "http://www.opengis.net/ows/1.1",
+		Version:  "1.1.0",
+		Exception: WMTSException{
+			Code:    code,
+			Locator: locator,
+			Text:    fmt.Sprintf(format, args...),
+		},
+	})
+}
+
+// writeXML encodes v as the XML response body
+func writeXML(w http.ResponseWriter, v interface{}) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", mimeTypeXML)
+	}
+
+	w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("error trying to encode response (%s)", err)
+	}
+}